@@ -20,6 +20,7 @@ export default function NowPlaying() {
   } | null>(null);
   const [msgOpen, setMsgOpen] = useState<Record<string, boolean>>({});
   const [msgText, setMsgText] = useState<Record<string, string>>({});
+  const [controlNote, setControlNote] = useState<Record<string, string>>({});
   const [overflowTitles, setOverflowTitles] = useState<Record<string, boolean>>({});
   const keyFor = (s: NowEntry) => `${(s.server_type || "emby").toLowerCase()}|${s.session_id}`;
 
@@ -111,14 +112,26 @@ export default function NowPlaying() {
     const alias = (entry.server_type || "emby").toLowerCase();
     const sid = entry.session_id;
     try {
-      if (action === "pause" || action === "unpause") {
-        await fetch(`${apiBase}/api/now/sessions/${alias}/${sid}/pause`, {
-          method: "POST",
-          headers: { "Content-Type": "application/json" },
-          body: JSON.stringify({ paused: action === "pause" }),
-        });
-      } else if (action === "stop") {
-        await fetch(`${apiBase}/api/now/sessions/${alias}/${sid}/stop`, { method: "POST" });
+      if (action === "pause" || action === "unpause" || action === "stop") {
+        const res =
+          action === "stop"
+            ? await fetch(`${apiBase}/api/now/sessions/${alias}/${sid}/stop`, { method: "POST" })
+            : await fetch(`${apiBase}/api/now/sessions/${alias}/${sid}/pause`, {
+                method: "POST",
+                headers: { "Content-Type": "application/json" },
+                body: JSON.stringify({ paused: action === "pause" }),
+              });
+        // The server confirms whether the player actually changed state
+        const body = (await res.json().catch(() => null)) as { outcome?: string; error?: string } | null;
+        let note = "";
+        if (!res.ok) note = body?.error || `Failed (${res.status})`;
+        else if (body?.outcome === "not_supported") note = "Not supported by this player";
+        else if (body?.outcome === "timeout") note = "Sent, but the player didn't respond";
+        const k = keyFor(entry);
+        setControlNote((prev) => ({ ...prev, [k]: note }));
+        if (note) {
+          setTimeout(() => setControlNote((prev) => ({ ...prev, [k]: "" })), 5000);
+        }
       } else if (action === "message") {
         await fetch(`${apiBase}/api/now/sessions/${alias}/${sid}/message`, {
           method: "POST",
@@ -628,6 +641,9 @@ export default function NowPlaying() {
                           <Icon name="stop" />
                         </button>
                       </div>
+                      {controlNote[keyFor(s)] && (
+                        <div className="mt-1 text-[11px] text-amber-400">{controlNote[keyFor(s)]}</div>
+                      )}
                       {msgOpen[keyFor(s)] && (
                         <div className="mt-2 flex items-center gap-2">
                           <input
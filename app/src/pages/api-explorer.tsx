@@ -26,7 +26,7 @@ type Endpoint = {
 // Read admin token similar to lib/api.ts
 const ADMIN_TOKEN_STORAGE_KEY = "emby_admin_token";
 function adminAuthHeaderFor(path: string): Record<string, string> {
-  if (!path.startsWith("/admin") && !path.startsWith("/api/settings")) return {};
+  if (!path.startsWith("/admin") && !path.startsWith("/api/settings") && !path.startsWith("/api/setup")) return {};
   try {
     if (typeof window !== "undefined") {
       const t = window.localStorage.getItem(ADMIN_TOKEN_STORAGE_KEY);
@@ -146,6 +146,15 @@ const endpoints: Endpoint[] = [
     usage: "Fix sessions showing incorrect start times due to reactivation overwrites.",
     dangerous: true,
   },
+  {
+    id: "admin-backfill-palettes",
+    category: "Admin",
+    method: "POST",
+    path: "/admin/images/palettes/backfill",
+    description: "Extract poster color palettes for movies and series that have none stored yet.",
+    usage: "Theme cards for items whose posters were cached before palettes were stored.",
+    params: [{ key: "limit", kind: "query", placeholder: "200" }],
+  },
   {
     id: "admin-remap-item-dry",
     category: "Admin",
@@ -158,6 +167,64 @@ const endpoints: Endpoint[] = [
       { key: "to_id", kind: "query", required: true, placeholder: "new_id" },
     ],
   },
+  {
+    id: "admin-devices",
+    category: "Admin",
+    method: "GET",
+    path: "/admin/devices",
+    description: "Device ids seen in playback history with their admin names and merge groups.",
+    usage: "Find the ids of one physical device before naming or merging them.",
+  },
+  {
+    id: "admin-device-rename",
+    category: "Admin",
+    method: "PUT",
+    path: "/admin/devices/:id",
+    description: "Name a device and every id merged with it; an empty name clears it.",
+    usage: "Replace opaque device GUIDs with readable names in stats and history.",
+    params: [
+      { key: "id", kind: "path", required: true, placeholder: "device_id" },
+      { key: "name", kind: "body", placeholder: "Living Room TV" },
+    ],
+  },
+  {
+    id: "admin-device-unmerge",
+    category: "Admin",
+    method: "DELETE",
+    path: "/admin/devices/:id/alias",
+    description: "Take a device out of its merged group; the canonical device dissolves the group.",
+    usage: "Undo a device merge.",
+    params: [{ key: "id", kind: "path", required: true, placeholder: "device_id" }],
+    dangerous: true,
+  },
+  {
+    id: "admin-user-mappings",
+    category: "Admin",
+    method: "GET",
+    path: "/admin/user-mappings",
+    description: "People with the accounts on each media server mapped to them.",
+    usage: "Check which Emby, Plex and Jellyfin accounts top users and watch time count together.",
+  },
+  {
+    id: "admin-user-mapping-unmap",
+    category: "Admin",
+    method: "DELETE",
+    path: "/admin/user-mappings/users/:id",
+    description: "Take one account out of the person it is mapped to.",
+    usage: "Undo a wrong mapping without touching the person's other accounts.",
+    params: [{ key: "id", kind: "path", required: true, placeholder: "user_id" }],
+    dangerous: true,
+  },
+  {
+    id: "admin-user-mapping-delete",
+    category: "Admin",
+    method: "DELETE",
+    path: "/admin/user-mappings/:id",
+    description: "Remove a person; their accounts count separately again.",
+    usage: "Dissolve a person mapping entirely.",
+    params: [{ key: "id", kind: "path", required: true, placeholder: "person_id" }],
+    dangerous: true,
+  },
   {
     id: "admin-remap-item-apply",
     category: "Admin",
@@ -214,7 +281,10 @@ const endpoints: Endpoint[] = [
     path: "/stats/usage",
     description: "Watch time per day.",
     usage: "Usage trends over time.",
-    params: [{ key: "days", kind: "query", placeholder: "14" }],
+    params: [
+      { key: "days", kind: "query", placeholder: "14" },
+      { key: "server", kind: "query", placeholder: "plex|default-plex" },
+    ],
   },
   {
     id: "stats-top-users",
@@ -226,6 +296,7 @@ const endpoints: Endpoint[] = [
     params: [
       { key: "timeframe", kind: "query", placeholder: "1d|3d|7d|14d|30d" },
       { key: "limit", kind: "query", placeholder: "10" },
+      { key: "server", kind: "query", placeholder: "plex|default-plex" },
     ],
   },
   {
@@ -238,6 +309,7 @@ const endpoints: Endpoint[] = [
     params: [
       { key: "timeframe", kind: "query", placeholder: "1d|3d|7d|14d|30d" },
       { key: "limit", kind: "query", placeholder: "10" },
+      { key: "server", kind: "query", placeholder: "plex|default-plex" },
     ],
   },
   {
@@ -289,8 +361,8 @@ const endpoints: Endpoint[] = [
     category: "Stats",
     method: "GET",
     path: "/stats/users/:id",
-    description: "Details for one user.",
-    usage: "Per-user drilldown.",
+    description: "Details for one user, with their devices and (admins only) IP history.",
+    usage: "Per-user drilldown; audit account sharing.",
     params: [{ key: "id", kind: "path", required: true, placeholder: "emby-user-id" }],
   },
   {
@@ -317,7 +389,10 @@ const endpoints: Endpoint[] = [
     path: "/stats/play-methods",
     description: "Playback methods summary and recent transcodes.",
     usage: "DirectPlay vs Transcode, with per-stream breakdown.",
-    params: [{ key: "days", kind: "query", placeholder: "30" }],
+    params: [
+      { key: "days", kind: "query", placeholder: "30" },
+      { key: "server", kind: "query", placeholder: "plex|default-plex" },
+    ],
   },
   {
     id: "stats-playback-methods",
@@ -403,6 +478,161 @@ const endpoints: Endpoint[] = [
       { key: "server", kind: "query", placeholder: "default-jellyfin" },
     ],
   },
+  {
+    id: "stats-session-events",
+    category: "Stats",
+    method: "GET",
+    path: "/stats/sessions/:id/events",
+    description: "Raw playback events of one session in recorded order, with position and time deltas.",
+    usage: "Debug disputed watch time; format=csv downloads the events.",
+    params: [
+      { key: "id", kind: "path", required: true, placeholder: "1234" },
+      { key: "format", kind: "query", placeholder: "csv" },
+    ],
+  },
+  {
+    id: "stats-requests-funnel",
+    category: "Stats",
+    method: "GET",
+    path: "/stats/requests/funnel",
+    description: "Overseerr/Jellyseerr requests: requested → added → watched within the window.",
+    usage: "See whether requested content actually gets watched (needs OVERSEERR_URL/OVERSEERR_API_KEY).",
+    params: [
+      { key: "days", kind: "query", placeholder: "180" },
+      { key: "window", kind: "query", placeholder: "30" },
+      { key: "limit", kind: "query", placeholder: "100" },
+    ],
+  },
+  {
+    id: "stats-library-health",
+    category: "Stats",
+    method: "GET",
+    path: "/stats/library/health",
+    description: "Health score per library from unwatched share, duplicates, codec modernity and bitrate fit.",
+    usage: "Track a single curation KPI per server and library.",
+    params: [
+      { key: "days", kind: "query", placeholder: "365" },
+      { key: "server", kind: "query", placeholder: "plex|default-plex" },
+    ],
+  },
+  {
+    id: "stats-library-compare",
+    category: "Stats",
+    method: "GET",
+    path: "/stats/library/compare",
+    description: "Movies and episodes present on one server but not the other, plus titles both have in different resolutions.",
+    usage: "Keep a backup server in sync with the primary library.",
+    params: [
+      { key: "server_a", kind: "query", placeholder: "default-emby" },
+      { key: "server_b", kind: "query", placeholder: "default-jellyfin" },
+      { key: "media_type", kind: "query", placeholder: "Movie|Episode" },
+      { key: "limit", kind: "query", placeholder: "200" },
+    ],
+  },
+  {
+    id: "stats-idle-windows",
+    category: "Stats",
+    method: "GET",
+    path: "/stats/idle-windows",
+    description: "Longest and typical daily windows with no streaming per server, plus idle share per hour of day.",
+    usage: "Pick a time for maintenance, backups or transcode batches when nobody is watching.",
+    params: [
+      { key: "days", kind: "query", placeholder: "30" },
+      { key: "tz", kind: "query", placeholder: "Europe/Berlin" },
+      { key: "min_idle_pct", kind: "query", placeholder: "90" },
+      { key: "server", kind: "query", placeholder: "plex|default-plex" },
+    ],
+  },
+  {
+    id: "stats-bandwidth",
+    category: "Stats",
+    method: "GET",
+    path: "/stats/bandwidth",
+    description: "Outbound streaming bandwidth per hour (average and peak Mbps, GB) with totals per user and server.",
+    usage: "See how much upload your streams use and when it peaks.",
+    params: [
+      { key: "days", kind: "query", placeholder: "7" },
+      { key: "server", kind: "query", placeholder: "plex|default-plex" },
+    ],
+  },
+  {
+    id: "stats-heatmap",
+    category: "Stats",
+    method: "GET",
+    path: "/stats/heatmap",
+    description: "Watch seconds by weekday and hour of the day as a 7×24 matrix, Sunday first.",
+    usage: "Render a heatmap of when people watch, overall or for one user.",
+    params: [
+      { key: "days", kind: "query", placeholder: "30" },
+      { key: "tz", kind: "query", placeholder: "Europe/Berlin" },
+      { key: "user_id", kind: "query", placeholder: "emby-user-id" },
+      { key: "server", kind: "query", placeholder: "plex|default-plex" },
+    ],
+  },
+  {
+    id: "stats-track-overrides",
+    category: "Stats",
+    method: "GET",
+    path: "/stats/track-overrides",
+    description: "Per item, how often sessions switched audio, toggled subtitles or started on a non-default audio track.",
+    usage: "Find items whose default audio or subtitle tracks viewers keep changing.",
+    params: [
+      { key: "days", kind: "query", placeholder: "30" },
+      { key: "limit", kind: "query", placeholder: "50" },
+      { key: "item_id", kind: "query", placeholder: "item id" },
+    ],
+  },
+  {
+    id: "reports-costs",
+    category: "Stats",
+    method: "GET",
+    path: "/reports/costs",
+    description: "Approximate monthly cost per user and server from the cost_* settings.",
+    usage: "Split shared server running costs by hours streamed, bandwidth and transcoding.",
+    params: [
+      { key: "months", kind: "query", placeholder: "3" },
+      { key: "tz", kind: "query", placeholder: "Europe/Berlin" },
+      { key: "server", kind: "query", placeholder: "plex|default-plex" },
+    ],
+  },
+  {
+    id: "public-ids",
+    category: "Stats",
+    method: "GET",
+    path: "/api/public-ids",
+    description: "Stable public id and deep link for a session, item, user or saved view.",
+    usage: "Build links for notifications and exports that survive remaps and re-syncs.",
+    params: [
+      { key: "kind", kind: "query", required: true, placeholder: "session|item|user|report" },
+      { key: "id", kind: "query", required: true, placeholder: "internal id" },
+    ],
+  },
+  {
+    id: "resolve-public-id",
+    category: "Stats",
+    method: "GET",
+    path: "/resolve/:publicId",
+    description: "What a public id currently points at, following remaps and re-syncs.",
+    usage: "Open deep links from notifications and exports; add redirect=true to jump to the data.",
+    params: [
+      { key: "publicId", kind: "path", required: true, placeholder: "itm_3kq9v2m7xw4pc" },
+      { key: "redirect", kind: "query", placeholder: "true" },
+    ],
+  },
+  {
+    id: "grafana-series",
+    category: "Stats",
+    method: "GET",
+    path: "/grafana/query",
+    description: "One daily series (watch_hours, streams, transcodes, active_users) as {time, value} rows.",
+    usage: "Grafana Infinity datasource; the JSON datasource uses POST /grafana/search and /grafana/query.",
+    params: [
+      { key: "target", kind: "query", required: true, placeholder: "watch_hours" },
+      { key: "from", kind: "query", placeholder: "unix ms" },
+      { key: "to", kind: "query", placeholder: "unix ms" },
+      { key: "server", kind: "query", placeholder: "plex|default-plex" },
+    ],
+  },
 
   // Items & images
   {
@@ -456,6 +686,26 @@ const endpoints: Endpoint[] = [
       { key: "value", kind: "body", required: true, placeholder: "true|false|string" },
     ],
   },
+  {
+    id: "api-me-stats-defaults",
+    category: "Settings",
+    method: "GET",
+    path: "/api/me/stats-defaults",
+    description: "Global and personal default timeframe and server scope for stats requests.",
+    usage: "See which timeframe/server is applied when a stats call omits them.",
+  },
+  {
+    id: "api-me-stats-defaults-update",
+    category: "Settings",
+    method: "PUT",
+    path: "/api/me/stats-defaults",
+    description: "Set your own default timeframe and server scope; empty values use the global defaults.",
+    usage: "Default your views to one server without passing ?server= everywhere.",
+    params: [
+      { key: "timeframe", kind: "body", placeholder: "7d" },
+      { key: "server", kind: "body", placeholder: "plex" },
+    ],
+  },
 
   // Now Playing
   {
@@ -544,7 +794,7 @@ const endpoints: Endpoint[] = [
     category: "Now",
     method: "POST",
     path: "/api/now/sessions/:server/:id/pause",
-    description: "Pause or resume a session on a specific server.",
+    description: "Pause or resume a session on a specific server and confirm the player's new state (outcome: applied, not_supported or timeout).",
     usage: "Multi-server aware moderation.",
     params: [
       { key: "server", kind: "path", required: true, placeholder: "emby|plex|jellyfin" },
@@ -557,7 +807,7 @@ const endpoints: Endpoint[] = [
     category: "Now",
     method: "POST",
     path: "/api/now/sessions/:server/:id/stop",
-    description: "Stop a session on a specific server.",
+    description: "Stop a session on a specific server and confirm it ended (outcome: applied, not_supported or timeout).",
     usage: "Multi-server aware moderation.",
     params: [
       { key: "server", kind: "path", required: true, placeholder: "emby|plex|jellyfin" },
@@ -650,6 +900,75 @@ const endpoints: Endpoint[] = [
     usage: "Drive UI around self-registration flows.",
   },
 
+  // First-run setup
+  {
+    id: "setup-status",
+    category: "Setup",
+    method: "GET",
+    path: "/api/setup/status",
+    description: "First-run setup state: needs_setup, next_step, admin/server presence and chosen defaults.",
+    usage: "Decide whether to show the setup wizard. Public.",
+  },
+  {
+    id: "setup-admin",
+    category: "Setup",
+    method: "POST",
+    path: "/api/setup/admin",
+    description: "Create the first admin account and sign it in. Only while no app user exists.",
+    usage: "Step 1 of the setup wizard.",
+    params: [
+      { key: "username", kind: "body", required: true, placeholder: "admin" },
+      { key: "password", kind: "body", required: true, placeholder: "at least 8 characters" },
+    ],
+  },
+  {
+    id: "setup-servers-test",
+    category: "Setup",
+    method: "POST",
+    path: "/api/setup/servers/test",
+    description: "Check that a media server answers and accepts the API key, without saving it.",
+    usage: "Validate connection details before adding a server. Protected.",
+    params: [
+      { key: "type", kind: "body", required: true, placeholder: "emby | jellyfin | plex" },
+      { key: "base_url", kind: "body", required: true, placeholder: "http://jellyfin:8096" },
+      { key: "api_key", kind: "body", required: true, placeholder: "api key / Plex token" },
+      { key: "name", kind: "body", required: false, placeholder: "Living room" },
+    ],
+  },
+  {
+    id: "setup-servers-add",
+    category: "Setup",
+    method: "POST",
+    path: "/api/setup/servers",
+    description: "Test, store and start monitoring a media server.",
+    usage: "Step 2 of the setup wizard. Protected.",
+    params: [
+      { key: "type", kind: "body", required: true, placeholder: "emby | jellyfin | plex" },
+      { key: "base_url", kind: "body", required: true, placeholder: "http://jellyfin:8096" },
+      { key: "api_key", kind: "body", required: true, placeholder: "api key / Plex token" },
+      { key: "name", kind: "body", required: false, placeholder: "Living room" },
+      { key: "id", kind: "body", required: false, placeholder: "jellyfin-living-room" },
+      { key: "external_url", kind: "body", required: false, placeholder: "https://jellyfin.example.com" },
+    ],
+  },
+  {
+    id: "setup-defaults",
+    category: "Setup",
+    method: "PUT",
+    path: "/api/setup/defaults",
+    description: "Choose the registration mode; send history_retention_days as a JSON number to set playback history retention (0 keeps everything).",
+    usage: "Step 3 of the setup wizard. Protected.",
+    params: [{ key: "registration_mode", kind: "body", required: false, placeholder: "closed | secret | open" }],
+  },
+  {
+    id: "setup-complete",
+    category: "Setup",
+    method: "POST",
+    path: "/api/setup/complete",
+    description: "Finish setup and lock the setup endpoints.",
+    usage: "Last step of the setup wizard. Protected.",
+  },
+
   // Admin - Refresh & scheduler
   {
     id: "admin-refresh-start",
@@ -722,6 +1041,32 @@ const endpoints: Endpoint[] = [
     description: "Scheduler last/next runs stats.",
     usage: "Monitoring scheduled syncs. Protected.",
   },
+  {
+    id: "admin-schedulers",
+    category: "Admin",
+    method: "GET",
+    path: "/admin/schedulers",
+    description: "Background schedulers and whether each is paused.",
+    usage: "Check what is paused before or after a maintenance window.",
+  },
+  {
+    id: "admin-schedulers-pause",
+    category: "Admin",
+    method: "POST",
+    path: "/admin/schedulers/pause",
+    description: "Pause the named schedulers (sync, cleanup, transcoding), or all when name is empty.",
+    usage: "Keep syncs and cleanups off the SQLite writer during imports, vacuums or backups.",
+    params: [{ key: "name", kind: "query", placeholder: "sync,cleanup" }],
+  },
+  {
+    id: "admin-schedulers-resume",
+    category: "Admin",
+    method: "POST",
+    path: "/admin/schedulers/resume",
+    description: "Resume the named schedulers, or all when name is empty.",
+    usage: "End a maintenance window.",
+    params: [{ key: "name", kind: "query", placeholder: "sync" }],
+  },
 
   // Admin - Maintenance & cleanup
   {
@@ -750,6 +1095,22 @@ const endpoints: Endpoint[] = [
     description: "Normalize position tick units.",
     usage: "Data hygiene if needed. Protected.",
   },
+  {
+    id: "admin-import-tautulli",
+    category: "Admin",
+    method: "POST",
+    path: "/admin/import/tautulli?dry_run=true",
+    description: "Import Plex watch history from a Tautulli database (multipart file or path).",
+    usage: "Migrating from Tautulli. Protected.",
+  },
+  {
+    id: "admin-import-playback-reporting",
+    category: "Admin",
+    method: "POST",
+    path: "/admin/import/playback-reporting?dry_run=true",
+    description: "Import Emby/Jellyfin Playback Reporting plugin history (database or export; multipart file or path).",
+    usage: "Migrating from the Playback Reporting plugin. Protected.",
+  },
   {
     id: "admin-recover-intervals",
     category: "Admin",
@@ -960,6 +1321,88 @@ const endpoints: Endpoint[] = [
     description: "System performance metrics and database connection pool stats.",
     usage: "Monitor system health and performance. Protected.",
   },
+  {
+    id: "admin-servers-list",
+    category: "Admin",
+    method: "GET",
+    path: "/admin/servers",
+    description: "Configured media servers and their connection settings (no API keys).",
+    usage: "Review stored servers. Protected.",
+  },
+  {
+    id: "admin-servers-create",
+    category: "Admin",
+    method: "POST",
+    path: "/admin/servers",
+    description: "Add a media server and start monitoring it without a restart.",
+    usage: "Tested first unless ?skip_test=true. Protected.",
+    params: [
+      { key: "type", kind: "body", required: true, placeholder: "emby | jellyfin | plex" },
+      { key: "base_url", kind: "body", required: true, placeholder: "http://jellyfin:8096" },
+      { key: "api_key", kind: "body", required: true, placeholder: "api key / Plex token" },
+      { key: "name", kind: "body", required: false, placeholder: "Living room" },
+      { key: "id", kind: "body", required: false, placeholder: "jellyfin-living-room" },
+    ],
+  },
+  {
+    id: "admin-servers-update",
+    category: "Admin",
+    method: "PUT",
+    path: "/admin/servers/:id",
+    description: "Change a media server's settings; omitted fields are kept.",
+    usage: "Rotate an API key or rename a server. Protected.",
+    params: [
+      { key: "id", kind: "path", required: true, placeholder: "jellyfin-living-room" },
+      { key: "api_key", kind: "body", required: false, placeholder: "new api key" },
+      { key: "name", kind: "body", required: false, placeholder: "Living room" },
+    ],
+  },
+  {
+    id: "admin-servers-test",
+    category: "Admin",
+    method: "POST",
+    path: "/admin/servers/:id/test",
+    description: "Test a server's connection and API key permissions and diagnose failures.",
+    usage: "Try changed settings in the body before saving them. Protected.",
+    params: [
+      { key: "id", kind: "path", required: true, placeholder: "jellyfin-living-room" },
+      { key: "base_url", kind: "body", required: false, placeholder: "http://jellyfin:8096" },
+      { key: "api_key", kind: "body", required: false, placeholder: "api key / Plex token" },
+    ],
+  },
+  {
+    id: "admin-servers-delete",
+    category: "Admin",
+    method: "DELETE",
+    path: "/admin/servers/:id",
+    description: "Stop monitoring a media server and remove its stored configuration.",
+    usage: "History is kept. Protected.",
+    params: [{ key: "id", kind: "path", required: true, placeholder: "jellyfin-living-room" }],
+  },
+  {
+    id: "admin-notifications",
+    category: "Admin",
+    method: "GET",
+    path: "/admin/notifications",
+    description: "Notification channels and event kinds with their toggles.",
+    usage: "See which events are sent; switch them with notify_event_<kind> settings. Protected.",
+  },
+  {
+    id: "admin-notifications-test",
+    category: "Admin",
+    method: "POST",
+    path: "/admin/notifications/test?channel=discord",
+    description: "Send a test notification to one channel (discord, telegram, gotify, pushover).",
+    usage: "Check a channel's credentials. Protected.",
+  },
+  {
+    id: "admin-reports-test-email",
+    category: "Admin",
+    method: "POST",
+    path: "/admin/reports/test-email",
+    description: "Email last week's server report now (REPORT_EMAIL_TO, or ?to=).",
+    usage: "Check SMTP and the weekly report. Protected.",
+  },
 
   // Admin - Diagnostics (media metadata coverage)
   {
@@ -987,6 +1430,20 @@ const endpoints: Endpoint[] = [
       { key: "media_type", kind: "query", placeholder: "Movie|Episode" },
       { key: "limit", kind: "query", placeholder: "50" },
     ],
+  },  {
+    id: "admin-diag-watch-audit",
+    category: "Admin/Diagnostics",
+    method: "GET",
+    path: "/admin/diagnostics/watch-time-audit",
+    description: "Per-user watch time from intervals vs play events, session summaries and server play history.",
+    usage: "Check watch-time accuracy and spot sources that disagree. Protected.",
+    params: [
+      { key: "days", kind: "query", placeholder: "7" },
+      { key: "user_id", kind: "query", placeholder: "user id" },
+      { key: "server", kind: "query", placeholder: "server id" },
+      { key: "threshold_pct", kind: "query", placeholder: "10" },
+      { key: "remote", kind: "query", placeholder: "1|0" },
+    ],
   },
 ];
 
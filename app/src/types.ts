@@ -22,6 +22,27 @@ export type UserDetail = {
   last_seen_movies: UserTopItem[];
   last_seen_episodes: UserTopItem[];
   finished_series: UserTopItem[];
+  devices: UserDevice[];
+  ip_history?: UserAddress[]; // admins only
+};
+
+export type UserDevice = {
+  device_id: string;
+  device_name: string;
+  client: string;
+  client_version?: string;
+  platform?: string;
+  sessions: number;
+  first_seen: number;
+  last_seen: number;
+};
+
+export type UserAddress = {
+  address: string;
+  sessions: number;
+  devices: number;
+  first_seen: number;
+  last_seen: number;
 };
 
 export type UserTopItem = {
@@ -53,13 +74,15 @@ export type TopItem = {
   server_id?: string;
 };
 
-export type ItemRow = { id: string; name?: string; type?: string; display?: string };
+export type ItemRow = { id: string; name?: string; type?: string; display?: string; palette?: string[] };
 
 export type ServerSyncProgress = {
   server_id: string;
   server_name?: string;
   total: number;
   processed: number;
+  changed?: number;
+  unchanged?: number;
   stage?: string;
   running: boolean;
   done: boolean;
@@ -128,11 +151,23 @@ export type PlayMethodCounts = {
 };
 
 // Stats responses
+export type ServerOverview = {
+  server_id: string;
+  server_name: string;
+  server_type: string;
+  users: number;
+  items: number;
+  sessions: number;
+  watch_hours: number;
+};
+
 export type OverviewData = {
   total_users: number;
   total_items: number;
   total_plays: number;
   unique_plays: number;
+  watch_hours?: number;
+  servers?: ServerOverview[];
 };
 
 export type QualityBuckets = {
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,24 +19,33 @@ import (
 	admin "emby-analytics/internal/handlers/admin"
 	auth "emby-analytics/internal/handlers/auth"
 	configHandler "emby-analytics/internal/handlers/config"
+	export "emby-analytics/internal/handlers/export"
 	health "emby-analytics/internal/handlers/health"
 	images "emby-analytics/internal/handlers/images"
 	items "emby-analytics/internal/handlers/items"
 	now "emby-analytics/internal/handlers/now"
 	serversHandler "emby-analytics/internal/handlers/servers"
 	settings "emby-analytics/internal/handlers/settings"
+	setup "emby-analytics/internal/handlers/setup"
 	stats "emby-analytics/internal/handlers/stats"
 	verhandler "emby-analytics/internal/handlers/version"
+	views "emby-analytics/internal/handlers/views"
+	"emby-analytics/internal/hooks"
+	"emby-analytics/internal/hostmetrics"
+	"emby-analytics/internal/httpx"
+	"emby-analytics/internal/imagecache"
 	"emby-analytics/internal/logging"
 	"emby-analytics/internal/middleware"
 	"emby-analytics/internal/monitors"
 	"emby-analytics/internal/sync"
 	tasks "emby-analytics/internal/tasks"
+	"emby-analytics/internal/trakt"
 
 	// Multi-server clients
-	"emby-analytics/internal/jellyfin"
 	"emby-analytics/internal/media"
-	"emby-analytics/internal/plex"
+	"emby-analytics/internal/notify"
+	"emby-analytics/internal/queries"
+	"emby-analytics/internal/secrets"
 	"emby-analytics/internal/sessioncache"
 
 	"github.com/gofiber/fiber/v3"
@@ -66,6 +79,11 @@ func colorStatus(code int) string {
 
 func main() {
 	_ = godotenv.Load()
+	// Optional YAML config file; environment variables override its values
+	if err := config.ApplyConfigFile(os.Getenv("CONFIG_FILE")); err != nil {
+		fmt.Printf("[ERROR] Invalid config file: %v\n", err)
+		os.Exit(1)
+	}
 	cfg := config.Load()
 
 	// Initialize structured logging
@@ -102,6 +120,39 @@ func main() {
 	logger.Info("=====================================================")
 	logger.Info("        Starting Emby Analytics Application")
 	logger.Info("=====================================================")
+
+	// Shared retry/backoff and circuit breaker settings for all media server clients
+	httpx.SetDefaults(httpx.Options{
+		MaxRetries:       cfg.HTTPMaxRetries,
+		BaseBackoff:      time.Duration(cfg.HTTPRetryBackoffMs) * time.Millisecond,
+		BreakerThreshold: cfg.HTTPBreakerThreshold,
+		BreakerCooldown:  time.Duration(cfg.HTTPBreakerCooldownSec) * time.Second,
+	})
+	notify.Configure(cfg.NotifyWebhookURL)
+	notify.SetPublicURL(cfg.PublicURL)
+	if cfg.NotifyDiscordWebhookURL != "" {
+		notify.AddChannel(notify.DiscordChannel{WebhookURL: cfg.NotifyDiscordWebhookURL})
+	}
+	if cfg.NotifyTelegramBotToken != "" && cfg.NotifyTelegramChatID != "" {
+		notify.AddChannel(notify.TelegramChannel{BotToken: cfg.NotifyTelegramBotToken, ChatID: cfg.NotifyTelegramChatID})
+	}
+	if cfg.NotifyGotifyURL != "" && cfg.NotifyGotifyToken != "" {
+		notify.AddChannel(notify.GotifyChannel{URL: cfg.NotifyGotifyURL, Token: cfg.NotifyGotifyToken})
+	}
+	if cfg.NotifyPushoverToken != "" && cfg.NotifyPushoverUser != "" {
+		notify.AddChannel(notify.PushoverChannel{AppToken: cfg.NotifyPushoverToken, UserKey: cfg.NotifyPushoverUser})
+	}
+	tasks.SetSessionEndedNotifications(cfg.NotifySessionEnded)
+	if cfg.HookScript != "" {
+		hooks.RegisterScript(cfg.HookScript, cfg.HookEvents, time.Duration(cfg.HookTimeoutSec)*time.Second)
+	}
+	imagecache.Configure(int64(cfg.ImgCacheMaxMB)<<20, time.Duration(cfg.ImgCacheTTLHours)*time.Hour)
+	// Per-server TLS (custom CA / insecure) and proxy settings apply to every client talking to that host
+	for _, sc := range cfg.MediaServers {
+		if err := httpx.ConfigureHost(sc.BaseURL, sc.TransportOptions()); err != nil {
+			logger.Warn("Failed to apply connection options for media server", "server", sc.ID, "error", err)
+		}
+	}
 	em := emby.New(cfg.EmbyBaseURL, cfg.EmbyAPIKey)
 
 	// Build MultiServerManager (Plex/Jellyfin for now; Emby support via legacy paths)
@@ -111,15 +162,46 @@ func main() {
 
 	multiMgr := media.NewMultiServerManager(sessionCache)
 	for _, sc := range cfg.MediaServers {
-		switch sc.Type {
-		case media.ServerTypePlex:
-			multiMgr.AddServer(sc, plex.New(sc))
-		case media.ServerTypeJellyfin:
-			multiMgr.AddServer(sc, jellyfin.New(sc))
-		case media.ServerTypeEmby:
-			multiMgr.AddServer(sc, media.NewEmbyAdapter(sc))
+		if client := setup.NewClient(sc); client != nil {
+			multiMgr.AddServer(sc, client)
 		}
 	}
+	// Surface expired/revoked credentials (repeated 401/403) instead of silently returning empty
+	// stats. Hosts are resolved when the change happens, so servers added or re-pointed under
+	// /admin/servers are covered too.
+	httpx.OnAuthChange(func(host string, status httpx.AuthStatus) {
+		for _, sc := range multiMgr.GetServerConfigs() {
+			if u, err := url.Parse(sc.BaseURL); err != nil || u.Host != host {
+				continue
+			}
+			name := sc.Name
+			if name == "" {
+				name = sc.ID
+			}
+			if status.Broken {
+				logging.Module("credentials").Error("Media server is rejecting credentials; check the API key/token",
+					"server", sc.ID, "name", sc.Name, "status", status.LastStatus, "consecutive_failures", status.ConsecutiveFailures)
+				notify.Send(notify.Event{
+					Kind:    notify.KindServerAuthBroken,
+					Title:   "Credentials rejected: " + name,
+					Message: name + " is rejecting the API key; sync and live sessions stop until it is fixed",
+					Fields: map[string]string{
+						"server_id":            sc.ID,
+						"status":               strconv.Itoa(status.LastStatus),
+						"consecutive_failures": strconv.Itoa(status.ConsecutiveFailures),
+					},
+				})
+			} else {
+				logging.Module("credentials").Info("Media server credentials working again", "server", sc.ID, "name", sc.Name)
+				notify.Send(notify.Event{
+					Kind:    notify.KindServerAuthRestored,
+					Title:   "Credentials working again: " + name,
+					Message: name + " accepts the API key again",
+					Fields:  map[string]string{"server_id": sc.ID},
+				})
+			}
+		}
+	})
 
 	// ---- Database Initialization & Migration ----
 	absPath, err := filepath.Abs(cfg.SQLitePath)
@@ -190,26 +272,107 @@ func main() {
 	}
 	defer func(dbh *sql.DB) { _ = dbh.Close() }(sqlDB)
 	logger.Info("Database connection established")
+	// Poster palettes extracted while caching images are persisted per item
+	imagecache.Default().SetPaletteStore(images.PaletteStore(sqlDB))
 
-	// Ensure legacy Emby rows carry file paths required for multi-server stats.
-	embyServerID, embyServerType := tasks.ResolveEmbyServer(cfg, multiMgr)
-	tasks.BackfillLegacyFilePaths(sqlDB, em, embyServerID, embyServerType)
+	// Watch-for and transcode alert rules declared in the config file
+	if err := admin.ApplyFileRules(sqlDB, cfg.NotificationRules); err != nil {
+		logger.Error("Invalid notification rules", "error", err)
+		os.Exit(1)
+	}
 
-	// Initial user sync AFTER schema is ready.
-	logger.Info("Starting initial user and lifetime stats sync")
-	tasks.RunUserSyncOnce(sqlDB, multiMgr)
-	logger.Info("Initial user sync completed")
+	// Stored media servers are the source of truth, managed under /admin/servers; servers from
+	// the environment or config file only seed them the first time their id is seen
+	if key, err := secrets.LoadKey(cfg.SecretKey, filepath.Join(filepath.Dir(cfg.SQLitePath), "secret.key")); err != nil {
+		logger.Error("Failed to load the secret key; media server API keys will be stored in plain text", "error", err)
+	} else if err := secrets.Configure(key); err != nil {
+		logger.Error("Failed to configure the secret key; media server API keys will be stored in plain text", "error", err)
+	}
+	if n, err := queries.SealStoredMediaServerKeys(context.Background(), sqlDB); errors.Is(err, secrets.ErrNoKey) {
+		logger.Error("Stored media server API keys are NOT sealed; set SECRET_KEY or fix the secret.key file next to the database", "count", n)
+	} else if err != nil {
+		logger.Warn("Failed to seal stored media server API keys", "error", err)
+	} else if n > 0 {
+		logger.Info("Sealed stored media server API keys", "count", n)
+	}
+	if seeded, err := queries.SeedStoredMediaServers(context.Background(), sqlDB, cfg.MediaServers, time.Now().Unix()); err != nil {
+		logger.Warn("Failed to store media servers from the environment", "error", err)
+	} else if len(seeded) > 0 {
+		logger.Info("Stored media servers from the environment", "servers", seeded)
+	}
+	deletedServers, err := queries.DeletedMediaServerIDs(context.Background(), sqlDB)
+	if err != nil {
+		logger.Warn("Failed to load deleted media servers", "error", err)
+	}
+	if stored, err := queries.ListStoredMediaServers(context.Background(), sqlDB); err != nil {
+		logger.Warn("Failed to load stored media servers; using the environment's", "error", err)
+	} else {
+		envServers := make(map[string]media.ServerConfig, len(cfg.MediaServers))
+		for _, sc := range cfg.MediaServers {
+			envServers[sc.ID] = sc
+			if deletedServers[sc.ID] {
+				multiMgr.RemoveServer(sc.ID)
+				logger.Info("Media server was deleted under /admin/servers; ignoring its environment settings", "server", sc.ID)
+			}
+		}
+		for _, sc := range stored {
+			if env, ok := envServers[sc.ID]; ok && env != sc {
+				logger.Warn("Media server settings differ from the environment; the stored ones apply (change them under /admin/servers)", "server", sc.ID)
+			}
+			if err := httpx.ConfigureHost(sc.BaseURL, sc.TransportOptions()); err != nil {
+				logger.Warn("Failed to apply connection options for media server", "server", sc.ID, "error", err)
+			}
+			if client := setup.NewClient(sc); client != nil {
+				multiMgr.AddServer(sc, client)
+			}
+		}
+		cfg.MediaServers = stored
+	}
 
-	// Kick off background sync loops for playback history and user metadata across servers
-	tasks.StartSyncLoop(sqlDB, multiMgr, cfg)
-	tasks.StartUserSyncLoop(sqlDB, multiMgr, cfg)
-	tasks.StartSnapshotLoop(sqlDB)
+	// Initial syncs run in the background so the API and health checks answer right after
+	// startup; /health reports "warming_up" until they finish. The sync loops start once the
+	// initial user sync is done, as before.
+	embyServerID, embyServerType := tasks.ResolveEmbyServer(cfg, multiMgr)
+	tasks.StartWarmup([]tasks.WarmupJob{
+		// Ensure legacy Emby rows carry file paths required for multi-server stats.
+		{Stage: "Backfilling legacy file paths", Run: func() {
+			tasks.BackfillLegacyFilePaths(sqlDB, em, embyServerID, embyServerType)
+		}},
+		// Derive platforms of sessions recorded before they were captured.
+		{Stage: "Backfilling session platforms", Run: func() { tasks.BackfillSessionPlatforms(sqlDB) }},
+		{Stage: "Syncing users and lifetime stats", Run: func() { tasks.RunUserSyncOnce(sqlDB, multiMgr) }},
+		// Kick off background sync loops for playback history and user metadata across servers
+		{Stage: "Starting sync loops", Run: func() {
+			tasks.StartSyncLoop(sqlDB, multiMgr, cfg)
+			tasks.StartUserSyncLoop(sqlDB, multiMgr, cfg)
+			tasks.StartSnapshotLoop(sqlDB)
+			tasks.StartDownloadTrackingLoop(sqlDB, multiMgr, cfg)
+			tasks.StartDVRSyncLoop(sqlDB, multiMgr, cfg)
+			tasks.StartRollupLoop(sqlDB, cfg)
+			tasks.StartIntervalCompactionLoop(sqlDB, cfg)
+			tasks.StartHistoryRetentionLoop(sqlDB, cfg)
+			tasks.StartServerVersionLoop(sqlDB, multiMgr)
+			tasks.StartRequestSyncLoop(sqlDB, cfg)
+			if weekday, ok := tasks.ParseMaintenanceWeekday(cfg.DBMaintenanceWeekday); ok {
+				hour, minute := tasks.ParseMaintenanceTime(cfg.DBMaintenanceTime)
+				tasks.StartDBMaintenanceSchedule(sqlDB, cfg.SQLitePath, weekday, hour, minute)
+			}
+		}},
+		// One-off cleanup of orphaned server items on startup
+		{Stage: "Cleaning up orphaned server items", Run: func() { tasks.CleanupOrphanedServerItems(sqlDB, multiMgr) }},
+	})
 
-	// One-off cleanup of orphaned server items on startup
-	tasks.CleanupOrphanedServerItems(sqlDB, multiMgr)
+	// Scrobble linked users' plays to Trakt; switched on and off with trakt_scrobble_enabled
+	var traktClient *trakt.Client
+	if cfg.TraktClientID != "" && cfg.TraktClientSecret != "" {
+		traktClient = trakt.New(cfg.TraktClientID, cfg.TraktClientSecret)
+		trakt.StartScrobbler(sqlDB, traktClient, multiMgr)
+	}
 
 	// ---- Session Processing (Hybrid State-Polling Approach) ----
 	sessionProcessor := tasks.NewSessionProcessor(sqlDB, multiMgr)
+	// Emby playback events are attributed to the legacy Emby server, so they merge with its polled sessions
+	sessionProcessor.Intervalizer.ServerID = embyServerID
 	logger.Info("Session processor initialized")
 
 	pollInterval := time.Duration(cfg.NowPollSec) * time.Second
@@ -218,17 +381,42 @@ func main() {
 	}
 	broadcaster := now.NewBroadcaster(em, pollInterval)
 	broadcaster.SessionProcessor = sessionProcessor.ProcessActiveSessions
+	pollCadence := tasks.NewPollCadence(cfg.PollMode, pollInterval,
+		time.Duration(cfg.PollSlowSec)*time.Second, time.Duration(cfg.WebhookStaleMinutes)*time.Minute)
+	tasks.SetPollCadence(pollCadence)
+	if pollCadence.Mode == tasks.PollModeWebhookPrimary {
+		pollCadence.Servers = func() []string {
+			var ids []string
+			for id := range multiMgr.GetEnabledClients() {
+				ids = append(ids, id)
+			}
+			return ids
+		}
+		broadcaster.Cadence = pollCadence
+	}
 	now.SetBroadcaster(broadcaster)
 	now.SetMultiServerManager(multiMgr)
+	now.SetDB(sqlDB)
 	serversHandler.SetManager(multiMgr)
 	broadcaster.Start()
-	logger.Info("REST API session polling started", "interval", pollInterval)
+	logger.Info("REST API session polling started", "interval", pollInterval, "mode", pollCadence.Mode)
 	defer broadcaster.Stop()
 
+	// Jellyfin playback events feed the Intervalizer alongside polling
+	if cfg.JellyfinWebSocket {
+		jellyfinIngests := tasks.NewJellyfinEventIngests(sqlDB)
+		defer jellyfinIngests.StopAll()
+		for _, sc := range multiMgr.GetServerConfigs() {
+			jellyfinIngests.Sync(sc)
+		}
+		tasks.SetJellyfinEventIngests(jellyfinIngests)
+	}
+
 	// ---- Fiber App and Routes ----
 	app := fiber.New(fiber.Config{
 		EnableIPValidation: true,
 		ProxyHeader:        fiber.HeaderXForwardedFor,
+		BodyLimit:          max(cfg.MaxBodyMB, 1) << 20,
 	})
 	app.Use(recover.New())
 
@@ -239,7 +427,7 @@ func main() {
 			c.Set("Access-Control-Allow-Origin", origin)
 			c.Set("Vary", "Origin")
 			c.Set("Access-Control-Allow-Credentials", "true")
-			c.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Admin-Token")
+			c.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Admin-Token, X-API-Key")
 			c.Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
 			if c.Method() == fiber.MethodOptions {
 				return c.SendStatus(fiber.StatusNoContent)
@@ -253,6 +441,7 @@ func main() {
 
 	// Attach session user to context
 	app.Use(middleware.AttachUser(sqlDB, cfg))
+	app.Use(middleware.AttachViewer(cfg.AdminToken))
 
 	// Health Routes
 	// Optional: auto-auth cookie for UI
@@ -274,10 +463,23 @@ func main() {
 	app.Get("/health/frontend", health.FrontendHealth(sqlDB))
 	// Version Route
 	app.Get("/version", verhandler.GetVersion())
+	// Saved views: stored filter sets applied to any stats route via ?view=<id>
+	app.Get("/api/views", views.ListViews(sqlDB))
+	app.Post("/api/views", views.CreateView(sqlDB))
+	app.Get("/api/views/:id", views.GetView(sqlDB))
+	app.Put("/api/views/:id", views.UpdateView(sqlDB))
+	app.Delete("/api/views/:id", views.DeleteView(sqlDB))
+	app.Use("/stats", views.ApplyView(sqlDB))
+	// Default timeframe and server scope for stats requests that don't pass them
+	app.Use("/stats", stats.ApplyStatsDefaults(sqlDB))
+	app.Use("/stats", middleware.ServerWarnings(multiMgr))
 	// Stats API Routes
 	app.Get("/stats/overview", stats.Overview(sqlDB))
+	app.Get("/stats/dashboard", stats.DashboardHandler(sqlDB, multiMgr, em))
 	app.Get("/stats/usage", stats.Usage(sqlDB, multiMgr))
+	app.Get("/stats/usage/version-markers", stats.UsageVersionMarkers(sqlDB))
 	app.Get("/stats/top/users", stats.TopUsers(sqlDB, multiMgr))
+	app.Get("/stats/leaderboards", stats.Leaderboards(sqlDB, multiMgr))
 
 	app.Get("/stats/top/items", stats.TopItems(sqlDB, em))
 	// Inject manager so TopItems can enrich non-Emby items
@@ -288,8 +490,37 @@ func main() {
 	app.Get("/stats/users/total", stats.UsersTotal(sqlDB))
 	app.Get("/stats/users/:id", stats.UserDetailHandler(sqlDB, em))
 	app.Get("/stats/users/:id/watch-time", stats.UserWatchTimeHandler(sqlDB))
+	app.Get("/stats/users/:id/quota", stats.UserQuotaHandler(sqlDB))
+	app.Get("/stats/me/goals", stats.MyGoalsHandler(sqlDB))
+	app.Post("/stats/me/goals", stats.CreateGoalHandler(sqlDB))
+	app.Put("/stats/me/goals/:id", stats.UpdateGoalHandler(sqlDB))
+	app.Delete("/stats/me/goals/:id", stats.DeleteGoalHandler(sqlDB))
+	app.Get("/stats/me/privacy", stats.MyPrivacyHandler(sqlDB))
+	app.Put("/stats/me/privacy", stats.UpdateMyPrivacyHandler(sqlDB))
+	smtpCfg := notify.SMTPConfig{Host: cfg.SMTPHost, Port: cfg.SMTPPort, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword, From: cfg.SMTPFrom}
+	app.Get("/api/me/subscriptions", stats.MySubscriptionsHandler(sqlDB, smtpCfg.Enabled()))
+	reportH, reportM := tasks.ParseSummaryTime(cfg.ReportTime)
+	weeklyReporter := tasks.NewWeeklyReporter(sqlDB, smtpCfg, cfg.ReportEmailTo, reportH, reportM, tasks.LoadSummaryLocation(cfg.ReportTZ))
+	app.Put("/api/me/subscriptions", stats.UpdateMySubscriptionHandler(sqlDB))
+	app.Get("/api/me/subscriptions/preview", stats.PreviewMyDigestHandler(sqlDB))
+	app.Delete("/api/me/subscriptions/:kind", stats.DeleteMySubscriptionHandler(sqlDB))
+	app.Get("/api/me/stats-defaults", stats.MyStatsDefaultsHandler(sqlDB))
+	app.Put("/api/me/stats-defaults", stats.UpdateMyStatsDefaultsHandler(sqlDB))
+	app.Get("/stats/users/:id/continue-watching", stats.ContinueWatchingHandler(sqlDB, multiMgr))
+	app.Get("/stats/resume-backlog", stats.ResumeBacklogHandler(sqlDB, multiMgr))
+	app.Get("/stats/devices/:deviceId/history", stats.DeviceHistoryHandler(sqlDB))
+	app.Get("/stats/platforms", stats.Platforms(sqlDB))
+	app.Get("/stats/ratings", stats.Ratings(sqlDB, cfg.ChildUsers))
+	app.Get("/stats/ratings/restricted", stats.RestrictedRatings(sqlDB, cfg.ChildUsers, cfg.ChildMaxRating))
+	app.Get("/stats/sessions/history", stats.SessionHistory(sqlDB))
+	app.Get("/stats/sessions/:id/events", stats.SessionEventsHandler(sqlDB))
+	app.Get("/stats/requests/funnel", stats.RequestFunnelHandler(sqlDB, cfg.OverseerrURL != "" && cfg.OverseerrAPIKey != ""))
+	app.Get("/stats/activity-feed", stats.ActivityFeedHandler(sqlDB))
+	app.Get("/stats/content-age", stats.ContentAge(sqlDB))
 	app.Get("/stats/users/watch-time", stats.AllUsersWatchTimeHandler(sqlDB))
 	app.Get("/stats/play-methods", stats.PlayMethods(sqlDB, em))
+	app.Get("/stats/recommendations/clients", stats.ClientRecommendationsHandler(sqlDB))
+	app.Get("/stats/clients/capabilities", stats.ClientCapabilitiesHandler(sqlDB))
 	app.Get("/stats/items/by-codec/:codec", stats.ItemsByCodec(sqlDB))
 	app.Get("/stats/items/by-genre/:genre", stats.ItemsByGenre(sqlDB))
 	app.Get("/stats/series/by-genre/:genre", stats.SeriesByGenre(sqlDB))
@@ -297,12 +528,31 @@ func main() {
 	app.Get("/stats/movies", stats.Movies(sqlDB))
 	app.Get("/stats/series", stats.Series(sqlDB))
 	app.Get("/stats/top/series", stats.TopSeries(sqlDB))
+	app.Get("/stats/downloads", stats.Downloads(sqlDB))
+	app.Get("/stats/errors", stats.Errors(sqlDB))
+	app.Get("/stats/dvr", stats.DVR(sqlDB))
+	app.Get("/stats/dvr/weekly", stats.DVRRecordingsPerWeek(sqlDB))
+	app.Get("/stats/dvr/top-shows", stats.DVRTopShows(sqlDB))
+	app.Get("/stats/trending", stats.Trending(sqlDB))
+	app.Get("/stats/tags/top", stats.TopTags(sqlDB))
 
 	// Storage Analytics Routes
 	app.Get("/stats/storage/stale-content", stats.StaleContent(sqlDB))
 	app.Get("/stats/storage/roi", stats.ROIAnalysis(sqlDB))
 	app.Get("/stats/storage/duplicates", stats.Duplicates(sqlDB))
 	app.Get("/stats/storage/predictions", stats.StoragePredictions(sqlDB))
+	app.Get("/stats/library/downgrade-candidates", stats.DowngradeCandidates(sqlDB))
+	app.Get("/stats/library/storage-timeline", stats.StorageTimelineHandler(sqlDB))
+	app.Get("/stats/library/health", stats.LibraryHealthHandler(sqlDB))
+	app.Get("/stats/library/compare", stats.LibraryCompareHandler(sqlDB))
+	app.Get("/stats/idle-windows", stats.IdleWindowsHandler(sqlDB))
+	app.Get("/stats/bandwidth", stats.BandwidthHandler(sqlDB))
+	app.Get("/stats/heatmap", stats.HeatmapHandler(sqlDB))
+	app.Get("/stats/track-overrides", stats.TrackOverridesHandler(sqlDB))
+	app.Get("/reports/costs", stats.CostsReportHandler(sqlDB))
+	// Stable public ids for deep links in notifications and exports
+	app.Get("/api/public-ids", stats.PublicIDHandler(sqlDB))
+	app.Get("/resolve/:publicId", stats.ResolvePublicIDHandler(sqlDB))
 
 	// Backward compatibility routes (hyphenated versions)
 	app.Get("/stats/top-users", stats.TopUsers(sqlDB, multiMgr))
@@ -315,18 +565,29 @@ func main() {
 	// Item & Image Routes
 	// Multi-server-aware items lookup (falls back to legacy where needed)
 	app.Get("/items/by-ids", items.ByIDsMS(sqlDB, multiMgr))
+	app.Get("/items/availability", items.Availability(multiMgr))
 	imgOpts := images.NewOpts(cfg)
-	app.Get("/img/primary/:id", images.Primary(imgOpts))
-	app.Get("/img/backdrop/:id", images.Backdrop(imgOpts))
+	// Image routes answer HEAD as well so caches can revalidate cheaply
+	imgMethods := []string{fiber.MethodGet, fiber.MethodHead}
+	app.Add(imgMethods, "/img/primary/:id", images.Primary(imgOpts))
+	app.Add(imgMethods, "/img/backdrop/:id", images.Backdrop(imgOpts))
 	// Multi-server image routes
-	app.Get("/img/primary/:server/:id", images.MultiServerPrimary(multiMgr))
-	app.Get("/img/backdrop/:server/:id", images.MultiServerBackdrop(multiMgr))
+	app.Add(imgMethods, "/img/primary/:server/:id", images.MultiServerPrimary(multiMgr))
+	app.Add(imgMethods, "/img/backdrop/:server/:id", images.MultiServerBackdrop(multiMgr))
 	// Now Playing Routes
 	app.Get("/api/now-playing/summary", now.Summary)
 	// Legacy single-Emby snapshot remains for compatibility with current UI
 	app.Get("/now/snapshot", now.Snapshot)
 	// New multi-server snapshot for updated UI/clients
 	app.Get("/api/now/snapshot", now.MultiSnapshot)
+	// Transcoding sessions only, for homepage widgets (optionally behind WIDGET_API_KEY)
+	app.Get("/api/now/transcoding", middleware.WidgetAuth(cfg.WidgetAPIKey, cfg.AdminToken), now.Transcoding)
+	// Grafana SimpleJSON/Infinity datasource over daily history (same optional WIDGET_API_KEY)
+	grafanaAuth := middleware.WidgetAuth(cfg.WidgetAPIKey, cfg.AdminToken)
+	app.Get("/grafana", grafanaAuth, stats.GrafanaTestHandler())
+	app.Post("/grafana/search", grafanaAuth, stats.GrafanaSearchHandler())
+	app.Post("/grafana/query", grafanaAuth, stats.GrafanaQueryHandler(sqlDB))
+	app.Get("/grafana/query", grafanaAuth, stats.GrafanaSeriesHandler(sqlDB))
 	// Multi-server WebSocket stream (optional ?server=emby|plex|jellyfin|all)
 	app.Get("/api/now/ws", func(c fiber.Ctx) error {
 		if ws.IsWebSocketUpgrade(c) {
@@ -344,7 +605,7 @@ func main() {
 	app.Post("/now/:id/stop", now.StopSession)
 	app.Post("/now/:id/message", now.MessageSession)
 	// Server list/health
-	app.Get("/api/servers", serversHandler.List())
+	app.Get("/api/servers", serversHandler.List(sqlDB))
 
 	// Server-aware now controls
 	app.Post("/api/now/sessions/:server/:id/pause", now.MultiPauseSession)
@@ -356,6 +617,13 @@ func main() {
 
 	// Protected admin endpoints (admin session OR ADMIN_TOKEN)
 	adminAuth := middleware.AdminAccess(sqlDB, cfg.AdminToken, cfg)
+	// Maintenance mode: refuse mutating admin calls, but keep webhook ingest flowing
+	// and allow DB maintenance and pausing schedulers, which is what maintenance windows are for
+	app.Use("/admin", middleware.MaintenanceGuard(sqlDB, "/admin/webhook/", "/admin/db/maintenance", "/admin/schedulers/"))
+	// Session notes/tags are stored on play_sessions, so only admins may set them
+	app.Post("/api/now/sessions/:server/:id/note", adminAuth, now.MultiSessionNote(sqlDB))
+	// Personal data export: admins, or the app user linked to this media user
+	app.Get("/export/users/:id/data.zip", middleware.AdminOrLinkedUser(sqlDB, cfg.AdminToken, cfg, "id"), export.UserDataPackage(sqlDB))
 
 	// Settings Routes (admin-protected for updates)
 	app.Get("/api/settings", settings.GetSettings(sqlDB))
@@ -363,18 +631,64 @@ func main() {
 
 	app.Post("/admin/refresh/start", adminAuth, admin.StartPostHandler(rm, sqlDB, em, cfg.RefreshChunkSize))
 	app.Post("/admin/refresh/incremental", adminAuth, admin.StartIncrementalHandler(rm, sqlDB, em))
+	app.Post("/admin/library-scan", adminAuth, admin.TriggerLibraryScan(rm, sqlDB, em, multiMgr))
 	app.Post("/admin/enrich/missing-items", adminAuth, admin.EnrichMissingItems(sqlDB, multiMgr))
 	app.Get("/admin/refresh/status", adminAuth, admin.StatusHandler(rm))
 	app.Get("/admin/webhook/stats", adminAuth, admin.GetWebhookStats())
 	app.Post("/admin/reset-all", adminAuth, admin.ResetAllData(sqlDB, multiMgr))
 	app.Post("/admin/reset-lifetime", adminAuth, admin.ResetLifetimeWatch(sqlDB))
 	app.Post("/admin/users/force-sync", adminAuth, admin.ForceUserSync(sqlDB, multiMgr))
+	app.Post("/admin/users/bulk", adminAuth, admin.BulkMediaUsers(sqlDB))
+	app.Post("/admin/sql", adminAuth, admin.SQLConsole(sqlDB))
+	app.Post("/admin/db/maintenance", adminAuth, admin.StartDBMaintenance(sqlDB, cfg.SQLitePath))
+	app.Get("/admin/db/maintenance/status", adminAuth, admin.DBMaintenanceStatus(cfg.SQLitePath))
+	app.Get("/admin/retention/preview", adminAuth, admin.RetentionPreview(sqlDB, cfg))
+	app.Get("/admin/trakt", adminAuth, admin.TraktStatus(sqlDB, traktClient))
+	app.Post("/admin/trakt/users/:id/link", adminAuth, admin.TraktLinkUser(sqlDB, traktClient))
+	app.Delete("/admin/trakt/users/:id", adminAuth, admin.TraktUnlinkUser(sqlDB, traktClient))
+	app.Get("/admin/quotas", adminAuth, admin.ListUserQuotas(sqlDB))
+	app.Put("/admin/users/:id/quota", adminAuth, admin.SetUserQuota(sqlDB))
+	app.Delete("/admin/users/:id/quota", adminAuth, admin.DeleteUserQuota(sqlDB))
+	// Device names and merges, applied to device stats and session history
+	app.Get("/admin/devices", adminAuth, admin.ListDevices(sqlDB))
+	app.Post("/admin/devices/merge", adminAuth, admin.MergeDevices(sqlDB))
+	app.Put("/admin/devices/:id", adminAuth, admin.RenameDevice(sqlDB))
+	app.Delete("/admin/devices/:id/alias", adminAuth, admin.UnmergeDevice(sqlDB))
+	app.Get("/admin/user-mappings", adminAuth, admin.ListUserMappings(sqlDB))
+	app.Post("/admin/user-mappings", adminAuth, admin.MapUsers(sqlDB))
+	app.Delete("/admin/user-mappings/users/:id", adminAuth, admin.UnmapUser(sqlDB))
+	app.Delete("/admin/user-mappings/:id", adminAuth, admin.DeletePerson(sqlDB))
+	app.Get("/admin/watch-for", adminAuth, admin.ListWatchFor(sqlDB))
+	app.Post("/admin/watch-for", adminAuth, admin.CreateWatchFor(sqlDB))
+	app.Get("/admin/watch-for/hits", adminAuth, admin.ListWatchForHits(sqlDB))
+	app.Put("/admin/watch-for/:id", adminAuth, admin.UpdateWatchFor(sqlDB))
+	app.Delete("/admin/watch-for/:id", adminAuth, admin.DeleteWatchFor(sqlDB))
+	app.Get("/admin/transcode-alerts", adminAuth, admin.ListTranscodeAlerts(sqlDB))
+	app.Post("/admin/transcode-alerts", adminAuth, admin.CreateTranscodeAlert(sqlDB))
+	app.Get("/admin/transcode-alerts/events", adminAuth, admin.ListTranscodeAlertEvents(sqlDB))
+	app.Put("/admin/transcode-alerts/:id", adminAuth, admin.UpdateTranscodeAlert(sqlDB))
+	app.Delete("/admin/transcode-alerts/:id", adminAuth, admin.DeleteTranscodeAlert(sqlDB))
+	app.Get("/admin/notifications", adminAuth, admin.NotificationStatus(sqlDB))
+	app.Post("/admin/notifications/test", adminAuth, admin.NotificationTest())
+	app.Post("/admin/reports/test-email", adminAuth, admin.ReportTestEmail(weeklyReporter, smtpCfg.Enabled()))
 	app.All("/admin/fix-pos-units", adminAuth, admin.FixPosUnits(sqlDB))
 	app.Post("/admin/sync/all", adminAuth, admin.SyncAllServers(sqlDB, multiMgr, cfg))
 	app.Post("/admin/sync/server/:id", adminAuth, admin.SyncServer(sqlDB, multiMgr, cfg))
+	app.Get("/admin/servers", adminAuth, admin.ListServers(multiMgr))
+	app.Post("/admin/servers", adminAuth, admin.CreateServer(sqlDB, multiMgr))
+	app.Put("/admin/servers/:id", adminAuth, admin.UpdateServer(sqlDB, multiMgr))
+	app.Delete("/admin/servers/:id", adminAuth, admin.DeleteServer(sqlDB, multiMgr))
+	app.Post("/admin/servers/:id/test", adminAuth, admin.TestServerConnection(sqlDB, multiMgr))
 	app.Delete("/admin/server/:id/media", adminAuth, admin.DeleteServerMedia(sqlDB, multiMgr))
+	app.Post("/admin/server/:id/trending-collection", adminAuth, admin.ExportTrendingCollection(sqlDB, multiMgr))
 	app.Get("/admin/debug/users", adminAuth, admin.DebugUsers(em))
 	app.Post("/admin/recover-intervals", adminAuth, admin.RecoverIntervalsHandler(sqlDB))
+	// Historical import: approximate sessions from the Emby activity log before our first tracked session
+	app.Post("/admin/import/activity-log", adminAuth, admin.ImportActivityLog(sqlDB, em, embyServerID, embyServerType))
+	// Historical import: sessions from a Tautulli database for a Plex server
+	app.Post("/admin/import/tautulli", adminAuth, admin.ImportTautulli(sqlDB, multiMgr))
+	// Historical import: sessions from the Playback Reporting plugin for an Emby or Jellyfin server
+	app.Post("/admin/import/playback-reporting", adminAuth, admin.ImportPlaybackReporting(sqlDB, multiMgr, embyServerID, embyServerType))
 	// Backfill series linkage for episodes
 	app.Get("/admin/backfill/series", adminAuth, admin.BackfillSeries(sqlDB, em, multiMgr))
 	app.Get("/admin/library/runtime-outliers", adminAuth, stats.RuntimeOutliers(sqlDB))
@@ -394,6 +708,8 @@ func main() {
 	// Remap stale item_id to a valid destination id
 	app.Get("/admin/remap-item", adminAuth, admin.RemapItem(sqlDB, em))
 	app.Post("/admin/remap-item", adminAuth, admin.RemapItem(sqlDB, em))
+	app.Get("/admin/remap-user", adminAuth, admin.RemapUser(sqlDB))
+	app.Post("/admin/remap-user", adminAuth, admin.RemapUser(sqlDB))
 	app.Get("/admin/debug/item-intervals/:id", adminAuth, admin.DebugItemIntervals(sqlDB))
 
 	// Debug: inspect recent play_sessions
@@ -443,6 +759,8 @@ func main() {
 
 	// Admin: backfill started_at from events/intervals
 	app.Post("/admin/cleanup/backfill-started-at", adminAuth, admin.BackfillStartedAt(sqlDB))
+	// Admin: extract poster color palettes for items cached before palettes were stored
+	app.Post("/admin/images/palettes/backfill", adminAuth, admin.BackfillPalettes(sqlDB, multiMgr))
 
 	// Debug: expose current active sessions from Emby
 	app.Get("/admin/debug/emby-sessions", adminAuth, admin.DebugEmbySessions(em))
@@ -457,25 +775,39 @@ func main() {
 	// Admin diagnostics for media metadata coverage
 	app.Get("/admin/diagnostics/media-field-coverage", adminAuth, admin.MediaFieldCoverage(sqlDB))
 	app.Get("/admin/diagnostics/items/missing", adminAuth, admin.MissingItems(sqlDB))
+	app.Get("/admin/diagnostics/sync-coverage", adminAuth, admin.SyncCoverage(sqlDB, multiMgr))
+	app.Get("/admin/diagnostics/watch-time-audit", adminAuth, admin.WatchTimeAudit(sqlDB, multiMgr))
 
 	// Webhook endpoint with separate authentication
 	webhookAuth := middleware.WebhookAuth(cfg.WebhookSecret)
-	app.Post("/admin/webhook/emby", webhookAuth, admin.WebhookHandler(rm, sqlDB, em))
+	app.Post("/admin/webhook/emby", webhookAuth, admin.WebhookHandler(rm, sqlDB, em, multiMgr, embyServerID))
 
 	// Auth endpoints
+	auth.SetMultiServerManager(multiMgr) // media server logins (AUTH_MEDIA_LOGIN)
 	app.Post("/auth/login", auth.LoginHandler(sqlDB, cfg))
 	app.Post("/auth/logout", auth.LogoutHandler(sqlDB, cfg))
 	app.Post("/auth/register", auth.RegisterHandler(sqlDB, cfg))
 	app.Get("/auth/me", auth.MeHandler(sqlDB, cfg))
 	app.Get("/auth/config", auth.ConfigHandler(sqlDB, cfg))
 
+	// First-run setup wizard: the admin step is open while no app user exists, later steps
+	// need that admin (or ADMIN_TOKEN), and everything but the status locks once completed
+	setup.SetManager(multiMgr)
+	setupOpen := setup.RequireOpen(sqlDB)
+	app.Get("/api/setup/status", setup.StatusHandler(sqlDB, cfg))
+	app.Post("/api/setup/admin", setupOpen, auth.SetupAdminHandler(sqlDB, cfg))
+	app.Post("/api/setup/servers/test", setupOpen, adminAuth, setup.TestServerHandler())
+	app.Post("/api/setup/servers", setupOpen, adminAuth, setup.AddServerHandler(sqlDB))
+	app.Put("/api/setup/defaults", setupOpen, adminAuth, setup.SaveDefaultsHandler(sqlDB, cfg))
+	app.Post("/api/setup/complete", setupOpen, adminAuth, setup.CompleteHandler(sqlDB, cfg))
+
 	// Static UI Serving
 	if cfg.AuthEnabled {
 		app.Use(middleware.RequireUserForUI(cfg))
 	}
 	app.Use("/", static.New(cfg.WebPath))
 	app.Use(func(c fiber.Ctx) error {
-		if c.Method() == fiber.MethodGet && !startsWithAny(c.Path(), "/stats", "/health", "/admin", "/now", "/config", "/api", "/items", "/img") {
+		if c.Method() == fiber.MethodGet && !startsWithAny(c.Path(), "/stats", "/health", "/admin", "/now", "/config", "/api", "/items", "/img", "/export", "/resolve") {
 			// If a static exported page exists at /path/index.html, serve it (supports clean URLs without trailing slash)
 			reqPath := c.Path()
 			// Normalize leading slash
@@ -496,6 +828,8 @@ func main() {
 	// Start sync scheduler
 	logger.Info("Starting smart sync scheduler")
 	scheduler := sync.NewScheduler(sqlDB, em, rm)
+	// Weekly server report emails (REPORT_EMAIL_TO) go out from the scheduler's minute tick
+	scheduler.AddJob("weekly_report", weeklyReporter.CheckDue)
 	scheduler.Start()
 
 	// Start cleanup scheduler
@@ -509,6 +843,86 @@ func main() {
 	transcodingMonitor.Start()
 	defer transcodingMonitor.Stop()
 
+	// Start per-user viewing quota monitor
+	quotaMonitor := monitors.NewQuotaMonitor(sqlDB, multiMgr, time.Minute)
+	quotaMonitor.Start()
+	defer quotaMonitor.Stop()
+
+	// Start paused session auto-stop (no-op unless IDLE_STOP_MINUTES or a server override is set)
+	idleMonitor := monitors.NewIdleSessionMonitor(sqlDB, multiMgr, monitors.IdlePolicy{
+		Minutes:         cfg.IdleStopMinutes,
+		WarnMinutes:     cfg.IdleStopWarnMinutes,
+		ServerMinutes:   cfg.IdleStopServerMinutes,
+		ClientWhitelist: cfg.IdleStopClientWhitelist,
+	}, 30*time.Second)
+	idleMonitor.Start()
+	defer idleMonitor.Stop()
+
+	// Per-event notification toggles (notify_event_<kind> settings) and the events sent from
+	// session starts and server checks
+	notify.SetEventFilter(func(kind string) bool {
+		return settings.GetSettingBool(sqlDB, settings.NotifyEventKey(kind), notify.DefaultEnabled(kind))
+	})
+	monitors.StartSessionNotifications(sqlDB)
+	serverMonitor := monitors.NewServerMonitor(multiMgr, time.Minute)
+	serverMonitor.Start()
+	defer serverMonitor.Stop()
+
+	// Start personal watch goal nudges
+	goalMonitor := monitors.NewGoalMonitor(sqlDB, multiMgr, 15*time.Minute)
+	goalMonitor.Start()
+	defer goalMonitor.Stop()
+
+	// Start watch-for monitor (alerts when listed items/series/titles start playing)
+	watchForMonitor := monitors.NewWatchForMonitor(sqlDB, multiMgr, 15*time.Second)
+	watchForMonitor.Start()
+	defer watchForMonitor.Stop()
+
+	// Start transcode reason spike alerts (immediate or collected into a daily digest)
+	digestHour, digestMinute := tasks.ParseSummaryTime(cfg.TranscodeAlertDigestTime)
+	transcodeAlertMonitor := monitors.NewTranscodeAlertMonitor(sqlDB, time.Duration(cfg.TranscodeAlertIntervalSec)*time.Second,
+		digestHour, digestMinute, tasks.LoadSummaryLocation(cfg.TranscodeAlertDigestTZ))
+	transcodeAlertMonitor.Start()
+	defer transcodeAlertMonitor.Stop()
+
+	// Optional host NIC sampling (validates session bitrates against actual traffic)
+	if cfg.HostMetricsEnabled {
+		hostCollector := hostmetrics.NewCollector(multiMgr, time.Duration(cfg.HostMetricsIntervalSec)*time.Second, cfg.HostMetricsInterfaces)
+		hostmetrics.SetDefault(hostCollector)
+		hostCollector.Start()
+		defer hostCollector.Stop()
+	}
+
+	// Start daily summary posts (Discord / Telegram) when configured
+	var summaryDests []tasks.SummaryDestination
+	if cfg.DiscordSummaryWebhookURL != "" {
+		h, m := tasks.ParseSummaryTime(cfg.DiscordSummaryTime)
+		summaryDests = append(summaryDests, tasks.SummaryDestination{
+			Name: "discord", Hour: h, Minute: m, Location: tasks.LoadSummaryLocation(cfg.DiscordSummaryTZ),
+			Send: func(s notify.Summary) error {
+				return notify.DiscordChannel{WebhookURL: cfg.DiscordSummaryWebhookURL}.Deliver(s.Event())
+			},
+		})
+	}
+	if cfg.TelegramBotToken != "" && cfg.TelegramChatID != "" {
+		h, m := tasks.ParseSummaryTime(cfg.TelegramSummaryTime)
+		summaryDests = append(summaryDests, tasks.SummaryDestination{
+			Name: "telegram", Hour: h, Minute: m, Location: tasks.LoadSummaryLocation(cfg.TelegramSummaryTZ),
+			Send: func(s notify.Summary) error {
+				return notify.TelegramChannel{BotToken: cfg.TelegramBotToken, ChatID: cfg.TelegramChatID}.Deliver(s.Event())
+			},
+		})
+	}
+	summaryScheduler := tasks.NewDailySummaryScheduler(sqlDB, summaryDests)
+	summaryScheduler.Start()
+	defer summaryScheduler.Stop()
+
+	// Start weekly personal digest emails when SMTP is configured
+	digestH, digestM := tasks.ParseSummaryTime(cfg.PersonalDigestTime)
+	digestScheduler := tasks.NewPersonalDigestScheduler(sqlDB, smtpCfg, digestH, digestM, tasks.LoadSummaryLocation(cfg.PersonalDigestTZ))
+	digestScheduler.Start()
+	defer digestScheduler.Stop()
+
 	// Add scheduler stats endpoint (protected)
 	app.Get("/admin/scheduler/stats", adminAuth, func(c fiber.Ctx) error {
 		stats, err := sync.GetSchedulerStats(sqlDB)
@@ -527,12 +941,26 @@ func main() {
 		return c.JSON(stats)
 	})
 
+	// Pause/resume background schedulers around imports, vacuums and backups
+	pausable := map[string]admin.PausableScheduler{
+		"sync":        scheduler,
+		"cleanup":     cleanupScheduler,
+		"transcoding": transcodingMonitor,
+	}
+	app.Get("/admin/schedulers", adminAuth, admin.SchedulersStatus(pausable))
+	app.Post("/admin/schedulers/pause", adminAuth, admin.SetSchedulersPaused(pausable, true))
+	app.Post("/admin/schedulers/resume", adminAuth, admin.SetSchedulersPaused(pausable, false))
+
 	// System metrics endpoint (protected)
 	app.Get("/admin/metrics", adminAuth, admin.SystemMetricsHandler(sqlDB))
+	app.Get("/admin/logging", adminAuth, admin.GetLogging())
+	app.Put("/admin/logging", adminAuth, admin.UpdateLogging())
+	app.Get("/admin/logs", adminAuth, admin.RecentLogs())
 
 	// App user management (admin-only)
 	app.Get("/admin/app-users", adminAuth, auth.ListAppUsers(sqlDB))
 	app.Post("/admin/app-users", adminAuth, auth.CreateAppUser(sqlDB))
+	app.Post("/admin/app-users/bulk", adminAuth, auth.BulkAppUsers(sqlDB))
 	app.Put("/admin/app-users/:id", adminAuth, auth.UpdateAppUser(sqlDB))
 	app.Delete("/admin/app-users/:id", adminAuth, auth.DeleteAppUser(sqlDB))
 
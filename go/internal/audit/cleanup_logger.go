@@ -54,6 +54,9 @@ func NewCleanupLogger(db *sql.DB, operationType string, createdBy string) (*Clea
 		return nil, err
 	}
 
+	// Capture stats before the job touches anything so the details endpoint can show a diff
+	saveSnapshot(db, jobID, "stats_before")
+
 	return &CleanupLogger{db: db, jobID: jobID}, nil
 }
 
@@ -91,6 +94,7 @@ func (cl *CleanupLogger) CompleteJob(totalChecked, itemsProcessed int, summary m
 		WHERE id = ?
 	`, time.Now().Unix(), totalChecked, itemsProcessed, summaryJSON, cl.jobID)
 
+	saveSnapshot(cl.db, cl.jobID, "stats_after")
 	return err
 }
 
@@ -105,6 +109,8 @@ func (cl *CleanupLogger) FailJob(errorMsg string) error {
 		WHERE id = ?
 	`, time.Now().Unix(), string(summaryJSON), cl.jobID)
 
+	// A failed job may have applied part of its changes
+	saveSnapshot(cl.db, cl.jobID, "stats_after")
 	return err
 }
 
@@ -0,0 +1,201 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// StatsSnapshot is a compact summary of watch history used to verify that a
+// cleanup or remap job didn't distort stats.
+type StatsSnapshot struct {
+	TakenAt        int64            `json:"taken_at"`
+	TotalIntervals int64            `json:"total_intervals"`
+	TotalSeconds   int64            `json:"total_seconds"`
+	UserSeconds    map[string]int64 `json:"user_seconds"`   // user_id -> watched seconds
+	ItemIntervals  map[string]int64 `json:"item_intervals"` // item_id -> interval count
+}
+
+type UserHoursChange struct {
+	UserID      string  `json:"user_id"`
+	HoursBefore float64 `json:"hours_before"`
+	HoursAfter  float64 `json:"hours_after"`
+	HoursDelta  float64 `json:"hours_delta"`
+}
+
+type ItemCountChange struct {
+	ItemID string `json:"item_id"`
+	Before int64  `json:"before"`
+	After  int64  `json:"after"`
+	Delta  int64  `json:"delta"`
+}
+
+// StatsDiff lists only the users and items whose numbers changed.
+type StatsDiff struct {
+	BeforeAt            int64             `json:"before_at"`
+	AfterAt             int64             `json:"after_at"`
+	TotalHoursBefore    float64           `json:"total_hours_before"`
+	TotalHoursAfter     float64           `json:"total_hours_after"`
+	TotalHoursDelta     float64           `json:"total_hours_delta"`
+	TotalIntervalsDelta int64             `json:"total_intervals_delta"`
+	Users               []UserHoursChange `json:"users"`
+	Items               []ItemCountChange `json:"items"`
+	ItemsChanged        int               `json:"items_changed"`
+}
+
+// maxDiffItems caps the per-item list returned in a diff
+const maxDiffItems = 500
+
+// TakeStatsSnapshot captures total watch seconds per user and interval counts per item.
+func TakeStatsSnapshot(db *sql.DB) (*StatsSnapshot, error) {
+	snap := &StatsSnapshot{
+		TakenAt:       time.Now().Unix(),
+		UserSeconds:   map[string]int64{},
+		ItemIntervals: map[string]int64{},
+	}
+
+	rows, err := db.Query(`SELECT user_id, COUNT(*), COALESCE(SUM(duration_seconds), 0) FROM play_intervals GROUP BY user_id`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var userID string
+		var count, seconds int64
+		if err := rows.Scan(&userID, &count, &seconds); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		snap.UserSeconds[userID] = seconds
+		snap.TotalIntervals += count
+		snap.TotalSeconds += seconds
+	}
+	rows.Close()
+
+	rows, err = db.Query(`SELECT item_id, COUNT(*) FROM play_intervals GROUP BY item_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var itemID string
+		var count int64
+		if err := rows.Scan(&itemID, &count); err != nil {
+			return nil, err
+		}
+		snap.ItemIntervals[itemID] = count
+	}
+	return snap, rows.Err()
+}
+
+func hours(seconds int64) float64 {
+	return float64(seconds) / 3600.0
+}
+
+// DiffStatsSnapshots compares two snapshots; changes are sorted by magnitude.
+func DiffStatsSnapshots(before, after *StatsSnapshot) *StatsDiff {
+	d := &StatsDiff{
+		BeforeAt:            before.TakenAt,
+		AfterAt:             after.TakenAt,
+		TotalHoursBefore:    hours(before.TotalSeconds),
+		TotalHoursAfter:     hours(after.TotalSeconds),
+		TotalHoursDelta:     hours(after.TotalSeconds - before.TotalSeconds),
+		TotalIntervalsDelta: after.TotalIntervals - before.TotalIntervals,
+		Users:               []UserHoursChange{},
+		Items:               []ItemCountChange{},
+	}
+
+	users := map[string]struct{}{}
+	for id := range before.UserSeconds {
+		users[id] = struct{}{}
+	}
+	for id := range after.UserSeconds {
+		users[id] = struct{}{}
+	}
+	for id := range users {
+		b, a := before.UserSeconds[id], after.UserSeconds[id]
+		if a == b {
+			continue
+		}
+		d.Users = append(d.Users, UserHoursChange{UserID: id, HoursBefore: hours(b), HoursAfter: hours(a), HoursDelta: hours(a - b)})
+	}
+	sort.Slice(d.Users, func(i, j int) bool {
+		return absFloat(d.Users[i].HoursDelta) > absFloat(d.Users[j].HoursDelta)
+	})
+
+	items := map[string]struct{}{}
+	for id := range before.ItemIntervals {
+		items[id] = struct{}{}
+	}
+	for id := range after.ItemIntervals {
+		items[id] = struct{}{}
+	}
+	for id := range items {
+		b, a := before.ItemIntervals[id], after.ItemIntervals[id]
+		if a == b {
+			continue
+		}
+		d.Items = append(d.Items, ItemCountChange{ItemID: id, Before: b, After: a, Delta: a - b})
+	}
+	sort.Slice(d.Items, func(i, j int) bool {
+		di, dj := absInt(d.Items[i].Delta), absInt(d.Items[j].Delta)
+		if di != dj {
+			return di > dj
+		}
+		return d.Items[i].ItemID < d.Items[j].ItemID
+	})
+	d.ItemsChanged = len(d.Items)
+	if len(d.Items) > maxDiffItems {
+		d.Items = d.Items[:maxDiffItems]
+	}
+	return d
+}
+
+// GetCleanupJobStatsDiff returns the before/after diff for a job, or nil when
+// either snapshot is missing (older jobs, or a job still running).
+func GetCleanupJobStatsDiff(db *sql.DB, jobID string) (*StatsDiff, error) {
+	var beforeJSON, afterJSON sql.NullString
+	err := db.QueryRow(`SELECT stats_before, stats_after FROM cleanup_jobs WHERE id = ?`, jobID).Scan(&beforeJSON, &afterJSON)
+	if err != nil {
+		return nil, err
+	}
+	if !beforeJSON.Valid || !afterJSON.Valid || beforeJSON.String == "" || afterJSON.String == "" {
+		return nil, nil
+	}
+	var before, after StatsSnapshot
+	if err := json.Unmarshal([]byte(beforeJSON.String), &before); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(afterJSON.String), &after); err != nil {
+		return nil, err
+	}
+	return DiffStatsSnapshots(&before, &after), nil
+}
+
+// saveSnapshot stores a snapshot in the given cleanup_jobs column; failures are
+// non-fatal since the snapshot is only an audit aid.
+func saveSnapshot(db *sql.DB, jobID, column string) {
+	snap, err := TakeStatsSnapshot(db)
+	if err != nil {
+		return
+	}
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return
+	}
+	_, _ = db.Exec(`UPDATE cleanup_jobs SET `+column+` = ? WHERE id = ?`, string(b), jobID)
+}
+
+func absFloat(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func absInt(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -27,10 +27,22 @@ type Config struct {
 	SQLitePath string
 	WebPath    string
 
+	// MaxBodyMB caps request bodies, e.g. database uploads to /admin/import/tautulli
+	MaxBodyMB int
+
 	// Streaming / polling
 	KeepAliveSec int
 	NowPollSec   int
 
+	// Webhook-primary polling: while webhooks keep arriving, session polling slows to
+	// PollSlowSec; after WebhookStaleMinutes without one it returns to NowPollSec
+	PollMode            string // "rest" (default) or "webhook-primary"
+	PollSlowSec         int    // e.g. 60
+	WebhookStaleMinutes int    // e.g. 5
+
+	// JellyfinWebSocket feeds playback from each Jellyfin server's WebSocket into the Intervalizer
+	JellyfinWebSocket bool
+
 	// Session cache configuration
 	NowCacheTTL      int // Cache freshness duration in seconds (default: 5)
 	NowCacheDebounce int // WebSocket event debounce in milliseconds (default: 250)
@@ -43,10 +55,46 @@ type Config struct {
 	// User sync
 	UserSyncIntervalSec int `env:"USERSYNC_INTERVAL" envDefault:"43200"` // 12 hours
 
+	// Offline download / sync job polling (0 disables)
+	DownloadPollSec int // e.g. 60
+
+	// DVR recording / series timer sync (0 disables)
+	DVRSyncIntervalSec int // e.g. 3600
+
+	// Rollup job (trending scores and other derived aggregates)
+	RollupIntervalSec    int // e.g. 3600
+	TrendingHalfLifeDays int // e.g. 7
+
+	// Interval compaction (merges near-adjacent intervals of finished sessions; 0 disables)
+	IntervalCompactIntervalSec int // e.g. 86400
+	IntervalCompactGapSec      int // largest gap between intervals that is merged, e.g. 10
+
+	// Data retention; RetentionDaysSessions -1 defers to the history_retention_days setting
+	RetentionDaysSessions int // finished sessions with their intervals and events; 0 keeps all
+	RetentionDaysEvents   int // play events of finished sessions, pruned sooner; 0 keeps all
+	RetentionIntervalSec  int // how often expired data is pruned, e.g. 21600
+
+	// Weekly database maintenance (integrity_check, ANALYZE, VACUUM); empty weekday disables
+	DBMaintenanceWeekday string // e.g. "sunday"
+	DBMaintenanceTime    string // HH:MM server local time, e.g. "03:00"
+
+	// Host network sampling (reads /proc/net/dev; Linux only)
+	HostMetricsEnabled     bool
+	HostMetricsIntervalSec int      // e.g. 10
+	HostMetricsInterfaces  []string // empty: all non-loopback interfaces
+
+	// Outbound media server HTTP (shared by Emby/Jellyfin/Plex clients)
+	HTTPMaxRetries         int // retries for idempotent requests, e.g. 2
+	HTTPRetryBackoffMs     int // first retry delay; doubles per attempt, e.g. 1000
+	HTTPBreakerThreshold   int // consecutive failures before a host's breaker opens, e.g. 5
+	HTTPBreakerCooldownSec int // how long an open breaker rejects requests, e.g. 30
+
 	// Images
 	ImgQuality          int // e.g. 90
 	ImgPrimaryMaxWidth  int // e.g. 300
 	ImgBackdropMaxWidth int // e.g. 1280
+	ImgCacheMaxMB       int // in-memory poster cache size, e.g. 64
+	ImgCacheTTLHours    int // how long cached posters are served, e.g. 24
 
 	// Admin refresh
 	RefreshChunkSize int // e.g. 200
@@ -55,13 +103,90 @@ type Config struct {
 	AdminToken      string // Authentication token for admin endpoints
 	WebhookSecret   string // Secret for webhook signature validation
 	AdminAutoCookie bool   // If true, server sets HttpOnly cookie to auto-auth UI
+	WidgetAPIKey    string // Optional key for dashboard widget endpoints (/api/now/transcoding, /grafana)
+	// SecretKey seals stored media server API keys; empty uses a key file next to the database
+	SecretKey string
+
+	// Notifications
+	NotifyWebhookURL   string // receives admin notifications (e.g. watch-for matches) as JSON POSTs
+	PublicURL          string // address the web UI is reached at, for absolute deep links
+	NotifySessionEnded bool   // also send a session_ended notification with the session summary
+	// Watch-for and transcode alert rules from the config file, stored on startup
+	NotificationRules NotificationRules
+
+	// Notification channels; each receives the events whose notify_event_<kind> setting is on.
+	// Discord and Telegram default to the daily summary webhook and bot below
+	NotifyDiscordWebhookURL string
+	NotifyTelegramBotToken  string
+	NotifyTelegramChatID    string
+	NotifyGotifyURL         string
+	NotifyGotifyToken       string
+	NotifyPushoverToken     string
+	NotifyPushoverUser      string
+
+	// Lifecycle hook script (see internal/hooks); empty HookEvents means the session events
+	HookScript     string
+	HookEvents     []string
+	HookTimeoutSec int
+
+	// Auto-stop of sessions left paused; 0 minutes disables it. IdleStopServerMinutes overrides
+	// the limit per server ID (0 exempts the server), whitelisted client apps are never stopped
+	IdleStopMinutes         int
+	IdleStopWarnMinutes     int
+	IdleStopServerMinutes   map[string]int
+	IdleStopClientWhitelist []string
+
+	// Parental rating audit: sessions of child users (media user IDs or names) above the
+	// rating are reported by /stats/ratings/restricted
+	ChildUsers     []string
+	ChildMaxRating string
+
+	// Transcode reason spike alerts; digest rules are sent once a day at "HH:MM" in the IANA timezone
+	TranscodeAlertIntervalSec int
+	TranscodeAlertDigestTime  string
+	TranscodeAlertDigestTZ    string
+
+	// Daily summary destinations; times are "HH:MM" in the destination's IANA timezone (empty = server local)
+	DiscordSummaryWebhookURL string
+	DiscordSummaryTime       string
+	DiscordSummaryTZ         string
+	TelegramBotToken         string
+	TelegramChatID           string
+	TelegramSummaryTime      string
+	TelegramSummaryTZ        string
+
+	// Outgoing email (SMTP) and the weekly personal digest sent on Mondays at "HH:MM" in the IANA timezone
+	SMTPHost           string
+	SMTPPort           int
+	SMTPUsername       string
+	SMTPPassword       string
+	SMTPFrom           string
+	PersonalDigestTime string
+	PersonalDigestTZ   string
+
+	// Weekly server report emailed to comma-separated admin addresses on Mondays at "HH:MM"
+	ReportEmailTo string
+	ReportTime    string
+	ReportTZ      string
+
+	// Overseerr/Jellyseerr requests, synced for the requested → watched funnel
+	OverseerrURL             string
+	OverseerrAPIKey          string
+	OverseerrSyncIntervalMin int
+
+	// Trakt.tv API application, for scrobbling linked users' plays
+	TraktClientID     string
+	TraktClientSecret string
 
 	// App auth (users + sessions)
-	AuthEnabled            bool   // if true, gate UI behind session auth
-	AuthRegistrationMode   string // closed|secret|open (default closed)
-	AuthRegistrationSecret string // invite/registration secret when mode=secret
-	AuthCookieName         string // cookie name for session token
-	AuthSessionTTLMinutes  int    // session lifetime in minutes
+	AuthEnabled            bool     // if true, gate UI behind session auth
+	AuthRegistrationMode   string   // closed|secret|open (default closed)
+	AuthRegistrationFixed  bool     // AUTH_REGISTRATION_MODE was set, overriding the mode chosen during setup
+	AuthRegistrationSecret string   // invite/registration secret when mode=secret
+	AuthCookieName         string   // cookie name for session token
+	AuthSessionTTLMinutes  int      // session lifetime in minutes
+	AuthMediaLogin         bool     // also accept Emby/Jellyfin credentials, provisioning a linked app user
+	AuthMediaServers       []string // server IDs checked for media logins (empty = every Emby/Jellyfin server)
 
 	// Logging
 	LogLevel  string // DEBUG, INFO, WARN, ERROR
@@ -73,6 +198,19 @@ type Config struct {
 	RefreshSseDebug bool // LOG: /admin/refresh/* SSE
 }
 
+// NotificationRules are the watch-for and transcode alert rules declared in the config file.
+type NotificationRules struct {
+	WatchFor        []FileRule `json:"watch_for,omitempty"`
+	TranscodeAlerts []FileRule `json:"transcode_alerts,omitempty"`
+}
+
+// FileRule is one rule as written in the config file: its fields with their YAML types and
+// the file:line it starts on, for error messages.
+type FileRule struct {
+	Source string         `json:"source"`
+	Fields map[string]any `json:"fields"`
+}
+
 func Load() Config {
 	dbPath := env("SQLITE_PATH", "/var/lib/emby-analytics/emby.db")
 	webPath := env("WEB_PATH", "/app/web")
@@ -101,9 +239,14 @@ func Load() Config {
 		ImgQuality:             envInt("IMG_QUALITY", 90),
 		ImgPrimaryMaxWidth:     envInt("IMG_PRIMARY_MAX_WIDTH", 300),
 		ImgBackdropMaxWidth:    envInt("IMG_BACKDROP_MAX_WIDTH", 1280),
+		ImgCacheMaxMB:          envInt("IMG_CACHE_MAX_MB", 64),
+		ImgCacheTTLHours:       envInt("IMG_CACHE_TTL_HOURS", 24),
 		RefreshChunkSize:       envInt("REFRESH_CHUNK_SIZE", 200),
 		AdminToken:             env("ADMIN_TOKEN", ""),
 		WebhookSecret:          env("WEBHOOK_SECRET", ""),
+		SecretKey:              env("SECRET_KEY", ""),
+		NotifyWebhookURL:       env("NOTIFY_WEBHOOK_URL", ""),
+		PublicURL:              env("PUBLIC_URL", ""),
 		AdminAutoCookie:        envBool("ADMIN_AUTO_COOKIE", false),
 		AuthEnabled:            envBool("AUTH_ENABLED", true),
 		AuthRegistrationMode:   env("AUTH_REGISTRATION_MODE", "closed"),
@@ -116,12 +259,157 @@ func Load() Config {
 		NowSseDebug:            envBool("NOW_SSE_DEBUG", false),
 		RefreshSseDebug:        envBool("REFRESH_SSE_DEBUG", false),
 		UserSyncIntervalSec:    envInt("USERSYNC_INTERVAL", 43200), // Changed from 3600 to 43200 (12 hours)
+		DownloadPollSec:        envInt("DOWNLOAD_POLL_SEC", 60),
+		DVRSyncIntervalSec:     envInt("DVR_SYNC_INTERVAL", 3600),
+		RollupIntervalSec:      envInt("ROLLUP_INTERVAL", 3600),
+		TrendingHalfLifeDays:   envInt("TRENDING_HALF_LIFE_DAYS", 7),
+		HTTPMaxRetries:         envInt("HTTP_MAX_RETRIES", 2),
+		HTTPRetryBackoffMs:     envInt("HTTP_RETRY_BACKOFF_MS", 1000),
+		HTTPBreakerThreshold:   envInt("HTTP_BREAKER_THRESHOLD", 5),
+		HTTPBreakerCooldownSec: envInt("HTTP_BREAKER_COOLDOWN_SEC", 30),
 	}
 
+	cfg.AuthRegistrationFixed = env("AUTH_REGISTRATION_MODE", "") != ""
+
 	// Load multi-server configuration
 	cfg.MediaServers = loadMediaServers(embyBase, embyKey, embyExternal)
 	cfg.DefaultServerID = env("DEFAULT_MEDIA_SERVER", getDefaultServerID(cfg.MediaServers))
 
+	// Interval compaction
+	cfg.IntervalCompactIntervalSec = envInt("INTERVAL_COMPACT_INTERVAL", 86400)
+	cfg.IntervalCompactGapSec = envInt("INTERVAL_COMPACT_GAP_SEC", 10)
+
+	// Request body limit
+	cfg.MaxBodyMB = envInt("MAX_BODY_MB", 4)
+
+	// Data retention
+	cfg.RetentionDaysSessions = envInt("RETENTION_DAYS_SESSIONS", -1)
+	cfg.RetentionDaysEvents = envInt("RETENTION_DAYS_EVENTS", 0)
+	cfg.RetentionIntervalSec = envInt("RETENTION_INTERVAL", 21600)
+
+	// Session summaries in outgoing notifications
+	cfg.NotifySessionEnded = envBool("NOTIFY_SESSION_ENDED", false)
+
+	// Notification channels
+	// Discord and Telegram fall back to the daily summary destinations, so one webhook or
+	// bot serves both
+	cfg.NotifyDiscordWebhookURL = env("NOTIFY_DISCORD_WEBHOOK_URL", env("DISCORD_SUMMARY_WEBHOOK_URL", ""))
+	cfg.NotifyTelegramBotToken = env("NOTIFY_TELEGRAM_BOT_TOKEN", env("TELEGRAM_BOT_TOKEN", ""))
+	cfg.NotifyTelegramChatID = env("NOTIFY_TELEGRAM_CHAT_ID", env("TELEGRAM_CHAT_ID", ""))
+	cfg.NotifyGotifyURL = env("NOTIFY_GOTIFY_URL", "")
+	cfg.NotifyGotifyToken = env("NOTIFY_GOTIFY_TOKEN", "")
+	cfg.NotifyPushoverToken = env("NOTIFY_PUSHOVER_TOKEN", "")
+	cfg.NotifyPushoverUser = env("NOTIFY_PUSHOVER_USER", "")
+
+	// Webhook-primary session polling
+	cfg.PollMode = strings.ToLower(strings.TrimSpace(env("POLL_MODE", "rest")))
+	cfg.PollSlowSec = envInt("POLL_SLOW_SEC", 60)
+	cfg.WebhookStaleMinutes = envInt("WEBHOOK_STALE_MINUTES", 5)
+
+	// Jellyfin playback events
+	cfg.JellyfinWebSocket = envBool("JELLYFIN_WEBSOCKET", true)
+
+	// Lifecycle hook script
+	cfg.HookScript = env("HOOK_SCRIPT", "")
+	for _, ev := range strings.Split(env("HOOK_EVENTS", ""), ",") {
+		if ev = strings.TrimSpace(ev); ev != "" {
+			cfg.HookEvents = append(cfg.HookEvents, ev)
+		}
+	}
+	cfg.HookTimeoutSec = envInt("HOOK_TIMEOUT_SEC", 10)
+
+	// Dashboard widget endpoints
+	cfg.WidgetAPIKey = env("WIDGET_API_KEY", "")
+
+	if raw := env("NOTIFICATION_RULES", ""); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.NotificationRules); err != nil {
+			fmt.Printf("[WARN] Ignoring invalid NOTIFICATION_RULES: %v\n", err)
+		}
+	}
+
+	// Paused session auto-stop
+	cfg.IdleStopMinutes = envInt("IDLE_STOP_MINUTES", 0)
+	cfg.IdleStopWarnMinutes = envInt("IDLE_STOP_WARN_MINUTES", 5)
+	cfg.IdleStopServerMinutes = map[string]int{}
+	for _, pair := range strings.Split(env("IDLE_STOP_SERVER_MINUTES", ""), ",") {
+		id, mins, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || strings.TrimSpace(id) == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(mins)); err == nil && n >= 0 {
+			cfg.IdleStopServerMinutes[strings.TrimSpace(id)] = n
+		}
+	}
+	for _, name := range strings.Split(env("IDLE_STOP_CLIENT_WHITELIST", ""), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			cfg.IdleStopClientWhitelist = append(cfg.IdleStopClientWhitelist, name)
+		}
+	}
+
+	// Parental rating audit
+	for _, user := range strings.Split(env("CHILD_USERS", ""), ",") {
+		if user = strings.TrimSpace(user); user != "" {
+			cfg.ChildUsers = append(cfg.ChildUsers, user)
+		}
+	}
+	cfg.ChildMaxRating = env("CHILD_MAX_RATING", "PG")
+
+	// Transcode reason spike alerts
+	cfg.TranscodeAlertIntervalSec = envInt("TRANSCODE_ALERT_INTERVAL_SEC", 300)
+	cfg.TranscodeAlertDigestTime = env("TRANSCODE_ALERT_DIGEST_TIME", "09:00")
+	cfg.TranscodeAlertDigestTZ = env("TRANSCODE_ALERT_DIGEST_TZ", "")
+
+	// Weekly database maintenance
+	cfg.DBMaintenanceWeekday = env("DB_MAINTENANCE_WEEKDAY", "")
+	cfg.DBMaintenanceTime = env("DB_MAINTENANCE_TIME", "03:00")
+
+	// Sign-in with media server accounts
+	cfg.AuthMediaLogin = envBool("AUTH_MEDIA_LOGIN", false)
+	for _, id := range strings.Split(env("AUTH_MEDIA_SERVERS", ""), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			cfg.AuthMediaServers = append(cfg.AuthMediaServers, id)
+		}
+	}
+
+	// Host network sampling
+	cfg.HostMetricsEnabled = envBool("HOST_METRICS_ENABLED", false)
+	cfg.HostMetricsIntervalSec = envInt("HOST_METRICS_INTERVAL_SEC", 10)
+	for _, name := range strings.Split(env("HOST_METRICS_INTERFACES", ""), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			cfg.HostMetricsInterfaces = append(cfg.HostMetricsInterfaces, name)
+		}
+	}
+
+	// Daily summary destinations
+	cfg.DiscordSummaryWebhookURL = env("DISCORD_SUMMARY_WEBHOOK_URL", "")
+	cfg.DiscordSummaryTime = env("DISCORD_SUMMARY_TIME", "08:00")
+	cfg.DiscordSummaryTZ = env("DISCORD_SUMMARY_TZ", "")
+	cfg.TelegramBotToken = env("TELEGRAM_BOT_TOKEN", "")
+	cfg.TelegramChatID = env("TELEGRAM_CHAT_ID", "")
+	cfg.TelegramSummaryTime = env("TELEGRAM_SUMMARY_TIME", "08:00")
+	cfg.TelegramSummaryTZ = env("TELEGRAM_SUMMARY_TZ", "")
+
+	// Outgoing email
+	cfg.SMTPHost = env("SMTP_HOST", "")
+	cfg.SMTPPort = envInt("SMTP_PORT", 587)
+	cfg.SMTPUsername = env("SMTP_USERNAME", "")
+	cfg.SMTPPassword = env("SMTP_PASSWORD", "")
+	cfg.SMTPFrom = env("SMTP_FROM", "")
+	cfg.PersonalDigestTime = env("PERSONAL_DIGEST_TIME", "08:00")
+	cfg.PersonalDigestTZ = env("PERSONAL_DIGEST_TZ", "")
+	cfg.ReportEmailTo = env("REPORT_EMAIL_TO", "")
+	cfg.ReportTime = env("REPORT_TIME", "08:00")
+	cfg.ReportTZ = env("REPORT_TZ", "")
+
+	// Media requests
+	cfg.OverseerrURL = strings.TrimRight(env("OVERSEERR_URL", ""), "/")
+	cfg.OverseerrAPIKey = env("OVERSEERR_API_KEY", "")
+	cfg.OverseerrSyncIntervalMin = envInt("OVERSEERR_SYNC_INTERVAL_MIN", 60)
+
+	// Trakt scrobbling
+	cfg.TraktClientID = env("TRAKT_CLIENT_ID", "")
+	cfg.TraktClientSecret = env("TRAKT_CLIENT_SECRET", "")
+
 	// Auto-generate and persist admin token if not provided
 	if cfg.AdminToken == "" {
 		tokenFile := filepath.Join(filepath.Dir(dbPath), "admin_token")
@@ -273,6 +561,10 @@ func loadMediaServersSimple() []media.ServerConfig {
 				APIKey:      key,
 				ExternalURL: env("EMBY_EXTERNAL_URL", base),
 				Enabled:     envBool("EMBY_ENABLED", true),
+
+				TLSCAFile:             env("EMBY_TLS_CA_FILE", ""),
+				TLSInsecureSkipVerify: envBool("EMBY_TLS_INSECURE", false),
+				ProxyURL:              env("EMBY_PROXY_URL", ""),
 			})
 		}
 	}
@@ -288,6 +580,10 @@ func loadMediaServersSimple() []media.ServerConfig {
 				APIKey:      key,
 				ExternalURL: env("PLEX_EXTERNAL_URL", base),
 				Enabled:     envBool("PLEX_ENABLED", true),
+
+				TLSCAFile:             env("PLEX_TLS_CA_FILE", ""),
+				TLSInsecureSkipVerify: envBool("PLEX_TLS_INSECURE", false),
+				ProxyURL:              env("PLEX_PROXY_URL", ""),
 			})
 		}
 	}
@@ -303,6 +599,10 @@ func loadMediaServersSimple() []media.ServerConfig {
 				APIKey:      key,
 				ExternalURL: env("JELLYFIN_EXTERNAL_URL", base),
 				Enabled:     envBool("JELLYFIN_ENABLED", true),
+
+				TLSCAFile:             env("JELLYFIN_TLS_CA_FILE", ""),
+				TLSInsecureSkipVerify: envBool("JELLYFIN_TLS_INSECURE", false),
+				ProxyURL:              env("JELLYFIN_PROXY_URL", ""),
 			})
 		}
 	}
@@ -364,6 +664,10 @@ func loadMediaServersNumbered() []media.ServerConfig {
 			APIKey:      key,
 			ExternalURL: ext,
 			Enabled:     enabled,
+
+			TLSCAFile:             env(prefix+"TLS_CA_FILE", ""),
+			TLSInsecureSkipVerify: envBool(prefix+"TLS_INSECURE", false),
+			ProxyURL:              env(prefix+"PROXY_URL", ""),
 		})
 	}
 	return servers
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"emby-analytics/internal/media"
+)
+
+// ApplyConfigFile loads an optional YAML config file (CONFIG_FILE) and exports
+// its values as environment variables so Load picks them up. Environment
+// variables that are already set always win over the file.
+//
+// Scalar keys map to the env var of the same name, upper-cased, with nested
+// sections joined by '_':
+//
+//	sync_interval: 300          -> SYNC_INTERVAL
+//	http:
+//	  max_retries: 3            -> HTTP_MAX_RETRIES
+//
+// Lists of scalars become comma-separated values, and the mappings of pairSettings become
+// "key:value" pairs, so policies such as idle_stop can be written out in full:
+//
+//	idle_stop:
+//	  client_whitelist: [Kodi, Infuse]  -> IDLE_STOP_CLIENT_WHITELIST=Kodi,Infuse
+//	  server_minutes: {plex-1: 0}       -> IDLE_STOP_SERVER_MINUTES=plex-1:0
+//
+// The media_servers list is converted to MEDIA_SERVERS JSON and notification_rules to
+// NOTIFICATION_RULES JSON.
+func ApplyConfigFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	values, err := configFileValues(path, data)
+	if err != nil {
+		return err
+	}
+
+	applied := 0
+	for k, v := range values {
+		if os.Getenv(k) != "" {
+			continue
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return fmt.Errorf("set %s: %w", k, err)
+		}
+		applied++
+	}
+	fmt.Printf("[INFO] Loaded %d settings from config file %s (%d overridden by environment)\n", applied, path, len(values)-applied)
+	return nil
+}
+
+// configFileValues maps the settings of a config file to the env vars they stand for.
+func configFileValues(path string, data []byte) (map[string]string, error) {
+	root, err := parseYAML(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if root.kind != yamlMap {
+		return nil, fmt.Errorf("%s:%d: top level must be a mapping of settings", path, root.line)
+	}
+
+	values := map[string]string{}
+	for _, key := range root.keys {
+		node := root.index[key]
+		if key == "media_servers" {
+			servers, err := mediaServersFromYAML(path, node)
+			if err != nil {
+				return nil, err
+			}
+			b, _ := json.Marshal(servers)
+			values["MEDIA_SERVERS"] = string(b)
+			continue
+		}
+		if key == "notification_rules" {
+			rules, err := notificationRulesFromYAML(path, node)
+			if err != nil {
+				return nil, err
+			}
+			b, _ := json.Marshal(rules)
+			values["NOTIFICATION_RULES"] = string(b)
+			continue
+		}
+		if err := flattenYAML(path, strings.ToUpper(key), node, values); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// pairSettings are the env vars holding comma-separated "key:value" pairs, written in the
+// config file as a mapping.
+var pairSettings = map[string]bool{
+	"IDLE_STOP_SERVER_MINUTES": true,
+}
+
+func flattenYAML(path, prefix string, node *yamlNode, out map[string]string) error {
+	name := strings.ToLower(prefix)
+	switch node.kind {
+	case yamlScalar:
+		out[prefix] = node.value
+	case yamlMap:
+		if pairSettings[prefix] {
+			pairs := make([]string, 0, len(node.keys))
+			for _, key := range node.keys {
+				child := node.index[key]
+				if child.kind != yamlScalar || strings.ContainsAny(key, ",:") || strings.Contains(child.value, ",") {
+					return fmt.Errorf("%s:%d: %s.%s must be a plain value", path, node.lines[key], name, key)
+				}
+				pairs = append(pairs, key+":"+child.value)
+			}
+			out[prefix] = strings.Join(pairs, ",")
+			return nil
+		}
+		for _, key := range node.keys {
+			if err := flattenYAML(path, prefix+"_"+strings.ToUpper(key), node.index[key], out); err != nil {
+				return err
+			}
+		}
+	case yamlList:
+		vals := make([]string, 0, len(node.items))
+		for i, item := range node.items {
+			if item.kind != yamlScalar {
+				return fmt.Errorf("%s:%d: %s[%d]: only lists of plain values are supported here", path, item.line, name, i)
+			}
+			if strings.Contains(item.value, ",") {
+				return fmt.Errorf("%s:%d: %s[%d]: values can't contain commas", path, item.line, name, i)
+			}
+			vals = append(vals, item.value)
+		}
+		out[prefix] = strings.Join(vals, ",")
+	}
+	return nil
+}
+
+// notificationRulesFromYAML reads the watch_for and transcode_alerts lists. Each rule keeps
+// its fields as typed values and its file:line, so the rules are validated where they are
+// stored (see Config.NotificationRules) with errors pointing back at the file.
+func notificationRulesFromYAML(path string, node *yamlNode) (NotificationRules, error) {
+	var rules NotificationRules
+	if node.kind != yamlMap {
+		return rules, fmt.Errorf("%s:%d: notification_rules must be a mapping", path, node.line)
+	}
+	for _, key := range node.keys {
+		var dst *[]FileRule
+		switch key {
+		case "watch_for":
+			dst = &rules.WatchFor
+		case "transcode_alerts":
+			dst = &rules.TranscodeAlerts
+		default:
+			return rules, fmt.Errorf("%s:%d: notification_rules: unknown key %q (expected watch_for or transcode_alerts)", path, node.lines[key], key)
+		}
+		list := node.index[key]
+		if list.kind != yamlList {
+			return rules, fmt.Errorf("%s:%d: notification_rules.%s must be a list", path, list.line, key)
+		}
+		for i, item := range list.items {
+			if item.kind != yamlMap {
+				return rules, fmt.Errorf("%s:%d: notification_rules.%s[%d] must be a mapping", path, item.line, key, i)
+			}
+			fields := make(map[string]any, len(item.keys))
+			for _, field := range item.keys {
+				child := item.index[field]
+				if child.kind != yamlScalar {
+					return rules, fmt.Errorf("%s:%d: notification_rules.%s[%d].%s must be a scalar", path, item.lines[field], key, i, field)
+				}
+				var v any
+				if err := child.src.Decode(&v); err != nil {
+					return rules, fmt.Errorf("%s:%d: notification_rules.%s[%d].%s: %v", path, child.line, key, i, field, err)
+				}
+				fields[field] = v
+			}
+			*dst = append(*dst, FileRule{Source: fmt.Sprintf("%s:%d", path, item.line), Fields: fields})
+		}
+	}
+	return rules, nil
+}
+
+func mediaServersFromYAML(path string, node *yamlNode) ([]media.ServerConfig, error) {
+	if node.kind != yamlList {
+		return nil, fmt.Errorf("%s:%d: media_servers must be a list", path, node.line)
+	}
+	servers := make([]media.ServerConfig, 0, len(node.items))
+	seen := map[string]int{}
+	for i, item := range node.items {
+		if item.kind != yamlMap {
+			return nil, fmt.Errorf("%s:%d: media_servers[%d] must be a mapping", path, item.line, i)
+		}
+		sc := media.ServerConfig{Enabled: true}
+		for _, key := range item.keys {
+			child := item.index[key]
+			if child.kind != yamlScalar {
+				return nil, fmt.Errorf("%s:%d: media_servers[%d].%s must be a scalar", path, child.line, i, key)
+			}
+			v := child.value
+			switch key {
+			case "id":
+				sc.ID = v
+			case "type":
+				sc.Type = media.ServerType(strings.ToLower(v))
+			case "name":
+				sc.Name = v
+			case "base_url":
+				sc.BaseURL = strings.TrimRight(v, "/")
+			case "api_key":
+				sc.APIKey = v
+			case "external_url":
+				sc.ExternalURL = v
+			case "enabled":
+				b, ok := parseYAMLBool(v)
+				if !ok {
+					return nil, fmt.Errorf("%s:%d: media_servers[%d].enabled: expected true or false, got %q", path, child.line, i, v)
+				}
+				sc.Enabled = b
+			case "tls_ca_file":
+				sc.TLSCAFile = v
+			case "tls_insecure_skip_verify":
+				b, ok := parseYAMLBool(v)
+				if !ok {
+					return nil, fmt.Errorf("%s:%d: media_servers[%d].tls_insecure_skip_verify: expected true or false, got %q", path, child.line, i, v)
+				}
+				sc.TLSInsecureSkipVerify = b
+			case "proxy_url":
+				sc.ProxyURL = v
+			default:
+				return nil, fmt.Errorf("%s:%d: media_servers[%d]: unknown key %q", path, item.lines[key], i, key)
+			}
+		}
+
+		switch sc.Type {
+		case media.ServerTypeEmby, media.ServerTypePlex, media.ServerTypeJellyfin:
+		case "":
+			return nil, fmt.Errorf("%s:%d: media_servers[%d]: type is required (emby, plex or jellyfin)", path, item.line, i)
+		default:
+			return nil, fmt.Errorf("%s:%d: media_servers[%d]: unsupported type %q", path, item.lines["type"], i, sc.Type)
+		}
+		if sc.BaseURL == "" {
+			return nil, fmt.Errorf("%s:%d: media_servers[%d]: base_url is required", path, item.line, i)
+		}
+		if sc.APIKey == "" {
+			return nil, fmt.Errorf("%s:%d: media_servers[%d]: api_key is required", path, item.line, i)
+		}
+		if sc.ID == "" {
+			sc.ID = fmt.Sprintf("%s-%d", sc.Type, i+1)
+		}
+		if prev, dup := seen[sc.ID]; dup {
+			return nil, fmt.Errorf("%s:%d: media_servers[%d]: duplicate id %q (also used on line %d)", path, item.line, i, sc.ID, prev)
+		}
+		seen[sc.ID] = item.line
+		if sc.Name == "" {
+			sc.Name = sc.ID
+		}
+		if sc.ExternalURL == "" {
+			sc.ExternalURL = sc.BaseURL
+		}
+		servers = append(servers, sc)
+	}
+	return servers, nil
+}
+
+func parseYAMLBool(v string) (bool, bool) {
+	switch strings.ToLower(v) {
+	case "true", "yes", "on", "1":
+		return true, true
+	case "false", "no", "off", "0":
+		return false, true
+	}
+	return false, false
+}
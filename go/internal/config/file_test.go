@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigFileValues(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want map[string]string
+	}{
+		{"empty file", "", map[string]string{}},
+		{"comments only", "# nothing yet\n---\n", map[string]string{}},
+		{"scalars and sections", `
+sync_interval: 300   # seconds
+log_level: "info"
+http:
+  max_retries: 3
+  proxy: ''
+`, map[string]string{"SYNC_INTERVAL": "300", "LOG_LEVEL": "info", "HTTP_MAX_RETRIES": "3", "HTTP_PROXY": ""}},
+		{"null and quoted values", `
+webhook_secret: ~
+public_url: "https://stats.example.com/#x"
+`, map[string]string{"WEBHOOK_SECRET": "", "PUBLIC_URL": "https://stats.example.com/#x"}},
+		{"policy lists and pairs", `
+idle_stop:
+  minutes: 30
+  client_whitelist: [Kodi, Infuse]
+  server_minutes:
+    plex-1: 0
+    home: 45
+child_users:
+  - kid1
+  - kid2
+`, map[string]string{
+			"IDLE_STOP_MINUTES":          "30",
+			"IDLE_STOP_CLIENT_WHITELIST": "Kodi,Infuse",
+			"IDLE_STOP_SERVER_MINUTES":   "plex-1:0,home:45",
+			"CHILD_USERS":                "kid1,kid2",
+		}},
+		{"anchors", `
+defaults: &d 60
+hook_timeout_sec: *d
+`, map[string]string{"DEFAULTS": "60", "HOOK_TIMEOUT_SEC": "60"}},
+		{"block scalar", "motd: |\n  two\n  lines\n", map[string]string{"MOTD": "two\nlines\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := configFileValues("c.yaml", []byte(tt.yaml))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("values = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string // prefix of the error, with its location
+	}{
+		{"not a mapping", "- a\n- b\n", "c.yaml:1: top level must be a mapping"},
+		{"syntax", "a: 1\n b: 2\n", "c.yaml: yaml: line 2"},
+		{"duplicate key", "a: 1\nb: 2\na: 3\n", `c.yaml:3: duplicate key "a" (first defined on line 1)`},
+		{"nested list", "x:\n  - [1, 2]\n", "c.yaml:2: x[0]: only lists of plain values"},
+		{"comma in list", "child_users: ['a,b']\n", "c.yaml:1: child_users[0]: values can't contain commas"},
+		{"pair with a list", "idle_stop:\n  server_minutes:\n    a: [1]\n", "c.yaml:3: idle_stop_server_minutes.a must be a plain value"},
+		{"server without key", "media_servers:\n  - type: emby\n    base_url: http://e\n", "c.yaml:2: media_servers[0]: api_key is required"},
+		{"server type", "media_servers:\n  - type: kodi\n    base_url: http://e\n    api_key: k\n", `c.yaml:2: media_servers[0]: unsupported type "kodi"`},
+		{"server bool", "media_servers:\n  - type: plex\n    base_url: http://p\n    api_key: k\n    enabled: maybe\n", "c.yaml:5: media_servers[0].enabled: expected true or false"},
+		{"duplicate server", "media_servers:\n  - {id: a, type: plex, base_url: http://p, api_key: k}\n  - {id: a, type: emby, base_url: http://e, api_key: k}\n", `c.yaml:3: media_servers[1]: duplicate id "a" (also used on line 2)`},
+		{"rule kind", "notification_rules:\n  alerts: []\n", `c.yaml:2: notification_rules: unknown key "alerts"`},
+		{"rule shape", "notification_rules:\n  watch_for:\n    - item\n", "c.yaml:3: notification_rules.watch_for[0] must be a mapping"},
+		{"rule field", "notification_rules:\n  watch_for:\n    - kind: item\n      value: [a]\n", "c.yaml:4: notification_rules.watch_for[0].value must be a scalar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := configFileValues("c.yaml", []byte(tt.yaml))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("error = %v, want prefix %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigFileServersAndRules(t *testing.T) {
+	values, err := configFileValues("c.yaml", []byte(`
+media_servers:
+  - type: Jellyfin
+    base_url: https://jf.lan/
+    api_key: "0123"
+notification_rules:
+  watch_for:
+    - kind: series
+      value: "Star Wars*"
+      enabled: false
+  transcode_alerts:
+    - reason: SubtitleCodecNotSupported
+      spike_factor: 2.5
+      window_minutes: 30
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	var servers []map[string]any
+	if err := json.Unmarshal([]byte(values["MEDIA_SERVERS"]), &servers); err != nil || len(servers) != 1 {
+		t.Fatalf("MEDIA_SERVERS = %s, %v", values["MEDIA_SERVERS"], err)
+	}
+	if s := servers[0]; s["id"] != "jellyfin-1" || s["type"] != "jellyfin" || s["base_url"] != "https://jf.lan" || s["api_key"] != "0123" {
+		t.Errorf("server = %v", s)
+	}
+
+	var rules NotificationRules
+	if err := json.Unmarshal([]byte(values["NOTIFICATION_RULES"]), &rules); err != nil {
+		t.Fatalf("NOTIFICATION_RULES = %s, %v", values["NOTIFICATION_RULES"], err)
+	}
+	want := NotificationRules{
+		WatchFor: []FileRule{{Source: "c.yaml:8", Fields: map[string]any{"kind": "series", "value": "Star Wars*", "enabled": false}}},
+		// Fields keep their YAML types (numbers come back from JSON as float64)
+		TranscodeAlerts: []FileRule{{Source: "c.yaml:12", Fields: map[string]any{
+			"reason": "SubtitleCodecNotSupported", "spike_factor": 2.5, "window_minutes": float64(30)}}},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules = %+v", rules)
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CONFIG_FILE is decoded with yaml.v3 into yamlNodes, a reduced tree of mappings, lists
+// and scalars. Every node remembers its source line so validation errors can point at the
+// offending location; aliases are resolved and duplicate keys are rejected.
+
+type yamlKind int
+
+const (
+	yamlScalar yamlKind = iota
+	yamlMap
+	yamlList
+)
+
+type yamlNode struct {
+	kind  yamlKind
+	line  int
+	value string               // scalar; "" for null
+	keys  []string             // map, in file order
+	index map[string]*yamlNode // map
+	lines map[string]int       // map: line of each key
+	items []*yamlNode          // list
+	src   *yaml.Node           // the decoded node, for typed values
+}
+
+func parseYAML(file string, data []byte) (*yamlNode, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return &yamlNode{kind: yamlMap, line: 1, index: map[string]*yamlNode{}, lines: map[string]int{}}, nil
+		}
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	var extra yaml.Node
+	if err := dec.Decode(&extra); err == nil {
+		return nil, fmt.Errorf("%s:%d: only one YAML document is supported", file, extra.Line)
+	}
+	root, err := convertYAML(file, &doc, 0)
+	if err == nil && root.kind == yamlScalar && root.src.Tag == "!!null" {
+		// a file with nothing but comments
+		root = &yamlNode{kind: yamlMap, line: root.line, index: map[string]*yamlNode{}, lines: map[string]int{}}
+	}
+	return root, err
+}
+
+func convertYAML(file string, n *yaml.Node, depth int) (*yamlNode, error) {
+	if depth > 32 {
+		return nil, fmt.Errorf("%s:%d: nesting too deep", file, n.Line)
+	}
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return &yamlNode{kind: yamlMap, line: n.Line, index: map[string]*yamlNode{}, lines: map[string]int{}}, nil
+		}
+		return convertYAML(file, n.Content[0], depth+1)
+	case yaml.AliasNode:
+		return convertYAML(file, n.Alias, depth+1)
+	case yaml.ScalarNode:
+		out := &yamlNode{kind: yamlScalar, line: n.Line, value: n.Value, src: n}
+		if n.Tag == "!!null" {
+			out.value = ""
+		}
+		return out, nil
+	case yaml.SequenceNode:
+		out := &yamlNode{kind: yamlList, line: n.Line, src: n}
+		for _, c := range n.Content {
+			item, err := convertYAML(file, c, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out.items = append(out.items, item)
+		}
+		return out, nil
+	case yaml.MappingNode:
+		out := &yamlNode{kind: yamlMap, line: n.Line, index: map[string]*yamlNode{}, lines: map[string]int{}, src: n}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("%s:%d: keys must be plain strings", file, k.Line)
+			}
+			if k.Value == "<<" {
+				return nil, fmt.Errorf("%s:%d: merge keys are not supported", file, k.Line)
+			}
+			if prev, dup := out.lines[k.Value]; dup {
+				return nil, fmt.Errorf("%s:%d: duplicate key %q (first defined on line %d)", file, k.Line, k.Value, prev)
+			}
+			child, err := convertYAML(file, v, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out.keys = append(out.keys, k.Value)
+			out.index[k.Value] = child
+			out.lines[k.Value] = k.Line
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s:%d: unsupported YAML node", file, n.Line)
+}
@@ -0,0 +1,2 @@
+-- Down migration: drop index only; SQLite column drops require a table rebuild
+DROP INDEX IF EXISTS idx_play_sessions_syncplay_group;
@@ -0,0 +1,5 @@
+-- Track SyncPlay / watch-together group membership on play sessions
+ALTER TABLE play_sessions ADD COLUMN syncplay_group_id TEXT;
+
+-- Index for collapsing group members when computing item popularity
+CREATE INDEX IF NOT EXISTS idx_play_sessions_syncplay_group ON play_sessions(syncplay_group_id, item_id);
@@ -0,0 +1,2 @@
+-- Drop download tracking table
+DROP TABLE IF EXISTS download_jobs;
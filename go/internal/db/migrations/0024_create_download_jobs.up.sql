@@ -0,0 +1,23 @@
+-- Offline download / device sync jobs, tracked separately from streaming sessions
+CREATE TABLE IF NOT EXISTS download_jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_id TEXT NOT NULL,
+    server_type TEXT NOT NULL,
+    job_id TEXT NOT NULL,                  -- server-side job / job item identifier
+    user_id TEXT,
+    user_name TEXT,
+    item_id TEXT,
+    item_name TEXT,
+    item_type TEXT,
+    device_name TEXT,
+    status TEXT NOT NULL,                  -- last reported status (Queued, Transferring, Completed, ...)
+    progress REAL NOT NULL DEFAULT 0,      -- 0-100
+    size_bytes INTEGER NOT NULL DEFAULT 0,
+    first_seen_at INTEGER NOT NULL,        -- unix timestamp
+    last_seen_at INTEGER NOT NULL,         -- unix timestamp
+    completed_at INTEGER,                  -- unix timestamp when first seen completed
+    UNIQUE(server_id, job_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_download_jobs_first_seen ON download_jobs(first_seen_at);
+CREATE INDEX IF NOT EXISTS idx_download_jobs_user ON download_jobs(user_id);
@@ -0,0 +1,3 @@
+-- Drop DVR tracking tables
+DROP TABLE IF EXISTS dvr_series_timers;
+DROP TABLE IF EXISTS dvr_recordings;
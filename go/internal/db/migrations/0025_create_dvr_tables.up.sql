@@ -0,0 +1,35 @@
+-- DVR recordings (completed, in-progress and scheduled) synced from Live TV capable servers
+CREATE TABLE IF NOT EXISTS dvr_recordings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_id TEXT NOT NULL,
+    server_type TEXT NOT NULL,
+    recording_id TEXT NOT NULL,            -- server recording / timer id
+    name TEXT,
+    series_name TEXT,
+    channel_name TEXT,
+    status TEXT,
+    start_ts INTEGER,                      -- unix timestamp
+    end_ts INTEGER,                        -- unix timestamp
+    size_bytes INTEGER NOT NULL DEFAULT 0,
+    series_timer_id TEXT,
+    is_scheduled INTEGER NOT NULL DEFAULT 0,
+    last_synced_at INTEGER NOT NULL,       -- unix timestamp of last sync that reported this row
+    removed_at INTEGER,                    -- set when the server stops reporting the recording
+    UNIQUE(server_id, recording_id, is_scheduled)
+);
+
+CREATE INDEX IF NOT EXISTS idx_dvr_recordings_start ON dvr_recordings(start_ts);
+CREATE INDEX IF NOT EXISTS idx_dvr_recordings_series ON dvr_recordings(series_name);
+
+-- Series recording rules
+CREATE TABLE IF NOT EXISTS dvr_series_timers (
+    server_id TEXT NOT NULL,
+    server_type TEXT NOT NULL,
+    timer_id TEXT NOT NULL,
+    name TEXT,
+    channel_name TEXT,
+    record_new_only INTEGER NOT NULL DEFAULT 0,
+    record_any_channel INTEGER NOT NULL DEFAULT 0,
+    last_synced_at INTEGER NOT NULL,
+    PRIMARY KEY (server_id, timer_id)
+);
@@ -0,0 +1,2 @@
+-- Drop trending score table
+DROP TABLE IF EXISTS item_trending;
@@ -0,0 +1,12 @@
+-- Exponentially decayed watch-hour score per item, refreshed by the rollup job
+CREATE TABLE IF NOT EXISTS item_trending (
+    item_id TEXT PRIMARY KEY,
+    server_id TEXT,
+    server_type TEXT,
+    score REAL NOT NULL DEFAULT 0,         -- decayed watch hours
+    hours_7d REAL NOT NULL DEFAULT 0,      -- raw watch hours over the last 7 days
+    last_watched_at INTEGER,               -- unix timestamp
+    updated_at INTEGER NOT NULL            -- unix timestamp of the rollup that computed the row
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_trending_score ON item_trending(score DESC);
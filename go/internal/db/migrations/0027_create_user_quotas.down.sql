@@ -0,0 +1,3 @@
+-- Drop user quota tables
+DROP TABLE IF EXISTS user_quota_events;
+DROP TABLE IF EXISTS user_quotas;
@@ -0,0 +1,18 @@
+-- Per-user viewing time quotas (minutes); NULL limits mean "no limit" for that period
+CREATE TABLE IF NOT EXISTS user_quotas (
+    user_id TEXT PRIMARY KEY,
+    daily_minutes INTEGER,
+    weekly_minutes INTEGER,
+    enforce INTEGER NOT NULL DEFAULT 0,          -- 1 = message/stop sessions once exhausted
+    warning_minutes INTEGER NOT NULL DEFAULT 10, -- send a grace warning when this many minutes remain
+    updated_at INTEGER NOT NULL
+);
+
+-- Records which warnings/stops were already issued per quota period to avoid repeats
+CREATE TABLE IF NOT EXISTS user_quota_events (
+    user_id TEXT NOT NULL,
+    period_key TEXT NOT NULL,                    -- e.g. 'day:2025-01-31' or 'week:2025-01-27'
+    kind TEXT NOT NULL,                          -- 'warning' or 'exhausted'
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (user_id, period_key, kind)
+);
@@ -0,0 +1,3 @@
+-- Drop saved views table
+DROP INDEX IF EXISTS idx_saved_views_user;
+DROP TABLE IF EXISTS saved_views;
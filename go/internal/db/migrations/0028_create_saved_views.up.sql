@@ -0,0 +1,13 @@
+-- Named filter sets persisted per app user; filters is a JSON object of stats query params
+CREATE TABLE IF NOT EXISTS saved_views (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES app_user(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    filters TEXT NOT NULL DEFAULT '{}',          -- e.g. {"days":"30","server":"jellyfin","device":"Kids iPad"}
+    shared INTEGER NOT NULL DEFAULT 0,           -- 1 = any signed-in user may apply it via ?view=<id>
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    UNIQUE(user_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_views_user ON saved_views(user_id);
@@ -0,0 +1 @@
+ALTER TABLE emby_user DROP COLUMN exclude_from_stats;
@@ -0,0 +1,2 @@
+-- Allow admins to hide media users (test accounts, family kiosks) from stats
+ALTER TABLE emby_user ADD COLUMN exclude_from_stats INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- Drop watch-for tables
+DROP INDEX IF EXISTS idx_watch_for_hits_created;
+DROP TABLE IF EXISTS watch_for_hits;
+DROP TABLE IF EXISTS watch_for_rules;
@@ -0,0 +1,28 @@
+-- Items, series or name patterns admins want to be alerted about when playback starts
+CREATE TABLE IF NOT EXISTS watch_for_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,                          -- 'item', 'series' or 'pattern'
+    value TEXT NOT NULL,                         -- item id, series id, or name glob such as 'Star Wars*'
+    server_id TEXT,                              -- NULL = any server
+    note TEXT,
+    enabled INTEGER NOT NULL DEFAULT 1,
+    created_at INTEGER NOT NULL
+);
+
+-- One row per rule/session/item match; also prevents repeat alerts while a session keeps playing
+CREATE TABLE IF NOT EXISTS watch_for_hits (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    rule_id INTEGER NOT NULL REFERENCES watch_for_rules(id) ON DELETE CASCADE,
+    server_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    item_id TEXT NOT NULL,
+    item_name TEXT,
+    user_id TEXT,
+    user_name TEXT,
+    device_name TEXT,
+    client_app TEXT,
+    created_at INTEGER NOT NULL,
+    UNIQUE(rule_id, server_id, session_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_watch_for_hits_created ON watch_for_hits(created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE cleanup_jobs DROP COLUMN stats_after;
+ALTER TABLE cleanup_jobs DROP COLUMN stats_before;
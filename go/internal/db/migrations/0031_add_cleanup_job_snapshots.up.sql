@@ -0,0 +1,3 @@
+-- Stats snapshots captured before/after cleanup and remap jobs (JSON, see audit.StatsSnapshot)
+ALTER TABLE cleanup_jobs ADD COLUMN stats_before TEXT;
+ALTER TABLE cleanup_jobs ADD COLUMN stats_after TEXT;
@@ -0,0 +1,2 @@
+-- Drop playback error tracking table
+DROP TABLE IF EXISTS playback_errors;
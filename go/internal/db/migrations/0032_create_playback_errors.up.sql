@@ -0,0 +1,21 @@
+-- Playback errors reported by media server webhooks, so chronically failing files can be found
+CREATE TABLE IF NOT EXISTS playback_errors (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_id TEXT NOT NULL,
+    server_type TEXT NOT NULL,
+    event TEXT NOT NULL,                   -- raw event name, e.g. playback.error
+    session_id TEXT,
+    item_id TEXT,
+    item_name TEXT,
+    item_type TEXT,
+    user_id TEXT,
+    user_name TEXT,
+    client_name TEXT,
+    device_name TEXT,
+    error_text TEXT,
+    occurred_at INTEGER NOT NULL           -- unix timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_playback_errors_occurred ON playback_errors(occurred_at);
+CREATE INDEX IF NOT EXISTS idx_playback_errors_item ON playback_errors(item_id);
+CREATE INDEX IF NOT EXISTS idx_playback_errors_client ON playback_errors(client_name);
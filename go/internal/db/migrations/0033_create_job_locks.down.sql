@@ -0,0 +1,2 @@
+-- Drop job lock table
+DROP TABLE IF EXISTS job_locks;
@@ -0,0 +1,8 @@
+-- DB-backed job locks so only one library sync per server runs at a time,
+-- even across processes. Locks expire unless renewed, allowing stale-lock takeover.
+CREATE TABLE IF NOT EXISTS job_locks (
+    name TEXT PRIMARY KEY,                 -- e.g. library_sync:<server_id>
+    owner TEXT NOT NULL,                   -- unique token of the current holder
+    acquired_at INTEGER NOT NULL,          -- unix timestamp
+    expires_at INTEGER NOT NULL            -- unix timestamp; renewed by the holder's heartbeat
+);
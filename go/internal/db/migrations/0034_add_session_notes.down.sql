@@ -0,0 +1,2 @@
+ALTER TABLE play_sessions DROP COLUMN tags;
+ALTER TABLE play_sessions DROP COLUMN note;
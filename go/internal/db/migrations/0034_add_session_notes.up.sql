@@ -0,0 +1,3 @@
+-- Admin notes/tags attached to a live session from Now Playing; kept on the finalized row for history search
+ALTER TABLE play_sessions ADD COLUMN note TEXT;
+ALTER TABLE play_sessions ADD COLUMN tags TEXT; -- comma-separated, lowercase
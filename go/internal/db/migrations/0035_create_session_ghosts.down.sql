@@ -0,0 +1,2 @@
+-- Drop ghost session links
+DROP TABLE IF EXISTS session_ghosts;
@@ -0,0 +1,15 @@
+-- Duplicate session objects reported for the same device+item; only the primary session is tracked
+CREATE TABLE IF NOT EXISTS session_ghosts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_id TEXT NOT NULL,
+    ghost_session_id TEXT NOT NULL,
+    primary_session_id TEXT NOT NULL,
+    user_id TEXT,
+    item_id TEXT,
+    device_name TEXT,
+    client_name TEXT,
+    detected_at INTEGER NOT NULL           -- unix timestamp
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_session_ghosts_ghost ON session_ghosts(server_id, ghost_session_id, item_id);
+CREATE INDEX IF NOT EXISTS idx_session_ghosts_primary ON session_ghosts(server_id, primary_session_id);
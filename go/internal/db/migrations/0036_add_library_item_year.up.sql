@@ -0,0 +1,2 @@
+-- Release year per library item, used for content-age stats
+ALTER TABLE library_item ADD COLUMN production_year INTEGER;
@@ -0,0 +1,2 @@
+ALTER TABLE play_sessions DROP COLUMN transcode_height;
+ALTER TABLE play_sessions DROP COLUMN transcode_width;
@@ -0,0 +1,3 @@
+-- Output resolution of video transcodes, used to spot 4K files that are never watched in 4K
+ALTER TABLE play_sessions ADD COLUMN transcode_width INTEGER;
+ALTER TABLE play_sessions ADD COLUMN transcode_height INTEGER;
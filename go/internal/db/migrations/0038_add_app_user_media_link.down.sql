@@ -0,0 +1 @@
+ALTER TABLE app_user DROP COLUMN media_user_id;
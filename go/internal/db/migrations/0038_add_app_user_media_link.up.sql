@@ -0,0 +1,2 @@
+-- Media server user an app account belongs to, so users can access their own data
+ALTER TABLE app_user ADD COLUMN media_user_id TEXT;
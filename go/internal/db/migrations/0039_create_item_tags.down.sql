@@ -0,0 +1,2 @@
+-- Drop item tags
+DROP TABLE IF EXISTS item_tags;
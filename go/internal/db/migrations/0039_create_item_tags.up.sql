@@ -0,0 +1,9 @@
+-- Tags (Emby/Jellyfin) and labels (Plex) per library item; item_id matches library_item.id
+CREATE TABLE IF NOT EXISTS item_tags (
+    item_id TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    server_id TEXT,
+    PRIMARY KEY (item_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_tags_tag ON item_tags(tag COLLATE NOCASE);
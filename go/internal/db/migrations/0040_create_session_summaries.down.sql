@@ -0,0 +1,2 @@
+-- Drop session summaries
+DROP TABLE IF EXISTS session_summaries;
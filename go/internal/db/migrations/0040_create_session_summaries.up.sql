@@ -0,0 +1,16 @@
+-- One row per finalized play session with the figures sent in session_ended notifications.
+-- Counters accumulate when a session row is reactivated and finalized again.
+CREATE TABLE IF NOT EXISTS session_summaries (
+    session_fk INTEGER PRIMARY KEY REFERENCES play_sessions(id) ON DELETE CASCADE,
+    active_seconds INTEGER NOT NULL DEFAULT 0,
+    pause_count INTEGER NOT NULL DEFAULT 0,
+    seek_count INTEGER NOT NULL DEFAULT 0,
+    bitrate_sum INTEGER NOT NULL DEFAULT 0,     -- sum of sampled bitrates (bps), for the running average
+    bitrate_samples INTEGER NOT NULL DEFAULT 0,
+    avg_bitrate_bps INTEGER,
+    max_width INTEGER,
+    max_height INTEGER,
+    finalized_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_summaries_finalized ON session_summaries(finalized_at);
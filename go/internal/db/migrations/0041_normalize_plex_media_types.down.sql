@@ -0,0 +1,5 @@
+-- Restore Plex's lower-case item types
+UPDATE library_item SET media_type = 'movie' WHERE server_type = 'plex' AND media_type = 'Movie';
+UPDATE library_item SET media_type = 'episode' WHERE server_type = 'plex' AND media_type = 'Episode';
+UPDATE library_item SET media_type = 'show' WHERE server_type = 'plex' AND media_type = 'Series';
+UPDATE library_item SET media_type = 'season' WHERE server_type = 'plex' AND media_type = 'Season';
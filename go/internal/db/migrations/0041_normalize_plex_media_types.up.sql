@@ -0,0 +1,8 @@
+-- Plex items were stored with Plex's lower-case types ("movie", "episode"), which library
+-- stats filtering on 'Movie'/'Episode' never matched. Use the names shared by all servers.
+-- Emby and Jellyfin never report these lower-case spellings, so no server filter is needed
+-- (items added by session enrichment may lack a correct server_type).
+UPDATE library_item SET media_type = 'Movie' WHERE media_type = 'movie';
+UPDATE library_item SET media_type = 'Episode' WHERE media_type = 'episode';
+UPDATE library_item SET media_type = 'Series' WHERE media_type = 'show';
+UPDATE library_item SET media_type = 'Season' WHERE media_type = 'season';
@@ -0,0 +1,6 @@
+-- Drop transcode alert tables
+DROP INDEX IF EXISTS idx_play_sessions_started_at;
+DROP INDEX IF EXISTS idx_transcode_alert_events_pending;
+DROP INDEX IF EXISTS idx_transcode_alert_events_rule;
+DROP TABLE IF EXISTS transcode_alert_events;
+DROP TABLE IF EXISTS transcode_alert_rules;
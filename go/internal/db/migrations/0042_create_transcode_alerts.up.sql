@@ -0,0 +1,33 @@
+-- Alert rules for spikes in a specific transcode reason (e.g. SubtitleCodecNotSupported after a client update)
+CREATE TABLE IF NOT EXISTS transcode_alert_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reason TEXT NOT NULL,                        -- transcode reason as reported by the server, matched case-insensitively
+    server_id TEXT,                              -- NULL = any server
+    window_minutes INTEGER NOT NULL DEFAULT 60,  -- sessions started within this window are counted
+    baseline_days INTEGER NOT NULL DEFAULT 7,    -- history the window is compared against
+    min_count INTEGER NOT NULL DEFAULT 5,        -- never alert below this many sessions
+    spike_factor REAL NOT NULL DEFAULT 3,        -- window count must reach factor x the baseline rate
+    delivery TEXT NOT NULL DEFAULT 'immediate',  -- 'immediate' or 'digest'
+    note TEXT,
+    enabled INTEGER NOT NULL DEFAULT 1,
+    created_at INTEGER NOT NULL
+);
+
+-- One row per detected spike; digest rules are delivered once a day and then stamped
+CREATE TABLE IF NOT EXISTS transcode_alert_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    rule_id INTEGER NOT NULL REFERENCES transcode_alert_rules(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    server_id TEXT,
+    window_start INTEGER NOT NULL,
+    window_end INTEGER NOT NULL,
+    session_count INTEGER NOT NULL,
+    baseline REAL NOT NULL,                      -- expected sessions per window from the baseline period
+    delivery TEXT NOT NULL,
+    delivered_at INTEGER,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_transcode_alert_events_rule ON transcode_alert_events(rule_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_transcode_alert_events_pending ON transcode_alert_events(delivered_at);
+CREATE INDEX IF NOT EXISTS idx_play_sessions_started_at ON play_sessions(started_at);
@@ -0,0 +1,3 @@
+-- Drop user goal tables
+DROP TABLE IF EXISTS user_goal_events;
+DROP TABLE IF EXISTS user_goals;
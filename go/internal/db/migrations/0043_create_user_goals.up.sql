@@ -0,0 +1,24 @@
+-- Personal watch goals owned by an app user; progress is computed from the linked media user's history
+CREATE TABLE IF NOT EXISTS user_goals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES app_user(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,                          -- 'max_hours', 'min_hours' or 'finish_series'
+    period TEXT NOT NULL DEFAULT 'week',         -- 'week' or 'month'
+    target_hours REAL,                           -- hour goals only
+    series_id TEXT,                              -- finish_series only
+    title TEXT,
+    notify INTEGER NOT NULL DEFAULT 0,           -- 1 = send nudges while the goal is running
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_goals_user ON user_goals(user_id);
+
+-- Records which nudges were already sent per goal period to avoid repeats
+CREATE TABLE IF NOT EXISTS user_goal_events (
+    goal_id INTEGER NOT NULL REFERENCES user_goals(id) ON DELETE CASCADE,
+    period_key TEXT NOT NULL,                    -- e.g. 'week:2025-01-27' or 'month:2025-01-01'
+    kind TEXT NOT NULL,                          -- 'near_limit', 'exceeded', 'behind' or 'achieved'
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (goal_id, period_key, kind)
+);
@@ -0,0 +1,2 @@
+-- Drop server version history
+DROP TABLE IF EXISTS server_versions;
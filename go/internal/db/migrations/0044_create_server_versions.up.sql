@@ -0,0 +1,12 @@
+-- Server software versions as observed by the daily version check; one row per change
+CREATE TABLE IF NOT EXISTS server_versions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server_id TEXT NOT NULL,
+    server_type TEXT NOT NULL,
+    version TEXT NOT NULL,
+    previous_version TEXT,                       -- NULL for the first version seen
+    detected_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_server_versions_server ON server_versions(server_id, detected_at);
+CREATE INDEX IF NOT EXISTS idx_server_versions_detected ON server_versions(detected_at);
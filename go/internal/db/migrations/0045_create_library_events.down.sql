@@ -0,0 +1,5 @@
+-- Drop library event tracking
+DROP TRIGGER IF EXISTS library_events_item_changed;
+DROP TRIGGER IF EXISTS library_events_item_removed;
+DROP TRIGGER IF EXISTS library_events_item_added;
+DROP TABLE IF EXISTS library_events;
@@ -0,0 +1,132 @@
+-- Library additions and removals with their resolution tier and size, so storage per quality
+-- tier can be charted over time. Sizes fall back to bitrate x runtime when the file size is
+-- unknown. A tier or size change (e.g. a file upgraded to 4K) records a removal and an addition.
+CREATE TABLE IF NOT EXISTS library_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_id TEXT NOT NULL,
+    server_id TEXT,
+    event TEXT NOT NULL,                         -- 'added' or 'removed'
+    tier TEXT NOT NULL,                          -- '4K', '1080p', '720p', 'SD' or 'Unknown'
+    size_bytes INTEGER NOT NULL,
+    occurred_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_library_events_occurred ON library_events(occurred_at);
+
+-- Items already in the library count as added when they were first synced
+INSERT INTO library_events (item_id, server_id, event, tier, size_bytes, occurred_at)
+SELECT li.id, li.server_id, 'added',
+    CASE
+        WHEN COALESCE(li.width, 0) >= 1921 THEN '4K'
+        WHEN COALESCE(li.width, 0) >= 1281 THEN '1080p'
+        WHEN COALESCE(li.width, 0) >= 1200 THEN '720p'
+        WHEN COALESCE(li.width, 0) > 0 THEN 'SD'
+        WHEN COALESCE(li.height, 0) >= 2160 THEN '4K'
+        WHEN COALESCE(li.height, 0) >= 1080 THEN '1080p'
+        WHEN COALESCE(li.height, 0) >= 720 THEN '720p'
+        WHEN COALESCE(li.height, 0) > 0 THEN 'SD'
+        ELSE 'Unknown'
+    END,
+    COALESCE(NULLIF(li.file_size_bytes, 0), CAST(COALESCE(li.bitrate_bps, 0) / 8.0 * COALESCE(li.run_time_ticks, 0) / 10000000.0 AS INTEGER)),
+    COALESCE(CAST(strftime('%s', li.created_at) AS INTEGER), CAST(strftime('%s', 'now') AS INTEGER))
+FROM library_item li
+WHERE COALESCE(NULLIF(li.file_size_bytes, 0), CAST(COALESCE(li.bitrate_bps, 0) / 8.0 * COALESCE(li.run_time_ticks, 0) / 10000000.0 AS INTEGER)) > 0;
+
+CREATE TRIGGER IF NOT EXISTS library_events_item_added AFTER INSERT ON library_item
+BEGIN
+    INSERT INTO library_events (item_id, server_id, event, tier, size_bytes, occurred_at)
+    SELECT NEW.id, NEW.server_id, 'added',
+        CASE
+            WHEN COALESCE(NEW.width, 0) >= 1921 THEN '4K'
+            WHEN COALESCE(NEW.width, 0) >= 1281 THEN '1080p'
+            WHEN COALESCE(NEW.width, 0) >= 1200 THEN '720p'
+            WHEN COALESCE(NEW.width, 0) > 0 THEN 'SD'
+            WHEN COALESCE(NEW.height, 0) >= 2160 THEN '4K'
+            WHEN COALESCE(NEW.height, 0) >= 1080 THEN '1080p'
+            WHEN COALESCE(NEW.height, 0) >= 720 THEN '720p'
+            WHEN COALESCE(NEW.height, 0) > 0 THEN 'SD'
+            ELSE 'Unknown'
+        END,
+        COALESCE(NULLIF(NEW.file_size_bytes, 0), CAST(COALESCE(NEW.bitrate_bps, 0) / 8.0 * COALESCE(NEW.run_time_ticks, 0) / 10000000.0 AS INTEGER)),
+        CAST(strftime('%s', 'now') AS INTEGER)
+    WHERE COALESCE(NULLIF(NEW.file_size_bytes, 0), CAST(COALESCE(NEW.bitrate_bps, 0) / 8.0 * COALESCE(NEW.run_time_ticks, 0) / 10000000.0 AS INTEGER)) > 0;
+END;
+
+CREATE TRIGGER IF NOT EXISTS library_events_item_removed AFTER DELETE ON library_item
+BEGIN
+    INSERT INTO library_events (item_id, server_id, event, tier, size_bytes, occurred_at)
+    SELECT OLD.id, OLD.server_id, 'removed',
+        CASE
+            WHEN COALESCE(OLD.width, 0) >= 1921 THEN '4K'
+            WHEN COALESCE(OLD.width, 0) >= 1281 THEN '1080p'
+            WHEN COALESCE(OLD.width, 0) >= 1200 THEN '720p'
+            WHEN COALESCE(OLD.width, 0) > 0 THEN 'SD'
+            WHEN COALESCE(OLD.height, 0) >= 2160 THEN '4K'
+            WHEN COALESCE(OLD.height, 0) >= 1080 THEN '1080p'
+            WHEN COALESCE(OLD.height, 0) >= 720 THEN '720p'
+            WHEN COALESCE(OLD.height, 0) > 0 THEN 'SD'
+            ELSE 'Unknown'
+        END,
+        COALESCE(NULLIF(OLD.file_size_bytes, 0), CAST(COALESCE(OLD.bitrate_bps, 0) / 8.0 * COALESCE(OLD.run_time_ticks, 0) / 10000000.0 AS INTEGER)),
+        CAST(strftime('%s', 'now') AS INTEGER)
+    WHERE COALESCE(NULLIF(OLD.file_size_bytes, 0), CAST(COALESCE(OLD.bitrate_bps, 0) / 8.0 * COALESCE(OLD.run_time_ticks, 0) / 10000000.0 AS INTEGER)) > 0;
+END;
+
+CREATE TRIGGER IF NOT EXISTS library_events_item_changed
+AFTER UPDATE OF width, height, file_size_bytes, bitrate_bps, run_time_ticks ON library_item
+WHEN COALESCE(NULLIF(OLD.file_size_bytes, 0), CAST(COALESCE(OLD.bitrate_bps, 0) / 8.0 * COALESCE(OLD.run_time_ticks, 0) / 10000000.0 AS INTEGER)) <> COALESCE(NULLIF(NEW.file_size_bytes, 0), CAST(COALESCE(NEW.bitrate_bps, 0) / 8.0 * COALESCE(NEW.run_time_ticks, 0) / 10000000.0 AS INTEGER))
+  OR (CASE
+          WHEN COALESCE(OLD.width, 0) >= 1921 THEN '4K'
+          WHEN COALESCE(OLD.width, 0) >= 1281 THEN '1080p'
+          WHEN COALESCE(OLD.width, 0) >= 1200 THEN '720p'
+          WHEN COALESCE(OLD.width, 0) > 0 THEN 'SD'
+          WHEN COALESCE(OLD.height, 0) >= 2160 THEN '4K'
+          WHEN COALESCE(OLD.height, 0) >= 1080 THEN '1080p'
+          WHEN COALESCE(OLD.height, 0) >= 720 THEN '720p'
+          WHEN COALESCE(OLD.height, 0) > 0 THEN 'SD'
+          ELSE 'Unknown'
+      END) <> (CASE
+          WHEN COALESCE(NEW.width, 0) >= 1921 THEN '4K'
+          WHEN COALESCE(NEW.width, 0) >= 1281 THEN '1080p'
+          WHEN COALESCE(NEW.width, 0) >= 1200 THEN '720p'
+          WHEN COALESCE(NEW.width, 0) > 0 THEN 'SD'
+          WHEN COALESCE(NEW.height, 0) >= 2160 THEN '4K'
+          WHEN COALESCE(NEW.height, 0) >= 1080 THEN '1080p'
+          WHEN COALESCE(NEW.height, 0) >= 720 THEN '720p'
+          WHEN COALESCE(NEW.height, 0) > 0 THEN 'SD'
+          ELSE 'Unknown'
+      END)
+BEGIN
+    INSERT INTO library_events (item_id, server_id, event, tier, size_bytes, occurred_at)
+    SELECT OLD.id, OLD.server_id, 'removed',
+        CASE
+            WHEN COALESCE(OLD.width, 0) >= 1921 THEN '4K'
+            WHEN COALESCE(OLD.width, 0) >= 1281 THEN '1080p'
+            WHEN COALESCE(OLD.width, 0) >= 1200 THEN '720p'
+            WHEN COALESCE(OLD.width, 0) > 0 THEN 'SD'
+            WHEN COALESCE(OLD.height, 0) >= 2160 THEN '4K'
+            WHEN COALESCE(OLD.height, 0) >= 1080 THEN '1080p'
+            WHEN COALESCE(OLD.height, 0) >= 720 THEN '720p'
+            WHEN COALESCE(OLD.height, 0) > 0 THEN 'SD'
+            ELSE 'Unknown'
+        END,
+        COALESCE(NULLIF(OLD.file_size_bytes, 0), CAST(COALESCE(OLD.bitrate_bps, 0) / 8.0 * COALESCE(OLD.run_time_ticks, 0) / 10000000.0 AS INTEGER)),
+        CAST(strftime('%s', 'now') AS INTEGER)
+    WHERE COALESCE(NULLIF(OLD.file_size_bytes, 0), CAST(COALESCE(OLD.bitrate_bps, 0) / 8.0 * COALESCE(OLD.run_time_ticks, 0) / 10000000.0 AS INTEGER)) > 0;
+    INSERT INTO library_events (item_id, server_id, event, tier, size_bytes, occurred_at)
+    SELECT NEW.id, NEW.server_id, 'added',
+        CASE
+            WHEN COALESCE(NEW.width, 0) >= 1921 THEN '4K'
+            WHEN COALESCE(NEW.width, 0) >= 1281 THEN '1080p'
+            WHEN COALESCE(NEW.width, 0) >= 1200 THEN '720p'
+            WHEN COALESCE(NEW.width, 0) > 0 THEN 'SD'
+            WHEN COALESCE(NEW.height, 0) >= 2160 THEN '4K'
+            WHEN COALESCE(NEW.height, 0) >= 1080 THEN '1080p'
+            WHEN COALESCE(NEW.height, 0) >= 720 THEN '720p'
+            WHEN COALESCE(NEW.height, 0) > 0 THEN 'SD'
+            ELSE 'Unknown'
+        END,
+        COALESCE(NULLIF(NEW.file_size_bytes, 0), CAST(COALESCE(NEW.bitrate_bps, 0) / 8.0 * COALESCE(NEW.run_time_ticks, 0) / 10000000.0 AS INTEGER)),
+        CAST(strftime('%s', 'now') AS INTEGER)
+    WHERE COALESCE(NULLIF(NEW.file_size_bytes, 0), CAST(COALESCE(NEW.bitrate_bps, 0) / 8.0 * COALESCE(NEW.run_time_ticks, 0) / 10000000.0 AS INTEGER)) > 0;
+END;
@@ -0,0 +1 @@
+ALTER TABLE play_sessions DROP COLUMN transcode_bitrate;
@@ -0,0 +1,2 @@
+-- Video bitrate negotiated for transcodes (bps), compared against the source bitrate per client
+ALTER TABLE play_sessions ADD COLUMN transcode_bitrate INTEGER;
@@ -0,0 +1 @@
+-- No-op: the old Plex grandparent keys are not restored.
@@ -0,0 +1,28 @@
+-- Plex episodes stored their series as the grandparent key ("/library/metadata/123") while
+-- sessions use the bare rating key; store the rating key everywhere.
+UPDATE library_item
+SET series_id = REPLACE(series_id, '/library/metadata/', '')
+WHERE series_id LIKE '/library/metadata/%';
+
+INSERT INTO series (id, name, year, created_at, updated_at)
+SELECT REPLACE(id, '/library/metadata/', ''), name, year, created_at, CURRENT_TIMESTAMP
+FROM series
+WHERE id LIKE '/library/metadata/%'
+ON CONFLICT(id) DO UPDATE SET
+    name = COALESCE(NULLIF(series.name, ''), excluded.name),
+    year = COALESCE(series.year, excluded.year);
+
+DELETE FROM series WHERE id LIKE '/library/metadata/%';
+
+UPDATE user_goals
+SET series_id = REPLACE(series_id, '/library/metadata/', '')
+WHERE series_id LIKE '/library/metadata/%';
+
+-- Every linked series gets a row, so stats can join on series_id alone.
+INSERT INTO series (id, name, year, created_at, updated_at)
+SELECT series_id, MAX(NULLIF(TRIM(series_name), '')), NULL, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+FROM library_item
+WHERE series_id IS NOT NULL AND TRIM(series_id) != ''
+GROUP BY series_id
+ON CONFLICT(id) DO UPDATE SET
+    name = COALESCE(NULLIF(series.name, ''), excluded.name);
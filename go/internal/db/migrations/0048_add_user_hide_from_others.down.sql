@@ -0,0 +1 @@
+ALTER TABLE emby_user DROP COLUMN hide_from_others;
@@ -0,0 +1,2 @@
+-- Users who opt out of appearing by name to other (non-admin) viewers; they still count in aggregates
+ALTER TABLE emby_user ADD COLUMN hide_from_others INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_play_sessions_ended_at;
+DROP INDEX IF EXISTS idx_emby_user_first_seen;
+DROP TRIGGER IF EXISTS emby_user_first_seen;
+ALTER TABLE emby_user DROP COLUMN first_seen_at;
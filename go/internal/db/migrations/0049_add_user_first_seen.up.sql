@@ -0,0 +1,15 @@
+-- When a media user was first seen, for "new user" entries in the activity feed. Existing users
+-- are dated by their first recorded session; users without playback stay undated.
+ALTER TABLE emby_user ADD COLUMN first_seen_at INTEGER;
+
+UPDATE emby_user
+SET first_seen_at = (SELECT MIN(ps.started_at) FROM play_sessions ps WHERE ps.user_id = emby_user.id);
+
+CREATE TRIGGER IF NOT EXISTS emby_user_first_seen AFTER INSERT ON emby_user
+WHEN NEW.first_seen_at IS NULL
+BEGIN
+    UPDATE emby_user SET first_seen_at = CAST(strftime('%s', 'now') AS INTEGER) WHERE id = NEW.id;
+END;
+
+CREATE INDEX IF NOT EXISTS idx_emby_user_first_seen ON emby_user(first_seen_at);
+CREATE INDEX IF NOT EXISTS idx_play_sessions_ended_at ON play_sessions(ended_at);
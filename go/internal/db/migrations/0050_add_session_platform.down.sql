@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_play_sessions_platform;
+ALTER TABLE play_sessions DROP COLUMN platform;
+ALTER TABLE play_sessions DROP COLUMN client_version;
@@ -0,0 +1,4 @@
+-- Client app version and OS platform (e.g. "Android TV" vs "Android") reported for the session
+ALTER TABLE play_sessions ADD COLUMN client_version TEXT;
+ALTER TABLE play_sessions ADD COLUMN platform TEXT;
+CREATE INDEX IF NOT EXISTS idx_play_sessions_platform ON play_sessions(platform, started_at);
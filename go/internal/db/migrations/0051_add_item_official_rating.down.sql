@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_library_item_official_rating;
+ALTER TABLE library_item DROP COLUMN official_rating;
@@ -0,0 +1,3 @@
+-- Parental rating (PG, R, TV-MA, ...) of the item as reported by the media server
+ALTER TABLE library_item ADD COLUMN official_rating TEXT;
+CREATE INDEX IF NOT EXISTS idx_library_item_official_rating ON library_item(official_rating);
@@ -0,0 +1,2 @@
+-- Drop user email subscriptions
+DROP TABLE IF EXISTS user_subscriptions;
@@ -0,0 +1,13 @@
+-- Email subscriptions of app users; one row per user and kind
+CREATE TABLE IF NOT EXISTS user_subscriptions (
+    user_id INTEGER NOT NULL REFERENCES app_user(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,                          -- 'weekly_digest'
+    email TEXT NOT NULL,
+    enabled INTEGER NOT NULL DEFAULT 1,
+    last_period_key TEXT,                        -- period of the last digest sent, e.g. 'week:2025-01-27'
+    last_sent_at INTEGER,
+    last_error TEXT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (user_id, kind)
+);
@@ -0,0 +1,14 @@
+-- Media servers added at runtime through the setup wizard; servers configured through the
+-- environment or config file take precedence over a stored server with the same id
+CREATE TABLE IF NOT EXISTS media_server_config (
+    id TEXT PRIMARY KEY,
+    type TEXT NOT NULL,                          -- 'emby' | 'jellyfin' | 'plex'
+    name TEXT NOT NULL,
+    base_url TEXT NOT NULL,
+    api_key TEXT NOT NULL,
+    external_url TEXT,
+    enabled INTEGER NOT NULL DEFAULT 1,
+    tls_insecure_skip_verify INTEGER NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
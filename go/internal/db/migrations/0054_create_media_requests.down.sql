@@ -0,0 +1,2 @@
+-- Drop synced media requests
+DROP TABLE IF EXISTS media_requests;
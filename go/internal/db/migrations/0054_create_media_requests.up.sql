@@ -0,0 +1,19 @@
+-- Media requests synced from Overseerr/Jellyseerr; rows are replaced on every sync
+CREATE TABLE IF NOT EXISTS media_requests (
+    id INTEGER PRIMARY KEY,                      -- request id in Overseerr/Jellyseerr
+    media_type TEXT NOT NULL,                    -- 'movie' | 'tv'
+    tmdb_id INTEGER,
+    tvdb_id INTEGER,
+    is_4k INTEGER NOT NULL DEFAULT 0,
+    request_status INTEGER NOT NULL,             -- 1 pending, 2 approved, 3 declined
+    media_status INTEGER NOT NULL,               -- 1 unknown .. 4 partially available, 5 available
+    requested_by TEXT,                           -- display name
+    requested_by_username TEXT,                  -- account name on the media server, when known
+    requested_at INTEGER NOT NULL,
+    added_at INTEGER,                            -- when the title became available
+    media_item_key TEXT,                         -- Emby/Jellyfin item id, lowercase without dashes
+    plex_rating_key TEXT,
+    synced_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_requests_requested_at ON media_requests(requested_at);
@@ -0,0 +1 @@
+ALTER TABLE library_item DROP COLUMN content_hash;
@@ -0,0 +1,2 @@
+-- Fingerprint of the synced metadata, so library syncs can skip items that did not change
+ALTER TABLE library_item ADD COLUMN content_hash TEXT;
@@ -0,0 +1,2 @@
+-- Drop per user stats defaults
+DROP TABLE IF EXISTS user_stats_defaults;
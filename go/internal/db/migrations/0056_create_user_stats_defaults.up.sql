@@ -0,0 +1,7 @@
+-- Per app user overrides of the global default stats timeframe and server scope
+CREATE TABLE IF NOT EXISTS user_stats_defaults (
+    user_id INTEGER PRIMARY KEY REFERENCES app_user(id) ON DELETE CASCADE,
+    timeframe TEXT,                              -- '7d', '30d', 'all-time', ...; NULL uses the global default
+    server TEXT,                                 -- server id or type ('emby', 'plex', 'jellyfin', 'all'); NULL uses the global default
+    updated_at INTEGER NOT NULL
+);
@@ -0,0 +1,2 @@
+-- Drop poster color palettes
+DROP TABLE IF EXISTS item_palettes;
@@ -0,0 +1,8 @@
+-- Poster color palettes extracted while caching images, keyed like the image cache
+CREATE TABLE IF NOT EXISTS item_palettes (
+    server_id TEXT NOT NULL,
+    item_id TEXT NOT NULL,                       -- the server's own item id (the series id for episode posters)
+    colors TEXT NOT NULL,                        -- comma separated #rrggbb, most common first
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (server_id, item_id)
+);
@@ -0,0 +1,3 @@
+-- Drop device names and merges
+DROP INDEX IF EXISTS idx_device_aliases_canonical;
+DROP TABLE IF EXISTS device_aliases;
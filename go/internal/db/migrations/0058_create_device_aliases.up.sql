@@ -0,0 +1,9 @@
+-- Admin managed device names and merges: every device_id of a merged group points at the
+-- group's canonical device_id, and all rows of a group carry the group's display name
+CREATE TABLE IF NOT EXISTS device_aliases (
+    device_id TEXT PRIMARY KEY,
+    canonical_id TEXT NOT NULL,                  -- equals device_id for the canonical device itself
+    name TEXT,                                   -- display name override; NULL shows the device id
+    updated_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_device_aliases_canonical ON device_aliases(canonical_id);
@@ -0,0 +1,6 @@
+-- Drop track switch tracking
+DROP INDEX IF EXISTS idx_play_track_changes_session;
+DROP TABLE IF EXISTS play_track_changes;
+ALTER TABLE play_sessions DROP COLUMN subtitle_language;
+ALTER TABLE play_sessions DROP COLUMN audio_default;
+ALTER TABLE play_sessions DROP COLUMN audio_language;
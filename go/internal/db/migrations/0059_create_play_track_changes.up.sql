@@ -0,0 +1,18 @@
+-- Audio and subtitle tracks selected during playback. play_sessions keeps the tracks playback
+-- started on (NULL when the server didn't say); play_track_changes records every switch seen
+-- between polls. Languages are "und" when a track has no language tag, subtitles "off" when
+-- disabled.
+ALTER TABLE play_sessions ADD COLUMN audio_language TEXT;
+ALTER TABLE play_sessions ADD COLUMN audio_default INTEGER;  -- 1 when started on the item's default audio track
+ALTER TABLE play_sessions ADD COLUMN subtitle_language TEXT;
+
+CREATE TABLE IF NOT EXISTS play_track_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_fk INTEGER NOT NULL REFERENCES play_sessions(id) ON DELETE CASCADE,
+    track TEXT NOT NULL,                      -- audio|subtitle
+    from_language TEXT NOT NULL,
+    to_language TEXT NOT NULL,
+    position_ticks INTEGER,
+    created_at INTEGER NOT NULL               -- unix seconds
+);
+CREATE INDEX IF NOT EXISTS idx_play_track_changes_session ON play_track_changes(session_fk);
@@ -0,0 +1,3 @@
+-- Drop public ids
+DROP INDEX IF EXISTS idx_public_ids_target;
+DROP TABLE IF EXISTS public_ids;
@@ -0,0 +1,12 @@
+-- Stable, URL-safe ids used in deep links (notifications, exports). target_id is the entity's
+-- current internal id; fingerprint re-finds the entity on its server after that id changes
+-- (library re-syncs, remaps). A target may have several public ids, old links keep working.
+CREATE TABLE IF NOT EXISTS public_ids (
+    public_id TEXT PRIMARY KEY,               -- e.g. itm_3kq9v2m7xw4p
+    kind TEXT NOT NULL,                       -- session|item|user|report
+    target_id TEXT NOT NULL,                  -- play_sessions.id, library_item.id, emby_user.id or saved_views.id
+    server_id TEXT NOT NULL DEFAULT '',
+    fingerprint TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL               -- unix seconds
+);
+CREATE INDEX IF NOT EXISTS idx_public_ids_target ON public_ids(kind, target_id);
@@ -0,0 +1 @@
+ALTER TABLE library_item DROP COLUMN provider_ids;
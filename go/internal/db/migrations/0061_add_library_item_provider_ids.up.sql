@@ -0,0 +1,3 @@
+-- External ids (imdb, tmdb, tvdb) per library item as sorted provider:id pairs, used to match
+-- the same title across servers
+ALTER TABLE library_item ADD COLUMN provider_ids TEXT;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_bandwidth_samples_ts;
+DROP TABLE IF EXISTS bandwidth_samples;
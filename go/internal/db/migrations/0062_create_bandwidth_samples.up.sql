@@ -0,0 +1,16 @@
+-- Outbound bitrate of each playing stream, sampled about once a minute by the session
+-- processor. seconds is the stretch of time a sample stands for, so bitrate_bps * seconds
+-- is the data delivered in it; samples of one poll share ts, which gives concurrent totals.
+CREATE TABLE IF NOT EXISTS bandwidth_samples (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ts INTEGER NOT NULL,                      -- unix seconds
+    server_id TEXT NOT NULL,
+    server_type TEXT,
+    user_id TEXT NOT NULL,
+    user_name TEXT,
+    session_id TEXT,
+    play_method TEXT,
+    bitrate_bps INTEGER NOT NULL,
+    seconds INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_bandwidth_samples_ts ON bandwidth_samples(ts);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_play_events_created_at;
+DROP INDEX IF EXISTS idx_play_intervals_session;
+DROP INDEX IF EXISTS idx_play_events_session;
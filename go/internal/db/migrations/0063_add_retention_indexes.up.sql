@@ -0,0 +1,8 @@
+-- Indexes for retention pruning
+
+-- Deleting a session cascades to its events and intervals; without these every cascade scans the table
+CREATE INDEX IF NOT EXISTS idx_play_events_session ON play_events(session_fk);
+CREATE INDEX IF NOT EXISTS idx_play_intervals_session ON play_intervals(session_fk);
+
+-- Play events expire on their own, sooner than sessions
+CREATE INDEX IF NOT EXISTS idx_play_events_created_at ON play_events(created_at);
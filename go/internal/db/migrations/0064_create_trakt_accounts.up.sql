@@ -0,0 +1,11 @@
+-- Trakt accounts linked to media users, for scrobbling their plays
+CREATE TABLE IF NOT EXISTS trakt_accounts (
+  user_id TEXT PRIMARY KEY,          -- emby_user.id
+  trakt_username TEXT,
+  access_token TEXT NOT NULL,
+  refresh_token TEXT NOT NULL,
+  expires_at INTEGER NOT NULL,       -- unix seconds
+  linked_at INTEGER NOT NULL,
+  last_scrobble_at INTEGER,
+  last_error TEXT
+);
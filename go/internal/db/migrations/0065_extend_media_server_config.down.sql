@@ -0,0 +1,3 @@
+ALTER TABLE media_server_config DROP COLUMN deleted_at;
+ALTER TABLE media_server_config DROP COLUMN proxy_url;
+ALTER TABLE media_server_config DROP COLUMN tls_ca_file;
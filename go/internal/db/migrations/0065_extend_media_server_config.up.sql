@@ -0,0 +1,6 @@
+-- Stored media servers become the source of truth managed under /admin/servers; environment
+-- servers only seed them. A deleted server keeps a tombstone row (deleted_at) so the
+-- environment doesn't add it back on the next start. api_key holds the sealed key.
+ALTER TABLE media_server_config ADD COLUMN tls_ca_file TEXT;
+ALTER TABLE media_server_config ADD COLUMN proxy_url TEXT;
+ALTER TABLE media_server_config ADD COLUMN deleted_at INTEGER;
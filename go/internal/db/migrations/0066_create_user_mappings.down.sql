@@ -0,0 +1,3 @@
+-- Drop user identity mappings
+DROP INDEX IF EXISTS idx_user_mappings_person;
+DROP TABLE IF EXISTS user_mappings;
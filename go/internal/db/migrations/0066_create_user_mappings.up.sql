@@ -0,0 +1,9 @@
+-- Admin managed identities: the accounts one person has on different media servers. Every
+-- account of a person points at the person's id, and all rows of a person carry its name
+CREATE TABLE IF NOT EXISTS user_mappings (
+    user_id TEXT PRIMARY KEY,                    -- emby_user.id, server-prefixed for non-default servers
+    person_id TEXT NOT NULL,
+    person_name TEXT NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_user_mappings_person ON user_mappings(person_id);
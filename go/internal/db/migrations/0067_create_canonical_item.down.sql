@@ -0,0 +1,3 @@
+-- Drop cross-server item groups
+DROP INDEX IF EXISTS idx_canonical_item_canonical;
+DROP TABLE IF EXISTS canonical_item;
@@ -0,0 +1,10 @@
+-- Library items that are the same title on different servers (or copies on one), matched
+-- by a shared imdb, tmdb or tvdb id. Rebuilt after library syncs; every item of a group
+-- points at the group's canonical item, and items without duplicates have no row
+CREATE TABLE IF NOT EXISTS canonical_item (
+    item_id TEXT PRIMARY KEY,                    -- library_item.id
+    canonical_id TEXT NOT NULL,                  -- library_item.id of the group's canonical item
+    match_key TEXT NOT NULL,                     -- the shared provider id, e.g. imdb:tt0111161
+    updated_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_canonical_item_canonical ON canonical_item(canonical_id);
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/md5"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +13,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"emby-analytics/internal/httpx"
 )
 
 //
@@ -65,43 +68,6 @@ func readJSON(resp *http.Response, dst any) error {
 	return nil
 }
 
-// doWithRetry performs HTTP request with exponential backoff retry
-func (c *Client) doWithRetry(req *http.Request, maxRetries int) (*http.Response, error) {
-	var lastErr error
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		// Clone the request for retry attempts
-		var reqClone *http.Request
-		if attempt > 0 {
-			reqClone = req.Clone(req.Context())
-		} else {
-			reqClone = req
-		}
-
-		resp, err := c.http.Do(reqClone)
-		if err == nil && resp.StatusCode < 500 {
-			// Success or client error (don't retry client errors)
-			return resp, nil
-		}
-
-		if resp != nil {
-			resp.Body.Close()
-		}
-
-		lastErr = err
-		if err == nil {
-			lastErr = fmt.Errorf("server error: %d", resp.StatusCode)
-		}
-
-		if attempt < maxRetries {
-			// Exponential backoff: 1s, 2s, 4s, 8s
-			backoff := time.Duration(1<<uint(attempt)) * time.Second
-			time.Sleep(backoff)
-		}
-	}
-
-	return nil, fmt.Errorf("request failed after %d attempts: %w", maxRetries+1, lastErr)
-}
-
 //
 // ---------- Client ----------
 //
@@ -109,7 +75,7 @@ func (c *Client) doWithRetry(req *http.Request, maxRetries int) (*http.Response,
 type Client struct {
 	BaseURL  string
 	APIKey   string
-	http     *http.Client
+	http     *httpx.Client
 	cache    sync.Map
 	cacheTTL time.Duration
 }
@@ -119,14 +85,7 @@ func New(baseURL, apiKey string) *Client {
 		BaseURL:  strings.TrimRight(baseURL, "/"),
 		APIKey:   apiKey,
 		cacheTTL: time.Hour, // 1 hour TTL
-		http: &http.Client{
-			Timeout: 30 * time.Second, // Increased from 15s to 30s
-			Transport: &http.Transport{
-				MaxIdleConns:       10,
-				IdleConnTimeout:    30 * time.Second,
-				DisableCompression: false,
-			},
-		},
+		http:     httpx.New(),
 	}
 }
 
@@ -148,6 +107,8 @@ type EmbyItem struct {
 	ParentIndexNumber *int   `json:"ParentIndexNumber"` // season
 	IndexNumber       *int   `json:"IndexNumber"`       // episode
 	ProductionYear    *int   `json:"ProductionYear"`    // year for movies
+	// External ids such as {"Imdb": "tt0111161"}
+	ProviderIds map[string]string `json:"ProviderIds,omitempty"`
 }
 
 type embyItemsResp struct {
@@ -234,13 +195,13 @@ func (c *Client) ItemsByIDs(ids []string) ([]EmbyItem, error) {
 	q := url.Values{}
 	q.Set("api_key", c.APIKey)
 	q.Set("Ids", strings.Join(ids, ","))
-	// Ensure we get series linkage and episode codes when requesting
-	q.Set("Fields", "SeriesId,SeriesName,ParentIndexNumber,IndexNumber")
+	// Ensure we get series linkage, episode codes and external ids when requesting
+	q.Set("Fields", "SeriesId,SeriesName,ParentIndexNumber,IndexNumber,ProviderIds")
 
 	req, _ := http.NewRequest("GET", endpoint+"?"+q.Encode(), nil)
 	req.Header.Set("X-Emby-Token", c.APIKey)
 
-	resp, err := c.doWithRetry(req, 2) // Retry up to 2 times
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -268,7 +229,7 @@ func (c *Client) SeriesGenres(seriesID string) ([]string, error) {
 	q.Set("Fields", "Genres")
 	req, _ := http.NewRequest("GET", u+"?"+q.Encode(), nil)
 	req.Header.Set("X-Emby-Token", c.APIKey)
-	resp, err := c.doWithRetry(req, 2)
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -301,6 +262,10 @@ type LibraryItem struct {
 	FilePath       string   `json:"Path,omitempty"`
 	ProductionYear *int     `json:"ProductionYear,omitempty"`
 	Genres         []string `json:"Genres,omitempty"`
+	Tags           []string `json:"Tags,omitempty"`
+	OfficialRating string   `json:"OfficialRating,omitempty"`
+
+	ProviderIds map[string]string `json:"ProviderIds,omitempty"`
 }
 
 // Detailed struct for fetching media info with codec data
@@ -312,7 +277,16 @@ type DetailedLibraryItem struct {
 	Container    string   `json:"Container"`
 	RunTimeTicks int64    `json:"RunTimeTicks"`
 	Genres       []string `json:"Genres"`
-	MediaSources []struct {
+	// Older servers return plain Tags, newer ones TagItems; both are merged
+	Tags     []string `json:"Tags"`
+	TagItems []struct {
+		Name string `json:"Name"`
+	} `json:"TagItems"`
+	OfficialRating string `json:"OfficialRating"`
+	// Requested by SearchItems and the library sync
+	ProductionYear *int              `json:"ProductionYear"`
+	ProviderIds    map[string]string `json:"ProviderIds"`
+	MediaSources   []struct {
 		Bitrate      int64  `json:"Bitrate"`
 		Size         int64  `json:"Size"`
 		Path         string `json:"Path"`
@@ -341,7 +315,7 @@ func (c *Client) FindSeriesIDByName(name string) (string, error) {
 		q.Set("Limit", "1")
 		req, _ := http.NewRequest("GET", u+"?"+q.Encode(), nil)
 		req.Header.Set("X-Emby-Token", c.APIKey)
-		resp, err := c.doWithRetry(req, 2)
+		resp, err := c.http.Do(req)
 		if err != nil {
 			return "", err
 		}
@@ -509,7 +483,7 @@ func (c *Client) GetItemsChunk(limit, page int) ([]LibraryItem, error) {
 	u := fmt.Sprintf("%s/emby/Items", c.BaseURL)
 	q := url.Values{}
 	q.Set("api_key", c.APIKey)
-	q.Set("Fields", "Path,MediaSources,MediaStreams,RunTimeTicks,Container,ProductionYear,Genres")
+	q.Set("Fields", "Path,MediaSources,MediaStreams,RunTimeTicks,Container,ProductionYear,Genres,Tags,TagItems,ProviderIds")
 	q.Set("Recursive", "true")
 	q.Set("StartIndex", fmt.Sprintf("%d", page*limit))
 	q.Set("Limit", fmt.Sprintf("%d", limit))
@@ -529,11 +503,14 @@ func (c *Client) GetItemsChunk(limit, page int) ([]LibraryItem, error) {
 	if err := readJSON(resp, &out); err != nil {
 		return nil, err
 	}
+	return libraryItemsFromDetailed(out.Items), nil
+}
 
-	// Convert to LibraryItem format, creating ONE entry per media item
+// libraryItemsFromDetailed converts to LibraryItem format, creating ONE entry per media item
+func libraryItemsFromDetailed(items []DetailedLibraryItem) []LibraryItem {
 	var result []LibraryItem
 
-	for _, item := range out.Items {
+	for _, item := range items {
 		var firstVideoCodec string
 		var firstVideoHeight *int
 		var firstVideoWidth *int
@@ -584,22 +561,50 @@ func (c *Client) GetItemsChunk(limit, page int) ([]LibraryItem, error) {
 			szPtr = &firstSize
 		}
 		result = append(result, LibraryItem{
-			Id:            item.Id, // Use original ID without suffix
-			Name:          item.Name,
-			Type:          item.Type,
-			Height:        firstVideoHeight,
-			Width:         firstVideoWidth,
-			Codec:         firstVideoCodec,
-			Container:     item.Container,
-			RunTimeTicks:  &rt,
-			BitrateBps:    brPtr,
-			FileSizeBytes: szPtr,
-			FilePath:      firstPath,
-			Genres:        item.Genres,
+			Id:             item.Id, // Use original ID without suffix
+			Name:           item.Name,
+			Type:           item.Type,
+			Height:         firstVideoHeight,
+			Width:          firstVideoWidth,
+			Codec:          firstVideoCodec,
+			Container:      item.Container,
+			RunTimeTicks:   &rt,
+			BitrateBps:     brPtr,
+			FileSizeBytes:  szPtr,
+			FilePath:       firstPath,
+			ProductionYear: item.ProductionYear,
+			Genres:         item.Genres,
+			Tags:           mergeTagNames(item.Tags, item.TagItems),
+			OfficialRating: item.OfficialRating,
+			ProviderIds:    item.ProviderIds,
 		})
 	}
 
-	return result, nil
+	return result
+}
+
+// mergeTagNames combines Tags and TagItems names, dropping blanks and case-insensitive duplicates.
+func mergeTagNames(tags []string, tagItems []struct {
+	Name string `json:"Name"`
+}) []string {
+	seen := make(map[string]bool, len(tags)+len(tagItems))
+	var out []string
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		out = append(out, name)
+	}
+	for _, t := range tags {
+		add(t)
+	}
+	for _, t := range tagItems {
+		add(t.Name)
+	}
+	return out
 }
 
 //
@@ -766,6 +771,51 @@ func (c *Client) GetUsers() ([]EmbyUser, error) {
 	return out.Items, nil
 }
 
+// ErrUnauthorized is returned by AuthenticateByName when Emby rejects the credentials.
+var ErrUnauthorized = errors.New("emby: invalid username or password")
+
+// authClientHeader identifies the app in Emby's device list for credential checks.
+const authClientHeader = `MediaBrowser Client="Emby Analytics", Device="Emby Analytics", DeviceId="emby-analytics-login", Version="1.0"`
+
+// AuthenticateByName verifies a user's own Emby credentials and returns the user. The
+// access token Emby issues is logged out again right away; only the identity is needed.
+func (c *Client) AuthenticateByName(username, password string) (*EmbyUser, error) {
+	body, _ := json.Marshal(map[string]string{"Username": username, "Pw": password})
+	req, _ := http.NewRequest("POST", c.BaseURL+"/emby/Users/AuthenticateByName", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Emby-Authorization", authClientHeader)
+
+	// Bypass retries and credential tracking: a wrong password must not count
+	// against the server's API key health.
+	resp, err := c.http.HTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		resp.Body.Close()
+		return nil, ErrUnauthorized
+	}
+	var out struct {
+		User        EmbyUser `json:"User"`
+		AccessToken string   `json:"AccessToken"`
+	}
+	if err := readJSON(resp, &out); err != nil {
+		return nil, err
+	}
+	if out.User.Id == "" {
+		return nil, ErrUnauthorized
+	}
+
+	if out.AccessToken != "" {
+		logout, _ := http.NewRequest("POST", c.BaseURL+"/emby/Sessions/Logout", nil)
+		logout.Header.Set("X-Emby-Token", out.AccessToken)
+		if r, err := c.http.HTTPClient().Do(logout); err == nil {
+			r.Body.Close()
+		}
+	}
+	return &out.User, nil
+}
+
 // GetUserData fetches user's watch status for items
 func (c *Client) GetUserData(userID string) ([]UserDataItem, error) {
 	u := fmt.Sprintf("%s/emby/Users/%s/Items", c.BaseURL, userID)
@@ -794,10 +844,41 @@ func (c *Client) GetUserData(userID string) ([]UserDataItem, error) {
 	return out.Items, nil
 }
 
+// GetResumeItems returns the user's continue-watching list: videos with a saved
+// playback position, most recently played first.
+func (c *Client) GetResumeItems(userID string, limit int) ([]UserDataItem, error) {
+	u := fmt.Sprintf("%s/emby/Users/%s/Items/Resume", c.BaseURL, userID)
+	q := url.Values{}
+	q.Set("api_key", c.APIKey)
+	q.Set("Fields", "UserData,RunTimeTicks")
+	q.Set("MediaTypes", "Video")
+	if limit > 0 {
+		q.Set("Limit", fmt.Sprintf("%d", limit))
+	}
+
+	req, _ := http.NewRequest("GET", u+"?"+q.Encode(), nil)
+	req.Header.Set("X-Emby-Token", c.APIKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Items []UserDataItem `json:"Items"`
+	}
+	if err := readJSON(resp, &out); err != nil {
+		return nil, err
+	}
+
+	return out.Items, nil
+}
+
 type UserDataItem struct {
 	Id           string `json:"Id"`
 	Name         string `json:"Name"`
 	Type         string `json:"Type"`
+	SeriesName   string `json:"SeriesName"`
 	RunTimeTicks int64  `json:"RunTimeTicks"`
 	UserData     struct {
 		Played         bool   `json:"Played"`
@@ -825,8 +906,9 @@ type EmbySession struct {
 	DurationTicks int64 `json:"DurationTicks"`
 
 	// Client/device
-	App    string `json:"Client"`
-	Device string `json:"DeviceName"`
+	App        string `json:"Client"`
+	AppVersion string `json:"ApplicationVersion,omitempty"`
+	Device     string `json:"DeviceName"`
 
 	// Playback details
 	PlayMethod string `json:"PlayMethod,omitempty"` // "Direct"/"Transcode"
@@ -848,6 +930,10 @@ type EmbySession struct {
 	SubLang  string `json:"SubLang,omitempty"`
 	SubCodec string `json:"SubCodec,omitempty"`
 
+	// Selected stream indices from PlayState; nil when not reported, -1 for none
+	AudioIndex *int `json:"AudioIndex,omitempty"`
+	SubIndex   *int `json:"SubIndex,omitempty"`
+
 	// Transcode details (when PlayMethod=Transcode)
 	TransVideoFrom string `json:"TransVideoFrom,omitempty"`
 	TransVideoTo   string `json:"TransVideoTo,omitempty"`
@@ -868,8 +954,12 @@ type EmbySession struct {
 	TransReasons      []string `json:"TransReasons,omitempty"`
 	TransCompletion   float64  `json:"TransCompletion,omitempty"`
 	TransPosTicks     int64    `json:"TransPosTicks,omitempty"`
+	TransHardware     bool     `json:"TransHardware,omitempty"`
 	RemoteAddress     string   `json:"RemoteAddress,omitempty"`
 	IsPaused          bool     `json:"IsPaused,omitempty"`
+
+	// SyncPlay group the session belongs to (if the server reports one)
+	SyncPlayGroupID string `json:"SyncPlayGroupId,omitempty"`
 }
 
 type rawSession struct {
@@ -877,9 +967,13 @@ type rawSession struct {
 	UserID         string `json:"UserId"`
 	UserName       string `json:"UserName"`
 	Client         string `json:"Client"`
+	AppVersion     string `json:"ApplicationVersion"`
 	DeviceName     string `json:"DeviceName"`
 	RemoteEndPoint string `json:"RemoteEndPoint"` // Emby provides remote IP address
 
+	// Present on builds with SyncPlay / watch-together support
+	SyncPlayGroupId string `json:"SyncPlayGroupId"`
+
 	NowPlayingItem *struct {
 		Id           string `json:"Id"`
 		Name         string `json:"Name"`
@@ -943,6 +1037,8 @@ type rawSession struct {
 		TranscodeReasons       []string `json:"TranscodeReasons"`     // e.g. AudioCodecNotSupported
 		CompletionPercentage   float64  `json:"CompletionPercentage"` // if server reports it
 		TranscodePositionTicks int64    `json:"TranscodePositionTicks"`
+		VideoDecoderIsHardware bool     `json:"VideoDecoderIsHardware"`
+		VideoEncoderIsHardware bool     `json:"VideoEncoderIsHardware"`
 	} `json:"TranscodingInfo"`
 }
 
@@ -956,7 +1052,7 @@ func (c *Client) GetActiveSessions() ([]EmbySession, error) {
 	// Some setups prefer header token; keep header for compatibility.
 	req.Header.Set("X-Emby-Token", c.APIKey)
 
-	resp, err := c.doWithRetry(req, 2) // Retry up to 2 times
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -974,11 +1070,12 @@ func (c *Client) GetActiveSessions() ([]EmbySession, error) {
 		}
 
 		es := EmbySession{
-			SessionID: rs.Id,
-			UserID:    rs.UserID,
-			UserName:  rs.UserName,
-			App:       rs.Client,
-			Device:    rs.DeviceName,
+			SessionID:  rs.Id,
+			UserID:     rs.UserID,
+			UserName:   rs.UserName,
+			App:        rs.Client,
+			AppVersion: rs.AppVersion,
+			Device:     rs.DeviceName,
 		}
 
 		// Item + duration
@@ -993,6 +1090,7 @@ func (c *Client) GetActiveSessions() ([]EmbySession, error) {
 
 		// Extract remote address
 		es.RemoteAddress = rs.RemoteEndPoint
+		es.SyncPlayGroupID = rs.SyncPlayGroupId
 
 		// Extract transcoding reasons if transcoding is active
 		if rs.TranscodingInfo != nil {
@@ -1010,6 +1108,7 @@ func (c *Client) GetActiveSessions() ([]EmbySession, error) {
 			currentAudioIndex = rs.PlayState.AudioStreamIndex
 			currentSubtitleIndex = rs.PlayState.SubtitleStreamIndex
 		}
+		es.AudioIndex, es.SubIndex = currentAudioIndex, currentSubtitleIndex
 
 		// Resolution / HDR / audio lang & channels / subs info
 		for _, ms := range rs.NowPlayingItem.MediaStreams {
@@ -1137,6 +1236,7 @@ func (c *Client) GetActiveSessions() ([]EmbySession, error) {
 			es.TransReasons = append(es.TransReasons, rs.TranscodingInfo.TranscodeReasons...)
 			es.TransCompletion = rs.TranscodingInfo.CompletionPercentage
 			es.TransPosTicks = rs.TranscodingInfo.TranscodePositionTicks
+			es.TransHardware = rs.TranscodingInfo.VideoDecoderIsHardware || rs.TranscodingInfo.VideoEncoderIsHardware
 
 			if v := rs.TranscodingInfo.VideoCodec; v != "" {
 				es.TransVideoTo = strings.ToUpper(v)
@@ -1192,24 +1292,21 @@ func (c *Client) Pause(sessionID string) error {
 	u := fmt.Sprintf("%s/emby/Sessions/%s/Playing/Pause?api_key=%s", c.BaseURL, sessionID, url.QueryEscape(c.APIKey))
 	req, _ := http.NewRequest("POST", u, nil)
 	req.Header.Set("X-Emby-Token", c.APIKey)
-	_, err := c.http.Do(req)
-	return err
+	return httpx.CommandStatus(c.http.Do(req))
 }
 
 func (c *Client) Unpause(sessionID string) error {
 	u := fmt.Sprintf("%s/emby/Sessions/%s/Playing/Unpause?api_key=%s", c.BaseURL, sessionID, url.QueryEscape(c.APIKey))
 	req, _ := http.NewRequest("POST", u, nil)
 	req.Header.Set("X-Emby-Token", c.APIKey)
-	_, err := c.http.Do(req)
-	return err
+	return httpx.CommandStatus(c.http.Do(req))
 }
 
 func (c *Client) Stop(sessionID string) error {
 	u := fmt.Sprintf("%s/emby/Sessions/%s/Playing/Stop?api_key=%s", c.BaseURL, sessionID, url.QueryEscape(c.APIKey))
 	req, _ := http.NewRequest("POST", u, nil)
 	req.Header.Set("X-Emby-Token", c.APIKey)
-	_, err := c.http.Do(req)
-	return err
+	return httpx.CommandStatus(c.http.Do(req))
 }
 
 func (c *Client) SendMessage(sessionID, header, text string, timeoutMs int) error {
@@ -1231,8 +1328,9 @@ func (c *Client) SendMessage(sessionID, header, text string, timeoutMs int) erro
 }
 
 type EmbySystemInfo struct {
-	ID   string `json:"Id"`
-	Name string `json:"ServerName"`
+	ID      string `json:"Id"`
+	Name    string `json:"ServerName"`
+	Version string `json:"Version"`
 }
 
 // GetSystemInfo fetches server information including the server ID
@@ -1256,3 +1354,343 @@ func (c *Client) GetSystemInfo() (*EmbySystemInfo, error) {
 
 	return &info, nil
 }
+
+//
+// ---------- Sync / offline downloads ----------
+//
+
+// SyncJob is a device sync (offline download) job as reported by /Sync/Jobs
+type SyncJob struct {
+	Id          string  `json:"Id"`
+	TargetId    string  `json:"TargetId"`
+	TargetName  string  `json:"TargetName"`
+	UserId      string  `json:"UserId"`
+	Name        string  `json:"Name"`
+	Status      string  `json:"Status"`
+	Progress    float64 `json:"Progress"`
+	Quality     string  `json:"Quality"`
+	DateCreated string  `json:"DateCreated"`
+}
+
+// SyncJobItem is a single item within a sync job as reported by /Sync/JobItems
+type SyncJobItem struct {
+	Id          string  `json:"Id"`
+	JobId       string  `json:"JobId"`
+	ItemId      string  `json:"ItemId"`
+	ItemName    string  `json:"ItemName"`
+	TargetId    string  `json:"TargetId"`
+	Status      string  `json:"Status"`
+	Progress    float64 `json:"Progress"`
+	DateCreated string  `json:"DateCreated"`
+}
+
+// GetSyncJobs returns all sync jobs known to the server
+func (c *Client) GetSyncJobs() ([]SyncJob, error) {
+	u := fmt.Sprintf("%s/emby/Sync/Jobs", c.BaseURL)
+	q := url.Values{}
+	q.Set("api_key", c.APIKey)
+
+	req, _ := http.NewRequest("GET", u+"?"+q.Encode(), nil)
+	req.Header.Set("X-Emby-Token", c.APIKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Items []SyncJob `json:"Items"`
+	}
+	if err := readJSON(resp, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// GetSyncJobItems returns the per-item entries of all sync jobs
+func (c *Client) GetSyncJobItems() ([]SyncJobItem, error) {
+	u := fmt.Sprintf("%s/emby/Sync/JobItems", c.BaseURL)
+	q := url.Values{}
+	q.Set("api_key", c.APIKey)
+
+	req, _ := http.NewRequest("GET", u+"?"+q.Encode(), nil)
+	req.Header.Set("X-Emby-Token", c.APIKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Items []SyncJobItem `json:"Items"`
+	}
+	if err := readJSON(resp, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+//
+// ---------- Live TV / DVR ----------
+//
+
+// LiveTvRecording is a completed or in-progress DVR recording (BaseItemDto subset)
+type LiveTvRecording struct {
+	Id            string `json:"Id"`
+	Name          string `json:"Name"`
+	SeriesName    string `json:"SeriesName"`
+	ChannelName   string `json:"ChannelName"`
+	Status        string `json:"Status"`
+	StartDate     string `json:"StartDate"`
+	EndDate       string `json:"EndDate"`
+	SeriesTimerId string `json:"SeriesTimerId"`
+	IsSeries      bool   `json:"IsSeries"`
+	MediaSources  []struct {
+		Size int64 `json:"Size"`
+	} `json:"MediaSources"`
+}
+
+// LiveTvTimer is a scheduled (one-off) recording
+type LiveTvTimer struct {
+	Id            string `json:"Id"`
+	Name          string `json:"Name"`
+	ChannelName   string `json:"ChannelName"`
+	Status        string `json:"Status"`
+	StartDate     string `json:"StartDate"`
+	EndDate       string `json:"EndDate"`
+	SeriesTimerId string `json:"SeriesTimerId"`
+}
+
+// LiveTvSeriesTimer is a series recording rule
+type LiveTvSeriesTimer struct {
+	Id               string `json:"Id"`
+	Name             string `json:"Name"`
+	ChannelName      string `json:"ChannelName"`
+	RecordNewOnly    bool   `json:"RecordNewOnly"`
+	RecordAnyChannel bool   `json:"RecordAnyChannel"`
+}
+
+// getLiveTv GETs a /LiveTv endpoint and decodes its Items array into dst
+func (c *Client) getLiveTv(path string, q url.Values, dst any) error {
+	u := fmt.Sprintf("%s/emby/LiveTv/%s", c.BaseURL, path)
+	if q == nil {
+		q = url.Values{}
+	}
+	q.Set("api_key", c.APIKey)
+
+	req, _ := http.NewRequest("GET", u+"?"+q.Encode(), nil)
+	req.Header.Set("X-Emby-Token", c.APIKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	return readJSON(resp, dst)
+}
+
+// GetRecordings returns all DVR recordings with media source sizes
+func (c *Client) GetRecordings() ([]LiveTvRecording, error) {
+	q := url.Values{}
+	q.Set("Fields", "MediaSources")
+	var out struct {
+		Items []LiveTvRecording `json:"Items"`
+	}
+	if err := c.getLiveTv("Recordings", q, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// GetTimers returns upcoming scheduled recordings
+func (c *Client) GetTimers() ([]LiveTvTimer, error) {
+	var out struct {
+		Items []LiveTvTimer `json:"Items"`
+	}
+	if err := c.getLiveTv("Timers", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// GetSeriesTimers returns configured series recording rules
+func (c *Client) GetSeriesTimers() ([]LiveTvSeriesTimer, error) {
+	var out struct {
+		Items []LiveTvSeriesTimer `json:"Items"`
+	}
+	if err := c.getLiveTv("SeriesTimers", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+//
+// ---------- Collections ----------
+//
+
+// CreateCollection creates a BoxSet containing the given items and returns its Id
+func (c *Client) CreateCollection(name string, itemIDs []string) (string, error) {
+	u := fmt.Sprintf("%s/emby/Collections", c.BaseURL)
+	q := url.Values{}
+	q.Set("api_key", c.APIKey)
+	q.Set("Name", name)
+	q.Set("Ids", strings.Join(itemIDs, ","))
+	q.Set("IsLocked", "true")
+
+	req, _ := http.NewRequest("POST", u+"?"+q.Encode(), nil)
+	req.Header.Set("X-Emby-Token", c.APIKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		Id string `json:"Id"`
+	}
+	if err := readJSON(resp, &out); err != nil {
+		return "", err
+	}
+	return out.Id, nil
+}
+
+// DeleteItem deletes an item (used for replacing generated collections)
+func (c *Client) DeleteItem(itemID string) error {
+	u := fmt.Sprintf("%s/emby/Items/%s?api_key=%s", c.BaseURL, itemID, url.QueryEscape(c.APIKey))
+	req, _ := http.NewRequest("DELETE", u, nil)
+	req.Header.Set("X-Emby-Token", c.APIKey)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("delete item %s: http %d", itemID, resp.StatusCode)
+	}
+	return nil
+}
+
+//
+// ---------- Library scans ----------
+//
+
+// RefreshLibrary starts a scan of all libraries on the server
+func (c *Client) RefreshLibrary() error {
+	u := fmt.Sprintf("%s/emby/Library/Refresh?api_key=%s", c.BaseURL, url.QueryEscape(c.APIKey))
+	req, _ := http.NewRequest("POST", u, nil)
+	req.Header.Set("X-Emby-Token", c.APIKey)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("library refresh: http %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// LibraryScanRunning reports whether the "Scan media library" scheduled task is running
+func (c *Client) LibraryScanRunning() (bool, error) {
+	u := fmt.Sprintf("%s/emby/ScheduledTasks?IsHidden=false&api_key=%s", c.BaseURL, url.QueryEscape(c.APIKey))
+	req, _ := http.NewRequest("GET", u, nil)
+	req.Header.Set("X-Emby-Token", c.APIKey)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return false, err
+	}
+	var tasks []struct {
+		Key   string `json:"Key"`
+		State string `json:"State"`
+	}
+	if err := readJSON(resp, &tasks); err != nil {
+		return false, err
+	}
+	for _, t := range tasks {
+		if t.Key == "RefreshLibrary" {
+			return t.State != "Idle", nil
+		}
+	}
+	return false, nil
+}
+
+//
+// ---------- Search ----------
+//
+
+// SearchItems searches movies and series by name, optionally narrowed by
+// production year and a provider ID such as "imdb:tt0111161".
+func (c *Client) SearchItems(term string, year int, providerID string) ([]LibraryItem, error) {
+	u := fmt.Sprintf("%s/emby/Items", c.BaseURL)
+	q := url.Values{}
+	q.Set("api_key", c.APIKey)
+	q.Set("Fields", "Path,MediaSources,MediaStreams,RunTimeTicks,Container,ProductionYear,Genres,ProviderIds")
+	q.Set("Recursive", "true")
+	q.Set("IncludeItemTypes", "Movie,Series")
+	q.Set("Limit", "50")
+	if term != "" {
+		q.Set("SearchTerm", term)
+	}
+	if year > 0 {
+		q.Set("Years", fmt.Sprintf("%d", year))
+	}
+	if provider, id, ok := strings.Cut(providerID, ":"); ok {
+		q.Set("AnyProviderIdEquals", provider+"."+id)
+	}
+
+	req, _ := http.NewRequest("GET", u+"?"+q.Encode(), nil)
+	req.Header.Set("X-Emby-Token", c.APIKey)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Items []DetailedLibraryItem `json:"Items"`
+	}
+	if err := readJSON(resp, &out); err != nil {
+		return nil, err
+	}
+	return libraryItemsFromDetailed(out.Items), nil
+}
+
+//
+// ---------- Activity Log ----------
+//
+
+// ActivityLogEntry is one row of the server's activity log
+type ActivityLogEntry struct {
+	Id            int64  `json:"Id"`
+	Name          string `json:"Name"`
+	ShortOverview string `json:"ShortOverview"`
+	Type          string `json:"Type"`
+	ItemId        string `json:"ItemId"`
+	Date          string `json:"Date"`
+	UserId        string `json:"UserId"`
+	Severity      string `json:"Severity"`
+}
+
+// GetActivityLog returns one page of activity log entries (newest first)
+// dated on or after minDate, plus the total number of matching entries.
+func (c *Client) GetActivityLog(startIndex, limit int, minDate time.Time) ([]ActivityLogEntry, int, error) {
+	u := fmt.Sprintf("%s/emby/System/ActivityLog/Entries", c.BaseURL)
+	q := url.Values{}
+	q.Set("api_key", c.APIKey)
+	q.Set("StartIndex", fmt.Sprintf("%d", startIndex))
+	q.Set("Limit", fmt.Sprintf("%d", limit))
+	if !minDate.IsZero() {
+		q.Set("MinDate", minDate.UTC().Format(time.RFC3339))
+	}
+
+	req, _ := http.NewRequest("GET", u+"?"+q.Encode(), nil)
+	req.Header.Set("X-Emby-Token", c.APIKey)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	var out struct {
+		Items            []ActivityLogEntry `json:"Items"`
+		TotalRecordCount int                `json:"TotalRecordCount"`
+	}
+	if err := readJSON(resp, &out); err != nil {
+		return nil, 0, err
+	}
+	return out.Items, out.TotalRecordCount, nil
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"emby-analytics/internal/httpx"
 	"emby-analytics/internal/logging"
 
 	"github.com/gorilla/websocket"
@@ -105,6 +106,7 @@ func (w *EmbyWS) dial() (*websocket.Conn, *http.Response, error) {
 		HandshakeTimeout:  15 * time.Second,
 		EnableCompression: true,
 		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true}, // Allow self-signed certs
+		Proxy:             httpx.ProxyFunc(w.Cfg.BaseURL),
 	}
 
 	header := http.Header{
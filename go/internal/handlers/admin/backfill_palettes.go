@@ -0,0 +1,28 @@
+package admin
+
+import (
+	"database/sql"
+	"strconv"
+
+	"emby-analytics/internal/handlers/images"
+	"emby-analytics/internal/media"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// BackfillPalettes extracts poster color palettes for library items that don't have
+// one yet, reusing posters already in the image cache and fetching the rest.
+// POST /admin/images/palettes/backfill?limit=200
+func BackfillPalettes(db *sql.DB, mgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		limit, _ := strconv.Atoi(c.Query("limit", "200"))
+		if limit <= 0 || limit > 2000 {
+			limit = 200
+		}
+		res, err := images.BackfillPalettes(c, db, mgr, limit)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(res)
+	}
+}
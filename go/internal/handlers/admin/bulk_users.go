@@ -0,0 +1,141 @@
+package admin
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type bulkMediaUsersRequest struct {
+	UserIDs []string `json:"user_ids"`
+	Action  string   `json:"action"` // "exclude", "include", "hide", "unhide" or "delete_inactive"
+	DryRun  bool     `json:"dry_run"`
+}
+
+type bulkUserFailure struct {
+	UserID string `json:"user_id"`
+	Error  string `json:"error"`
+}
+
+type bulkMediaUsersResult struct {
+	Action    string            `json:"action"`
+	DryRun    bool              `json:"dry_run"`
+	Succeeded []string          `json:"succeeded"`
+	Failed    []bulkUserFailure `json:"failed"`
+}
+
+// neverActiveCondition matches media users without any recorded playback.
+const neverActiveCondition = `
+	NOT EXISTS (SELECT 1 FROM play_sessions ps WHERE ps.user_id = emby_user.id)
+	AND NOT EXISTS (SELECT 1 FROM play_intervals pi WHERE pi.user_id = emby_user.id)
+	AND NOT EXISTS (SELECT 1 FROM lifetime_watch lw WHERE lw.user_id = emby_user.id
+		AND (COALESCE(lw.emby_ms, 0) > 0 OR COALESCE(lw.trakt_ms, 0) > 0))`
+
+// BulkMediaUsers updates many media users in one transaction:
+//   - exclude / include toggle exclude_from_stats for the given user_ids
+//   - hide / unhide toggle hide_from_others (shown as "Anonymous" to non-admins)
+//   - delete_inactive marks users that never played anything as deleted and
+//     excluded from stats, the way user sync retires users gone from the server
+//     (a user still on the server is revived by the next sync but stays excluded);
+//     with no user_ids every never-active user is targeted
+//
+// The response lists each user id under succeeded or failed.
+func BulkMediaUsers(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req bulkMediaUsersRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		action := strings.ToLower(strings.TrimSpace(req.Action))
+		switch action {
+		case "exclude", "include", "hide", "unhide":
+			if len(req.UserIDs) == 0 {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "user_ids is required"})
+			}
+		case "delete_inactive":
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "action must be one of exclude, include, hide, unhide, delete_inactive"})
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer tx.Rollback()
+
+		res := bulkMediaUsersResult{Action: action, DryRun: req.DryRun, Succeeded: []string{}, Failed: []bulkUserFailure{}}
+
+		ids := req.UserIDs
+		if action == "delete_inactive" && len(ids) == 0 {
+			rows, err := tx.Query(`SELECT id FROM emby_user WHERE deleted_at IS NULL AND ` + neverActiveCondition + ` ORDER BY id`)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			for rows.Next() {
+				var id string
+				if err := rows.Scan(&id); err == nil {
+					ids = append(ids, id)
+				}
+			}
+			rows.Close()
+		}
+
+		seen := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			id = strings.TrimSpace(id)
+			if id == "" || seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			var deleted, inactive bool
+			err := tx.QueryRow(`SELECT deleted_at IS NOT NULL, (`+neverActiveCondition+`) FROM emby_user WHERE id = ?`, id).Scan(&deleted, &inactive)
+			if err == sql.ErrNoRows {
+				res.Failed = append(res.Failed, bulkUserFailure{UserID: id, Error: "user not found"})
+				continue
+			}
+			if err != nil {
+				res.Failed = append(res.Failed, bulkUserFailure{UserID: id, Error: err.Error()})
+				continue
+			}
+			if action == "delete_inactive" && deleted {
+				res.Failed = append(res.Failed, bulkUserFailure{UserID: id, Error: "user already deleted"})
+				continue
+			}
+			if action == "delete_inactive" && !inactive {
+				res.Failed = append(res.Failed, bulkUserFailure{UserID: id, Error: "user has playback history"})
+				continue
+			}
+			if req.DryRun {
+				res.Succeeded = append(res.Succeeded, id)
+				continue
+			}
+
+			switch action {
+			case "exclude", "include":
+				flag := 0
+				if action == "exclude" {
+					flag = 1
+				}
+				_, err = tx.Exec(`UPDATE emby_user SET exclude_from_stats = ? WHERE id = ?`, flag, id)
+			case "hide", "unhide":
+				_, err = tx.Exec(`UPDATE emby_user SET hide_from_others = ? WHERE id = ?`, action == "hide", id)
+			case "delete_inactive":
+				_, err = tx.Exec(`UPDATE emby_user SET deleted_at = CURRENT_TIMESTAMP, exclude_from_stats = 1 WHERE id = ?`, id)
+			}
+			if err != nil {
+				res.Failed = append(res.Failed, bulkUserFailure{UserID: id, Error: err.Error()})
+				continue
+			}
+			res.Succeeded = append(res.Succeeded, id)
+		}
+
+		if !req.DryRun {
+			if err := tx.Commit(); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+		}
+		return c.JSON(res)
+	}
+}
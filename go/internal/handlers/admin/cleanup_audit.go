@@ -49,10 +49,17 @@ func GetCleanupJobDetails(db *sql.DB) fiber.Handler {
 			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 		}
 
+		// Before/after stats diff; nil for jobs recorded before snapshots existed
+		diff, err := audit.GetCleanupJobStatsDiff(db, jobID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
 		return c.JSON(fiber.Map{
-			"job":   job,
-			"items": items,
-			"count": len(items),
+			"job":        job,
+			"items":      items,
+			"count":      len(items),
+			"stats_diff": diff,
 		})
 	}
 }
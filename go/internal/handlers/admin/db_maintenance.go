@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"database/sql"
+
+	"emby-analytics/internal/tasks"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// StartDBMaintenance runs integrity_check, ANALYZE and VACUUM in the background.
+// Steps can be skipped with {"integrity_check": false, "analyze": false, "vacuum": false};
+// progress and the database size before/after are reported by DBMaintenanceStatus.
+// POST /admin/db/maintenance
+func StartDBMaintenance(db *sql.DB, dbPath string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		opts := tasks.DefaultDBMaintenanceOptions()
+		if len(c.Body()) > 0 {
+			if err := c.Bind().Body(&opts); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+			}
+		}
+
+		started, err := tasks.StartDBMaintenance(db, dbPath, opts, "manual")
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !started {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":    "database maintenance is already running",
+				"progress": tasks.GetDBMaintenanceProgress(),
+			})
+		}
+		return c.Status(fiber.StatusAccepted).JSON(tasks.GetDBMaintenanceProgress())
+	}
+}
+
+// DBMaintenanceStatus reports the current or most recent maintenance run along with the
+// current database file size.
+// GET /admin/db/maintenance/status
+func DBMaintenanceStatus(dbPath string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"current_size_bytes": tasks.DBFileSize(dbPath),
+			"progress":           tasks.GetDBMaintenanceProgress(),
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package admin
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ListDevices returns every device id with playback history and its name and merge group.
+// GET /admin/devices
+func ListDevices(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		devices, err := queries.ListDevices(c, db)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(devices)
+	}
+}
+
+// RenameDevice sets the display name of a device (and every id merged with it).
+// PUT /admin/devices/:id {"name": "Living Room TV"}; an empty name clears it.
+func RenameDevice(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		deviceID := strings.TrimSpace(c.Params("id"))
+		if deviceID == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "missing device id"})
+		}
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		g, err := queries.RenameDevice(c, db, deviceID, strings.TrimSpace(req.Name), time.Now().Unix())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(g)
+	}
+}
+
+// MergeDevices maps several device ids onto one canonical device, e.g. after an app
+// reinstall gave the same TV a new id.
+// POST /admin/devices/merge {"canonical_id": "...", "device_ids": ["...", "..."], "name": "optional"}
+func MergeDevices(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req struct {
+			CanonicalID string   `json:"canonical_id"`
+			DeviceIDs   []string `json:"device_ids"`
+			Name        string   `json:"name"`
+		}
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		canonical := strings.TrimSpace(req.CanonicalID)
+		ids := make([]string, 0, len(req.DeviceIDs))
+		for _, id := range req.DeviceIDs {
+			if id = strings.TrimSpace(id); id != "" && id != canonical {
+				ids = append(ids, id)
+			}
+		}
+		if canonical == "" || len(ids) == 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "canonical_id and at least one other device id are required"})
+		}
+		g, err := queries.MergeDevices(c, db, canonical, ids, strings.TrimSpace(req.Name), time.Now().Unix())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(g)
+	}
+}
+
+// UnmergeDevice takes a device out of its merged group; for the canonical device it
+// dissolves the group and clears its name.
+// DELETE /admin/devices/:id/alias
+func UnmergeDevice(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		deviceID := strings.TrimSpace(c.Params("id"))
+		if deviceID == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "missing device id"})
+		}
+		if err := queries.RemoveDeviceAlias(c, db, deviceID); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"ok": true, "device_id": deviceID})
+	}
+}
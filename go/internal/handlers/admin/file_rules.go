@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"emby-analytics/internal/config"
+)
+
+// ApplyFileRules stores the notification rules of the config file, checked by the same rules
+// as the API. A rule that matches a stored one (same kind, value and server for watch-for,
+// same reason and server for transcode alerts) updates it, otherwise it is added; rules
+// removed from the file stay until deleted through the API. Invalid rules are reported with
+// their file:line and nothing is stored.
+func ApplyFileRules(db *sql.DB, rules config.NotificationRules) error {
+	watchFor := make([]watchForRequest, len(rules.WatchFor))
+	for i, r := range rules.WatchFor {
+		if err := decodeFileRule(r, &watchFor[i]); err != nil {
+			return err
+		}
+		if msg := watchFor[i].validate(); msg != "" {
+			return fmt.Errorf("%s: watch_for: %s", r.Source, msg)
+		}
+	}
+	alerts := make([]transcodeAlertRequest, len(rules.TranscodeAlerts))
+	for i, r := range rules.TranscodeAlerts {
+		if err := decodeFileRule(r, &alerts[i]); err != nil {
+			return err
+		}
+		if msg := alerts[i].validate(); msg != "" {
+			return fmt.Errorf("%s: transcode_alerts: %s", r.Source, msg)
+		}
+	}
+	if len(watchFor) == 0 && len(alerts) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	now := time.Now().Unix()
+	for _, req := range watchFor {
+		enabled := req.Enabled == nil || *req.Enabled
+		res, err := tx.Exec(`
+			UPDATE watch_for_rules SET note = ?, enabled = ?
+			WHERE kind = ? AND value = ? AND COALESCE(server_id, '') = ?`,
+			nullIfEmpty(req.Note), enabled, req.Kind, req.Value, req.ServerID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			continue
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO watch_for_rules (kind, value, server_id, note, enabled, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			req.Kind, req.Value, nullIfEmpty(req.ServerID), nullIfEmpty(req.Note), enabled, now); err != nil {
+			return err
+		}
+	}
+	for _, req := range alerts {
+		enabled := req.Enabled == nil || *req.Enabled
+		res, err := tx.Exec(`
+			UPDATE transcode_alert_rules
+			SET window_minutes = ?, baseline_days = ?, min_count = ?, spike_factor = ?, delivery = ?, note = ?, enabled = ?
+			WHERE LOWER(reason) = LOWER(?) AND COALESCE(server_id, '') = ?`,
+			*req.WindowMinutes, *req.BaselineDays, *req.MinCount, *req.SpikeFactor, req.Delivery, nullIfEmpty(req.Note), enabled,
+			req.Reason, req.ServerID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			continue
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO transcode_alert_rules
+				(reason, server_id, window_minutes, baseline_days, min_count, spike_factor, delivery, note, enabled, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			req.Reason, nullIfEmpty(req.ServerID), *req.WindowMinutes, *req.BaselineDays, *req.MinCount, *req.SpikeFactor,
+			req.Delivery, nullIfEmpty(req.Note), enabled, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// decodeFileRule fills an API request from a config file rule, rejecting unknown fields.
+func decodeFileRule(r config.FileRule, dst any) error {
+	b, err := json.Marshal(r.Fields)
+	if err != nil {
+		return fmt.Errorf("%s: %v", r.Source, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return fmt.Errorf("%s: %v", r.Source, err)
+	}
+	return nil
+}
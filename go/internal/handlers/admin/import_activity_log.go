@@ -0,0 +1,28 @@
+package admin
+
+import (
+	"database/sql"
+
+	"emby-analytics/internal/emby"
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/tasks"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ImportActivityLog synthesizes approximate sessions from the Emby activity log
+// for the period before emby-analytics started tracking.
+// POST /admin/import/activity-log?days=30
+func ImportActivityLog(db *sql.DB, em *emby.Client, serverID string, serverType media.ServerType) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 30)
+		if days <= 0 || days > 365 {
+			days = 30
+		}
+		res, err := tasks.ImportEmbyActivityLog(db, em, serverID, serverType, days)
+		if err != nil {
+			return c.Status(502).JSON(fiber.Map{"error": err.Error(), "result": res})
+		}
+		return c.JSON(res)
+	}
+}
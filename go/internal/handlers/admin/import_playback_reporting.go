@@ -0,0 +1,81 @@
+package admin
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/tasks"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ImportPlaybackReporting converts the history of the Playback Reporting plugin into
+// sessions of an Emby or Jellyfin server. The plugin's playback_reporting.db or its backup
+// export is uploaded as the multipart field "file" or read from path on the server. The
+// plugin records times in the media server's local time; tz names that zone when it
+// differs from ours. server defaults to the primary Emby server.
+// POST /admin/import/playback-reporting?tz=Europe/Berlin&dry_run=true
+func ImportPlaybackReporting(db *sql.DB, multiMgr *media.MultiServerManager, defaultServerID string, defaultServerType media.ServerType) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		serverID, serverType := defaultServerID, defaultServerType
+		if requested := strings.TrimSpace(c.Query("server", "")); requested != "" {
+			var sc media.ServerConfig
+			var ok bool
+			if multiMgr != nil {
+				sc, ok = multiMgr.GetServerConfigs()[requested]
+			}
+			if !ok || (sc.Type != media.ServerTypeEmby && sc.Type != media.ServerTypeJellyfin) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("%q is not a configured Emby or Jellyfin server", requested)})
+			}
+			serverID, serverType = sc.ID, sc.Type
+		}
+		loc := time.Local
+		if tz := strings.TrimSpace(c.Query("tz", "")); tz != "" {
+			l, err := time.LoadLocation(tz)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown timezone: " + tz})
+			}
+			loc = l
+		}
+		dryRun := c.Query("dry_run", "false") == "true"
+
+		path, cleanup, err := importFile(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer cleanup()
+
+		rows, err := readPlaybackReporting(path, loc)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		res, err := tasks.ImportPlaybackReporting(db, rows, serverID, serverType, dryRun)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error(), "result": res})
+		}
+		return c.JSON(res)
+	}
+}
+
+// readPlaybackReporting reads a SQLite database or, failing the header check, an export.
+func readPlaybackReporting(path string, loc *time.Location) ([]tasks.PlaybackReportingRow, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	header := make([]byte, 16)
+	n, _ := f.Read(header)
+	if bytes.HasPrefix(header[:n], []byte("SQLite format 3")) {
+		return tasks.ReadPlaybackReportingDB(path, loc)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	return tasks.ReadPlaybackReportingCSV(f, loc)
+}
@@ -0,0 +1,99 @@
+package admin
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/tasks"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ImportTautulli converts the watch history of a Tautulli database into sessions of a Plex
+// server. The database is uploaded as the multipart field "file" (within MAX_BODY_MB) or,
+// for larger ones, read from a path on the server. server defaults to the only Plex server.
+// POST /admin/import/tautulli?server=plex-1&dry_run=true
+// POST /admin/import/tautulli?path=/config/tautulli.db
+func ImportTautulli(db *sql.DB, multiMgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var servers map[string]media.ServerConfig
+		if multiMgr != nil {
+			servers = multiMgr.GetServerConfigs()
+		}
+		serverID, err := tautulliServer(servers, strings.TrimSpace(c.Query("server", "")))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		dryRun := c.Query("dry_run", "false") == "true"
+
+		path, cleanup, err := importFile(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer cleanup()
+
+		src, err := tasks.OpenTautulliDB(path)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer src.Close()
+
+		res, err := tasks.ImportTautulli(db, src, serverID, dryRun)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error(), "result": res})
+		}
+		return c.JSON(res)
+	}
+}
+
+// importFile returns the path of a database uploaded as the multipart field "file", or of
+// one already on the server passed as path. cleanup removes an uploaded copy.
+func importFile(c fiber.Ctx) (path string, cleanup func(), err error) {
+	cleanup = func() {}
+	if path = strings.TrimSpace(c.Query("path", "")); path != "" {
+		_, err = os.Stat(path)
+		return path, cleanup, err
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return "", cleanup, fmt.Errorf("upload the file as 'file' or pass path")
+	}
+	tmp, err := os.CreateTemp("", "import-*")
+	if err != nil {
+		return "", cleanup, err
+	}
+	tmp.Close()
+	cleanup = func() { os.Remove(tmp.Name()) }
+	if err := c.SaveFile(fh, tmp.Name()); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	return tmp.Name(), cleanup, nil
+}
+
+// tautulliServer picks the Plex server to import into: the requested one, or the only one.
+func tautulliServer(servers map[string]media.ServerConfig, requested string) (string, error) {
+	var plex []string
+	for id, sc := range servers {
+		if sc.Type == media.ServerTypePlex {
+			plex = append(plex, id)
+		}
+	}
+	sort.Strings(plex)
+	switch {
+	case requested != "":
+		if sc, ok := servers[requested]; ok && sc.Type == media.ServerTypePlex {
+			return requested, nil
+		}
+		return "", fmt.Errorf("%q is not a configured Plex server", requested)
+	case len(plex) == 1:
+		return plex[0], nil
+	case len(plex) == 0:
+		return "", fmt.Errorf("no Plex server is configured")
+	}
+	return "", fmt.Errorf("several Plex servers are configured (%s); pass server", strings.Join(plex, ", "))
+}
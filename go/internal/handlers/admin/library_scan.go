@@ -0,0 +1,121 @@
+package admin
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"emby-analytics/internal/emby"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	libraryScanPollInterval = 15 * time.Second
+	libraryScanMaxWait      = 30 * time.Minute
+)
+
+type libraryScanRequest struct {
+	ServerIDs []string `json:"server_ids"` // empty = all enabled servers
+	Sync      bool     `json:"sync"`       // run an incremental analytics sync once the scans finish
+}
+
+type libraryScanResult struct {
+	ServerID   string `json:"server_id"`
+	ServerName string `json:"server_name"`
+	ServerType string `json:"server_type"`
+	Triggered  bool   `json:"triggered"`
+	Error      string `json:"error,omitempty"`
+}
+
+// TriggerLibraryScan starts a library scan on the upstream media servers
+// (Emby/Jellyfin /Library/Refresh, Plex section refresh). With sync=true an
+// incremental analytics sync is started once every triggered scan has finished.
+func TriggerLibraryScan(rm *RefreshManager, db *sql.DB, em *emby.Client, mgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req libraryScanRequest
+		if len(c.Body()) > 0 {
+			if err := c.Bind().Body(&req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+			}
+		}
+		if c.Query("sync") == "true" {
+			req.Sync = true
+		}
+
+		targets := map[string]media.MediaServerClient{}
+		if len(req.ServerIDs) == 0 {
+			targets = mgr.GetEnabledClients()
+		} else {
+			for _, id := range req.ServerIDs {
+				client, ok := mgr.GetClient(id)
+				if !ok || client == nil {
+					return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "unknown server " + id})
+				}
+				targets[id] = client
+			}
+		}
+
+		results := make([]libraryScanResult, 0, len(targets))
+		scanners := map[string]media.LibraryScanner{}
+		for id, client := range targets {
+			res := libraryScanResult{
+				ServerID:   id,
+				ServerName: client.GetServerName(),
+				ServerType: string(client.GetServerType()),
+			}
+			scanner, ok := client.(media.LibraryScanner)
+			if !ok {
+				res.Error = "library scans are not supported for this server type"
+				results = append(results, res)
+				continue
+			}
+			if err := scanner.RefreshLibrary(); err != nil {
+				res.Error = err.Error()
+				logging.Warn("library scan trigger failed", "server_id", id, "error", err)
+			} else {
+				res.Triggered = true
+				scanners[id] = scanner
+				logging.Info("library scan triggered", "server_id", id)
+			}
+			results = append(results, res)
+		}
+		sort.Slice(results, func(i, j int) bool { return results[i].ServerID < results[j].ServerID })
+
+		syncScheduled := req.Sync && len(scanners) > 0
+		if syncScheduled {
+			go syncAfterLibraryScans(rm, db, em, scanners)
+		}
+		return c.JSON(fiber.Map{"results": results, "sync_scheduled": syncScheduled})
+	}
+}
+
+// syncAfterLibraryScans waits for the upstream scans to finish (or time out)
+// and then starts an incremental analytics sync.
+func syncAfterLibraryScans(rm *RefreshManager, db *sql.DB, em *emby.Client, scanners map[string]media.LibraryScanner) {
+	deadline := time.Now().Add(libraryScanMaxWait)
+	for time.Now().Before(deadline) {
+		time.Sleep(libraryScanPollInterval)
+		running := false
+		for id, s := range scanners {
+			busy, err := s.LibraryScanRunning()
+			if err != nil {
+				logging.Debug("library scan status check failed", "server_id", id, "error", err)
+				continue
+			}
+			if busy {
+				running = true
+				break
+			}
+		}
+		if !running {
+			logging.Info("library scans finished; starting incremental sync")
+			rm.StartIncremental(db, em)
+			return
+		}
+	}
+	logging.Warn("library scans still running after timeout; starting incremental sync anyway", "waited", libraryScanMaxWait.String())
+	rm.StartIncremental(db, em)
+}
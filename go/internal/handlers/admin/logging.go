@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"strings"
+
+	"emby-analytics/internal/logging"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type loggingSettings struct {
+	Level        string            `json:"level"`
+	Format       string            `json:"format"`
+	Modules      map[string]string `json:"modules"`
+	KnownModules []string          `json:"known_modules"`
+}
+
+type loggingUpdateRequest struct {
+	Level   string            `json:"level"`
+	Format  string            `json:"format"`
+	Modules map[string]string `json:"modules"` // module -> level; "" or "default" clears the override
+}
+
+func currentLoggingSettings() loggingSettings {
+	return loggingSettings{
+		Level:        logging.LevelName(logging.GetLevel()),
+		Format:       logging.GetFormat(),
+		Modules:      logging.ModuleLevels(),
+		KnownModules: logging.KnownModules(),
+	}
+}
+
+// GetLogging returns the current log level, format and per-module overrides.
+func GetLogging() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return c.JSON(currentLoggingSettings())
+	}
+}
+
+// UpdateLogging changes the log level/format and per-module overrides at runtime.
+// Changes are not persisted; a restart reverts to LOG_LEVEL/LOG_FORMAT.
+func UpdateLogging() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req loggingUpdateRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+
+		// Validate everything before applying anything.
+		var level logging.Level
+		if req.Level != "" {
+			l, ok := logging.ParseLevel(req.Level)
+			if !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "level must be one of DEBUG, INFO, WARN, ERROR"})
+			}
+			level = l
+		}
+		format := strings.ToLower(strings.TrimSpace(req.Format))
+		if format != "" && format != "json" && format != "text" && format != "dev" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format must be one of json, text, dev"})
+		}
+		moduleLevels := map[string]logging.Level{}
+		for name, lvl := range req.Modules {
+			if lvl == "" || strings.EqualFold(lvl, "default") {
+				continue
+			}
+			l, ok := logging.ParseLevel(lvl)
+			if !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid level for module " + name})
+			}
+			moduleLevels[name] = l
+		}
+
+		if req.Level != "" {
+			logging.SetLevel(level)
+		}
+		if format != "" {
+			if err := logging.SetFormat(format); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+		}
+		for name, lvl := range req.Modules {
+			if l, ok := moduleLevels[name]; ok {
+				logging.SetModuleLevel(name, l)
+			} else if lvl == "" || strings.EqualFold(lvl, "default") {
+				logging.ClearModuleLevel(name)
+			}
+		}
+
+		settings := currentLoggingSettings()
+		logging.Info("Logging settings updated", "level", settings.Level, "format", settings.Format, "modules", settings.Modules)
+		return c.JSON(settings)
+	}
+}
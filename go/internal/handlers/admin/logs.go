@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/logging"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// RecentLogs returns recent WARN/ERROR log records kept in memory.
+// Query params: level (warn|error, default warn), module, since (RFC3339 or unix seconds), limit (default 200).
+func RecentLogs() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		filter := logging.RecentFilter{
+			MinLevel: logging.LevelWarn,
+			Module:   strings.TrimSpace(c.Query("module")),
+			Limit:    200,
+		}
+		if lv := c.Query("level"); lv != "" {
+			l, ok := logging.ParseLevel(lv)
+			if !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "level must be WARN or ERROR"})
+			}
+			filter.MinLevel = l
+		}
+		if since := strings.TrimSpace(c.Query("since")); since != "" {
+			if secs, err := strconv.ParseInt(since, 10, 64); err == nil {
+				filter.Since = time.Unix(secs, 0)
+			} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+				filter.Since = t
+			} else {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "since must be RFC3339 or unix seconds"})
+			}
+		}
+		if v := c.Query("limit"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				filter.Limit = n
+			}
+		}
+
+		entries := logging.Recent(filter)
+		return c.JSON(fiber.Map{
+			"entries": entries,
+			"count":   len(entries),
+		})
+	}
+}
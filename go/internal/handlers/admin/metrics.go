@@ -2,7 +2,12 @@ package admin
 
 import (
 	"database/sql"
+	"emby-analytics/internal/hostmetrics"
+	"emby-analytics/internal/httpx"
+	"emby-analytics/internal/imagecache"
 	"emby-analytics/internal/logging"
+	"emby-analytics/internal/monitors"
+	"emby-analytics/internal/tasks"
 	"runtime"
 	"time"
 
@@ -14,6 +19,16 @@ type SystemMetrics struct {
 	Database    DatabaseMetrics    `json:"database"`
 	Runtime     RuntimeMetrics     `json:"runtime"`
 	Performance PerformanceMetrics `json:"performance"`
+	HTTPClients []httpx.HostStats  `json:"http_clients"`
+	ImageCache  imagecache.Stats   `json:"image_cache"`
+	// Duplicate session objects dropped by the session processor, per server
+	GhostSessions []tasks.GhostSessionStats `json:"ghost_sessions"`
+	// Sessions warned and stopped by the paused session auto-stop, per server
+	IdleStops []monitors.IdleStopStats `json:"idle_stops"`
+	// Playbacks seen by both polling and Emby playback events, recorded once
+	IngestDedup []tasks.IngestDedupStats `json:"ingest_dedup"`
+	// NIC throughput vs session-reported bitrates; present only when HOST_METRICS_ENABLED
+	HostNetwork *hostmetrics.Snapshot `json:"host_network,omitempty"`
 }
 
 type DatabaseMetrics struct {
@@ -125,6 +140,17 @@ func SystemMetricsHandler(db *sql.DB) fiber.Handler {
 			metrics.Performance.AvgResponseTime = avgDuration.String()
 		}
 
+		// Outbound media server requests (retries, breaker state, latency)
+		metrics.HTTPClients = httpx.Snapshot()
+		metrics.ImageCache = imagecache.Default().Stats()
+		metrics.GhostSessions = tasks.GhostSessionSnapshot()
+		metrics.IdleStops = monitors.IdleStopSnapshot()
+		metrics.IngestDedup = tasks.IngestDedupSnapshot()
+		if hc := hostmetrics.Default(); hc != nil {
+			snap := hc.Snapshot()
+			metrics.HostNetwork = &snap
+		}
+
 		// Log metrics periodically
 		logging.Debug("[metrics] DB connections: open=%d, in_use=%d, idle=%d, wait_count=%d",
 			metrics.Database.OpenConnections,
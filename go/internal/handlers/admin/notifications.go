@@ -0,0 +1,59 @@
+package admin
+
+import (
+	"database/sql"
+	"strings"
+
+	"emby-analytics/internal/handlers/settings"
+	"emby-analytics/internal/notify"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GET /admin/notifications
+// Lists the configured notification channels and every event kind with whether it is sent.
+// Kinds are switched with PUT /api/settings/notify_event_<kind> {"value":"true"|"false"}.
+func NotificationStatus(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		events := make([]fiber.Map, 0, len(notify.EventKinds))
+		for _, k := range notify.EventKinds {
+			events = append(events, fiber.Map{
+				"kind":        k.Kind,
+				"description": k.Description,
+				"default":     k.Default,
+				"enabled":     settings.GetSettingBool(db, settings.NotifyEventKey(k.Kind), k.Default),
+				"setting":     settings.NotifyEventKey(k.Kind),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"channels": notify.ChannelNames(),
+			"webhook":  notify.WebhookConfigured(),
+			"events":   events,
+		})
+	}
+}
+
+// POST /admin/notifications/test?channel=discord
+// Sends a test event to one channel right away, whatever the event toggles, and reports the
+// channel's error.
+func NotificationTest() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		name := strings.ToLower(strings.TrimSpace(c.Query("channel", "")))
+		known := false
+		for _, n := range notify.ChannelNames() {
+			known = known || n == name
+		}
+		if !known {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "unknown channel: " + name, "channels": notify.ChannelNames()})
+		}
+		err := notify.SendTo(name, notify.Event{
+			Kind:    "test",
+			Title:   "Test notification",
+			Message: "Emby Analytics can reach this channel.",
+		})
+		if err != nil {
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"sent": name})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"emby-analytics/internal/logging"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"emby-analytics/internal/config"
 	"emby-analytics/internal/emby"
 	"emby-analytics/internal/media"
+	"emby-analytics/internal/queries"
 	syncpkg "emby-analytics/internal/sync"
 	"emby-analytics/internal/tasks"
 	"emby-analytics/internal/types"
@@ -51,21 +53,49 @@ func (rm *RefreshManager) get() Progress {
 
 // Start a background refresh with full sync
 func (rm *RefreshManager) Start(db *sql.DB, em *emby.Client, chunkSize int) {
+	lock := rm.acquireLibraryLock(db)
+	if lock == nil {
+		return
+	}
 	rm.set(Progress{Message: "Starting full refresh...", Running: true})
-	go rm.refreshWorker(db, em, chunkSize, false)
+	go rm.refreshWorker(db, em, chunkSize, false, lock)
 }
 
 // StartIncremental starts a background incremental sync
 func (rm *RefreshManager) StartIncremental(db *sql.DB, em *emby.Client) {
+	lock := rm.acquireLibraryLock(db)
+	if lock == nil {
+		return
+	}
 	rm.set(Progress{Message: "Starting incremental sync...", Running: true})
-	go rm.refreshWorker(db, em, 1000, true)
+	go rm.refreshWorker(db, em, 1000, true, lock)
+}
+
+// acquireLibraryLock takes the Emby server's library sync lock so a refresh never
+// overlaps another refresh, the scheduler or a library ingest. Returns nil when busy.
+func (rm *RefreshManager) acquireLibraryLock(db *sql.DB) *tasks.JobLock {
+	serverID, _ := tasks.ResolveEmbyServer(rm.cfg, rm.multiMgr)
+	lock, err := tasks.TryJobLock(db, tasks.LibrarySyncLockName(serverID))
+	if err != nil {
+		logging.Warn("refresh lock failed", "server_id", serverID, "error", err)
+		return nil
+	}
+	if lock == nil {
+		logging.Info("refresh skipped: library sync already running", "server_id", serverID)
+		if !rm.Get().Running {
+			rm.set(Progress{Error: "A library sync is already running for this server", Done: true})
+		}
+	}
+	return lock
 }
 
-func (rm *RefreshManager) refreshWorker(db *sql.DB, em *emby.Client, chunkSize int, incremental bool) {
+func (rm *RefreshManager) refreshWorker(db *sql.DB, em *emby.Client, chunkSize int, incremental bool, lock *tasks.JobLock) {
 	defer rm.triggerMultiServerSync(db)
+	defer lock.Release() // released before the multi-server ingest takes the lock again
 
 	var total int
 	var actualItemsProcessed int
+	var changed, unchanged int
 
 	if incremental {
 		// Phase 1: Incremental Library Metadata Refresh
@@ -99,7 +129,7 @@ func (rm *RefreshManager) refreshWorker(db *sql.DB, em *emby.Client, chunkSize i
 
 		// Process the incremental items
 		if len(libraryEntries) > 0 {
-			dbEntriesInserted := rm.processLibraryEntries(db, em, libraryEntries)
+			dbEntriesInserted, _ := rm.processLibraryEntries(db, em, libraryEntries, nil)
 			logging.Debug("Processed %d items, inserted/updated %d entries", len(libraryEntries), dbEntriesInserted)
 		}
 
@@ -108,10 +138,11 @@ func (rm *RefreshManager) refreshWorker(db *sql.DB, em *emby.Client, chunkSize i
 			logging.Debug("Failed to update sync timestamp: %v", err)
 		}
 
+		others := rm.syncOtherLibraries(db, Progress{Total: total, Processed: actualItemsProcessed})
 		rm.set(Progress{
 			Total:     total,
 			Processed: actualItemsProcessed,
-			Message:   fmt.Sprintf("Incremental sync complete! Processed %d items", actualItemsProcessed),
+			Message:   fmt.Sprintf("Incremental sync complete! Processed %d items, synced %d other server libraries", actualItemsProcessed, others),
 			Done:      true,
 			Running:   false,
 		})
@@ -129,6 +160,13 @@ func (rm *RefreshManager) refreshWorker(db *sql.DB, em *emby.Client, chunkSize i
 		total = count
 		rm.set(Progress{Total: total, Message: "Fetching library items...", Running: true})
 
+		// Items whose content hash matches the stored one are skipped
+		serverID, _ := tasks.ResolveEmbyServer(rm.cfg, rm.multiMgr)
+		hashes, err := tasks.LibraryItemHashes(db, serverID)
+		if err != nil {
+			logging.Warn("failed to load library item hashes - writing every item", "error", err)
+		}
+
 		// Step 2: Fetch library items in chunks
 		page := 0
 		for actualItemsProcessed < total {
@@ -144,7 +182,9 @@ func (rm *RefreshManager) refreshWorker(db *sql.DB, em *emby.Client, chunkSize i
 			}
 
 			// Process library entries
-			_ = rm.processLibraryEntries(db, em, libraryEntries)
+			written, skipped := rm.processLibraryEntries(db, em, libraryEntries, hashes)
+			changed += written
+			unchanged += skipped
 
 			// Simple counting now that we have 1:1 mapping
 			actualItemsProcessed += len(libraryEntries)
@@ -152,9 +192,11 @@ func (rm *RefreshManager) refreshWorker(db *sql.DB, em *emby.Client, chunkSize i
 			rm.set(Progress{
 				Total:     total,
 				Processed: actualItemsProcessed,
-				Message:   fmt.Sprintf("Processed %d / %d items", actualItemsProcessed, total),
+				Message:   fmt.Sprintf("Processed %d / %d items (%d changed, %d unchanged)", actualItemsProcessed, total, changed, unchanged),
 				Page:      page,
 				Running:   true,
+				Changed:   changed,
+				Unchanged: unchanged,
 			})
 			page++
 			time.Sleep(100 * time.Millisecond)
@@ -166,6 +208,12 @@ func (rm *RefreshManager) refreshWorker(db *sql.DB, em *emby.Client, chunkSize i
 		}
 	}
 
+	// Jellyfin, Plex and further Emby servers are synced with the primary library
+	var otherLibraries int
+	if !incremental {
+		otherLibraries = rm.syncOtherLibraries(db, Progress{Total: total, Processed: total, Changed: changed, Unchanged: unchanged})
+	}
+
 	// Phase 2: Play History Collection (only for full sync)
 	if !incremental {
 		rm.set(Progress{
@@ -173,6 +221,8 @@ func (rm *RefreshManager) refreshWorker(db *sql.DB, em *emby.Client, chunkSize i
 			Processed: total,
 			Message:   "Library complete! Now collecting play history...",
 			Running:   true,
+			Changed:   changed,
+			Unchanged: unchanged,
 		})
 
 		// Get all users and collect their complete history
@@ -189,6 +239,8 @@ func (rm *RefreshManager) refreshWorker(db *sql.DB, em *emby.Client, chunkSize i
 				Processed: total,
 				Message:   fmt.Sprintf("Collecting history for user %s (%d/%d)...", user.Name, userIndex+1, len(users)),
 				Running:   true,
+				Changed:   changed,
+				Unchanged: unchanged,
 			})
 
 			// Get unlimited history for this user (0 = all history)
@@ -239,13 +291,51 @@ func (rm *RefreshManager) refreshWorker(db *sql.DB, em *emby.Client, chunkSize i
 		rm.set(Progress{
 			Total:     total,
 			Processed: total,
-			Message:   fmt.Sprintf("Complete! Library: %d items, History: %d events from %d users", actualItemsProcessed, totalHistoryEvents, len(users)),
+			Message:   fmt.Sprintf("Complete! Library: %d items (%d changed, %d unchanged), Other server libraries: %d, History: %d events from %d users", actualItemsProcessed, changed, unchanged, otherLibraries, totalHistoryEvents, len(users)),
 			Done:      true,
 			Running:   false,
+			Changed:   changed,
+			Unchanged: unchanged,
 		})
 	}
 }
 
+// syncOtherLibraries ingests the library of every enabled server besides the primary Emby
+// one, so library stats cover all servers. p is the progress to report each server under;
+// it returns how many libraries were synced.
+func (rm *RefreshManager) syncOtherLibraries(db *sql.DB, p Progress) int {
+	if rm.multiMgr == nil {
+		return 0
+	}
+	primaryID, _ := tasks.ResolveEmbyServer(rm.cfg, rm.multiMgr)
+	configs := rm.multiMgr.GetServerConfigs()
+	clients := rm.multiMgr.GetAllClients()
+	var servers []media.ServerConfig
+	for id, client := range clients {
+		sc, ok := configs[id]
+		if !ok || client == nil || !sc.Enabled || id == primaryID {
+			continue
+		}
+		servers = append(servers, sc)
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+
+	synced := 0
+	for i, sc := range servers {
+		p.Message = fmt.Sprintf("Syncing %s library (%d/%d)...", sc.Name, i+1, len(servers))
+		p.Running = true
+		rm.set(p)
+		if tasks.IngestServerLibrary(db, sc, clients[sc.ID]) {
+			synced++
+		}
+	}
+	if synced > 0 {
+		tasks.CleanupOrphanedSeries(db)
+		tasks.RebuildCanonicalItems(db)
+	}
+	return synced
+}
+
 func (rm *RefreshManager) triggerMultiServerSync(db *sql.DB) {
 	if rm.multiMgr == nil {
 		return
@@ -259,9 +349,10 @@ func (rm *RefreshManager) triggerMultiServerSync(db *sql.DB) {
 	}()
 }
 
-// processLibraryEntries handles the insertion and enrichment of library items
-func (rm *RefreshManager) processLibraryEntries(db *sql.DB, em *emby.Client, libraryEntries []emby.LibraryItem) int {
-	dbEntriesInserted := 0
+// processLibraryEntries handles the insertion and enrichment of library items. Items whose
+// content hash matches hashes are skipped; it returns how many items were written and skipped.
+func (rm *RefreshManager) processLibraryEntries(db *sql.DB, em *emby.Client, libraryEntries []emby.LibraryItem, hashes map[string]string) (int, int) {
+	dbEntriesInserted, unchanged := 0, 0
 	serverID, serverType := tasks.ResolveEmbyServer(rm.cfg, rm.multiMgr)
 	// Cache SeriesID -> CSV genres to avoid repeated Emby lookups
 	seriesGenresCache := map[string]*string{}
@@ -279,6 +370,11 @@ func (rm *RefreshManager) processLibraryEntries(db *sql.DB, em *emby.Client, lib
 			// Do not insert Series into library_item
 			continue
 		}
+		hash := tasks.LibraryItemHash([]any{serverID, entry})
+		if hash != "" && hashes[entry.Id] == hash {
+			unchanged++
+			continue
+		}
 		// Extract width from height for older data compatibility
 		var width *int
 		if entry.Height != nil && *entry.Height > 0 {
@@ -295,8 +391,8 @@ func (rm *RefreshManager) processLibraryEntries(db *sql.DB, em *emby.Client, lib
 			genresCSV = &g
 		}
 		result, err := db.Exec(`
-            INSERT INTO library_item (id, server_id, server_type, item_id, name, media_type, height, width, run_time_ticks, container, video_codec, file_size_bytes, bitrate_bps, file_path, genres, created_at, updated_at)
-            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+            INSERT INTO library_item (id, server_id, server_type, item_id, name, media_type, height, width, run_time_ticks, container, video_codec, file_size_bytes, bitrate_bps, file_path, genres, production_year, provider_ids, content_hash, created_at, updated_at)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
             ON CONFLICT(id) DO UPDATE SET
                 server_id = COALESCE(NULLIF(excluded.server_id, ''), library_item.server_id),
                 server_type = COALESCE(NULLIF(excluded.server_type, ''), library_item.server_type),
@@ -312,8 +408,16 @@ func (rm *RefreshManager) processLibraryEntries(db *sql.DB, em *emby.Client, lib
                 bitrate_bps = COALESCE(excluded.bitrate_bps, library_item.bitrate_bps),
                 file_path = COALESCE(NULLIF(excluded.file_path, ''), library_item.file_path),
                 genres = COALESCE(NULLIF(excluded.genres, ''), library_item.genres),
+                production_year = COALESCE(excluded.production_year, library_item.production_year),
+                provider_ids = COALESCE(NULLIF(excluded.provider_ids, ''), library_item.provider_ids),
+                content_hash = excluded.content_hash,
                 updated_at = CURRENT_TIMESTAMP
-        `, entry.Id, serverID, string(serverType), entry.Id, entry.Name, entry.Type, entry.Height, width, entry.RunTimeTicks, entry.Container, entry.Codec, entry.FileSizeBytes, entry.BitrateBps, nullIfEmpty(entry.FilePath), genresCSV)
+        `, entry.Id, serverID, string(serverType), entry.Id, entry.Name, entry.Type, entry.Height, width, entry.RunTimeTicks, entry.Container, entry.Codec, entry.FileSizeBytes, entry.BitrateBps, nullIfEmpty(entry.FilePath), genresCSV, entry.ProductionYear, nullIfEmpty(queries.FormatProviderIDs(entry.ProviderIds)), nullIfEmpty(hash))
+		if err == nil {
+			if terr := tasks.ReplaceItemTags(db, entry.Id, serverID, entry.Tags); terr != nil {
+				logging.Debug("failed to store item tags", "item_id", entry.Id, "error", terr)
+			}
+		}
 
 		// For episodes, ensure we have proper series info
 		if entry.Type == "Episode" && em != nil {
@@ -372,7 +476,7 @@ func (rm *RefreshManager) processLibraryEntries(db *sql.DB, em *emby.Client, lib
 			}
 		}
 	}
-	return dbEntriesInserted
+	return dbEntriesInserted, unchanged
 }
 
 // helper: convert empty string to nil for COALESCE updates
@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"encoding/json"
 
+	"emby-analytics/internal/audit"
 	"emby-analytics/internal/emby"
+	"emby-analytics/internal/queries"
 	"github.com/gofiber/fiber/v3"
 )
 
@@ -57,9 +59,18 @@ func RemapItem(db *sql.DB, em *emby.Client) fiber.Handler {
 		// Optionally apply changes in a transaction
 		updatedIv, updatedSess := 0, 0
 		deletedItems := 0
+		jobID := ""
 		if apply {
+			// Audit the remap so the job details endpoint can show a before/after stats diff
+			logger, err := audit.NewCleanupLogger(db, "remap-item", "admin")
+			if err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": "Failed to initialize audit log: " + err.Error()})
+			}
+			jobID = logger.GetJobID()
+
 			tx, err := db.Begin()
 			if err != nil {
+				logger.FailJob(err.Error())
 				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 			}
 			// Repoint intervals
@@ -69,6 +80,7 @@ func RemapItem(db *sql.DB, em *emby.Client) fiber.Handler {
 				}
 			} else {
 				_ = tx.Rollback()
+				logger.FailJob(err.Error())
 				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 			}
 
@@ -79,6 +91,14 @@ func RemapItem(db *sql.DB, em *emby.Client) fiber.Handler {
 				}
 			} else {
 				_ = tx.Rollback()
+				logger.FailJob(err.Error())
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+
+			// Deep links to the old item lead to the new one
+			if err := queries.RemapPublicIDs(c, tx, queries.PublicKindItem, req.FromID, req.ToID); err != nil {
+				_ = tx.Rollback()
+				logger.FailJob(err.Error())
 				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 			}
 
@@ -89,12 +109,22 @@ func RemapItem(db *sql.DB, em *emby.Client) fiber.Handler {
 				}
 			} else {
 				_ = tx.Rollback()
+				logger.FailJob(err.Error())
 				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 			}
 
 			if err := tx.Commit(); err != nil {
+				logger.FailJob(err.Error())
 				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 			}
+
+			logger.LogItemAction("remapped", req.FromID, "", toType, req.ToID,
+				map[string]interface{}{"intervals": updatedIv, "sessions": updatedSess})
+			logger.CompleteJob(1, updatedIv+updatedSess, map[string]interface{}{
+				"updated_intervals":     updatedIv,
+				"updated_sessions":      updatedSess,
+				"deleted_library_items": deletedItems,
+			})
 		}
 
 		return c.JSON(fiber.Map{
@@ -106,6 +136,7 @@ func RemapItem(db *sql.DB, em *emby.Client) fiber.Handler {
 			"updated_intervals":     updatedIv,
 			"updated_sessions":      updatedSess,
 			"deleted_library_items": deletedItems,
+			"job_id":                jobID,
 		})
 	}
 }
@@ -0,0 +1,175 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"emby-analytics/internal/audit"
+	"emby-analytics/internal/queries"
+	"github.com/gofiber/fiber/v3"
+)
+
+// userRefTables hold media user IDs that are repointed as-is during a user remap.
+var userRefTables = []string{
+	"play_sessions",
+	"play_intervals",
+	"download_jobs",
+	"playback_errors",
+	"session_ghosts",
+	"watch_for_hits",
+}
+
+// RemapUser moves history from a stale media user ID to a new one, e.g. after a user
+// was deleted and recreated on the media server.
+// GET  /admin/remap-user?from_id=OLD&to_id=NEW   -> dry-run summary
+// POST /admin/remap-user (JSON {from_id,to_id})  -> apply remap
+func RemapUser(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		apply := string(c.Request().Header.Method()) == fiber.MethodPost
+
+		var req remapRequest
+		if apply {
+			if err := json.Unmarshal(c.Body(), &req); err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "invalid JSON body"})
+			}
+		} else {
+			req.FromID = c.Query("from_id", "")
+			req.ToID = c.Query("to_id", "")
+		}
+		if req.FromID == "" || req.ToID == "" || req.FromID == req.ToID {
+			return c.Status(400).JSON(fiber.Map{"error": "from_id and to_id required and must differ"})
+		}
+
+		// The destination must be a known user; a fresh account appears after the next user sync
+		var toName string
+		err := db.QueryRow(`SELECT name FROM emby_user WHERE id = ?`, req.ToID).Scan(&toName)
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{"error": "to_id is not a known user; run a user sync first"})
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		var fromName string
+		_ = db.QueryRow(`SELECT name FROM emby_user WHERE id = ?`, req.FromID).Scan(&fromName)
+
+		// Count references
+		refs := fiber.Map{}
+		for _, table := range userRefTables {
+			var n int
+			_ = db.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE user_id = ?`, req.FromID).Scan(&n)
+			refs[table] = n
+		}
+		var lifetimeMs int64
+		_ = db.QueryRow(`SELECT COALESCE(emby_ms, 0) FROM lifetime_watch WHERE user_id = ?`, req.FromID).Scan(&lifetimeMs)
+		refs["lifetime_watch_ms"] = lifetimeMs
+
+		updated := map[string]int{}
+		deletedUsers := 0
+		jobID := ""
+		if apply {
+			// Audit the remap so the job details endpoint can show a before/after stats diff
+			logger, err := audit.NewCleanupLogger(db, "remap-user", "admin")
+			if err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": "Failed to initialize audit log: " + err.Error()})
+			}
+			jobID = logger.GetJobID()
+
+			fail := func(tx *sql.Tx, err error) error {
+				_ = tx.Rollback()
+				logger.FailJob(err.Error())
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+
+			tx, err := db.Begin()
+			if err != nil {
+				logger.FailJob(err.Error())
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+
+			// Repoint history (keep user_name as-is to preserve display history)
+			for _, table := range userRefTables {
+				res, err := tx.Exec(`UPDATE `+table+` SET user_id = ? WHERE user_id = ?`, req.ToID, req.FromID)
+				if err != nil {
+					return fail(tx, err)
+				}
+				n, _ := res.RowsAffected()
+				updated[table] = int(n)
+			}
+
+			// Lifetime totals are summed into the destination's row
+			if _, err := tx.Exec(`
+				INSERT INTO lifetime_watch (user_id, total_ms, emby_ms, trakt_ms)
+				SELECT ?, COALESCE(total_ms, 0), COALESCE(emby_ms, 0), COALESCE(trakt_ms, 0)
+				FROM lifetime_watch WHERE user_id = ?
+				ON CONFLICT(user_id) DO UPDATE SET
+					total_ms = COALESCE(lifetime_watch.total_ms, 0) + excluded.total_ms,
+					emby_ms = COALESCE(lifetime_watch.emby_ms, 0) + excluded.emby_ms,
+					trakt_ms = COALESCE(lifetime_watch.trakt_ms, 0) + excluded.trakt_ms
+			`, req.ToID, req.FromID); err != nil {
+				return fail(tx, err)
+			}
+			if _, err := tx.Exec(`DELETE FROM lifetime_watch WHERE user_id = ?`, req.FromID); err != nil {
+				return fail(tx, err)
+			}
+
+			// A quota follows the user unless the new account already has one
+			if _, err := tx.Exec(`UPDATE OR IGNORE user_quotas SET user_id = ? WHERE user_id = ?`, req.ToID, req.FromID); err != nil {
+				return fail(tx, err)
+			}
+			if _, err := tx.Exec(`DELETE FROM user_quotas WHERE user_id = ?`, req.FromID); err != nil {
+				return fail(tx, err)
+			}
+
+			// App logins linked to the old media user follow it
+			res, err := tx.Exec(`UPDATE app_user SET media_user_id = ? WHERE media_user_id = ?`, req.ToID, req.FromID)
+			if err != nil {
+				return fail(tx, err)
+			}
+			n, _ := res.RowsAffected()
+			updated["app_user"] = int(n)
+
+			// Deep links to the old user lead to the new one
+			if err := queries.RemapPublicIDs(c, tx, queries.PublicKindUser, req.FromID, req.ToID); err != nil {
+				return fail(tx, err)
+			}
+
+			// Safe delete old user record
+			res, err = tx.Exec(`DELETE FROM emby_user WHERE id = ?`, req.FromID)
+			if err != nil {
+				return fail(tx, err)
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				deletedUsers = int(n)
+			}
+
+			if err := tx.Commit(); err != nil {
+				logger.FailJob(err.Error())
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+
+			metadata := map[string]interface{}{}
+			total := 0
+			for table, n := range updated {
+				metadata[table] = n
+				total += n
+			}
+			logger.LogItemAction("remapped", req.FromID, fromName, "User", req.ToID, metadata)
+			logger.CompleteJob(1, total, map[string]interface{}{
+				"updated":       metadata,
+				"deleted_users": deletedUsers,
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"from_id":       req.FromID,
+			"to_id":         req.ToID,
+			"from_user":     fiber.Map{"name": fromName},
+			"to_user":       fiber.Map{"name": toName},
+			"refs":          refs,
+			"applied":       apply,
+			"updated":       updated,
+			"deleted_users": deletedUsers,
+			"job_id":        jobID,
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package admin
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/tasks"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ReportTestEmail sends last week's server report right away, to the REPORT_EMAIL_TO
+// addresses or only to "to". It doesn't count as the week's scheduled report.
+// POST /admin/reports/test-email?to=admin@example.com
+func ReportTestEmail(reporter *tasks.WeeklyReporter, smtpEnabled bool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if !smtpEnabled {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "smtp not configured (SMTP_HOST, SMTP_FROM)"})
+		}
+		to := reporter.Recipients()
+		if addr := strings.TrimSpace(c.Query("to", "")); addr != "" {
+			to = []string{addr}
+		}
+		if len(to) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "no recipients: set REPORT_EMAIL_TO or pass to"})
+		}
+		report, err := reporter.Send(context.Background(), reporter.LastWeekStart(time.Now()), to)
+		if err != nil {
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"sent_to": to, "week_start": report.WeekStart, "week_end": report.WeekEnd})
+	}
+}
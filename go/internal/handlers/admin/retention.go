@@ -0,0 +1,42 @@
+package admin
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"emby-analytics/internal/config"
+	"emby-analytics/internal/tasks"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GET /admin/retention/preview?session_days=&event_days=
+// Dry run of the retention pass: counts the sessions, intervals, play events and bandwidth
+// samples the next pass would delete under the current policy. session_days and event_days
+// preview a different policy instead (0 keeps everything). Nothing is deleted.
+func RetentionPreview(db *sql.DB, cfg config.Config) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		policy := tasks.ResolveRetentionPolicy(db, cfg)
+		for key, dst := range map[string]*int{"session_days": &policy.SessionDays, "event_days": &policy.EventDays} {
+			v := c.Query(key)
+			if v == "" {
+				continue
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": key + " must be a non-negative number of days"})
+			}
+			*dst = n
+			if key == "session_days" {
+				policy.SessionDaysSource = "query"
+			}
+		}
+
+		report, err := tasks.PruneHistory(db, policy, time.Now(), true)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(report)
+	}
+}
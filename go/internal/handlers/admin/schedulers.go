@@ -0,0 +1,73 @@
+package admin
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// PausableScheduler is a background job that can be paused, e.g. while an import,
+// vacuum or backup needs the SQLite writer to itself.
+type PausableScheduler interface {
+	Pause()
+	Resume()
+	Paused() bool
+}
+
+// SchedulerState reports whether a background scheduler is paused.
+type SchedulerState struct {
+	Name   string `json:"name"`
+	Paused bool   `json:"paused"`
+}
+
+func schedulerStates(schedulers map[string]PausableScheduler) []SchedulerState {
+	out := make([]SchedulerState, 0, len(schedulers))
+	for name, s := range schedulers {
+		out = append(out, SchedulerState{Name: name, Paused: s.Paused()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// GET /admin/schedulers - The background schedulers and whether each is paused.
+func SchedulersStatus(schedulers map[string]PausableScheduler) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return c.JSON(schedulerStates(schedulers))
+	}
+}
+
+// POST /admin/schedulers/pause?name=sync,cleanup and POST /admin/schedulers/resume?name=
+// Pauses or resumes the named schedulers, or all of them when name is omitted. Pausing
+// lets a running job finish but skips every scheduled run until resumed; the state is
+// kept in memory only, so a restart resumes everything.
+func SetSchedulersPaused(schedulers map[string]PausableScheduler, paused bool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		targets := make([]PausableScheduler, 0, len(schedulers))
+		if raw := strings.TrimSpace(c.Query("name", "")); raw != "" {
+			for _, name := range strings.Split(raw, ",") {
+				name = strings.ToLower(strings.TrimSpace(name))
+				if name == "" {
+					continue
+				}
+				s, ok := schedulers[name]
+				if !ok {
+					return c.Status(404).JSON(fiber.Map{"error": "unknown scheduler: " + name, "schedulers": schedulerStates(schedulers)})
+				}
+				targets = append(targets, s)
+			}
+		} else {
+			for _, s := range schedulers {
+				targets = append(targets, s)
+			}
+		}
+		for _, s := range targets {
+			if paused {
+				s.Pause()
+			} else {
+				s.Resume()
+			}
+		}
+		return c.JSON(schedulerStates(schedulers))
+	}
+}
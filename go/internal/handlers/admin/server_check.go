@@ -0,0 +1,222 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"emby-analytics/internal/handlers/setup"
+	"emby-analytics/internal/httpx"
+	"emby-analytics/internal/media"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ServerCheck is one step of a connection test.
+type ServerCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Millis  int64  `json:"ms"`
+	Detail  string `json:"detail,omitempty"`
+	// Kind and Status classify a failure (see httpx.Diagnose)
+	Kind   string `json:"kind,omitempty"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ServerDiagnosis is the result of POST /admin/servers/:id/test.
+type ServerDiagnosis struct {
+	ServerID string `json:"server_id"`
+	Type     string `json:"type"`
+	OK       bool   `json:"ok"`
+	// Diagnosis is "ok" or the kind of the first failed check; Hint says what to look at
+	Diagnosis string        `json:"diagnosis"`
+	Hint      string        `json:"hint,omitempty"`
+	Checks    []ServerCheck `json:"checks"`
+}
+
+// POST /admin/servers/:id/test
+// Tests a server's connection and API key permissions without changing anything: a health
+// check, system info, listing users and sessions, and fetching one known item. The body
+// takes the fields of PUT /admin/servers/:id to try changes before saving them; for an id
+// that isn't configured yet it describes the new server (type, base_url, api_key).
+func TestServerConnection(db *sql.DB, multiMgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if multiMgr == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "multi-server not initialized"})
+		}
+		id := c.Params("id")
+		cur, exists := multiMgr.GetServerConfigs()[id]
+		var p serverPatch
+		if len(c.Body()) > 0 {
+			if err := c.Bind().Body(&p); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+			}
+		}
+		if !exists && p.Type == nil && p.BaseURL == nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "server not found; send type, base_url and api_key to test a new one"})
+		}
+		sc, err := p.apply(cur)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		sc.ID = id
+
+		// Connection options are per host, so trying new ones applies them to the host for
+		// the test and puts the saved ones back afterwards
+		if opts := sc.TransportOptions(); opts != cur.TransportOptions() || sc.BaseURL != cur.BaseURL {
+			if opts != (httpx.TransportOptions{}) {
+				if err := httpx.ConfigureHost(sc.BaseURL, opts); err != nil {
+					return c.JSON(diagnose(sc, []ServerCheck{{Name: "connection_options", Kind: "config", Error: err.Error()}}))
+				}
+				if exists {
+					defer httpx.ConfigureHost(cur.BaseURL, cur.TransportOptions())
+				} else {
+					defer httpx.ConfigureHost(sc.BaseURL, httpx.TransportOptions{})
+				}
+			}
+		}
+		return c.JSON(diagnose(sc, runServerChecks(db, sc)))
+	}
+}
+
+// runServerChecks runs the checks in order; after a failed health check or system info
+// request the rest are skipped, as they would fail the same way.
+func runServerChecks(db *sql.DB, sc media.ServerConfig) []ServerCheck {
+	client := setup.NewClient(sc)
+	var checks []ServerCheck
+	failed := false
+	run := func(name string, fn func() (string, error)) {
+		if failed {
+			checks = append(checks, ServerCheck{Name: name, Skipped: true, Detail: "skipped after an earlier failure"})
+			return
+		}
+		start := time.Now()
+		detail, err := fn()
+		ch := ServerCheck{Name: name, OK: err == nil, Millis: time.Since(start).Milliseconds(), Detail: detail}
+		if err != nil {
+			ch.Kind, ch.Status = httpx.Diagnose(err)
+			ch.Error = err.Error()
+		}
+		checks = append(checks, ch)
+	}
+	stopOnFailure := func() { failed = len(checks) > 0 && !checks[len(checks)-1].OK }
+
+	run("health", func() (string, error) {
+		h, err := client.CheckHealth()
+		if err != nil {
+			return "", err
+		}
+		if h == nil || !h.IsReachable {
+			msg := "server is not reachable"
+			if h != nil && h.Error != "" {
+				msg = h.Error
+			}
+			return "", errors.New(msg)
+		}
+		return fmt.Sprintf("answered in %d ms", h.ResponseTime), nil
+	})
+	stopOnFailure()
+	run("system_info", func() (string, error) {
+		info, err := client.GetSystemInfo()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s", info.Name, info.Version), nil
+	})
+	stopOnFailure()
+
+	var sessions []media.Session
+	run("users", func() (string, error) {
+		users, err := client.GetUsers()
+		return fmt.Sprintf("%d users", len(users)), err
+	})
+	run("sessions", func() (string, error) {
+		var err error
+		sessions, err = client.GetActiveSessions()
+		return fmt.Sprintf("%d active sessions", len(sessions)), err
+	})
+
+	itemID := knownItemID(db, sc.ID)
+	for _, s := range sessions {
+		if itemID == "" && s.ItemID != "" {
+			itemID = s.ItemID
+		}
+	}
+	if itemID == "" && !failed {
+		checks = append(checks, ServerCheck{Name: "item", OK: true, Skipped: true, Detail: "no known item on this server yet"})
+		return checks
+	}
+	run("item", func() (string, error) {
+		items, err := client.ItemsByIDs([]string{itemID})
+		if err != nil {
+			return "", err
+		}
+		if len(items) == 0 {
+			return fmt.Sprintf("item %s was not returned; it may have been removed", itemID), nil
+		}
+		return fmt.Sprintf("fetched %q", items[0].Name), nil
+	})
+	return checks
+}
+
+// knownItemID returns an item recently played on the server, if any.
+func knownItemID(db *sql.DB, serverID string) string {
+	var id string
+	_ = db.QueryRow(`
+		SELECT item_id FROM play_sessions
+		WHERE server_id = ? AND COALESCE(item_id, '') <> ''
+		ORDER BY started_at DESC LIMIT 1`, serverID).Scan(&id)
+	return id
+}
+
+// diagnose sums up the checks with a hint for the first failure.
+func diagnose(sc media.ServerConfig, checks []ServerCheck) ServerDiagnosis {
+	d := ServerDiagnosis{ServerID: sc.ID, Type: string(sc.Type), OK: true, Diagnosis: "ok", Checks: checks}
+	for _, ch := range checks {
+		if ch.OK || ch.Skipped {
+			continue
+		}
+		d.OK = false
+		d.Diagnosis = ch.Kind
+		d.Hint = failureHint(sc, ch)
+		break
+	}
+	return d
+}
+
+func failureHint(sc media.ServerConfig, ch ServerCheck) string {
+	host := sc.BaseURL
+	if u, err := url.Parse(sc.BaseURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	switch ch.Kind {
+	case httpx.FailureAuth:
+		if ch.Name == "users" && sc.Type == media.ServerTypePlex {
+			return "The token can't list users; use the Plex server owner's token."
+		}
+		return fmt.Sprintf("The server rejected the API key (HTTP %d) while checking %s. Check the key, and that it belongs to an administrator.", ch.Status, ch.Name)
+	case httpx.FailureTLS:
+		return "TLS verification failed. Set tls_ca_file to the CA that signed the server's certificate, or tls_insecure_skip_verify for a self-signed one, or use http://."
+	case httpx.FailureDNS:
+		return fmt.Sprintf("%s doesn't resolve from here. Check the host name in base_url (inside Docker, use the container or service name).", host)
+	case httpx.FailureNetwork:
+		return fmt.Sprintf("Couldn't connect to %s. Check the port in base_url and that the server is reachable from this machine or container.", host)
+	case httpx.FailureTimeout:
+		return fmt.Sprintf("%s didn't answer in time. Check firewalls, and whether the server is overloaded.", host)
+	case httpx.FailureProxy:
+		return "The proxy in proxy_url failed to connect. Check its address and that it allows the server's host."
+	case httpx.FailureNotFound, httpx.FailureResponse:
+		return fmt.Sprintf("%s answers, but not with the %s API. Check base_url, including any path prefix set on a reverse proxy.", host, sc.Type)
+	case httpx.FailureServer:
+		return fmt.Sprintf("The server answered with an error (HTTP %d) while checking %s; see its logs.", ch.Status, ch.Name)
+	case httpx.FailureCircuitOpen:
+		return "Requests to this host are paused after repeated failures; try again in a moment."
+	case "config":
+		return "The connection options are invalid (tls_ca_file must be a readable PEM file, proxy_url an http(s) or socks5 URL)."
+	}
+	return fmt.Sprintf("The %s check failed: %s", ch.Name, ch.Error)
+}
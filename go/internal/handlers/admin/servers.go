@@ -0,0 +1,211 @@
+package admin
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/handlers/setup"
+	"emby-analytics/internal/httpx"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/queries"
+	"emby-analytics/internal/tasks"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// serverOut is a media server as listed under /admin/servers; the API key is never returned.
+type serverOut struct {
+	ID                    string           `json:"id"`
+	Type                  media.ServerType `json:"type"`
+	Name                  string           `json:"name"`
+	BaseURL               string           `json:"base_url"`
+	ExternalURL           string           `json:"external_url,omitempty"`
+	Enabled               bool             `json:"enabled"`
+	APIKeySet             bool             `json:"api_key_set"`
+	TLSCAFile             string           `json:"tls_ca_file,omitempty"`
+	TLSInsecureSkipVerify bool             `json:"tls_insecure_skip_verify"`
+	ProxyURL              string           `json:"proxy_url,omitempty"`
+}
+
+func toServerOut(sc media.ServerConfig) serverOut {
+	return serverOut{
+		ID:                    sc.ID,
+		Type:                  sc.Type,
+		Name:                  sc.Name,
+		BaseURL:               sc.BaseURL,
+		ExternalURL:           sc.ExternalURL,
+		Enabled:               sc.Enabled,
+		APIKeySet:             sc.APIKey != "",
+		TLSCAFile:             sc.TLSCAFile,
+		TLSInsecureSkipVerify: sc.TLSInsecureSkipVerify,
+		ProxyURL:              sc.ProxyURL,
+	}
+}
+
+// GET /admin/servers
+// Lists the configured media servers with their connection settings, without API keys.
+func ListServers(multiMgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if multiMgr == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "multi-server not initialized"})
+		}
+		out := []serverOut{}
+		for _, sc := range multiMgr.GetServerConfigs() {
+			out = append(out, toServerOut(sc))
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+		return c.JSON(out)
+	}
+}
+
+// POST /admin/servers
+// Adds a media server, e.g. {"type":"jellyfin","name":"Living room","base_url":"http://jf:8096",
+// "api_key":"..."}, and starts monitoring it without a restart. Enabled servers must pass a
+// connection test unless skip_test=true.
+func CreateServer(db *sql.DB, multiMgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if multiMgr == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "multi-server not initialized"})
+		}
+		var req setup.ServerRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		sc, err := req.ServerConfig()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if _, exists := multiMgr.GetServerConfigs()[sc.ID]; exists {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "a server with id " + sc.ID + " already exists"})
+		}
+		return saveServer(c, db, multiMgr, sc, fiber.StatusCreated, "added")
+	}
+}
+
+// serverPatch holds the fields of PUT /admin/servers/:id; omitted ones keep their value.
+type serverPatch struct {
+	Type                  *string `json:"type"`
+	Name                  *string `json:"name"`
+	BaseURL               *string `json:"base_url"`
+	APIKey                *string `json:"api_key"`
+	ExternalURL           *string `json:"external_url"`
+	Enabled               *bool   `json:"enabled"`
+	TLSInsecureSkipVerify *bool   `json:"tls_insecure_skip_verify"`
+	TLSCAFile             *string `json:"tls_ca_file"`
+	ProxyURL              *string `json:"proxy_url"`
+}
+
+// PUT /admin/servers/:id
+// Changes a media server, e.g. {"api_key":"..."} or {"enabled":false}, and swaps its client
+// in place. The type can't change; enabled servers must pass a connection test unless
+// skip_test=true.
+func UpdateServer(db *sql.DB, multiMgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if multiMgr == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "multi-server not initialized"})
+		}
+		id := c.Params("id")
+		cur, ok := multiMgr.GetServerConfigs()[id]
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "server not found"})
+		}
+		var p serverPatch
+		if err := c.Bind().Body(&p); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		if p.Type != nil && !strings.EqualFold(strings.TrimSpace(*p.Type), string(cur.Type)) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "type can't be changed; add a new server instead"})
+		}
+		sc, err := p.apply(cur)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return saveServer(c, db, multiMgr, sc, fiber.StatusOK, "updated")
+	}
+}
+
+// apply validates the patch on top of cur. With a zero cur it describes a new server; the
+// caller sets its id.
+func (p serverPatch) apply(cur media.ServerConfig) (media.ServerConfig, error) {
+	// The id stays as configured, even one the setup rules wouldn't accept
+	req := setup.ServerRequest{
+		Type:                  patched(p.Type, string(cur.Type)),
+		Name:                  patched(p.Name, cur.Name),
+		BaseURL:               patched(p.BaseURL, cur.BaseURL),
+		APIKey:                patched(p.APIKey, cur.APIKey),
+		ExternalURL:           patched(p.ExternalURL, cur.ExternalURL),
+		TLSInsecureSkipVerify: cur.TLSInsecureSkipVerify,
+		TLSCAFile:             patched(p.TLSCAFile, cur.TLSCAFile),
+		ProxyURL:              patched(p.ProxyURL, cur.ProxyURL),
+		Enabled:               p.Enabled,
+	}
+	if p.TLSInsecureSkipVerify != nil {
+		req.TLSInsecureSkipVerify = *p.TLSInsecureSkipVerify
+	}
+	if req.Enabled == nil && cur.ID != "" {
+		req.Enabled = &cur.Enabled
+	}
+	sc, err := req.ServerConfig()
+	if err != nil {
+		return sc, err
+	}
+	sc.ID = cur.ID
+	return sc, nil
+}
+
+func patched(v *string, cur string) string {
+	if v == nil {
+		return cur
+	}
+	return *v
+}
+
+// saveServer tests an enabled server, stores it and hands the manager a new client.
+func saveServer(c fiber.Ctx, db *sql.DB, multiMgr *media.MultiServerManager, sc media.ServerConfig, status int, verb string) error {
+	var test *setup.ServerTest
+	if sc.Enabled && c.Query("skip_test", "false") != "true" {
+		t := setup.TestServer(sc)
+		if !t.OK {
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": t.Error, "test": t})
+		}
+		test = &t
+	}
+	if err := httpx.ConfigureHost(sc.BaseURL, sc.TransportOptions()); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := queries.SaveStoredMediaServer(c, db, sc, time.Now().Unix()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	multiMgr.AddServer(sc, setup.NewClient(sc))
+	tasks.SyncJellyfinEventIngest(sc)
+	logging.Info("Media server "+verb, "server", sc.ID, "type", sc.Type, "name", sc.Name, "enabled", sc.Enabled)
+	return c.Status(status).JSON(fiber.Map{"server": toServerOut(sc), "test": test})
+}
+
+// DELETE /admin/servers/:id
+// Stops monitoring a media server and removes it from the stored configuration, so it isn't
+// seeded again from the environment. Its history is kept; DELETE /admin/server/:id/media
+// removes that.
+func DeleteServer(db *sql.DB, multiMgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if multiMgr == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "multi-server not initialized"})
+		}
+		id := c.Params("id")
+		_, configured := multiMgr.GetServerConfigs()[id]
+		stored, err := queries.DeleteStoredMediaServer(c, db, id, time.Now().Unix())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !configured && !stored {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "server not found"})
+		}
+		multiMgr.RemoveServer(id)
+		tasks.StopJellyfinEventIngest(id)
+		logging.Info("Media server removed", "server", id)
+		return c.JSON(fiber.Map{"deleted": id})
+	}
+}
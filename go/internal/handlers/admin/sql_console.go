@@ -0,0 +1,294 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	sqlConsoleDefaultRows = 1000
+	sqlConsoleMaxRows     = 10000
+	sqlConsoleTimeout     = 10 * time.Second
+)
+
+type sqlConsoleRequest struct {
+	Query  string `json:"query"`
+	Format string `json:"format"`
+	Limit  int    `json:"limit"`
+}
+
+type sqlConsoleResponse struct {
+	Columns    []string        `json:"columns"`
+	Rows       [][]interface{} `json:"rows"`
+	RowCount   int             `json:"row_count"`
+	Truncated  bool            `json:"truncated"`
+	DurationMs int64           `json:"duration_ms"`
+}
+
+// VDBE opcodes that indicate a statement would modify the database.
+var sqlConsoleWriteOpcodes = map[string]bool{
+	"OpenWrite":   true,
+	"Insert":      true,
+	"Delete":      true,
+	"IdxInsert":   true,
+	"IdxDelete":   true,
+	"Destroy":     true,
+	"Clear":       true,
+	"CreateBtree": true,
+	"ParseSchema": true,
+	"VCreate":     true,
+	"VDestroy":    true,
+	"VUpdate":     true,
+	"Vacuum":      true,
+}
+
+// Write opcodes that act on a cursor; reads also use them to fill temporary tables for
+// DISTINCT, UNION and IN, so they are allowed on cursors opened by sqlConsoleTempOpcodes.
+var sqlConsoleCursorOpcodes = map[string]bool{
+	"Insert":    true,
+	"Delete":    true,
+	"IdxInsert": true,
+	"IdxDelete": true,
+}
+
+// Opcodes that open a temporary table or index in their P1 cursor.
+var sqlConsoleTempOpcodes = map[string]bool{
+	"OpenEphemeral": true,
+	"OpenAutoindex": true,
+	"OpenDup":       true,
+	"SorterOpen":    true,
+}
+
+// SQLConsole executes a single read-only SELECT statement and returns the rows
+// as JSON (default) or CSV. Statements are validated up front, checked via
+// EXPLAIN for write opcodes, and run on a connection with query_only enabled
+// under a statement timeout.
+func SQLConsole(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req sqlConsoleRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		if f := c.Query("format"); f != "" {
+			req.Format = f
+		}
+		query, err := normalizeConsoleQuery(req.Query)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		limit := req.Limit
+		if limit <= 0 {
+			limit = sqlConsoleDefaultRows
+		}
+		if limit > sqlConsoleMaxRows {
+			limit = sqlConsoleMaxRows
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), sqlConsoleTimeout)
+		defer cancel()
+
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer conn.Close()
+
+		if _, err := conn.ExecContext(ctx, `PRAGMA query_only = ON`); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		// Restore the pooled connection to its normal mode before it is reused.
+		defer conn.ExecContext(context.Background(), `PRAGMA query_only = OFF`)
+
+		if err := checkConsoleQueryPlan(ctx, conn, query); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		start := time.Now()
+		resp, err := runConsoleQuery(ctx, conn, query, limit)
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return c.Status(fiber.StatusRequestTimeout).JSON(fiber.Map{"error": fmt.Sprintf("query exceeded %s timeout", sqlConsoleTimeout)})
+			}
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		resp.DurationMs = time.Since(start).Milliseconds()
+
+		if strings.EqualFold(req.Format, "csv") {
+			return writeConsoleCSV(c, resp)
+		}
+		return c.JSON(resp)
+	}
+}
+
+// normalizeConsoleQuery trims the statement, drops trailing semicolons and
+// rejects anything other than a single SELECT/WITH statement.
+func normalizeConsoleQuery(raw string) (string, error) {
+	q := strings.TrimSpace(raw)
+	for strings.HasSuffix(q, ";") {
+		q = strings.TrimSpace(strings.TrimSuffix(q, ";"))
+	}
+	if q == "" {
+		return "", errors.New("query is required")
+	}
+	lower := strings.ToLower(q)
+	if !strings.HasPrefix(lower, "select") && !strings.HasPrefix(lower, "with") {
+		return "", errors.New("only SELECT or WITH statements are allowed")
+	}
+	if hasUnquotedSemicolon(q) {
+		return "", errors.New("only a single statement is allowed")
+	}
+	return q, nil
+}
+
+// hasUnquotedSemicolon reports whether q contains a ';' outside of string
+// literals, quoted identifiers and comments.
+func hasUnquotedSemicolon(q string) bool {
+	var quote byte
+	for i := 0; i < len(q); i++ {
+		ch := q[i]
+		if quote != 0 {
+			if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '[':
+			quote = ']'
+		case ch == '-' && i+1 < len(q) && q[i+1] == '-':
+			for i < len(q) && q[i] != '\n' {
+				i++
+			}
+		case ch == '/' && i+1 < len(q) && q[i+1] == '*':
+			end := strings.Index(q[i+2:], "*/")
+			if end < 0 {
+				return false
+			}
+			i += end + 3
+		case ch == ';':
+			return true
+		}
+	}
+	return false
+}
+
+// checkConsoleQueryPlan compiles the statement with EXPLAIN and rejects it if
+// the generated program contains any opcode that writes to the database. Writes
+// to temporary tables of the statement itself are allowed.
+func checkConsoleQueryPlan(ctx context.Context, conn *sql.Conn, query string) error {
+	rows, err := conn.QueryContext(ctx, "EXPLAIN "+query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	opcodeIdx, p1Idx := -1, -1
+	for i, col := range cols {
+		switch {
+		case strings.EqualFold(col, "opcode"):
+			opcodeIdx = i
+		case strings.EqualFold(col, "p1"):
+			p1Idx = i
+		}
+	}
+	if opcodeIdx < 0 || p1Idx < 0 {
+		return errors.New("unable to inspect query plan")
+	}
+
+	values := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	temp := map[string]bool{} // P1 cursors of temporary tables
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		op := fmt.Sprint(values[opcodeIdx])
+		if b, ok := values[opcodeIdx].([]byte); ok {
+			op = string(b)
+		}
+		cursor := fmt.Sprint(values[p1Idx])
+		if sqlConsoleTempOpcodes[op] {
+			temp[cursor] = true
+		}
+		if sqlConsoleWriteOpcodes[op] && !(sqlConsoleCursorOpcodes[op] && temp[cursor]) {
+			return fmt.Errorf("statement is not read-only (%s)", op)
+		}
+	}
+	return rows.Err()
+}
+
+func runConsoleQuery(ctx context.Context, conn *sql.Conn, query string, limit int) (*sqlConsoleResponse, error) {
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	resp := &sqlConsoleResponse{Columns: cols, Rows: [][]interface{}{}}
+	for rows.Next() {
+		if len(resp.Rows) >= limit {
+			resp.Truncated = true
+			break
+		}
+		values := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		resp.Rows = append(resp.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	resp.RowCount = len(resp.Rows)
+	return resp, nil
+}
+
+func writeConsoleCSV(c fiber.Ctx, resp *sqlConsoleResponse) error {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	_ = w.Write(resp.Columns)
+	record := make([]string, len(resp.Columns))
+	for _, row := range resp.Rows {
+		for i, v := range row {
+			if v == nil {
+				record[i] = ""
+			} else {
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		_ = w.Write(record)
+	}
+	w.Flush()
+	c.Set("Content-Type", "text/csv; charset=utf-8")
+	c.Set("Content-Disposition", `attachment; filename="query.csv"`)
+	return c.SendString(sb.String())
+}
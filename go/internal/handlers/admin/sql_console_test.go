@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"emby-analytics/internal/db"
+)
+
+func TestNormalizeConsoleQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr string
+	}{
+		{"select", "  SELECT 1  ", "SELECT 1", ""},
+		{"trailing semicolons", "select 1; ; ", "select 1", ""},
+		{"with", "WITH x AS (SELECT 1) SELECT * FROM x", "WITH x AS (SELECT 1) SELECT * FROM x", ""},
+		{"empty", " ; ", "", "query is required"},
+		{"not a select", "DELETE FROM emby_user", "", "only SELECT or WITH"},
+		{"pragma", "PRAGMA query_only = OFF", "", "only SELECT or WITH"},
+		{"multiple statements", "SELECT 1; DELETE FROM emby_user", "", "single statement"},
+		{"second statement after a comment", "SELECT 1 -- note\n; DROP TABLE emby_user", "", "single statement"},
+		{"semicolon in a string", "SELECT 'a;b'", "SELECT 'a;b'", ""},
+		{"semicolon in an identifier", `SELECT 1 AS "x;y", 2 AS [p;q], 3 AS ` + "`r;s`", `SELECT 1 AS "x;y", 2 AS [p;q], 3 AS ` + "`r;s`", ""},
+		{"semicolon in a line comment", "SELECT 1 -- a; b\nFROM emby_user", "SELECT 1 -- a; b\nFROM emby_user", ""},
+		{"semicolon in a block comment", "SELECT /* a; b */ 1", "SELECT /* a; b */ 1", ""},
+		{"escaped quote", "SELECT 'it''s;' ; SELECT 2", "", "single statement"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeConsoleQuery(tt.query)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("normalizeConsoleQuery = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckConsoleQueryPlan(t *testing.T) {
+	sqlDB, err := db.Open(filepath.Join(t.TempDir(), "console.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := sqlDB.Exec(`CREATE TABLE t (a INTEGER, b TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	tests := []struct {
+		name     string
+		query    string
+		readOnly bool
+	}{
+		{"select", "SELECT a, b FROM t WHERE a > 1 ORDER BY b", true},
+		{"group by", "SELECT b, COUNT(*) FROM t GROUP BY b", true},
+		{"distinct", "SELECT DISTINCT b FROM t", true},
+		{"union", "SELECT a FROM t UNION SELECT a FROM t", true},
+		{"in subquery", "SELECT a FROM t WHERE a IN (SELECT a FROM t)", true},
+		{"recursive cte", "WITH RECURSIVE n(x) AS (SELECT 1 UNION SELECT x + 1 FROM n WHERE x < 5) SELECT x FROM n", true},
+		{"delete in a cte", "WITH old AS (SELECT a FROM t) DELETE FROM t WHERE a IN (SELECT a FROM old)", false},
+		{"insert from a cte", "WITH v(a) AS (SELECT 1) INSERT INTO t (a) SELECT a FROM v", false},
+		{"update in a cte", "WITH v AS (SELECT 1) UPDATE t SET b = 'x'", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkConsoleQueryPlan(ctx, conn, tt.query)
+			if tt.readOnly && err != nil {
+				t.Errorf("rejected read-only statement: %v", err)
+			}
+			if !tt.readOnly && (err == nil || !strings.Contains(err.Error(), "not read-only")) {
+				t.Errorf("error = %v, want a read-only rejection", err)
+			}
+		})
+	}
+}
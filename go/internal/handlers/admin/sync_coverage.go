@@ -0,0 +1,130 @@
+package admin
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"emby-analytics/internal/handlers/settings"
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/tasks"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const libraryReportedSettingPrefix = "library_sync_items_"
+
+// SyncCoverageMissing counts synced movies and episodes lacking a metadata field.
+type SyncCoverageMissing struct {
+	Runtime int `json:"runtime"`
+	Size    int `json:"size"`
+	Genres  int `json:"genres"`
+}
+
+// ServerSyncCoverage is the library sync state of one server.
+type ServerSyncCoverage struct {
+	ServerID    string `json:"server_id"`
+	ServerName  string `json:"server_name"`
+	ServerType  string `json:"server_type"`
+	SyncEnabled bool   `json:"sync_enabled"`
+	// LastSyncAt is the last successful library sync; nil when the library was never synced
+	LastSyncAt *time.Time `json:"last_sync_at"`
+	AgeHours   *float64   `json:"age_hours"`
+	Stale      bool       `json:"stale"`
+	// ReportedItems is the server's movie and episode count at the last sync; nil for syncs
+	// that predate it being recorded
+	ReportedItems *int                `json:"reported_items"`
+	SyncedItems   int                 `json:"synced_items"`
+	CoveragePct   *float64            `json:"coverage_pct"`
+	Missing       SyncCoverageMissing `json:"missing"`
+	Running       bool                `json:"running"`
+	LastError     string              `json:"last_error,omitempty"`
+}
+
+// GET /admin/diagnostics/sync-coverage?stale_hours=24
+// Per server: movies and episodes synced vs reported by the server, when the library was last
+// synced and how many synced items lack runtime, size or genres, to tell whether stats are
+// built on stale or partial library data.
+func SyncCoverage(db *sql.DB, mgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		staleHours := parseQueryInt(c, "stale_hours", 24)
+		if staleHours <= 0 {
+			staleHours = 24
+		}
+
+		type itemCounts struct{ synced, noRuntime, noSize, noGenres int }
+		counts := map[string]itemCounts{}
+		rows, err := db.Query(`
+			SELECT COALESCE(server_id, ''),
+			       COUNT(*),
+			       SUM(CASE WHEN COALESCE(run_time_ticks, 0) <= 0 THEN 1 ELSE 0 END),
+			       SUM(CASE WHEN COALESCE(file_size_bytes, 0) <= 0 THEN 1 ELSE 0 END),
+			       SUM(CASE WHEN TRIM(COALESCE(genres, '')) = '' THEN 1 ELSE 0 END)
+			FROM library_item
+			WHERE media_type IN ('Movie', 'Episode')
+			GROUP BY 1`)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		for rows.Next() {
+			var sid string
+			var n itemCounts
+			if err := rows.Scan(&sid, &n.synced, &n.noRuntime, &n.noSize, &n.noGenres); err != nil {
+				rows.Close()
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			counts[sid] = n
+		}
+		rows.Close()
+
+		progress := map[string]tasks.ServerSyncProgress{}
+		for _, p := range tasks.GetServerSyncProgressSnapshot() {
+			progress[p.ServerID] = p
+		}
+
+		out := []ServerSyncCoverage{}
+		now := time.Now().UTC()
+		if mgr != nil {
+			for id, sc := range mgr.GetServerConfigs() {
+				n := counts[id]
+				cov := ServerSyncCoverage{
+					ServerID:    id,
+					ServerName:  sc.Name,
+					ServerType:  string(sc.Type),
+					SyncEnabled: settings.GetSyncEnabled(db, id, sc.Enabled),
+					SyncedItems: n.synced,
+					Missing:     SyncCoverageMissing{Runtime: n.noRuntime, Size: n.noSize, Genres: n.noGenres},
+					Stale:       true,
+				}
+				if ts, err := time.Parse(time.RFC3339, settings.GetSettingValue(db, librarySyncSettingPrefix+id, "")); err == nil {
+					age := math.Round(now.Sub(ts).Hours()*10) / 10
+					cov.LastSyncAt = &ts
+					cov.AgeHours = &age
+					cov.Stale = now.Sub(ts) > time.Duration(staleHours)*time.Hour
+				}
+				if v, err := strconv.Atoi(settings.GetSettingValue(db, libraryReportedSettingPrefix+id, "")); err == nil {
+					cov.ReportedItems = &v
+					if v > 0 {
+						pct := math.Round(float64(n.synced)/float64(v)*1000) / 10
+						cov.CoveragePct = &pct
+					}
+				}
+				if p, ok := progress[id]; ok {
+					cov.Running = p.Running
+					cov.LastError = p.Error
+				}
+				out = append(out, cov)
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].ServerID < out[j].ServerID })
+
+		// Items from before servers were tracked separately belong to no server
+		return c.JSON(fiber.Map{
+			"stale_hours":      staleHours,
+			"servers":          out,
+			"unassigned_items": counts[""].synced,
+		})
+	}
+}
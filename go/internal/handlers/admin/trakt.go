@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"database/sql"
+
+	"emby-analytics/internal/handlers/settings"
+	"emby-analytics/internal/trakt"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GET /admin/trakt
+// Trakt scrobbling status: whether TRAKT_CLIENT_ID/TRAKT_CLIENT_SECRET are set, whether the
+// trakt_scrobble_enabled setting is on, the linked accounts and the links still waiting for
+// their code to be entered.
+func TraktStatus(db *sql.DB, client *trakt.Client) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		accounts, err := trakt.Accounts(db)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{
+			"configured": client != nil,
+			"enabled":    settings.GetSettingBool(db, settings.TraktScrobbleEnabledKey, false),
+			"accounts":   accounts,
+			"pending":    trakt.PendingLinks(),
+		})
+	}
+}
+
+// POST /admin/trakt/users/:id/link
+// Starts linking a media user to a Trakt account with the device-code flow. The response
+// holds the code the user enters at verification_url; the account is stored once they do.
+func TraktLinkUser(db *sql.DB, client *trakt.Client) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if client == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Trakt is not configured (set TRAKT_CLIENT_ID and TRAKT_CLIENT_SECRET)"})
+		}
+		userID := c.Params("id")
+		var exists int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM emby_user WHERE id = ? AND deleted_at IS NULL`, userID).Scan(&exists); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if exists == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		}
+		link, err := trakt.StartLink(db, client, userID)
+		if err != nil {
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusAccepted).JSON(link)
+	}
+}
+
+// DELETE /admin/trakt/users/:id
+// Unlinks a media user's Trakt account and revokes its token.
+func TraktUnlinkUser(db *sql.DB, client *trakt.Client) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		removed, err := trakt.Unlink(db, client, c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !removed {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no trakt account linked"})
+		}
+		return c.JSON(fiber.Map{"unlinked": true})
+	}
+}
@@ -0,0 +1,255 @@
+package admin
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type TranscodeAlertRule struct {
+	ID            int64   `json:"id"`
+	Reason        string  `json:"reason"`
+	ServerID      string  `json:"server_id,omitempty"`
+	WindowMinutes int     `json:"window_minutes"`
+	BaselineDays  int     `json:"baseline_days"`
+	MinCount      int     `json:"min_count"`
+	SpikeFactor   float64 `json:"spike_factor"`
+	Delivery      string  `json:"delivery"`
+	Note          string  `json:"note,omitempty"`
+	Enabled       bool    `json:"enabled"`
+	CreatedAt     int64   `json:"created_at"`
+}
+
+type TranscodeAlertEvent struct {
+	ID           int64   `json:"id"`
+	RuleID       int64   `json:"rule_id"`
+	Reason       string  `json:"reason"`
+	ServerID     string  `json:"server_id,omitempty"`
+	WindowStart  int64   `json:"window_start"`
+	WindowEnd    int64   `json:"window_end"`
+	SessionCount int     `json:"session_count"`
+	Baseline     float64 `json:"baseline"`
+	Delivery     string  `json:"delivery"`
+	DeliveredAt  *int64  `json:"delivered_at"`
+	CreatedAt    int64   `json:"created_at"`
+}
+
+type transcodeAlertRequest struct {
+	Reason        string   `json:"reason"`
+	ServerID      string   `json:"server_id"`
+	WindowMinutes *int     `json:"window_minutes"`
+	BaselineDays  *int     `json:"baseline_days"`
+	MinCount      *int     `json:"min_count"`
+	SpikeFactor   *float64 `json:"spike_factor"`
+	Delivery      string   `json:"delivery"`
+	Note          string   `json:"note"`
+	Enabled       *bool    `json:"enabled"`
+}
+
+// validate checks the request and fills in the defaults for omitted thresholds.
+func (r *transcodeAlertRequest) validate() string {
+	r.Reason = strings.TrimSpace(r.Reason)
+	r.Delivery = strings.ToLower(strings.TrimSpace(r.Delivery))
+	if r.Reason == "" {
+		return "reason is required"
+	}
+	switch r.Delivery {
+	case "":
+		r.Delivery = "immediate"
+	case "immediate", "digest":
+	default:
+		return "delivery must be one of immediate, digest"
+	}
+	intDefault := func(v **int, def int) {
+		if *v == nil {
+			*v = &def
+		}
+	}
+	intDefault(&r.WindowMinutes, 60)
+	intDefault(&r.BaselineDays, 7)
+	intDefault(&r.MinCount, 5)
+	if r.SpikeFactor == nil {
+		factor := 3.0
+		r.SpikeFactor = &factor
+	}
+	if *r.WindowMinutes < 5 || *r.WindowMinutes > 7*24*60 {
+		return "window_minutes must be between 5 and 10080"
+	}
+	if *r.BaselineDays < 1 || *r.BaselineDays > 90 {
+		return "baseline_days must be between 1 and 90"
+	}
+	if *r.MinCount < 1 {
+		return "min_count must be at least 1"
+	}
+	if *r.SpikeFactor < 1 {
+		return "spike_factor must be at least 1"
+	}
+	return ""
+}
+
+const transcodeAlertRuleColumns = `id, reason, COALESCE(server_id, ''), window_minutes, baseline_days, min_count,
+	spike_factor, delivery, COALESCE(note, ''), enabled, created_at`
+
+func scanTranscodeAlertRule(row interface{ Scan(...any) error }) (*TranscodeAlertRule, error) {
+	var r TranscodeAlertRule
+	if err := row.Scan(&r.ID, &r.Reason, &r.ServerID, &r.WindowMinutes, &r.BaselineDays, &r.MinCount,
+		&r.SpikeFactor, &r.Delivery, &r.Note, &r.Enabled, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListTranscodeAlerts returns all transcode reason alert rules.
+func ListTranscodeAlerts(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		rows, err := db.Query(`SELECT ` + transcodeAlertRuleColumns + ` FROM transcode_alert_rules ORDER BY id`)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+		out := []TranscodeAlertRule{}
+		for rows.Next() {
+			r, err := scanTranscodeAlertRule(rows)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			out = append(out, *r)
+		}
+		return c.JSON(out)
+	}
+}
+
+// CreateTranscodeAlert adds a spike rule for one transcode reason.
+func CreateTranscodeAlert(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req transcodeAlertRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		if msg := req.validate(); msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+		enabled := req.Enabled == nil || *req.Enabled
+		res, err := db.Exec(`
+			INSERT INTO transcode_alert_rules
+				(reason, server_id, window_minutes, baseline_days, min_count, spike_factor, delivery, note, enabled, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			req.Reason, nullIfEmpty(req.ServerID), *req.WindowMinutes, *req.BaselineDays, *req.MinCount, *req.SpikeFactor,
+			req.Delivery, nullIfEmpty(req.Note), enabled, time.Now().Unix())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, _ := res.LastInsertId()
+		r, err := scanTranscodeAlertRule(db.QueryRow(`SELECT `+transcodeAlertRuleColumns+` FROM transcode_alert_rules WHERE id = ?`, id))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusCreated).JSON(r)
+	}
+}
+
+// UpdateTranscodeAlert replaces a transcode reason alert rule.
+func UpdateTranscodeAlert(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+		var req transcodeAlertRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		if msg := req.validate(); msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+		enabled := req.Enabled == nil || *req.Enabled
+		res, err := db.Exec(`
+			UPDATE transcode_alert_rules
+			SET reason = ?, server_id = ?, window_minutes = ?, baseline_days = ?, min_count = ?, spike_factor = ?,
+			    delivery = ?, note = ?, enabled = ?
+			WHERE id = ?`,
+			req.Reason, nullIfEmpty(req.ServerID), *req.WindowMinutes, *req.BaselineDays, *req.MinCount, *req.SpikeFactor,
+			req.Delivery, nullIfEmpty(req.Note), enabled, id)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "rule not found"})
+		}
+		r, err := scanTranscodeAlertRule(db.QueryRow(`SELECT `+transcodeAlertRuleColumns+` FROM transcode_alert_rules WHERE id = ?`, id))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(r)
+	}
+}
+
+// DeleteTranscodeAlert removes a rule and its recorded spikes.
+func DeleteTranscodeAlert(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+		_, _ = db.Exec(`DELETE FROM transcode_alert_events WHERE rule_id = ?`, id)
+		res, err := db.Exec(`DELETE FROM transcode_alert_rules WHERE id = ?`, id)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "rule not found"})
+		}
+		return c.JSON(fiber.Map{"deleted": true})
+	}
+}
+
+// ListTranscodeAlertEvents returns detected spikes, newest first (?rule_id=, ?pending=true, ?limit=).
+func ListTranscodeAlertEvents(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		limit, _ := strconv.Atoi(c.Query("limit", "100"))
+		if limit <= 0 || limit > 1000 {
+			limit = 100
+		}
+		var where []string
+		args := []interface{}{}
+		if ruleID := c.Query("rule_id", ""); ruleID != "" {
+			where = append(where, "rule_id = ?")
+			args = append(args, ruleID)
+		}
+		if c.Query("pending", "") == "true" {
+			where = append(where, "delivered_at IS NULL")
+		}
+		clause := ""
+		if len(where) > 0 {
+			clause = "WHERE " + strings.Join(where, " AND ")
+		}
+		args = append(args, limit)
+		rows, err := db.Query(`
+			SELECT id, rule_id, reason, COALESCE(server_id, ''), window_start, window_end, session_count, baseline,
+			       delivery, delivered_at, created_at
+			FROM transcode_alert_events `+clause+`
+			ORDER BY created_at DESC, id DESC
+			LIMIT ?`, args...)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+		out := []TranscodeAlertEvent{}
+		for rows.Next() {
+			var e TranscodeAlertEvent
+			var deliveredAt sql.NullInt64
+			if err := rows.Scan(&e.ID, &e.RuleID, &e.Reason, &e.ServerID, &e.WindowStart, &e.WindowEnd, &e.SessionCount,
+				&e.Baseline, &e.Delivery, &deliveredAt, &e.CreatedAt); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			if deliveredAt.Valid {
+				e.DeliveredAt = &deliveredAt.Int64
+			}
+			out = append(out, e)
+		}
+		return c.JSON(out)
+	}
+}
@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"emby-analytics/internal/handlers/settings"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// trendingCollectionPrefix keys the id of the collection last exported to each server
+const trendingCollectionPrefix = "trending_collection_id_"
+
+type trendingExportRequest struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit"`
+}
+
+// ExportTrendingCollection creates a collection of the top trending items on a server, so the
+// analytics show up directly in the media server UI. The collection the previous export
+// created is replaced; collections with the same name made by hand are not touched.
+func ExportTrendingCollection(db *sql.DB, mgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		serverID := c.Params("id")
+		if serverID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "server id is required"})
+		}
+		client, ok := mgr.GetClient(serverID)
+		if !ok || client == nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "server not found"})
+		}
+		collections, ok := client.(media.CollectionManager)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("%s servers do not support collection export", client.GetServerType())})
+		}
+
+		var req trendingExportRequest
+		_ = c.Bind().Body(&req)
+		if req.Limit <= 0 || req.Limit > 100 {
+			req.Limit = 25
+		}
+		if strings.TrimSpace(req.Name) == "" {
+			req.Name = "Trending on the server"
+			if n := strings.TrimSpace(client.GetServerName()); n != "" {
+				req.Name = "Trending on " + n
+			}
+		}
+
+		// Over-fetch since episodes collapse into their series
+		items, err := queries.TrendingItems(c, db, "", serverID, req.Limit*3)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		ids := make([]string, 0, req.Limit)
+		seen := make(map[string]struct{})
+		for _, it := range items {
+			id := it.ItemID
+			if strings.EqualFold(it.Type, "Episode") {
+				var seriesID string
+				_ = db.QueryRow(`SELECT COALESCE(series_id, '') FROM library_item WHERE id = ?`, it.ItemID).Scan(&seriesID)
+				if seriesID != "" {
+					id = seriesID
+				}
+			}
+			if _, dup := seen[id]; dup {
+				continue
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+			if len(ids) >= req.Limit {
+				break
+			}
+		}
+		if len(ids) == 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "no trending items for this server yet"})
+		}
+
+		key := trendingCollectionPrefix + serverID
+		previousID := settings.GetSettingValue(db, key, "")
+		collectionID, err := collections.ReplaceCollection(previousID, req.Name, ids)
+		if collectionID == "" {
+			if err == nil {
+				err = fmt.Errorf("server returned no collection id")
+			}
+			logging.Debug("trending collection export failed", "server_id", serverID, "error", err)
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
+		}
+		if serr := settings.SetSettingValue(db, key, collectionID); serr != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": serr.Error()})
+		}
+		out := fiber.Map{
+			"collection_id": collectionID,
+			"name":          req.Name,
+			"item_count":    len(ids),
+		}
+		if err != nil {
+			logging.Debug("previous trending collection not removed", "server_id", serverID, "collection_id", previousID, "error", err)
+			out["warning"] = "previous collection " + previousID + " could not be removed: " + err.Error()
+		}
+		return c.JSON(out)
+	}
+}
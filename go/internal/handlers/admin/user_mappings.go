@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ListUserMappings returns every person with the media server accounts mapped to them.
+// GET /admin/user-mappings
+func ListUserMappings(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		people, err := queries.ListPeople(c, db)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(people)
+	}
+}
+
+// MapUsers maps accounts on different servers onto one person, so top users and watch time
+// count them together. Without person_id it creates a person named name; with one it adds
+// the accounts to that person, renaming it when name is given. Accounts move out of any
+// person they were mapped to before.
+// POST /admin/user-mappings {"person_id": "optional", "name": "Jane", "user_ids": ["...", "..."]}
+func MapUsers(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req struct {
+			PersonID string   `json:"person_id"`
+			Name     string   `json:"name"`
+			UserIDs  []string `json:"user_ids"`
+		}
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		personID, name := strings.TrimSpace(req.PersonID), strings.TrimSpace(req.Name)
+		ids := make([]string, 0, len(req.UserIDs))
+		for _, id := range req.UserIDs {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		if personID == "" && (name == "" || len(ids) == 0) {
+			return c.Status(400).JSON(fiber.Map{"error": "name and at least one user id are required for a new person"})
+		}
+		p, err := queries.MapUsers(c, db, personID, name, ids, time.Now().Unix())
+		switch {
+		case errors.Is(err, queries.ErrUnknownUser):
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, queries.ErrUnknownPerson):
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		case err != nil:
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(p)
+	}
+}
+
+// DeletePerson removes a person; their accounts count separately again.
+// DELETE /admin/user-mappings/:id
+func DeletePerson(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		personID := strings.TrimSpace(c.Params("id"))
+		ok, err := queries.DeletePerson(c, db, personID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !ok {
+			return c.Status(404).JSON(fiber.Map{"error": "person not found"})
+		}
+		return c.JSON(fiber.Map{"ok": true, "person_id": personID})
+	}
+}
+
+// UnmapUser takes one account out of its person.
+// DELETE /admin/user-mappings/users/:id
+func UnmapUser(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID := strings.TrimSpace(c.Params("id"))
+		ok, err := queries.UnmapUser(c, db, userID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !ok {
+			return c.Status(404).JSON(fiber.Map{"error": "user is not mapped to a person"})
+		}
+		return c.JSON(fiber.Map{"ok": true, "user_id": userID})
+	}
+}
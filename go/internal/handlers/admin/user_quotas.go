@@ -0,0 +1,87 @@
+package admin
+
+import (
+	"database/sql"
+	"time"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type userQuotaRequest struct {
+	DailyMinutes   *int `json:"daily_minutes"`
+	WeeklyMinutes  *int `json:"weekly_minutes"`
+	Enforce        bool `json:"enforce"`
+	WarningMinutes *int `json:"warning_minutes"`
+}
+
+// ListUserQuotas returns all configured user quotas with their current usage.
+func ListUserQuotas(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		quotas, err := queries.ListUserQuotas(c, db, time.Now())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(quotas)
+	}
+}
+
+// SetUserQuota creates or replaces the daily/weekly viewing quota for a user.
+func SetUserQuota(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID := c.Params("id")
+		if userID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "user id is required"})
+		}
+		var req userQuotaRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		if req.DailyMinutes == nil && req.WeeklyMinutes == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "daily_minutes or weekly_minutes is required"})
+		}
+		if (req.DailyMinutes != nil && *req.DailyMinutes < 0) || (req.WeeklyMinutes != nil && *req.WeeklyMinutes < 0) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "quota minutes must be >= 0"})
+		}
+		warning := 10
+		if req.WarningMinutes != nil && *req.WarningMinutes >= 0 {
+			warning = *req.WarningMinutes
+		}
+
+		_, err := db.Exec(`
+			INSERT INTO user_quotas (user_id, daily_minutes, weekly_minutes, enforce, warning_minutes, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(user_id) DO UPDATE SET
+				daily_minutes = excluded.daily_minutes,
+				weekly_minutes = excluded.weekly_minutes,
+				enforce = excluded.enforce,
+				warning_minutes = excluded.warning_minutes,
+				updated_at = excluded.updated_at
+		`, userID, req.DailyMinutes, req.WeeklyMinutes, req.Enforce, warning, time.Now().Unix())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		q, err := queries.GetUserQuota(c, db, userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(q)
+	}
+}
+
+// DeleteUserQuota removes a user's quota (and any issued warnings).
+func DeleteUserQuota(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID := c.Params("id")
+		if userID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "user id is required"})
+		}
+		if _, err := db.Exec(`DELETE FROM user_quotas WHERE user_id = ?`, userID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		_, _ = db.Exec(`DELETE FROM user_quota_events WHERE user_id = ?`, userID)
+		return c.JSON(fiber.Map{"deleted": true})
+	}
+}
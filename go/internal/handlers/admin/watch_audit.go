@@ -0,0 +1,152 @@
+package admin
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Watch-time audit limits; the server comparison relies on play history, which the
+// servers return for at most 100 recent items per user.
+const (
+	watchAuditDefaultDays = 7
+	watchAuditMaxDays     = 90
+	watchAuditMinHours    = 0.25
+)
+
+// WatchAuditUser is one user's audit row plus the server play history comparison.
+type WatchAuditUser struct {
+	queries.WatchAuditRow
+	// ServerPlayedItems counts items the server reports played in the period
+	ServerPlayedItems int `json:"server_played_items"`
+	// MissingLocally lists items the server reports played with no local watch time
+	MissingLocally []string `json:"missing_locally"`
+	ServerError    string   `json:"server_error,omitempty"`
+}
+
+// GET /admin/diagnostics/watch-time-audit?days=7&user_id=&server=&threshold_pct=10&remote=1
+// Per user, compares watch time from play intervals (what the stats use) with the time
+// rebuilt from play events, the active time of session summaries and, unless remote=0,
+// the runtime of items the media server reports played in the period. Sources differing
+// from the intervals by more than threshold_pct percent (and 15 minutes) are flagged.
+func WatchTimeAudit(db *sql.DB, mgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", watchAuditDefaultDays)
+		if days <= 0 {
+			days = watchAuditDefaultDays
+		}
+		if days > watchAuditMaxDays {
+			days = watchAuditMaxDays
+		}
+		threshold := float64(parseQueryInt(c, "threshold_pct", 10))
+		if threshold < 0 {
+			threshold = 10
+		}
+		userID := strings.TrimSpace(c.Query("user_id"))
+		serverID := strings.TrimSpace(c.Query("server"))
+		remote := c.Query("remote", "1") != "0"
+
+		to := time.Now()
+		from := to.AddDate(0, 0, -days)
+		rows, err := queries.WatchTimeAudit(c, db, from.Unix(), to.Unix(), userID, "", serverID, threshold, watchAuditMinHours)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		users := make([]WatchAuditUser, 0, len(rows))
+		divergent := 0
+		for _, row := range rows {
+			u := WatchAuditUser{WatchAuditRow: row, MissingLocally: []string{}}
+			if remote && mgr != nil {
+				if err := compareServerHistory(c, db, mgr, &u, from, to, days, threshold); err != nil {
+					u.ServerError = err.Error()
+				}
+			}
+			if u.Divergent {
+				divergent++
+			}
+			users = append(users, u)
+		}
+
+		return c.JSON(fiber.Map{
+			"from":           from.UTC().Format(time.RFC3339),
+			"to":             to.UTC().Format(time.RFC3339),
+			"days":           days,
+			"threshold_pct":  threshold,
+			"users":          users,
+			"divergent":      divergent,
+			"remote_checked": remote && mgr != nil,
+		})
+	}
+}
+
+// compareServerHistory adds the server source to u: the runtime of the items the server
+// reports played in [from, to) against the local interval time on those items.
+func compareServerHistory(c fiber.Ctx, db *sql.DB, mgr *media.MultiServerManager, u *WatchAuditUser, from, to time.Time, days int, threshold float64) error {
+	serverID := u.ServerID
+	if serverID == "" {
+		serverID = "default-emby"
+	}
+	client, ok := mgr.GetClient(serverID)
+	if !ok {
+		return fmt.Errorf("server %s not configured", serverID)
+	}
+	remoteUserID := strings.TrimPrefix(u.UserID, serverID+"::")
+	history, err := client.GetUserPlayHistory(remoteUserID, days)
+	if err != nil {
+		return err
+	}
+
+	storedID := func(id string) string {
+		if serverID == "default-emby" {
+			return id
+		}
+		return serverID + "::" + id
+	}
+	names := map[string]string{}
+	var ids []string
+	for _, h := range history {
+		if strings.TrimSpace(h.ID) == "" {
+			continue
+		}
+		played, err := time.Parse(time.RFC3339, h.DatePlayed)
+		if err != nil {
+			played, err = time.Parse("2006-01-02T15:04:05", h.DatePlayed)
+		}
+		if err != nil || played.Before(from) || !played.Before(to) {
+			continue
+		}
+		id := storedID(h.ID)
+		if _, seen := names[id]; !seen {
+			names[id] = h.Name
+			ids = append(ids, id)
+		}
+	}
+	u.ServerPlayedItems = len(ids)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	runtimes, err := queries.ItemRuntimeHours(c, db, ids)
+	if err != nil {
+		return err
+	}
+	var runtime, local float64
+	for _, id := range ids {
+		seconds := u.ItemSeconds[id]
+		if seconds <= 0 {
+			u.MissingLocally = append(u.MissingLocally, names[id])
+		}
+		runtime += runtimes[id]
+		local += seconds / 3600
+	}
+	u.AddSource(queries.AuditSource{Source: queries.AuditSourceServer, Items: len(ids), Hours: runtime, IntervalHours: local},
+		threshold, watchAuditMinHours)
+	return nil
+}
@@ -0,0 +1,213 @@
+package admin
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/monitors"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type WatchForRule struct {
+	ID        int64  `json:"id"`
+	Kind      string `json:"kind"`
+	Value     string `json:"value"`
+	ServerID  string `json:"server_id,omitempty"`
+	Note      string `json:"note,omitempty"`
+	Enabled   bool   `json:"enabled"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type WatchForHit struct {
+	ID         int64  `json:"id"`
+	RuleID     int64  `json:"rule_id"`
+	ServerID   string `json:"server_id"`
+	SessionID  string `json:"session_id"`
+	ItemID     string `json:"item_id"`
+	ItemName   string `json:"item_name"`
+	UserID     string `json:"user_id"`
+	UserName   string `json:"user_name"`
+	DeviceName string `json:"device_name"`
+	ClientApp  string `json:"client_app"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+type watchForRequest struct {
+	Kind     string `json:"kind"`
+	Value    string `json:"value"`
+	ServerID string `json:"server_id"`
+	Note     string `json:"note"`
+	Enabled  *bool  `json:"enabled"`
+}
+
+func (r *watchForRequest) validate() string {
+	r.Kind = strings.ToLower(strings.TrimSpace(r.Kind))
+	r.Value = strings.TrimSpace(r.Value)
+	switch r.Kind {
+	case "item", "series", "pattern":
+	default:
+		return "kind must be one of item, series, pattern"
+	}
+	if r.Value == "" {
+		return "value is required"
+	}
+	if r.Kind == "pattern" {
+		if _, err := monitors.WatchForPattern(r.Value); err != nil {
+			return "invalid pattern"
+		}
+	}
+	return ""
+}
+
+func getWatchForRule(db *sql.DB, id int64) (*WatchForRule, error) {
+	var r WatchForRule
+	err := db.QueryRow(`
+		SELECT id, kind, value, COALESCE(server_id, ''), COALESCE(note, ''), enabled, created_at
+		FROM watch_for_rules WHERE id = ?`, id).
+		Scan(&r.ID, &r.Kind, &r.Value, &r.ServerID, &r.Note, &r.Enabled, &r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListWatchFor returns all watch-for rules.
+func ListWatchFor(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		rows, err := db.Query(`
+			SELECT id, kind, value, COALESCE(server_id, ''), COALESCE(note, ''), enabled, created_at
+			FROM watch_for_rules ORDER BY id`)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+		out := []WatchForRule{}
+		for rows.Next() {
+			var r WatchForRule
+			if err := rows.Scan(&r.ID, &r.Kind, &r.Value, &r.ServerID, &r.Note, &r.Enabled, &r.CreatedAt); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			out = append(out, r)
+		}
+		return c.JSON(out)
+	}
+}
+
+// CreateWatchFor adds an item, series or name pattern to the watch-for list.
+func CreateWatchFor(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req watchForRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		if msg := req.validate(); msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+		enabled := req.Enabled == nil || *req.Enabled
+		res, err := db.Exec(`
+			INSERT INTO watch_for_rules (kind, value, server_id, note, enabled, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			req.Kind, req.Value, nullIfEmpty(req.ServerID), nullIfEmpty(req.Note), enabled, time.Now().Unix())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, _ := res.LastInsertId()
+		r, err := getWatchForRule(db, id)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusCreated).JSON(r)
+	}
+}
+
+// UpdateWatchFor replaces a watch-for rule.
+func UpdateWatchFor(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+		var req watchForRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		if msg := req.validate(); msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+		enabled := req.Enabled == nil || *req.Enabled
+		res, err := db.Exec(`
+			UPDATE watch_for_rules SET kind = ?, value = ?, server_id = ?, note = ?, enabled = ?
+			WHERE id = ?`,
+			req.Kind, req.Value, nullIfEmpty(req.ServerID), nullIfEmpty(req.Note), enabled, id)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "rule not found"})
+		}
+		r, err := getWatchForRule(db, id)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(r)
+	}
+}
+
+// DeleteWatchFor removes a rule and its recorded hits.
+func DeleteWatchFor(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+		}
+		_, _ = db.Exec(`DELETE FROM watch_for_hits WHERE rule_id = ?`, id)
+		res, err := db.Exec(`DELETE FROM watch_for_rules WHERE id = ?`, id)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "rule not found"})
+		}
+		return c.JSON(fiber.Map{"deleted": true})
+	}
+}
+
+// ListWatchForHits returns recent matches, newest first (?rule_id=, ?limit=).
+func ListWatchForHits(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		limit, _ := strconv.Atoi(c.Query("limit", "100"))
+		if limit <= 0 || limit > 1000 {
+			limit = 100
+		}
+		where := ""
+		args := []interface{}{}
+		if ruleID := c.Query("rule_id", ""); ruleID != "" {
+			where = "WHERE rule_id = ?"
+			args = append(args, ruleID)
+		}
+		args = append(args, limit)
+		rows, err := db.Query(`
+			SELECT id, rule_id, server_id, session_id, item_id, COALESCE(item_name, ''), COALESCE(user_id, ''),
+			       COALESCE(user_name, ''), COALESCE(device_name, ''), COALESCE(client_app, ''), created_at
+			FROM watch_for_hits `+where+`
+			ORDER BY created_at DESC, id DESC
+			LIMIT ?`, args...)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+		out := []WatchForHit{}
+		for rows.Next() {
+			var h WatchForHit
+			if err := rows.Scan(&h.ID, &h.RuleID, &h.ServerID, &h.SessionID, &h.ItemID, &h.ItemName, &h.UserID,
+				&h.UserName, &h.DeviceName, &h.ClientApp, &h.CreatedAt); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			out = append(out, h)
+		}
+		return c.JSON(out)
+	}
+}
@@ -4,19 +4,25 @@ import (
 	"database/sql"
 	"emby-analytics/internal/logging"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 
 	"emby-analytics/internal/emby"
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/tasks"
 )
 
 // EmbyWebhookPayload represents the structure of webhook data from Emby
 type EmbyWebhookPayload struct {
-	Server    ServerInfo `json:"Server"`
-	Event     string     `json:"Event"`
-	User      UserInfo   `json:"User,omitempty"`
-	Item      ItemInfo   `json:"Item,omitempty"`
-	Timestamp string     `json:"Timestamp"`
+	Server      ServerInfo  `json:"Server"`
+	Event       string      `json:"Event"`
+	Title       string      `json:"Title,omitempty"`
+	Description string      `json:"Description,omitempty"`
+	User        UserInfo    `json:"User,omitempty"`
+	Item        ItemInfo    `json:"Item,omitempty"`
+	Session     SessionInfo `json:"Session,omitempty"`
+	Timestamp   string      `json:"Timestamp"`
 }
 
 type ServerInfo struct {
@@ -29,6 +35,12 @@ type UserInfo struct {
 	Name string `json:"Name"`
 }
 
+type SessionInfo struct {
+	Id         string `json:"Id"`
+	Client     string `json:"Client"`
+	DeviceName string `json:"DeviceName"`
+}
+
 type ItemInfo struct {
 	Id       string `json:"Id"`
 	Name     string `json:"Name"`
@@ -36,8 +48,9 @@ type ItemInfo struct {
 	ParentId string `json:"ParentId,omitempty"`
 }
 
-// WebhookHandler handles incoming webhooks from Emby
-func WebhookHandler(rm *RefreshManager, db *sql.DB, em *emby.Client) fiber.Handler {
+// WebhookHandler handles incoming webhooks from Emby. serverID identifies the
+// server the webhook belongs to; ?server_id= overrides it (e.g. for Jellyfin).
+func WebhookHandler(rm *RefreshManager, db *sql.DB, em *emby.Client, mgr *media.MultiServerManager, serverID string) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		// Parse webhook payload
 		var payload EmbyWebhookPayload
@@ -47,6 +60,17 @@ func WebhookHandler(rm *RefreshManager, db *sql.DB, em *emby.Client) fiber.Handl
 		}
 
 		logging.Debug("📨 Received event: %s for item: %s (%s)", payload.Event, payload.Item.Name, payload.Item.Type)
+		tasks.RecordWebhook(c.Query("server_id", serverID), time.Now(), isPlaybackEvent(payload.Event))
+
+		// Record playback failures for /stats/errors
+		if isPlaybackErrorEvent(payload.Event) {
+			sid := c.Query("server_id", serverID)
+			serverType := string(media.ServerTypeEmby)
+			if cfg, ok := mgr.GetServerConfigs()[sid]; ok {
+				serverType = string(cfg.Type)
+			}
+			recordPlaybackError(db, sid, serverType, payload)
+		}
 
 		// Handle library-related events
 		if isLibraryEvent(payload.Event) {
@@ -87,6 +111,40 @@ func isLibraryEvent(event string) bool {
 	return false
 }
 
+// isPlaybackEvent matches playback webhooks (playback.start, playback.stop, PlaybackStart, ...)
+func isPlaybackEvent(event string) bool {
+	return strings.Contains(strings.ToLower(event), "playback")
+}
+
+// isPlaybackErrorEvent matches playback failure events (playback.error, PlaybackFailed, ...)
+func isPlaybackErrorEvent(event string) bool {
+	e := strings.ToLower(event)
+	return strings.Contains(e, "playback") && (strings.Contains(e, "error") || strings.Contains(e, "fail"))
+}
+
+// recordPlaybackError stores a playback error event; failures are only logged
+func recordPlaybackError(db *sql.DB, serverID, serverType string, p EmbyWebhookPayload) {
+	errText := strings.TrimSpace(p.Description)
+	if errText == "" {
+		errText = strings.TrimSpace(p.Title)
+	}
+	occurredAt := time.Now().Unix()
+	if t, err := time.Parse(time.RFC3339, p.Timestamp); err == nil {
+		occurredAt = t.Unix()
+	}
+	if _, err := db.Exec(`
+		INSERT INTO playback_errors (server_id, server_type, event, session_id, item_id, item_name, item_type,
+			user_id, user_name, client_name, device_name, error_text, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		serverID, serverType, p.Event, nullIfEmpty(p.Session.Id), nullIfEmpty(p.Item.Id), nullIfEmpty(p.Item.Name),
+		nullIfEmpty(p.Item.Type), nullIfEmpty(p.User.Id), nullIfEmpty(p.User.Name), nullIfEmpty(p.Session.Client),
+		nullIfEmpty(p.Session.DeviceName), nullIfEmpty(errText), occurredAt); err != nil {
+		logging.Warn("failed to record playback error", "item_id", p.Item.Id, "error", err)
+		return
+	}
+	logging.Debug("playback error recorded", "item", p.Item.Name, "client", p.Session.Client, "error", errText)
+}
+
 // isMediaItem determines if an item type is a media item we track
 func isMediaItem(itemType string) bool {
 	mediaTypes := []string{
@@ -104,14 +162,19 @@ func isMediaItem(itemType string) bool {
 	return false
 }
 
-// GetWebhookStats returns webhook activity statistics
+// GetWebhookStats returns webhook activity statistics and the session polling cadence
 func GetWebhookStats() fiber.Handler {
 	return func(c fiber.Ctx) error {
+		var polling any
+		if p := tasks.CurrentPollCadence(); p != nil {
+			polling = p.Status(time.Now())
+		}
 		// For now, return basic info
 		// In the future, we could track webhook statistics in the database
 		return c.JSON(fiber.Map{
 			"webhook_endpoint": "/admin/webhook/emby",
 			"supported_events": []string{
+				"playback.error",
 				"library.new",
 				"item.added",
 				"item.updated",
@@ -124,7 +187,8 @@ func GetWebhookStats() fiber.Handler {
 				"Episode",
 				"Video",
 			},
-			"status": "active",
+			"status":  "active",
+			"polling": polling,
 		})
 	}
 }
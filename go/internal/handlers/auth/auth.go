@@ -2,13 +2,16 @@ package auth
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"emby-analytics/internal/config"
 	dbutil "emby-analytics/internal/db"
+	"emby-analytics/internal/handlers/settings"
 	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
@@ -111,11 +114,26 @@ func LoginHandler(db *sql.DB, cfg config.Config) fiber.Handler {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "username and password required"})
 		}
 		u, hash, err := getUserByUsername(db, req.Username)
-		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid credentials"})
-		}
-		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)) != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid credentials"})
+		if err != nil || bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)) != nil {
+			if !cfg.AuthMediaLogin {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid credentials"})
+			}
+			// Fall back to the media servers' own accounts
+			mu, err := authenticateMediaUser(cfg, req.Username, req.Password)
+			if errors.Is(err, media.ErrInvalidCredentials) {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid credentials"})
+			}
+			if err != nil {
+				return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "media server unavailable"})
+			}
+			u, err = mediaAppUser(db, mu)
+			if errors.Is(err, errUsernameTaken) {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "username belongs to a local account; ask an admin to link it"})
+			}
+			if err != nil {
+				logging.Error("failed to provision media user", "error", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "provisioning error"})
+			}
 		}
 		token, exp, err := upsertSession(db, u.ID, time.Duration(cfg.AuthSessionTTLMinutes)*time.Minute)
 		if err != nil {
@@ -198,12 +216,13 @@ type AuthConfig struct {
 	RegistrationMode string `json:"registration_mode"`
 	RegistrationOpen bool   `json:"registration_open"`
 	RequiresSecret   bool   `json:"requires_secret"`
+	MediaServerLogin bool   `json:"media_server_login"`
 }
 
 func ConfigHandler(db *sql.DB, cfg config.Config) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		n, _ := countUsers(db)
-		mode := strings.ToLower(cfg.AuthRegistrationMode)
+		mode := registrationMode(db, cfg)
 		open := false
 		requiresSecret := false
 		switch mode {
@@ -226,6 +245,7 @@ func ConfigHandler(db *sql.DB, cfg config.Config) fiber.Handler {
 			RegistrationMode: mode,
 			RegistrationOpen: open,
 			RequiresSecret:   requiresSecret,
+			MediaServerLogin: cfg.AuthMediaLogin,
 		})
 	}
 }
@@ -235,7 +255,7 @@ func isRegistrationAllowed(db *sql.DB, cfg config.Config, c fiber.Ctx, providedS
 	if n, err := countUsers(db); err == nil && n == 0 {
 		return true, "admin"
 	}
-	mode := strings.ToLower(cfg.AuthRegistrationMode)
+	mode := registrationMode(db, cfg)
 	switch mode {
 	case "open":
 		return true, "user"
@@ -253,6 +273,15 @@ func isRegistrationAllowed(db *sql.DB, cfg config.Config, c fiber.Ctx, providedS
 	}
 }
 
+// registrationMode is AUTH_REGISTRATION_MODE when set, otherwise the mode chosen during setup.
+func registrationMode(db *sql.DB, cfg config.Config) string {
+	mode := strings.ToLower(cfg.AuthRegistrationMode)
+	if !cfg.AuthRegistrationFixed {
+		mode = settings.GetSettingValue(db, settings.AuthRegistrationModeKey, mode)
+	}
+	return mode
+}
+
 func readAuthCookie(c fiber.Ctx, cfg config.Config) string {
 	return c.Cookies(cfg.AuthCookieName)
 }
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type bulkAppUsersReq struct {
+	IDs    []int64 `json:"ids"`
+	Action string  `json:"action"` // "set_role" or "delete"
+	Role   string  `json:"role"`   // required for set_role
+}
+
+type bulkFailure struct {
+	ID    int64  `json:"id"`
+	Error string `json:"error"`
+}
+
+type bulkResult struct {
+	Action    string        `json:"action"`
+	Succeeded []int64       `json:"succeeded"`
+	Failed    []bulkFailure `json:"failed"`
+}
+
+// BulkAppUsers applies a role change or deletion to many app users in a single
+// transaction. Unknown ids are reported as failures; the whole batch is rolled
+// back if it would leave no admin account.
+func BulkAppUsers(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req bulkAppUsersReq
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+		}
+		if len(req.IDs) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ids required"})
+		}
+		action := strings.ToLower(strings.TrimSpace(req.Action))
+		role := ""
+		switch action {
+		case "set_role":
+			role = normalizeRole(req.Role)
+			if role == "" {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "role must be 'admin' or 'user'"})
+			}
+		case "delete":
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "action must be 'set_role' or 'delete'"})
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer tx.Rollback()
+
+		res := bulkResult{Action: action, Succeeded: []int64{}, Failed: []bulkFailure{}}
+		seen := make(map[int64]bool, len(req.IDs))
+		for _, id := range req.IDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			var r sql.Result
+			if action == "delete" {
+				r, err = tx.Exec(`DELETE FROM app_user WHERE id=?`, id)
+			} else {
+				r, err = tx.Exec(`UPDATE app_user SET role=? WHERE id=?`, role, id)
+			}
+			if err != nil {
+				res.Failed = append(res.Failed, bulkFailure{ID: id, Error: err.Error()})
+				continue
+			}
+			if n, _ := r.RowsAffected(); n == 0 {
+				res.Failed = append(res.Failed, bulkFailure{ID: id, Error: "user not found"})
+				continue
+			}
+			res.Succeeded = append(res.Succeeded, id)
+		}
+
+		// Prevent removing the last admin
+		var admins int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM app_user WHERE lower(role)='admin'`).Scan(&admins); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if admins == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "batch would remove the last admin; no changes applied"})
+		}
+
+		if err := tx.Commit(); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(res)
+	}
+}
@@ -10,15 +10,16 @@ import (
 )
 
 type AppUser struct {
-	ID        int64  `json:"id"`
-	Username  string `json:"username"`
-	Role      string `json:"role"`
-	CreatedAt string `json:"created_at"`
+	ID          int64  `json:"id"`
+	Username    string `json:"username"`
+	Role        string `json:"role"`
+	MediaUserID string `json:"media_user_id,omitempty"` // linked media server user
+	CreatedAt   string `json:"created_at"`
 }
 
 func ListAppUsers(db *sql.DB) fiber.Handler {
 	return func(c fiber.Ctx) error {
-		rows, err := db.Query(`SELECT id, username, role, COALESCE(strftime('%Y-%m-%dT%H:%M:%fZ', created_at), '') as created_at FROM app_user ORDER BY id ASC`)
+		rows, err := db.Query(`SELECT id, username, role, COALESCE(media_user_id, ''), COALESCE(strftime('%Y-%m-%dT%H:%M:%fZ', created_at), '') as created_at FROM app_user ORDER BY id ASC`)
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 		}
@@ -26,7 +27,7 @@ func ListAppUsers(db *sql.DB) fiber.Handler {
 		out := make([]AppUser, 0, 8)
 		for rows.Next() {
 			var u AppUser
-			if err := rows.Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt); err == nil {
+			if err := rows.Scan(&u.ID, &u.Username, &u.Role, &u.MediaUserID, &u.CreatedAt); err == nil {
 				out = append(out, u)
 			}
 		}
@@ -68,9 +69,10 @@ func CreateAppUser(db *sql.DB) fiber.Handler {
 }
 
 type updateUserReq struct {
-	Username *string `json:"username"`
-	Password *string `json:"password"`
-	Role     *string `json:"role"`
+	Username    *string `json:"username"`
+	Password    *string `json:"password"`
+	Role        *string `json:"role"`
+	MediaUserID *string `json:"media_user_id"` // empty string unlinks
 }
 
 func UpdateAppUser(db *sql.DB) fiber.Handler {
@@ -144,6 +146,11 @@ func UpdateAppUser(db *sql.DB) fiber.Handler {
 				return translateUserWriteErr(c, err)
 			}
 		}
+		if req.MediaUserID != nil {
+			if _, err := db.Exec(`UPDATE app_user SET media_user_id = NULLIF(?, '') WHERE id=?`, strings.TrimSpace(*req.MediaUserID), id); err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+		}
 		return c.JSON(fiber.Map{"id": id, "username": newUsername, "role": newRole})
 	}
 }
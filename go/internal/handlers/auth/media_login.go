@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+
+	"emby-analytics/internal/config"
+	dbutil "emby-analytics/internal/db"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+)
+
+// multiServerMgr provides the Emby/Jellyfin servers checked for media server logins
+var multiServerMgr *media.MultiServerManager
+
+// SetMultiServerManager sets the manager used for media server logins
+func SetMultiServerManager(mgr *media.MultiServerManager) {
+	multiServerMgr = mgr
+}
+
+// mediaAccountPassword is stored for app users provisioned by a media server login. It is
+// not a bcrypt hash, so those accounts can only sign in through their media server.
+const mediaAccountPassword = "!media"
+
+var errUsernameTaken = errors.New("username belongs to a local account")
+
+// authenticateMediaUser checks the credentials against each enabled Emby/Jellyfin server
+// (limited to AUTH_MEDIA_SERVERS when set) and returns the first matching user. It returns
+// media.ErrInvalidCredentials when no server accepted them, or the last error when none
+// of the servers could be asked.
+func authenticateMediaUser(cfg config.Config, username, password string) (*media.User, error) {
+	if multiServerMgr == nil {
+		return nil, media.ErrInvalidCredentials
+	}
+	allowed := map[string]bool{}
+	for _, id := range cfg.AuthMediaServers {
+		allowed[id] = true
+	}
+	configs := multiServerMgr.GetServerConfigs()
+	ids := make([]string, 0, len(configs))
+	for id, sc := range configs {
+		if !sc.Enabled || (len(allowed) > 0 && !allowed[id]) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var lastErr error
+	rejected := false
+	for _, id := range ids {
+		client, ok := multiServerMgr.GetClient(id)
+		if !ok || client == nil {
+			continue
+		}
+		authenticator, ok := client.(media.CredentialAuthenticator)
+		if !ok {
+			continue // Plex accounts live on plex.tv, not on the server
+		}
+		u, err := authenticator.AuthenticateUser(username, password)
+		if err == nil {
+			return u, nil
+		}
+		if errors.Is(err, media.ErrInvalidCredentials) {
+			rejected = true
+			continue
+		}
+		logging.Warn("media server login check failed", "server_id", id, "error", err)
+		lastErr = err
+	}
+	if rejected || lastErr == nil {
+		return nil, media.ErrInvalidCredentials
+	}
+	return nil, lastErr
+}
+
+// mediaAppUser returns the app user linked to the media user, provisioning one with the
+// regular user (viewer) role on first login. An unlinked local account with the same name
+// is never taken over; an admin has to link it instead.
+func mediaAppUser(db *sql.DB, mu *media.User) (*userRow, error) {
+	var u userRow
+	err := dbutil.QueryRowWithRetry(db,
+		`SELECT id, username, role FROM app_user WHERE media_user_id = ? ORDER BY id LIMIT 1`,
+		[]any{mu.ID},
+		func(row *sql.Row) error {
+			return row.Scan(&u.ID, &u.Username, &u.Role)
+		},
+	)
+	if err == nil {
+		return &u, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if _, _, err := getUserByUsername(db, mu.Name); err == nil {
+		return nil, errUsernameTaken
+	}
+	res, err := dbutil.ExecWithRetry(db,
+		`INSERT INTO app_user (username, password_hash, role, media_user_id) VALUES (?, ?, 'user', ?)`,
+		mu.Name, mediaAccountPassword, mu.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	logging.Info("provisioned app user from media server login", "username", mu.Name, "media_user_id", mu.ID, "server_id", mu.ServerID)
+	return &userRow{ID: id, Username: mu.Name, Role: "user"}, nil
+}
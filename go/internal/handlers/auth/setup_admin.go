@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/config"
+	dbutil "emby-analytics/internal/db"
+	"emby-analytics/internal/handlers/settings"
+	"emby-analytics/internal/logging"
+
+	"github.com/gofiber/fiber/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minSetupPasswordLen is the shortest password accepted for the first admin account.
+const minSetupPasswordLen = 8
+
+// SetupAdminHandler creates the first admin account during first-run setup and signs it in.
+// It refuses once any app user exists, so it can't be used to add a second admin.
+func SetupAdminHandler(db *sql.DB, cfg config.Config) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req loginReq
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+		}
+		req.Username = strings.TrimSpace(req.Username)
+		if req.Username == "" || len(req.Password) < minSetupPasswordLen {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "username and a password of at least 8 characters required"})
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "hash error"})
+		}
+
+		// Insert only while the table is empty so concurrent requests can't both create an admin
+		res, err := dbutil.ExecWithRetry(db, `
+			INSERT INTO app_user (username, password_hash, role)
+			SELECT ?, ?, 'admin' WHERE NOT EXISTS (SELECT 1 FROM app_user)
+		`, req.Username, string(hash))
+		if err != nil {
+			logging.Error("failed to create setup admin", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create user"})
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "an admin account already exists"})
+		}
+		uid, err := res.LastInsertId()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create user"})
+		}
+		// The wizard is now in progress; it stays open until it is explicitly completed
+		if err := settings.SetSettingValue(db, settings.SetupCompletedKey, "false"); err != nil {
+			logging.Warn("failed to record setup progress", "error", err)
+		}
+
+		token, exp, err := upsertSession(db, uid, time.Duration(cfg.AuthSessionTTLMinutes)*time.Minute)
+		if err != nil {
+			logging.Error("failed to create session", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "session error"})
+		}
+		setAuthCookie(c, cfg, token, exp)
+		return c.Status(http.StatusCreated).JSON(fiber.Map{"user": fiber.Map{"id": uid, "username": req.Username, "role": "admin"}})
+	}
+}
@@ -0,0 +1,314 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"emby-analytics/internal/notify"
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// userDataFile is one dataset in a user's data package.
+type userDataFile struct {
+	name        string
+	description string
+	query       string
+	csv         bool // also write a CSV copy next to the JSON
+}
+
+var userDataFiles = []userDataFile{
+	{
+		name:        "user",
+		description: "Media server account record(s) for this user",
+		query:       `SELECT * FROM emby_user WHERE id = ?`,
+	},
+	{
+		name:        "sessions",
+		description: "Every playback session: item, client, device, address, play method and timestamps (unix seconds)",
+		query:       `SELECT * FROM play_sessions WHERE user_id = ? ORDER BY started_at`,
+		csv:         true,
+	},
+	{
+		name:        "intervals",
+		description: "Watched segments per session; duration_seconds is counted as watch time",
+		query:       `SELECT * FROM play_intervals WHERE user_id = ? ORDER BY start_ts`,
+		csv:         true,
+	},
+	{
+		name:        "events",
+		description: "Raw start/progress/stop events recorded for the user's sessions",
+		query: `SELECT pe.* FROM play_events pe JOIN play_sessions ps ON ps.id = pe.session_fk
+			WHERE ps.user_id = ? ORDER BY pe.created_at`,
+		csv: true,
+	},
+	{
+		name:        "devices",
+		description: "Devices and clients the user played from, with first/last use and known addresses",
+		query: `SELECT COALESCE(device_id, '') AS device, COALESCE(client_name, '') AS client,
+			COUNT(*) AS sessions, MIN(started_at) AS first_seen, MAX(COALESCE(ended_at, started_at)) AS last_seen,
+			GROUP_CONCAT(DISTINCT remote_address) AS remote_addresses
+			FROM play_sessions WHERE user_id = ? GROUP BY 1, 2 ORDER BY last_seen DESC`,
+		csv: true,
+	},
+	{
+		name:        "playback_errors",
+		description: "Playback errors reported for the user",
+		query:       `SELECT * FROM playback_errors WHERE user_id = ? ORDER BY occurred_at`,
+	},
+}
+
+// UserDataPackage returns a zip with everything stored about one media user, for
+// data access requests. Each dataset is written as JSON (and CSV for tabular ones)
+// together with derived stats and a README describing the files.
+// GET /export/users/:id/data.zip
+func UserDataPackage(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID := c.Params("id")
+		if userID == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "missing user id"})
+		}
+		var known int
+		if err := db.QueryRow(`
+			SELECT (SELECT COUNT(*) FROM emby_user WHERE id = ?) + (SELECT COUNT(*) FROM play_sessions WHERE user_id = ?)`,
+			userID, userID).Scan(&known); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if known == 0 {
+			return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+		}
+
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		readme := &bytes.Buffer{}
+		fmt.Fprintf(readme, "Data package for media user %s\nGenerated %s\n\n", userID, time.Now().UTC().Format(time.RFC3339))
+
+		for _, f := range userDataFiles {
+			cols, records, err := queryRecords(db, f.query, userID)
+			if err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf("%s: %v", f.name, err)})
+			}
+			if err := writeJSON(zw, f.name+".json", records); err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			fmt.Fprintf(readme, "%s.json - %s (%d rows)\n", f.name, f.description, len(records))
+			if f.csv {
+				if err := writeCSV(zw, f.name+".csv", cols, records); err != nil {
+					return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+				}
+				fmt.Fprintf(readme, "%s.csv - same rows as %s.json\n", f.name, f.name)
+			}
+		}
+
+		stats, err := derivedStats(db, userID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err := writeJSON(zw, "stats.json", stats); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		fmt.Fprintln(readme, "stats.json - Totals, watch hours per month and most watched items derived from intervals")
+
+		// A stable link to the user that keeps working after remaps and re-syncs
+		if publicID, err := queries.PublicID(c, db, queries.PublicKindUser, userID); err == nil {
+			fmt.Fprintf(readme, "\nLink: %s\n", notify.Link(publicID))
+		} else if err != sql.ErrNoRows {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		w, err := zw.Create("README.txt")
+		if err == nil {
+			_, err = io.Copy(w, readme)
+		}
+		if err == nil {
+			err = zw.Close()
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		c.Set("Content-Type", "application/zip")
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s-data.zip"`, sanitizeFilename(userID)))
+		return c.Send(buf.Bytes())
+	}
+}
+
+// queryRecords runs a query and returns its columns and rows as column->value maps.
+func queryRecords(db *sql.DB, query string, args ...any) ([]string, []map[string]any, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	out := []map[string]any{}
+	for rows.Next() {
+		vals := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, nil, err
+		}
+		rec := make(map[string]any, len(cols))
+		for i, col := range cols {
+			if b, ok := vals[i].([]byte); ok {
+				rec[col] = string(b)
+			} else {
+				rec[col] = vals[i]
+			}
+		}
+		out = append(out, rec)
+	}
+	return cols, out, rows.Err()
+}
+
+func writeJSON(zw *zip.Writer, name string, v any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeCSV(zw *zip.Writer, name string, cols []string, records []map[string]any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(cols); err != nil {
+		return err
+	}
+	row := make([]string, len(cols))
+	for _, rec := range records {
+		for i, col := range cols {
+			row[i] = csvValue(rec[col])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case time.Time:
+		return t.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+type monthHours struct {
+	Month string  `json:"month"`
+	Hours float64 `json:"hours"`
+}
+
+type itemHours struct {
+	ItemID string  `json:"item_id"`
+	Name   string  `json:"name"`
+	Hours  float64 `json:"hours"`
+}
+
+func derivedStats(db *sql.DB, userID string) (fiber.Map, error) {
+	var sessions, items int
+	var hours float64
+	var first, last sql.NullInt64
+	if err := db.QueryRow(`
+		SELECT COUNT(*), COUNT(DISTINCT item_id), MIN(started_at), MAX(COALESCE(ended_at, started_at))
+		FROM play_sessions WHERE user_id = ?`, userID).Scan(&sessions, &items, &first, &last); err != nil {
+		return nil, err
+	}
+	if err := db.QueryRow(`SELECT COALESCE(SUM(duration_seconds), 0) / 3600.0 FROM play_intervals WHERE user_id = ?`,
+		userID).Scan(&hours); err != nil {
+		return nil, err
+	}
+
+	byMonth := []monthHours{}
+	rows, err := db.Query(`
+		SELECT strftime('%Y-%m', start_ts, 'unixepoch') AS m, SUM(duration_seconds) / 3600.0
+		FROM play_intervals WHERE user_id = ? GROUP BY m ORDER BY m`, userID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var m monthHours
+		if rows.Scan(&m.Month, &m.Hours) == nil {
+			byMonth = append(byMonth, m)
+		}
+	}
+	rows.Close()
+
+	topItems := []itemHours{}
+	rows, err = db.Query(`
+		SELECT pi.item_id, COALESCE(MAX(li.name), MAX(ps.item_name), pi.item_id), SUM(pi.duration_seconds) / 3600.0
+		FROM play_intervals pi
+		LEFT JOIN play_sessions ps ON ps.id = pi.session_fk
+		LEFT JOIN library_item li ON li.id = pi.item_id
+		WHERE pi.user_id = ?
+		GROUP BY pi.item_id ORDER BY 3 DESC LIMIT 25`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var it itemHours
+		if rows.Scan(&it.ItemID, &it.Name, &it.Hours) == nil {
+			topItems = append(topItems, it)
+		}
+	}
+
+	out := fiber.Map{
+		"user_id":        userID,
+		"sessions":       sessions,
+		"distinct_items": items,
+		"watch_hours":    hours,
+		"hours_by_month": byMonth,
+		"top_items":      topItems,
+	}
+	if first.Valid {
+		out["first_activity"] = time.Unix(first.Int64, 0).UTC().Format(time.RFC3339)
+	}
+	if last.Valid {
+		out["last_activity"] = time.Unix(last.Int64, 0).UTC().Format(time.RFC3339)
+	}
+	return out, nil
+}
+
+// sanitizeFilename keeps a user id safe for a Content-Disposition filename.
+func sanitizeFilename(s string) string {
+	b := []byte(s)
+	for i, ch := range b {
+		if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '_') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
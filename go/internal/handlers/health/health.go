@@ -2,7 +2,9 @@ package health
 
 import (
 	"database/sql"
+	"emby-analytics/internal/handlers/settings"
 	"emby-analytics/internal/logging"
+	"emby-analytics/internal/tasks"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
@@ -14,6 +16,12 @@ type HealthStatus struct {
 	Database      DatabaseHealth      `json:"database"`
 	DataIntegrity DataIntegrityHealth `json:"data_integrity"`
 	Performance   PerformanceHealth   `json:"performance"`
+	// Maintenance is set while maintenance (read-only) mode is on so the UI can show a banner
+	Maintenance bool `json:"maintenance"`
+	// Readiness is "warming_up" while the initial syncs after startup are still running;
+	// the API already answers, but user and library data may be incomplete
+	Readiness string             `json:"readiness"`
+	Warmup    tasks.WarmupStatus `json:"warmup"`
 }
 
 type DatabaseHealth struct {
@@ -46,7 +54,9 @@ func Health(db *sql.DB) fiber.Handler {
 		status := HealthStatus{
 			OK:        true,
 			Timestamp: time.Now().Format(time.RFC3339),
+			Warmup:    tasks.Warmup(),
 		}
+		status.Readiness = status.Warmup.State
 
 		// Test database connectivity
 		dbStart := time.Now()
@@ -66,6 +76,8 @@ func Health(db *sql.DB) fiber.Handler {
 			stats := db.Stats()
 			status.Database.OpenConns = stats.OpenConnections
 			status.Database.IdleConns = stats.Idle
+
+			status.Maintenance = settings.MaintenanceEnabled(db)
 		}
 
 		// Test data integrity
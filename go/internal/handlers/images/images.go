@@ -14,6 +14,8 @@ import (
 	"github.com/gofiber/fiber/v3"
 
 	"emby-analytics/internal/config"
+	"emby-analytics/internal/httpx"
+	"emby-analytics/internal/imagecache"
 	"emby-analytics/internal/media"
 )
 
@@ -33,7 +35,7 @@ func NewOpts(cfg config.Config) Opts {
 		Quality:          cfg.ImgQuality,
 		PrimaryMaxWidth:  cfg.ImgPrimaryMaxWidth,
 		BackdropMaxWidth: cfg.ImgBackdropMaxWidth,
-		HTTPClient:       &http.Client{Timeout: 20 * time.Second},
+		HTTPClient:       &http.Client{Timeout: 20 * time.Second, Transport: httpx.Transport()},
 	}
 }
 
@@ -46,14 +48,29 @@ func getenvInt(key string, def int) int {
 	return def
 }
 
+// conditionalHeaders are forwarded upstream so the media server can answer 304.
+var conditionalHeaders = []string{"If-None-Match", "If-Modified-Since"}
+
+// passthroughHeaders are copied from the upstream image response.
+var passthroughHeaders = []string{"ETag", "Last-Modified", "Content-Length"}
+
 func proxyImage(c fiber.Ctx, client *http.Client, fullURL string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
+	method := http.MethodGet
+	if c.Method() == fiber.MethodHead {
+		method = http.MethodHead
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, nil)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	for _, h := range conditionalHeaders {
+		if v := c.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -68,11 +85,47 @@ func proxyImage(c fiber.Ctx, client *http.Client, fullURL string) error {
 		c.Set("Content-Type", "image/jpeg")
 	}
 	c.Set("Cache-Control", "public, max-age=3600, s-maxage=3600")
+	for _, h := range passthroughHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			c.Set(h, v)
+		}
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if method == http.MethodHead {
+		if n, err := strconv.Atoi(resp.Header.Get("Content-Length")); err == nil {
+			c.Response().Header.SetContentLength(n)
+		}
+		c.Response().SkipBody = true
+		return nil
+	}
 
 	_, copyErr := io.Copy(c, resp.Body)
 	return copyErr
 }
 
+// notModified reports whether the request's validators match the cached entry.
+// If-None-Match takes precedence over If-Modified-Since (RFC 9110 §13.2.2).
+func notModified(c fiber.Ctx, entry imagecache.Entry) bool {
+	if inm := c.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == entry.ETag {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := c.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !entry.FetchedAt.Truncate(time.Second).After(t)
+		}
+	}
+	return false
+}
+
 // GET /img/primary/:id
 func Primary(opts Opts) fiber.Handler {
 	return func(c fiber.Ctx) error {
@@ -110,10 +163,11 @@ func Backdrop(opts Opts) fiber.Handler {
 }
 
 // MultiServerPrimary handles image requests with server routing: /img/primary/:server/:id
+// Posters are served from the image cache; ?w= selects one of PosterWidths.
+// Cached entries carry an ETag and Last-Modified so revalidations get a 304.
 func MultiServerPrimary(multiServerMgr interface{}) fiber.Handler {
 	mgr, _ := multiServerMgr.(*media.MultiServerManager)
 	primaryWidth := getenvInt("IMG_PRIMARY_MAX_WIDTH", 300)
-	primaryHeight := getenvInt("IMG_PRIMARY_MAX_HEIGHT", int(float64(primaryWidth)*1.5))
 	quality := getenvInt("IMG_QUALITY", 90)
 
 	return func(c fiber.Ctx) error {
@@ -129,13 +183,49 @@ func MultiServerPrimary(multiServerMgr interface{}) fiber.Handler {
 			return c.Status(404).JSON(fiber.Map{"error": "server configuration not found"})
 		}
 
-		imageURL, err := buildServerImageURL(*cfg, id, imageVariantPrimary, primaryWidth, primaryHeight, quality)
-		if err != nil {
-			return c.Status(502).JSON(fiber.Map{"error": err.Error()})
+		width := primaryWidth
+		if w, err := strconv.Atoi(c.Query("w", "")); err == nil && isPosterWidth(w) {
+			width = w
 		}
 
-		httpClient := &http.Client{Timeout: 20 * time.Second}
-		return proxyImage(c, httpClient, imageURL)
+		cache := imagecache.Default()
+		key := posterCacheKey(cfg.ID, id, width)
+		cacheStatus := "HIT"
+		entry, ok := cache.Get(key)
+		if !ok {
+			cacheStatus = "MISS"
+			imageURL, err := buildServerImageURL(*cfg, id, imageVariantPrimary, width, posterHeight(width), quality)
+			if err != nil {
+				return c.Status(502).JSON(fiber.Map{"error": err.Error()})
+			}
+			data, ct, status, err := fetchImage(posterHTTPClient, imageURL)
+			if err != nil {
+				return c.Status(502).JSON(fiber.Map{"error": err.Error()})
+			}
+			if status != http.StatusOK {
+				c.Status(status)
+				return c.Send(data)
+			}
+			entry = cache.Put(key, posterColorKey(cfg.ID, id), data, ct)
+		}
+
+		c.Set("Content-Type", entry.ContentType)
+		c.Set("Cache-Control", "public, max-age=3600, s-maxage=3600")
+		c.Set("X-Cache", cacheStatus)
+		c.Set("ETag", entry.ETag)
+		c.Set("Last-Modified", entry.FetchedAt.UTC().Format(http.TimeFormat))
+		if entry.Color != "" {
+			c.Set("X-Dominant-Color", entry.Color)
+		}
+		if notModified(c, entry) {
+			return c.SendStatus(http.StatusNotModified)
+		}
+		if c.Method() == fiber.MethodHead {
+			c.Response().Header.SetContentLength(len(entry.Data))
+			c.Response().SkipBody = true
+			return nil
+		}
+		return c.Send(entry.Data)
 	}
 }
 
@@ -162,7 +252,7 @@ func MultiServerBackdrop(multiServerMgr interface{}) fiber.Handler {
 			return c.Status(502).JSON(fiber.Map{"error": err.Error()})
 		}
 
-		httpClient := &http.Client{Timeout: 20 * time.Second}
+		httpClient := &http.Client{Timeout: 20 * time.Second, Transport: httpx.Transport()}
 		return proxyImage(c, httpClient, imageURL)
 	}
 }
@@ -0,0 +1,121 @@
+package images
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/imagecache"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/queries"
+)
+
+// dbPaletteStore persists poster palettes in item_palettes, keyed by the
+// cache's server/item color key.
+type dbPaletteStore struct {
+	db *sql.DB
+}
+
+// PaletteStore returns an imagecache.PaletteStore backed by the database.
+func PaletteStore(db *sql.DB) imagecache.PaletteStore {
+	return dbPaletteStore{db: db}
+}
+
+func splitColorKey(colorKey string) (string, string, bool) {
+	serverID, itemID, ok := strings.Cut(colorKey, "/")
+	return serverID, itemID, ok && serverID != "" && itemID != ""
+}
+
+func (s dbPaletteStore) LoadPalette(colorKey string) ([]string, bool) {
+	serverID, itemID, ok := splitColorKey(colorKey)
+	if !ok {
+		return nil, false
+	}
+	palette, err := queries.LoadItemPalette(context.Background(), s.db, serverID, itemID)
+	if err != nil {
+		logging.Warn("failed to load poster palette", "key", colorKey, "error", err)
+		return nil, false
+	}
+	return palette, len(palette) > 0
+}
+
+func (s dbPaletteStore) SavePalette(colorKey string, palette []string) {
+	serverID, itemID, ok := splitColorKey(colorKey)
+	if !ok {
+		return
+	}
+	if err := queries.SaveItemPalette(context.Background(), s.db, serverID, itemID, palette, time.Now().Unix()); err != nil {
+		logging.Warn("failed to save poster palette", "key", colorKey, "error", err)
+	}
+}
+
+// PaletteBackfillResult summarizes one BackfillPalettes run.
+type PaletteBackfillResult struct {
+	Candidates int `json:"candidates"`
+	FromCache  int `json:"from_cache"` // palettes taken from posters already in the image cache
+	Fetched    int `json:"fetched"`
+	Failed     int `json:"failed"`
+}
+
+// BackfillPalettes extracts palettes for up to limit library posters that have none
+// stored yet. Posters already in the image cache are used as they are; the rest are
+// fetched at the default width and cached like any other poster request.
+func BackfillPalettes(ctx context.Context, db *sql.DB, mgr *media.MultiServerManager, limit int) (PaletteBackfillResult, error) {
+	var res PaletteBackfillResult
+	targets, err := queries.MissingItemPalettes(ctx, db, limit)
+	if err != nil {
+		return res, err
+	}
+	res.Candidates = len(targets)
+	cache := imagecache.Default()
+	width := defaultPosterWidth()
+	quality := getenvInt("IMG_QUALITY", 90)
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		colorKey := posterColorKey(t.ServerID, t.ItemID)
+		if savePaletteFromCache(db, t, colorKey) {
+			res.FromCache++
+			continue
+		}
+		cfg := resolveServerConfig(mgr, t.ServerID)
+		if cfg == nil {
+			res.Failed++
+			continue
+		}
+		data, ct, err := fetchPoster(*cfg, t.ItemID, width, quality)
+		if err != nil {
+			res.Failed++
+			continue
+		}
+		if e := cache.Put(posterCacheKey(t.ServerID, t.ItemID, width), colorKey, data, ct); len(e.Palette) == 0 {
+			res.Failed++
+			continue
+		}
+		res.Fetched++
+	}
+	return res, nil
+}
+
+// savePaletteFromCache stores the palette of a poster cached before palettes were
+// persisted, reporting whether one was found.
+func savePaletteFromCache(db *sql.DB, t queries.PaletteTarget, colorKey string) bool {
+	cache := imagecache.Default()
+	palette, ok := cache.Palette(colorKey)
+	if !ok {
+		for _, w := range PosterWidths {
+			if e, hit := cache.Get(posterCacheKey(t.ServerID, t.ItemID, w)); hit {
+				palette, _ = imagecache.Palette(e.Data, imagecache.PaletteSize)
+				break
+			}
+		}
+	}
+	if len(palette) == 0 {
+		return false
+	}
+	PaletteStore(db).SavePalette(colorKey, palette)
+	return true
+}
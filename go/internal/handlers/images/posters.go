@@ -0,0 +1,121 @@
+package images
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"emby-analytics/internal/httpx"
+	"emby-analytics/internal/imagecache"
+	"emby-analytics/internal/media"
+)
+
+// PosterWidths are the poster variants served by /img/primary/:server/:id?w=.
+var PosterWidths = []int{150, 300, 600}
+
+const maxImageBytes = 10 << 20
+
+var posterHTTPClient = &http.Client{Timeout: 20 * time.Second, Transport: httpx.Transport()}
+
+func isPosterWidth(w int) bool {
+	for _, pw := range PosterWidths {
+		if pw == w {
+			return true
+		}
+	}
+	return false
+}
+
+func posterCacheKey(serverID, itemID string, width int) string {
+	return fmt.Sprintf("primary/%s/%s/%d", serverID, itemID, width)
+}
+
+func posterColorKey(serverID, itemID string) string {
+	return serverID + "/" + itemID
+}
+
+// fetchImage downloads an image, returning its bytes, content type and HTTP status.
+func fetchImage(client *http.Client, fullURL string) ([]byte, string, int, error) {
+	resp, err := client.Get(fullURL)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes))
+	if err != nil {
+		return nil, "", 0, err
+	}
+	ct := resp.Header.Get("Content-Type")
+	if ct == "" {
+		ct = "image/jpeg"
+	}
+	return data, ct, resp.StatusCode, nil
+}
+
+// fetchPoster downloads a poster variant from the server.
+func fetchPoster(server media.ServerConfig, itemID string, width, quality int) ([]byte, string, error) {
+	imageURL, err := buildServerImageURL(server, itemID, imageVariantPrimary, width, posterHeight(width), quality)
+	if err != nil {
+		return nil, "", err
+	}
+	data, ct, status, err := fetchImage(posterHTTPClient, imageURL)
+	if err != nil {
+		return nil, "", err
+	}
+	if status != http.StatusOK {
+		return nil, "", fmt.Errorf("http %d", status)
+	}
+	return data, ct, nil
+}
+
+// posterHeight is the height requested for a poster variant: IMG_PRIMARY_MAX_HEIGHT scaled from
+// IMG_PRIMARY_MAX_WIDTH to width, or 2:3 when it is unset.
+func posterHeight(width int) int {
+	baseWidth := getenvInt("IMG_PRIMARY_MAX_WIDTH", 300)
+	baseHeight := getenvInt("IMG_PRIMARY_MAX_HEIGHT", int(float64(baseWidth)*1.5))
+	if baseWidth <= 0 {
+		return int(float64(width) * 1.5)
+	}
+	return baseHeight * width / baseWidth
+}
+
+// defaultPosterWidth is the poster variant prefetched for hints and palettes.
+func defaultPosterWidth() int {
+	width := getenvInt("IMG_PRIMARY_MAX_WIDTH", 300)
+	if !isPosterWidth(width) {
+		width = PosterWidths[len(PosterWidths)/2]
+	}
+	return width
+}
+
+// PosterHints returns ready-to-use poster URLs per width and, once known, the
+// poster's color palette (dominant color first). The default-width poster is
+// fetched into the cache in the background so the palette is available on a
+// later snapshot.
+func PosterHints(mgr *media.MultiServerManager, serverParam, itemID string) (map[string]string, []string) {
+	cfg := resolveServerConfig(mgr, serverParam)
+	if cfg == nil || itemID == "" {
+		return nil, nil
+	}
+	base := "/img/primary/" + url.PathEscape(serverParam) + "/" + url.PathEscape(itemID)
+	variants := make(map[string]string, len(PosterWidths))
+	for _, w := range PosterWidths {
+		variants[strconv.Itoa(w)] = base + "?w=" + strconv.Itoa(w)
+	}
+
+	cache := imagecache.Default()
+	colorKey := posterColorKey(cfg.ID, itemID)
+	palette, ok := cache.Palette(colorKey)
+	if !ok {
+		width := defaultPosterWidth()
+		quality := getenvInt("IMG_QUALITY", 90)
+		server := *cfg
+		cache.Prefetch(posterCacheKey(cfg.ID, itemID, width), colorKey, func() ([]byte, string, error) {
+			return fetchPoster(server, itemID, width, quality)
+		})
+	}
+	return variants, palette
+}
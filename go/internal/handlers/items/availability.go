@@ -0,0 +1,188 @@
+package items
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v3"
+
+	"emby-analytics/internal/media"
+)
+
+type AvailabilityItem struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	Type          string            `json:"type"`
+	Year          *int              `json:"year,omitempty"`
+	Resolution    string            `json:"resolution"`
+	Width         *int              `json:"width,omitempty"`
+	Height        *int              `json:"height,omitempty"`
+	Codec         string            `json:"video_codec,omitempty"`
+	Container     string            `json:"container,omitempty"`
+	BitrateBps    *int64            `json:"bitrate_bps,omitempty"`
+	FileSizeBytes *int64            `json:"file_size_bytes,omitempty"`
+	FilePath      string            `json:"file_path,omitempty"`
+	ProviderIDs   map[string]string `json:"provider_ids,omitempty"`
+}
+
+type ServerAvailability struct {
+	ServerID   string             `json:"server_id"`
+	ServerName string             `json:"server_name"`
+	ServerType string             `json:"server_type"`
+	Available  bool               `json:"available"`
+	Error      string             `json:"error,omitempty"`
+	Items      []AvailabilityItem `json:"items"`
+}
+
+// resolutionLabel buckets by width like the quality stats, falling back to height.
+func resolutionLabel(width, height *int) string {
+	if width != nil && *width > 0 {
+		w := *width
+		switch {
+		case w > 3840:
+			return "8K"
+		case w > 1920:
+			return "4K"
+		case w > 1280:
+			return "1080p"
+		case w >= 1200:
+			return "720p"
+		default:
+			return "SD"
+		}
+	}
+	if height != nil && *height > 0 {
+		h := *height
+		switch {
+		case h >= 4320:
+			return "8K"
+		case h >= 2160:
+			return "4K"
+		case h >= 1080:
+			return "1080p"
+		case h >= 720:
+			return "720p"
+		default:
+			return "SD"
+		}
+	}
+	return "Resolution Not Available"
+}
+
+func searchServers(targets map[string]media.MediaServerClient, q media.ItemQuery, results map[string]*ServerAvailability) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for id, client := range targets {
+		searcher, ok := client.(media.ItemSearcher)
+		if !ok {
+			results[id].Error = "item search is not supported for this server type"
+			continue
+		}
+		wg.Add(1)
+		go func(id string, s media.ItemSearcher) {
+			defer wg.Done()
+			found, err := s.SearchItems(q)
+			mu.Lock()
+			defer mu.Unlock()
+			res := results[id]
+			if err != nil {
+				res.Error = err.Error()
+				return
+			}
+			res.Error = ""
+			for _, it := range found {
+				res.Items = append(res.Items, AvailabilityItem{
+					ID:            it.ID,
+					Name:          it.Name,
+					Type:          it.Type,
+					Year:          it.ProductionYear,
+					Resolution:    resolutionLabel(it.Width, it.Height),
+					Width:         it.Width,
+					Height:        it.Height,
+					Codec:         it.Codec,
+					Container:     it.Container,
+					BitrateBps:    it.BitrateBps,
+					FileSizeBytes: it.FileSizeBytes,
+					FilePath:      it.FilePath,
+					ProviderIDs:   it.ProviderIDs,
+				})
+			}
+			res.Available = len(res.Items) > 0
+		}(id, searcher)
+	}
+	wg.Wait()
+}
+
+// GET /items/availability?name=&year=&provider=imdb:tt0111161
+// Searches every enabled server and reports which ones have the movie/series and
+// in what quality. Servers that can't filter by provider ID (Jellyfin, Plex) are
+// re-queried by the name found on another server when only a provider ID is given.
+func Availability(mgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		q := media.ItemQuery{
+			Name:       strings.TrimSpace(c.Query("name", "")),
+			ProviderID: strings.TrimSpace(c.Query("provider", "")),
+		}
+		if y, err := strconv.Atoi(c.Query("year", "")); err == nil && y > 0 {
+			q.Year = y
+		}
+		if q.ProviderID != "" {
+			if _, _, ok := media.SplitProviderID(q.ProviderID); !ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "provider must look like imdb:tt0111161"})
+			}
+		}
+		if q.Name == "" && q.ProviderID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name or provider is required"})
+		}
+
+		targets := mgr.GetEnabledClients()
+		results := make(map[string]*ServerAvailability, len(targets))
+		for id, client := range targets {
+			results[id] = &ServerAvailability{
+				ServerID:   id,
+				ServerName: client.GetServerName(),
+				ServerType: string(client.GetServerType()),
+				Items:      []AvailabilityItem{},
+			}
+		}
+		searchServers(targets, q, results)
+
+		// Provider-only lookup: retry the servers without hits using the resolved title
+		if q.Name == "" {
+			for _, res := range results {
+				if len(res.Items) > 0 {
+					q.Name = res.Items[0].Name
+					break
+				}
+			}
+			if q.Name != "" {
+				retry := map[string]media.MediaServerClient{}
+				for id, res := range results {
+					if !res.Available && res.Error == "" {
+						retry[id] = targets[id]
+					}
+				}
+				searchServers(retry, q, results)
+			}
+		}
+
+		out := make([]ServerAvailability, 0, len(results))
+		availableOn := []string{}
+		for _, res := range results {
+			out = append(out, *res)
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].ServerID < out[j].ServerID })
+		for _, res := range out {
+			if res.Available {
+				availableOn = append(availableOn, res.ServerID)
+			}
+		}
+		return c.JSON(fiber.Map{
+			"query":        fiber.Map{"name": q.Name, "year": q.Year, "provider": q.ProviderID},
+			"available_on": availableOn,
+			"servers":      out,
+		})
+	}
+}
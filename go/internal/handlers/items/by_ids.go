@@ -10,6 +10,7 @@ import (
 
 	"emby-analytics/internal/emby"
 	"emby-analytics/internal/media"
+	"emby-analytics/internal/queries"
 )
 
 type ItemRow struct {
@@ -17,6 +18,8 @@ type ItemRow struct {
 	Name    string `json:"name,omitempty"`
 	Type    string `json:"type,omitempty"`
 	Display string `json:"display,omitempty"`
+	// Poster color palette, dominant color first, once the poster has been cached
+	Palette []string `json:"palette,omitempty"`
 }
 
 // GET /items/by-ids?ids=a,b,c
@@ -127,9 +130,14 @@ func ByIDs(db *sql.DB, em *emby.Client) fiber.Handler {
 		}
 
 		// 3) Build output in the same order as requested
+		palettes, err := queries.ItemPalettes(c, db, ids)
+		if err != nil {
+			log.Printf("Palette lookup failed: %v", err)
+		}
 		out := make([]ItemRow, 0, len(ids))
 		for _, id := range ids {
 			if r, ok := base[id]; ok {
+				r.Palette = palettes[id]
 				// Ensure we have at least basic info
 				if r.Name == "" && r.Type == "" {
 					// Item exists in DB but has no data - try to get from Emby directly
@@ -331,9 +339,14 @@ func ByIDsMS(db *sql.DB, mgr *media.MultiServerManager) fiber.Handler {
 		}
 
 		// Build output in request order
+		palettes, err := queries.ItemPalettes(c, db, ids)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
 		out := make([]ItemRow, 0, len(ids))
 		for _, id := range ids {
 			if r, ok := base[id]; ok {
+				r.Palette = palettes[id]
 				if r.Display == "" {
 					if r.Name != "" {
 						r.Display = r.Name
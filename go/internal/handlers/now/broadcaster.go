@@ -21,6 +21,14 @@ type Broadcaster struct {
 	cancel     context.CancelFunc
 	// Optional callback to run server-side processing each poll
 	SessionProcessor func()
+	// Optional poll cadence replacing the fixed interval (webhook-primary polling)
+	Cadence PollCadence
+}
+
+// PollCadence varies the polling interval and can request an immediate poll.
+type PollCadence interface {
+	Interval(now time.Time) time.Duration
+	Wake() <-chan struct{}
 }
 
 // NewBroadcaster creates a new broadcaster instance
@@ -79,6 +87,10 @@ func (b *Broadcaster) RemoveClient(conn *ws.Conn) {
 
 // broadcastLoop is the main polling and broadcasting goroutine
 func (b *Broadcaster) broadcastLoop() {
+	if b.Cadence != nil {
+		b.cadenceLoop()
+		return
+	}
 	ticker := time.NewTicker(b.interval)
 	defer ticker.Stop()
 
@@ -95,6 +107,32 @@ func (b *Broadcaster) broadcastLoop() {
 	}
 }
 
+// cadenceLoop polls at the interval the cadence picks after each poll, or right away when
+// it wakes the loop.
+func (b *Broadcaster) cadenceLoop() {
+	b.broadcast()
+	timer := time.NewTimer(b.Cadence.Interval(time.Now()))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-timer.C:
+		case <-b.Cadence.Wake():
+			// Drop a tick that fired meanwhile, so it doesn't cut the next wait short
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+		b.broadcast()
+		timer.Reset(b.Cadence.Interval(time.Now()))
+	}
+}
+
 // broadcast fetches data from Emby and sends to all connected clients
 func (b *Broadcaster) broadcast() {
 	// MODIFIED: Fetch entries first. If it fails, do not broadcast.
@@ -128,7 +166,7 @@ func (b *Broadcaster) sendToClient(conn *ws.Conn) {
 }
 
 func (b *Broadcaster) sendToClientWithData(conn *ws.Conn, entries []NowEntry) {
-	if err := conn.WriteJSON(entries); err != nil {
+	if err := conn.WriteJSON(anonymizeEntries(entries, connViewer(conn))); err != nil {
 		b.RemoveClient(conn)
 		_ = conn.Close()
 	}
@@ -158,6 +196,7 @@ func (b *Broadcaster) fetchNowPlayingEntries() ([]NowEntry, error) {
 			Timestamp:   nowTime,
 			Title:       s.ItemName,
 			User:        s.UserName,
+			userID:      s.UserID,
 			App:         s.App,
 			Device:      s.Device,
 			PlayMethod:  s.PlayMethod,
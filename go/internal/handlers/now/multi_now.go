@@ -8,13 +8,28 @@ import (
 
 	"github.com/gofiber/fiber/v3"
 
+	"emby-analytics/internal/handlers/images"
 	"emby-analytics/internal/media"
+	"emby-analytics/internal/middleware"
 	"context"
 )
 
 // multiServerMgr holds the global multi-server manager for handlers
 var multiServerMgr *media.MultiServerManager
 
+// posterHints returns cached poster variants, dominant color and palette for a session's poster
+func posterHints(itemType, itemID, seriesID, serverType string) (map[string]string, string, []string) {
+	id := itemID
+	if itemType == "Episode" && seriesID != "" {
+		id = seriesID
+	}
+	variants, palette := images.PosterHints(multiServerMgr, serverType, id)
+	if len(palette) == 0 {
+		return variants, "", nil
+	}
+	return variants, palette[0], palette
+}
+
 // SetMultiServerManager sets the manager for multi-server handlers
 func SetMultiServerManager(mgr *media.MultiServerManager) {
 	multiServerMgr = mgr
@@ -33,13 +48,23 @@ func MultiSnapshot(c fiber.Ctx) error {
 			if ss, err := multiServerMgr.GetAllSessionsCached(context.Background()); err == nil {
 				sessions = ss
 			}
+			middleware.SetServerWarnings(c, multiServerMgr.ServerWarnings())
 		case string(media.ServerTypeEmby), string(media.ServerTypePlex), string(media.ServerTypeJellyfin):
 			// Filter strictly by server type alias
 			for _, client := range multiServerMgr.ClientsByType(media.ServerType(lf)) {
-				if ss, err := client.GetActiveSessions(); err == nil {
+				ss, err := client.GetActiveSessions()
+				multiServerMgr.RecordServerResult(client.GetServerID(), err)
+				if err == nil {
 					sessions = append(sessions, ss...)
 				}
 			}
+			var warnings []media.ServerWarning
+			for _, w := range multiServerMgr.ServerWarnings() {
+				if string(w.ServerType) == lf {
+					warnings = append(warnings, w)
+				}
+			}
+			middleware.SetServerWarnings(c, warnings)
 		default:
 			// Unknown alias: return empty (no fallback to ID)
 		}
@@ -79,6 +104,7 @@ func MultiSnapshot(c fiber.Ctx) error {
 						Timestamp:   nowMs,
 						Title:       s.ItemName,
 						User:        s.UserName,
+						userID:      s.UserID,
 						App:         s.App,
 						Device:      s.Device,
 						PlayMethod:  s.PlayMethod,
@@ -133,7 +159,7 @@ func MultiSnapshot(c fiber.Ctx) error {
 						ServerType: "emby",
 					})
 				}
-				return c.JSON(out)
+				return c.JSON(anonymizeEntries(out, middleware.CurrentViewer(c)))
 			}
 		}
 	}
@@ -156,37 +182,45 @@ func MultiSnapshot(c fiber.Ctx) error {
 			subsText = strconv.Itoa(s.SubtitleCount)
 		}
 		poster := ""
+		var posterVariants map[string]string
+		var posterColor string
+		var posterPalette []string
 		if s.ItemID != "" {
 			poster = getPosterURL(s.ItemType, s.ItemID, s.SeriesID, string(s.ServerType))
+			posterVariants, posterColor, posterPalette = posterHints(s.ItemType, s.ItemID, s.SeriesID, string(s.ServerType))
 		}
 
 		entry := NowEntry{
-			Timestamp:   nowMs,
-			Title:       s.ItemName,
-			User:        s.UserName,
-			App:         s.ClientApp,
-			Device:      s.DeviceName,
-			PlayMethod:  s.PlayMethod,
-			Video:       strings.TrimSpace(videoDetailFromNormalized(s)),
-			Audio:       strings.TrimSpace(audioDetailFromNormalized(s)),
-			Subs:        subsText,
-			Bitrate:     s.Bitrate,
-			ProgressPct: progressPct,
-			PositionSec: s.PositionMs / 1000,
-			DurationSec: s.DurationMs / 1000,
-			Poster:      poster,
-			SessionID:   s.SessionID,
-			ItemID:      s.ItemID,
-			ItemType:    s.ItemType,
-			Container:   s.Container,
-			Width:       s.Width,
-			Height:      s.Height,
-			DolbyVision: s.DolbyVision,
-			HDR10:       s.HDR10,
-			AudioLang:   s.AudioLanguage,
-			AudioCh:     s.AudioChannels,
-			SubLang:     s.SubtitleLanguage,
-			SubCodec:    s.SubtitleCodec,
+			Timestamp:      nowMs,
+			Title:          s.ItemName,
+			User:           s.UserName,
+			userID:         s.UserID,
+			App:            s.ClientApp,
+			Device:         s.DeviceName,
+			PlayMethod:     s.PlayMethod,
+			Video:          strings.TrimSpace(videoDetailFromNormalized(s)),
+			Audio:          strings.TrimSpace(audioDetailFromNormalized(s)),
+			Subs:           subsText,
+			Bitrate:        s.Bitrate,
+			ProgressPct:    progressPct,
+			PositionSec:    s.PositionMs / 1000,
+			DurationSec:    s.DurationMs / 1000,
+			Poster:         poster,
+			PosterVariants: posterVariants,
+			PosterColor:    posterColor,
+			PosterPalette:  posterPalette,
+			SessionID:      s.SessionID,
+			ItemID:         s.ItemID,
+			ItemType:       s.ItemType,
+			Container:      s.Container,
+			Width:          s.Width,
+			Height:         s.Height,
+			DolbyVision:    s.DolbyVision,
+			HDR10:          s.HDR10,
+			AudioLang:      s.AudioLanguage,
+			AudioCh:        s.AudioChannels,
+			SubLang:        s.SubtitleLanguage,
+			SubCodec:       s.SubtitleCodec,
 			// Transcode details
 			TransVideoFrom: strings.ToUpper(s.VideoCodec),
 			TransVideoTo:   strings.ToUpper(s.TranscodeVideoCodec),
@@ -215,9 +249,10 @@ func MultiSnapshot(c fiber.Ctx) error {
 		// Server metadata for UI filtering/coloring
 		entry.ServerID = s.ServerID
 		entry.ServerType = string(s.ServerType)
+		attachNote(&entry)
 		out = append(out, entry)
 	}
-	return c.JSON(out)
+	return c.JSON(anonymizeEntries(out, middleware.CurrentViewer(c)))
 }
 
 // MultiPauseSession pauses or resumes a session on a specific server
@@ -238,15 +273,9 @@ func MultiPauseSession(c fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 	if body.Paused != nil && !*body.Paused {
-		if err := client.UnpauseSession(sessionID); err != nil {
-			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
-		}
-		return c.SendStatus(fiber.StatusNoContent)
+		return controlSession(c, actionUnpause, sessionID, func() error { return client.UnpauseSession(sessionID) }, clientSessionState(client, sessionID))
 	}
-	if err := client.PauseSession(sessionID); err != nil {
-		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
-	}
-	return c.SendStatus(fiber.StatusNoContent)
+	return controlSession(c, actionPause, sessionID, func() error { return client.PauseSession(sessionID) }, clientSessionState(client, sessionID))
 }
 
 // MultiStopSession stops a session on a specific server
@@ -261,10 +290,7 @@ func MultiStopSession(c fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	if err := client.StopSession(sessionID); err != nil {
-		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
-	}
-	return c.SendStatus(fiber.StatusNoContent)
+	return controlSession(c, actionStop, sessionID, func() error { return client.StopSession(sessionID) }, clientSessionState(client, sessionID))
 }
 
 // MultiMessageSession sends a message to a session on a specific server
@@ -338,6 +364,23 @@ func resolveServerClient(alias string) (media.MediaServerClient, error) {
 	}
 }
 
+// clientSessionState reports whether a session is still active on a server, and whether it
+// is paused, from the server's active sessions.
+func clientSessionState(client media.MediaServerClient, sessionID string) sessionState {
+	return func() (bool, bool, error) {
+		sessions, err := client.GetActiveSessions()
+		if err != nil {
+			return false, false, err
+		}
+		for _, s := range sessions {
+			if s.SessionID == sessionID {
+				return true, s.IsPaused, nil
+			}
+		}
+		return false, false, nil
+	}
+}
+
 // Helpers mapping normalized session to UI strings
 func videoDetailFromNormalized(s media.Session) string {
 	parts := []string{}
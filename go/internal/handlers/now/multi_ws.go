@@ -35,7 +35,7 @@ func MultiWS() func(*ws.Conn) {
 				_ = conn.WriteJSON([]NowEntry{})
 				return true
 			}
-			if err := conn.WriteJSON(entries); err != nil {
+			if err := conn.WriteJSON(anonymizeEntries(entries, connViewer(conn))); err != nil {
 				return false
 			}
 			return true
@@ -101,14 +101,19 @@ func fetchMultiNowEntries(filter string) ([]NowEntry, error) {
 			subsText = fmt.Sprintf("%d", s.SubtitleCount)
 		}
 		poster := ""
+		var posterVariants map[string]string
+		var posterColor string
+		var posterPalette []string
 		if s.ItemID != "" {
 			poster = getPosterURL(s.ItemType, s.ItemID, s.SeriesID, string(s.ServerType))
+			posterVariants, posterColor, posterPalette = posterHints(s.ItemType, s.ItemID, s.SeriesID, string(s.ServerType))
 		}
 
 		e := NowEntry{
 			Timestamp:      nowMs,
 			Title:          s.ItemName,
 			User:           s.UserName,
+			userID:         s.UserID,
 			App:            s.ClientApp,
 			Device:         s.DeviceName,
 			PlayMethod:     s.PlayMethod,
@@ -120,6 +125,9 @@ func fetchMultiNowEntries(filter string) ([]NowEntry, error) {
 			PositionSec:    s.PositionMs / 1000,
 			DurationSec:    s.DurationMs / 1000,
 			Poster:         poster,
+			PosterVariants: posterVariants,
+			PosterColor:    posterColor,
+			PosterPalette:  posterPalette,
 			SessionID:      s.SessionID,
 			ItemID:         s.ItemID,
 			ItemType:       s.ItemType,
@@ -156,6 +164,7 @@ func fetchMultiNowEntries(filter string) ([]NowEntry, error) {
 			e.StreamDetail = fmt.Sprintf("%s (%s)", e.StreamPath, mbps(s.Bitrate))
 			e.TransVideoBitrate = s.TranscodeBitrate
 		}
+		attachNote(&e)
 		out = append(out, e)
 	}
 	return out, nil
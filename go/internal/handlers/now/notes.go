@@ -0,0 +1,137 @@
+package now
+
+import (
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	maxNoteLength = 500
+	maxTagLength  = 32
+	maxTags       = 10
+	noteRetention = 24 * time.Hour
+)
+
+// sessionNote is an admin annotation on a live session. It is kept in memory for
+// the Now Playing views and written to the session's play_sessions row.
+type sessionNote struct {
+	Note  string
+	Tags  []string
+	SetAt time.Time
+}
+
+var (
+	notesMu      sync.RWMutex
+	sessionNotes = map[string]sessionNote{}
+)
+
+func noteKey(serverID, sessionID string) string {
+	return serverID + "|" + sessionID
+}
+
+// noteFor returns the note attached to a live session, if any.
+func noteFor(serverID, sessionID string) (sessionNote, bool) {
+	notesMu.RLock()
+	defer notesMu.RUnlock()
+	n, ok := sessionNotes[noteKey(serverID, sessionID)]
+	return n, ok
+}
+
+func setNote(serverID, sessionID string, n sessionNote) {
+	notesMu.Lock()
+	defer notesMu.Unlock()
+	// Drop notes for sessions that ended long ago
+	for k, v := range sessionNotes {
+		if time.Since(v.SetAt) > noteRetention {
+			delete(sessionNotes, k)
+		}
+	}
+	if n.Note == "" && len(n.Tags) == 0 {
+		delete(sessionNotes, noteKey(serverID, sessionID))
+		return
+	}
+	sessionNotes[noteKey(serverID, sessionID)] = n
+}
+
+// attachNote copies a session's note and tags onto a Now Playing entry.
+func attachNote(e *NowEntry) {
+	if n, ok := noteFor(e.ServerID, e.SessionID); ok {
+		e.Note = n.Note
+		e.Tags = n.Tags
+	}
+}
+
+// normalizeTags lowercases, trims and de-duplicates tags. Commas are stripped
+// because tags are stored comma-separated.
+func normalizeTags(in []string) []string {
+	out := make([]string, 0, len(in))
+	seen := map[string]bool{}
+	for _, t := range in {
+		t = strings.Join(strings.Fields(strings.ReplaceAll(t, ",", " ")), " ")
+		t = strings.ToLower(sanitizeMessageInput(t, maxTagLength))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+		if len(out) == maxTags {
+			break
+		}
+	}
+	return out
+}
+
+// MultiSessionNote attaches a note and/or tags to an active session.
+// POST /api/now/sessions/:server/:id/note  body: {"note":"...", "tags":["buffering"]}
+// An empty note with no tags clears the annotation.
+func MultiSessionNote(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		serverAlias := strings.ToLower(c.Params("server"))
+		sessionID := c.Params("id")
+		if multiServerMgr == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "multi-server not initialized"})
+		}
+		client, err := resolveServerClient(serverAlias)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		var body struct {
+			Note string   `json:"note"`
+			Tags []string `json:"tags"`
+		}
+		if err := c.Bind().Body(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON body"})
+		}
+		note := sessionNote{
+			Note:  sanitizeMessageInput(body.Note, maxNoteLength),
+			Tags:  normalizeTags(body.Tags),
+			SetAt: time.Now(),
+		}
+
+		serverID := client.GetServerID()
+		res, err := db.Exec(`
+			UPDATE play_sessions
+			SET note = NULLIF(?, ''), tags = NULLIF(?, '')
+			WHERE server_id = ? AND session_id = ? AND is_active = true`,
+			note.Note, strings.Join(note.Tags, ","), serverID, sessionID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "session is not being tracked yet"})
+		}
+
+		setNote(serverID, sessionID, note)
+		return c.JSON(fiber.Map{
+			"server_id":  serverID,
+			"session_id": sessionID,
+			"note":       note.Note,
+			"tags":       note.Tags,
+		})
+	}
+}
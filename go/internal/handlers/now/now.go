@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"database/sql"
 	"emby-analytics/internal/logging"
+	"emby-analytics/internal/middleware"
 	"encoding/json"
 	"fmt"
 	"html"
@@ -35,7 +36,12 @@ type NowEntry struct {
 	PositionSec int64  `json:"position_sec,omitempty"`
 	DurationSec int64  `json:"duration_sec,omitempty"`
 	Poster      string `json:"poster"`
-	SessionID   string `json:"session_id"`
+	// Cached poster URLs keyed by width, plus the poster's dominant color for placeholders
+	// and a small palette (dominant first) for themed cards
+	PosterVariants map[string]string `json:"poster_variants,omitempty"`
+	PosterColor    string            `json:"poster_color,omitempty"`
+	PosterPalette  []string          `json:"poster_palette,omitempty"`
+	SessionID      string            `json:"session_id"`
 
 	ItemID   string `json:"item_id"`
 	ItemType string `json:"item_type,omitempty"`
@@ -80,6 +86,12 @@ type NowEntry struct {
 	ServerID   string `json:"server_id,omitempty"`
 	ServerType string `json:"server_type,omitempty"`
 	SeriesID   string `json:"series_id,omitempty"`
+
+	// Admin annotation set via POST /api/now/sessions/:server/:id/note
+	Note string   `json:"note,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+
+	userID string // media user, for hiding users who opted out (see anonymizeEntries)
 }
 
 // getPosterURL returns the appropriate poster URL for a media session
@@ -350,6 +362,7 @@ func Snapshot(c fiber.Ctx) error {
 			Timestamp:   nowMs,
 			Title:       s.ItemName,
 			User:        s.UserName,
+			userID:      s.UserID,
 			App:         s.App,
 			Device:      s.Device,
 			PlayMethod:  s.PlayMethod,
@@ -441,7 +454,7 @@ func Snapshot(c fiber.Ctx) error {
 			IsPaused: s.IsPaused,
 		})
 	}
-	return c.JSON(out)
+	return c.JSON(anonymizeEntries(out, middleware.CurrentViewer(c)))
 }
 
 // Stream pushes snapshots periodically via SSE (default message events).
@@ -523,6 +536,7 @@ func Stream(c fiber.Ctx) error {
 				Timestamp:   nowMs,
 				Title:       s.ItemName,
 				User:        s.UserName,
+				userID:      s.UserID,
 				App:         s.App,
 				Device:      s.Device,
 				PlayMethod:  s.PlayMethod,
@@ -663,15 +677,9 @@ func PauseSession(c fiber.Ctx) error {
 	}
 
 	if body.Paused != nil && !*body.Paused {
-		if err := em.Unpause(id); err != nil {
-			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
-		}
-		return c.SendStatus(fiber.StatusNoContent)
-	}
-	if err := em.Pause(id); err != nil {
-		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
+		return controlSession(c, actionUnpause, id, func() error { return em.Unpause(id) }, embySessionState(em, id))
 	}
-	return c.SendStatus(fiber.StatusNoContent)
+	return controlSession(c, actionPause, id, func() error { return em.Pause(id) }, embySessionState(em, id))
 }
 
 // POST /now/sessions/:id/stop
@@ -681,10 +689,23 @@ func StopSession(c fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	if err := em.Stop(id); err != nil {
-		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
+	return controlSession(c, actionStop, id, func() error { return em.Stop(id) }, embySessionState(em, id))
+}
+
+// embySessionState looks a session up among Emby's active sessions.
+func embySessionState(em *emby.Client, sessionID string) sessionState {
+	return func() (bool, bool, error) {
+		sessions, err := em.GetActiveSessions()
+		if err != nil {
+			return false, false, err
+		}
+		for _, s := range sessions {
+			if s.SessionID == sessionID {
+				return true, s.IsPaused, nil
+			}
+		}
+		return false, false, nil
 	}
-	return c.SendStatus(fiber.StatusNoContent)
 }
 
 // POST /now/sessions/:id/message  body: {header?, text, timeout_ms?}
@@ -0,0 +1,63 @@
+package now
+
+import (
+	"context"
+	"database/sql"
+
+	ws "github.com/saveblush/gofiber3-contrib/websocket"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/middleware"
+	"emby-analytics/internal/queries"
+)
+
+var privacyDB *sql.DB
+
+// SetDB sets the database used to look up users who hide from other viewers.
+func SetDB(db *sql.DB) {
+	privacyDB = db
+}
+
+// connViewer returns the Viewer of the request that opened a websocket.
+func connViewer(conn *ws.Conn) middleware.Viewer {
+	v, _ := conn.Locals(middleware.ViewerLocalsKey).(middleware.Viewer)
+	return v
+}
+
+// anonymizeEntries returns entries with the names of users hidden from v replaced by
+// "Anonymous". Entries are copied, never modified, since snapshots are shared between
+// connections.
+func anonymizeEntries(entries []NowEntry, v middleware.Viewer) []NowEntry {
+	if len(entries) == 0 {
+		return entries
+	}
+	hides := hiddenFrom(v)
+	if hides == nil {
+		return entries
+	}
+	out := make([]NowEntry, len(entries))
+	copy(out, entries)
+	for i := range out {
+		if hides(out[i].userID) {
+			out[i].User = queries.AnonymousName
+		}
+	}
+	return out
+}
+
+// hiddenFrom returns whether a media user's name is hidden from v, or nil when v may see
+// every name. Everyone is hidden when the lookup fails.
+func hiddenFrom(v middleware.Viewer) func(userID string) bool {
+	if v.Admin || privacyDB == nil {
+		return nil
+	}
+	hidden, err := queries.HiddenUsers(context.Background(), privacyDB, v.AppUserID)
+	if err != nil {
+		logging.Warn("failed to load hidden users; anonymizing all", "error", err)
+		return func(string) bool { return true }
+	}
+	if len(hidden) == 0 {
+		return nil
+	}
+	return func(userID string) bool { return hidden[userID] }
+}
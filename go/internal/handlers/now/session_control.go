@@ -0,0 +1,96 @@
+package now
+
+import (
+	"errors"
+	"time"
+
+	"emby-analytics/internal/httpx"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Outcomes of a session control command.
+const (
+	ControlApplied      = "applied"       // the server reports the new state
+	ControlNotSupported = "not_supported" // the server or player rejected the command
+	ControlTimeout      = "timeout"       // accepted, but the state didn't change in time
+)
+
+// Control actions.
+const (
+	actionPause   = "pause"
+	actionUnpause = "unpause"
+	actionStop    = "stop"
+)
+
+// How long and how often a command's effect is polled for. Plex players in particular accept
+// commands they never carry out, so a 2xx alone doesn't mean anything happened.
+var (
+	controlConfirmTimeout = 5 * time.Second
+	controlPollInterval   = 500 * time.Millisecond
+)
+
+// ControlResult reports whether a pause, unpause or stop command took effect.
+type ControlResult struct {
+	Outcome   string `json:"outcome"`
+	Action    string `json:"action"`
+	SessionID string `json:"session_id"`
+	WaitedMs  int64  `json:"waited_ms"`
+	// Paused is the session's last seen pause state; nil once it's gone or never seen
+	Paused *bool  `json:"paused"`
+	Error  string `json:"error,omitempty"`
+}
+
+// sessionState looks up a session on its server: whether it is still active and paused.
+type sessionState func() (active, paused bool, err error)
+
+// controlSession sends a control command and polls the session until the server reports the
+// expected state or controlConfirmTimeout passes. Rejected commands are reported as
+// not_supported; other upstream failures as 502.
+func controlSession(c fiber.Ctx, action, sessionID string, send func() error, state sessionState) error {
+	res := ControlResult{Action: action, SessionID: sessionID}
+	if err := send(); err != nil {
+		if errors.Is(err, httpx.ErrCommandRejected) {
+			res.Outcome = ControlNotSupported
+			res.Error = err.Error()
+			return c.JSON(res)
+		}
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	start := time.Now()
+	deadline := start.Add(controlConfirmTimeout)
+	res.Outcome = ControlTimeout
+	for {
+		active, paused, err := state()
+		if err == nil {
+			if active {
+				res.Paused = &paused
+			} else {
+				res.Paused = nil
+			}
+			if controlApplied(action, active, paused) {
+				res.Outcome = ControlApplied
+				break
+			}
+		}
+		if time.Now().Add(controlPollInterval).After(deadline) {
+			break
+		}
+		time.Sleep(controlPollInterval)
+	}
+	res.WaitedMs = time.Since(start).Milliseconds()
+	return c.JSON(res)
+}
+
+func controlApplied(action string, active, paused bool) bool {
+	switch action {
+	case actionStop:
+		return !active
+	case actionPause:
+		return active && paused
+	case actionUnpause:
+		return active && !paused
+	}
+	return false
+}
@@ -5,15 +5,22 @@ import (
 	"strings"
 	"sync"
 
+	"emby-analytics/internal/hostmetrics"
+	"emby-analytics/internal/media"
+
 	"github.com/gofiber/fiber/v3"
 )
 
 // NowPlayingSummary is a compact metrics payload for the Now Playing header.
 // outbound_mbps is a 5-sample rolling average of all active session bitrates.
+// host_tx_mbps is the measured NIC outbound rate, present only when host metrics are enabled.
 type NowPlayingSummary struct {
-	OutboundMbps     float64 `json:"outbound_mbps"`
-	ActiveStreams    int     `json:"active_streams"`
-	ActiveTranscodes int     `json:"active_transcodes"`
+	OutboundMbps     float64  `json:"outbound_mbps"`
+	ActiveStreams    int      `json:"active_streams"`
+	ActiveTranscodes int      `json:"active_transcodes"`
+	HostTxMbps       *float64 `json:"host_tx_mbps,omitempty"`
+	// Warnings lists servers whose sessions are missing from these numbers
+	Warnings []media.ServerWarning `json:"warnings,omitempty"`
 }
 
 // ring buffer for smoothing outbound_mbps (approx 5s window at 1s+ polling)
@@ -56,6 +63,22 @@ func (r *mbpsRing) avgOr(v float64) float64 {
 
 var summaryRing = newMbpsRing(5)
 
+// isTranscoding reports whether a session is actually re-encoding. Remux-only sessions
+// (PlayMethod=Transcode but codecs copied) intentionally don't count.
+func isTranscoding(videoMethod, audioMethod string, reasons []string) bool {
+	if strings.EqualFold(videoMethod, "Transcode") || strings.EqualFold(audioMethod, "Transcode") {
+		return true
+	}
+	// Heuristic: subtitles/burn-in indicated by reasons
+	for _, r := range reasons {
+		rr := strings.ToLower(r)
+		if strings.Contains(rr, "subtitle") || strings.Contains(rr, "burn") {
+			return true
+		}
+	}
+	return false
+}
+
 // minimal shape used within this file for aggregation
 type embySessionLite struct {
 	IsPaused      bool
@@ -72,20 +95,21 @@ type embySessionLite struct {
 func Summary(c fiber.Ctx) error {
 	// Prefer multi-server aggregation when available
 	var sessionsEmb []embySessionLite
+	var warnings []media.ServerWarning
 	if multiServerMgr != nil {
-		if ss, err := multiServerMgr.GetAllSessions(); err == nil {
-			// Convert normalized sessions to a minimal shape
-			for _, s := range ss {
-				sessionsEmb = append(sessionsEmb, embySessionLite{
-					IsPaused:      s.IsPaused,
-					VideoMethod:   s.VideoMethod,
-					AudioMethod:   s.AudioMethod,
-					TransReasons:  s.TranscodeReasons,
-					Bitrate:       s.Bitrate,
-					TransVideoBit: s.TranscodeBitrate,
-					TransAudioBit: 0, // not currently tracked per-audio in normalized type
-				})
-			}
+		var ss []media.Session
+		ss, warnings = multiServerMgr.GetAllSessionsWithWarnings()
+		// Convert normalized sessions to a minimal shape
+		for _, s := range ss {
+			sessionsEmb = append(sessionsEmb, embySessionLite{
+				IsPaused:      s.IsPaused,
+				VideoMethod:   s.VideoMethod,
+				AudioMethod:   s.AudioMethod,
+				TransReasons:  s.TranscodeReasons,
+				Bitrate:       s.Bitrate,
+				TransVideoBit: s.TranscodeBitrate,
+				TransAudioBit: 0, // not currently tracked per-audio in normalized type
+			})
 		}
 	}
 	if len(sessionsEmb) == 0 {
@@ -119,22 +143,7 @@ func Summary(c fiber.Ctx) error {
 		}
 		active++
 
-		// Determine if this session is actually transcoding (re-encoding)
-		// We intentionally do NOT count remux-only sessions (PlayMethod=Transcode but codecs copied)
-		isTrans := false
-		if strings.EqualFold(s.VideoMethod, "Transcode") || strings.EqualFold(s.AudioMethod, "Transcode") {
-			isTrans = true
-		} else if len(s.TransReasons) > 0 {
-			// Heuristic: subtitles/burn-in indicated by reasons
-			for _, r := range s.TransReasons {
-				rr := strings.ToLower(r)
-				if strings.Contains(rr, "subtitle") || strings.Contains(rr, "burn") {
-					isTrans = true
-					break
-				}
-			}
-		}
-		if isTrans {
+		if isTranscoding(s.VideoMethod, s.AudioMethod, s.TransReasons) {
 			transcodes++
 		}
 
@@ -160,9 +169,16 @@ func Summary(c fiber.Ctx) error {
 	avg := summaryRing.avgOr(mbps)
 	avg = math.Round(avg*10) / 10
 
-	return c.JSON(NowPlayingSummary{
+	out := NowPlayingSummary{
 		OutboundMbps:     avg,
 		ActiveStreams:    active,
 		ActiveTranscodes: transcodes,
-	})
+		Warnings:         warnings,
+	}
+	if hc := hostmetrics.Default(); hc != nil {
+		if tx, ok := hc.LatestTxMbps(); ok {
+			out.HostTxMbps = &tx
+		}
+	}
+	return c.JSON(out)
 }
@@ -0,0 +1,115 @@
+package now
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/middleware"
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// transcodingMaxAge is how long dashboards may cache /api/now/transcoding, in seconds.
+const transcodingMaxAge = 5
+
+// TranscodingSession is the compact shape of one transcoding session for dashboard widgets.
+type TranscodingSession struct {
+	ServerID    string  `json:"server_id"`
+	User        string  `json:"user"`
+	Item        string  `json:"item"`
+	Reason      string  `json:"reason,omitempty"`
+	Video       string  `json:"video,omitempty"` // e.g. "HEVC → H264"
+	Speed       float64 `json:"speed,omitempty"` // x realtime, Plex only
+	FPS         float64 `json:"fps,omitempty"`   // encoder fps, Emby/Jellyfin only
+	Hardware    bool    `json:"hw"`
+	BitrateMbps float64 `json:"bitrate_mbps"`
+	Progress    float64 `json:"progress,omitempty"` // transcode completion %
+	Paused      bool    `json:"paused"`
+}
+
+// TranscodingSummary is the response of /api/now/transcoding.
+type TranscodingSummary struct {
+	Count         int                  `json:"count"`
+	HardwareCount int                  `json:"hw_count"`
+	TotalMbps     float64              `json:"total_mbps"`
+	Sessions      []TranscodingSession `json:"sessions"`
+	// Warnings lists servers whose sessions are missing from these numbers
+	Warnings []media.ServerWarning `json:"warnings,omitempty"`
+}
+
+// Transcoding lists only the sessions that are re-encoding, in a compact shape for homepage
+// widgets (Homepage, Homarr). Optional server filters by server ID or type.
+// GET /api/now/transcoding
+func Transcoding(c fiber.Ctx) error {
+	out := TranscodingSummary{Sessions: []TranscodingSession{}}
+	if multiServerMgr != nil {
+		sessions, _ := multiServerMgr.GetAllSessionsCached(context.Background())
+		out.Warnings = multiServerMgr.ServerWarnings()
+
+		filter := strings.ToLower(strings.TrimSpace(c.Query("server")))
+		hides := hiddenFrom(middleware.CurrentViewer(c))
+		for _, s := range sessions {
+			if filter != "" && filter != "all" && filter != strings.ToLower(s.ServerID) && filter != string(s.ServerType) {
+				continue
+			}
+			if !isTranscoding(s.VideoMethod, s.AudioMethod, s.TranscodeReasons) {
+				continue
+			}
+			out.Sessions = append(out.Sessions, compactTranscode(s, hides))
+		}
+	}
+
+	var total float64
+	for _, s := range out.Sessions {
+		if s.Hardware {
+			out.HardwareCount++
+		}
+		total += s.BitrateMbps
+	}
+	out.Count = len(out.Sessions)
+	out.TotalMbps = math.Round(total*10) / 10
+
+	// Widgets poll this; let browsers and proxies reuse a response for a few seconds.
+	cacheScope := "public"
+	if c.Get("X-API-Key") != "" || c.Query("apikey") != "" {
+		cacheScope = "private"
+	}
+	c.Set("Cache-Control", cacheScope+", max-age="+strconv.Itoa(transcodingMaxAge))
+	c.Set("Vary", "Origin, X-API-Key")
+	return c.JSON(out)
+}
+
+func compactTranscode(s media.Session, hides func(string) bool) TranscodingSession {
+	item := s.ItemName
+	if s.ItemName == "" {
+		item = s.ItemType
+	}
+	user := s.UserName
+	if hides != nil && hides(s.UserID) {
+		user = queries.AnonymousName
+	}
+	bps := s.Bitrate
+	if bps <= 0 {
+		bps = s.TranscodeBitrate
+	}
+	ts := TranscodingSession{
+		ServerID:    s.ServerID,
+		User:        user,
+		Item:        item,
+		Reason:      strings.Join(s.TranscodeReasons, ", "),
+		Speed:       math.Round(s.TranscodeSpeed*10) / 10,
+		FPS:         math.Round(s.TranscodeFramerate),
+		Hardware:    s.TranscodeHardware,
+		BitrateMbps: math.Round(float64(bps)/100_000) / 10,
+		Progress:    math.Round(s.TranscodeProgress*10) / 10,
+		Paused:      s.IsPaused,
+	}
+	if strings.EqualFold(s.VideoMethod, "Transcode") && s.TranscodeVideoCodec != "" {
+		ts.Video = strings.ToUpper(s.VideoCodec) + " → " + s.TranscodeVideoCodec
+	}
+	return ts
+}
@@ -1,7 +1,11 @@
 package servers
 
 import (
+	"database/sql"
+
+	"emby-analytics/internal/httpx"
 	"emby-analytics/internal/media"
+	"emby-analytics/internal/queries"
 	"github.com/gofiber/fiber/v3"
 )
 
@@ -10,8 +14,8 @@ var mgr *media.MultiServerManager
 // SetManager sets the multi-server manager
 func SetManager(m *media.MultiServerManager) { mgr = m }
 
-// List returns configured servers with health status
-func List() fiber.Handler {
+// List returns configured servers with health status and software version history
+func List(db *sql.DB) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		if mgr == nil {
 			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "multi-server not initialized"})
@@ -19,20 +23,46 @@ func List() fiber.Handler {
 		cfgs := mgr.GetServerConfigs()
 		health := mgr.GetServerHealth()
 		type serverOut struct {
-			ID      string              `json:"id"`
-			Type    media.ServerType    `json:"type"`
-			Name    string              `json:"name"`
-			Enabled bool                `json:"enabled"`
-			Health  *media.ServerHealth `json:"health"`
+			ID         string              `json:"id"`
+			Type       media.ServerType    `json:"type"`
+			Name       string              `json:"name"`
+			Enabled    bool                `json:"enabled"`
+			Health     *media.ServerHealth `json:"health"`
+			AuthBroken bool                `json:"auth_broken"`
+			Auth       httpx.AuthStatus    `json:"auth"`
+			// Version is the latest recorded software version; history is oldest first
+			Version        string                        `json:"version,omitempty"`
+			VersionHistory []queries.ServerVersionChange `json:"version_history"`
+		}
+		history, err := queries.ServerVersionChanges(c, db, "", 0)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		byServer := make(map[string][]queries.ServerVersionChange)
+		for _, v := range history {
+			byServer[v.ServerID] = append(byServer[v.ServerID], v)
 		}
 		out := make([]serverOut, 0, len(cfgs))
 		for id, cfg := range cfgs {
+			auth := httpx.AuthStatusFor(cfg.BaseURL)
+			versions := byServer[id]
+			if versions == nil {
+				versions = []queries.ServerVersionChange{}
+			}
+			version := ""
+			if len(versions) > 0 {
+				version = versions[len(versions)-1].Version
+			}
 			out = append(out, serverOut{
-				ID:      id,
-				Type:    cfg.Type,
-				Name:    cfg.Name,
-				Enabled: cfg.Enabled,
-				Health:  health[id],
+				ID:             id,
+				Type:           cfg.Type,
+				Name:           cfg.Name,
+				Enabled:        cfg.Enabled,
+				Health:         health[id],
+				AuthBroken:     auth.Broken,
+				Auth:           auth,
+				Version:        version,
+				VersionHistory: versions,
 			})
 		}
 		return c.JSON(out)
@@ -3,6 +3,8 @@ package settings
 import (
 	"database/sql"
 	"emby-analytics/internal/logging"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,6 +13,45 @@ import (
 
 const syncEnabledPrefix = "sync_enabled_"
 
+// notifyEventPrefix keys the per-kind notification toggles, e.g. notify_event_playback_started.
+const notifyEventPrefix = "notify_event_"
+
+// MaintenanceModeKey toggles read-only maintenance mode: background ingest keeps running but
+// mutating admin endpoints are refused.
+const MaintenanceModeKey = "maintenance_mode"
+
+// SetupCompletedKey records that the first-run setup wizard was finished; its endpoints are
+// locked afterwards. Installs that predate the wizard don't have it.
+const SetupCompletedKey = "setup_completed"
+
+// AuthRegistrationModeKey is the registration mode chosen during setup, used when
+// AUTH_REGISTRATION_MODE isn't set.
+const AuthRegistrationModeKey = "auth_registration_mode"
+
+// HistoryRetentionDaysKey is how many days of playback history are kept; 0 keeps everything.
+const HistoryRetentionDaysKey = "history_retention_days"
+
+// DefaultTimeframeKey and DefaultServerKey are the timeframe and server scope applied to stats
+// requests that don't pass them; app users can override both for themselves.
+const (
+	DefaultTimeframeKey = "default_timeframe"
+	DefaultServerKey    = "default_server"
+)
+
+// Cost model of /reports/costs: running cost per streaming hour, bandwidth per GB delivered,
+// the extra per GB delivered from a transcode, fixed monthly costs split by hours watched,
+// and the currency label shown with the figures.
+const (
+	CostPerStreamHourKey  = "cost_per_stream_hour"
+	CostPerGBKey          = "cost_per_gb"
+	CostPerTranscodeGBKey = "cost_per_transcode_gb"
+	CostMonthlyFixedKey   = "cost_monthly_fixed"
+	CostCurrencyKey       = "cost_currency"
+)
+
+// TraktScrobbleEnabledKey switches scrobbling of linked users' plays to Trakt on or off.
+const TraktScrobbleEnabledKey = "trakt_scrobble_enabled"
+
 type Setting struct {
 	Key       string `json:"key" db:"key"`
 	Value     string `json:"value" db:"value"`
@@ -91,11 +132,37 @@ func isValidSetting(key, value string) bool {
 		}
 		return value == "true" || value == "false"
 	}
+	if strings.HasPrefix(key, notifyEventPrefix) {
+		if !isValidSyncKeySuffix(strings.TrimPrefix(key, notifyEventPrefix)) {
+			return false
+		}
+		return value == "true" || value == "false"
+	}
 	switch key {
 	case "include_trakt_items":
 		return value == "true" || value == "false"
 	case "prevent_4k_video_transcoding":
 		return value == "true" || value == "false"
+	case "count_group_watch_once":
+		return value == "true" || value == "false"
+	case MaintenanceModeKey:
+		return value == "true" || value == "false"
+	case TraktScrobbleEnabledKey:
+		return value == "true" || value == "false"
+	case AuthRegistrationModeKey:
+		return value == "closed" || value == "secret" || value == "open"
+	case HistoryRetentionDaysKey:
+		n, err := strconv.Atoi(value)
+		return err == nil && n >= 0
+	case DefaultTimeframeKey:
+		return value == "" || ValidTimeframe(value)
+	case DefaultServerKey:
+		return value == "" || ValidServerScope(value)
+	case CostPerStreamHourKey, CostPerGBKey, CostPerTranscodeGBKey, CostMonthlyFixedKey:
+		f, err := strconv.ParseFloat(value, 64)
+		return err == nil && f >= 0 && !math.IsInf(f, 0)
+	case CostCurrencyKey:
+		return len(strings.TrimSpace(value)) <= 8
 	default:
 		return false // Only allow known settings
 	}
@@ -114,6 +181,16 @@ func GetSettingValue(db *sql.DB, key, defaultValue string) string {
 	return value
 }
 
+// SetSettingValue stores a setting value
+func SetSettingValue(db *sql.DB, key, value string) error {
+	_, err := db.Exec(`
+		INSERT INTO app_settings (key, value, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`, key, value, time.Now().UTC())
+	return err
+}
+
 // Helper function to get a boolean setting value
 func GetSettingBool(db *sql.DB, key string, defaultValue bool) bool {
 	value := GetSettingValue(db, key, "")
@@ -140,6 +217,21 @@ func isValidSyncKeySuffix(s string) bool {
 	return true
 }
 
+// ValidTimeframe reports whether tf is a timeframe the stats endpoints accept
+func ValidTimeframe(tf string) bool {
+	switch tf {
+	case "1d", "3d", "7d", "14d", "30d", "all-time":
+		return true
+	}
+	return false
+}
+
+// ValidServerScope reports whether s can be stored as a default server scope: a server id,
+// a server type or "all"
+func ValidServerScope(s string) bool {
+	return isValidSyncKeySuffix(s)
+}
+
 // SyncSettingKey returns the storage key for a server sync toggle
 func SyncSettingKey(serverID string) string {
 	return syncEnabledPrefix + serverID
@@ -149,3 +241,22 @@ func SyncSettingKey(serverID string) string {
 func GetSyncEnabled(db *sql.DB, serverID string, defaultValue bool) bool {
 	return GetSettingBool(db, SyncSettingKey(serverID), defaultValue)
 }
+
+// NotifyEventKey returns the storage key for a notification event kind toggle
+func NotifyEventKey(kind string) string {
+	return notifyEventPrefix + kind
+}
+
+// HistoryRetentionDays returns how many days of playback history to keep; 0 keeps everything
+func HistoryRetentionDays(db *sql.DB) int {
+	n, err := strconv.Atoi(GetSettingValue(db, HistoryRetentionDaysKey, "0"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// MaintenanceEnabled reports whether maintenance (read-only) mode is switched on
+func MaintenanceEnabled(db *sql.DB) bool {
+	return GetSettingBool(db, MaintenanceModeKey, false)
+}
@@ -0,0 +1,352 @@
+package setup
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/config"
+	"emby-analytics/internal/handlers/settings"
+	"emby-analytics/internal/httpx"
+	"emby-analytics/internal/jellyfin"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/plex"
+	"emby-analytics/internal/queries"
+	"emby-analytics/internal/tasks"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+var mgr *media.MultiServerManager
+
+// SetManager sets the multi-server manager that servers added during setup join
+func SetManager(m *media.MultiServerManager) { mgr = m }
+
+// NewClient builds the client for a media server configuration, or nil for an unknown type
+func NewClient(sc media.ServerConfig) media.MediaServerClient {
+	switch sc.Type {
+	case media.ServerTypePlex:
+		return plex.New(sc)
+	case media.ServerTypeJellyfin:
+		return jellyfin.New(sc)
+	case media.ServerTypeEmby:
+		return media.NewEmbyAdapter(sc)
+	}
+	return nil
+}
+
+// Status is the response of GET /api/setup/status.
+type Status struct {
+	// NeedsSetup is true until the wizard is completed; installs that already had an app user
+	// before the wizard existed count as set up
+	NeedsSetup       bool   `json:"needs_setup"`
+	Completed        bool   `json:"completed"`
+	HasAdmin         bool   `json:"has_admin"`
+	ServerCount      int    `json:"server_count"`
+	RegistrationMode string `json:"registration_mode"`
+	// RegistrationModeFixed is true when AUTH_REGISTRATION_MODE overrides the mode chosen here
+	RegistrationModeFixed bool `json:"registration_mode_fixed"`
+	HistoryRetentionDays  int  `json:"history_retention_days"`
+	// NextStep is the first unfinished step: admin, servers, defaults or done
+	NextStep string `json:"next_step"`
+}
+
+func countAppUsers(db *sql.DB) int {
+	var n int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM app_user`).Scan(&n)
+	return n
+}
+
+// completed reports whether first-run setup is over. The setting is written as false when
+// the wizard creates the admin, so installs without it are existing ones.
+func completed(db *sql.DB) bool {
+	switch settings.GetSettingValue(db, settings.SetupCompletedKey, "") {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return countAppUsers(db) > 0
+}
+
+func serverCount() int {
+	if mgr == nil {
+		return 0
+	}
+	return len(mgr.GetServerConfigs())
+}
+
+// StatusHandler reports the first-run setup state; it is public so the UI can redirect to
+// the wizard before anyone can sign in.
+func StatusHandler(db *sql.DB, cfg config.Config) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		done := completed(db)
+		st := Status{
+			NeedsSetup:            !done,
+			Completed:             done,
+			HasAdmin:              countAppUsers(db) > 0,
+			ServerCount:           serverCount(),
+			RegistrationMode:      strings.ToLower(cfg.AuthRegistrationMode),
+			RegistrationModeFixed: cfg.AuthRegistrationFixed,
+			HistoryRetentionDays:  settings.HistoryRetentionDays(db),
+		}
+		if !cfg.AuthRegistrationFixed {
+			st.RegistrationMode = settings.GetSettingValue(db, settings.AuthRegistrationModeKey, st.RegistrationMode)
+		}
+		switch {
+		case done:
+			st.NextStep = "done"
+		case !st.HasAdmin:
+			st.NextStep = "admin"
+		case st.ServerCount == 0:
+			st.NextStep = "servers"
+		default:
+			st.NextStep = "defaults"
+		}
+		return c.JSON(st)
+	}
+}
+
+// RequireOpen refuses setup calls once setup has been completed.
+func RequireOpen(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if completed(db) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "setup has already been completed"})
+		}
+		return c.Next()
+	}
+}
+
+// ServerRequest is a media server as sent by the setup wizard and /admin/servers.
+type ServerRequest struct {
+	ID                    string `json:"id"`
+	Type                  string `json:"type"`
+	Name                  string `json:"name"`
+	BaseURL               string `json:"base_url"`
+	APIKey                string `json:"api_key"`
+	ExternalURL           string `json:"external_url"`
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify"`
+	TLSCAFile             string `json:"tls_ca_file"`
+	ProxyURL              string `json:"proxy_url"`
+	// Enabled defaults to true
+	Enabled *bool `json:"enabled"`
+}
+
+// ServerTest is the outcome of a connectivity test.
+type ServerTest struct {
+	OK         bool   `json:"ok"`
+	Reachable  bool   `json:"reachable"`
+	ServerName string `json:"server_name,omitempty"`
+	Version    string `json:"version,omitempty"`
+	ResponseMS int64  `json:"response_time_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+var idUnsafe = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// ServerConfig validates a server request into a configuration. The id defaults to the
+// type and name, e.g. "jellyfin-living-room".
+func (r ServerRequest) ServerConfig() (media.ServerConfig, error) {
+	sc := media.ServerConfig{
+		ID:                    strings.ToLower(strings.TrimSpace(r.ID)),
+		Type:                  media.ServerType(strings.ToLower(strings.TrimSpace(r.Type))),
+		Name:                  strings.TrimSpace(r.Name),
+		BaseURL:               strings.TrimRight(strings.TrimSpace(r.BaseURL), "/"),
+		APIKey:                strings.TrimSpace(r.APIKey),
+		ExternalURL:           strings.TrimRight(strings.TrimSpace(r.ExternalURL), "/"),
+		Enabled:               true,
+		TLSInsecureSkipVerify: r.TLSInsecureSkipVerify,
+		TLSCAFile:             strings.TrimSpace(r.TLSCAFile),
+		ProxyURL:              strings.TrimSpace(r.ProxyURL),
+	}
+	if r.Enabled != nil {
+		sc.Enabled = *r.Enabled
+	}
+	switch sc.Type {
+	case media.ServerTypeEmby, media.ServerTypeJellyfin, media.ServerTypePlex:
+	default:
+		return sc, errors.New("type must be emby, jellyfin or plex")
+	}
+	if u, err := url.Parse(sc.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return sc, errors.New("base_url must be an http(s) URL")
+	}
+	if sc.APIKey == "" {
+		return sc, errors.New("api_key is required")
+	}
+	if sc.ProxyURL != "" {
+		u, err := url.Parse(sc.ProxyURL)
+		if err != nil || u.Host == "" {
+			return sc, errors.New("proxy_url must be an http(s) or socks5 URL")
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return sc, errors.New("proxy_url must be an http(s) or socks5 URL")
+		}
+	}
+	if sc.Name == "" {
+		sc.Name = strings.ToUpper(string(sc.Type[:1])) + string(sc.Type[1:])
+	}
+	if sc.ExternalURL == "" {
+		sc.ExternalURL = sc.BaseURL
+	}
+	if sc.ID == "" {
+		sc.ID = strings.TrimRight(string(sc.Type)+"-"+strings.Trim(idUnsafe.ReplaceAllString(strings.ToLower(sc.Name), "-"), "-"), "-")
+	}
+	if idUnsafe.MatchString(sc.ID) {
+		return sc, errors.New("id may only contain a-z, 0-9, '-' and '_'")
+	}
+	return sc, nil
+}
+
+// TestServer checks that the server answers and accepts the API key.
+func TestServer(sc media.ServerConfig) ServerTest {
+	// Only install connection options the server needs; zero options would drop those of an
+	// already configured server on the same host
+	if sc.TransportOptions() != (httpx.TransportOptions{}) {
+		if err := httpx.ConfigureHost(sc.BaseURL, sc.TransportOptions()); err != nil {
+			return ServerTest{Error: err.Error()}
+		}
+	}
+	client := NewClient(sc)
+	var out ServerTest
+	if health, err := client.CheckHealth(); err != nil {
+		out.Error = err.Error()
+	} else if health != nil {
+		out.Reachable = health.IsReachable
+		out.ResponseMS = health.ResponseTime
+		out.Error = health.Error
+	}
+	if !out.Reachable {
+		if out.Error == "" {
+			out.Error = "server is not reachable"
+		}
+		return out
+	}
+	info, err := client.GetSystemInfo()
+	if err != nil {
+		out.Error = fmt.Sprintf("server rejected the request; check the API key: %v", err)
+		return out
+	}
+	out.OK = true
+	out.ServerName = info.Name
+	out.Version = info.Version
+	return out
+}
+
+// TestServerHandler tests connectivity to a media server without saving it, e.g.
+// {"type":"jellyfin","base_url":"http://jellyfin:8096","api_key":"..."}.
+func TestServerHandler() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req ServerRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		sc, err := req.ServerConfig()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(TestServer(sc))
+	}
+}
+
+// AddServerHandler tests a media server and, when it answers, stores it and starts
+// monitoring it right away. Servers configured through the environment keep their ids.
+func AddServerHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if mgr == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "multi-server not initialized"})
+		}
+		var req ServerRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		sc, err := req.ServerConfig()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if _, exists := mgr.GetServerConfigs()[sc.ID]; exists {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "a server with id " + sc.ID + " already exists"})
+		}
+		test := TestServer(sc)
+		if !test.OK {
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": test.Error, "test": test})
+		}
+		if err := queries.SaveStoredMediaServer(c, db, sc, time.Now().Unix()); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		mgr.AddServer(sc, NewClient(sc))
+		tasks.SyncJellyfinEventIngest(sc)
+		logging.Info("Media server added during setup", "server", sc.ID, "type", sc.Type, "name", sc.Name)
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"id":   sc.ID,
+			"type": sc.Type,
+			"name": sc.Name,
+			"test": test,
+		})
+	}
+}
+
+type defaultsRequest struct {
+	RegistrationMode     *string `json:"registration_mode"`
+	HistoryRetentionDays *int    `json:"history_retention_days"`
+}
+
+// SaveDefaultsHandler stores the registration mode (closed, secret or open) and how many
+// days of playback history to keep (0 keeps everything), e.g.
+// {"registration_mode":"closed","history_retention_days":365}.
+func SaveDefaultsHandler(db *sql.DB, cfg config.Config) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req defaultsRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		values := map[string]string{}
+		if req.RegistrationMode != nil {
+			mode := strings.ToLower(strings.TrimSpace(*req.RegistrationMode))
+			if mode != "closed" && mode != "secret" && mode != "open" {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "registration_mode must be closed, secret or open"})
+			}
+			if mode == "secret" && cfg.AuthRegistrationSecret == "" {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "registration_mode secret requires AUTH_REGISTRATION_SECRET"})
+			}
+			values[settings.AuthRegistrationModeKey] = mode
+		}
+		if req.HistoryRetentionDays != nil {
+			if *req.HistoryRetentionDays < 0 {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "history_retention_days must be 0 or more"})
+			}
+			values[settings.HistoryRetentionDaysKey] = strconv.Itoa(*req.HistoryRetentionDays)
+		}
+		for key, value := range values {
+			if err := settings.SetSettingValue(db, key, value); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+		}
+		return StatusHandler(db, cfg)(c)
+	}
+}
+
+// CompleteHandler finishes setup once an admin and a media server exist, locking the
+// setup endpoints.
+func CompleteHandler(db *sql.DB, cfg config.Config) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if countAppUsers(db) == 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "create the admin account first"})
+		}
+		if serverCount() == 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "add a media server first"})
+		}
+		if err := settings.SetSettingValue(db, settings.SetupCompletedKey, "true"); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		logging.Info("First-run setup completed")
+		return StatusHandler(db, cfg)(c)
+	}
+}
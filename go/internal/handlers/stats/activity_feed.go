@@ -0,0 +1,89 @@
+package stats
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+
+	"emby-analytics/internal/middleware"
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ActivityFeed is the response of /stats/activity-feed.
+type ActivityFeed struct {
+	Items      []queries.FeedItem `json:"items"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// ActivityFeedHandler returns session starts and stops, library additions, new users and
+// admin actions as one reverse-chronological feed. Admin actions are only listed for admins.
+// Query params: types (comma-separated, default all), limit (default 50, max 200), cursor
+// (next_cursor of the previous page).
+// GET /stats/activity-feed
+func ActivityFeedHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		limit := parseQueryInt(c, "limit", 50)
+		if limit <= 0 || limit > 200 {
+			limit = 50
+		}
+
+		admin := middleware.CurrentViewer(c).Admin
+		var types []string
+		if raw := strings.TrimSpace(c.Query("types", "")); raw != "" {
+			for _, t := range strings.Split(raw, ",") {
+				t = strings.TrimSpace(strings.ToLower(t))
+				if t == "" || slices.Contains(types, t) {
+					continue
+				}
+				if !slices.Contains(queries.FeedTypes, t) {
+					return c.Status(400).JSON(fiber.Map{"error": "unknown type " + t + "; expected " + strings.Join(queries.FeedTypes, ", ")})
+				}
+				if t == queries.FeedAdminAction && !admin {
+					return c.Status(403).JSON(fiber.Map{"error": "admin_action requires admin"})
+				}
+				types = append(types, t)
+			}
+		}
+		if len(types) == 0 {
+			for _, t := range queries.FeedTypes {
+				if t != queries.FeedAdminAction || admin {
+					types = append(types, t)
+				}
+			}
+		}
+
+		var after *queries.FeedCursor
+		if token := c.Query("cursor", ""); token != "" {
+			cur, err := queries.DecodeFeedCursor(token)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+			}
+			after = &cur
+		}
+
+		items, next, err := queries.ActivityFeed(c, db, types, after, limit)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if mask := viewerMask(c, db); !mask.empty() {
+			for i := range items {
+				if items[i].UserID != "" && mask.hides(items[i].UserID) {
+					items[i].UserID = ""
+					items[i].UserName = queries.AnonymousName
+				}
+			}
+		}
+
+		out := ActivityFeed{Items: items}
+		if out.Items == nil {
+			out.Items = []queries.FeedItem{}
+		}
+		if next != nil {
+			out.NextCursor = next.Encode()
+		}
+		return c.JSON(out)
+	}
+}
@@ -0,0 +1,43 @@
+package stats
+
+import (
+	"database/sql"
+	"time"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GET /stats/bandwidth?days=7&server=
+// Outbound streaming bandwidth over the last `days` (1-90): average and peak Mbps and GB
+// delivered per hour, plus totals per user and per server. Built from the bitrate of
+// playing streams, sampled about once a minute by the session processor, so it starts
+// filling from the first poll after upgrading.
+func BandwidthHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 7)
+		if days < 1 || days > 90 {
+			days = 7
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+
+		now := time.Now()
+		report, err := queries.BandwidthStats(c, db, now.AddDate(0, 0, -days).Unix(), now.Unix()+1, serverType, serverID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if mask := viewerMask(c, db); !mask.empty() {
+			for i := range report.Users {
+				if u := &report.Users[i]; mask.hides(u.UserID) {
+					u.UserID = ""
+					u.UserName = queries.AnonymousName
+				}
+			}
+		}
+		return c.JSON(fiber.Map{
+			"days":      days,
+			"bandwidth": report,
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package stats
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Codec support verdicts.
+const (
+	codecSupported   = "yes"
+	codecUnsupported = "no"
+	codecPartial     = "partial" // played directly at times, transcoded for the codec at others
+	codecUnknown     = "unknown" // only transcoded for unrelated reasons (bitrate, subtitles, ...)
+)
+
+// CodecSupport is what a client's sessions say about one source codec.
+type CodecSupport struct {
+	Codec    string `json:"codec"`
+	Sessions int    `json:"sessions"`
+	Direct   int    `json:"direct"` // stream played or copied without re-encoding
+	// Rejected sessions were transcoded because the client can't decode the codec
+	Rejected int `json:"rejected"`
+	// Limited sessions were transcoded for the codec's profile, level, bit depth or range
+	Limited int    `json:"limited"`
+	Verdict string `json:"verdict"`
+}
+
+// ClientCapabilities is the codec support matrix of one client app.
+type ClientCapabilities struct {
+	Client   string         `json:"client"`
+	Sessions int            `json:"sessions"`
+	Video    []CodecSupport `json:"video"`
+	Audio    []CodecSupport `json:"audio"`
+}
+
+// CodecCompatibility summarises one codec across clients.
+type CodecCompatibility struct {
+	Kind     string `json:"kind"` // video or audio
+	Codec    string `json:"codec"`
+	Sessions int    `json:"sessions"`
+	// RejectedPct is the share of the codec's sessions transcoded because of it
+	RejectedPct float64  `json:"rejected_pct"`
+	Supported   []string `json:"supported"`
+	Unsupported []string `json:"unsupported"`
+	Partial     []string `json:"partial"`
+}
+
+// codecEvidence classifies one stream of a session: direct, rejected, limited or none of them
+// when it was transcoded for reasons unrelated to the codec. Sessions without recorded
+// reasons (Plex) count as rejected when the codec was changed.
+func codecEvidence(kind, method, from, to string, reasons []string) (direct, rejected, limited bool) {
+	if !strings.Contains(strings.ToLower(method), "transcode") {
+		return true, false, false
+	}
+	if len(reasons) == 0 {
+		return false, to != "" && to != from, false
+	}
+	for _, r := range reasons {
+		r = strings.ToLower(r)
+		if !strings.HasPrefix(r, kind) || !strings.HasSuffix(r, "notsupported") {
+			continue
+		}
+		switch strings.TrimSuffix(strings.TrimPrefix(r, kind), "notsupported") {
+		case "codec":
+			rejected = true
+		case "profile", "level", "bitdepth", "range", "rangetype", "framerate", "channels", "samplerate":
+			limited = true
+		}
+	}
+	return false, rejected, limited && !rejected
+}
+
+func codecVerdict(s CodecSupport) string {
+	switch {
+	case s.Rejected == 0 && s.Limited == 0 && s.Direct > 0:
+		return codecSupported
+	case s.Direct == 0 && s.Limited == 0 && s.Rejected > 0:
+		return codecUnsupported
+	case s.Direct+s.Limited+s.Rejected > 0 && (s.Rejected > 0 || s.Limited > 0):
+		return codecPartial
+	}
+	return codecUnknown
+}
+
+// GET /stats/clients/capabilities?days=90&min_sessions=3&server=
+// A codec support matrix per client app inferred from direct plays vs. codec related
+// transcode reasons (e.g. Chromecast: HEVC no, AC3 yes), plus per codec the clients that
+// play it directly and those that can't, to help decide which encodes to standardize on.
+func ClientCapabilitiesHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 90)
+		if days <= 0 || days > 3650 {
+			days = 90
+		}
+		minSessions := parseQueryInt(c, "min_sessions", 3)
+		if minSessions <= 0 {
+			minSessions = 3
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+
+		where, serverArgs := appendServerFilter(`ps.started_at >= ?
+			AND COALESCE(li.media_type, 'Unknown') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')`,
+			"ps", serverType, serverID)
+		args := append([]any{time.Now().AddDate(0, 0, -days).Unix()}, serverArgs...)
+		rows, err := db.Query(`
+			SELECT COALESCE(NULLIF(TRIM(ps.client_name), ''), 'Unknown'),
+			       COALESCE(ps.video_method, ''), UPPER(COALESCE(ps.video_codec_from, '')), UPPER(COALESCE(ps.video_codec_to, '')),
+			       COALESCE(ps.audio_method, ''), UPPER(COALESCE(ps.audio_codec_from, '')), UPPER(COALESCE(ps.audio_codec_to, '')),
+			       COALESCE(ps.transcode_reasons, '')
+			FROM play_sessions ps
+			LEFT JOIN library_item li ON li.id = ps.item_id
+			WHERE `+where, args...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+
+		type clientAcc struct {
+			sessions     int
+			video, audio map[string]*CodecSupport
+		}
+		clients := map[string]*clientAcc{}
+		record := func(m map[string]*CodecSupport, kind, method, from, to string, reasons []string) {
+			if from == "" {
+				return
+			}
+			s := m[from]
+			if s == nil {
+				s = &CodecSupport{Codec: from}
+				m[from] = s
+			}
+			s.Sessions++
+			direct, rejected, limited := codecEvidence(kind, method, from, to, reasons)
+			switch {
+			case direct:
+				s.Direct++
+			case rejected:
+				s.Rejected++
+			case limited:
+				s.Limited++
+			}
+		}
+		for rows.Next() {
+			var client, vMethod, vFrom, vTo, aMethod, aFrom, aTo, reasonList string
+			if err := rows.Scan(&client, &vMethod, &vFrom, &vTo, &aMethod, &aFrom, &aTo, &reasonList); err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			var reasons []string
+			for _, r := range strings.Split(reasonList, ",") {
+				if r = strings.TrimSpace(r); r != "" {
+					reasons = append(reasons, r)
+				}
+			}
+			acc := clients[client]
+			if acc == nil {
+				acc = &clientAcc{video: map[string]*CodecSupport{}, audio: map[string]*CodecSupport{}}
+				clients[client] = acc
+			}
+			acc.sessions++
+			record(acc.video, "video", vMethod, vFrom, vTo, reasons)
+			record(acc.audio, "audio", aMethod, aFrom, aTo, reasons)
+		}
+		if err := rows.Err(); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		matrix := func(m map[string]*CodecSupport) []CodecSupport {
+			out := make([]CodecSupport, 0, len(m))
+			for _, s := range m {
+				s.Verdict = codecVerdict(*s)
+				out = append(out, *s)
+			}
+			sort.Slice(out, func(i, j int) bool {
+				if out[i].Sessions != out[j].Sessions {
+					return out[i].Sessions > out[j].Sessions
+				}
+				return out[i].Codec < out[j].Codec
+			})
+			return out
+		}
+
+		outClients := []ClientCapabilities{}
+		codecs := map[string]*CodecCompatibility{}
+		rejectedByCodec := map[string]int{}
+		summarize := func(kind, client string, list []CodecSupport) {
+			for _, s := range list {
+				key := kind + "|" + s.Codec
+				cc := codecs[key]
+				if cc == nil {
+					cc = &CodecCompatibility{Kind: kind, Codec: s.Codec, Supported: []string{}, Unsupported: []string{}, Partial: []string{}}
+					codecs[key] = cc
+				}
+				cc.Sessions += s.Sessions
+				rejectedByCodec[key] += s.Rejected
+				switch s.Verdict {
+				case codecSupported:
+					cc.Supported = append(cc.Supported, client)
+				case codecUnsupported:
+					cc.Unsupported = append(cc.Unsupported, client)
+				case codecPartial:
+					cc.Partial = append(cc.Partial, client)
+				}
+			}
+		}
+		for client, acc := range clients {
+			if acc.sessions < minSessions {
+				continue
+			}
+			caps := ClientCapabilities{Client: client, Sessions: acc.sessions, Video: matrix(acc.video), Audio: matrix(acc.audio)}
+			summarize("video", client, caps.Video)
+			summarize("audio", client, caps.Audio)
+			outClients = append(outClients, caps)
+		}
+		sort.Slice(outClients, func(i, j int) bool {
+			if outClients[i].Sessions != outClients[j].Sessions {
+				return outClients[i].Sessions > outClients[j].Sessions
+			}
+			return outClients[i].Client < outClients[j].Client
+		})
+
+		outCodecs := make([]CodecCompatibility, 0, len(codecs))
+		for key, cc := range codecs {
+			cc.RejectedPct = pct(rejectedByCodec[key], cc.Sessions)
+			sort.Strings(cc.Supported)
+			sort.Strings(cc.Unsupported)
+			sort.Strings(cc.Partial)
+			outCodecs = append(outCodecs, *cc)
+		}
+		sort.Slice(outCodecs, func(i, j int) bool {
+			if outCodecs[i].Kind != outCodecs[j].Kind {
+				return outCodecs[i].Kind > outCodecs[j].Kind // video first
+			}
+			if outCodecs[i].Sessions != outCodecs[j].Sessions {
+				return outCodecs[i].Sessions > outCodecs[j].Sessions
+			}
+			return outCodecs[i].Codec < outCodecs[j].Codec
+		})
+
+		return c.JSON(fiber.Map{"days": days, "min_sessions": minSessions, "clients": outClients, "codecs": outCodecs})
+	}
+}
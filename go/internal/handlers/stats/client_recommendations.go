@@ -0,0 +1,308 @@
+package stats
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// recommendationShare is the share of a client's sessions a problem must reach before it is
+// recommended on; codec support uses a stricter threshold since it targets one codec.
+const (
+	recommendationShare      = 0.25
+	codecRecommendationShare = 0.5
+)
+
+// ClientCodecStats is how often a client transcoded video of one source codec.
+type ClientCodecStats struct {
+	Codec        string  `json:"codec"`
+	Sessions     int     `json:"sessions"`
+	Transcoded   int     `json:"transcoded"`
+	TranscodePct float64 `json:"transcode_pct"`
+}
+
+// ClientReasonCount is one transcode reason and how many of a client's sessions reported it.
+type ClientReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// ClientQualityStats summarises playback quality for one client app.
+type ClientQualityStats struct {
+	Client              string              `json:"client"`
+	Sessions            int                 `json:"sessions"`
+	VideoTranscodes     int                 `json:"video_transcodes"`
+	VideoTranscodePct   float64             `json:"video_transcode_pct"`
+	AudioOnlyTranscodes int                 `json:"audio_only_transcodes"`
+	MedianSourceMbps    float64             `json:"median_source_mbps"`
+	MedianTranscodeMbps float64             `json:"median_transcode_mbps"` // 0 until transcode bitrates are recorded
+	Codecs              []ClientCodecStats  `json:"codecs"`
+	TopReasons          []ClientReasonCount `json:"top_reasons"`
+}
+
+// ClientRecommendation is one suggested server setting or user education target.
+type ClientRecommendation struct {
+	Client   string  `json:"client"`
+	Kind     string  `json:"kind"` // codec_support, bitrate_limit, subtitles or audio
+	Message  string  `json:"message"`
+	Sessions int     `json:"sessions"` // sessions affected
+	SharePct float64 `json:"share_pct"`
+	Codec    string  `json:"codec,omitempty"`
+	// SuggestedLimitMbps is the typical bitrate the client ends up with when transcoding
+	SuggestedLimitMbps float64 `json:"suggested_limit_mbps,omitempty"`
+}
+
+// ClientRecommendations is the response of /stats/recommendations/clients.
+type ClientRecommendations struct {
+	Days            int                    `json:"days"`
+	MinSessions     int                    `json:"min_sessions"`
+	Clients         []ClientQualityStats   `json:"clients"`
+	Recommendations []ClientRecommendation `json:"recommendations"`
+}
+
+type clientAccumulator struct {
+	stats          ClientQualityStats
+	codecs         map[string]*ClientCodecStats
+	reasons        map[string]int
+	sourceBitrates []float64
+	transBitrates  []float64
+	bitrateLimited int
+	bitrateTrans   []float64
+	subtitles      int
+}
+
+// ClientRecommendationsHandler analyses sessions per client app (source vs. negotiated
+// transcode bitrate, transcoded codecs and transcode reasons) and suggests server-side
+// bitrate limits or settings users should change.
+// Query params: days (default 90), min_sessions (default 10), server.
+// GET /stats/recommendations/clients
+func ClientRecommendationsHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 90)
+		if days <= 0 || days > 3650 {
+			days = 90
+		}
+		minSessions := parseQueryInt(c, "min_sessions", 10)
+		if minSessions <= 0 {
+			minSessions = 10
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+
+		where, serverArgs := appendServerFilter(`ps.started_at >= ?
+			AND COALESCE(li.media_type, 'Unknown') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')`,
+			"ps", serverType, serverID)
+		args := append([]interface{}{time.Now().AddDate(0, 0, -days).Unix()}, serverArgs...)
+		rows, err := db.Query(`
+			SELECT COALESCE(NULLIF(TRIM(ps.client_name), ''), 'Unknown'),
+			       UPPER(COALESCE(ps.video_codec_from, '')),
+			       LOWER(COALESCE(ps.video_method, '')),
+			       LOWER(COALESCE(ps.audio_method, '')),
+			       COALESCE(ps.transcode_reasons, ''),
+			       COALESCE(li.bitrate_bps, 0),
+			       COALESCE(ps.transcode_bitrate, 0)
+			FROM play_sessions ps
+			LEFT JOIN library_item li ON li.id = ps.item_id
+			WHERE `+where, args...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+
+		byClient := map[string]*clientAccumulator{}
+		for rows.Next() {
+			var client, codec, videoMethod, audioMethod, reasons string
+			var sourceBps, transBps int64
+			if err := rows.Scan(&client, &codec, &videoMethod, &audioMethod, &reasons, &sourceBps, &transBps); err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			acc := byClient[client]
+			if acc == nil {
+				acc = &clientAccumulator{stats: ClientQualityStats{Client: client}, codecs: map[string]*ClientCodecStats{}, reasons: map[string]int{}}
+				byClient[client] = acc
+			}
+			acc.add(codec, strings.Contains(videoMethod, "transcode"), strings.Contains(audioMethod, "transcode"), reasons, sourceBps, transBps)
+		}
+		if err := rows.Err(); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		out := ClientRecommendations{Days: days, MinSessions: minSessions, Clients: []ClientQualityStats{}, Recommendations: []ClientRecommendation{}}
+		for _, acc := range byClient {
+			out.Clients = append(out.Clients, acc.finish())
+			if acc.stats.Sessions >= minSessions {
+				out.Recommendations = append(out.Recommendations, acc.recommend(minSessions)...)
+			}
+		}
+		sort.Slice(out.Clients, func(i, j int) bool { return out.Clients[i].Sessions > out.Clients[j].Sessions })
+		sort.SliceStable(out.Recommendations, func(i, j int) bool {
+			return out.Recommendations[i].Sessions > out.Recommendations[j].Sessions
+		})
+		return c.JSON(out)
+	}
+}
+
+func (a *clientAccumulator) add(codec string, videoTranscode, audioTranscode bool, reasons string, sourceBps, transBps int64) {
+	a.stats.Sessions++
+	if sourceBps > 0 {
+		a.sourceBitrates = append(a.sourceBitrates, float64(sourceBps))
+	}
+	if videoTranscode {
+		a.stats.VideoTranscodes++
+		if transBps > 0 {
+			a.transBitrates = append(a.transBitrates, float64(transBps))
+		}
+	} else if audioTranscode {
+		a.stats.AudioOnlyTranscodes++
+	}
+	if codec != "" {
+		cs := a.codecs[codec]
+		if cs == nil {
+			cs = &ClientCodecStats{Codec: codec}
+			a.codecs[codec] = cs
+		}
+		cs.Sessions++
+		if videoTranscode {
+			cs.Transcoded++
+		}
+	}
+
+	bitrateLimited, subtitles := false, false
+	for _, r := range strings.Split(reasons, ",") {
+		if r = strings.TrimSpace(r); r == "" {
+			continue
+		}
+		a.reasons[r]++
+		lower := strings.ToLower(r)
+		bitrateLimited = bitrateLimited || strings.Contains(lower, "bitrate")
+		subtitles = subtitles || strings.Contains(lower, "subtitle")
+	}
+	if bitrateLimited {
+		a.bitrateLimited++
+		if transBps > 0 {
+			a.bitrateTrans = append(a.bitrateTrans, float64(transBps))
+		}
+	}
+	if subtitles {
+		a.subtitles++
+	}
+}
+
+func (a *clientAccumulator) finish() ClientQualityStats {
+	s := a.stats
+	s.VideoTranscodePct = pct(s.VideoTranscodes, s.Sessions)
+	s.MedianSourceMbps = bpsToMbps(medianFloat(a.sourceBitrates))
+	s.MedianTranscodeMbps = bpsToMbps(medianFloat(a.transBitrates))
+
+	s.Codecs = make([]ClientCodecStats, 0, len(a.codecs))
+	for _, cs := range a.codecs {
+		cs.TranscodePct = pct(cs.Transcoded, cs.Sessions)
+		s.Codecs = append(s.Codecs, *cs)
+	}
+	sort.Slice(s.Codecs, func(i, j int) bool { return s.Codecs[i].Sessions > s.Codecs[j].Sessions })
+
+	s.TopReasons = make([]ClientReasonCount, 0, len(a.reasons))
+	for r, n := range a.reasons {
+		s.TopReasons = append(s.TopReasons, ClientReasonCount{Reason: r, Count: n})
+	}
+	sort.Slice(s.TopReasons, func(i, j int) bool {
+		if s.TopReasons[i].Count != s.TopReasons[j].Count {
+			return s.TopReasons[i].Count > s.TopReasons[j].Count
+		}
+		return s.TopReasons[i].Reason < s.TopReasons[j].Reason
+	})
+	if len(s.TopReasons) > 5 {
+		s.TopReasons = s.TopReasons[:5]
+	}
+	return s
+}
+
+func (a *clientAccumulator) recommend(minSessions int) []ClientRecommendation {
+	client, total := a.stats.Client, a.stats.Sessions
+	var out []ClientRecommendation
+
+	for _, cs := range a.codecs {
+		if cs.Sessions < minSessions || float64(cs.Transcoded) < codecRecommendationShare*float64(cs.Sessions) {
+			continue
+		}
+		out = append(out, ClientRecommendation{
+			Client:   client,
+			Kind:     "codec_support",
+			Codec:    cs.Codec,
+			Sessions: cs.Transcoded,
+			SharePct: pct(cs.Transcoded, cs.Sessions),
+			Message: fmt.Sprintf("%s clients transcode %.0f%% of %s — enable %s playback in the client's settings or use a client that supports it",
+				client, pct(cs.Transcoded, cs.Sessions), cs.Codec, cs.Codec),
+		})
+	}
+
+	if float64(a.bitrateLimited) >= recommendationShare*float64(total) {
+		r := ClientRecommendation{
+			Client:   client,
+			Kind:     "bitrate_limit",
+			Sessions: a.bitrateLimited,
+			SharePct: pct(a.bitrateLimited, total),
+		}
+		source := bpsToMbps(medianFloat(a.sourceBitrates))
+		if limit := bpsToMbps(medianFloat(a.bitrateTrans)); limit > 0 {
+			r.SuggestedLimitMbps = limit
+			r.Message = fmt.Sprintf("%s transcodes %.0f%% of sessions because the bitrate exceeds its limit (typical source %.1f Mbps, delivered %.1f Mbps) — raise the client's quality setting, or set a %.1f Mbps streaming limit on the server so playback starts at a rate it can sustain",
+				client, r.SharePct, source, limit, limit)
+		} else {
+			r.Message = fmt.Sprintf("%s transcodes %.0f%% of sessions because the bitrate exceeds its limit (typical source %.1f Mbps) — raise the client's quality setting or set a server-side streaming limit for it",
+				client, r.SharePct, source)
+		}
+		out = append(out, r)
+	}
+
+	if float64(a.subtitles) >= recommendationShare*float64(total) {
+		out = append(out, ClientRecommendation{
+			Client:   client,
+			Kind:     "subtitles",
+			Sessions: a.subtitles,
+			SharePct: pct(a.subtitles, total),
+			Message: fmt.Sprintf("%s transcodes %.0f%% of sessions to burn in subtitles — prefer text subtitles (SRT) or a client that renders image subtitles",
+				client, pct(a.subtitles, total)),
+		})
+	}
+
+	if n := a.stats.AudioOnlyTranscodes; float64(n) >= recommendationShare*float64(total) {
+		out = append(out, ClientRecommendation{
+			Client:   client,
+			Kind:     "audio",
+			Sessions: n,
+			SharePct: pct(n, total),
+			Message: fmt.Sprintf("%s transcodes audio for %.0f%% of sessions — check the client's audio output or passthrough settings",
+				client, pct(n, total)),
+		})
+	}
+	return out
+}
+
+func pct(n, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(total)*1000) / 10
+}
+
+func medianFloat(v []float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	s := append([]float64(nil), v...)
+	sort.Float64s(s)
+	mid := len(s) / 2
+	if len(s)%2 == 0 {
+		return (s[mid-1] + s[mid]) / 2
+	}
+	return s[mid]
+}
+
+func bpsToMbps(bps float64) float64 {
+	return math.Round(bps/1e6*10) / 10
+}
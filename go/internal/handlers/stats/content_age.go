@@ -0,0 +1,149 @@
+package stats
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type ContentAgeBucket struct {
+	Label          string  `json:"label"`
+	StartYear      int     `json:"start_year,omitempty"`
+	WatchHours     float64 `json:"watch_hours"`
+	Plays          int     `json:"plays"`
+	LibraryItems   int     `json:"library_items"`
+	WatchShare     float64 `json:"watch_share_pct"`
+	LibraryShare   float64 `json:"library_share_pct"`
+	WatchToLibrary float64 `json:"watch_to_library_ratio"` // >1 means watched more than its share of the library
+}
+
+// itemYearExpr is an item's release year, falling back to its series' year for episodes.
+const itemYearExpr = "COALESCE(NULLIF(li.production_year, 0), NULLIF(s.year, 0))"
+
+// GET /stats/content-age?days=365&group=decade|year&server=
+// Watch hours by release decade (or year) next to how much of the library each
+// decade makes up, to show whether the older catalog is actually watched.
+func ContentAge(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 365)
+		if days <= 0 || days > 3650 {
+			days = 365
+		}
+		group := c.Query("group", "decade")
+		if group != "year" {
+			group = "decade"
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		since := time.Now().AddDate(0, 0, -days).Unix()
+
+		buckets := map[int]*ContentAgeBucket{} // keyed by start year; 0 = unknown
+		bucketFor := func(year sql.NullInt64) *ContentAgeBucket {
+			key := 0
+			if year.Valid && year.Int64 > 0 {
+				key = int(year.Int64)
+				if group == "decade" {
+					key = key / 10 * 10
+				}
+			}
+			b, ok := buckets[key]
+			if !ok {
+				b = &ContentAgeBucket{StartYear: key}
+				switch {
+				case key == 0:
+					b.Label = "Unknown"
+				case group == "decade":
+					b.Label = fmt.Sprintf("%ds", key)
+				default:
+					b.Label = strconv.Itoa(key)
+				}
+				buckets[key] = b
+			}
+			return b
+		}
+
+		where, sargs := appendServerFilter("pi.start_ts >= ? AND "+excludeLiveTvFilterAlias("li"), "li", serverType, serverID)
+		rows, err := db.Query(`
+			SELECT `+itemYearExpr+` AS yr,
+			       SUM(pi.duration_seconds) / 3600.0,
+			       COUNT(DISTINCT pi.session_fk)
+			FROM play_intervals pi
+			JOIN library_item li ON li.id = pi.item_id
+			LEFT JOIN series s ON s.id = li.series_id
+			WHERE `+where+`
+			GROUP BY yr`, append([]any{since}, sargs...)...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		var totalHours float64
+		for rows.Next() {
+			var yr sql.NullInt64
+			var hours float64
+			var plays int
+			if err := rows.Scan(&yr, &hours, &plays); err != nil {
+				continue
+			}
+			b := bucketFor(yr)
+			b.WatchHours += hours
+			b.Plays += plays
+			totalHours += hours
+		}
+		rows.Close()
+
+		where, sargs = appendServerFilter("li.media_type IN ('Movie', 'Episode')", "li", serverType, serverID)
+		rows, err = db.Query(`
+			SELECT `+itemYearExpr+` AS yr, COUNT(*)
+			FROM library_item li
+			LEFT JOIN series s ON s.id = li.series_id
+			WHERE `+where+`
+			GROUP BY yr`, sargs...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+		totalItems := 0
+		for rows.Next() {
+			var yr sql.NullInt64
+			var n int
+			if err := rows.Scan(&yr, &n); err != nil {
+				continue
+			}
+			bucketFor(yr).LibraryItems += n
+			totalItems += n
+		}
+
+		out := make([]ContentAgeBucket, 0, len(buckets))
+		for _, b := range buckets {
+			if totalHours > 0 {
+				b.WatchShare = math.Round(b.WatchHours/totalHours*1000) / 10
+			}
+			if totalItems > 0 {
+				b.LibraryShare = math.Round(float64(b.LibraryItems)/float64(totalItems)*1000) / 10
+			}
+			if b.LibraryShare > 0 {
+				b.WatchToLibrary = math.Round(b.WatchShare/b.LibraryShare*100) / 100
+			}
+			b.WatchHours = math.Round(b.WatchHours*100) / 100
+			out = append(out, *b)
+		}
+		// Oldest first; unknown release year last
+		sort.Slice(out, func(i, j int) bool {
+			if (out[i].StartYear == 0) != (out[j].StartYear == 0) {
+				return out[j].StartYear == 0
+			}
+			return out[i].StartYear < out[j].StartYear
+		})
+
+		return c.JSON(fiber.Map{
+			"days":          days,
+			"group":         group,
+			"total_hours":   math.Round(totalHours*100) / 100,
+			"library_items": totalItems,
+			"buckets":       out,
+		})
+	}
+}
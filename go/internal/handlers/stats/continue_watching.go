@@ -0,0 +1,197 @@
+package stats
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"emby-analytics/internal/media"
+)
+
+// resumeBacklogTTL is how long the aggregate backlog is reused; it costs one server call per user.
+const resumeBacklogTTL = 5 * time.Minute
+
+// ContinueWatchingItem is one partially watched item on a user's resume list.
+type ContinueWatchingItem struct {
+	ItemID         string  `json:"item_id"`
+	Name           string  `json:"name"`
+	Type           string  `json:"type"`
+	SeriesName     string  `json:"series_name,omitempty"`
+	RuntimeHours   float64 `json:"runtime_hours"`
+	PositionHours  float64 `json:"position_hours"`
+	RemainingHours float64 `json:"remaining_hours"`
+	ProgressPct    float64 `json:"progress_pct"`
+	LastPlayed     string  `json:"last_played,omitempty"`
+}
+
+// ContinueWatching is a user's resume list with the hours left to finish it.
+type ContinueWatching struct {
+	UserID       string                 `json:"user_id"`
+	Name         string                 `json:"name"`
+	ServerID     string                 `json:"server_id"`
+	Items        []ContinueWatchingItem `json:"items"`
+	BacklogHours float64                `json:"backlog_hours"`
+}
+
+// ResumeBacklog aggregates the continue-watching lists of every tracked user.
+type ResumeBacklog struct {
+	BacklogHours float64            `json:"backlog_hours"`
+	Items        int                `json:"items"`
+	Users        []ContinueWatching `json:"users"`
+	// Servers whose users were skipped because the server can't report resume state (Plex)
+	UnsupportedServers []string `json:"unsupported_servers"`
+	GeneratedAt        string   `json:"generated_at"`
+}
+
+// ContinueWatchingHandler returns the user's continue-watching list from their media server.
+// GET /stats/users/:id/continue-watching?limit=50
+func ContinueWatchingHandler(db *sql.DB, mgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID := c.Params("id")
+		if userID == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "User ID is required"})
+		}
+		limit := parseQueryInt(c, "limit", 50)
+		if limit <= 0 || limit > 200 {
+			limit = 50
+		}
+
+		out := ContinueWatching{UserID: userID, Items: []ContinueWatchingItem{}}
+		err := db.QueryRow(`SELECT name, COALESCE(server_id, '') FROM emby_user WHERE id = ?`, userID).Scan(&out.Name, &out.ServerID)
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{"error": "User not found"})
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		reporter, ok := resumeReporter(mgr, out.ServerID)
+		if !ok {
+			return c.Status(501).JSON(fiber.Map{"error": "continue watching is not supported for this user's server"})
+		}
+		items, err := reporter.GetResumeItems(userID, limit)
+		if err != nil {
+			return c.Status(502).JSON(fiber.Map{"error": err.Error()})
+		}
+		out.Items, out.BacklogHours = continueWatchingItems(items)
+		return c.JSON(out)
+	}
+}
+
+var resumeBacklogCache struct {
+	mu      sync.Mutex
+	at      time.Time
+	backlog ResumeBacklog
+}
+
+// ResumeBacklogHandler returns the hours of partially watched content across all users
+// (excluded users are skipped), a dashboard engagement indicator. Cached for five minutes.
+// GET /stats/resume-backlog
+func ResumeBacklogHandler(db *sql.DB, mgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		resumeBacklogCache.mu.Lock()
+		defer resumeBacklogCache.mu.Unlock()
+		if !resumeBacklogCache.at.IsZero() && time.Since(resumeBacklogCache.at) < resumeBacklogTTL {
+			return c.JSON(resumeBacklogCache.backlog)
+		}
+
+		rows, err := db.Query(`
+			SELECT id, name, COALESCE(server_id, '')
+			FROM emby_user
+			WHERE deleted_at IS NULL AND exclude_from_stats = 0
+			ORDER BY name
+		`)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		var users []ContinueWatching
+		for rows.Next() {
+			var u ContinueWatching
+			if err := rows.Scan(&u.UserID, &u.Name, &u.ServerID); err != nil {
+				rows.Close()
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			users = append(users, u)
+		}
+		rows.Close()
+
+		out := ResumeBacklog{Users: []ContinueWatching{}, UnsupportedServers: []string{}}
+		unsupported := map[string]bool{}
+		for _, u := range users {
+			reporter, ok := resumeReporter(mgr, u.ServerID)
+			if !ok {
+				if !unsupported[u.ServerID] {
+					unsupported[u.ServerID] = true
+					out.UnsupportedServers = append(out.UnsupportedServers, u.ServerID)
+				}
+				continue
+			}
+			items, err := reporter.GetResumeItems(u.UserID, 0)
+			if err != nil {
+				continue // one unreachable server shouldn't hide everyone else's backlog
+			}
+			u.Items, u.BacklogHours = continueWatchingItems(items)
+			if len(u.Items) == 0 {
+				continue
+			}
+			out.BacklogHours += u.BacklogHours
+			out.Items += len(u.Items)
+			out.Users = append(out.Users, u)
+		}
+		sort.Slice(out.Users, func(i, j int) bool { return out.Users[i].BacklogHours > out.Users[j].BacklogHours })
+		out.BacklogHours = math.Round(out.BacklogHours*100) / 100
+		out.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+
+		resumeBacklogCache.at = time.Now()
+		resumeBacklogCache.backlog = out
+		return c.JSON(out)
+	}
+}
+
+func resumeReporter(mgr *media.MultiServerManager, serverID string) (media.ResumeReporter, bool) {
+	if mgr == nil {
+		return nil, false
+	}
+	client, ok := mgr.GetClient(serverID)
+	if !ok || client == nil {
+		return nil, false
+	}
+	reporter, ok := client.(media.ResumeReporter)
+	return reporter, ok
+}
+
+// continueWatchingItems converts resume entries and sums the hours left to finish them.
+func continueWatchingItems(items []media.UserDataItem) ([]ContinueWatchingItem, float64) {
+	out := make([]ContinueWatchingItem, 0, len(items))
+	var backlog float64
+	for _, it := range items {
+		if it.PlaybackPositionMs <= 0 {
+			continue
+		}
+		item := ContinueWatchingItem{
+			ItemID:        it.ID,
+			Name:          it.Name,
+			Type:          it.Type,
+			SeriesName:    it.SeriesName,
+			RuntimeHours:  msToHours(it.RuntimeMs),
+			PositionHours: msToHours(it.PlaybackPositionMs),
+			LastPlayed:    it.LastPlayed,
+		}
+		if it.RuntimeMs > it.PlaybackPositionMs {
+			remaining := float64(it.RuntimeMs-it.PlaybackPositionMs) / 3_600_000
+			item.RemainingHours = math.Round(remaining*100) / 100
+			item.ProgressPct = math.Round(float64(it.PlaybackPositionMs)/float64(it.RuntimeMs)*1000) / 10
+			backlog += remaining
+		}
+		out = append(out, item)
+	}
+	return out, math.Round(backlog*100) / 100
+}
+
+func msToHours(ms int64) float64 {
+	return math.Round(float64(ms)/3_600_000*100) / 100
+}
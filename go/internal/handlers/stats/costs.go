@@ -0,0 +1,75 @@
+package stats
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/handlers/settings"
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// costModel reads the cost rates from settings; unset or invalid rates are 0.
+func costModel(db *sql.DB) queries.CostModel {
+	rate := func(key string) float64 {
+		v, err := strconv.ParseFloat(settings.GetSettingValue(db, key, "0"), 64)
+		if err != nil || v < 0 {
+			return 0
+		}
+		return v
+	}
+	return queries.CostModel{
+		Currency:       strings.TrimSpace(settings.GetSettingValue(db, settings.CostCurrencyKey, "")),
+		PerStreamHour:  rate(settings.CostPerStreamHourKey),
+		PerGB:          rate(settings.CostPerGBKey),
+		PerTranscodeGB: rate(settings.CostPerTranscodeGBKey),
+		MonthlyFixed:   rate(settings.CostMonthlyFixedKey),
+	}
+}
+
+// GET /reports/costs?months=3&tz=&server=
+// Approximate monthly cost per user and server for the last `months` local calendar months
+// in ?tz= (IANA name, default server local), priced with the cost_* settings: hours
+// streamed, GB delivered and transcoded GB, plus each row's share of the fixed monthly cost
+// by hours watched. Lets households sharing a server split its running costs by usage.
+func CostsReportHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		loc := time.Local
+		if tz := strings.TrimSpace(c.Query("tz")); tz != "" {
+			l, err := time.LoadLocation(tz)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "unknown timezone: " + tz})
+			}
+			loc = l
+		}
+		months := parseQueryInt(c, "months", 3)
+		if months <= 0 || months > 24 {
+			months = 3
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+
+		model := costModel(db)
+		report, err := queries.CostAttribution(c, db, months, loc, time.Now(), model, serverType, serverID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if mask := viewerMask(c, db); !mask.empty() {
+			for i := range report {
+				for j := range report[i].Rows {
+					if r := &report[i].Rows[j]; mask.hides(r.UserID) {
+						r.UserID = ""
+						r.UserName = queries.AnonymousName
+					}
+				}
+			}
+		}
+		return c.JSON(fiber.Map{
+			"timezone": loc.String(),
+			"model":    model,
+			"months":   report,
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"emby-analytics/internal/emby"
+	"emby-analytics/internal/media"
+)
+
+// dashboardTTL is how long a computed dashboard is reused; page loads and quick refreshes
+// within it share one computation.
+const dashboardTTL = 30 * time.Second
+
+// dashboardCards are the cards /stats/dashboard can compute, in response order.
+var dashboardCards = []string{"overview", "usage", "top_users", "top_items"}
+
+// Dashboard is the combined payload for the dashboard's initial load.
+type Dashboard struct {
+	Cards  map[string]any    `json:"cards"`
+	Errors map[string]string `json:"errors,omitempty"`
+	// Warnings lists servers whose data is currently missing from the cards
+	Warnings    []media.ServerWarning `json:"warnings,omitempty"`
+	GeneratedAt string                `json:"generated_at"`
+	Cached      bool                  `json:"cached"`
+}
+
+type dashboardParams struct {
+	cards     []string
+	days      int
+	timeframe string
+	limit     int
+	server    string
+	tag       string
+}
+
+func (p dashboardParams) key() string {
+	return fmt.Sprintf("%s|%d|%s|%d|%s|%s", strings.Join(p.cards, ","), p.days, p.timeframe, p.limit, p.server, p.tag)
+}
+
+var dashboardCache struct {
+	mu      sync.Mutex
+	entries map[string]dashboardCacheEntry
+}
+
+type dashboardCacheEntry struct {
+	at   time.Time
+	data Dashboard
+}
+
+// DashboardHandler computes the overview, usage, top users and top items cards in one
+// request. Cards are computed concurrently and the result is cached briefly, so the
+// dashboard no longer fans out into one request per card on load.
+// Query params: cards (comma-separated, default all), days (usage, default 14),
+// timeframe/days (top lists), limit (top lists, default 10), server (usage and top lists)
+// and tag (top items).
+// GET /stats/dashboard
+func DashboardHandler(db *sql.DB, mgr *media.MultiServerManager, em *emby.Client) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		p := dashboardParams{
+			days:      parseQueryInt(c, "days", 14),
+			timeframe: timeframeParam(c),
+			limit:     parseQueryInt(c, "limit", 10),
+			server:    c.Query("server", ""),
+			tag:       c.Query("tag", ""),
+		}
+		if p.days <= 0 {
+			p.days = 14
+		}
+		if p.limit <= 0 || p.limit > 100 {
+			p.limit = 10
+		}
+		cards, unknown := parseDashboardCards(c.Query("cards", ""))
+		if len(unknown) > 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "unknown cards: " + strings.Join(unknown, ", "), "cards": dashboardCards})
+		}
+		p.cards = cards
+
+		key := p.key()
+		dashboardCache.mu.Lock()
+		if e, ok := dashboardCache.entries[key]; ok && time.Since(e.at) < dashboardTTL {
+			dashboardCache.mu.Unlock()
+			out := e.data
+			out.Cached = true
+			out.Warnings = mgr.ServerWarnings()
+			return c.JSON(anonymizeDashboard(out, viewerMask(c, db)))
+		}
+		dashboardCache.mu.Unlock()
+
+		out := computeDashboard(db, mgr, em, p)
+
+		dashboardCache.mu.Lock()
+		if dashboardCache.entries == nil {
+			dashboardCache.entries = map[string]dashboardCacheEntry{}
+		}
+		for k, e := range dashboardCache.entries {
+			if time.Since(e.at) >= dashboardTTL {
+				delete(dashboardCache.entries, k)
+			}
+		}
+		if len(out.Errors) == 0 {
+			dashboardCache.entries[key] = dashboardCacheEntry{at: time.Now(), data: out}
+		}
+		dashboardCache.mu.Unlock()
+		out.Warnings = mgr.ServerWarnings()
+		return c.JSON(anonymizeDashboard(out, viewerMask(c, db)))
+	}
+}
+
+// parseDashboardCards returns the requested cards in canonical order; empty selects all.
+func parseDashboardCards(raw string) ([]string, []string) {
+	if strings.TrimSpace(raw) == "" {
+		return dashboardCards, nil
+	}
+	want := map[string]bool{}
+	var unknown []string
+	for _, part := range strings.Split(raw, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, card := range dashboardCards {
+			if card == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			unknown = append(unknown, name)
+			continue
+		}
+		want[name] = true
+	}
+	var out []string
+	for _, card := range dashboardCards {
+		if want[card] {
+			out = append(out, card)
+		}
+	}
+	sort.Strings(unknown)
+	return out, unknown
+}
+
+// computeDashboard runs every selected card concurrently; a failing card is reported in
+// Errors without failing the others.
+//
+// The cards don't share a read transaction, so a sync committing mid-request can show in
+// one card and not yet in another. A snapshot would not make them agree: top users add
+// live watch time and the overview adds server health from memory, which move on
+// regardless. It would also pin one of the four pooled connections, serialising the
+// cards, and every card goes through the *sql.DB helpers its standalone endpoint uses.
+// The cards are as consistent as when the dashboard fetched them one request at a time.
+func computeDashboard(db *sql.DB, mgr *media.MultiServerManager, em *emby.Client, p dashboardParams) Dashboard {
+	ctx := context.Background()
+	out := Dashboard{Cards: map[string]any{}, Errors: map[string]string{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, card := range p.cards {
+		wg.Add(1)
+		go func(card string) {
+			defer wg.Done()
+			var data any
+			var err error
+			switch card {
+			case "overview":
+				data, err = overviewData(db, mgr)
+			case "usage":
+				serverType, serverID := normalizeServerParam(p.server)
+				data, err = usageRows(db, mgr, p.days, serverType, serverID)
+			case "top_users":
+				data, err = topUsersData(ctx, db, mgr, p.timeframe, p.limit, p.server)
+			case "top_items":
+				data, err = topItemsData(ctx, db, em, p.timeframe, p.limit, p.server, p.tag)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				out.Errors[card] = err.Error()
+				return
+			}
+			out.Cards[card] = data
+		}(card)
+	}
+	wg.Wait()
+	if len(out.Errors) == 0 {
+		out.Errors = nil
+	}
+	out.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+	return out
+}
@@ -0,0 +1,191 @@
+package stats
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type DeviceUserStat struct {
+	UserID   string  `json:"user_id"`
+	UserName string  `json:"user_name"`
+	Sessions int     `json:"sessions"`
+	Hours    float64 `json:"hours"`
+}
+
+type DeviceSession struct {
+	SessionID     string   `json:"session_id"`
+	ServerID      string   `json:"server_id"`
+	UserID        string   `json:"user_id"`
+	UserName      string   `json:"user_name"`
+	ItemID        string   `json:"item_id"`
+	ItemName      string   `json:"item_name"`
+	ItemType      string   `json:"item_type"`
+	ClientName    string   `json:"client_name"`
+	ClientVersion string   `json:"client_version,omitempty"`
+	Platform      string   `json:"platform,omitempty"`
+	PlayMethod    string   `json:"play_method"`
+	StartedAt     int64    `json:"started_at"`
+	EndedAt       *int64   `json:"ended_at,omitempty"`
+	Hours         float64  `json:"hours"`
+	Note          string   `json:"note,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+type DeviceHistory struct {
+	DeviceID   string           `json:"device_id"` // canonical id when the device is merged
+	DeviceName string           `json:"device_name"`
+	DeviceIDs  []string         `json:"device_ids"` // every id merged into the device
+	Days       int              `json:"days"`
+	Clients    []string         `json:"clients"`
+	Platforms  []string         `json:"platforms"`
+	TotalHours float64          `json:"total_hours"`
+	Sessions   int              `json:"sessions"`
+	Users      []DeviceUserStat `json:"users"`
+	History    []DeviceSession  `json:"history"`
+}
+
+// GET /stats/devices/:deviceId/history?days=30&limit=50&server=&platform=
+// Sessions and watch hours for one device across all users, so shared devices
+// (living-room TVs) can be analyzed separately from personal ones. Any id of a merged
+// device covers the whole device. platform keeps only sessions on that OS platform
+// ("Unknown" for sessions without one).
+func DeviceHistoryHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		deviceID := c.Params("deviceId", "")
+		if deviceID == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "missing device id"})
+		}
+		days := parseQueryInt(c, "days", 30)
+		if days <= 0 || days > 3650 {
+			days = 30
+		}
+		limit := parseQueryInt(c, "limit", 50)
+		if limit <= 0 || limit > 500 {
+			limit = 50
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		since := time.Now().AddDate(0, 0, -days).Unix()
+
+		device, err := queries.ResolveDevice(c, db, deviceID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		where := "ps.device_id IN (?" + strings.Repeat(", ?", len(device.DeviceIDs)-1) + ") AND ps.started_at >= ?"
+		args := make([]any, 0, len(device.DeviceIDs)+1)
+		for _, id := range device.DeviceIDs {
+			args = append(args, id)
+		}
+		where, sargs := appendServerFilter(where, "ps", serverType, serverID)
+		args = append(append(args, since), sargs...)
+		pwhere, pargs := platformFilter("ps", c.Query("platform", ""))
+		where += pwhere
+		args = append(args, pargs...)
+
+		name := device.Name
+		if name == "" {
+			name = device.CanonicalID
+		}
+		out := DeviceHistory{DeviceID: device.CanonicalID, DeviceName: name, DeviceIDs: device.DeviceIDs, Days: days, Clients: []string{}, Platforms: []string{}, Users: []DeviceUserStat{}, History: []DeviceSession{}}
+
+		rows, err := db.Query(`
+			SELECT DISTINCT COALESCE(ps.client_name, '')
+			FROM play_sessions ps
+			WHERE `+where+` AND COALESCE(ps.client_name, '') != ''
+			ORDER BY 1`, args...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err == nil {
+				out.Clients = append(out.Clients, name)
+			}
+		}
+		rows.Close()
+
+		rows, err = db.Query(`
+			SELECT DISTINCT ps.platform
+			FROM play_sessions ps
+			WHERE `+where+` AND COALESCE(ps.platform, '') != ''
+			ORDER BY 1`, args...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err == nil {
+				out.Platforms = append(out.Platforms, name)
+			}
+		}
+		rows.Close()
+
+		// Per-user totals; hours come from the session's watch intervals
+		rows, err = db.Query(`
+			SELECT ps.user_id,
+			       COALESCE(MAX(u.name), MAX(ps.user_name), ps.user_id),
+			       COUNT(DISTINCT ps.id),
+			       COALESCE(SUM(iv.seconds), 0) / 3600.0
+			FROM play_sessions ps
+			LEFT JOIN emby_user u ON u.id = ps.user_id
+			LEFT JOIN (
+				SELECT session_fk, SUM(duration_seconds) AS seconds FROM play_intervals GROUP BY session_fk
+			) iv ON iv.session_fk = ps.id
+			WHERE `+where+`
+			GROUP BY ps.user_id
+			ORDER BY 4 DESC`, args...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		for rows.Next() {
+			var s DeviceUserStat
+			if err := rows.Scan(&s.UserID, &s.UserName, &s.Sessions, &s.Hours); err == nil {
+				out.Users = append(out.Users, s)
+				out.Sessions += s.Sessions
+				out.TotalHours += s.Hours
+			}
+		}
+		rows.Close()
+
+		rows, err = db.Query(`
+			SELECT ps.session_id, COALESCE(ps.server_id, ''), ps.user_id,
+			       COALESCE(u.name, ps.user_name, ps.user_id),
+			       ps.item_id, COALESCE(li.name, ps.item_name, ''), COALESCE(ps.item_type, ''),
+			       COALESCE(ps.client_name, ''), COALESCE(ps.client_version, ''), COALESCE(ps.platform, ''),
+			       COALESCE(ps.play_method, ''),
+			       ps.started_at, ps.ended_at,
+			       COALESCE((SELECT SUM(duration_seconds) FROM play_intervals WHERE session_fk = ps.id), 0) / 3600.0,
+			       COALESCE(ps.note, ''), COALESCE(ps.tags, '')
+			FROM play_sessions ps
+			LEFT JOIN emby_user u ON u.id = ps.user_id
+			LEFT JOIN library_item li ON li.id = ps.item_id
+			WHERE `+where+`
+			ORDER BY ps.started_at DESC
+			LIMIT ?`, append(args, limit)...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var s DeviceSession
+			var ended sql.NullInt64
+			var tags string
+			if err := rows.Scan(&s.SessionID, &s.ServerID, &s.UserID, &s.UserName, &s.ItemID, &s.ItemName, &s.ItemType,
+				&s.ClientName, &s.ClientVersion, &s.Platform, &s.PlayMethod, &s.StartedAt, &ended, &s.Hours, &s.Note, &tags); err != nil {
+				continue
+			}
+			if ended.Valid {
+				v := ended.Int64
+				s.EndedAt = &v
+			}
+			s.Tags = splitTags(tags)
+			out.History = append(out.History, s)
+		}
+
+		return c.JSON(out)
+	}
+}
@@ -0,0 +1,9 @@
+package stats
+
+// Device names and merges (device_aliases) applied to play_sessions ps: join
+// deviceAliasJoin, then group by deviceKeyExpr and display deviceNameExpr.
+const (
+	deviceAliasJoin = "LEFT JOIN device_aliases da ON da.device_id = ps.device_id"
+	deviceKeyExpr   = "COALESCE(da.canonical_id, ps.device_id)"
+	deviceNameExpr  = "COALESCE(NULLIF(da.name, ''), ps.device_id, '')"
+)
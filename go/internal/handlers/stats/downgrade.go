@@ -0,0 +1,111 @@
+package stats
+
+import (
+	"database/sql"
+	"math"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type DowngradeCandidate struct {
+	ID                 string  `json:"id"`
+	Title              string  `json:"title"`
+	ItemType           string  `json:"item_type"`
+	ServerID           string  `json:"server_id"`
+	Width              int     `json:"width"`
+	Sessions           int     `json:"sessions"`
+	Viewers            int     `json:"viewers"`
+	DownscaledSessions int     `json:"downscaled_sessions"`
+	UnknownSessions    int     `json:"unknown_output_sessions"`
+	MaxOutputHeight    int     `json:"max_output_height,omitempty"`
+	SizeGB             float64 `json:"size_gb"`
+	Estimated1080pGB   float64 `json:"estimated_1080p_gb"`
+	SavingsGB          float64 `json:"savings_gb"`
+}
+
+// transcodedTo1080Expr matches sessions whose video was transcoded to 1080p or lower
+// (by output width, or by height when the width was not reported).
+const transcodedTo1080Expr = `LOWER(COALESCE(ps.video_method, '')) LIKE '%transcode%'
+	AND (ps.transcode_width BETWEEN 1 AND 1920
+	     OR (COALESCE(ps.transcode_width, 0) = 0 AND ps.transcode_height BETWEEN 1 AND 1080))`
+
+// transcodedUnknownExpr matches video transcodes recorded before output size was tracked.
+const transcodedUnknownExpr = `LOWER(COALESCE(ps.video_method, '')) LIKE '%transcode%'
+	AND COALESCE(ps.transcode_width, 0) = 0 AND COALESCE(ps.transcode_height, 0) = 0`
+
+// GET /stats/library/downgrade-candidates?target_mbps=10&strict=0&limit=100&server=
+// 4K items that every viewer only ever watched transcoded down to 1080p or lower,
+// with the space a 1080p copy at target_mbps would save. Transcodes whose output
+// size is unknown (older sessions) still qualify unless strict=1.
+func DowngradeCandidates(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		limit := parseQueryInt(c, "limit", 100)
+		if limit <= 0 || limit > 1000 {
+			limit = 100
+		}
+		targetMbps, err := strconv.ParseFloat(c.Query("target_mbps", "10"), 64)
+		if err != nil || targetMbps <= 0 {
+			targetMbps = 10
+		}
+		strict := c.Query("strict", "") == "1"
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+
+		having := "downscaled > 0 AND downscaled + unknown = sessions"
+		if strict {
+			having = "downscaled > 0 AND downscaled = sessions"
+		}
+		where, args := appendServerFilter("li.width > 1920", "li", serverType, serverID)
+		rows, err := db.Query(`
+			SELECT li.id, COALESCE(li.name, ''), COALESCE(li.media_type, ''), COALESCE(li.server_id, ''),
+			       li.width, COALESCE(li.file_size_bytes, 0), COALESCE(li.run_time_ticks, 0),
+			       COUNT(ps.id) AS sessions,
+			       COUNT(DISTINCT ps.user_id),
+			       SUM(CASE WHEN `+transcodedTo1080Expr+` THEN 1 ELSE 0 END) AS downscaled,
+			       SUM(CASE WHEN `+transcodedUnknownExpr+` THEN 1 ELSE 0 END) AS unknown,
+			       MAX(COALESCE(ps.transcode_height, 0))
+			FROM library_item li
+			JOIN play_sessions ps ON ps.item_id = li.id
+			WHERE `+where+`
+			GROUP BY li.id
+			HAVING `+having+`
+			ORDER BY li.file_size_bytes DESC
+			LIMIT ?`, append(args, limit)...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+
+		items := []DowngradeCandidate{}
+		var totalSize, totalSavings float64
+		for rows.Next() {
+			var d DowngradeCandidate
+			var sizeBytes, ticks int64
+			if err := rows.Scan(&d.ID, &d.Title, &d.ItemType, &d.ServerID, &d.Width, &sizeBytes, &ticks,
+				&d.Sessions, &d.Viewers, &d.DownscaledSessions, &d.UnknownSessions, &d.MaxOutputHeight); err != nil {
+				continue
+			}
+			// 1080p size from runtime at the target bitrate; without a runtime assume a quarter of the 4K file
+			est := float64(sizeBytes) / 4
+			if ticks > 0 {
+				est = float64(ticks) / 1e7 * targetMbps * 1e6 / 8
+			}
+			savings := math.Max(0, float64(sizeBytes)-est)
+			d.SizeGB = math.Round(float64(sizeBytes)/(1<<30)*100) / 100
+			d.Estimated1080pGB = math.Round(est/(1<<30)*100) / 100
+			d.SavingsGB = math.Round(savings/(1<<30)*100) / 100
+			totalSize += d.SizeGB
+			totalSavings += d.SavingsGB
+			items = append(items, d)
+		}
+
+		return c.JSON(fiber.Map{
+			"target_mbps":      targetMbps,
+			"strict":           strict,
+			"candidates":       items,
+			"total_count":      len(items),
+			"total_size_gb":    math.Round(totalSize*100) / 100,
+			"total_savings_gb": math.Round(totalSavings*100) / 100,
+		})
+	}
+}
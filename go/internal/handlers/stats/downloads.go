@@ -0,0 +1,138 @@
+package stats
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// DownloadSummary aggregates offline download / sync activity next to streaming for comparison
+type DownloadSummary struct {
+	Days              int                `json:"days"`
+	TotalJobs         int                `json:"total_jobs"`
+	CompletedJobs     int                `json:"completed_jobs"`
+	FailedJobs        int                `json:"failed_jobs"`
+	ActiveJobs        int                `json:"active_jobs"`
+	TotalSizeGB       float64            `json:"total_size_gb"`
+	StreamingSessions int                `json:"streaming_sessions"`
+	ByUser            []DownloadUserStat `json:"by_user"`
+	Recent            []DownloadEntry    `json:"recent"`
+}
+
+type DownloadUserStat struct {
+	UserID    string  `json:"user_id"`
+	UserName  string  `json:"user_name"`
+	Downloads int     `json:"downloads"`
+	SizeGB    float64 `json:"size_gb"`
+}
+
+type DownloadEntry struct {
+	ServerID    string  `json:"server_id"`
+	ServerType  string  `json:"server_type"`
+	UserID      string  `json:"user_id"`
+	UserName    string  `json:"user_name"`
+	ItemID      string  `json:"item_id"`
+	ItemName    string  `json:"item_name"`
+	DeviceName  string  `json:"device_name"`
+	Status      string  `json:"status"`
+	Progress    float64 `json:"progress"`
+	SizeBytes   int64   `json:"size_bytes"`
+	FirstSeenAt int64   `json:"first_seen_at"`
+	LastSeenAt  int64   `json:"last_seen_at"`
+	CompletedAt *int64  `json:"completed_at,omitempty"`
+}
+
+// Downloads returns offline download / sync activity over the last N days (default 30)
+func Downloads(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 30)
+		if days <= 0 || days > 3650 {
+			days = 30
+		}
+		limit := parseQueryInt(c, "limit", 50)
+		if limit <= 0 || limit > 500 {
+			limit = 50
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		since := time.Now().AddDate(0, 0, -days).Unix()
+
+		where, sargs := appendServerFilter("dj.first_seen_at >= ?", "dj", serverType, serverID)
+		args := append([]any{since}, sargs...)
+
+		out := DownloadSummary{Days: days, ByUser: []DownloadUserStat{}, Recent: []DownloadEntry{}}
+
+		var totalBytes int64
+		if err := db.QueryRow(`
+			SELECT COUNT(*),
+			       COALESCE(SUM(CASE WHEN dj.completed_at IS NOT NULL THEN 1 ELSE 0 END), 0),
+			       COALESCE(SUM(CASE WHEN LOWER(dj.status) IN ('failed', 'cancelled') THEN 1 ELSE 0 END), 0),
+			       COALESCE(SUM(CASE WHEN dj.completed_at IS NULL AND LOWER(dj.status) NOT IN ('failed', 'cancelled') THEN 1 ELSE 0 END), 0),
+			       COALESCE(SUM(dj.size_bytes), 0)
+			FROM download_jobs dj
+			WHERE `+where, args...).Scan(&out.TotalJobs, &out.CompletedJobs, &out.FailedJobs, &out.ActiveJobs, &totalBytes); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		out.TotalSizeGB = float64(totalBytes) / 1073741824.0
+
+		// Streaming sessions over the same window, so download traffic can be put in context
+		swhere, sargs2 := appendServerFilter("ps.started_at >= ?", "ps", serverType, serverID)
+		_ = db.QueryRow(`SELECT COUNT(*) FROM play_sessions ps WHERE `+swhere, append([]any{since}, sargs2...)...).Scan(&out.StreamingSessions)
+
+		rows, err := db.Query(`
+			SELECT COALESCE(dj.user_id, ''),
+			       COALESCE(NULLIF(dj.user_name, ''), u.name, ''),
+			       COUNT(*),
+			       COALESCE(SUM(dj.size_bytes), 0)
+			FROM download_jobs dj
+			LEFT JOIN emby_user u ON u.id = dj.user_id
+			WHERE `+where+`
+			GROUP BY dj.user_id
+			ORDER BY COUNT(*) DESC
+			LIMIT ?`, append(args, limit)...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		for rows.Next() {
+			var s DownloadUserStat
+			var bytes int64
+			if err := rows.Scan(&s.UserID, &s.UserName, &s.Downloads, &bytes); err == nil {
+				s.SizeGB = float64(bytes) / 1073741824.0
+				out.ByUser = append(out.ByUser, s)
+			}
+		}
+		rows.Close()
+
+		rows, err = db.Query(`
+			SELECT dj.server_id, dj.server_type, COALESCE(dj.user_id, ''),
+			       COALESCE(NULLIF(dj.user_name, ''), u.name, ''),
+			       COALESCE(dj.item_id, ''), COALESCE(NULLIF(dj.item_name, ''), li.name, ''),
+			       COALESCE(dj.device_name, ''), dj.status, dj.progress, dj.size_bytes,
+			       dj.first_seen_at, dj.last_seen_at, dj.completed_at
+			FROM download_jobs dj
+			LEFT JOIN emby_user u ON u.id = dj.user_id
+			LEFT JOIN library_item li ON li.id = dj.item_id
+			WHERE `+where+`
+			ORDER BY dj.last_seen_at DESC
+			LIMIT ?`, append(args, limit)...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var e DownloadEntry
+			var completed sql.NullInt64
+			if err := rows.Scan(&e.ServerID, &e.ServerType, &e.UserID, &e.UserName, &e.ItemID, &e.ItemName,
+				&e.DeviceName, &e.Status, &e.Progress, &e.SizeBytes, &e.FirstSeenAt, &e.LastSeenAt, &completed); err != nil {
+				continue
+			}
+			if completed.Valid {
+				v := completed.Int64
+				e.CompletedAt = &v
+			}
+			out.Recent = append(out.Recent, e)
+		}
+
+		return c.JSON(out)
+	}
+}
@@ -0,0 +1,158 @@
+package stats
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// DVRSummary describes current DVR usage across Live TV capable servers
+type DVRSummary struct {
+	TotalRecordings   int     `json:"total_recordings"`
+	ScheduledCount    int     `json:"scheduled_count"`
+	SeriesTimerCount  int     `json:"series_timer_count"`
+	StorageGB         float64 `json:"storage_gb"`
+	RecordedHours     float64 `json:"recorded_hours"`
+	RecordingsLast7d  int     `json:"recordings_last_7d"`
+	DeletedRecordings int     `json:"deleted_recordings"`
+}
+
+// DVRWeek is the number of recordings started in a given week
+type DVRWeek struct {
+	WeekStart  string  `json:"week_start"` // YYYY-MM-DD (Monday)
+	Recordings int     `json:"recordings"`
+	Hours      float64 `json:"hours"`
+	SizeGB     float64 `json:"size_gb"`
+}
+
+// DVRShow is a show ranked by how often it was recorded
+type DVRShow struct {
+	Name       string  `json:"name"`
+	Recordings int     `json:"recordings"`
+	Hours      float64 `json:"hours"`
+	SizeGB     float64 `json:"size_gb"`
+}
+
+// dvrShowNameExpr groups episodes of the same series, falling back to the program name
+const dvrShowNameExpr = "COALESCE(NULLIF(r.series_name, ''), r.name, 'Unknown')"
+
+// DVR returns a summary of recordings, scheduled timers and storage consumed
+func DVR(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		where, args := appendServerFilter("1=1", "r", serverType, serverID)
+		weekAgo := time.Now().AddDate(0, 0, -7).Unix()
+
+		var out DVRSummary
+		var sizeBytes, seconds int64
+		err := db.QueryRow(`
+			SELECT
+				COALESCE(SUM(CASE WHEN r.is_scheduled = 0 AND r.removed_at IS NULL THEN 1 ELSE 0 END), 0),
+				COALESCE(SUM(CASE WHEN r.is_scheduled = 1 AND r.removed_at IS NULL THEN 1 ELSE 0 END), 0),
+				COALESCE(SUM(CASE WHEN r.is_scheduled = 0 AND r.removed_at IS NULL THEN r.size_bytes ELSE 0 END), 0),
+				COALESCE(SUM(CASE WHEN r.is_scheduled = 0 AND r.removed_at IS NULL AND r.end_ts > r.start_ts THEN r.end_ts - r.start_ts ELSE 0 END), 0),
+				COALESCE(SUM(CASE WHEN r.is_scheduled = 0 AND r.start_ts >= ? THEN 1 ELSE 0 END), 0),
+				COALESCE(SUM(CASE WHEN r.is_scheduled = 0 AND r.removed_at IS NOT NULL THEN 1 ELSE 0 END), 0)
+			FROM dvr_recordings r
+			WHERE `+where, append([]any{weekAgo}, args...)...).Scan(
+			&out.TotalRecordings, &out.ScheduledCount, &sizeBytes, &seconds, &out.RecordingsLast7d, &out.DeletedRecordings)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		out.StorageGB = float64(sizeBytes) / 1073741824.0
+		out.RecordedHours = float64(seconds) / 3600.0
+
+		twhere, targs := appendServerFilter("1=1", "t", serverType, serverID)
+		_ = db.QueryRow(`SELECT COUNT(*) FROM dvr_series_timers t WHERE `+twhere, targs...).Scan(&out.SeriesTimerCount)
+
+		return c.JSON(out)
+	}
+}
+
+// DVRRecordingsPerWeek returns recordings grouped by ISO week over the last N weeks (default 12)
+func DVRRecordingsPerWeek(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		weeks := parseQueryInt(c, "weeks", 12)
+		if weeks <= 0 || weeks > 104 {
+			weeks = 12
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		since := time.Now().AddDate(0, 0, -7*weeks).Unix()
+		where, args := appendServerFilter("r.is_scheduled = 0 AND r.start_ts >= ?", "r", serverType, serverID)
+
+		rows, err := db.Query(`
+			SELECT
+				date(r.start_ts, 'unixepoch', '-6 days', 'weekday 1') AS week_start,
+				COUNT(*),
+				COALESCE(SUM(CASE WHEN r.end_ts > r.start_ts THEN r.end_ts - r.start_ts ELSE 0 END), 0),
+				COALESCE(SUM(r.size_bytes), 0)
+			FROM dvr_recordings r
+			WHERE `+where+`
+			GROUP BY week_start
+			ORDER BY week_start`, append([]any{since}, args...)...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+
+		out := []DVRWeek{}
+		for rows.Next() {
+			var w DVRWeek
+			var seconds, bytes int64
+			if err := rows.Scan(&w.WeekStart, &w.Recordings, &seconds, &bytes); err != nil {
+				continue
+			}
+			w.Hours = float64(seconds) / 3600.0
+			w.SizeGB = float64(bytes) / 1073741824.0
+			out = append(out, w)
+		}
+		return c.JSON(out)
+	}
+}
+
+// DVRTopShows returns the most recorded shows over the last N days (default all-time)
+func DVRTopShows(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		limit := parseQueryInt(c, "limit", 10)
+		if limit <= 0 || limit > 100 {
+			limit = 10
+		}
+		days := parseQueryInt(c, "days", 0)
+		var since int64
+		if days > 0 {
+			since = time.Now().AddDate(0, 0, -days).Unix()
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		where, args := appendServerFilter("r.is_scheduled = 0 AND COALESCE(r.start_ts, 0) >= ?", "r", serverType, serverID)
+
+		rows, err := db.Query(`
+			SELECT
+				`+dvrShowNameExpr+` AS show_name,
+				COUNT(*),
+				COALESCE(SUM(CASE WHEN r.end_ts > r.start_ts THEN r.end_ts - r.start_ts ELSE 0 END), 0),
+				COALESCE(SUM(CASE WHEN r.removed_at IS NULL THEN r.size_bytes ELSE 0 END), 0)
+			FROM dvr_recordings r
+			WHERE `+where+`
+			GROUP BY show_name
+			ORDER BY COUNT(*) DESC
+			LIMIT ?`, append(append([]any{since}, args...), limit)...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+
+		out := []DVRShow{}
+		for rows.Next() {
+			var s DVRShow
+			var seconds, bytes int64
+			if err := rows.Scan(&s.Name, &s.Recordings, &seconds, &bytes); err != nil {
+				continue
+			}
+			s.Hours = float64(seconds) / 3600.0
+			s.SizeGB = float64(bytes) / 1073741824.0
+			out = append(out, s)
+		}
+		return c.JSON(out)
+	}
+}
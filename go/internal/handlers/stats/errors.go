@@ -0,0 +1,149 @@
+package stats
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ErrorSummary groups playback errors so chronically failing files and clients stand out
+type ErrorSummary struct {
+	Days        int               `json:"days"`
+	TotalErrors int               `json:"total_errors"`
+	ByItem      []ItemErrorStat   `json:"by_item"`
+	ByClient    []ClientErrorStat `json:"by_client"`
+	Recent      []PlaybackError   `json:"recent"`
+}
+
+type ItemErrorStat struct {
+	ItemID    string `json:"item_id"`
+	ItemName  string `json:"item_name"`
+	ItemType  string `json:"item_type"`
+	FilePath  string `json:"file_path,omitempty"`
+	Errors    int    `json:"errors"`
+	Users     int    `json:"users"`
+	Clients   int    `json:"clients"`
+	LastError string `json:"last_error"`
+	LastSeen  int64  `json:"last_seen"`
+}
+
+type ClientErrorStat struct {
+	ClientName string `json:"client_name"`
+	Errors     int    `json:"errors"`
+	Items      int    `json:"items"`
+	Users      int    `json:"users"`
+	LastSeen   int64  `json:"last_seen"`
+}
+
+type PlaybackError struct {
+	ServerID   string `json:"server_id"`
+	ItemID     string `json:"item_id"`
+	ItemName   string `json:"item_name"`
+	UserName   string `json:"user_name"`
+	ClientName string `json:"client_name"`
+	DeviceName string `json:"device_name"`
+	ErrorText  string `json:"error_text"`
+	OccurredAt int64  `json:"occurred_at"`
+}
+
+// Errors returns playback errors over the last N days (default 30) grouped by item and client
+func Errors(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 30)
+		if days <= 0 || days > 3650 {
+			days = 30
+		}
+		limit := parseQueryInt(c, "limit", 25)
+		if limit <= 0 || limit > 500 {
+			limit = 25
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		since := time.Now().AddDate(0, 0, -days).Unix()
+
+		where, sargs := appendServerFilter("pe.occurred_at >= ?", "pe", serverType, serverID)
+		args := append([]any{since}, sargs...)
+
+		out := ErrorSummary{Days: days, ByItem: []ItemErrorStat{}, ByClient: []ClientErrorStat{}, Recent: []PlaybackError{}}
+
+		if err := db.QueryRow(`SELECT COUNT(*) FROM playback_errors pe WHERE `+where, args...).Scan(&out.TotalErrors); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		rows, err := db.Query(`
+			SELECT COALESCE(pe.item_id, ''),
+			       COALESCE(MAX(li.name), MAX(pe.item_name), ''),
+			       COALESCE(MAX(pe.item_type), ''),
+			       COALESCE(MAX(li.file_path), ''),
+			       COUNT(*),
+			       COUNT(DISTINCT pe.user_id),
+			       COUNT(DISTINCT pe.client_name),
+			       MAX(pe.occurred_at)
+			FROM playback_errors pe
+			LEFT JOIN library_item li ON li.id = pe.item_id
+			WHERE `+where+`
+			GROUP BY pe.item_id
+			ORDER BY COUNT(*) DESC, MAX(pe.occurred_at) DESC
+			LIMIT ?`, append(args, limit)...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		for rows.Next() {
+			var s ItemErrorStat
+			if err := rows.Scan(&s.ItemID, &s.ItemName, &s.ItemType, &s.FilePath, &s.Errors, &s.Users, &s.Clients, &s.LastSeen); err == nil {
+				out.ByItem = append(out.ByItem, s)
+			}
+		}
+		rows.Close()
+
+		// Most recent error text per item
+		for i := range out.ByItem {
+			_ = db.QueryRow(`
+				SELECT COALESCE(error_text, '') FROM playback_errors
+				WHERE COALESCE(item_id, '') = ? AND occurred_at >= ?
+				ORDER BY occurred_at DESC LIMIT 1`, out.ByItem[i].ItemID, since).Scan(&out.ByItem[i].LastError)
+		}
+
+		rows, err = db.Query(`
+			SELECT COALESCE(NULLIF(pe.client_name, ''), 'Unknown'),
+			       COUNT(*),
+			       COUNT(DISTINCT pe.item_id),
+			       COUNT(DISTINCT pe.user_id),
+			       MAX(pe.occurred_at)
+			FROM playback_errors pe
+			WHERE `+where+`
+			GROUP BY COALESCE(NULLIF(pe.client_name, ''), 'Unknown')
+			ORDER BY COUNT(*) DESC
+			LIMIT ?`, append(args, limit)...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		for rows.Next() {
+			var s ClientErrorStat
+			if err := rows.Scan(&s.ClientName, &s.Errors, &s.Items, &s.Users, &s.LastSeen); err == nil {
+				out.ByClient = append(out.ByClient, s)
+			}
+		}
+		rows.Close()
+
+		rows, err = db.Query(`
+			SELECT pe.server_id, COALESCE(pe.item_id, ''), COALESCE(pe.item_name, ''), COALESCE(pe.user_name, ''),
+			       COALESCE(pe.client_name, ''), COALESCE(pe.device_name, ''), COALESCE(pe.error_text, ''), pe.occurred_at
+			FROM playback_errors pe
+			WHERE `+where+`
+			ORDER BY pe.occurred_at DESC
+			LIMIT ?`, append(args, limit)...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var e PlaybackError
+			if err := rows.Scan(&e.ServerID, &e.ItemID, &e.ItemName, &e.UserName, &e.ClientName, &e.DeviceName, &e.ErrorText, &e.OccurredAt); err == nil {
+				out.Recent = append(out.Recent, e)
+			}
+		}
+
+		return c.JSON(out)
+	}
+}
@@ -0,0 +1,204 @@
+package stats
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/middleware"
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GoalWithProgress is a goal plus its progress in the current period; progress is omitted
+// while the app user isn't linked to a media user.
+type GoalWithProgress struct {
+	queries.UserGoal
+	Progress *queries.GoalProgress `json:"progress,omitempty"`
+}
+
+// MyGoals is the response of GET /stats/me/goals.
+type MyGoals struct {
+	MediaUserID string             `json:"media_user_id"`
+	Linked      bool               `json:"linked"`
+	Goals       []GoalWithProgress `json:"goals"`
+}
+
+type goalRequest struct {
+	Kind        string   `json:"kind"`
+	Period      string   `json:"period"`
+	TargetHours *float64 `json:"target_hours"`
+	SeriesID    string   `json:"series_id"`
+	Title       string   `json:"title"`
+	Notify      bool     `json:"notify"`
+}
+
+// MyGoalsHandler lists the signed-in user's goals with progress computed from the watch
+// history of their linked media user.
+func MyGoalsHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		goals, err := queries.ListUserGoals(c, db, userID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		out := MyGoals{MediaUserID: linkedMediaUser(db, userID), Goals: make([]GoalWithProgress, 0, len(goals))}
+		out.Linked = out.MediaUserID != ""
+		now := time.Now()
+		for _, g := range goals {
+			item := GoalWithProgress{UserGoal: g}
+			if out.Linked {
+				if item.Progress, err = queries.GetGoalProgress(c, db, g, out.MediaUserID, now); err != nil {
+					return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+				}
+			}
+			out.Goals = append(out.Goals, item)
+		}
+		return c.JSON(out)
+	}
+}
+
+// CreateGoalHandler adds a goal for the signed-in user.
+func CreateGoalHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		req, errMsg := parseGoalRequest(c, db)
+		if errMsg != "" {
+			return c.Status(400).JSON(fiber.Map{"error": errMsg})
+		}
+		now := time.Now().Unix()
+		res, err := db.Exec(`
+			INSERT INTO user_goals (user_id, kind, period, target_hours, series_id, title, notify, created_at, updated_at)
+			VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?)`,
+			userID, req.Kind, req.Period, req.TargetHours, req.SeriesID, req.Title, req.Notify, now, now)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, _ := res.LastInsertId()
+		g, err := queries.GetUserGoal(c, db, id, userID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusCreated).JSON(g)
+	}
+}
+
+// UpdateGoalHandler replaces a goal owned by the signed-in user.
+func UpdateGoalHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid goal id"})
+		}
+		req, errMsg := parseGoalRequest(c, db)
+		if errMsg != "" {
+			return c.Status(400).JSON(fiber.Map{"error": errMsg})
+		}
+		res, err := db.Exec(`
+			UPDATE user_goals SET kind = ?, period = ?, target_hours = ?, series_id = NULLIF(?, ''), title = ?, notify = ?, updated_at = ?
+			WHERE id = ? AND user_id = ?`,
+			req.Kind, req.Period, req.TargetHours, req.SeriesID, req.Title, req.Notify, time.Now().Unix(), id, userID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return c.Status(404).JSON(fiber.Map{"error": "goal not found"})
+		}
+		g, err := queries.GetUserGoal(c, db, id, userID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(g)
+	}
+}
+
+// DeleteGoalHandler removes a goal owned by the signed-in user.
+func DeleteGoalHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid goal id"})
+		}
+		res, err := db.Exec(`DELETE FROM user_goals WHERE id = ? AND user_id = ?`, id, userID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return c.Status(404).JSON(fiber.Map{"error": "goal not found"})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	}
+}
+
+func linkedMediaUser(db *sql.DB, appUserID int64) string {
+	var id sql.NullString
+	_ = db.QueryRow(`SELECT media_user_id FROM app_user WHERE id = ?`, appUserID).Scan(&id)
+	return strings.TrimSpace(id.String)
+}
+
+// parseGoalRequest validates a goal body and fills in the period and a default title.
+func parseGoalRequest(c fiber.Ctx, db *sql.DB) (*goalRequest, string) {
+	var req goalRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return nil, "invalid request body"
+	}
+	req.Kind = strings.ToLower(strings.TrimSpace(req.Kind))
+	req.Period = strings.ToLower(strings.TrimSpace(req.Period))
+	req.SeriesID = strings.TrimSpace(req.SeriesID)
+	req.Title = strings.TrimSpace(req.Title)
+	switch req.Period {
+	case "":
+		req.Period = "week"
+	case "week", "month":
+	default:
+		return nil, "period must be week or month"
+	}
+
+	switch req.Kind {
+	case queries.GoalMaxHours, queries.GoalMinHours:
+		if req.TargetHours == nil || *req.TargetHours <= 0 {
+			return nil, "target_hours must be greater than 0"
+		}
+		req.SeriesID = ""
+		if req.Title == "" {
+			bound := "At least"
+			if req.Kind == queries.GoalMaxHours {
+				bound = "Less than"
+			}
+			req.Title = fmt.Sprintf("%s %sh per %s", bound, strconv.FormatFloat(*req.TargetHours, 'f', -1, 64), req.Period)
+		}
+	case queries.GoalFinishSeries:
+		if req.SeriesID == "" {
+			return nil, "series_id is required"
+		}
+		var name sql.NullString
+		var episodes int
+		if err := db.QueryRow(`SELECT MAX(series_name), COUNT(*) FROM library_item WHERE series_id = ?`, req.SeriesID).Scan(&name, &episodes); err != nil || episodes == 0 {
+			return nil, "unknown series_id"
+		}
+		req.TargetHours = nil
+		if req.Title == "" {
+			req.Title = fmt.Sprintf("Finish %s this %s", strings.TrimSpace(name.String), req.Period)
+		}
+	default:
+		return nil, "kind must be max_hours, min_hours or finish_series"
+	}
+	return &req, ""
+}
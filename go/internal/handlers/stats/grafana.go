@@ -0,0 +1,143 @@
+package stats
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// maxGrafanaRange caps how much history one query may scan.
+const maxGrafanaRange = 3650 * 24 * time.Hour
+
+type grafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target  string `json:"target"`
+		RefID   string `json:"refId"`
+		Payload struct {
+			Server string `json:"server"`
+		} `json:"payload"`
+	} `json:"targets"`
+}
+
+// GrafanaSeries is one series of a JSON datasource response; datapoints are
+// [value, unix milliseconds] pairs.
+type GrafanaSeries struct {
+	Target     string       `json:"target"`
+	RefID      string       `json:"refId,omitempty"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// GrafanaPoint is one row of the Infinity-style GET /grafana/query response.
+type GrafanaPoint struct {
+	Time  int64   `json:"time"` // unix milliseconds
+	Value float64 `json:"value"`
+}
+
+// grafanaWindow clamps a query range to maxGrafanaRange, defaulting to the last 30 days.
+func grafanaWindow(from, to time.Time) (int64, int64) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() || !from.Before(to) {
+		from = to.AddDate(0, 0, -30)
+	}
+	if to.Sub(from) > maxGrafanaRange {
+		from = to.Add(-maxGrafanaRange)
+	}
+	return from.Unix(), to.Unix()
+}
+
+// GET /grafana - Connection test for the Grafana JSON datasource.
+func GrafanaTestHandler() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return c.SendString("OK")
+	}
+}
+
+// POST /grafana/search {"target": "watch"} - The series names containing target.
+func GrafanaSearchHandler() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req struct {
+			Target string `json:"target"`
+		}
+		_ = c.Bind().Body(&req) // an empty body lists everything
+		needle := strings.ToLower(strings.TrimSpace(req.Target))
+		out := []string{}
+		for _, name := range queries.DailySeriesNames {
+			if strings.Contains(name, needle) {
+				out = append(out, name)
+			}
+		}
+		return c.JSON(out)
+	}
+}
+
+// POST /grafana/query - Daily series for a SimpleJSON/JSON datasource query: each target
+// names a series from /grafana/search and may limit it with {"payload": {"server": "plex"}}.
+// Points are UTC days in the requested range.
+func GrafanaQueryHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req grafanaQueryRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		from, to := grafanaWindow(req.Range.From, req.Range.To)
+		out := make([]GrafanaSeries, 0, len(req.Targets))
+		for _, t := range req.Targets {
+			if t.Target == "" {
+				continue
+			}
+			serverType, serverID := normalizeServerParam(t.Payload.Server)
+			points, err := queries.DailySeries(c, db, t.Target, from, to, serverType, serverID)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+			}
+			s := GrafanaSeries{Target: t.Target, RefID: t.RefID, Datapoints: make([][2]float64, len(points))}
+			for i, p := range points {
+				s.Datapoints[i] = [2]float64{p.Value, float64(p.Day * 1000)}
+			}
+			out = append(out, s)
+		}
+		return c.JSON(out)
+	}
+}
+
+// GET /grafana/query?target=watch_hours&from=<ms>&to=<ms>&server= - One daily series as
+// rows of {time, value} for the Infinity datasource (pass ${__from} and ${__to}).
+func GrafanaSeriesHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		target := c.Query("target")
+		if target == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "target is required"})
+		}
+		from, to := grafanaWindow(queryMillis(c, "from"), queryMillis(c, "to"))
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		points, err := queries.DailySeries(c, db, target, from, to, serverType, serverID)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		out := make([]GrafanaPoint, len(points))
+		for i, p := range points {
+			out[i] = GrafanaPoint{Time: p.Day * 1000, Value: p.Value}
+		}
+		return c.JSON(out)
+	}
+}
+
+// queryMillis parses a unix millisecond query parameter; a missing or invalid one is zero.
+func queryMillis(c fiber.Ctx, key string) time.Time {
+	ms, err := strconv.ParseInt(c.Query(key), 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
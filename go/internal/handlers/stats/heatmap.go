@@ -0,0 +1,49 @@
+package stats
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GET /stats/heatmap?days=30&tz=&user_id=&server=
+// Watch time over the last `days` (1-366) local days in ?tz= (IANA name, default server
+// local) as a 7×24 matrix of seconds, weekday (Sunday first) by hour of the day, for a
+// "when do people watch" heatmap. ?user_id= narrows it to one user; users hidden from the
+// viewer can't be picked out that way.
+func HeatmapHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		loc := time.Local
+		if tz := strings.TrimSpace(c.Query("tz")); tz != "" {
+			l, err := time.LoadLocation(tz)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "unknown timezone: " + tz})
+			}
+			loc = l
+		}
+		days := parseQueryInt(c, "days", 30)
+		if days < 1 || days > 366 {
+			days = 30
+		}
+		userID := strings.TrimSpace(c.Query("user_id", ""))
+		if userID != "" && viewerMask(c, db).hides(userID) {
+			return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+
+		heat, err := queries.WatchHeatmapStats(c, db, days, loc, time.Now(), userID, serverType, serverID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{
+			"days":     days,
+			"timezone": loc.String(),
+			"user_id":  userID,
+			"heatmap":  heat,
+		})
+	}
+}
@@ -68,6 +68,14 @@ func serverPredicate(alias string, serverType, serverID string) (string, []inter
 	return fmt.Sprintf("%s = ?", column("server_id")), []interface{}{serverID}
 }
 
+// serverMatches applies the server filter to a row already loaded from rowType/rowID.
+func serverMatches(serverType, serverID, rowType, rowID string) bool {
+	if serverType != "" && strings.ToLower(strings.TrimSpace(rowType)) != serverType {
+		return false
+	}
+	return serverID == "" || strings.EqualFold(strings.TrimSpace(rowID), serverID)
+}
+
 func appendServerFilter(baseCondition, alias, serverType, serverID string) (string, []interface{}) {
 	predicate, args := serverPredicate(alias, serverType, serverID)
 	if predicate == "" {
@@ -113,3 +121,22 @@ func normalizedFilePathExpr(alias string) string {
 		normalizedCol, normalizedCol, normalizedCol,
 		col)
 }
+
+// libraryTitleKeyExpr returns SQL expression identifying a library item's title across servers:
+// its canonical_item group when it has copies matched by provider id, else its normalized
+// file path, else its id
+func libraryTitleKeyExpr(alias string) string {
+	table := alias
+	if table == "" {
+		table = "library_item"
+	}
+	return fmt.Sprintf(`COALESCE(
+		(SELECT 'canonical:' || ci.canonical_id FROM canonical_item ci WHERE ci.item_id = %[1]s.id),
+		CASE WHEN %[1]s.file_path IS NOT NULL AND TRIM(%[1]s.file_path) != '' THEN 'path:' || (%[2]s) END,
+		'id:' || %[1]s.id
+	)`, table, normalizedFilePathExpr(alias))
+}
+
+// hasTitleKeySQL limits library_item to items with a path or a canonical_item group, the ones
+// libraryTitleKeyExpr can match across servers
+const hasTitleKeySQL = `((file_path IS NOT NULL AND file_path != '') OR id IN (SELECT item_id FROM canonical_item))`
@@ -2,6 +2,7 @@ package stats
 
 import (
 	"database/sql"
+	"strings"
 
 	"github.com/gofiber/fiber/v3"
 )
@@ -43,6 +44,7 @@ func ItemsByCodec(db *sql.DB) fiber.Handler {
 			pageSize = 50
 		}
 		mediaType := c.Query("media_type", "")
+		tag := strings.TrimSpace(c.Query("tag", ""))
 
 		// Build query conditions
 		whereClause := "WHERE COALESCE(li.video_codec, 'Unknown') = ?"
@@ -52,6 +54,10 @@ func ItemsByCodec(db *sql.DB) fiber.Handler {
 			whereClause += " AND COALESCE(li.media_type, 'Unknown') = ?"
 			args = append(args, mediaType)
 		}
+		if tag != "" {
+			whereClause += " AND " + tagPredicate("li.id")
+			args = append(args, tag)
+		}
 
 		// Exclude live/TV channel types
 		whereClause += " AND COALESCE(li.media_type, 'Unknown') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')"
@@ -45,6 +45,7 @@ func ItemsByGenre(db *sql.DB) fiber.Handler {
 			pageSize = 50
 		}
 		mediaType := c.Query("media_type", "")
+		tag := strings.TrimSpace(c.Query("tag", ""))
 
 		// Match any genre token (case‑insensitive, token boundary)
 		where := "WHERE genres IS NOT NULL AND genres != '' AND INSTR(LOWER(',' || REPLACE(genres, ', ', ',') || ','), LOWER(',' || ? || ',')) > 0"
@@ -54,6 +55,10 @@ func ItemsByGenre(db *sql.DB) fiber.Handler {
 			where += " AND COALESCE(media_type, 'Unknown') = ?"
 			args = append(args, mediaType)
 		}
+		if tag != "" {
+			where += " AND " + tagPredicate("library_item.id")
+			args = append(args, tag)
+		}
 
 		// Exclude live TV-ish items
 		where += " AND COALESCE(media_type, 'Unknown') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')"
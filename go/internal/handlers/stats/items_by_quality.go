@@ -3,6 +3,7 @@ package stats
 import (
 	"database/sql"
 	"net/url"
+	"strings"
 
 	"emby-analytics/internal/logging"
 	"github.com/gofiber/fiber/v3"
@@ -45,6 +46,7 @@ func ItemsByQuality(db *sql.DB) fiber.Handler {
 			pageSize = 50
 		}
 		mediaType := c.Query("media_type", "")
+		tag := strings.TrimSpace(c.Query("tag", ""))
 
 		// Build quality-based WHERE clause
 		var whereClause string
@@ -69,7 +71,7 @@ func ItemsByQuality(db *sql.DB) fiber.Handler {
 			heightRange = "1p-719p"
 		case "Unknown", "Resolution Not Available":
 			// Handle both legacy "Unknown" and current "Resolution Not Available"
-			whereClause = "WHERE li.height IS NULL OR li.height = 0"
+			whereClause = "WHERE (li.height IS NULL OR li.height = 0)"
 			heightRange = "No height data"
 		default:
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid quality parameter. Must be: 8K, 4K, 1080p, 720p, SD, Unknown, or Resolution Not Available"})
@@ -80,6 +82,10 @@ func ItemsByQuality(db *sql.DB) fiber.Handler {
 			whereClause += " AND COALESCE(li.media_type, 'Unknown') = ?"
 			args = append(args, mediaType)
 		}
+		if tag != "" {
+			whereClause += " AND " + tagPredicate("li.id")
+			args = append(args, tag)
+		}
 
 		// Exclude live/TV channel types
 		whereClause += " AND COALESCE(li.media_type, 'Unknown') NOT IN ('TvChannel', 'LiveTv', 'Channel')"
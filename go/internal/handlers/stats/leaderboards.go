@@ -0,0 +1,182 @@
+package stats
+
+import (
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/queries"
+	"emby-analytics/internal/tasks"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Default badge thresholds (hours watched within the period) per leaderboard period.
+var defaultBadgeHours = map[string][]int{
+	"week":  {10, 25, 50},
+	"month": {25, 50, 100},
+	"year":  {100, 250, 500},
+}
+
+type LeaderboardEntry struct {
+	Rank         int      `json:"rank"`
+	UserID       string   `json:"user_id"`
+	Name         string   `json:"name"`
+	ServerID     string   `json:"server_id"`
+	ServerName   string   `json:"server_name"`
+	Hours        float64  `json:"hours"`
+	PreviousRank *int     `json:"previous_rank"` // nil when the user was unranked last period
+	Movement     int      `json:"movement"`      // positive = climbed, negative = dropped
+	IsNew        bool     `json:"is_new"`
+	Badges       []string `json:"badges"`
+}
+
+type Leaderboard struct {
+	Period        string             `json:"period"`
+	PeriodStart   int64              `json:"period_start"`
+	PeriodEnd     int64              `json:"period_end"`
+	PreviousStart int64              `json:"previous_start"`
+	PreviousEnd   int64              `json:"previous_end"`
+	BadgeHours    []int              `json:"badge_hours"`
+	Entries       []LeaderboardEntry `json:"entries"`
+}
+
+// leaderboardWindows returns the current (in-progress) calendar period and the
+// previous full period in UTC. Weeks start on Monday.
+func leaderboardWindows(period string, now time.Time) (curStart, prevStart, prevEnd time.Time) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case "month":
+		curStart = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		prevStart = curStart.AddDate(0, -1, 0)
+	case "year":
+		curStart = time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		prevStart = curStart.AddDate(-1, 0, 0)
+	default: // week
+		offset := (int(day.Weekday()) + 6) % 7
+		curStart = day.AddDate(0, 0, -offset)
+		prevStart = curStart.AddDate(0, 0, -7)
+	}
+	return curStart, prevStart, curStart.Add(-time.Second)
+}
+
+func parseBadgeHours(raw string) []int {
+	var out []int
+	for _, part := range strings.Split(raw, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && n > 0 {
+			out = append(out, n)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Leaderboards returns users ranked by watch time for the current week, month or
+// year, with rank movement against the previous period and earned badges.
+// Query params: period (week|month|year), limit, badges (comma-separated hour thresholds).
+func Leaderboards(db *sql.DB, mgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		period := strings.ToLower(c.Query("period", "week"))
+		if _, ok := defaultBadgeHours[period]; !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "period must be one of week, month, year"})
+		}
+		limit := parseQueryInt(c, "limit", 10)
+		if limit <= 0 || limit > 100 {
+			limit = 10
+		}
+		badgeHours := defaultBadgeHours[period]
+		if raw := c.Query("badges", ""); raw != "" {
+			if parsed := parseBadgeHours(raw); len(parsed) > 0 {
+				badgeHours = parsed
+			}
+		}
+
+		now := time.Now().UTC()
+		curStart, prevStart, prevEnd := leaderboardWindows(period, now)
+
+		current, err := queries.TopUsersByWatchSeconds(c, db, curStart.Unix(), now.Unix(), "", "", 1000)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		previous, err := queries.TopUsersByWatchSeconds(c, db, prevStart.Unix(), prevEnd.Unix(), "", "", 1000)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		// Merge in-progress sessions so the current period is live-aware
+		byUser := make(map[string]int, len(current))
+		for i, row := range current {
+			byUser[row.UserID] = i
+		}
+		for userID, seconds := range tasks.GetLiveUserWatchTimesExcludingLiveTV() {
+			if i, ok := byUser[userID]; ok {
+				current[i].Hours += seconds / 3600.0
+				continue
+			}
+			row := queries.TopUserRow{UserID: userID, Hours: seconds / 3600.0}
+			if err := db.QueryRow(`SELECT name, COALESCE(server_id, '') FROM emby_user WHERE id = ? AND deleted_at IS NULL AND exclude_from_stats = 0`, userID).Scan(&row.Name, &row.ServerID); err != nil {
+				continue
+			}
+			byUser[userID] = len(current)
+			current = append(current, row)
+		}
+		sort.SliceStable(current, func(i, j int) bool { return current[i].Hours > current[j].Hours })
+
+		prevRanks := make(map[string]int, len(previous))
+		for i, row := range previous {
+			prevRanks[row.UserID] = i + 1
+		}
+
+		configs := mgr.GetServerConfigs()
+		mask := viewerMask(c, db)
+		entries := make([]LeaderboardEntry, 0, limit)
+		for i, row := range current {
+			if i >= limit {
+				break
+			}
+			e := LeaderboardEntry{
+				Rank:       i + 1,
+				UserID:     row.UserID,
+				Name:       row.Name,
+				ServerID:   row.ServerID,
+				ServerName: row.ServerID,
+				Hours:      row.Hours,
+				Badges:     []string{},
+			}
+			if cfg, ok := configs[row.ServerID]; ok {
+				e.ServerName = cfg.Name
+			}
+			if mask.hides(row.UserID) {
+				e.UserID = ""
+				e.Name = queries.AnonymousName
+			}
+			if prev, ok := prevRanks[row.UserID]; ok {
+				p := prev
+				e.PreviousRank = &p
+				e.Movement = prev - e.Rank
+			} else {
+				e.IsNew = true
+			}
+			for _, h := range badgeHours {
+				if row.Hours >= float64(h) {
+					e.Badges = append(e.Badges, strconv.Itoa(h)+"h club")
+				}
+			}
+			entries = append(entries, e)
+		}
+
+		return c.JSON(Leaderboard{
+			Period:        period,
+			PeriodStart:   curStart.Unix(),
+			PeriodEnd:     now.Unix(),
+			PreviousStart: prevStart.Unix(),
+			PreviousEnd:   prevEnd.Unix(),
+			BadgeHours:    badgeHours,
+			Entries:       entries,
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package stats
+
+import (
+	"database/sql"
+	"strings"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GET /stats/library/compare?server_a=&server_b=&media_type=Movie|Episode&limit=200
+// Movies and episodes one server has and the other lacks, matched by imdb/tmdb/tvdb id or
+// by normalized title and year, plus titles both have in different resolutions. Meant for
+// keeping a backup server in step with the primary library.
+func LibraryCompareHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		serverA := strings.TrimSpace(c.Query("server_a"))
+		serverB := strings.TrimSpace(c.Query("server_b"))
+		if serverA == "" || serverB == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "server_a and server_b are required"})
+		}
+		if serverA == serverB {
+			return c.Status(400).JSON(fiber.Map{"error": "server_a and server_b must differ"})
+		}
+		mediaType := ""
+		switch strings.ToLower(strings.TrimSpace(c.Query("media_type"))) {
+		case "":
+		case "movie", "movies":
+			mediaType = "Movie"
+		case "episode", "episodes":
+			mediaType = "Episode"
+		default:
+			return c.Status(400).JSON(fiber.Map{"error": "media_type must be Movie or Episode"})
+		}
+		limit := parseQueryInt(c, "limit", 200)
+		if limit < 1 || limit > 5000 {
+			limit = 200
+		}
+
+		cmp, err := queries.CompareLibraries(c, db, serverA, serverB, mediaType, limit)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		resp := fiber.Map{"comparison": cmp}
+		if mgr := getMultiServerManager(); mgr != nil {
+			configs := mgr.GetServerConfigs()
+			resp["server_a_name"] = configs[serverA].Name
+			resp["server_b_name"] = configs[serverB].Name
+		}
+		return c.JSON(resp)
+	}
+}
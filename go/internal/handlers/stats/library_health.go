@@ -0,0 +1,46 @@
+package stats
+
+import (
+	"database/sql"
+	"time"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GET /stats/library/health?days=365&server=
+// A 0-100 health score per server and library (movies, tv) combining the share of items
+// not watched in the last `days` (0 for ever), the share of duplicate copies, the share in
+// modern video codecs and the share with a bitrate suited to their resolution. Each
+// library lists the contributing factors with their weights.
+func LibraryHealthHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 365)
+		if days < 0 || days > 3650 {
+			days = 365
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+
+		var since int64
+		if days > 0 {
+			since = time.Now().AddDate(0, 0, -days).Unix()
+		}
+		libs, err := queries.LibraryHealthStats(c, db, serverType, serverID, since)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if mgr := getMultiServerManager(); mgr != nil {
+			configs := mgr.GetServerConfigs()
+			for i := range libs {
+				if cfg, ok := configs[libs[i].ServerID]; ok {
+					libs[i].ServerName = cfg.Name
+				}
+			}
+		}
+		return c.JSON(fiber.Map{
+			"days":      days,
+			"libraries": libs,
+		})
+	}
+}
@@ -49,15 +49,15 @@ func Movies(db *sql.DB) fiber.Handler {
 		movieAliasBase := "(" + movieMediaPredicate("li") + ") AND " + excludeLiveTvFilterAlias("li")
 		movieAliasWhere, movieAliasArgs := appendServerFilter(movieAliasBase, "li", serverType, serverID)
 
-		// Count total movies (deduplicated by file_path for All Servers, item_id for single server)
+		// Count total movies (deduplicated by title for All Servers, item_id for single server)
 		var countQuery string
 		if serverType == "" && serverID == "" {
-			// All Servers: deduplicate by file_path
+			// All Servers: deduplicate by provider id match or file_path
 			countQuery = fmt.Sprintf(`
 				SELECT COUNT(DISTINCT %s)
 				FROM library_item
-				WHERE %s AND file_path IS NOT NULL AND file_path != ''`,
-				normalizedFilePathExpr(""), movieWhere)
+				WHERE %s AND %s`,
+				libraryTitleKeyExpr(""), movieWhere, hasTitleKeySQL)
 		} else {
 			// Single server: use item_id
 			countQuery = fmt.Sprintf(`
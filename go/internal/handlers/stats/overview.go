@@ -2,86 +2,200 @@ package stats
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"emby-analytics/internal/handlers/admin"
+	"emby-analytics/internal/media"
 
 	"github.com/gofiber/fiber/v3"
 )
 
 type OverviewData struct {
-	TotalUsers  int `json:"total_users"`
-	TotalItems  int `json:"total_items"`
-	TotalPlays  int `json:"total_plays"`
-	UniquePlays int `json:"unique_plays"`
+	TotalUsers  int     `json:"total_users"`
+	TotalItems  int     `json:"total_items"`
+	TotalPlays  int     `json:"total_plays"`
+	UniquePlays int     `json:"unique_plays"`
+	WatchHours  float64 `json:"watch_hours"`
+	// Servers breaks the totals down per media server; unlike TotalItems, a title on
+	// several servers counts on each
+	Servers []ServerOverview `json:"servers"`
+}
+
+// ServerOverview is one media server's share of the overview.
+type ServerOverview struct {
+	ServerID   string  `json:"server_id"`
+	ServerName string  `json:"server_name"`
+	ServerType string  `json:"server_type"`
+	Users      int     `json:"users"`
+	Items      int     `json:"items"`
+	Sessions   int     `json:"sessions"`
+	WatchHours float64 `json:"watch_hours"`
 }
 
 func Overview(db *sql.DB) fiber.Handler {
 	return func(c fiber.Ctx) error {
-		start := time.Now()
-		data := OverviewData{}
-
-		// Count users (exclude soft-deleted users)
-		err := db.QueryRow(`SELECT COUNT(*) FROM emby_user WHERE deleted_at IS NULL`).Scan(&data.TotalUsers)
+		data, err := overviewData(db, getMultiServerManager())
 		if err != nil {
-			log.Printf("[overview] Error counting users: %v", err)
-			return c.Status(500).JSON(fiber.Map{"error": "Failed to count users"})
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 		}
+		return c.JSON(data)
+	}
+}
 
-		// Count unique library items using normalized paths and including pathless items
-		// Uses the same normalization as other stats endpoints for consistency
-		normalizedPath := normalizedFilePathExpr("")
-		query := fmt.Sprintf(`
-			SELECT COUNT(*) FROM (
-				-- Items with file paths: dedupe by normalized path
-				SELECT DISTINCT 'path:' || (%s) AS dedupe_key
-				FROM library_item
-				WHERE media_type NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
-					AND file_path IS NOT NULL
-					AND TRIM(file_path) != ''
-				UNION
-				-- Items without file paths: count by ID (no cross-server deduplication possible)
-				SELECT DISTINCT 'id:' || id AS dedupe_key
-				FROM library_item
-				WHERE media_type NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
-					AND (file_path IS NULL OR TRIM(file_path) = '')
-			)
-		`, normalizedPath)
-		
-		err = db.QueryRow(query).Scan(&data.TotalItems)
-		if err != nil {
-			log.Printf("[overview] Error counting library items: %v", err)
-			return c.Status(500).JSON(fiber.Map{"error": "Failed to count library items"})
-		}
+// overviewData computes the library and playback totals shown on the overview cards, with
+// a section per server; mgr names the configured servers and may be nil.
+func overviewData(db *sql.DB, mgr *media.MultiServerManager) (OverviewData, error) {
+	start := time.Now()
+	data := OverviewData{}
 
-		// Count total play sessions (exclude Live TV)
-		err = db.QueryRow(`SELECT COUNT(*) FROM play_sessions WHERE started_at IS NOT NULL AND COALESCE(item_type,'') NOT IN ('TvChannel','LiveTv','Channel','TvProgram')`).Scan(&data.TotalPlays)
-		if err != nil {
-			log.Printf("[overview] Error counting play sessions: %v", err)
-			return c.Status(500).JSON(fiber.Map{"error": "Failed to count play sessions"})
+	// Count users (exclude soft-deleted users)
+	err := db.QueryRow(`SELECT COUNT(*) FROM emby_user WHERE deleted_at IS NULL`).Scan(&data.TotalUsers)
+	if err != nil {
+		log.Printf("[overview] Error counting users: %v", err)
+		return data, errors.New("Failed to count users")
+	}
+
+	// Count unique library items: copies of a title on several servers, matched by provider
+	// id or normalized path, count once; pathless items without copies count by ID
+	query := fmt.Sprintf(`
+		SELECT COUNT(DISTINCT %s)
+		FROM library_item
+		WHERE media_type NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
+	`, libraryTitleKeyExpr(""))
+
+	err = db.QueryRow(query).Scan(&data.TotalItems)
+	if err != nil {
+		log.Printf("[overview] Error counting library items: %v", err)
+		return data, errors.New("Failed to count library items")
+	}
+
+	// Count total play sessions (exclude Live TV)
+	err = db.QueryRow(`SELECT COUNT(*) FROM play_sessions WHERE started_at IS NOT NULL AND COALESCE(item_type,'') NOT IN ('TvChannel','LiveTv','Channel','TvProgram')`).Scan(&data.TotalPlays)
+	if err != nil {
+		log.Printf("[overview] Error counting play sessions: %v", err)
+		return data, errors.New("Failed to count play sessions")
+	}
+
+	// Count unique items played (exclude Live TV)
+	err = db.QueryRow(`SELECT COUNT(DISTINCT item_id) FROM play_sessions WHERE started_at IS NOT NULL AND COALESCE(item_type,'') NOT IN ('TvChannel','LiveTv','Channel','TvProgram')`).Scan(&data.UniquePlays)
+	if err != nil {
+		log.Printf("[overview] Error counting unique plays: %v", err)
+		return data, errors.New("Failed to count unique plays")
+	}
+
+	data.Servers, err = serverOverviews(db, mgr)
+	if err != nil {
+		log.Printf("[overview] Error computing per-server totals: %v", err)
+		return data, errors.New("Failed to compute per-server totals")
+	}
+	for _, s := range data.Servers {
+		data.WatchHours += s.WatchHours
+	}
+
+	duration := time.Since(start)
+	isSlowQuery := duration > 1*time.Second
+	if isSlowQuery {
+		log.Printf("[overview] WARNING: Slow query took %v", duration)
+	}
+
+	// Track metrics
+	admin.IncrementQueryMetrics(duration, isSlowQuery)
+
+	log.Printf("[overview] Successfully fetched data in %v: users=%d, items=%d, plays=%d, unique=%d",
+		duration, data.TotalUsers, data.TotalItems, data.TotalPlays, data.UniquePlays)
+
+	return data, nil
+}
+
+// serverOverviews counts users, library items, sessions and watch hours per server_id. Every
+// configured server is listed, with zeros when it has no data yet, followed by servers that
+// only appear in the data (e.g. removed ones).
+func serverOverviews(db *sql.DB, mgr *media.MultiServerManager) ([]ServerOverview, error) {
+	byID := map[string]*ServerOverview{}
+	var order []string
+	get := func(id, serverType string) *ServerOverview {
+		if s, ok := byID[id]; ok {
+			if s.ServerType == "" {
+				s.ServerType = serverType
+			}
+			return s
+		}
+		s := &ServerOverview{ServerID: id, ServerName: id, ServerType: serverType}
+		byID[id] = s
+		order = append(order, id)
+		return s
+	}
+	if mgr != nil {
+		configs := mgr.GetServerConfigs()
+		ids := make([]string, 0, len(configs))
+		for id := range configs {
+			ids = append(ids, id)
 		}
+		sort.Slice(ids, func(i, j int) bool { return configs[ids[i]].Name < configs[ids[j]].Name })
+		for _, id := range ids {
+			s := get(id, string(configs[id].Type))
+			if configs[id].Name != "" {
+				s.ServerName = configs[id].Name
+			}
+		}
+	}
 
-		// Count unique items played (exclude Live TV)
-		err = db.QueryRow(`SELECT COUNT(DISTINCT item_id) FROM play_sessions WHERE started_at IS NOT NULL AND COALESCE(item_type,'') NOT IN ('TvChannel','LiveTv','Channel','TvProgram')`).Scan(&data.UniquePlays)
+	counts := []struct {
+		query string
+		set   func(s *ServerOverview, v float64)
+	}{
+		{`SELECT COALESCE(server_id, 'default-emby'), COALESCE(MAX(server_type), ''), COUNT(*)
+		  FROM emby_user WHERE deleted_at IS NULL
+		  GROUP BY 1`,
+			func(s *ServerOverview, v float64) { s.Users = int(v) }},
+		{`SELECT COALESCE(server_id, 'default-emby'), COALESCE(MAX(server_type), ''), COUNT(DISTINCT item_id)
+		  FROM library_item WHERE media_type NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
+		  GROUP BY 1`,
+			func(s *ServerOverview, v float64) { s.Items = int(v) }},
+		{`SELECT COALESCE(server_id, 'default-emby'), COALESCE(MAX(server_type), ''), COUNT(*)
+		  FROM play_sessions
+		  WHERE started_at IS NOT NULL AND COALESCE(item_type, '') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
+		  GROUP BY 1`,
+			func(s *ServerOverview, v float64) { s.Sessions = int(v) }},
+		// Capped at each interval's recorded duration so paused time is not counted
+		{`SELECT COALESCE(l.server_id, 'default-emby'), '',
+		         SUM(CASE WHEN l.duration_seconds IS NULL OR l.duration_seconds <= 0
+		                  THEN MAX(0, l.end_ts - l.start_ts)
+		                  ELSE MIN(l.duration_seconds, MAX(0, l.end_ts - l.start_ts))
+		             END) / 3600.0
+		  FROM play_intervals l
+		  LEFT JOIN library_item li ON li.id = l.item_id
+		  WHERE COALESCE(li.media_type, 'Unknown') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
+		  GROUP BY 1`,
+			func(s *ServerOverview, v float64) { s.WatchHours = v }},
+	}
+	for _, cnt := range counts {
+		rows, err := db.Query(cnt.query)
 		if err != nil {
-			log.Printf("[overview] Error counting unique plays: %v", err)
-			return c.Status(500).JSON(fiber.Map{"error": "Failed to count unique plays"})
+			return nil, err
 		}
-
-		duration := time.Since(start)
-		isSlowQuery := duration > 1*time.Second
-		if isSlowQuery {
-			log.Printf("[overview] WARNING: Slow query took %v", duration)
+		for rows.Next() {
+			var id, serverType string
+			var v float64
+			if err := rows.Scan(&id, &serverType, &v); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			cnt.set(get(id, serverType), v)
 		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
 
-		// Track metrics
-		admin.IncrementQueryMetrics(duration, isSlowQuery)
-
-		log.Printf("[overview] Successfully fetched data in %v: users=%d, items=%d, plays=%d, unique=%d",
-			duration, data.TotalUsers, data.TotalItems, data.TotalPlays, data.UniquePlays)
-
-		return c.JSON(data)
+	out := make([]ServerOverview, 0, len(order))
+	for _, id := range order {
+		out = append(out, *byID[id])
 	}
+	return out, nil
 }
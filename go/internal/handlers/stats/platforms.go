@@ -0,0 +1,138 @@
+package stats
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// unknownPlatform groups sessions without a recorded platform.
+const unknownPlatform = "Unknown"
+
+// ClientVersionStat counts sessions of one client app version.
+type ClientVersionStat struct {
+	Client   string `json:"client"`
+	Version  string `json:"version"`
+	Sessions int    `json:"sessions"`
+}
+
+// PlatformStat is the usage of one OS platform.
+type PlatformStat struct {
+	Platform   string              `json:"platform"`
+	Sessions   int                 `json:"sessions"`
+	Hours      float64             `json:"hours"`
+	Users      int                 `json:"users"`
+	Devices    int                 `json:"devices"`
+	Transcodes int                 `json:"transcodes"`
+	Clients    []string            `json:"clients"`
+	Versions   []ClientVersionStat `json:"versions"` // most used client versions first
+}
+
+// platformFilter returns an " AND ..." clause matching sessions of platform ("Unknown" matches
+// sessions without one); an empty platform matches everything.
+func platformFilter(alias, platform string) (string, []any) {
+	platform = strings.TrimSpace(platform)
+	if platform == "" {
+		return "", nil
+	}
+	col := "platform"
+	if alias != "" {
+		col = alias + ".platform"
+	}
+	if strings.EqualFold(platform, unknownPlatform) {
+		return " AND COALESCE(" + col + ", '') = ''", nil
+	}
+	return " AND LOWER(" + col + ") = LOWER(?)", []any{platform}
+}
+
+// GET /stats/platforms?days=30&server=&versions=5
+// Sessions, watch hours, users and devices per OS platform (e.g. "Android TV" vs "Android"),
+// with the clients and most used client versions seen on each.
+func Platforms(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 30)
+		if days <= 0 || days > 3650 {
+			days = 30
+		}
+		maxVersions := parseQueryInt(c, "versions", 5)
+		if maxVersions < 0 || maxVersions > 50 {
+			maxVersions = 5
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		since := time.Now().AddDate(0, 0, -days).Unix()
+
+		where, sargs := appendServerFilter("ps.started_at >= ?", "ps", serverType, serverID)
+		args := append([]any{since}, sargs...)
+
+		rows, err := db.Query(`
+			SELECT COALESCE(NULLIF(ps.platform, ''), '`+unknownPlatform+`') AS platform,
+			       COUNT(*),
+			       COALESCE(SUM(iv.seconds), 0) / 3600.0,
+			       COUNT(DISTINCT ps.user_id),
+			       COUNT(DISTINCT `+deviceKeyExpr+`),
+			       SUM(CASE WHEN ps.play_method = 'Transcode' OR LOWER(COALESCE(ps.video_method, '')) = 'transcode'
+			                     OR LOWER(COALESCE(ps.audio_method, '')) = 'transcode' THEN 1 ELSE 0 END)
+			FROM play_sessions ps
+			LEFT JOIN (
+				SELECT session_fk, SUM(duration_seconds) AS seconds FROM play_intervals GROUP BY session_fk
+			) iv ON iv.session_fk = ps.id
+			`+deviceAliasJoin+`
+			WHERE `+where+`
+			GROUP BY 1
+			ORDER BY 3 DESC, 2 DESC`, args...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		out := []PlatformStat{}
+		index := map[string]int{}
+		for rows.Next() {
+			p := PlatformStat{Clients: []string{}, Versions: []ClientVersionStat{}}
+			if err := rows.Scan(&p.Platform, &p.Sessions, &p.Hours, &p.Users, &p.Devices, &p.Transcodes); err != nil {
+				rows.Close()
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			index[p.Platform] = len(out)
+			out = append(out, p)
+		}
+		rows.Close()
+
+		rows, err = db.Query(`
+			SELECT COALESCE(NULLIF(ps.platform, ''), '`+unknownPlatform+`'),
+			       COALESCE(ps.client_name, ''), COALESCE(ps.client_version, ''), COUNT(*)
+			FROM play_sessions ps
+			WHERE `+where+` AND COALESCE(ps.client_name, '') != ''
+			GROUP BY 1, 2, 3
+			ORDER BY 4 DESC, 2, 3 DESC`, args...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+		seenClient := map[string]bool{}
+		for rows.Next() {
+			var platform string
+			var v ClientVersionStat
+			if err := rows.Scan(&platform, &v.Client, &v.Version, &v.Sessions); err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			i, ok := index[platform]
+			if !ok {
+				continue
+			}
+			if key := platform + "|" + v.Client; !seenClient[key] {
+				seenClient[key] = true
+				out[i].Clients = append(out[i].Clients, v.Client)
+			}
+			// Sessions recorded before versions were captured have none
+			if v.Version != "" && len(out[i].Versions) < maxVersions {
+				out[i].Versions = append(out[i].Versions, v)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"days": days, "platforms": out})
+	}
+}
@@ -44,6 +44,8 @@ type SessionDetail struct {
 	DeviceID          string `json:"device_id"`
 	DeviceName        string `json:"device_name"`
 	ClientName        string `json:"client_name"`
+	ClientVersion     string `json:"client_version,omitempty"`
+	Platform          string `json:"platform,omitempty"`
 	VideoMethod       string `json:"video_method"`
 	AudioMethod       string `json:"audio_method"`
 	SubtitleTranscode bool   `json:"subtitle_transcode"`
@@ -82,6 +84,10 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
 		showAll := c.Query("show_all", "false") == "true"
 		userFilter := c.Query("user_id", "")
 		mediaTypeFilter := c.Query("media_type", "")
+		platformParam := strings.TrimSpace(c.Query("platform", ""))
+		platformWhere, platformArgs := platformFilter("", platformParam)
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		serverWhere, serverArgs := appendServerFilter("1=1", "", serverType, serverID)
 
 		// Check if enhanced columns exist by checking table structure
 		var hasVideoMethod bool
@@ -123,19 +129,22 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
                         WHEN instr(lower(COALESCE(transcode_reasons,'')), 'audio') > 0 THEN 'Transcode'
                         ELSE 'DirectPlay'
                     END AS audio_method,
-                    play_method
+                    play_method,
+                    COALESCE(NULLIF(platform,''), 'Unknown') AS platform
                 FROM play_sessions
                 WHERE started_at >= (strftime('%s','now') - (? * 86400))
                     AND started_at IS NOT NULL
                     AND COALESCE(item_type,'') NOT IN ('TvChannel','LiveTv','Channel','TvProgram')
+                    AND ` + serverWhere + `
             )
             SELECT 
                 video_method,
                 audio_method,
                 CASE WHEN play_method = 'Transcode' OR video_method = 'Transcode' OR audio_method = 'Transcode' THEN 'Transcode' ELSE 'DirectPlay' END AS overall_method,
+                platform,
                 COUNT(*) AS cnt
             FROM derived
-            GROUP BY 1, 2, 3
+            GROUP BY 1, 2, 3, 4
         `
 
 		// Build session query with filters
@@ -144,7 +153,7 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
                 ps.item_name, 
                 ps.item_type, 
                 ps.device_id,
-                COALESCE(ps.device_id, 'Unknown Device') as device_name,
+                COALESCE(NULLIF(da.name, ''), ps.device_id, 'Unknown Device') as device_name,
                 ps.client_name, 
                 ps.item_id, 
                 ps.user_id,
@@ -154,6 +163,8 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
                 ps.session_id,
                 ps.play_method,
                 ps.server_type,
+                COALESCE(ps.client_version, ''),
+                COALESCE(ps.platform, ''),
                 -- Derive consistent methods for session details
                 CASE 
                     WHEN lower(COALESCE(ps.video_method,'')) = 'transcode' THEN 'Transcode'
@@ -181,6 +192,7 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
                 END AS subtitle_transcode
             FROM play_sessions ps
             LEFT JOIN emby_user eu ON ps.user_id = eu.id
+            LEFT JOIN device_aliases da ON da.device_id = ps.device_id
             WHERE ps.started_at >= (strftime('%s','now') - (? * 86400))
                 AND ps.started_at IS NOT NULL
                 AND COALESCE(ps.item_type,'') NOT IN ('TvChannel','LiveTv','Channel','TvProgram')`
@@ -189,6 +201,15 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
 		var queryParams []interface{}
 		queryParams = append(queryParams, days)
 
+		if pw, pargs := platformFilter("ps", platformParam); pw != "" {
+			sessionQueryBase += pw
+			queryParams = append(queryParams, pargs...)
+		}
+		if sw, sargs := appendServerFilter("", "ps", serverType, serverID); sw != "" {
+			sessionQueryBase += " AND " + sw
+			queryParams = append(queryParams, sargs...)
+		}
+
 		if !showAll {
 			// Only show transcoding sessions when show_all is false (backward compatibility)
 			sessionQueryBase += ` AND (
@@ -238,7 +259,7 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
         `
 		queryParams = append(queryParams, limit, offset)
 
-		rows, err := db.Query(query, days)
+		rows, err := db.Query(query, append([]any{days}, serverArgs...)...)
 		if err != nil {
 			logging.Debug("Enhanced query failed: %v", err)
 			return legacyPlayMethods(c, db, days, limit, offset)
@@ -261,26 +282,41 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
 			"TranscodeSubtitle": 0,
 		}
 
+		// Overall methods per platform, always across every platform
+		platforms := map[string]map[string]int{}
+
 		// Store session details for frontend
 		var sessionDetails []SessionDetail
 
 		// Process results with proper variable declarations
 		for rows.Next() {
-			var videoMethod, audioMethod, overallMethod string
+			var videoMethod, audioMethod, overallMethod, platform string
 			var cnt int
 
-			if err := rows.Scan(&videoMethod, &audioMethod, &overallMethod, &cnt); err != nil {
+			if err := rows.Scan(&videoMethod, &audioMethod, &overallMethod, &platform, &cnt); err != nil {
 				logging.Debug("Scan error: %v", err)
 				continue
 			}
 
+			if platforms[platform] == nil {
+				platforms[platform] = map[string]int{"DirectPlay": 0, "Transcode": 0}
+			}
+			if strings.EqualFold(overallMethod, "Transcode") {
+				platforms[platform]["Transcode"] += cnt
+			} else {
+				platforms[platform]["DirectPlay"] += cnt
+			}
+			if platformParam != "" && !strings.EqualFold(platform, platformParam) {
+				continue
+			}
+
 			// Normalize the methods to handle variations
 			normalizedVideo := normalize(videoMethod)
 			normalizedAudio := normalize(audioMethod)
 
 			// Create detailed key with normalized values
 			key := fmt.Sprintf("%s|%s", normalizedVideo, normalizedAudio)
-			methodBreakdown[key] = cnt
+			methodBreakdown[key] += cnt
 
 			// Update variables for categorization logic
 			videoMethod = normalizedVideo
@@ -323,7 +359,7 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
 					&session.ItemName, &session.ItemType, &session.DeviceID, &session.DeviceName,
 					&session.ClientName, &session.ItemID, &session.UserID, &session.UserName,
 					&session.StartedAt, &session.EndedAt, &session.SessionID, &session.PlayMethod,
-					&session.ServerType,
+					&session.ServerType, &session.ClientVersion, &session.Platform,
 					&session.VideoMethod, &session.AudioMethod, &subtitleTranscodeInt); err != nil {
 					logging.Debug("Session scan error: %v", err)
 					continue
@@ -341,10 +377,10 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
                 AND (
                     instr(lower(COALESCE(transcode_reasons,'')), 'subtitle') > 0 OR 
                     instr(lower(COALESCE(transcode_reasons,'')), 'burn') > 0
-                )
+                )` + platformWhere + ` AND ` + serverWhere + `
         `
 		var subtitleCount int
-		if err := db.QueryRow(subtitleQuery, days).Scan(&subtitleCount); err == nil {
+		if err := db.QueryRow(subtitleQuery, append(append([]any{days}, platformArgs...), serverArgs...)...).Scan(&subtitleCount); err == nil {
 			transcodeDetails["TranscodeSubtitle"] = subtitleCount
 		}
 
@@ -364,10 +400,10 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
                      AND lower(ps.video_codec_from) <> lower(ps.video_codec_to)) OR
                     (COALESCE(ps.audio_codec_from,'') <> '' AND COALESCE(ps.audio_codec_to,'') <> '' 
                      AND lower(ps.audio_codec_from) <> lower(ps.audio_codec_to))
-                )
+                )` + platformWhere + ` AND ` + serverWhere + `
         `
 		var directCount int
-		if err := db.QueryRow(directQuery, days).Scan(&directCount); err == nil {
+		if err := db.QueryRow(directQuery, append(append([]any{days}, platformArgs...), serverArgs...)...).Scan(&directCount); err == nil {
 			transcodeDetails["Direct"] = directCount
 		}
 
@@ -375,7 +411,7 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
 		sessionDetails = enrichSessionDetails(sessionDetails, em)
 
 		// Ensure we have the basic methods even if not in data
-		if summary["DirectPlay"] == 0 && summary["Transcode"] == 0 {
+		if summary["DirectPlay"] == 0 && summary["Transcode"] == 0 && platformParam == "" && serverType == "" && serverID == "" {
 			// If no data, try legacy mode as fallback
 			return legacyPlayMethods(c, db, days, limit, offset)
 		}
@@ -385,6 +421,7 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
 			"detailed":         methodBreakdown,
 			"transcodeDetails": transcodeDetails,
 			"sessionDetails":   sessionDetails,
+			"platforms":        platforms,
 			"days":             days,
 			"pagination": fiber.Map{
 				"limit":  limit,
@@ -397,6 +434,8 @@ func PlayMethods(db *sql.DB, em *emby.Client) fiber.Handler {
 
 // legacyPlayMethods provides the original functionality when new columns don't exist
 func legacyPlayMethods(c fiber.Ctx, db *sql.DB, days int, limit int, offset int) error {
+	serverType, serverID := normalizeServerParam(c.Query("server", ""))
+	serverWhere, serverArgs := appendServerFilter("1=1", "", serverType, serverID)
 	query := `
         SELECT
             COALESCE(play_method, '') AS raw_method,
@@ -405,10 +444,11 @@ func legacyPlayMethods(c fiber.Ctx, db *sql.DB, days int, limit int, offset int)
         WHERE started_at >= (strftime('%s','now') - (? * 86400))
             AND started_at IS NOT NULL
             AND COALESCE(item_type,'') NOT IN ('TvChannel','LiveTv','Channel','TvProgram')
+            AND ` + serverWhere + `
         GROUP BY raw_method
     `
 
-	rows, err := db.Query(query, days)
+	rows, err := db.Query(query, append([]any{days}, serverArgs...)...)
 	if err != nil {
 		logging.Debug("Legacy query failed: %v", err)
 		// Return empty data instead of error
@@ -0,0 +1,153 @@
+package stats
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/middleware"
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// MyPrivacy is the response of /stats/me/privacy.
+type MyPrivacy struct {
+	MediaUserID    string `json:"media_user_id"`
+	Linked         bool   `json:"linked"`
+	HideFromOthers bool   `json:"hide_from_others"`
+}
+
+// MyPrivacyHandler returns whether the signed-in user's linked media user is hidden from
+// other viewers.
+// GET /stats/me/privacy
+func MyPrivacyHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		out := MyPrivacy{MediaUserID: linkedMediaUser(db, userID)}
+		out.Linked = out.MediaUserID != ""
+		if out.Linked {
+			hidden, err := queries.GetUserHidden(c, db, out.MediaUserID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			out.HideFromOthers = hidden
+		}
+		return c.JSON(out)
+	}
+}
+
+// UpdateMyPrivacyHandler lets the signed-in user hide their linked media user from other
+// viewers: leaderboards, top users, usage, watch time, session history and now playing show
+// "Anonymous" instead of their name to everyone but admins, while their watch time still
+// counts in totals.
+// Body: {"hide_from_others": true}
+// PUT /stats/me/privacy
+func UpdateMyPrivacyHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		var req struct {
+			HideFromOthers bool `json:"hide_from_others"`
+		}
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		mediaUserID := linkedMediaUser(db, userID)
+		if mediaUserID == "" {
+			return c.Status(409).JSON(fiber.Map{"error": "account is not linked to a media user"})
+		}
+		if err := queries.SetUserHidden(c, db, mediaUserID, req.HideFromOthers); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.Status(404).JSON(fiber.Map{"error": "media user not found"})
+			}
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(MyPrivacy{MediaUserID: mediaUserID, Linked: true, HideFromOthers: req.HideFromOthers})
+	}
+}
+
+// userMask is the set of users whose names a viewer may not see.
+type userMask struct {
+	hidden map[string]bool
+	all    bool // the hidden users could not be loaded, so everyone is masked
+}
+
+func (m userMask) hides(userID string) bool { return m.all || m.hidden[userID] }
+
+func (m userMask) empty() bool { return !m.all && len(m.hidden) == 0 }
+
+// viewerMask returns the users hidden from the request's viewer; admins see everyone.
+func viewerMask(c fiber.Ctx, db *sql.DB) userMask {
+	v := middleware.CurrentViewer(c)
+	if v.Admin {
+		return userMask{}
+	}
+	hidden, err := queries.HiddenUsers(c, db, v.AppUserID)
+	if err != nil {
+		logging.Warn("failed to load hidden users; anonymizing all", "error", err)
+		return userMask{all: true}
+	}
+	return userMask{hidden: hidden}
+}
+
+// anonymizeTopUsers returns a copy of users with masked users renamed to Anonymous and their
+// IDs cleared, so their hours still count without revealing who they are. A merged person
+// is masked when any of their accounts is.
+func anonymizeTopUsers(users []TopUser, mask userMask) []TopUser {
+	if mask.empty() {
+		return users
+	}
+	out := make([]TopUser, len(users))
+	copy(out, users)
+	for i := range out {
+		if mask.hides(out[i].UserID) || slices.ContainsFunc(out[i].UserIDs, mask.hides) {
+			out[i].UserID = ""
+			out[i].Name = queries.AnonymousName
+			out[i].PersonID = ""
+			out[i].UserIDs = nil
+		}
+	}
+	return out
+}
+
+// anonymizeUsage returns a copy of rows with masked users renamed to Anonymous.
+func anonymizeUsage(rows []UsageRow, mask userMask) []UsageRow {
+	if mask.empty() {
+		return rows
+	}
+	out := make([]UsageRow, len(rows))
+	copy(out, rows)
+	for i := range out {
+		if mask.hides(out[i].userID) {
+			out[i].User = queries.AnonymousName
+		}
+	}
+	return out
+}
+
+// anonymizeDashboard masks the top users and usage cards; the cached dashboard is left
+// untouched.
+func anonymizeDashboard(d Dashboard, mask userMask) Dashboard {
+	if mask.empty() {
+		return d
+	}
+	cards := make(map[string]any, len(d.Cards))
+	for k, v := range d.Cards {
+		cards[k] = v
+	}
+	if users, ok := d.Cards["top_users"].([]TopUser); ok {
+		cards["top_users"] = anonymizeTopUsers(users, mask)
+	}
+	if rows, ok := d.Cards["usage"].([]UsageRow); ok {
+		cards["usage"] = anonymizeUsage(rows, mask)
+	}
+	d.Cards = cards
+	return d
+}
@@ -0,0 +1,87 @@
+package stats
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+
+	"emby-analytics/internal/notify"
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// PublicLink is a public id with the deep link and API path it resolves to.
+type PublicLink struct {
+	queries.PublicRef
+	Link    string `json:"link"`
+	APIPath string `json:"api_path,omitempty"`
+}
+
+// publicAPIPath is where an entity's data can be fetched.
+func publicAPIPath(kind, id string) string {
+	switch kind {
+	case queries.PublicKindSession:
+		return "/stats/sessions/" + url.PathEscape(id) + "/events"
+	case queries.PublicKindItem:
+		return "/items/by-ids?ids=" + url.QueryEscape(id)
+	case queries.PublicKindUser:
+		return "/stats/users/" + url.PathEscape(id)
+	case queries.PublicKindReport:
+		return "/api/views/" + url.PathEscape(id)
+	}
+	return ""
+}
+
+// PublicIDHandler returns the public id and deep link of an entity, creating the id on first use.
+// GET /api/public-ids?kind=session|item|user|report&id=<internal id>
+func PublicIDHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		kind := strings.ToLower(strings.TrimSpace(c.Query("kind")))
+		id := strings.TrimSpace(c.Query("id"))
+		if !queries.ValidPublicKind(kind) {
+			return c.Status(400).JSON(fiber.Map{"error": "kind must be session, item, user or report"})
+		}
+		if id == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "id is required"})
+		}
+		publicID, err := queries.PublicID(c, db, kind, id)
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{"error": kind + " not found"})
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"public_id": publicID, "kind": kind, "id": id, "link": notify.Link(publicID)})
+	}
+}
+
+// ResolvePublicIDHandler maps a public id to the entity it currently points at, following
+// re-syncs and remaps. With ?redirect=true it redirects to the entity's data instead.
+// GET /resolve/:publicId
+func ResolvePublicIDHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		ref, err := queries.ResolvePublicID(c, db, c.Params("publicId"))
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{"error": "unknown public id"})
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if ref.Kind == queries.PublicKindUser && viewerMask(c, db).hides(ref.ID) {
+			ref.ID, ref.Name = "", queries.AnonymousName
+		}
+
+		out := PublicLink{PublicRef: ref, Link: notify.Link(ref.PublicID)}
+		if ref.Found && ref.ID != "" {
+			out.APIPath = publicAPIPath(ref.Kind, ref.ID)
+		}
+		if c.Query("redirect") == "true" {
+			if out.APIPath == "" {
+				return c.Status(404).JSON(fiber.Map{"error": ref.Kind + " not available"})
+			}
+			return c.Redirect().To(out.APIPath)
+		}
+		return c.JSON(out)
+	}
+}
@@ -0,0 +1,278 @@
+package stats
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/middleware"
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// unratedRating groups items without a parental rating.
+const unratedRating = "Unrated"
+
+// itemRatingExpr is an item's parental rating, falling back to its series' rating for episodes.
+const itemRatingExpr = "COALESCE(NULLIF(UPPER(TRIM(li.official_rating)), ''), NULLIF(UPPER(TRIM(sr.official_rating)), ''), '" + unratedRating + "')"
+
+// RatingStat is the watch time of one parental rating.
+type RatingStat struct {
+	Rating string  `json:"rating"`
+	MinAge *int    `json:"min_age"` // nil for unrated or unrecognized ratings
+	Hours  float64 `json:"hours"`
+	Plays  int     `json:"plays"`
+	Users  int     `json:"users"`
+}
+
+// UserRatingHours is a user's watch time of one rating.
+type UserRatingHours struct {
+	Rating string  `json:"rating"`
+	Hours  float64 `json:"hours"`
+}
+
+// UserRatingStat is a user's watch time broken down by parental rating.
+type UserRatingStat struct {
+	UserID  string            `json:"user_id"`
+	Name    string            `json:"name"`
+	Child   bool              `json:"child"`
+	Hours   float64           `json:"hours"`
+	Ratings []UserRatingHours `json:"ratings"` // most watched first
+}
+
+// RestrictedSession is a child user's session of an item rated above their limit.
+type RestrictedSession struct {
+	SessionID int64   `json:"session_id"`
+	UserID    string  `json:"user_id"`
+	UserName  string  `json:"user_name"`
+	ItemID    string  `json:"item_id"`
+	ItemName  string  `json:"item_name"`
+	Rating    string  `json:"rating"`
+	MinAge    *int    `json:"min_age"`
+	StartedAt int64   `json:"started_at"`
+	Minutes   float64 `json:"minutes"`
+	Device    string  `json:"device"`
+	Client    string  `json:"client"`
+}
+
+func ratingMinAge(rating string) *int {
+	if age, ok := media.RatingAge(rating); ok {
+		return &age
+	}
+	return nil
+}
+
+// childUserIDs resolves configured child users, given as media user IDs or names, to user IDs.
+func childUserIDs(db *sql.DB, childUsers []string) (map[string]bool, error) {
+	ids := map[string]bool{}
+	if len(childUsers) == 0 {
+		return ids, nil
+	}
+	want := map[string]bool{}
+	for _, u := range childUsers {
+		want[strings.ToLower(strings.TrimSpace(u))] = true
+	}
+	rows, err := db.Query(`SELECT id, COALESCE(name, '') FROM emby_user`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		if want[strings.ToLower(id)] || (name != "" && want[strings.ToLower(name)]) {
+			ids[id] = true
+		}
+	}
+	return ids, rows.Err()
+}
+
+// GET /stats/ratings?days=30&server=
+// Watch hours per parental rating (PG, R, TV-MA, ...) and per user, youngest ratings first.
+// Users in childUsers are flagged as child profiles.
+func Ratings(db *sql.DB, childUsers []string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 30)
+		if days <= 0 || days > 3650 {
+			days = 30
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		since := time.Now().AddDate(0, 0, -days).Unix()
+
+		children, err := childUserIDs(db, childUsers)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		where, sargs := appendServerFilter("pi.start_ts >= ? AND "+excludeLiveTvFilterAlias("li"), "li", serverType, serverID)
+		rows, err := db.Query(`
+			SELECT `+itemRatingExpr+` AS rating,
+			       pi.user_id,
+			       COALESCE(u.name, pi.user_id),
+			       SUM(pi.duration_seconds) / 3600.0,
+			       COUNT(DISTINCT pi.session_fk)
+			FROM play_intervals pi
+			JOIN library_item li ON li.id = pi.item_id
+			LEFT JOIN library_item sr ON sr.id = li.series_id
+			LEFT JOIN emby_user u ON u.id = pi.user_id
+			WHERE `+where+` AND COALESCE(u.exclude_from_stats, 0) = 0
+			GROUP BY rating, pi.user_id`, append([]any{since}, sargs...)...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+
+		ratings := map[string]*RatingStat{}
+		users := map[string]*UserRatingStat{}
+		for rows.Next() {
+			var rating, userID, name string
+			var hours float64
+			var plays int
+			if err := rows.Scan(&rating, &userID, &name, &hours, &plays); err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			r, ok := ratings[rating]
+			if !ok {
+				r = &RatingStat{Rating: rating, MinAge: ratingMinAge(rating)}
+				ratings[rating] = r
+			}
+			r.Hours += hours
+			r.Plays += plays
+			r.Users++
+
+			u, ok := users[userID]
+			if !ok {
+				u = &UserRatingStat{UserID: userID, Name: name, Child: children[userID], Ratings: []UserRatingHours{}}
+				users[userID] = u
+			}
+			u.Hours += hours
+			u.Ratings = append(u.Ratings, UserRatingHours{Rating: rating, Hours: math.Round(hours*100) / 100})
+		}
+		if err := rows.Err(); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		outRatings := make([]RatingStat, 0, len(ratings))
+		for _, r := range ratings {
+			r.Hours = math.Round(r.Hours*100) / 100
+			outRatings = append(outRatings, *r)
+		}
+		// Youngest audience first; unrated and unrecognized ratings last
+		sort.Slice(outRatings, func(i, j int) bool {
+			a, b := outRatings[i], outRatings[j]
+			if (a.MinAge == nil) != (b.MinAge == nil) {
+				return b.MinAge == nil
+			}
+			if a.MinAge != nil && *a.MinAge != *b.MinAge {
+				return *a.MinAge < *b.MinAge
+			}
+			return a.Rating < b.Rating
+		})
+
+		mask := viewerMask(c, db)
+		outUsers := make([]UserRatingStat, 0, len(users))
+		for _, u := range users {
+			u.Hours = math.Round(u.Hours*100) / 100
+			sort.Slice(u.Ratings, func(i, j int) bool { return u.Ratings[i].Hours > u.Ratings[j].Hours })
+			if mask.hides(u.UserID) {
+				u.UserID = ""
+				u.Name = queries.AnonymousName
+			}
+			outUsers = append(outUsers, *u)
+		}
+		sort.Slice(outUsers, func(i, j int) bool { return outUsers[i].Hours > outUsers[j].Hours })
+
+		return c.JSON(fiber.Map{"days": days, "ratings": outRatings, "users": outUsers})
+	}
+}
+
+// GET /stats/ratings/restricted?days=30&max_rating=PG&include_unrated=false&limit=100
+// Admin only: sessions where a child user (CHILD_USERS) watched an item rated above
+// max_rating (default CHILD_MAX_RATING), newest first. Unrated items are left out unless
+// include_unrated is set.
+func RestrictedRatings(db *sql.DB, childUsers []string, defaultMaxRating string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if !middleware.CurrentViewer(c).Admin {
+			return c.Status(403).JSON(fiber.Map{"error": "admin access required"})
+		}
+		days := parseQueryInt(c, "days", 30)
+		if days <= 0 || days > 3650 {
+			days = 30
+		}
+		limit := parseQueryInt(c, "limit", 100)
+		if limit <= 0 || limit > 1000 {
+			limit = 100
+		}
+		maxRating := strings.TrimSpace(c.Query("max_rating", defaultMaxRating))
+		maxAge, ok := media.RatingAge(maxRating)
+		if !ok {
+			return c.Status(400).JSON(fiber.Map{"error": "unknown rating " + strconv.Quote(maxRating)})
+		}
+		includeUnrated, _ := strconv.ParseBool(c.Query("include_unrated", "false"))
+		since := time.Now().AddDate(0, 0, -days).Unix()
+
+		children, err := childUserIDs(db, childUsers)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		out := []RestrictedSession{}
+		if len(children) == 0 {
+			return c.JSON(fiber.Map{"days": days, "max_rating": maxRating, "child_users": []string{}, "sessions": out})
+		}
+
+		ids := make([]string, 0, len(children))
+		args := []any{since}
+		for id := range children {
+			ids = append(ids, id)
+			args = append(args, id)
+		}
+		sort.Strings(ids)
+		rows, err := db.Query(`
+			SELECT ps.id, ps.user_id, COALESCE(u.name, ps.user_id), ps.item_id,
+			       COALESCE(NULLIF(li.name, ''), ps.item_name, ''), `+itemRatingExpr+`,
+			       ps.started_at, `+deviceNameExpr+`, COALESCE(ps.client_name, ''),
+			       COALESCE(iv.seconds, 0) / 60.0
+			FROM play_sessions ps
+			JOIN library_item li ON li.id = ps.item_id
+			LEFT JOIN library_item sr ON sr.id = li.series_id
+			LEFT JOIN emby_user u ON u.id = ps.user_id
+			LEFT JOIN (
+				SELECT session_fk, SUM(duration_seconds) AS seconds FROM play_intervals GROUP BY session_fk
+			) iv ON iv.session_fk = ps.id
+			`+deviceAliasJoin+`
+			WHERE ps.started_at >= ? AND ps.user_id IN (?`+strings.Repeat(", ?", len(ids)-1)+`)
+			ORDER BY ps.started_at DESC`, args...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+		for rows.Next() && len(out) < limit {
+			var s RestrictedSession
+			if err := rows.Scan(&s.SessionID, &s.UserID, &s.UserName, &s.ItemID, &s.ItemName, &s.Rating,
+				&s.StartedAt, &s.Device, &s.Client, &s.Minutes); err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			s.MinAge = ratingMinAge(s.Rating)
+			if s.MinAge == nil && !includeUnrated {
+				continue
+			}
+			if s.MinAge != nil && *s.MinAge <= maxAge {
+				continue
+			}
+			s.Minutes = math.Round(s.Minutes*10) / 10
+			out = append(out, s)
+		}
+		if err := rows.Err(); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(fiber.Map{"days": days, "max_rating": maxRating, "child_users": ids, "sessions": out})
+	}
+}
@@ -0,0 +1,53 @@
+package stats
+
+import (
+	"database/sql"
+	"time"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GET /stats/requests/funnel?days=180&window=30&limit=100
+// Overseerr/Jellyseerr requests made in the last `days`: how many were added to the library
+// and how many were watched within `window` days of being added, by anyone and by the
+// requester. `requests` lists the most recent ones (limit, 0 for totals only).
+func RequestFunnelHandler(db *sql.DB, enabled bool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 180)
+		if days <= 0 || days > 3650 {
+			days = 180
+		}
+		window := parseQueryInt(c, "window", 30)
+		if window <= 0 || window > 365 {
+			window = 30
+		}
+		limit := parseQueryInt(c, "limit", 100)
+		if limit < 0 || limit > 1000 {
+			limit = 100
+		}
+
+		since := time.Now().AddDate(0, 0, -days).Unix()
+		funnel, err := queries.RequestFunnelStats(c, db, since, int64(window)*86400)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if len(funnel.Requests) > limit {
+			funnel.Requests = funnel.Requests[:limit]
+		}
+		var lastSynced sql.NullInt64
+		_ = db.QueryRow(`SELECT MAX(synced_at) FROM media_requests`).Scan(&lastSynced)
+
+		return c.JSON(fiber.Map{
+			"enabled":        enabled,
+			"last_synced_at": lastSynced.Int64,
+			"days":           days,
+			"window_days":    window,
+			"totals":         funnel.Totals,
+			"by_type":        funnel.ByType,
+			"declined":       funnel.Declined,
+			"requests":       funnel.Requests,
+		})
+	}
+}
@@ -41,12 +41,14 @@ type SeriesData struct {
 	PopularGenres          []GenreStats `json:"popular_genres"`
 }
 
-// helper SQL fragment to derive a series name from the episode's display name convention
-// We assume episodes have name like "Series Name - Episode Name (SxxExx)" after enrichment,
-// otherwise this returns NULL/empty.
-const seriesNameExpr = "TRIM(CASE WHEN INSTR(name, ' - ') > 0 THEN SUBSTR(name, 1, INSTR(name, ' - ') - 1) ELSE NULL END)"
-const seriesResolvedNameExpr = "COALESCE(NULLIF(TRIM(COALESCE(series_name, '')), ''), " + seriesNameExpr + ")"
-const seriesKeyExpr = "COALESCE(NULLIF(TRIM(COALESCE(series_id, '')), ''), " + seriesResolvedNameExpr + ")"
+// Series are identified by the series_id the media server reports for each episode.
+// seriesJoin attaches the series row to library_item li; seriesNameSQL prefers its name and
+// falls back to the name stored with the episode.
+const (
+	seriesJoin     = "LEFT JOIN series s ON s.id = li.series_id"
+	seriesNameSQL  = "COALESCE(NULLIF(TRIM(s.name), ''), NULLIF(TRIM(li.series_name), ''))"
+	hasSeriesIDSQL = "li.series_id IS NOT NULL AND TRIM(li.series_id) != ''"
+)
 
 func Series(db *sql.DB) fiber.Handler {
 	return func(c fiber.Ctx) error {
@@ -71,28 +73,26 @@ func Series(db *sql.DB) fiber.Handler {
 		}
 		if data.TotalSeries == 0 {
 			countQuery := fmt.Sprintf(`
-                SELECT COUNT(*) FROM (
-                    SELECT DISTINCT %s AS series_key
-                    FROM library_item
-                    WHERE %s
-                ) WHERE series_key IS NOT NULL AND series_key != ''
-            `, seriesKeyExpr, episodeWhere)
-			err = db.QueryRow(countQuery, episodeArgs...).Scan(&data.TotalSeries)
+                SELECT COUNT(DISTINCT li.series_id)
+                FROM library_item li
+                WHERE %s AND %s
+            `, episodeAliasWhere, hasSeriesIDSQL)
+			err = db.QueryRow(countQuery, episodeAliasArgs...).Scan(&data.TotalSeries)
 			if err != nil {
 				log.Printf("[series] Error counting series: %v", err)
 				return c.Status(500).JSON(fiber.Map{"error": "Failed to count series"})
 			}
 		}
 
-		// Total episodes (deduplicated by file_path for All Servers, item_id for single server)
+		// Total episodes (deduplicated by title for All Servers, item_id for single server)
 		var episodeCountQuery string
 		if serverType == "" && serverID == "" {
-			// All Servers: deduplicate by file_path
+			// All Servers: deduplicate by provider id match or file_path
 			episodeCountQuery = fmt.Sprintf(`
 				SELECT COUNT(DISTINCT %s)
 				FROM library_item
-				WHERE %s AND file_path IS NOT NULL AND file_path != ''`,
-				normalizedFilePathExpr(""), episodeWhere)
+				WHERE %s AND %s`,
+				libraryTitleKeyExpr(""), episodeWhere, hasTitleKeySQL)
 		} else {
 			// Single server: use item_id
 			episodeCountQuery = fmt.Sprintf(`
@@ -109,32 +109,33 @@ func Series(db *sql.DB) fiber.Handler {
 		largestSeriesQuery := fmt.Sprintf(`
             SELECT series_name, SUM(estimated_gb) AS total_gb
             FROM (
-                SELECT %s AS series_key,
+                SELECT li.series_id AS series_key,
                        %s AS series_name,
                        COALESCE(
-                         CASE WHEN file_size_bytes IS NOT NULL AND file_size_bytes > 0
-                              THEN file_size_bytes / 1073741824.0
+                         CASE WHEN li.file_size_bytes IS NOT NULL AND li.file_size_bytes > 0
+                              THEN li.file_size_bytes / 1073741824.0
                          END,
-                         CASE WHEN bitrate_bps > 0 AND run_time_ticks > 0
-                              THEN (bitrate_bps * (run_time_ticks / 10000000.0) / 8.0) / 1073741824.0
+                         CASE WHEN li.bitrate_bps > 0 AND li.run_time_ticks > 0
+                              THEN (li.bitrate_bps * (li.run_time_ticks / 10000000.0) / 8.0) / 1073741824.0
                          END,
-                         (COALESCE(run_time_ticks, 0) / 36000000000.0) * 
+                         (COALESCE(li.run_time_ticks, 0) / 36000000000.0) *
                          CASE
-                            WHEN COALESCE(height,0) >= 2160 THEN 25.0
-                            WHEN COALESCE(height,0) >= 1080 THEN 8.0
-                            WHEN COALESCE(height,0) >= 720  THEN 4.0
+                            WHEN COALESCE(li.height,0) >= 2160 THEN 25.0
+                            WHEN COALESCE(li.height,0) >= 1080 THEN 8.0
+                            WHEN COALESCE(li.height,0) >= 720  THEN 4.0
                             ELSE 2.0
                          END
                        ) AS estimated_gb
-                FROM library_item
-                WHERE %s
+                FROM library_item li
+                %s
+                WHERE %s AND %s
             )
-            WHERE series_key IS NOT NULL AND series_key != '' AND series_name IS NOT NULL AND series_name != ''
-            GROUP BY series_key, series_name
+            WHERE series_name IS NOT NULL
+            GROUP BY series_key
             ORDER BY total_gb DESC
             LIMIT 1
-        `, seriesKeyExpr, seriesResolvedNameExpr, episodeWhere)
-		err = db.QueryRow(largestSeriesQuery, episodeArgs...).Scan(&data.LargestSeriesName, &data.LargestSeriesGB)
+        `, seriesNameSQL, seriesJoin, episodeAliasWhere, hasSeriesIDSQL)
+		err = db.QueryRow(largestSeriesQuery, episodeAliasArgs...).Scan(&data.LargestSeriesName, &data.LargestSeriesGB)
 		if err != nil && err != sql.ErrNoRows {
 			log.Printf("[series] Error finding largest series: %v", err)
 		}
@@ -171,16 +172,17 @@ func Series(db *sql.DB) fiber.Handler {
 		longestSeriesQuery := fmt.Sprintf(`
             SELECT series_name, SUM(run_time_ticks) / 600000000 AS minutes
             FROM (
-                SELECT %s AS series_key, %s AS series_name, run_time_ticks
-                FROM library_item
-                WHERE %s AND run_time_ticks > 0
+                SELECT li.series_id AS series_key, %s AS series_name, li.run_time_ticks
+                FROM library_item li
+                %s
+                WHERE %s AND %s AND li.run_time_ticks > 0
             )
-            WHERE series_key IS NOT NULL AND series_key != '' AND series_name IS NOT NULL AND series_name != ''
-            GROUP BY series_key, series_name
+            WHERE series_name IS NOT NULL
+            GROUP BY series_key
             ORDER BY minutes DESC
             LIMIT 1
-        `, seriesKeyExpr, seriesResolvedNameExpr, episodeWhere)
-		err = db.QueryRow(longestSeriesQuery, episodeArgs...).Scan(&data.LongestSeriesName, &data.LongestSeriesMinutes)
+        `, seriesNameSQL, seriesJoin, episodeAliasWhere, hasSeriesIDSQL)
+		err = db.QueryRow(longestSeriesQuery, episodeAliasArgs...).Scan(&data.LongestSeriesName, &data.LongestSeriesMinutes)
 		if err != nil && err != sql.ErrNoRows {
 			log.Printf("[series] Error finding longest series: %v", err)
 		}
@@ -188,19 +190,20 @@ func Series(db *sql.DB) fiber.Handler {
 		// Most watched series (sum watch time across episodes of same series)
 		mostWatchedQuery := fmt.Sprintf(`
             SELECT series_name, SUM(hours) AS total_hours FROM (
-                SELECT %s AS series_key,
+                SELECT li.series_id AS series_key,
                        %s AS series_name,
                        SUM(pi.duration_seconds) / 3600.0 AS hours
                 FROM play_intervals pi
                 JOIN library_item li ON pi.item_id = li.id
-                WHERE %s
+                %s
+                WHERE %s AND %s
                 GROUP BY li.id
             )
-            WHERE series_key IS NOT NULL AND series_key != '' AND series_name IS NOT NULL AND series_name != ''
-            GROUP BY series_key, series_name
+            WHERE series_name IS NOT NULL
+            GROUP BY series_key
             ORDER BY total_hours DESC
             LIMIT 1
-        `, seriesKeyExpr, seriesResolvedNameExpr, episodeAliasWhere)
+        `, seriesNameSQL, seriesJoin, episodeAliasWhere, hasSeriesIDSQL)
 		err = db.QueryRow(mostWatchedQuery, episodeAliasArgs...).Scan(&data.MostWatchedSeries.Name, &data.MostWatchedSeries.Hours)
 		if err != nil && err != sql.ErrNoRows {
 			log.Printf("[series] Error finding most watched series: %v", err)
@@ -0,0 +1,59 @@
+package stats
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GET /stats/idle-windows?days=30&tz=&min_idle_pct=90&server=
+// When each server streams nothing over the last `days` local days in ?tz= (IANA name,
+// default server local): the longest idle stretch, the share of time idle, the share of
+// days each hour of the day was idle, and the typical daily window - the longest run of
+// hours idle on at least min_idle_pct percent of days - for scheduling maintenance,
+// backups or transcode batches.
+func IdleWindowsHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		loc := time.Local
+		if tz := strings.TrimSpace(c.Query("tz")); tz != "" {
+			l, err := time.LoadLocation(tz)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "unknown timezone: " + tz})
+			}
+			loc = l
+		}
+		days := parseQueryInt(c, "days", 30)
+		if days <= 0 || days > 366 {
+			days = 30
+		}
+		minIdle := 90.0
+		if v, err := strconv.ParseFloat(c.Query("min_idle_pct"), 64); err == nil && v > 0 && v <= 100 {
+			minIdle = v
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+
+		servers, err := queries.IdleWindows(c, db, days, loc, time.Now(), minIdle, serverType, serverID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if mgr := getMultiServerManager(); mgr != nil {
+			configs := mgr.GetServerConfigs()
+			for i := range servers {
+				if cfg, ok := configs[servers[i].ServerID]; ok {
+					servers[i].ServerName = cfg.Name
+				}
+			}
+		}
+		return c.JSON(fiber.Map{
+			"days":         days,
+			"timezone":     loc.String(),
+			"min_idle_pct": minIdle,
+			"servers":      servers,
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package stats
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ticksPerSecond converts Emby/Jellyfin position ticks (100ns) to seconds.
+const ticksPerSecond = 10_000_000
+
+// SessionEvent is one raw playback event as it was recorded.
+type SessionEvent struct {
+	ID              int64    `json:"id"`
+	Kind            string   `json:"kind"` // start|progress|pause|unpause|stop
+	IsPaused        bool     `json:"is_paused"`
+	PositionTicks   *int64   `json:"position_ticks"`
+	PositionSeconds *float64 `json:"position_seconds"`
+	PlaybackRate    *float64 `json:"playback_rate,omitempty"`
+	CreatedAt       int64    `json:"created_at"`
+	// Seconds of wall clock and of playback position since the previous event
+	WallDeltaSeconds     int64    `json:"wall_delta_seconds"`
+	PositionDeltaSeconds *float64 `json:"position_delta_seconds"`
+}
+
+// TrackChange is one audio or subtitle switch seen during a session.
+type TrackChange struct {
+	Track           string   `json:"track"` // audio|subtitle
+	FromLanguage    string   `json:"from_language"`
+	ToLanguage      string   `json:"to_language"` // "off" when subtitles were turned off
+	PositionSeconds *float64 `json:"position_seconds"`
+	CreatedAt       int64    `json:"created_at"`
+}
+
+// SessionEvents is the response of GET /stats/sessions/:id/events.
+type SessionEvents struct {
+	ID         int64  `json:"id"`
+	SessionID  string `json:"session_id"`
+	ServerID   string `json:"server_id"`
+	UserID     string `json:"user_id"`
+	UserName   string `json:"user_name"`
+	ItemID     string `json:"item_id"`
+	ItemName   string `json:"item_name"`
+	ClientName string `json:"client_name"`
+	StartedAt  int64  `json:"started_at"`
+	EndedAt    *int64 `json:"ended_at,omitempty"`
+	IsActive   bool   `json:"is_active"`
+	// WatchedSeconds is the watch time counted from the session's intervals, for comparison
+	// with what the events suggest
+	WatchedSeconds int64          `json:"watched_seconds"`
+	Events         []SessionEvent `json:"events"`
+	// Tracks playback started on (nil when not reported) and the switches made later
+	AudioLanguage    *string       `json:"audio_language"`
+	AudioDefault     *bool         `json:"audio_default"`
+	SubtitleLanguage *string       `json:"subtitle_language"`
+	TrackChanges     []TrackChange `json:"track_changes"`
+}
+
+// GET /stats/sessions/:id/events?format=csv
+// Raw play_events of one session (the id from /stats/sessions/history) in the order they
+// were recorded, with the time and position elapsed since the previous event. format=csv
+// downloads the events as CSV. Audio and subtitle switches are listed in track_changes.
+func SessionEventsHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil || id <= 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid session id"})
+		}
+
+		out := SessionEvents{ID: id, Events: []SessionEvent{}, TrackChanges: []TrackChange{}}
+		var ended sql.NullInt64
+		var audioLang, subtitleLang sql.NullString
+		var audioDefault sql.NullBool
+		err = db.QueryRow(`
+			SELECT ps.session_id, COALESCE(ps.server_id, ''), ps.user_id, COALESCE(u.name, ps.user_name, ps.user_id),
+			       ps.item_id, COALESCE(li.name, ps.item_name, ''), COALESCE(ps.client_name, ''),
+			       ps.started_at, ps.ended_at, COALESCE(ps.is_active, 0),
+			       COALESCE((SELECT SUM(duration_seconds) FROM play_intervals WHERE session_fk = ps.id), 0),
+			       ps.audio_language, ps.audio_default, ps.subtitle_language
+			FROM play_sessions ps
+			LEFT JOIN emby_user u ON u.id = ps.user_id
+			LEFT JOIN library_item li ON li.id = ps.item_id
+			WHERE ps.id = ?`, id).Scan(&out.SessionID, &out.ServerID, &out.UserID, &out.UserName,
+			&out.ItemID, &out.ItemName, &out.ClientName, &out.StartedAt, &ended, &out.IsActive, &out.WatchedSeconds,
+			&audioLang, &audioDefault, &subtitleLang)
+		if err == sql.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{"error": "session not found"})
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if ended.Valid {
+			v := ended.Int64
+			out.EndedAt = &v
+		}
+		if audioLang.Valid {
+			out.AudioLanguage = &audioLang.String
+		}
+		if audioDefault.Valid {
+			out.AudioDefault = &audioDefault.Bool
+		}
+		if subtitleLang.Valid {
+			out.SubtitleLanguage = &subtitleLang.String
+		}
+
+		rows, err := db.Query(`
+			SELECT id, kind, is_paused, position_ticks, playback_rate, created_at
+			FROM play_events
+			WHERE session_fk = ?
+			ORDER BY created_at, id`, id)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e SessionEvent
+			var pos sql.NullInt64
+			var rate sql.NullFloat64
+			if err := rows.Scan(&e.ID, &e.Kind, &e.IsPaused, &pos, &rate, &e.CreatedAt); err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			if pos.Valid {
+				ticks := pos.Int64
+				secs := float64(ticks) / ticksPerSecond
+				e.PositionTicks = &ticks
+				e.PositionSeconds = &secs
+			}
+			if rate.Valid {
+				r := rate.Float64
+				e.PlaybackRate = &r
+			}
+			if n := len(out.Events); n > 0 {
+				prev := out.Events[n-1]
+				e.WallDeltaSeconds = e.CreatedAt - prev.CreatedAt
+				if e.PositionSeconds != nil && prev.PositionSeconds != nil {
+					d := *e.PositionSeconds - *prev.PositionSeconds
+					e.PositionDeltaSeconds = &d
+				}
+			}
+			out.Events = append(out.Events, e)
+		}
+		if err := rows.Err(); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		tracks, err := db.Query(`
+			SELECT track, from_language, to_language, position_ticks, created_at
+			FROM play_track_changes
+			WHERE session_fk = ?
+			ORDER BY created_at, id`, id)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer tracks.Close()
+		for tracks.Next() {
+			var tc TrackChange
+			var pos sql.NullInt64
+			if err := tracks.Scan(&tc.Track, &tc.FromLanguage, &tc.ToLanguage, &pos, &tc.CreatedAt); err != nil {
+				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			}
+			if pos.Valid {
+				secs := float64(pos.Int64) / ticksPerSecond
+				tc.PositionSeconds = &secs
+			}
+			out.TrackChanges = append(out.TrackChanges, tc)
+		}
+		if err := tracks.Err(); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if strings.EqualFold(c.Query("format"), "csv") {
+			return writeSessionEventsCSV(c, out)
+		}
+		return c.JSON(out)
+	}
+}
+
+func writeSessionEventsCSV(c fiber.Ctx, s SessionEvents) error {
+	opt := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', 3, 64)
+	}
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	_ = w.Write([]string{"id", "kind", "is_paused", "position_ticks", "position_seconds", "playback_rate",
+		"created_at", "wall_delta_seconds", "position_delta_seconds"})
+	for _, e := range s.Events {
+		ticks := ""
+		if e.PositionTicks != nil {
+			ticks = strconv.FormatInt(*e.PositionTicks, 10)
+		}
+		_ = w.Write([]string{
+			strconv.FormatInt(e.ID, 10), e.Kind, strconv.FormatBool(e.IsPaused), ticks, opt(e.PositionSeconds),
+			opt(e.PlaybackRate), strconv.FormatInt(e.CreatedAt, 10), strconv.FormatInt(e.WallDeltaSeconds, 10),
+			opt(e.PositionDeltaSeconds),
+		})
+	}
+	w.Flush()
+	c.Set("Content-Type", "text/csv; charset=utf-8")
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="session-%d-events.csv"`, s.ID))
+	return c.SendString(sb.String())
+}
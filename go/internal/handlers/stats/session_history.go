@@ -0,0 +1,123 @@
+package stats
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"emby-analytics/internal/queries"
+)
+
+type SessionHistoryEntry struct {
+	ID         int64    `json:"id"` // for /stats/sessions/:id/events
+	SessionID  string   `json:"session_id"`
+	ServerID   string   `json:"server_id"`
+	UserID     string   `json:"user_id"`
+	UserName   string   `json:"user_name"`
+	ItemID     string   `json:"item_id"`
+	ItemName   string   `json:"item_name"`
+	ItemType   string   `json:"item_type"`
+	ClientName string   `json:"client_name"`
+	DeviceID   string   `json:"device_id"`
+	DeviceName string   `json:"device_name"` // admin name of the device, or its id
+	PlayMethod string   `json:"play_method"`
+	StartedAt  int64    `json:"started_at"`
+	EndedAt    *int64   `json:"ended_at,omitempty"`
+	Hours      float64  `json:"hours"`
+	Note       string   `json:"note,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// splitTags turns the comma-separated play_sessions.tags column into a slice.
+func splitTags(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// GET /stats/sessions/history?q=&tag=&user_id=&annotated=1&days=30&limit=100&server=
+// Session history with admin notes/tags. q matches note, tags, title and user name;
+// tag matches one exact tag; annotated=1 limits results to sessions with a note or tag.
+func SessionHistory(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 30)
+		if days <= 0 || days > 3650 {
+			days = 30
+		}
+		limit := parseQueryInt(c, "limit", 100)
+		if limit <= 0 || limit > 1000 {
+			limit = 100
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		since := time.Now().AddDate(0, 0, -days).Unix()
+
+		where := "ps.started_at >= ?"
+		args := []any{since}
+		if q := strings.TrimSpace(c.Query("q", "")); q != "" {
+			like := "%" + strings.ToLower(q) + "%"
+			where += ` AND (LOWER(COALESCE(ps.note, '')) LIKE ? OR COALESCE(ps.tags, '') LIKE ?
+				OR LOWER(COALESCE(ps.item_name, '')) LIKE ? OR LOWER(COALESCE(ps.user_name, '')) LIKE ?)`
+			args = append(args, like, like, like, like)
+		}
+		if tag := strings.ToLower(strings.TrimSpace(c.Query("tag", ""))); tag != "" {
+			where += " AND (',' || COALESCE(ps.tags, '') || ',') LIKE ?"
+			args = append(args, "%,"+tag+",%")
+		}
+		if userID := strings.TrimSpace(c.Query("user_id", "")); userID != "" {
+			where += " AND ps.user_id = ?"
+			args = append(args, userID)
+		}
+		if c.Query("annotated", "") == "1" {
+			where += " AND (ps.note IS NOT NULL OR ps.tags IS NOT NULL)"
+		}
+		where, sargs := appendServerFilter(where, "ps", serverType, serverID)
+		args = append(args, sargs...)
+
+		rows, err := db.Query(`
+			SELECT ps.id, ps.session_id, COALESCE(ps.server_id, ''), ps.user_id,
+			       COALESCE(u.name, ps.user_name, ps.user_id),
+			       ps.item_id, COALESCE(li.name, ps.item_name, ''), COALESCE(ps.item_type, ''),
+			       COALESCE(ps.client_name, ''), COALESCE(ps.device_id, ''), `+deviceNameExpr+`, COALESCE(ps.play_method, ''),
+			       ps.started_at, ps.ended_at,
+			       COALESCE((SELECT SUM(duration_seconds) FROM play_intervals WHERE session_fk = ps.id), 0) / 3600.0,
+			       COALESCE(ps.note, ''), COALESCE(ps.tags, '')
+			FROM play_sessions ps
+			LEFT JOIN emby_user u ON u.id = ps.user_id
+			LEFT JOIN library_item li ON li.id = ps.item_id
+			`+deviceAliasJoin+`
+			WHERE `+where+`
+			ORDER BY ps.started_at DESC
+			LIMIT ?`, append(args, limit)...)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+
+		mask := viewerMask(c, db)
+		out := []SessionHistoryEntry{}
+		for rows.Next() {
+			var s SessionHistoryEntry
+			var ended sql.NullInt64
+			var tags string
+			if err := rows.Scan(&s.ID, &s.SessionID, &s.ServerID, &s.UserID, &s.UserName, &s.ItemID, &s.ItemName, &s.ItemType,
+				&s.ClientName, &s.DeviceID, &s.DeviceName, &s.PlayMethod, &s.StartedAt, &ended, &s.Hours, &s.Note, &tags); err != nil {
+				continue
+			}
+			if ended.Valid {
+				v := ended.Int64
+				s.EndedAt = &v
+			}
+			s.Tags = splitTags(tags)
+			if mask.hides(s.UserID) {
+				s.UserID = ""
+				s.UserName = queries.AnonymousName
+			}
+			out = append(out, s)
+		}
+
+		return c.JSON(fiber.Map{"days": days, "sessions": out})
+	}
+}
@@ -0,0 +1,110 @@
+package stats
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/handlers/settings"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/middleware"
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// MyStatsDefaults is the response of GET /api/me/stats-defaults.
+type MyStatsDefaults struct {
+	Global    queries.StatsDefaults `json:"global"`
+	User      queries.StatsDefaults `json:"user"`
+	Effective queries.StatsDefaults `json:"effective"`
+}
+
+// ApplyStatsDefaults is middleware for stats routes: requests without ?server= get the
+// default server scope and requests without ?timeframe= or ?days= the default timeframe.
+// The signed-in user's own defaults take precedence over the global settings, and
+// ?server=all or an empty ?server= still asks for every server.
+func ApplyStatsDefaults(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		args := c.Request().URI().QueryArgs()
+		needServer := !args.Has("server")
+		needTimeframe := !args.Has("timeframe") && !args.Has("days")
+		if !needServer && !needTimeframe {
+			return c.Next()
+		}
+		d := effectiveStatsDefaults(c, db)
+		if needServer && d.Server != "" {
+			args.Set("server", d.Server)
+		}
+		if needTimeframe && d.Timeframe != "" {
+			args.Set("timeframe", d.Timeframe)
+		}
+		return c.Next()
+	}
+}
+
+func globalStatsDefaults(db *sql.DB) queries.StatsDefaults {
+	return queries.StatsDefaults{
+		Timeframe: settings.GetSettingValue(db, settings.DefaultTimeframeKey, ""),
+		Server:    settings.GetSettingValue(db, settings.DefaultServerKey, ""),
+	}
+}
+
+func effectiveStatsDefaults(c fiber.Ctx, db *sql.DB) queries.StatsDefaults {
+	global := globalStatsDefaults(db)
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		return global
+	}
+	own, err := queries.UserStatsDefaults(c, db, userID)
+	if err != nil {
+		logging.Warn("failed to load user stats defaults", "user_id", userID, "error", err)
+		return global
+	}
+	return own.Or(global)
+}
+
+// MyStatsDefaultsHandler returns the global stats defaults, the signed-in user's overrides
+// and the combination applied to their stats requests.
+func MyStatsDefaultsHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		own, err := queries.UserStatsDefaults(c, db, userID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		global := globalStatsDefaults(db)
+		return c.JSON(MyStatsDefaults{Global: global, User: own, Effective: own.Or(global)})
+	}
+}
+
+// UpdateMyStatsDefaultsHandler sets the signed-in user's default timeframe and server scope,
+// e.g. {"timeframe":"7d","server":"plex"}. An empty field falls back to the global default.
+func UpdateMyStatsDefaultsHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		var req queries.StatsDefaults
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		req.Timeframe = strings.TrimSpace(req.Timeframe)
+		req.Server = strings.TrimSpace(req.Server)
+		if req.Timeframe != "" && !settings.ValidTimeframe(req.Timeframe) {
+			return c.Status(400).JSON(fiber.Map{"error": "timeframe must be one of 1d, 3d, 7d, 14d, 30d, all-time"})
+		}
+		if req.Server != "" && !settings.ValidServerScope(req.Server) {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid server"})
+		}
+		if err := queries.SaveUserStatsDefaults(c, db, userID, req, time.Now().Unix()); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		global := globalStatsDefaults(db)
+		return c.JSON(MyStatsDefaults{Global: global, User: req, Effective: req.Or(global)})
+	}
+}
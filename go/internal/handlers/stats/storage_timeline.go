@@ -0,0 +1,171 @@
+package stats
+
+import (
+	"database/sql"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// storageTiers are the resolution tiers tracked in library_events, lowest first.
+var storageTiers = []string{"SD", "720p", "1080p", "4K", "Unknown"}
+
+// StorageTimelinePoint is the library size per tier at the end of one bucket.
+type StorageTimelinePoint struct {
+	Date    string             `json:"date"` // bucket start, YYYY-MM-DD (UTC)
+	GB      map[string]float64 `json:"gb"`
+	TotalGB float64            `json:"total_gb"`
+}
+
+// StorageTimeline is the response of /stats/library/storage-timeline.
+type StorageTimeline struct {
+	Interval string                 `json:"interval"`
+	Tiers    []string               `json:"tiers"`
+	Points   []StorageTimelinePoint `json:"points"`
+	// TrackingSince is the first recorded library event; earlier points only reflect
+	// items that were already in the library when tracking started.
+	TrackingSince string `json:"tracking_since,omitempty"`
+}
+
+// StorageTimelineHandler returns storage per resolution tier over time, replayed from
+// library_events (additions minus removals, sizes estimated from bitrate x runtime when the
+// file size is unknown).
+// Query params: days (default 180), interval (day, week or month; default week).
+// GET /stats/library/storage-timeline
+func StorageTimelineHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 180)
+		if days <= 0 || days > 3650 {
+			days = 180
+		}
+		interval := strings.ToLower(c.Query("interval", "week"))
+		switch interval {
+		case "day", "week", "month":
+		default:
+			return c.Status(400).JSON(fiber.Map{"error": "interval must be day, week or month"})
+		}
+
+		now := time.Now().UTC()
+		first := storageBucketStart(now.AddDate(0, 0, -days), interval)
+		out, err := storageTimeline(db, first, now, interval)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(out)
+	}
+}
+
+func storageTimeline(db *sql.DB, first, now time.Time, interval string) (*StorageTimeline, error) {
+	out := &StorageTimeline{Interval: interval, Tiers: storageTiers, Points: []StorageTimelinePoint{}}
+
+	var since sql.NullInt64
+	if err := db.QueryRow(`SELECT MIN(occurred_at) FROM library_events`).Scan(&since); err != nil {
+		return nil, err
+	}
+	if since.Valid {
+		out.TrackingSince = time.Unix(since.Int64, 0).UTC().Format("2006-01-02")
+	}
+
+	// Running totals start from everything that happened before the first bucket.
+	totals := make(map[string]int64, len(storageTiers))
+	rows, err := db.Query(`
+		SELECT tier, SUM(CASE WHEN event = 'removed' THEN -size_bytes ELSE size_bytes END)
+		FROM library_events
+		WHERE occurred_at < ?
+		GROUP BY tier
+	`, first.Unix())
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var tier string
+		var bytes int64
+		if err := rows.Scan(&tier, &bytes); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		totals[tier] += bytes
+	}
+	rows.Close()
+
+	// Daily deltas within the window, folded into buckets below.
+	deltas := map[string]map[string]int64{}
+	rows, err = db.Query(`
+		SELECT strftime('%Y-%m-%d', datetime(occurred_at, 'unixepoch')) AS day, tier,
+		       SUM(CASE WHEN event = 'removed' THEN -size_bytes ELSE size_bytes END)
+		FROM library_events
+		WHERE occurred_at >= ?
+		GROUP BY day, tier
+	`, first.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day, tier string
+		var bytes int64
+		if err := rows.Scan(&day, &tier, &bytes); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			continue
+		}
+		key := storageBucketStart(t, interval).Format("2006-01-02")
+		if deltas[key] == nil {
+			deltas[key] = map[string]int64{}
+		}
+		deltas[key][tier] += bytes
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for b := first; !b.After(now); b = storageNextBucket(b, interval) {
+		key := b.Format("2006-01-02")
+		for tier, bytes := range deltas[key] {
+			totals[tier] += bytes
+		}
+		p := StorageTimelinePoint{Date: key, GB: make(map[string]float64, len(storageTiers))}
+		var total int64
+		for _, tier := range storageTiers {
+			bytes := totals[tier]
+			if bytes < 0 {
+				bytes = 0 // removals of items synced before tracking started
+			}
+			p.GB[tier] = bytesToGB(bytes)
+			total += bytes
+		}
+		p.TotalGB = bytesToGB(total)
+		out.Points = append(out.Points, p)
+	}
+	return out, nil
+}
+
+// storageBucketStart truncates t (UTC) to the start of its day, Monday-based week or month.
+func storageBucketStart(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case "week":
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+func storageNextBucket(t time.Time, interval string) time.Time {
+	switch interval {
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+func bytesToGB(b int64) float64 {
+	return math.Round(float64(b)/1073741824.0*100) / 100
+}
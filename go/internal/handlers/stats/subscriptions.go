@@ -0,0 +1,142 @@
+package stats
+
+import (
+	"database/sql"
+	"net/mail"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/middleware"
+	"emby-analytics/internal/notify"
+	"emby-analytics/internal/queries"
+	"emby-analytics/internal/tasks"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// MySubscriptions is the response of GET /api/me/subscriptions.
+type MySubscriptions struct {
+	// EmailEnabled is false until SMTP is configured; subscriptions are kept but not sent
+	EmailEnabled  bool                       `json:"email_enabled"`
+	Linked        bool                       `json:"linked"`
+	Kinds         []string                   `json:"kinds"`
+	Subscriptions []queries.UserSubscription `json:"subscriptions"`
+}
+
+type subscriptionRequest struct {
+	Kind    string `json:"kind"`
+	Email   string `json:"email"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// MySubscriptionsHandler lists the signed-in user's email subscriptions.
+func MySubscriptionsHandler(db *sql.DB, emailEnabled bool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		subs, err := queries.ListUserSubscriptions(c, db, userID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(MySubscriptions{
+			EmailEnabled:  emailEnabled,
+			Linked:        linkedMediaUser(db, userID) != "",
+			Kinds:         []string{queries.SubscriptionWeeklyDigest},
+			Subscriptions: subs,
+		})
+	}
+}
+
+// UpdateMySubscriptionHandler subscribes the signed-in user to an email digest or changes
+// the address, e.g. {"kind":"weekly_digest","email":"me@example.com","enabled":true}.
+// Digests are only sent while the app user is linked to a media user.
+func UpdateMySubscriptionHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		var req subscriptionRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		}
+		req.Kind = strings.ToLower(strings.TrimSpace(req.Kind))
+		if req.Kind == "" {
+			req.Kind = queries.SubscriptionWeeklyDigest
+		}
+		if req.Kind != queries.SubscriptionWeeklyDigest {
+			return c.Status(400).JSON(fiber.Map{"error": "kind must be " + queries.SubscriptionWeeklyDigest})
+		}
+		addr, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "a valid email address is required"})
+		}
+		enabled := req.Enabled == nil || *req.Enabled
+		if err := queries.SaveUserSubscription(c, db, userID, req.Kind, addr.Address, enabled, time.Now().Unix()); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		subs, err := queries.ListUserSubscriptions(c, db, userID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		for _, s := range subs {
+			if s.Kind == req.Kind {
+				return c.JSON(s)
+			}
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "subscription not saved"})
+	}
+}
+
+// DeleteMySubscriptionHandler unsubscribes the signed-in user from a kind of email.
+func DeleteMySubscriptionHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		res, err := db.Exec(`DELETE FROM user_subscriptions WHERE user_id = ? AND kind = ?`, userID, c.Params("kind"))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return c.Status(404).JSON(fiber.Map{"error": "subscription not found"})
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// PreviewMyDigestHandler renders the signed-in user's weekly digest for the current week so
+// far as HTML (?format=text for the plain text version).
+func PreviewMyDigestHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		mediaUserID := linkedMediaUser(db, userID)
+		if mediaUserID == "" {
+			return c.Status(409).JSON(fiber.Map{"error": "your account is not linked to a media user"})
+		}
+		var username string
+		_ = db.QueryRow(`SELECT username FROM app_user WHERE id = ?`, userID).Scan(&username)
+
+		start, end, _ := queries.GoalPeriodBounds("week", time.Now())
+		digest, err := tasks.BuildPersonalDigest(c, db, username, mediaUserID, start, end)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		msg, err := notify.RenderPersonalDigest("", digest)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if c.Query("format") == "text" {
+			c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+			return c.SendString(msg.Text)
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.SendString(msg.HTML)
+	}
+}
@@ -0,0 +1,44 @@
+package stats
+
+import (
+	"database/sql"
+	"time"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// TopTags returns item tags (Emby/Jellyfin tags, Plex labels) ranked by watch hours in the timeframe
+func TopTags(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		timeframe := c.Query("timeframe", "30d")
+		limit := parseQueryInt(c, "limit", 20)
+		if limit <= 0 || limit > 100 {
+			limit = 20
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+
+		now := time.Now().UTC()
+		winEnd := now.Unix()
+		var winStart int64
+		if timeframe != "all-time" {
+			winStart = now.AddDate(0, 0, -parseTimeframeToDays(timeframe)).Unix()
+		}
+
+		tags, err := queries.TopTags(c, db, winStart, winEnd, serverType, serverID, limit)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if tags == nil {
+			tags = []queries.TagStatsRow{}
+		}
+		return c.JSON(tags)
+	}
+}
+
+// tagPredicate matches library items carrying the tag bound to its single placeholder.
+// idColumn is the library_item id column, e.g. "li.id".
+func tagPredicate(idColumn string) string {
+	return "EXISTS (SELECT 1 FROM item_tags itg WHERE itg.item_id = " + idColumn + " AND itg.tag = ? COLLATE NOCASE)"
+}
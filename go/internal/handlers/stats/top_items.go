@@ -1,8 +1,10 @@
 package stats
 
 import (
+	"context"
 	"database/sql"
 	"emby-analytics/internal/emby"
+	"emby-analytics/internal/handlers/settings"
 	"emby-analytics/internal/media"
 	"emby-analytics/internal/queries"
 	"emby-analytics/internal/tasks"
@@ -22,6 +24,11 @@ type TopItem struct {
 	Display    string  `json:"display"`
 	ServerType string  `json:"server_type,omitempty"`
 	ServerID   string  `json:"server_id,omitempty"`
+	// GroupWatches is the number of distinct SyncPlay groups that watched the item in the window
+	GroupWatches int `json:"group_watches,omitempty"`
+	// ItemIDs lists every copy of the title that was summed into this row when it is on
+	// several servers; ItemID is then the copy with the most hours
+	ItemIDs []string `json:"item_ids,omitempty"`
 }
 
 // isDisallowedTopItemType filters out non-content entity types from Top Items.
@@ -42,290 +49,311 @@ func isDisallowedTopItemType(t string) bool {
 
 func TopItems(db *sql.DB, em *emby.Client) fiber.Handler {
 	return func(c fiber.Ctx) error {
-		rawServer := c.Query("server", "")
-		serverTypeFilter, serverIDFilter := normalizeServerParam(rawServer)
-		timeframe := c.Query("timeframe", "")
-		if timeframe == "" {
-			// Fallback to days parameter if timeframe not provided
-			days := parseQueryInt(c, "days", 14)
-			if days <= 0 {
-				timeframe = "all-time"
-			} else if days == 1 {
-				timeframe = "1d"
-			} else if days == 3 {
-				timeframe = "3d"
-			} else if days == 7 {
-				timeframe = "7d"
-			} else if days == 14 {
-				timeframe = "14d"
-			} else if days == 30 {
-				timeframe = "30d"
-			} else {
-				timeframe = "30d" // Default for large day values
-			}
-		}
-		if timeframe == "" {
-			timeframe = "14d" // Final fallback
-		}
-
 		limit := parseQueryInt(c, "limit", 10)
 		if limit <= 0 || limit > 100 {
 			limit = 10
 		}
+		out, err := topItemsData(c, db, em, timeframeParam(c), limit, c.Query("server", ""), c.Query("tag", ""))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(out)
+	}
+}
 
-		days := parseTimeframeToDays(timeframe)
-		now := time.Now().UTC()
-		winEnd := now.Unix()
-		winStart := now.AddDate(0, 0, -days).Unix()
+// topItemsData ranks items by coalesced watch hours in timeframe, including live sessions.
+// rawServer and tag are the optional ?server= and ?tag= filters.
+func topItemsData(ctx context.Context, db *sql.DB, em *emby.Client, timeframe string, limit int, rawServer, tag string) ([]TopItem, error) {
+	serverTypeFilter, serverIDFilter := normalizeServerParam(rawServer)
 
-		if timeframe == "all-time" {
-			winStart = 0
-			winEnd = now.AddDate(100, 0, 0).Unix()
+	// Optional tag filter (Emby/Jellyfin tags, Plex labels)
+	var taggedIDs map[string]bool
+	if tag = strings.TrimSpace(tag); tag != "" {
+		ids, err := queries.ItemIDsWithTag(ctx, db, tag)
+		if err != nil {
+			return nil, err
 		}
+		taggedIDs = ids
+	}
 
-		// 1. Get historical data (broad candidate set)
-		historicalRows, err := queries.TopItemsByWatchSeconds(c, db, winStart, winEnd, 1000)
-		// If the primary query errors, don't fail hard; attempt fallback path below
-		if err != nil {
-			historicalRows = nil
-		}
-
-		if err != nil || len(historicalRows) == 0 {
-			// Fallback to counting sessions if intervals aren't populated
-			rows, err := db.Query(`
-        SELECT 
-            li.id,
-            li.name,
-            li.media_type,
-            COUNT(DISTINCT ps.id) * 0.5 as hours
-        FROM library_item li
-        LEFT JOIN play_sessions ps ON ps.item_id = li.id
-        WHERE ps.started_at >= ? AND ps.started_at <= ?
-          AND `+excludeLiveTvFilter()+`
-        GROUP BY li.id, li.name, li.media_type
-        ORDER BY hours DESC
-        LIMIT ?
-    `, winStart, winEnd, 1000)
-
-			if err == nil {
-				defer rows.Close()
-				historicalRows = []queries.TopItemRow{}
-				for rows.Next() {
-					var r queries.TopItemRow
-					if err := rows.Scan(&r.ItemID, &r.Name, &r.Type, &r.Hours); err == nil {
-						r.Display = r.Name
-						historicalRows = append(historicalRows, r)
-					}
-				}
-			}
+	days := parseTimeframeToDays(timeframe)
+	now := time.Now().UTC()
+	winEnd := now.Unix()
+	winStart := now.AddDate(0, 0, -days).Unix()
+
+	if timeframe == "all-time" {
+		winStart = 0
+		winEnd = now.AddDate(100, 0, 0).Unix()
+	}
+
+	// 1. Get historical data (broad candidate set)
+	historicalRows, err := queries.TopItemsByWatchSeconds(ctx, db, winStart, winEnd, 1000)
+	// If the primary query errors, don't fail hard; attempt fallback path below
+	if err != nil {
+		historicalRows = nil
+	}
+
+	if err != nil || len(historicalRows) == 0 {
+		// Fallback to counting sessions if intervals aren't populated
+		if fallback, ferr := queries.TopItemsBySessionCount(ctx, db, winStart, winEnd, 1000); ferr == nil {
+			historicalRows = fallback
 		}
+	}
 
-		// 2. Build item details map and candidate set for precise duration calculation
-		combinedHours := make(map[string]float64)
-		itemDetails := make(map[string]TopItem)
-		candidateIDs := make(map[string]struct{})
-		for _, row := range historicalRows {
-			// Exclude Live TV content from candidates
-			if strings.EqualFold(row.Type, "TvChannel") || strings.EqualFold(row.Type, "LiveTv") || strings.EqualFold(row.Type, "Channel") || strings.EqualFold(row.Type, "TvProgram") {
-				continue
-			}
-			// Exclude obviously invalid content types (e.g., Person)
-			if isDisallowedTopItemType(row.Type) {
-				continue
-			}
-			itemDetails[row.ItemID] = TopItem{ItemID: row.ItemID, Name: row.Name, Type: row.Type}
-			candidateIDs[row.ItemID] = struct{}{}
+	// 2. Build item details map and candidate set for precise duration calculation
+	combinedHours := make(map[string]float64)
+	itemDetails := make(map[string]TopItem)
+	candidateIDs := make(map[string]struct{})
+	for _, row := range historicalRows {
+		// Exclude Live TV content from candidates
+		if strings.EqualFold(row.Type, "TvChannel") || strings.EqualFold(row.Type, "LiveTv") || strings.EqualFold(row.Type, "Channel") || strings.EqualFold(row.Type, "TvProgram") {
+			continue
 		}
+		// Exclude obviously invalid content types (e.g., Person)
+		if isDisallowedTopItemType(row.Type) {
+			continue
+		}
+		itemDetails[row.ItemID] = TopItem{ItemID: row.ItemID, Name: row.Name, Type: row.Type}
+		candidateIDs[row.ItemID] = struct{}{}
+	}
 
-		// 2.5. Always supplement from play_intervals to include items missing from library_item.
-		// This is a broad query to ensure any item with any watch history is a candidate.
-		// The exact time clamping is handled robustly in computeExactItemHours.
-		{
-			intervalRows, err := db.Query(`
+	// 2.5. Always supplement from play_intervals to include items missing from library_item.
+	// This is a broad query to ensure any item with any watch history is a candidate.
+	// The exact time clamping is handled robustly in computeExactItemHours.
+	{
+		intervalRows, err := db.Query(`
                 SELECT DISTINCT l.item_id, 0.0 as hours
                 FROM play_intervals l
             `)
 
-			if err == nil {
-				defer intervalRows.Close()
-				missingItemIDs := []string{}
-
-				for intervalRows.Next() {
-					var itemID string
-					var hours float64
-					if err := intervalRows.Scan(&itemID, &hours); err == nil {
-						// Track as candidate; exact computation performed below
-						candidateIDs[itemID] = struct{}{}
-
-						// Ensure we have details; if missing in library_item, mark for fetch
-						if _, ok := itemDetails[itemID]; !ok {
-							var name, itemType string
-							scanErr := db.QueryRow("SELECT name, media_type FROM library_item WHERE id = ?", itemID).Scan(&name, &itemType)
-							if scanErr != nil {
-								missingItemIDs = append(missingItemIDs, itemID)
-								itemDetails[itemID] = TopItem{ItemID: itemID, Name: "Loading...", Type: "Unknown"}
-							} else {
-								itemDetails[itemID] = TopItem{ItemID: itemID, Name: name, Type: itemType}
-							}
+		if err == nil {
+			defer intervalRows.Close()
+			missingItemIDs := []string{}
+
+			for intervalRows.Next() {
+				var itemID string
+				var hours float64
+				if err := intervalRows.Scan(&itemID, &hours); err == nil {
+					// Track as candidate; exact computation performed below
+					candidateIDs[itemID] = struct{}{}
+
+					// Ensure we have details; if missing in library_item, mark for fetch
+					if _, ok := itemDetails[itemID]; !ok {
+						var name, itemType string
+						scanErr := db.QueryRow("SELECT name, media_type FROM library_item WHERE id = ?", itemID).Scan(&name, &itemType)
+						if scanErr != nil {
+							missingItemIDs = append(missingItemIDs, itemID)
+							itemDetails[itemID] = TopItem{ItemID: itemID, Name: "Loading...", Type: "Unknown"}
+						} else {
+							itemDetails[itemID] = TopItem{ItemID: itemID, Name: name, Type: itemType}
 						}
 					}
 				}
+			}
 
-				// Fetch missing items from Emby in batch for display (do not persist here; server_id context is unknown)
-				if len(missingItemIDs) > 0 && em != nil {
-					if embyItems, fetchErr := em.ItemsByIDs(missingItemIDs); fetchErr == nil {
-						for _, item := range embyItems {
-							itemDetails[item.Id] = TopItem{ItemID: item.Id, Name: item.Name, Type: item.Type}
-						}
+			// Fetch missing items from Emby in batch for display (do not persist here; server_id context is unknown)
+			if len(missingItemIDs) > 0 && em != nil {
+				if embyItems, fetchErr := em.ItemsByIDs(missingItemIDs); fetchErr == nil {
+					for _, item := range embyItems {
+						itemDetails[item.Id] = TopItem{ItemID: item.Id, Name: item.Name, Type: item.Type}
 					}
 				}
 			}
 		}
+	}
 
-		// 3. Compute exact, coalesced watch hours per candidate using per-session interval merging
-		exactHours, err := computeExactItemHours(db, keys(candidateIDs), winStart, winEnd)
-		if err != nil {
-			// Do not fail hard; log and continue with coarse hours
-			fmt.Printf("[WARN] TopItems exact hours computation failed: %v\n", err)
-			exactHours = map[string]float64{}
+	// 3. Compute exact, coalesced watch hours per candidate using per-session interval merging
+	exactHours, err := computeExactItemHours(db, keys(candidateIDs), winStart, winEnd)
+	if err != nil {
+		// Do not fail hard; log and continue with coarse hours
+		fmt.Printf("[WARN] TopItems exact hours computation failed: %v\n", err)
+		exactHours = map[string]float64{}
+	}
+	for id, hrs := range exactHours {
+		combinedHours[id] = hrs
+	}
+	groupWatches := countGroupWatches(db, keys(candidateIDs), winStart, winEnd)
+
+	// 4. Get live data and merge
+	liveWatchTimes := tasks.GetLiveItemWatchTimes() // Returns seconds
+	for itemID, seconds := range liveWatchTimes {
+		// Determine type to allow exclusion of Live TV
+		var name, itemType string
+		if det, ok := itemDetails[itemID]; ok {
+			name, itemType = det.Name, det.Type
+		} else {
+			_ = db.QueryRow("SELECT name, media_type FROM library_item WHERE id = ?", itemID).Scan(&name, &itemType)
+		}
+		if strings.EqualFold(itemType, "TvChannel") || strings.EqualFold(itemType, "LiveTv") || strings.EqualFold(itemType, "Channel") || strings.EqualFold(itemType, "TvProgram") {
+			continue // Skip live TV from Top Items
+		}
+
+		combinedHours[itemID] += seconds / 3600.0
+
+		// Ensure we have item details for display
+		if _, ok := itemDetails[itemID]; !ok {
+			if name == "" && em != nil {
+				if embyItems, fetchErr := em.ItemsByIDs([]string{itemID}); fetchErr == nil && len(embyItems) > 0 {
+					it := embyItems[0]
+					name = it.Name
+					itemType = it.Type
+				}
+			}
+			if name == "" {
+				name = fmt.Sprintf("Unknown Item (%s)", shortID(itemID))
+			}
+			if itemType == "" {
+				itemType = "Unknown"
+			}
+			itemDetails[itemID] = TopItem{ItemID: itemID, Name: name, Type: itemType}
+		}
+	}
+
+	// 5. Convert map back to slice
+	finalResult := make([]TopItem, 0, len(combinedHours))
+	for itemID, hours := range combinedHours {
+		if taggedIDs != nil && !taggedIDs[itemID] {
+			continue
+		}
+		details := itemDetails[itemID]
+		// Exclude Live TV types from final top items
+		if strings.EqualFold(details.Type, "TvChannel") || strings.EqualFold(details.Type, "LiveTv") || strings.EqualFold(details.Type, "Channel") || strings.EqualFold(details.Type, "TvProgram") {
+			continue
+		}
+		// Exclude disallowed entity types (e.g., Person, Album)
+		if isDisallowedTopItemType(details.Type) {
+			continue
 		}
-		for id, hrs := range exactHours {
-			combinedHours[id] = hrs
+		// Resolve server metadata for image routing and filtering
+		stype, sid := resolveServerMeta(db, itemID)
+		if !serverMatches(serverTypeFilter, serverIDFilter, stype, sid) {
+			continue
 		}
+		finalResult = append(finalResult, TopItem{
+			ItemID:       itemID,
+			Name:         details.Name,
+			Type:         details.Type,
+			Hours:        hours,
+			Display:      details.Name, // Default display before enrichment
+			ServerType:   stype,
+			ServerID:     sid,
+			GroupWatches: groupWatches[itemID],
+		})
+	}
 
-		// 4. Get live data and merge
-		liveWatchTimes := tasks.GetLiveItemWatchTimes() // Returns seconds
-		for itemID, seconds := range liveWatchTimes {
-			// Determine type to allow exclusion of Live TV
-			var name, itemType string
-			if det, ok := itemDetails[itemID]; ok {
-				name, itemType = det.Name, det.Type
-			} else {
-				_ = db.QueryRow("SELECT name, media_type FROM library_item WHERE id = ?", itemID).Scan(&name, &itemType)
-			}
-			if strings.EqualFold(itemType, "TvChannel") || strings.EqualFold(itemType, "LiveTv") || strings.EqualFold(itemType, "Channel") || strings.EqualFold(itemType, "TvProgram") {
-				continue // Skip live TV from Top Items
-			}
+	// 5.5. Count a title on several servers once
+	finalResult = foldCanonicalItems(ctx, db, finalResult)
 
-			combinedHours[itemID] += seconds / 3600.0
+	// 6. Sort and limit
+	sort.Slice(finalResult, func(i, j int) bool {
+		return finalResult[i].Hours > finalResult[j].Hours
+	})
+	if len(finalResult) > limit {
+		finalResult = finalResult[:limit]
+	}
 
-			// Ensure we have item details for display
-			if _, ok := itemDetails[itemID]; !ok {
-				if name == "" && em != nil {
-					if embyItems, fetchErr := em.ItemsByIDs([]string{itemID}); fetchErr == nil && len(embyItems) > 0 {
-						it := embyItems[0]
-						name = it.Name
-						itemType = it.Type
-					}
-				}
-				if name == "" {
-					name = fmt.Sprintf("Unknown Item (%s)", shortID(itemID))
-				}
-				if itemType == "" {
-					itemType = "Unknown"
-				}
-				itemDetails[itemID] = TopItem{ItemID: itemID, Name: name, Type: itemType}
-			}
-		}
+	// 7. Enrichment: prefer multi-server resolution first, then Emby fallback for display
+	if mgr := getMultiServerManager(); mgr != nil {
+		enrichItemsMulti(db, finalResult)
+	}
+	enrichItems(finalResult, em)
 
-		// 5. Convert map back to slice
-		finalResult := make([]TopItem, 0, len(combinedHours))
-		for itemID, hours := range combinedHours {
-			details := itemDetails[itemID]
-			// Exclude Live TV types from final top items
-			if strings.EqualFold(details.Type, "TvChannel") || strings.EqualFold(details.Type, "LiveTv") || strings.EqualFold(details.Type, "Channel") || strings.EqualFold(details.Type, "TvProgram") {
-				continue
-			}
-			// Exclude disallowed entity types (e.g., Person, Album)
-			if isDisallowedTopItemType(details.Type) {
-				continue
-			}
-			// Resolve server metadata for image routing and filtering
-			stype, sid := resolveServerMeta(db, itemID)
-			if serverTypeFilter != "" && strings.ToLower(strings.TrimSpace(stype)) != serverTypeFilter {
-				continue
-			}
-			if serverIDFilter != "" && !strings.EqualFold(strings.TrimSpace(sid), serverIDFilter) {
-				continue
+	// 7.5. Ensure sane display fallbacks after enrichment
+	for i := range finalResult {
+		if strings.TrimSpace(finalResult[i].Display) == "" {
+			if strings.TrimSpace(finalResult[i].Name) != "" {
+				finalResult[i].Display = finalResult[i].Name
+			} else {
+				finalResult[i].Display = fmt.Sprintf("Unknown Item (%s)", shortID(finalResult[i].ItemID))
 			}
-			finalResult = append(finalResult, TopItem{
-				ItemID:     itemID,
-				Name:       details.Name,
-				Type:       details.Type,
-				Hours:      hours,
-				Display:    details.Name, // Default display before enrichment
-				ServerType: stype,
-				ServerID:   sid,
-			})
-		}
-
-		// 6. Sort and limit
-		sort.Slice(finalResult, func(i, j int) bool {
-			return finalResult[i].Hours > finalResult[j].Hours
-		})
-		if len(finalResult) > limit {
-			finalResult = finalResult[:limit]
 		}
-
-		// 7. Enrichment: prefer multi-server resolution first, then Emby fallback for display
-		if mgr := getMultiServerManager(); mgr != nil {
-			enrichItemsMulti(db, finalResult)
+		if strings.TrimSpace(finalResult[i].Type) == "" {
+			finalResult[i].Type = "Unknown"
 		}
-		enrichItems(finalResult, em)
+	}
 
-		// 7.5. Ensure sane display fallbacks after enrichment
-		for i := range finalResult {
-			if strings.TrimSpace(finalResult[i].Display) == "" {
-				if strings.TrimSpace(finalResult[i].Name) != "" {
-					finalResult[i].Display = finalResult[i].Name
-				} else {
-					finalResult[i].Display = fmt.Sprintf("Unknown Item (%s)", shortID(finalResult[i].ItemID))
+	// 7.6. Final DB-based polish: for any remaining Unknown placeholders, try library_item one more time
+	for i := range finalResult {
+		if strings.HasPrefix(finalResult[i].Name, "Unknown Item (") || strings.HasPrefix(finalResult[i].Display, "Unknown Item (") {
+			var n, t string
+			_ = db.QueryRow("SELECT name, media_type FROM library_item WHERE id = ?", finalResult[i].ItemID).Scan(&n, &t)
+			if strings.TrimSpace(n) != "" && !strings.HasPrefix(n, "Unknown Item (") {
+				finalResult[i].Name = n
+				if strings.TrimSpace(finalResult[i].Display) == "" || strings.HasPrefix(finalResult[i].Display, "Unknown Item (") {
+					finalResult[i].Display = n
 				}
 			}
-			if strings.TrimSpace(finalResult[i].Type) == "" {
-				finalResult[i].Type = "Unknown"
+			if strings.TrimSpace(t) != "" && !strings.EqualFold(t, "Unknown") {
+				finalResult[i].Type = t
 			}
-		}
-
-		// 7.6. Final DB-based polish: for any remaining Unknown placeholders, try library_item one more time
-		for i := range finalResult {
-			if strings.HasPrefix(finalResult[i].Name, "Unknown Item (") || strings.HasPrefix(finalResult[i].Display, "Unknown Item (") {
-				var n, t string
-				_ = db.QueryRow("SELECT name, media_type FROM library_item WHERE id = ?", finalResult[i].ItemID).Scan(&n, &t)
-				if strings.TrimSpace(n) != "" && !strings.HasPrefix(n, "Unknown Item (") {
-					finalResult[i].Name = n
-					if strings.TrimSpace(finalResult[i].Display) == "" || strings.HasPrefix(finalResult[i].Display, "Unknown Item (") {
-						finalResult[i].Display = n
-					}
-				}
-				if strings.TrimSpace(t) != "" && !strings.EqualFold(t, "Unknown") {
-					finalResult[i].Type = t
-				}
-				// If still unknown, use most recent session name/type as a last resort
-				if strings.HasPrefix(finalResult[i].Name, "Unknown Item (") || strings.TrimSpace(finalResult[i].Name) == "" {
-					var sn, st string
-					_ = db.QueryRow(`
+			// If still unknown, use most recent session name/type as a last resort
+			if strings.HasPrefix(finalResult[i].Name, "Unknown Item (") || strings.TrimSpace(finalResult[i].Name) == "" {
+				var sn, st string
+				_ = db.QueryRow(`
                         SELECT item_name, item_type
                         FROM play_sessions
                         WHERE item_id = ?
                         ORDER BY started_at DESC
                         LIMIT 1
                     `, finalResult[i].ItemID).Scan(&sn, &st)
-					if strings.TrimSpace(sn) != "" {
-						finalResult[i].Name = sn
-						if strings.TrimSpace(finalResult[i].Display) == "" || strings.HasPrefix(finalResult[i].Display, "Unknown Item (") {
-							finalResult[i].Display = sn
-						}
-					}
-					if strings.TrimSpace(st) != "" && !strings.EqualFold(st, "Unknown") {
-						finalResult[i].Type = st
+				if strings.TrimSpace(sn) != "" {
+					finalResult[i].Name = sn
+					if strings.TrimSpace(finalResult[i].Display) == "" || strings.HasPrefix(finalResult[i].Display, "Unknown Item (") {
+						finalResult[i].Display = sn
 					}
 				}
+				if strings.TrimSpace(st) != "" && !strings.EqualFold(st, "Unknown") {
+					finalResult[i].Type = st
+				}
 			}
 		}
+	}
+
+	return finalResult, nil
+}
 
-		return c.JSON(finalResult)
+// foldCanonicalItems sums the copies of a title that canonical_item groups together into
+// the row of the copy with the most hours.
+func foldCanonicalItems(ctx context.Context, db *sql.DB, items []TopItem) []TopItem {
+	canonical, err := queries.CanonicalItemIDs(ctx, db)
+	if err != nil || len(canonical) == 0 {
+		return items
+	}
+	out := make([]TopItem, 0, len(items))
+	byGroup := map[string]int{}
+	best := map[string]float64{}
+	for _, it := range items {
+		group, ok := canonical[it.ItemID]
+		if !ok {
+			out = append(out, it)
+			continue
+		}
+		i, seen := byGroup[group]
+		if !seen {
+			it.ItemIDs = []string{it.ItemID}
+			byGroup[group] = len(out)
+			best[group] = it.Hours
+			out = append(out, it)
+			continue
+		}
+		row := &out[i]
+		row.ItemIDs = append(row.ItemIDs, it.ItemID)
+		row.Hours += it.Hours
+		row.GroupWatches += it.GroupWatches
+		if it.Hours > best[group] {
+			best[group] = it.Hours
+			row.ItemID, row.Name, row.Display = it.ItemID, it.Name, it.Display
+			row.ServerType, row.ServerID = it.ServerType, it.ServerID
+		}
+	}
+	for _, i := range byGroup {
+		if len(out[i].ItemIDs) < 2 {
+			out[i].ItemIDs = nil
+			continue
+		}
+		sort.Strings(out[i].ItemIDs)
 	}
+	return out
 }
 
 // shortID returns a safe short prefix of an ID for display.
@@ -370,8 +398,21 @@ type interval struct {
 	e int64
 }
 
+// syncPlayPrimaryFilter restricts play_sessions (aliased ps) to one member per SyncPlay group and item,
+// so a group watch contributes once instead of once per participant. The member with the most recorded
+// watch time is kept (the first to join on a tie): whoever joined late or left early would undercount it.
+const syncPlayPrimaryFilter = `
+          AND (COALESCE(ps.syncplay_group_id, '') = ''
+               OR ps.id = (SELECT g.id FROM play_sessions g
+                           LEFT JOIN play_intervals gi ON gi.session_fk = g.id
+                           WHERE g.syncplay_group_id = ps.syncplay_group_id AND g.item_id = ps.item_id
+                           GROUP BY g.id
+                           ORDER BY COALESCE(SUM(gi.duration_seconds), 0) DESC, g.id
+                           LIMIT 1))`
+
 // computeExactItemHours merges overlapping intervals per session for the given item IDs and window.
 // It returns total hours per item, clamped to [winStart, winEnd].
+// When count_group_watch_once is enabled, only one member of each SyncPlay group is counted.
 func computeExactItemHours(db *sql.DB, itemIDs []string, winStart, winEnd int64) (map[string]float64, error) {
 	out := make(map[string]float64)
 	if len(itemIDs) == 0 {
@@ -412,14 +453,19 @@ func computeExactItemHours(db *sql.DB, itemIDs []string, winStart, winEnd int64)
 	}
 	args = append(args, winEnd, winStart) // for clamp and filter
 
+	groupFilter := ""
+	if settings.GetSettingBool(db, "count_group_watch_once", false) {
+		groupFilter = syncPlayPrimaryFilter
+	}
+
 	query := fmt.Sprintf(`
         SELECT pi.item_id, ps.session_id, pi.start_ts, pi.end_ts, pi.duration_seconds
         FROM play_intervals pi
         JOIN play_sessions ps ON ps.id = pi.session_fk
         WHERE pi.item_id IN (%s)
-          AND pi.start_ts <= ? AND pi.end_ts >= ?
+          AND pi.start_ts <= ? AND pi.end_ts >= ?%s
         ORDER BY pi.item_id, ps.session_id, pi.start_ts, pi.end_ts
-    `, strings.Join(placeholders, ","))
+    `, strings.Join(placeholders, ","), groupFilter)
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
@@ -475,6 +521,43 @@ func computeExactItemHours(db *sql.DB, itemIDs []string, winStart, winEnd int64)
 	return out, nil
 }
 
+// countGroupWatches returns the number of distinct SyncPlay groups per item that started within the window.
+// Errors are swallowed; the annotation is informational only.
+func countGroupWatches(db *sql.DB, itemIDs []string, winStart, winEnd int64) map[string]int {
+	out := make(map[string]int)
+	if len(itemIDs) == 0 {
+		return out
+	}
+	placeholders := make([]string, len(itemIDs))
+	args := make([]any, 0, len(itemIDs)+2)
+	for i, id := range itemIDs {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+	args = append(args, winStart, winEnd)
+	q := fmt.Sprintf(`
+        SELECT item_id, COUNT(DISTINCT syncplay_group_id)
+        FROM play_sessions
+        WHERE item_id IN (%s)
+          AND COALESCE(syncplay_group_id, '') <> ''
+          AND started_at >= ? AND started_at <= ?
+        GROUP BY item_id
+    `, strings.Join(placeholders, ","))
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return out
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err == nil && n > 0 {
+			out[id] = n
+		}
+	}
+	return out
+}
+
 // Your original enrichment logic, now in a helper function for clarity.
 func enrichItems(items []TopItem, em *emby.Client) {
 	allEnrichIDs := make([]string, 0)
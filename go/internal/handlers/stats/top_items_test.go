@@ -0,0 +1,75 @@
+package stats
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"emby-analytics/internal/db"
+)
+
+// openSyncPlayDB migrates a fresh SQLite file with one SyncPlay group: alice and bob watch
+// Movie A together, bob for longer, while carol is in the group but plays Movie B.
+func openSyncPlayDB(t *testing.T) *sql.DB {
+	t.Helper()
+	path := filepath.ToSlash(filepath.Join(t.TempDir(), "stats.db"))
+	if err := db.MigrateUp(fmt.Sprintf("sqlite://file:%s?mode=rwc", path)); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	conn, err := db.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	stmts := []string{
+		`INSERT INTO play_sessions (id, user_id, session_id, item_id, item_name, device_id, client_name, started_at, ended_at, is_active, syncplay_group_id)
+		 VALUES (1, 'alice', 'sa', 'movie-a', 'Movie A', 'd1', 'Web', 1000, 2800, 0, 'g1'),
+		        (2, 'bob', 'sb', 'movie-a', 'Movie A', 'd2', 'Web', 1000, 4600, 0, 'g1'),
+		        (3, 'carol', 'sc', 'movie-b', 'Movie B', 'd3', 'Web', 1000, 1900, 0, 'g1')`,
+		`INSERT INTO play_intervals (session_fk, item_id, user_id, start_ts, end_ts, start_pos_ticks, end_pos_ticks, duration_seconds, seeked)
+		 VALUES (1, 'movie-a', 'alice', 1000, 2800, 0, 0, 1800, 0),
+		        (2, 'movie-a', 'bob', 1000, 4600, 0, 0, 3600, 0),
+		        (3, 'movie-b', 'carol', 1000, 1900, 0, 0, 900, 0)`,
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			t.Fatalf("seed: %v\n%s", err, s)
+		}
+	}
+	return conn
+}
+
+func TestComputeExactItemHoursGroupWatch(t *testing.T) {
+	conn := openSyncPlayDB(t)
+	items := []string{"movie-a", "movie-b"}
+
+	hours, err := computeExactItemHours(conn, items, 0, 10000)
+	if err != nil {
+		t.Fatalf("compute: %v", err)
+	}
+	if math.Abs(hours["movie-a"]-1.5) > 1e-6 {
+		t.Errorf("movie-a without the setting: got %vh, want 1.5h (every member)", hours["movie-a"])
+	}
+
+	if _, err := conn.Exec(`INSERT INTO app_settings (key, value) VALUES ('count_group_watch_once', 'true')`); err != nil {
+		t.Fatalf("setting: %v", err)
+	}
+	hours, err = computeExactItemHours(conn, items, 0, 10000)
+	if err != nil {
+		t.Fatalf("compute: %v", err)
+	}
+	if math.Abs(hours["movie-a"]-1.0) > 1e-6 {
+		t.Errorf("movie-a: got %vh, want 1h (bob, the longest member)", hours["movie-a"])
+	}
+	if math.Abs(hours["movie-b"]-0.25) > 1e-6 {
+		t.Errorf("movie-b: got %vh, want 0.25h (carol is not merged into the Movie A watch)", hours["movie-b"])
+	}
+
+	groups := countGroupWatches(conn, items, 0, 10000)
+	if groups["movie-a"] != 1 || groups["movie-b"] != 1 {
+		t.Errorf("group watches = %v, want one per item", groups)
+	}
+}
@@ -49,24 +49,24 @@ func TopSeries(db *sql.DB) fiber.Handler {
 			winEnd = now.AddDate(100, 0, 0).Unix()
 		}
 
-		// Prefer series_id grouping when available, otherwise group by derived series name.
-		// Sum overlap within window using MIN/MAX clamp.
+		// Group by series_id; sum overlap within window using MIN/MAX clamp.
 		rows, err := db.Query(`
             WITH iv AS (
                 SELECT 
-                    COALESCE(li.series_id, '') AS sid,
-                    COALESCE(li.series_name, CASE WHEN INSTR(li.name,' - ')>0 THEN SUBSTR(li.name,1,INSTR(li.name,' - ')-1) ELSE li.name END) AS sname,
+                    li.series_id AS sid,
+                    `+seriesNameSQL+` AS sname,
                     MIN(pi.end_ts, ?) - MAX(pi.start_ts, ?) AS overlap
                 FROM play_intervals pi
                 JOIN library_item li ON li.id = pi.item_id
-                WHERE li.media_type='Episode' AND `+excludeLiveTvFilter()+`
+                `+seriesJoin+`
+                WHERE li.media_type='Episode' AND `+excludeLiveTvFilterAlias("li")+` AND `+hasSeriesIDSQL+`
                   AND pi.start_ts <= ? AND pi.end_ts >= ?
                 GROUP BY pi.id
             )
-            SELECT sid, sname, SUM(CASE WHEN overlap>0 THEN overlap ELSE 0 END) / 3600.0 AS hours
+            SELECT sid, MAX(sname), SUM(CASE WHEN overlap>0 THEN overlap ELSE 0 END) / 3600.0 AS hours
             FROM iv
-            WHERE sname IS NOT NULL AND sname <> ''
-            GROUP BY sid, sname
+            GROUP BY sid
+            HAVING MAX(sname) IS NOT NULL
             ORDER BY hours DESC
             LIMIT ?
         `, winEnd, winStart, winEnd, winStart, limit)
@@ -1,12 +1,16 @@
 package stats
 
 import (
+	"context"
 	"database/sql"
 	"emby-analytics/internal/handlers/settings"
+	"emby-analytics/internal/logging"
 	"emby-analytics/internal/media"
 	"emby-analytics/internal/queries"
 	"emby-analytics/internal/tasks"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
@@ -18,179 +22,210 @@ type TopUser struct {
 	ServerID   string  `json:"server_id"`
 	ServerName string  `json:"server_name"`
 	Hours      float64 `json:"hours"`
+	// PersonID and UserIDs are set when the row sums the accounts of a mapped person; the
+	// fields above then describe the account with the most hours, with the person's name
+	PersonID string   `json:"person_id,omitempty"`
+	UserIDs  []string `json:"user_ids,omitempty"`
 }
 
 func TopUsers(db *sql.DB, mgr *media.MultiServerManager) fiber.Handler {
 	return func(c fiber.Ctx) error {
-		// --- Parameter Parsing ---
-		timeframe := c.Query("timeframe", "")
-		if timeframe == "" {
-			// Fallback to days parameter if timeframe not provided
-			days := parseQueryInt(c, "days", 14)
-			if days <= 0 {
-				timeframe = "all-time"
-			} else if days == 1 {
-				timeframe = "1d"
-			} else if days == 3 {
-				timeframe = "3d"
-			} else if days == 7 {
-				timeframe = "7d"
-			} else if days == 14 {
-				timeframe = "14d"
-			} else if days == 30 {
-				timeframe = "30d"
-			} else {
-				timeframe = "30d" // Default for large day values
-			}
-		}
-		if timeframe == "" {
-			timeframe = "14d" // Final fallback
-		}
-
+		timeframe := timeframeParam(c)
 		limit := parseQueryInt(c, "limit", 10)
 		if limit <= 0 || limit > 100 {
 			limit = 10
 		}
+		out, err := topUsersData(c, db, mgr, timeframe, limit, c.Query("server", ""))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(anonymizeTopUsers(out, viewerMask(c, db)))
+	}
+}
 
-		// --- "All-Time" Logic with dynamic Trakt calculation ---
-		if timeframe == "all-time" {
-			// Get the setting for whether to include Trakt items
-			includeTrakt := settings.GetSettingBool(db, "include_trakt_items", false)
-
-			rows, err := db.Query(`
-				SELECT
-					u.id,
-					u.name,
-					u.server_id,
-					CASE WHEN ? = 1 THEN 
-						COALESCE((lw.emby_ms + lw.trakt_ms) / 3600000.0, 0)
-					ELSE 
-						COALESCE(lw.emby_ms / 3600000.0, 0) 
-					END AS hours
-				FROM emby_user u
-				LEFT JOIN lifetime_watch lw ON lw.user_id = u.id
-				WHERE (lw.emby_ms > 0 OR lw.trakt_ms > 0) AND u.deleted_at IS NULL
-				ORDER BY hours DESC
-				LIMIT ?;
-			`, includeTrakt, limit)
-			if err != nil {
-				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
-			}
-			defer rows.Close()
-
-			out := []TopUser{}
-			configs := mgr.GetServerConfigs()
-			for rows.Next() {
-				var u TopUser
-				if err := rows.Scan(&u.UserID, &u.Name, &u.ServerID, &u.Hours); err != nil {
-					return c.Status(500).JSON(fiber.Map{"error": err.Error()})
-				}
-				if cfg, ok := configs[u.ServerID]; ok {
-					u.ServerName = cfg.Name
-				} else {
-					u.ServerName = u.ServerID
-				}
-				out = append(out, u)
-			}
-			return c.JSON(out)
+// timeframeParam reads ?timeframe=, falling back to the legacy ?days= parameter.
+func timeframeParam(c fiber.Ctx) string {
+	timeframe := c.Query("timeframe", "")
+	if timeframe == "" {
+		// Fallback to days parameter if timeframe not provided
+		days := parseQueryInt(c, "days", 14)
+		if days <= 0 {
+			timeframe = "all-time"
+		} else if days == 1 {
+			timeframe = "1d"
+		} else if days == 3 {
+			timeframe = "3d"
+		} else if days == 7 {
+			timeframe = "7d"
+		} else if days == 14 {
+			timeframe = "14d"
+		} else if days == 30 {
+			timeframe = "30d"
+		} else {
+			timeframe = "30d" // Default for large day values
 		}
+	}
+	if timeframe == "" {
+		timeframe = "14d" // Final fallback
+	}
+	return timeframe
+}
+
+// topUsersData ranks users by watch hours in timeframe, including live sessions. Accounts
+// mapped to the same person are ranked together. rawServer is the optional ?server= filter.
+func topUsersData(ctx context.Context, db *sql.DB, mgr *media.MultiServerManager, timeframe string, limit int, rawServer string) ([]TopUser, error) {
+	serverType, serverID := normalizeServerParam(rawServer)
 
-		// --- Live-Aware Time-Windowed Logic ---
-		days := parseTimeframeToDays(timeframe)
-		now := time.Now().UTC()
-		winEnd := now.Unix()
-		winStart := now.AddDate(0, 0, -days).Unix()
+	// --- "All-Time" Logic with dynamic Trakt calculation ---
+	if timeframe == "all-time" {
+		// Get the setting for whether to include Trakt items
+		includeTrakt := settings.GetSettingBool(db, "include_trakt_items", false)
 
-		// 1. Get historical data from the database (fetch a high number to merge before limiting)
-		historicalRows, err := queries.TopUsersByWatchSeconds(c, db, winStart, winEnd, 1000)
+		rows, err := queries.TopUsersAllTime(ctx, db, includeTrakt, serverType, serverID, 1000)
 		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			return nil, err
 		}
 
-		if err != nil || len(historicalRows) == 0 {
-			// Fallback to counting sessions if intervals aren't populated
-			rows, err := db.Query(`
-        SELECT 
-            u.id, 
-            u.name, 
-            COUNT(DISTINCT ps.id) * 0.5 as hours
-        FROM emby_user u
-        LEFT JOIN play_sessions ps ON ps.user_id = u.id
-        LEFT JOIN library_item li ON li.id = ps.item_id
-        WHERE ps.started_at >= ? AND ps.started_at <= ?
-          AND (li.id IS NULL OR `+excludeLiveTvFilter()+`)
-        GROUP BY u.id, u.name
-        ORDER BY hours DESC
-        LIMIT ?
-    `, winStart, winEnd, 1000)
-
-			if err == nil {
-				defer rows.Close()
-				historicalRows = []queries.TopUserRow{}
-				for rows.Next() {
-					var r queries.TopUserRow
-					if err := rows.Scan(&r.UserID, &r.Name, &r.ServerID, &r.Hours); err == nil {
-						historicalRows = append(historicalRows, r)
-					}
-				}
+		out := make([]TopUser, 0, len(rows))
+		configs := mgr.GetServerConfigs()
+		for _, r := range rows {
+			u := TopUser{UserID: r.UserID, Name: r.Name, ServerID: r.ServerID, Hours: r.Hours}
+			if cfg, ok := configs[u.ServerID]; ok {
+				u.ServerName = cfg.Name
+			} else {
+				u.ServerName = u.ServerID
 			}
+			out = append(out, u)
 		}
+		return limitTopUsers(mergePeople(ctx, db, out, configs), limit), nil
+	}
 
-		// 2. Prepare to combine historical and live data
-		combinedHours := make(map[string]float64)
-		userNames := make(map[string]string)
-		userServers := make(map[string]string)
+	// --- Live-Aware Time-Windowed Logic ---
+	days := parseTimeframeToDays(timeframe)
+	now := time.Now().UTC()
+	winEnd := now.Unix()
+	winStart := now.AddDate(0, 0, -days).Unix()
 
-		for _, row := range historicalRows {
-			combinedHours[row.UserID] += row.Hours
-			userNames[row.UserID] = row.Name
-			userServers[row.UserID] = row.ServerID
+	// 1. Get historical data from the database (fetch a high number to merge before limiting)
+	historicalRows, err := queries.TopUsersByWatchSeconds(ctx, db, winStart, winEnd, serverType, serverID, 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(historicalRows) == 0 {
+		// Fallback to counting sessions if intervals aren't populated
+		if fallback, ferr := queries.TopUsersBySessionCount(ctx, db, winStart, winEnd, serverType, serverID, 1000); ferr == nil {
+			historicalRows = fallback
 		}
+	}
 
-		// 3. Get live data from the Intervalizer and merge it
-		// Live contribution (exclude LiveTV)
-		liveWatchTimes := tasks.GetLiveUserWatchTimesExcludingLiveTV() // Returns seconds
-		for userID, seconds := range liveWatchTimes {
-			combinedHours[userID] += seconds / 3600.0 // Convert seconds to hours
-			// Ensure we have a username, even if the user only has a live session
-			if _, ok := userNames[userID]; !ok {
-				var name string
-				// This query is fast and only runs for new users with live sessions
-				_ = db.QueryRow("SELECT name FROM emby_user WHERE id = ?", userID).Scan(&name)
-				userNames[userID] = name
-			}
+	// 2. Prepare to combine historical and live data
+	combinedHours := make(map[string]float64)
+	userNames := make(map[string]string)
+	userServers := make(map[string]string)
+
+	for _, row := range historicalRows {
+		combinedHours[row.UserID] += row.Hours
+		userNames[row.UserID] = row.Name
+		userServers[row.UserID] = row.ServerID
+	}
+
+	// 3. Get live data from the Intervalizer and merge it
+	// Live contribution (exclude LiveTV)
+	liveWatchTimes := tasks.GetLiveUserWatchTimesExcludingLiveTV() // Returns seconds
+	userWhere, userArgs := appendServerFilter("id = ? AND exclude_from_stats = 0", "", serverType, serverID)
+	for userID, seconds := range liveWatchTimes {
+		combinedHours[userID] += seconds / 3600.0 // Convert seconds to hours
+		// Ensure we have a username, even if the user only has a live session; users of other
+		// servers than the one filtered on get none and are left out below
+		if _, ok := userNames[userID]; !ok {
+			var name, userServer string
+			// This query is fast and only runs for new users with live sessions
+			_ = db.QueryRow("SELECT name, COALESCE(server_id, '') FROM emby_user WHERE "+userWhere,
+				append([]any{userID}, userArgs...)...).Scan(&name, &userServer)
+			userNames[userID] = name
+			userServers[userID] = userServer
 		}
+	}
 
-		// 4. Convert the combined map back to a slice for sorting
-		configs := mgr.GetServerConfigs()
-		finalResult := make([]TopUser, 0, len(combinedHours))
-		for userID, hours := range combinedHours {
-			if userNames[userID] != "" { // Only include users we have a name for
-				serverID := userServers[userID]
-				serverName := serverID
-				if cfg, ok := configs[serverID]; ok {
-					serverName = cfg.Name
-				}
-				finalResult = append(finalResult, TopUser{
-					UserID:     userID,
-					Name:       userNames[userID],
-					ServerID:   serverID,
-					ServerName: serverName,
-					Hours:      hours,
-				})
+	// 4. Convert the combined map back to a slice for sorting
+	configs := mgr.GetServerConfigs()
+	finalResult := make([]TopUser, 0, len(combinedHours))
+	for userID, hours := range combinedHours {
+		if userNames[userID] != "" { // Only include users we have a name for
+			serverID := userServers[userID]
+			serverName := serverID
+			if cfg, ok := configs[serverID]; ok {
+				serverName = cfg.Name
 			}
+			finalResult = append(finalResult, TopUser{
+				UserID:     userID,
+				Name:       userNames[userID],
+				ServerID:   serverID,
+				ServerName: serverName,
+				Hours:      hours,
+			})
 		}
+	}
+
+	// 5. Sum up the accounts of mapped people, sort by hours and apply the final limit
+	return limitTopUsers(mergePeople(ctx, db, finalResult, configs), limit), nil
+}
 
-		// 5. Sort the final combined list by hours, descending
-		sort.Slice(finalResult, func(i, j int) bool {
-			return finalResult[i].Hours > finalResult[j].Hours
-		})
+// limitTopUsers sorts users by hours, descending, and keeps the first limit.
+func limitTopUsers(users []TopUser, limit int) []TopUser {
+	sort.SliceStable(users, func(i, j int) bool {
+		return users[i].Hours > users[j].Hours
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users
+}
 
-		// 6. Apply the final limit
-		if len(finalResult) > limit {
-			finalResult = finalResult[:limit]
+// mergePeople folds the accounts of each mapped person into one row, keeping the account
+// with the most hours as the row's user and naming the servers of all of them.
+func mergePeople(ctx context.Context, db *sql.DB, users []TopUser, configs map[string]media.ServerConfig) []TopUser {
+	people, err := queries.UserPeople(ctx, db)
+	if err != nil {
+		logging.Warn("failed to load user mappings; ranking accounts separately", "error", err)
+		return users
+	}
+	if len(people) == 0 {
+		return users
+	}
+	out := make([]TopUser, 0, len(users))
+	byPerson := map[string]int{}
+	best := map[string]float64{}     // hours of the row's account
+	servers := map[string][]string{} // server names of the person's accounts
+	for _, u := range users {
+		ref, ok := people[u.UserID]
+		if !ok {
+			out = append(out, u)
+			continue
 		}
-
-		return c.JSON(finalResult)
+		if !slices.Contains(servers[ref.PersonID], u.ServerName) {
+			servers[ref.PersonID] = append(servers[ref.PersonID], u.ServerName)
+		}
+		i, seen := byPerson[ref.PersonID]
+		if !seen {
+			u.PersonID, u.Name, u.UserIDs = ref.PersonID, ref.Name, []string{u.UserID}
+			byPerson[ref.PersonID] = len(out)
+			best[ref.PersonID] = u.Hours
+			out = append(out, u)
+			continue
+		}
+		p := &out[i]
+		p.UserIDs = append(p.UserIDs, u.UserID)
+		p.Hours += u.Hours
+		if u.Hours > best[ref.PersonID] {
+			best[ref.PersonID] = u.Hours
+			p.UserID, p.ServerID = u.UserID, u.ServerID
+		}
+	}
+	for personID, i := range byPerson {
+		sort.Strings(out[i].UserIDs)
+		out[i].ServerName = strings.Join(servers[personID], ", ")
 	}
+	return out
 }
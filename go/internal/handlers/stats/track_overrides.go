@@ -0,0 +1,35 @@
+package stats
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// GET /stats/track-overrides?days=30&limit=50&item_id=
+// Per item, how often sessions started in the last `days` overrode the default tracks:
+// switched audio, toggled or changed subtitles, or started on a non-default audio track.
+// Only sessions recorded with track information count.
+func TrackOverridesHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 30)
+		if days <= 0 || days > 3650 {
+			days = 30
+		}
+		limit := parseQueryInt(c, "limit", 50)
+		if limit <= 0 || limit > 500 {
+			limit = 50
+		}
+		since := time.Now().AddDate(0, 0, -days).Unix()
+
+		items, err := queries.TrackOverrides(c, db, since, strings.TrimSpace(c.Query("item_id")), limit)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"days": days, "items": items})
+	}
+}
@@ -0,0 +1,25 @@
+package stats
+
+import (
+	"database/sql"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Trending returns items ordered by trending score (exponentially decayed watch hours) as computed by the rollup job
+func Trending(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		limit := parseQueryInt(c, "limit", 20)
+		if limit <= 0 || limit > 100 {
+			limit = 20
+		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		items, err := queries.TrendingItems(c, db, serverType, serverID, limit)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(items)
+	}
+}
@@ -2,11 +2,13 @@ package stats
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
 
 	"emby-analytics/internal/media"
+	"emby-analytics/internal/queries"
 )
 
 type UsageRow struct {
@@ -15,6 +17,7 @@ type UsageRow struct {
 	ServerID   string  `json:"server_id"`
 	ServerName string  `json:"server_name"`
 	Hours      float64 `json:"hours"`
+	userID     string  // for viewer masking, see anonymizeUsage
 }
 
 func Usage(db *sql.DB, mgr *media.MultiServerManager) fiber.Handler {
@@ -23,65 +26,102 @@ func Usage(db *sql.DB, mgr *media.MultiServerManager) fiber.Handler {
 		if days <= 0 {
 			days = 14
 		}
+		serverType, serverID := normalizeServerParam(c.Query("server", ""))
+		out, err := usageRows(db, mgr, days, serverType, serverID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(anonymizeUsage(out, viewerMask(c, db)))
+	}
+}
 
-		now := time.Now().UTC()
-		winEnd := now.Unix()
-		winStart := now.AddDate(0, 0, -days).Unix()
+// usageRows returns watch hours per day and user over the last days days, for the users of
+// the servers serverType/serverID select (both empty for every server).
+func usageRows(db *sql.DB, mgr *media.MultiServerManager, days int, serverType, serverID string) ([]UsageRow, error) {
+	now := time.Now().UTC()
+	winEnd := now.Unix()
+	winStart := now.AddDate(0, 0, -days).Unix()
 
-		// CORRECTED & SIMPLIFIED: This query correctly calculates the overlap
-		// duration for each interval within the window and then sums it up per day and user.
-		query := `
-            SELECT
-                strftime('%Y-%m-%d', datetime(pi.start_ts, 'unixepoch')) AS day,
-                u.name,
-                u.server_id,
-                SUM(
-                    MAX(
-                        0,
-                        MIN(
-                            MIN(pi.end_ts, ?) - MAX(pi.start_ts, ?),
-                            CASE WHEN pi.duration_seconds IS NULL OR pi.duration_seconds <= 0
-                                 THEN (pi.end_ts - pi.start_ts)
-                                 ELSE pi.duration_seconds
-                            END
-                        )
+	// CORRECTED & SIMPLIFIED: This query correctly calculates the overlap
+	// duration for each interval within the window and then sums it up per day and user.
+	where, serverArgs := appendServerFilter(`pi.start_ts <= ? AND pi.end_ts >= ?
+            AND COALESCE(li.media_type, 'Unknown') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')`, "u", serverType, serverID)
+	query := `
+        SELECT
+            strftime('%Y-%m-%d', datetime(pi.start_ts, 'unixepoch')) AS day,
+            u.id,
+            u.name,
+            u.server_id,
+            SUM(
+                MAX(
+                    0,
+                    MIN(
+                        MIN(pi.end_ts, ?) - MAX(pi.start_ts, ?),
+                        CASE WHEN pi.duration_seconds IS NULL OR pi.duration_seconds <= 0
+                             THEN (pi.end_ts - pi.start_ts)
+                             ELSE pi.duration_seconds
+                        END
                     )
-                ) / 3600.0 AS hours
-            FROM play_intervals pi
-            JOIN emby_user u ON u.id = pi.user_id AND u.deleted_at IS NULL
-            LEFT JOIN library_item li ON li.id = pi.item_id
-            WHERE
-                pi.start_ts <= ? AND pi.end_ts >= ?
-                AND COALESCE(li.media_type, 'Unknown') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
-            GROUP BY day, u.name, u.server_id
-            ORDER BY day ASC, u.name ASC;
-        `
+                )
+            ) / 3600.0 AS hours
+        FROM play_intervals pi
+        JOIN emby_user u ON u.id = pi.user_id AND u.deleted_at IS NULL AND u.exclude_from_stats = 0
+        LEFT JOIN library_item li ON li.id = pi.item_id
+        WHERE
+            ` + where + `
+        GROUP BY day, u.id, u.name, u.server_id
+        ORDER BY day ASC, u.name ASC;
+    `
 
-		rows, err := db.Query(query, winEnd, winStart, winEnd, winStart)
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "usage query failed: " + err.Error()})
+	rows, err := db.Query(query, append([]any{winEnd, winStart, winEnd, winStart}, serverArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("usage query failed: %w", err)
+	}
+	defer rows.Close()
+
+	out := []UsageRow{}
+	for rows.Next() {
+		var r UsageRow
+		if err := rows.Scan(&r.Day, &r.userID, &r.User, &r.ServerID, &r.Hours); err != nil {
+			return nil, fmt.Errorf("failed to scan usage row: %w", err)
 		}
-		defer rows.Close()
+		out = append(out, r)
+	}
 
-		out := []UsageRow{}
-		for rows.Next() {
-			var r UsageRow
-			if err := rows.Scan(&r.Day, &r.User, &r.ServerID, &r.Hours); err != nil {
-				return c.Status(500).JSON(fiber.Map{"error": "failed to scan usage row: " + err.Error()})
-			}
-		    out = append(out, r)
+	// Fill in server names
+	configs := mgr.GetServerConfigs()
+	for i := range out {
+		if cfg, ok := configs[out[i].ServerID]; ok {
+			out[i].ServerName = cfg.Name
+		} else {
+			out[i].ServerName = out[i].ServerID
+		}
+	}
+	return out, nil
+}
+
+// UsageVersionMarkers returns server software version changes within the usage window so
+// the usage chart can mark the days a server was updated.
+// Query params: days (default 14), server (server ID, optional).
+// GET /stats/usage/version-markers
+func UsageVersionMarkers(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		days := parseQueryInt(c, "days", 14)
+		if days <= 0 {
+			days = 14
 		}
-		
-		// Fill in server names
-		configs := mgr.GetServerConfigs()
-		for i := range out {
-			if cfg, ok := configs[out[i].ServerID]; ok {
-				out[i].ServerName = cfg.Name
-			} else {
-				out[i].ServerName = out[i].ServerID
+		since := time.Now().UTC().AddDate(0, 0, -days).Unix()
+		out, err := queries.ServerVersionChanges(c, db, c.Query("server", ""), since)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		// The first recorded version is a baseline, not an update
+		markers := out[:0]
+		for _, v := range out {
+			if v.PreviousVersion != "" {
+				markers = append(markers, v)
 			}
 		}
-
-		return c.JSON(out)
+		return c.JSON(markers)
 	}
 }
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"emby-analytics/internal/emby"
+	"emby-analytics/internal/middleware"
+	"emby-analytics/internal/queries"
 
 	"github.com/gofiber/fiber/v3"
 )
@@ -39,9 +41,14 @@ type UserDetail struct {
 	LastSeenMovies      []UserTopItem  `json:"last_seen_movies"`
 	LastSeenEpisodes    []UserTopItem  `json:"last_seen_episodes"`
 	FinishedSeries      []UserTopItem  `json:"finished_series"`
+
+	Devices   []queries.UserDevice  `json:"devices"`
+	IPHistory []queries.UserAddress `json:"ip_history,omitempty"` // admins only
 }
 
 // GET /stats/users/:id?days=30&limit=10
+// devices and ip_history cover the user's whole history, so admins can spot a shared
+// account by its devices and addresses; ip_history is left out for non-admins.
 func UserDetailHandler(db *sql.DB, em *emby.Client) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		userID := c.Params("id", "")
@@ -73,6 +80,7 @@ func UserDetailHandler(db *sql.DB, em *emby.Client) fiber.Handler {
 			LastSeenMovies:      []UserTopItem{},
 			LastSeenEpisodes:    []UserTopItem{},
 			FinishedSeries:      []UserTopItem{},
+			Devices:             []queries.UserDevice{},
 		}
 
 		// user name
@@ -160,20 +168,14 @@ func UserDetailHandler(db *sql.DB, em *emby.Client) fiber.Handler {
 			AND ps.ended_at IS NOT NULL
 		`, userID).Scan(&detail.TotalEpisodes)
 
-		// Get total series finished - simplified approach without series_id
-		// For now, we'll count unique series names that the user has watched episodes from
+		// Get total series the user has watched episodes from
 		_ = db.QueryRow(`
-			SELECT COUNT(DISTINCT 
-				CASE 
-					WHEN li.name LIKE '%-%' THEN SUBSTR(li.name, 1, INSTR(li.name, ' - ') - 1)
-					ELSE NULL
-				END
-			)
+			SELECT COUNT(DISTINCT li.series_id)
 			FROM play_sessions ps
 			JOIN library_item li ON li.id = ps.item_id
 			WHERE ps.user_id = ? AND (`+episodeMediaPredicate("li")+`)
 			AND ps.ended_at IS NOT NULL
-			AND li.name LIKE '%-%'
+			AND `+hasSeriesIDSQL+`
 		`, userID).Scan(&detail.TotalSeriesFinished)
 
 		// Get last seen movies (limit 10)
@@ -266,31 +268,31 @@ func UserDetailHandler(db *sql.DB, em *emby.Client) fiber.Handler {
 			}
 		}
 
-		// Get finished series list (limit 10) using series_id when available
+		// Get finished series list (limit 10) by series_id
 		if rows, err := db.Query(`
             WITH watched AS (
                 SELECT 
-                    COALESCE(li.series_id, '') AS sid,
-                    COALESCE(li.series_name, CASE WHEN INSTR(li.name,' - ')>0 THEN SUBSTR(li.name,1,INSTR(li.name,' - ')-1) ELSE li.name END) AS sname,
+                    li.series_id AS sid,
+                    MAX(`+seriesNameSQL+`) AS sname,
                     COUNT(DISTINCT li.name) AS watched_episodes,
                     MAX(ps.ended_at) AS last_watched
                 FROM play_sessions ps
                 JOIN library_item li ON li.id = ps.item_id
-                WHERE ps.user_id = ? AND li.media_type='Episode' AND ps.ended_at IS NOT NULL
-                GROUP BY sid, sname
+                `+seriesJoin+`
+                WHERE ps.user_id = ? AND li.media_type='Episode' AND ps.ended_at IS NOT NULL AND `+hasSeriesIDSQL+`
+                GROUP BY li.series_id
             ), totals AS (
                 SELECT 
-                    COALESCE(series_id,'') AS sid,
-                    COALESCE(series_name, CASE WHEN INSTR(name,' - ')>0 THEN SUBSTR(name,1,INSTR(name,' - ')-1) ELSE name END) AS sname,
-                    COUNT(DISTINCT name) AS total_episodes
-                FROM library_item
-                WHERE media_type='Episode'
-                GROUP BY sid, sname
+                    li.series_id AS sid,
+                    COUNT(DISTINCT li.name) AS total_episodes
+                FROM library_item li
+                WHERE li.media_type='Episode' AND `+hasSeriesIDSQL+`
+                GROUP BY li.series_id
             )
             SELECT watched.sid, watched.sname, 'Series' as media_type, watched.watched_episodes
             FROM watched 
-            JOIN totals USING (sid, sname)
-            WHERE watched.watched_episodes = totals.total_episodes AND totals.total_episodes > 1
+            JOIN totals USING (sid)
+            WHERE watched.sname IS NOT NULL AND watched.watched_episodes = totals.total_episodes AND totals.total_episodes > 1
             ORDER BY watched.last_watched DESC
             LIMIT 10
         `, userID); err == nil {
@@ -310,6 +312,15 @@ func UserDetailHandler(db *sql.DB, em *emby.Client) fiber.Handler {
 			}
 		}
 
+		if devices, err := queries.UserDevices(c, db, userID); err == nil {
+			detail.Devices = devices
+		}
+		if middleware.CurrentViewer(c).Admin {
+			if addrs, err := queries.UserAddresses(c, db, userID); err == nil {
+				detail.IPHistory = addrs
+			}
+		}
+
 		return c.JSON(detail)
 	}
 }
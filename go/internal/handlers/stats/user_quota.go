@@ -0,0 +1,25 @@
+package stats
+
+import (
+	"database/sql"
+	"time"
+
+	"emby-analytics/internal/queries"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// UserQuotaHandler returns today's and this week's watch minutes for a user against their configured quota
+func UserQuotaHandler(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID := c.Params("id")
+		if userID == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "User ID is required"})
+		}
+		status, err := queries.GetUserQuotaStatus(c, db, userID, time.Now())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(status)
+	}
+}
@@ -1,8 +1,14 @@
 package stats
 
 import (
+	"context"
 	"database/sql"
 	"emby-analytics/internal/handlers/settings"
+	"emby-analytics/internal/queries"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 )
@@ -13,9 +19,38 @@ type UserWatchTime struct {
 	Hours      float64 `json:"hours"`
 	EmbyHours  float64 `json:"emby_hours"`
 	TraktHours float64 `json:"trakt_hours"`
+	// PersonID and UserIDs are set by AllUsersWatchTimeHandler for the sum of a mapped
+	// person's accounts; UserID is then the account with the most hours
+	PersonID string   `json:"person_id,omitempty"`
+	UserIDs  []string `json:"user_ids,omitempty"`
+	// Daily and Person are only filled by UserWatchTimeHandler
+	Daily  *UserDailyWatch `json:"daily,omitempty"`
+	Person *PersonWatch    `json:"person,omitempty"`
 }
 
-// UserWatchTimeHandler returns watch time for a specific user with dynamic Trakt inclusion
+// PersonWatch is the watch time of every account of the person a user is mapped to.
+type PersonWatch struct {
+	PersonID   string   `json:"person_id"`
+	Name       string   `json:"name"`
+	UserIDs    []string `json:"user_ids"`
+	Hours      float64  `json:"hours"`
+	EmbyHours  float64  `json:"emby_hours"`
+	TraktHours float64  `json:"trakt_hours"`
+}
+
+// UserDailyWatch is a user's recent watch time per local calendar day.
+type UserDailyWatch struct {
+	Timezone        string               `json:"timezone"`
+	Days            []queries.UserDayRow `json:"days"`
+	WeekdayAvgHours float64              `json:"weekday_avg_hours"`
+	WeekendAvgHours float64              `json:"weekend_avg_hours"`
+}
+
+// UserWatchTimeHandler returns watch time for a specific user with dynamic Trakt inclusion,
+// plus day buckets for the last ?days= (default 30) local days in ?tz= (IANA name, default
+// server local). Each day is flagged as weekend or as one of the ?holidays=YYYY-MM-DD,...
+// dates and carries a rolling 7-day average. For a user mapped to a person, person sums the
+// lifetime hours of all their accounts.
 func UserWatchTimeHandler(db *sql.DB) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		userID := c.Params("id")
@@ -23,6 +58,29 @@ func UserWatchTimeHandler(db *sql.DB) fiber.Handler {
 			return c.Status(400).JSON(fiber.Map{"error": "User ID is required"})
 		}
 
+		loc := time.Local
+		if tz := strings.TrimSpace(c.Query("tz")); tz != "" {
+			l, err := time.LoadLocation(tz)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "unknown timezone: " + tz})
+			}
+			loc = l
+		}
+		days := parseQueryInt(c, "days", 30)
+		if days <= 0 || days > 366 {
+			days = 30
+		}
+		holidays := map[string]bool{}
+		for _, d := range strings.Split(c.Query("holidays"), ",") {
+			if d = strings.TrimSpace(d); d == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", d); err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "holidays must be YYYY-MM-DD dates: " + d})
+			}
+			holidays[d] = true
+		}
+
 		// Get the setting for whether to include Trakt items
 		includeTrakt := settings.GetSettingBool(db, "include_trakt_items", false)
 
@@ -52,11 +110,46 @@ func UserWatchTimeHandler(db *sql.DB) fiber.Handler {
 			user.Hours = user.EmbyHours
 		}
 
+		dayRows, err := queries.UserDailyWatch(c, db, userID, days, loc, time.Now(), holidays)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		daily := &UserDailyWatch{Timezone: loc.String(), Days: dayRows}
+		var weekdayHours, weekendHours float64
+		var weekdays, weekends int
+		for _, d := range dayRows {
+			if d.Weekend {
+				weekendHours += d.Hours
+				weekends++
+			} else {
+				weekdayHours += d.Hours
+				weekdays++
+			}
+		}
+		if weekdays > 0 {
+			daily.WeekdayAvgHours = weekdayHours / float64(weekdays)
+		}
+		if weekends > 0 {
+			daily.WeekendAvgHours = weekendHours / float64(weekends)
+		}
+		user.Daily = daily
+
+		if user.Person, err = personWatch(c, db, userID, includeTrakt); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if viewerMask(c, db).hides(userID) {
+			user.Name = queries.AnonymousName
+			if user.Person != nil {
+				user.Person.Name = queries.AnonymousName
+			}
+		}
+
 		return c.JSON(user)
 	}
 }
 
-// AllUsersWatchTimeHandler returns watch time for all users with dynamic Trakt inclusion
+// AllUsersWatchTimeHandler returns watch time for all users with dynamic Trakt inclusion.
+// Accounts mapped to the same person are summed into one entry.
 func AllUsersWatchTimeHandler(db *sql.DB) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		// Get the setting for whether to include Trakt items
@@ -79,8 +172,7 @@ func AllUsersWatchTimeHandler(db *sql.DB) fiber.Handler {
 			ORDER BY 
 				CASE WHEN ? = 1 THEN (COALESCE(lw.emby_ms, 0) + COALESCE(lw.trakt_ms, 0))
 				     ELSE COALESCE(lw.emby_ms, 0) END DESC
-			LIMIT ?
-		`, includeTrakt, limit)
+		`, includeTrakt)
 
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
@@ -103,7 +195,98 @@ func AllUsersWatchTimeHandler(db *sql.DB) fiber.Handler {
 
 			users = append(users, user)
 		}
+		if err := rows.Err(); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		people, err := queries.UserPeople(c, db)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		users = mergeWatchTimePeople(users, people)
+		if len(users) > limit {
+			users = users[:limit]
+		}
+		if mask := viewerMask(c, db); !mask.empty() {
+			for i := range users {
+				if u := &users[i]; mask.hides(u.UserID) || slices.ContainsFunc(u.UserIDs, mask.hides) {
+					u.UserID = ""
+					u.Name = queries.AnonymousName
+					u.PersonID = ""
+					u.UserIDs = nil
+				}
+			}
+		}
 
 		return c.JSON(users)
 	}
 }
+
+// mergeWatchTimePeople sums the accounts of each mapped person into the entry of their
+// account with the most hours and re-sorts by hours.
+func mergeWatchTimePeople(users []UserWatchTime, people map[string]queries.PersonRef) []UserWatchTime {
+	if len(people) == 0 {
+		return users
+	}
+	out := make([]UserWatchTime, 0, len(users))
+	byPerson := map[string]int{}
+	for _, u := range users {
+		ref, ok := people[u.UserID]
+		if !ok {
+			out = append(out, u)
+			continue
+		}
+		// users is sorted by hours, so the first account seen is the one with the most
+		i, seen := byPerson[ref.PersonID]
+		if !seen {
+			u.PersonID, u.Name, u.UserIDs = ref.PersonID, ref.Name, []string{u.UserID}
+			byPerson[ref.PersonID] = len(out)
+			out = append(out, u)
+			continue
+		}
+		p := &out[i]
+		p.UserIDs = append(p.UserIDs, u.UserID)
+		p.Hours += u.Hours
+		p.EmbyHours += u.EmbyHours
+		p.TraktHours += u.TraktHours
+	}
+	for _, i := range byPerson {
+		sort.Strings(out[i].UserIDs)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Hours > out[j].Hours })
+	return out
+}
+
+// personWatch returns the lifetime watch time of the person userID is mapped to, or nil.
+func personWatch(ctx context.Context, db *sql.DB, userID string, includeTrakt bool) (*PersonWatch, error) {
+	var personID string
+	err := db.QueryRowContext(ctx, `SELECT person_id FROM user_mappings WHERE user_id = ?`, userID).Scan(&personID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p, err := queries.GetPerson(ctx, db, personID)
+	if err != nil {
+		return nil, err
+	}
+	pw := &PersonWatch{PersonID: p.PersonID, Name: p.Name, UserIDs: []string{}}
+	for _, a := range p.Users {
+		pw.UserIDs = append(pw.UserIDs, a.UserID)
+	}
+	err = db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(lw.emby_ms), 0) / 3600000.0, COALESCE(SUM(lw.trakt_ms), 0) / 3600000.0
+		FROM user_mappings um
+		LEFT JOIN lifetime_watch lw ON lw.user_id = um.user_id
+		WHERE um.person_id = ?
+	`, personID).Scan(&pw.EmbyHours, &pw.TraktHours)
+	if err != nil {
+		return nil, err
+	}
+	pw.Hours = pw.EmbyHours
+	if includeTrakt {
+		pw.Hours += pw.TraktHours
+	}
+	return pw, nil
+}
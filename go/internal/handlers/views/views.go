@@ -0,0 +1,253 @@
+package views
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/middleware"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// SavedView is a named set of stats query filters owned by an app user.
+type SavedView struct {
+	ID        int64             `json:"id"`
+	UserID    int64             `json:"user_id"`
+	Name      string            `json:"name"`
+	Filters   map[string]string `json:"filters"`
+	Shared    bool              `json:"shared"`
+	CreatedAt int64             `json:"created_at"`
+	UpdatedAt int64             `json:"updated_at"`
+}
+
+type viewRequest struct {
+	Name    string            `json:"name"`
+	Filters map[string]string `json:"filters"`
+	Shared  bool              `json:"shared"`
+}
+
+// ListViews returns the caller's views plus views other users have shared.
+func ListViews(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		rows, err := db.Query(`
+			SELECT id, user_id, name, filters, shared, created_at, updated_at
+			FROM saved_views
+			WHERE user_id = ? OR shared = 1
+			ORDER BY user_id = ? DESC, name COLLATE NOCASE ASC`, userID, userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		defer rows.Close()
+
+		out := make([]SavedView, 0, 8)
+		for rows.Next() {
+			v, err := scanView(rows)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			}
+			out = append(out, *v)
+		}
+		return c.JSON(out)
+	}
+}
+
+// GetView returns a single view visible to the caller.
+func GetView(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid view id"})
+		}
+		v, err := LoadView(db, id, userID)
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "view not found"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(v)
+	}
+}
+
+// CreateView stores a new named filter set for the caller.
+func CreateView(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		req, filtersJSON, errMsg := parseViewRequest(c)
+		if errMsg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errMsg})
+		}
+		now := time.Now().Unix()
+		res, err := db.Exec(`
+			INSERT INTO saved_views (user_id, name, filters, shared, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)`, userID, req.Name, filtersJSON, req.Shared, now, now)
+		if err != nil {
+			if strings.Contains(strings.ToLower(err.Error()), "unique") {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "a view with that name already exists"})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		id, _ := res.LastInsertId()
+		v, err := LoadView(db, id, userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusCreated).JSON(v)
+	}
+}
+
+// UpdateView replaces the name, filters and sharing flag of a view owned by the caller.
+func UpdateView(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid view id"})
+		}
+		req, filtersJSON, errMsg := parseViewRequest(c)
+		if errMsg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": errMsg})
+		}
+		res, err := db.Exec(`
+			UPDATE saved_views SET name = ?, filters = ?, shared = ?, updated_at = ?
+			WHERE id = ? AND user_id = ?`, req.Name, filtersJSON, req.Shared, time.Now().Unix(), id, userID)
+		if err != nil {
+			if strings.Contains(strings.ToLower(err.Error()), "unique") {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "a view with that name already exists"})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "view not found"})
+		}
+		v, err := LoadView(db, id, userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(v)
+	}
+}
+
+// DeleteView removes a view owned by the caller.
+func DeleteView(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "login required"})
+		}
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid view id"})
+		}
+		res, err := db.Exec(`DELETE FROM saved_views WHERE id = ? AND user_id = ?`, id, userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "view not found"})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	}
+}
+
+// ApplyView is middleware for stats routes: when ?view=<id> is present it loads the
+// stored filters and adds them to the request query. Parameters given explicitly in
+// the URL take precedence over the stored ones.
+func ApplyView(db *sql.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		raw := c.Query("view")
+		if raw == "" {
+			return c.Next()
+		}
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid view id"})
+		}
+		userID, _ := middleware.CurrentUserID(c)
+		v, err := LoadView(db, id, userID)
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "view not found"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		args := c.Request().URI().QueryArgs()
+		for k, val := range v.Filters {
+			if args.Has(k) {
+				continue
+			}
+			args.Set(k, val)
+		}
+		return c.Next()
+	}
+}
+
+// LoadView returns the view with the given id if it is owned by userID or shared.
+func LoadView(db *sql.DB, id, userID int64) (*SavedView, error) {
+	row := db.QueryRow(`
+		SELECT id, user_id, name, filters, shared, created_at, updated_at
+		FROM saved_views
+		WHERE id = ? AND (user_id = ? OR shared = 1)`, id, userID)
+	return scanView(row)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanView(r rowScanner) (*SavedView, error) {
+	var v SavedView
+	var filters string
+	var shared int
+	if err := r.Scan(&v.ID, &v.UserID, &v.Name, &filters, &shared, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		return nil, err
+	}
+	v.Shared = shared == 1
+	v.Filters = map[string]string{}
+	if filters != "" {
+		_ = json.Unmarshal([]byte(filters), &v.Filters)
+	}
+	return &v, nil
+}
+
+func parseViewRequest(c fiber.Ctx) (*viewRequest, string, string) {
+	var req viewRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return nil, "", "invalid request body"
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, "", "name is required"
+	}
+	filters := make(map[string]string, len(req.Filters))
+	for k, v := range req.Filters {
+		k = strings.TrimSpace(k)
+		// A view must not reference another view.
+		if k == "" || k == "view" {
+			continue
+		}
+		filters[k] = v
+	}
+	b, err := json.Marshal(filters)
+	if err != nil {
+		return nil, "", "invalid filters"
+	}
+	return &req, string(b), ""
+}
@@ -0,0 +1,111 @@
+// Package hooks exposes session and library lifecycle events to extensions. Go code can
+// subscribe with Register (typically from an init function in a file added to
+// cmd/emby-analytics), and HOOK_SCRIPT runs an external program for each event, so
+// self-hosters can add custom logging or mirror data elsewhere without forking.
+package hooks
+
+import (
+	"sync"
+	"time"
+
+	"emby-analytics/internal/logging"
+)
+
+// Event types
+const (
+	SessionStarted    = "session_started"     // a play session row was created
+	IntervalClosed    = "interval_closed"     // a session's watch interval was written for the last time
+	SessionFinalized  = "session_finalized"   // a session ended; Data is its summary
+	PlaybackPaused    = "playback_paused"     // a tracked playback was paused
+	PlaybackResumed   = "playback_resumed"    // a paused playback continued
+	PlaybackStopped   = "playback_stopped"    // a tracked playback ended, at its last position
+	LibraryItemSynced = "library_item_synced" // a library item was stored by a library sync
+)
+
+// AllEvents lists every event type in lifecycle order.
+var AllEvents = []string{SessionStarted, PlaybackPaused, PlaybackResumed, IntervalClosed, PlaybackStopped, SessionFinalized, LibraryItemSynced}
+
+// queueSize bounds the events waiting for handlers; further events are dropped.
+const queueSize = 1024
+
+// Event is delivered to hook handlers. Data is the event's payload and is what scripts
+// receive, JSON encoded, on stdin.
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// Handler processes one event.
+type Handler func(Event)
+
+var (
+	log      = logging.Module("hooks")
+	mu       sync.RWMutex
+	handlers = map[string][]Handler{}
+	queue    chan Event
+	start    sync.Once
+)
+
+// Register subscribes h to an event type; "*" subscribes to every type. Handlers run one
+// event at a time on a background goroutine in emit order, so a slow handler delays other
+// hooks but never session tracking.
+func Register(eventType string, h Handler) {
+	start.Do(func() {
+		queue = make(chan Event, queueSize)
+		go dispatch()
+	})
+	mu.Lock()
+	handlers[eventType] = append(handlers[eventType], h)
+	mu.Unlock()
+}
+
+// Enabled reports whether any handler receives eventType, so callers can skip building
+// payloads nobody consumes.
+func Enabled(eventType string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(handlers[eventType]) > 0 || len(handlers["*"]) > 0
+}
+
+// Emit queues an event for its handlers without blocking; it is dropped with a warning
+// when the queue is full.
+func Emit(eventType string, data any) {
+	if !Enabled(eventType) {
+		return
+	}
+	select {
+	case queue <- Event{Type: eventType, Time: time.Now().UTC(), Data: data}:
+	default:
+		log.Warn("hook queue full; dropping event", "type", eventType)
+	}
+}
+
+// EmitWait is Emit for background jobs that can afford to wait: it blocks until the event
+// is queued instead of dropping it.
+func EmitWait(eventType string, data any) {
+	if !Enabled(eventType) {
+		return
+	}
+	queue <- Event{Type: eventType, Time: time.Now().UTC(), Data: data}
+}
+
+func dispatch() {
+	for e := range queue {
+		mu.RLock()
+		hs := append(append([]Handler(nil), handlers[e.Type]...), handlers["*"]...)
+		mu.RUnlock()
+		for _, h := range hs {
+			run(h, e)
+		}
+	}
+}
+
+func run(h Handler, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("hook handler panicked", "type", e.Type, "panic", r)
+		}
+	}()
+	h(e)
+}
@@ -0,0 +1,47 @@
+package hooks
+
+import "emby-analytics/internal/media"
+
+// SessionStart is the payload of SessionStarted.
+type SessionStart struct {
+	SessionFK int64         `json:"session_fk"` // play_sessions.id
+	StartedAt int64         `json:"started_at"`
+	Session   media.Session `json:"session"`
+}
+
+// Interval is the payload of IntervalClosed: a contiguous watch segment as stored in
+// play_intervals.
+type Interval struct {
+	IntervalID      int64  `json:"interval_id"`
+	SessionFK       int64  `json:"session_fk"`
+	ServerID        string `json:"server_id"`
+	SessionID       string `json:"session_id"`
+	UserID          string `json:"user_id"`
+	ItemID          string `json:"item_id"`
+	StartTS         int64  `json:"start_ts"`
+	EndTS           int64  `json:"end_ts"`
+	DurationSeconds int    `json:"duration_seconds"`
+}
+
+// Playback is the payload of PlaybackPaused, PlaybackResumed and PlaybackStopped: where a
+// tracked playback stood when its state changed, as last polled.
+type Playback struct {
+	SessionFK  int64            `json:"session_fk"`
+	ServerID   string           `json:"server_id"`
+	ServerType media.ServerType `json:"server_type"`
+	SessionID  string           `json:"session_id"`
+	UserID     string           `json:"user_id"`
+	ItemID     string           `json:"item_id"`
+	ItemType   string           `json:"item_type"`
+	PositionMs int64            `json:"position_ms"`
+	DurationMs int64            `json:"duration_ms"` // 0 when the server didn't report the runtime
+}
+
+// LibraryItem is the payload of LibraryItemSynced; ID is the library_item.id the item was
+// stored under.
+type LibraryItem struct {
+	ID         string          `json:"id"`
+	ServerID   string          `json:"server_id"`
+	ServerType string          `json:"server_type"`
+	Item       media.MediaItem `json:"item"`
+}
@@ -0,0 +1,73 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// RegisterScript runs script for each of the given event types; when none are given it runs
+// for the session events only, since library syncs emit one event per item.
+// The event is passed as JSON on stdin and its type in the HOOK_EVENT environment
+// variable; a run is killed after timeout. Failures are logged with the script's output.
+func RegisterScript(script string, events []string, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	h := func(e Event) { runScript(script, e, timeout) }
+	if len(events) == 0 {
+		events = []string{SessionStarted, IntervalClosed, SessionFinalized}
+	}
+	for _, t := range events {
+		if !known(t) {
+			log.Warn("unknown hook event type", "type", t)
+			continue
+		}
+		Register(t, h)
+	}
+	log.Info("hook script registered", "script", script, "events", strings.Join(events, ","), "timeout", timeout)
+}
+
+func runScript(script string, e Event, timeout time.Duration) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Warn("hook event encoding failed", "type", e.Type, "error", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, script)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(), "HOOK_EVENT="+e.Type)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	started := time.Now()
+	if err := cmd.Run(); err != nil {
+		log.Warn("hook script failed", "type", e.Type, "error", err, "output", strings.TrimSpace(truncate(out.String(), 500)))
+		return
+	}
+	log.Debug("hook script ran", "type", e.Type, "duration", time.Since(started).Round(time.Millisecond))
+}
+
+func known(eventType string) bool {
+	for _, t := range AllEvents {
+		if t == eventType {
+			return true
+		}
+	}
+	return eventType == "*"
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,284 @@
+// Package hostmetrics samples host-level network throughput so the session bitrates
+// reported by media servers can be checked against what actually leaves the NIC.
+package hostmetrics
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+)
+
+// historyWindow is how much sample history the collector keeps in memory.
+const historyWindow = time.Hour
+
+// InterfaceRate is the throughput of one interface over the last sample period.
+type InterfaceRate struct {
+	Name   string  `json:"name"`
+	RxMbps float64 `json:"rx_mbps"`
+	TxMbps float64 `json:"tx_mbps"`
+}
+
+// Sample pairs measured NIC throughput with the session-reported bitrate at the same moment.
+type Sample struct {
+	Timestamp     int64   `json:"ts"`
+	RxMbps        float64 `json:"rx_mbps"`
+	TxMbps        float64 `json:"tx_mbps"`
+	EstimatedMbps float64 `json:"estimated_mbps"`
+	ActiveStreams int     `json:"active_streams"`
+}
+
+// Snapshot is the collector state exposed through /admin/metrics.
+type Snapshot struct {
+	IntervalSec int             `json:"interval_sec"`
+	Interfaces  []InterfaceRate `json:"interfaces"`
+	Latest      *Sample         `json:"latest,omitempty"`
+	// Averages over the retained history (up to one hour)
+	AvgTxMbps        float64 `json:"avg_tx_mbps"`
+	AvgEstimatedMbps float64 `json:"avg_estimated_mbps"`
+	// EstimateRatio is estimated/actual outbound traffic over the retained history;
+	// values well below 1 mean sessions under-report, well above 1 mean they over-report.
+	EstimateRatio *float64 `json:"estimate_ratio,omitempty"`
+	Samples       []Sample `json:"samples"`
+}
+
+// Collector periodically reads /proc/net/dev and records throughput next to the
+// sum of active session bitrates.
+type Collector struct {
+	mgr        *media.MultiServerManager
+	interval   time.Duration
+	interfaces map[string]bool // empty: every interface except loopback
+
+	mu      sync.RWMutex
+	prev    map[string]ifaceCounters
+	prevAt  time.Time
+	rates   []InterfaceRate
+	samples []Sample
+	next    int
+	full    bool
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewCollector creates a collector sampling the given interfaces (all non-loopback
+// interfaces when empty) every interval.
+func NewCollector(mgr *media.MultiServerManager, interval time.Duration, interfaces []string) *Collector {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	capacity := int(historyWindow / interval)
+	if capacity < 1 {
+		capacity = 1
+	}
+	want := make(map[string]bool, len(interfaces))
+	for _, name := range interfaces {
+		if name != "" {
+			want[name] = true
+		}
+	}
+	return &Collector{
+		mgr:        mgr,
+		interval:   interval,
+		interfaces: want,
+		samples:    make([]Sample, capacity),
+		quit:       make(chan struct{}),
+	}
+}
+
+// Start begins sampling.
+func (c *Collector) Start() {
+	c.wg.Add(1)
+	go c.loop()
+	logging.Info("Host network sampling started", "interval", c.interval)
+}
+
+// Stop gracefully stops sampling.
+func (c *Collector) Stop() {
+	close(c.quit)
+	c.wg.Wait()
+	logging.Info("Host network sampling stopped")
+}
+
+func (c *Collector) loop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	c.sample() // prime counters so the first tick yields a rate
+	for {
+		select {
+		case <-ticker.C:
+			c.sample()
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+func (c *Collector) sample() {
+	counters, err := readNetDev()
+	if err != nil {
+		logging.Debug("host network sample failed", "error", err)
+		return
+	}
+	now := time.Now()
+	estimated, streams := c.sessionMbps()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prev, prevAt := c.prev, c.prevAt
+	c.prev, c.prevAt = counters, now
+	if prev == nil {
+		return
+	}
+	elapsed := now.Sub(prevAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	var rates []InterfaceRate
+	var rxTotal, txTotal float64
+	for name, cur := range counters {
+		if !c.wants(name) {
+			continue
+		}
+		old, ok := prev[name]
+		if !ok || cur.RxBytes < old.RxBytes || cur.TxBytes < old.TxBytes {
+			continue // new interface or counter reset
+		}
+		r := InterfaceRate{
+			Name:   name,
+			RxMbps: round1(float64(cur.RxBytes-old.RxBytes) * 8 / elapsed / 1_000_000),
+			TxMbps: round1(float64(cur.TxBytes-old.TxBytes) * 8 / elapsed / 1_000_000),
+		}
+		rxTotal += r.RxMbps
+		txTotal += r.TxMbps
+		rates = append(rates, r)
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Name < rates[j].Name })
+	c.rates = rates
+
+	c.samples[c.next] = Sample{
+		Timestamp:     now.Unix(),
+		RxMbps:        round1(rxTotal),
+		TxMbps:        round1(txTotal),
+		EstimatedMbps: estimated,
+		ActiveStreams: streams,
+	}
+	c.next = (c.next + 1) % len(c.samples)
+	if c.next == 0 {
+		c.full = true
+	}
+}
+
+func (c *Collector) wants(name string) bool {
+	if len(c.interfaces) > 0 {
+		return c.interfaces[name]
+	}
+	return name != "lo"
+}
+
+// sessionMbps sums the bitrates of unpaused sessions the same way the Now Playing
+// summary does: overall bitrate first, transcode target as a fallback.
+func (c *Collector) sessionMbps() (float64, int) {
+	if c.mgr == nil {
+		return 0, 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.interval)
+	defer cancel()
+	sessions, err := c.mgr.GetAllSessionsCached(ctx)
+	if err != nil {
+		return 0, 0
+	}
+	var sumBps int64
+	streams := 0
+	for _, s := range sessions {
+		if s.IsPaused {
+			continue
+		}
+		streams++
+		bps := s.Bitrate
+		if bps <= 0 {
+			bps = s.TranscodeBitrate
+		}
+		if bps > 0 {
+			sumBps += bps
+		}
+	}
+	return round1(float64(sumBps) / 1_000_000), streams
+}
+
+// Snapshot returns the latest interface rates and the retained sample history, oldest first.
+func (c *Collector) Snapshot() Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snap := Snapshot{
+		IntervalSec: int(c.interval / time.Second),
+		Interfaces:  append([]InterfaceRate(nil), c.rates...),
+	}
+	if c.full {
+		snap.Samples = append(snap.Samples, c.samples[c.next:]...)
+	}
+	snap.Samples = append(snap.Samples, c.samples[:c.next]...)
+	if len(snap.Samples) == 0 {
+		return snap
+	}
+
+	latest := snap.Samples[len(snap.Samples)-1]
+	snap.Latest = &latest
+	var tx, est float64
+	for _, s := range snap.Samples {
+		tx += s.TxMbps
+		est += s.EstimatedMbps
+	}
+	n := float64(len(snap.Samples))
+	snap.AvgTxMbps = round1(tx / n)
+	snap.AvgEstimatedMbps = round1(est / n)
+	if tx > 0 {
+		ratio := math.Round(est/tx*100) / 100
+		snap.EstimateRatio = &ratio
+	}
+	return snap
+}
+
+// LatestTxMbps returns the most recent total outbound throughput.
+func (c *Collector) LatestTxMbps() (float64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.full && c.next == 0 {
+		return 0, false
+	}
+	i := (c.next - 1 + len(c.samples)) % len(c.samples)
+	return c.samples[i].TxMbps, true
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+var (
+	defaultMu        sync.RWMutex
+	defaultCollector *Collector
+)
+
+// SetDefault registers the process-wide collector; nil means host sampling is disabled.
+func SetDefault(c *Collector) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultCollector = c
+}
+
+// Default returns the process-wide collector, or nil when host sampling is disabled.
+func Default() *Collector {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultCollector
+}
@@ -0,0 +1,60 @@
+package hostmetrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// netDevPath is where Linux exposes per-interface byte counters.
+const netDevPath = "/proc/net/dev"
+
+// ifaceCounters are the cumulative byte counters of one interface.
+type ifaceCounters struct {
+	RxBytes uint64
+	TxBytes uint64
+}
+
+// readNetDev reads the counters of every interface from /proc/net/dev.
+func readNetDev() (map[string]ifaceCounters, error) {
+	f, err := os.Open(netDevPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseNetDev(f)
+}
+
+// parseNetDev parses the /proc/net/dev format: two header lines followed by one
+// "iface: rx_bytes rx_packets ... tx_bytes ..." line per interface.
+func parseNetDev(r io.Reader) (map[string]ifaceCounters, error) {
+	out := make(map[string]ifaceCounters)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		name, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue // header lines
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 16 {
+			return nil, fmt.Errorf("net/dev: unexpected field count %d for %q", len(fields), strings.TrimSpace(name))
+		}
+		rx, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("net/dev: rx_bytes: %w", err)
+		}
+		tx, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("net/dev: tx_bytes: %w", err)
+		}
+		out[strings.TrimSpace(name)] = ifaceCounters{RxBytes: rx, TxBytes: tx}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,34 @@
+package hostmetrics
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  123456     100    0    0    0     0          0         0   123456     100    0    0    0     0       0          0
+  eth0: 9876543210 8000000    0    0    0     0          0         0 1234567890123 9000000    0    0    0     0       0          0
+`
+
+func TestParseNetDev(t *testing.T) {
+	got, err := parseNetDev(strings.NewReader(sampleNetDev))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 interfaces, got %+v", got)
+	}
+	if c := got["eth0"]; c.RxBytes != 9876543210 || c.TxBytes != 1234567890123 {
+		t.Errorf("eth0: unexpected counters %+v", c)
+	}
+	if c := got["lo"]; c.RxBytes != 123456 || c.TxBytes != 123456 {
+		t.Errorf("lo: unexpected counters %+v", c)
+	}
+}
+
+func TestParseNetDevRejectsShortLines(t *testing.T) {
+	if _, err := parseNetDev(strings.NewReader("eth0: 1 2 3\n")); err == nil {
+		t.Fatal("expected error for truncated line")
+	}
+}
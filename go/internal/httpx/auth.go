@@ -0,0 +1,97 @@
+package httpx
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// authBrokenThreshold is the number of consecutive 401/403 responses after
+// which a host's credentials are reported as broken.
+const authBrokenThreshold = 3
+
+// AuthStatus describes whether a host is currently accepting our credentials.
+type AuthStatus struct {
+	Broken              bool       `json:"broken"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastStatus          int        `json:"last_status,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+}
+
+type authTracker struct {
+	mu     sync.Mutex
+	status AuthStatus
+}
+
+var (
+	authTrackers sync.Map // host -> *authTracker
+
+	authHookMu sync.RWMutex
+	authHook   func(host string, status AuthStatus)
+)
+
+func authTrackerFor(host string) *authTracker {
+	if t, ok := authTrackers.Load(host); ok {
+		return t.(*authTracker)
+	}
+	t, _ := authTrackers.LoadOrStore(host, &authTracker{})
+	return t.(*authTracker)
+}
+
+// OnAuthChange registers a callback invoked whenever a host's credentials
+// switch between working and broken.
+func OnAuthChange(fn func(host string, status AuthStatus)) {
+	authHookMu.Lock()
+	authHook = fn
+	authHookMu.Unlock()
+}
+
+// AuthStatusFor returns the credential status for baseURL's host.
+func AuthStatusFor(baseURL string) AuthStatus {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return AuthStatus{}
+	}
+	t := authTrackerFor(u.Host)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status
+}
+
+// recordAuth updates the tracker from a response status code. Only 401/403
+// count as failures; other 2xx/3xx responses prove the credentials work.
+func recordAuth(host string, code int) {
+	isFailure := code == http.StatusUnauthorized || code == http.StatusForbidden
+	if !isFailure && code >= 400 {
+		return
+	}
+
+	t := authTrackerFor(host)
+	t.mu.Lock()
+	now := time.Now()
+	wasBroken := t.status.Broken
+	if isFailure {
+		t.status.ConsecutiveFailures++
+		t.status.LastStatus = code
+		t.status.LastFailure = &now
+		t.status.Broken = t.status.ConsecutiveFailures >= authBrokenThreshold
+	} else {
+		t.status.ConsecutiveFailures = 0
+		t.status.LastSuccess = &now
+		t.status.Broken = false
+	}
+	changed := wasBroken != t.status.Broken
+	snapshot := t.status
+	t.mu.Unlock()
+
+	if changed {
+		authHookMu.RLock()
+		fn := authHook
+		authHookMu.RUnlock()
+		if fn != nil {
+			fn(host, snapshot)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package httpx
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	stateClosed   = "closed"
+	stateOpen     = "open"
+	stateHalfOpen = "half-open"
+)
+
+// breaker is a consecutive-failure circuit breaker shared by every client
+// talking to the same host.
+type breaker struct {
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+var breakers sync.Map // host -> *breaker
+
+func breakerFor(host string) *breaker {
+	if b, ok := breakers.Load(host); ok {
+		return b.(*breaker)
+	}
+	b, _ := breakers.LoadOrStore(host, &breaker{state: stateClosed})
+	return b.(*breaker)
+}
+
+// allow reports whether a request may proceed. After the cooldown an open
+// breaker lets a single probe request through (half-open).
+func (b *breaker) allow(cooldown time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < cooldown {
+			return false
+		}
+		b.state = stateHalfOpen
+		b.probing = true
+		return true
+	case stateHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	b.state = stateClosed
+	b.failures = 0
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// failure records a failed attempt and reports whether it opened the breaker.
+func (b *breaker) failure(threshold int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == stateHalfOpen || (b.state == stateClosed && b.failures >= threshold) {
+		b.state = stateOpen
+		b.openedAt = time.Now()
+		b.probing = false
+		return true
+	}
+	return false
+}
+
+func (b *breaker) currentState() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
@@ -0,0 +1,78 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBreakerStates(t *testing.T) {
+	b := &breaker{state: stateClosed}
+	if b.failure(2) {
+		t.Fatal("opened below the threshold")
+	}
+	if !b.failure(2) || b.currentState() != stateOpen {
+		t.Fatalf("state %s after reaching the threshold, want open", b.currentState())
+	}
+	if b.allow(time.Hour) {
+		t.Error("an open breaker let a request through during the cooldown")
+	}
+
+	// After the cooldown a single probe goes through
+	if !b.allow(0) || b.currentState() != stateHalfOpen {
+		t.Fatalf("state %s after the cooldown, want half-open", b.currentState())
+	}
+	if b.allow(0) {
+		t.Error("a second request went through while the probe is in flight")
+	}
+	// A failed probe opens it again straight away
+	if !b.failure(100) || b.currentState() != stateOpen {
+		t.Fatalf("state %s after a failed probe, want open", b.currentState())
+	}
+
+	// A successful probe closes it and resets the count
+	b.allow(0)
+	b.success()
+	if b.currentState() != stateClosed || !b.allow(time.Hour) {
+		t.Fatalf("state %s after a successful probe, want closed", b.currentState())
+	}
+	if b.failure(2) {
+		t.Error("failures before the probe still counted")
+	}
+}
+
+func TestClientBreaker(t *testing.T) {
+	srv, hits := flakyServer(t, nil, 500, 500, 200)
+	host := strings.TrimPrefix(srv.URL, "http://")
+	c := NewWithOptions(Options{MaxRetries: 0, BreakerThreshold: 2, BreakerCooldown: 50 * time.Millisecond})
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.Do(mustRequest(t, http.MethodGet, srv.URL))
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := c.Do(mustRequest(t, http.MethodGet, srv.URL)); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("the open breaker let %d requests through", hits.Load()-2)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	resp, err := c.Do(mustRequest(t, http.MethodGet, srv.URL))
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 || breakerFor(host).currentState() != stateClosed {
+		t.Errorf("probe status %d, breaker %s", resp.StatusCode, breakerFor(host).currentState())
+	}
+	m := metricsFor(host)
+	if m.breakerOpens.Load() != 1 || m.shortCircuited.Load() != 1 {
+		t.Errorf("breaker_opens = %d, short_circuited = %d", m.breakerOpens.Load(), m.shortCircuited.Load())
+	}
+}
@@ -0,0 +1,237 @@
+// Package httpx provides the HTTP client shared by the Emby, Jellyfin and Plex
+// integrations: pooled connections, retry with exponential backoff, a per-host
+// circuit breaker and per-host request metrics.
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Options controls timeouts, retries and circuit breaking for a Client.
+// Zero durations and thresholds fall back to the process-wide defaults (see
+// SetDefaults); MaxRetries is taken as given, so 0 disables retries.
+type Options struct {
+	Timeout          time.Duration // per-attempt timeout
+	MaxRetries       int           // retries after the first attempt (idempotent requests only)
+	BaseBackoff      time.Duration // first retry delay; doubles each attempt
+	MaxBackoff       time.Duration // upper bound for a single retry delay
+	BreakerThreshold int           // consecutive failures that open a host's breaker
+	BreakerCooldown  time.Duration // how long an open breaker rejects requests
+}
+
+// ErrCircuitOpen is returned when a host's circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+var (
+	defaultsMu sync.RWMutex
+	defaults   = Options{
+		Timeout:          30 * time.Second,
+		MaxRetries:       2,
+		BaseBackoff:      time.Second,
+		MaxBackoff:       10 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+
+	// sharedTransport pools connections across all media server clients.
+	sharedTransport = &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+)
+
+// SetDefaults replaces the process-wide defaults. Call before creating clients.
+func SetDefaults(o Options) {
+	defaultsMu.Lock()
+	defaults = o.withDefaults(defaults)
+	defaultsMu.Unlock()
+}
+
+// Defaults returns the current process-wide defaults.
+func Defaults() Options {
+	defaultsMu.RLock()
+	defer defaultsMu.RUnlock()
+	return defaults
+}
+
+func (o Options) withDefaults(d Options) Options {
+	if o.Timeout <= 0 {
+		o.Timeout = d.Timeout
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+	if o.BaseBackoff <= 0 {
+		o.BaseBackoff = d.BaseBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = d.MaxBackoff
+	}
+	if o.BreakerThreshold <= 0 {
+		o.BreakerThreshold = d.BreakerThreshold
+	}
+	if o.BreakerCooldown <= 0 {
+		o.BreakerCooldown = d.BreakerCooldown
+	}
+	return o
+}
+
+// Client wraps http.Client with retries, circuit breaking and metrics.
+type Client struct {
+	http *http.Client
+	opts Options
+}
+
+// New creates a client using the process-wide defaults.
+func New() *Client {
+	return NewWithOptions(Defaults())
+}
+
+// NewWithOptions creates a client with explicit options; zero fields use the defaults.
+func NewWithOptions(o Options) *Client {
+	o = o.withDefaults(Defaults())
+	return &Client{
+		http: &http.Client{Timeout: o.Timeout, Transport: hostRouter{}},
+		opts: o,
+	}
+}
+
+// HTTPClient exposes the underlying client for callers that need raw access
+// (e.g. long-lived streaming requests that must bypass retries).
+func (c *Client) HTTPClient() *http.Client {
+	return c.http
+}
+
+// Do sends the request. GET/HEAD requests are retried on network errors, 429 and
+// 5xx responses with exponential backoff; other methods are attempted once. When
+// retries are exhausted on an HTTP error the last response is returned so callers
+// can inspect the status as before.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	br := breakerFor(host)
+	m := metricsFor(host)
+
+	if !br.allow(c.opts.BreakerCooldown) {
+		m.shortCircuited.Add(1)
+		return nil, fmt.Errorf("%s: %w", host, ErrCircuitOpen)
+	}
+
+	maxRetries := c.opts.MaxRetries
+	if !isRetryable(req) {
+		maxRetries = 0
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		attemptReq := req
+		if attempt > 0 {
+			m.retries.Add(1)
+			attemptReq = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		start := time.Now()
+		resp, err := c.http.Do(attemptReq)
+		m.observe(time.Since(start))
+		if err == nil {
+			recordAuth(host, resp.StatusCode)
+		}
+
+		if err == nil && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			m.successes.Add(1)
+			br.success()
+			return resp, nil
+		}
+
+		m.failures.Add(1)
+		if br.failure(c.opts.BreakerThreshold) {
+			m.breakerOpens.Add(1)
+			maxRetries = attempt // host is now considered down; stop retrying
+		}
+
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("server error: %d", resp.StatusCode)
+		}
+
+		if attempt == maxRetries {
+			if err == nil {
+				return resp, nil
+			}
+			break
+		}
+
+		delay := c.backoff(attempt)
+		if resp != nil {
+			if ra := retryAfter(resp); ra > 0 && ra <= c.opts.MaxBackoff {
+				delay = ra
+			}
+			resp.Body.Close()
+		}
+		if err := sleepContext(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+
+	if maxRetries == 0 {
+		return nil, lastErr
+	}
+	return nil, fmt.Errorf("request failed after %d attempts: %w", maxRetries+1, lastErr)
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.opts.BaseBackoff << uint(attempt)
+	if d <= 0 || d > c.opts.MaxBackoff {
+		d = c.opts.MaxBackoff
+	}
+	// Up to 20% jitter so clients retrying the same host don't synchronise.
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
+}
+
+func isRetryable(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	}
+	return false
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return 0
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,134 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer answers with codes in turn, repeating the last one, and counts the requests.
+func flakyServer(t *testing.T, header http.Header, codes ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(hits.Add(1)) - 1
+		if n >= len(codes) {
+			n = len(codes) - 1
+		}
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(codes[n])
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func testClient(o Options) *Client {
+	if o.BreakerThreshold == 0 {
+		o.BreakerThreshold = 100
+	}
+	return NewWithOptions(o)
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	srv, hits := flakyServer(t, nil, 503, 502, 200)
+	c := testClient(Options{MaxRetries: 2, BaseBackoff: 20 * time.Millisecond, MaxBackoff: time.Second})
+
+	start := time.Now()
+	resp, err := c.Do(mustRequest(t, http.MethodGet, srv.URL))
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 || hits.Load() != 3 {
+		t.Errorf("status %d after %d requests, want 200 after 3", resp.StatusCode, hits.Load())
+	}
+	// 20ms then 40ms, each with up to 20% jitter
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("retries took %v, want about 60-72ms", elapsed)
+	}
+	if m := metricsFor(strings.TrimPrefix(srv.URL, "http://")); m.retries.Load() != 2 || m.failures.Load() != 2 {
+		t.Errorf("retries = %d, failures = %d", m.retries.Load(), m.failures.Load())
+	}
+}
+
+func TestRetriesExhaustedReturnLastResponse(t *testing.T) {
+	srv, hits := flakyServer(t, nil, 500)
+	c := testClient(Options{MaxRetries: 1, BaseBackoff: time.Millisecond})
+
+	resp, err := c.Do(mustRequest(t, http.MethodGet, srv.URL))
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 500 || hits.Load() != 2 {
+		t.Errorf("status %d after %d requests, want 500 after 2", resp.StatusCode, hits.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	srv, hits := flakyServer(t, http.Header{"Retry-After": {"1"}}, 429, 200)
+	c := testClient(Options{MaxRetries: 1, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Second})
+
+	start := time.Now()
+	resp, err := c.Do(mustRequest(t, http.MethodGet, srv.URL))
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want the server's Retry-After of 1s", elapsed)
+	}
+	if resp.StatusCode != 200 || hits.Load() != 2 {
+		t.Errorf("status %d after %d requests", resp.StatusCode, hits.Load())
+	}
+
+	// A Retry-After beyond MaxBackoff is ignored in favour of the normal backoff
+	srv, _ = flakyServer(t, http.Header{"Retry-After": {"30"}}, 429, 200)
+	c = testClient(Options{MaxRetries: 1, BaseBackoff: time.Millisecond, MaxBackoff: 100 * time.Millisecond})
+	start = time.Now()
+	resp, err = c.Do(mustRequest(t, http.MethodGet, srv.URL))
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("waited %v for a Retry-After above MaxBackoff", elapsed)
+	}
+}
+
+func TestPostIsNotRetried(t *testing.T) {
+	srv, hits := flakyServer(t, nil, 503, 200)
+	c := testClient(Options{MaxRetries: 3, BaseBackoff: time.Millisecond})
+
+	resp, err := c.Do(mustRequestBody(t, http.MethodPost, srv.URL, `{"a":1}`))
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 503 || hits.Load() != 1 {
+		t.Errorf("status %d after %d requests, want 503 after 1", resp.StatusCode, hits.Load())
+	}
+}
+
+func mustRequest(t *testing.T, method, url string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func mustRequestBody(t *testing.T, method, url, body string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
@@ -0,0 +1,33 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrCommandRejected is returned for remote control commands the server or the
+// target player refused or doesn't implement.
+var ErrCommandRejected = errors.New("command not supported")
+
+// CommandStatus turns the result of a fire-and-forget command request (pause,
+// stop, ...) into an error and closes the response body. 400, 404, 405 and 501
+// responses wrap ErrCommandRejected; other non-2xx responses are plain errors.
+func CommandStatus(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusBadRequest, resp.StatusCode == http.StatusNotFound,
+		resp.StatusCode == http.StatusMethodNotAllowed, resp.StatusCode == http.StatusNotImplemented:
+		return fmt.Errorf("%w: HTTP %d", ErrCommandRejected, resp.StatusCode)
+	default:
+		return fmt.Errorf("command failed: HTTP %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,98 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Failure kinds reported by Diagnose.
+const (
+	FailureAuth        = "auth"         // 401/403: the key or token was rejected
+	FailureTLS         = "tls"          // certificate or handshake problem
+	FailureDNS         = "dns"          // the host name doesn't resolve
+	FailureNetwork     = "network"      // refused, unreachable or reset connections
+	FailureTimeout     = "timeout"      // no answer in time
+	FailureProxy       = "proxy"        // the configured proxy failed
+	FailureNotFound    = "not_found"    // 404: the URL doesn't point at the server's API
+	FailureServer      = "server_error" // 5xx or 429
+	FailureCircuitOpen = "circuit_open" // requests to the host are paused after repeated failures
+	FailureHTTP        = "http"         // any other HTTP status
+	FailureResponse    = "bad_response" // an answer that isn't the API's, e.g. a login page
+	FailureUnknown     = "unknown"
+)
+
+// statusInError finds the status in the errors the media clients build from responses,
+// e.g. "http 401 from …", "HTTP 403" or "server error: 503".
+var statusInError = regexp.MustCompile(`(?i)\b(?:http|server error:)\s+(\d{3})\b`)
+
+// Diagnose sorts a failed media server request into one of the Failure kinds, with the
+// HTTP status when there was a response.
+func Diagnose(err error) (kind string, status int) {
+	if err == nil {
+		return "", 0
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return FailureCircuitOpen, 0
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var verify *tls.CertificateVerificationError
+	var record tls.RecordHeaderError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid) ||
+		errors.As(err, &verify) || errors.As(err, &record) {
+		return FailureTLS, 0
+	}
+
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "proxyconnect") || strings.Contains(msg, "socks connect") {
+		return FailureProxy, 0
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return FailureTimeout, 0
+		}
+		return FailureDNS, 0
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return FailureTimeout, 0
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return FailureNetwork, 0
+	}
+	if strings.Contains(msg, "tls:") || strings.Contains(msg, "x509:") {
+		return FailureTLS, 0
+	}
+
+	if m := statusInError.FindStringSubmatch(err.Error()); m != nil {
+		status, _ = strconv.Atoi(m[1])
+		switch {
+		case status == 401 || status == 403:
+			return FailureAuth, status
+		case status == 404:
+			return FailureNotFound, status
+		case status >= 500 || status == 429:
+			return FailureServer, status
+		}
+		return FailureHTTP, status
+	}
+	if strings.Contains(msg, "invalid character") || strings.Contains(msg, "cannot unmarshal") ||
+		strings.Contains(msg, "xml syntax error") {
+		return FailureResponse, 0
+	}
+	if strings.Contains(msg, "connection refused") || strings.Contains(msg, "no route to host") ||
+		strings.Contains(msg, "connection reset") || strings.Contains(msg, "eof") {
+		return FailureNetwork, 0
+	}
+	return FailureUnknown, 0
+}
@@ -0,0 +1,72 @@
+package httpx
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HostStats summarises outbound requests made to a single media server host.
+type HostStats struct {
+	Host           string  `json:"host"`
+	Requests       int64   `json:"requests"`
+	Successes      int64   `json:"successes"`
+	Failures       int64   `json:"failures"`
+	Retries        int64   `json:"retries"`
+	ShortCircuited int64   `json:"short_circuited"`
+	BreakerOpens   int64   `json:"breaker_opens"`
+	BreakerState   string  `json:"breaker_state"`
+	AvgLatencyMs   float64 `json:"avg_latency_ms"`
+}
+
+type hostMetrics struct {
+	requests       atomic.Int64
+	successes      atomic.Int64
+	failures       atomic.Int64
+	retries        atomic.Int64
+	shortCircuited atomic.Int64
+	breakerOpens   atomic.Int64
+	latencyNanos   atomic.Int64
+}
+
+var metrics sync.Map // host -> *hostMetrics
+
+func metricsFor(host string) *hostMetrics {
+	if m, ok := metrics.Load(host); ok {
+		return m.(*hostMetrics)
+	}
+	m, _ := metrics.LoadOrStore(host, &hostMetrics{})
+	return m.(*hostMetrics)
+}
+
+func (m *hostMetrics) observe(d time.Duration) {
+	m.requests.Add(1)
+	m.latencyNanos.Add(int64(d))
+}
+
+// Snapshot returns per-host request metrics sorted by host.
+func Snapshot() []HostStats {
+	out := []HostStats{}
+	metrics.Range(func(k, v interface{}) bool {
+		host := k.(string)
+		m := v.(*hostMetrics)
+		s := HostStats{
+			Host:           host,
+			Requests:       m.requests.Load(),
+			Successes:      m.successes.Load(),
+			Failures:       m.failures.Load(),
+			Retries:        m.retries.Load(),
+			ShortCircuited: m.shortCircuited.Load(),
+			BreakerOpens:   m.breakerOpens.Load(),
+			BreakerState:   breakerFor(host).currentState(),
+		}
+		if s.Requests > 0 {
+			s.AvgLatencyMs = float64(m.latencyNanos.Load()) / float64(s.Requests) / float64(time.Millisecond)
+		}
+		out = append(out, s)
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Host < out[j].Host })
+	return out
+}
@@ -0,0 +1,102 @@
+package httpx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// TransportOptions holds per-server connection settings.
+type TransportOptions struct {
+	CAFile             string // PEM bundle trusted in addition to the system roots
+	InsecureSkipVerify bool   // skip certificate verification entirely (self-signed setups)
+	ProxyURL           string // http://, https://, socks5:// or socks5h:// proxy for this server
+}
+
+func (o TransportOptions) isZero() bool {
+	return o.CAFile == "" && !o.InsecureSkipVerify && o.ProxyURL == ""
+}
+
+// hostTransports maps a server host (host:port) to its dedicated transport.
+var hostTransports sync.Map
+
+// hostRouter sends each request through the transport configured for its host,
+// falling back to the shared pooled transport.
+type hostRouter struct{}
+
+func (hostRouter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t, ok := hostTransports.Load(req.URL.Host); ok {
+		return t.(*http.Transport).RoundTrip(req)
+	}
+	return sharedTransport.RoundTrip(req)
+}
+
+// Transport returns a RoundTripper that honours per-server TLS and proxy settings.
+// Use it for any raw http.Client that talks to a configured media server.
+func Transport() http.RoundTripper {
+	return hostRouter{}
+}
+
+// ConfigureHost applies TLS and proxy options to every request sent to baseURL's host.
+// Zero options remove any previous configuration for the host.
+func ConfigureHost(baseURL string, o TransportOptions) error {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid base url %q", baseURL)
+	}
+	if o.isZero() {
+		hostTransports.Delete(u.Host)
+		return nil
+	}
+
+	tlsCfg := &tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", o.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	t := sharedTransport.Clone()
+	t.TLSClientConfig = tlsCfg
+	if o.ProxyURL != "" {
+		pu, err := url.Parse(o.ProxyURL)
+		if err != nil || pu.Host == "" {
+			return errors.New("invalid proxy url")
+		}
+		switch pu.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("unsupported proxy scheme %q", pu.Scheme)
+		}
+		t.Proxy = http.ProxyURL(pu)
+	}
+	hostTransports.Store(u.Host, t)
+	return nil
+}
+
+// ProxyFunc returns the proxy configured for baseURL's host, or nil when the host
+// connects directly. Intended for non-HTTP dialers such as websocket clients.
+func ProxyFunc(baseURL string) func(*http.Request) (*url.URL, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil
+	}
+	if t, ok := hostTransports.Load(u.Host); ok {
+		return t.(*http.Transport).Proxy
+	}
+	return nil
+}
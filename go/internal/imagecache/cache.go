@@ -0,0 +1,238 @@
+package imagecache
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Entry is a cached image variant.
+type Entry struct {
+	Data        []byte
+	ContentType string
+	Color       string   // dominant color as #rrggbb; empty when it could not be extracted
+	Palette     []string // up to PaletteSize colors, most common first; Palette[0] is Color
+	ETag        string   // strong validator derived from Data
+	FetchedAt   time.Time
+}
+
+type item struct {
+	key   string
+	entry Entry
+}
+
+// PaletteStore persists palettes by color key so they survive restarts.
+type PaletteStore interface {
+	LoadPalette(colorKey string) ([]string, bool)
+	SavePalette(colorKey string, palette []string)
+}
+
+// Cache is a size-bounded LRU of image bytes keyed by variant (server/item/width).
+// Color palettes are remembered per image (server/item) independently of the
+// byte cache so they survive eviction of the larger variants.
+type Cache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	ttl      time.Duration
+	size     int64
+	ll       *list.List
+	items    map[string]*list.Element
+	palettes map[string][]string
+	store    PaletteStore
+	inflight map[string]bool
+}
+
+// Stats describes cache occupancy.
+type Stats struct {
+	Entries  int   `json:"entries"`
+	Bytes    int64 `json:"bytes"`
+	MaxBytes int64 `json:"max_bytes"`
+	Colors   int   `json:"colors"`
+}
+
+// New creates a cache holding up to maxBytes of image data for ttl.
+func New(maxBytes int64, ttl time.Duration) *Cache {
+	return &Cache{
+		maxBytes: maxBytes,
+		ttl:      ttl,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+		palettes: map[string][]string{},
+		inflight: map[string]bool{},
+	}
+}
+
+var defaultCache = New(64<<20, 24*time.Hour)
+
+// Default returns the process-wide image cache.
+func Default() *Cache { return defaultCache }
+
+// Configure resizes the default cache; non-positive values keep the current setting.
+func Configure(maxBytes int64, ttl time.Duration) {
+	c := defaultCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if maxBytes > 0 {
+		c.maxBytes = maxBytes
+	}
+	if ttl > 0 {
+		c.ttl = ttl
+	}
+	c.evictLocked()
+}
+
+// SetPaletteStore makes the cache load palettes from and save new ones to store.
+func (c *Cache) SetPaletteStore(store PaletteStore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store = store
+}
+
+// Get returns a fresh cached variant.
+func (c *Cache) Get(key string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	it := el.Value.(*item)
+	if time.Since(it.entry.FetchedAt) > c.ttl {
+		c.removeLocked(el)
+		return Entry{}, false
+	}
+	c.ll.MoveToFront(el)
+	return it.entry, true
+}
+
+// Put stores a variant and extracts the image's color palette the first time
+// colorKey is seen, unless the palette store already has it. It returns the
+// stored entry.
+func (c *Cache) Put(key, colorKey string, data []byte, contentType string) Entry {
+	c.mu.Lock()
+	palette, known := c.palettes[colorKey]
+	store := c.store
+	c.mu.Unlock()
+	if !known && colorKey != "" && store != nil {
+		palette, known = store.LoadPalette(colorKey)
+	}
+	if !known {
+		palette, _ = Palette(data, PaletteSize)
+		if colorKey != "" && len(palette) > 0 && store != nil {
+			store.SavePalette(colorKey, palette)
+		}
+	}
+
+	e := Entry{Data: data, ContentType: contentType, Palette: palette, ETag: etagFor(data), FetchedAt: time.Now()}
+	if len(palette) > 0 {
+		e.Color = palette[0]
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if colorKey != "" && len(palette) > 0 {
+		c.palettes[colorKey] = palette
+	}
+	if int64(len(data)) > c.maxBytes {
+		return e
+	}
+	if el, ok := c.items[key]; ok {
+		c.removeLocked(el)
+	}
+	c.items[key] = c.ll.PushFront(&item{key: key, entry: e})
+	c.size += int64(len(data))
+	c.evictLocked()
+	return e
+}
+
+// etagFor returns a quoted entity tag for image bytes.
+func etagFor(data []byte) string {
+	sum := sha1.Sum(data)
+	return `"` + hex.EncodeToString(sum[:10]) + `"`
+}
+
+// Color returns the dominant color recorded for colorKey.
+func (c *Cache) Color(colorKey string) (string, bool) {
+	palette, ok := c.Palette(colorKey)
+	if !ok {
+		return "", false
+	}
+	return palette[0], true
+}
+
+// Palette returns the palette recorded for colorKey, consulting the palette
+// store when it isn't in memory.
+func (c *Cache) Palette(colorKey string) ([]string, bool) {
+	c.mu.Lock()
+	palette, ok := c.palettes[colorKey]
+	store := c.store
+	c.mu.Unlock()
+	if ok || store == nil || colorKey == "" {
+		return palette, ok
+	}
+	palette, ok = store.LoadPalette(colorKey)
+	if !ok || len(palette) == 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	c.palettes[colorKey] = palette
+	c.mu.Unlock()
+	return palette, true
+}
+
+// Prefetch fetches and stores a variant in the background unless it is cached
+// or already being fetched.
+func (c *Cache) Prefetch(key, colorKey string, fetch func() ([]byte, string, error)) {
+	if _, ok := c.Get(key); ok {
+		return
+	}
+	c.mu.Lock()
+	if c.inflight[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.inflight[key] = true
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.inflight, key)
+			c.mu.Unlock()
+		}()
+		data, ct, err := fetch()
+		if err != nil || len(data) == 0 {
+			return
+		}
+		c.Put(key, colorKey, data, ct)
+	}()
+}
+
+// Stats returns current occupancy.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{Entries: len(c.items), Bytes: c.size, MaxBytes: c.maxBytes, Colors: len(c.palettes)}
+}
+
+func (c *Cache) removeLocked(el *list.Element) {
+	it := el.Value.(*item)
+	c.ll.Remove(el)
+	delete(c.items, it.key)
+	c.size -= int64(len(it.entry.Data))
+}
+
+func (c *Cache) evictLocked() {
+	for c.size > c.maxBytes {
+		el := c.ll.Back()
+		if el == nil {
+			return
+		}
+		c.removeLocked(el)
+	}
+	// Keep the palette index bounded as well
+	if len(c.palettes) > 10000 {
+		c.palettes = map[string][]string{}
+	}
+}
@@ -0,0 +1,144 @@
+package imagecache
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"sort"
+)
+
+// PaletteSize is the number of colors extracted per image.
+const PaletteSize = 5
+
+// DominantColor returns the most common color of an encoded image as #rrggbb.
+func DominantColor(data []byte) (string, error) {
+	palette, err := Palette(data, 1)
+	if err != nil {
+		return "", err
+	}
+	return palette[0], nil
+}
+
+// Palette returns up to n distinct colors of an encoded image as #rrggbb, most
+// common first. Pixels are sampled on a coarse grid and bucketed to 4 bits per
+// channel; near-black and near-white buckets are skipped unless nothing else
+// remains, since letterboxing and title cards otherwise dominate most posters.
+// Buckets too close to an already chosen color are passed over so gradients
+// don't fill the palette with shades of one hue.
+func Palette(data []byte, n int) ([]string, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	if b.Empty() {
+		return nil, fmt.Errorf("empty image")
+	}
+	step := b.Dx() / 48
+	if s := b.Dy() / 48; s > step {
+		step = s
+	}
+	if step < 1 {
+		step = 1
+	}
+
+	buckets := map[uint16]*colorAcc{}
+	var fallback colorAcc
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		for x := b.Min.X; x < b.Max.X; x += step {
+			r32, g32, b32, a32 := img.At(x, y).RGBA()
+			if a32 < 0x8000 {
+				continue
+			}
+			r, g, bl := r32>>8, g32>>8, b32>>8
+			fallback.add(r, g, bl)
+			maxC, minC := max(r, g, bl), min(r, g, bl)
+			if maxC < 24 || minC > 232 {
+				continue
+			}
+			key := uint16(r>>4)<<8 | uint16(g>>4)<<4 | uint16(bl>>4)
+			a := buckets[key]
+			if a == nil {
+				a = &colorAcc{}
+				buckets[key] = a
+			}
+			a.add(r, g, bl)
+		}
+	}
+	if fallback.n == 0 {
+		return nil, fmt.Errorf("no opaque pixels")
+	}
+	if len(buckets) == 0 {
+		return []string{fallback.hex()}, nil
+	}
+
+	keys := make([]uint16, 0, len(buckets))
+	for k := range buckets {
+		keys = append(keys, k)
+	}
+	// Deterministic tie-break on bucket key
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := buckets[keys[i]], buckets[keys[j]]
+		if a.n != b.n {
+			return a.n > b.n
+		}
+		return keys[i] < keys[j]
+	})
+	var chosen []*colorAcc
+	for _, k := range keys {
+		if len(chosen) >= n {
+			break
+		}
+		a := buckets[k]
+		distinct := true
+		for _, c := range chosen {
+			if a.distance(c) < minPaletteDistance {
+				distinct = false
+				break
+			}
+		}
+		if distinct {
+			chosen = append(chosen, a)
+		}
+	}
+	out := make([]string, len(chosen))
+	for i, a := range chosen {
+		out[i] = a.hex()
+	}
+	return out, nil
+}
+
+// minPaletteDistance is the squared RGB distance below which two colors count as one.
+const minPaletteDistance = 48 * 48
+
+type colorAcc struct {
+	n       int
+	r, g, b uint64
+}
+
+func (a *colorAcc) add(r, g, b uint32) {
+	a.n++
+	a.r += uint64(r)
+	a.g += uint64(g)
+	a.b += uint64(b)
+}
+
+func (a *colorAcc) rgb() (int, int, int) {
+	n := uint64(a.n)
+	return int(a.r / n), int(a.g / n), int(a.b / n)
+}
+
+func (a *colorAcc) hex() string {
+	r, g, b := a.rgb()
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+func (a *colorAcc) distance(o *colorAcc) int {
+	r1, g1, b1 := a.rgb()
+	r2, g2, b2 := o.rgb()
+	dr, dg, db := r1-r2, g1-g2, b1-b2
+	return dr*dr + dg*dg + db*db
+}
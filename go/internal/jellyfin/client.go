@@ -13,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"emby-analytics/internal/httpx"
 	"emby-analytics/internal/media"
 )
 
@@ -23,7 +24,7 @@ type Client struct {
 	baseURL     string
 	apiKey      string
 	externalURL string
-	http        *http.Client
+	http        *httpx.Client
 	cache       sync.Map
 	cacheTTL    time.Duration
 }
@@ -37,14 +38,7 @@ func New(config media.ServerConfig) *Client {
 		apiKey:      config.APIKey,
 		externalURL: config.ExternalURL,
 		cacheTTL:    time.Hour,
-		http: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:       10,
-				IdleConnTimeout:    30 * time.Second,
-				DisableCompression: false,
-			},
-		},
+		http:        httpx.New(),
 	}
 }
 
@@ -117,9 +111,19 @@ type jellyfinSession struct {
 		TranscodeReasons       []string `json:"TranscodeReasons"`
 		CompletionPercentage   float64  `json:"CompletionPercentage"`
 		TranscodePositionTicks int64    `json:"TranscodePositionTicks"`
+		// "none" or the acceleration API in use, e.g. "vaapi", "qsv", "nvenc"
+		HardwareAccelerationType string `json:"HardwareAccelerationType"`
 	} `json:"TranscodingInfo"`
 }
 
+// jellyfinSyncPlayGroup mirrors GroupInfoDto returned by /SyncPlay/List
+type jellyfinSyncPlayGroup struct {
+	GroupId      string   `json:"GroupId"`
+	GroupName    string   `json:"GroupName"`
+	State        string   `json:"State"`
+	Participants []string `json:"Participants"` // user names
+}
+
 type jellyfinUser struct {
 	Id   string `json:"Id"`
 	Name string `json:"Name"`
@@ -144,6 +148,8 @@ type jellyfinMediaItem struct {
 	RunTimeTicks      *int64   `json:"RunTimeTicks"`
 	Container         string   `json:"Container"`
 	Genres            []string `json:"Genres"`
+	// External ids such as {"Imdb": "tt0111161"}
+	ProviderIds map[string]string `json:"ProviderIds,omitempty"`
 }
 
 // Jellyfin API uses 100-nanosecond ticks like Emby
@@ -196,40 +202,6 @@ func (c *Client) doRequest(endpoint string) (*http.Response, error) {
 	return c.http.Do(req)
 }
 
-// doWithRetry performs HTTP request with exponential backoff retry
-func (c *Client) doWithRetry(req *http.Request, maxRetries int) (*http.Response, error) {
-	var lastErr error
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		var reqClone *http.Request
-		if attempt > 0 {
-			reqClone = req.Clone(req.Context())
-		} else {
-			reqClone = req
-		}
-
-		resp, err := c.http.Do(reqClone)
-		if err == nil && resp.StatusCode < 500 {
-			return resp, nil
-		}
-
-		if resp != nil {
-			resp.Body.Close()
-		}
-
-		lastErr = err
-		if err == nil {
-			lastErr = fmt.Errorf("server error: %d", resp.StatusCode)
-		}
-
-		if attempt < maxRetries {
-			backoff := time.Duration(1<<uint(attempt)) * time.Second
-			time.Sleep(backoff)
-		}
-	}
-
-	return nil, fmt.Errorf("request failed after %d attempts: %w", maxRetries+1, lastErr)
-}
-
 // readJSON reads and parses JSON response
 func readJSON(resp *http.Response, dst interface{}) error {
 	defer resp.Body.Close()
@@ -267,7 +239,7 @@ func (c *Client) GetActiveSessions() ([]media.Session, error) {
 	req, _ := http.NewRequest("GET", u+"?"+q.Encode(), nil)
 	req.Header.Set("X-Emby-Token", c.apiKey)
 
-	resp, err := c.doWithRetry(req, 2)
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -289,9 +261,88 @@ func (c *Client) GetActiveSessions() ([]media.Session, error) {
 		sessions = append(sessions, session)
 	}
 
+	if len(sessions) > 1 {
+		c.annotateSyncPlayGroups(sessions)
+	}
+
 	return sessions, nil
 }
 
+// annotateSyncPlayGroups tags sessions with the SyncPlay group they take part in.
+// Failures are ignored: group info is an enrichment, not required for session tracking.
+func (c *Client) annotateSyncPlayGroups(sessions []media.Session) {
+	groups, err := c.getSyncPlayGroups()
+	if err != nil || len(groups) == 0 {
+		return
+	}
+	assignSyncPlayGroups(groups, sessions)
+}
+
+// assignSyncPlayGroups matches groups to sessions. Jellyfin names participants by user only,
+// so a user's other devices must not join the group with them: a group is matched to the
+// playing sessions of its participants that play the group's item, the item most of them
+// are playing. Groups whose item is ambiguous are skipped.
+func assignSyncPlayGroups(groups []jellyfinSyncPlayGroup, sessions []media.Session) {
+	byUser := make(map[string][]int)
+	for i, s := range sessions {
+		if s.ItemID != "" {
+			u := strings.ToLower(strings.TrimSpace(s.UserName))
+			byUser[u] = append(byUser[u], i)
+		}
+	}
+	for _, g := range groups {
+		members := make(map[string]bool)
+		votes := make(map[string]int) // item -> participants playing it
+		for _, p := range g.Participants {
+			u := strings.ToLower(strings.TrimSpace(p))
+			if members[u] {
+				continue
+			}
+			members[u] = true
+			items := make(map[string]bool)
+			for _, i := range byUser[u] {
+				items[sessions[i].ItemID] = true
+			}
+			for item := range items {
+				votes[item]++
+			}
+		}
+		item, best, tied := "", 0, false
+		for it, n := range votes {
+			switch {
+			case n > best:
+				item, best, tied = it, n, false
+			case n == best:
+				tied = true
+			}
+		}
+		if best == 0 || tied {
+			continue
+		}
+		for u := range members {
+			for _, i := range byUser[u] {
+				if sessions[i].ItemID == item && sessions[i].SyncPlayGroupID == "" {
+					sessions[i].SyncPlayGroupID = g.GroupId
+					sessions[i].SyncPlayGroupName = g.GroupName
+				}
+			}
+		}
+	}
+}
+
+// getSyncPlayGroups lists active SyncPlay groups visible to the API key
+func (c *Client) getSyncPlayGroups() ([]jellyfinSyncPlayGroup, error) {
+	resp, err := c.doRequest("/SyncPlay/List")
+	if err != nil {
+		return nil, err
+	}
+	var groups []jellyfinSyncPlayGroup
+	if err := readJSON(resp, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
 // convertSession converts Jellyfin session to normalized Session
 func (c *Client) convertSession(jellySess jellyfinSession) media.Session {
 	session := media.Session{
@@ -306,6 +357,8 @@ func (c *Client) convertSession(jellySess jellyfinSession) media.Session {
 		SeriesID:      jellySess.NowPlayingItem.SeriesId,
 		DurationMs:    ticksToMs(jellySess.NowPlayingItem.RunTimeTicks),
 		ClientApp:     jellySess.Client,
+		ClientVersion: jellySess.ApplicationVersion,
+		Platform:      media.NormalizePlatform("", jellySess.Client, jellySess.DeviceName),
 		DeviceName:    jellySess.DeviceName,
 		RemoteAddress: jellySess.RemoteEndPoint,
 		Container:     strings.ToUpper(jellySess.NowPlayingItem.Container),
@@ -341,6 +394,8 @@ func (c *Client) convertSession(jellySess jellyfinSession) media.Session {
 		currentAudioIndex = jellySess.PlayState.AudioStreamIndex
 		currentSubtitleIndex = jellySess.PlayState.SubtitleStreamIndex
 	}
+	session.AudioTrack = media.TrackFromIndex(currentAudioIndex)
+	session.SubtitleTrack = media.TrackFromIndex(currentSubtitleIndex)
 
 	// Process media streams
 	for _, ms := range jellySess.NowPlayingItem.MediaStreams {
@@ -448,6 +503,10 @@ func (c *Client) convertSession(jellySess jellyfinSession) media.Session {
 		session.TranscodeHeight = jellySess.TranscodingInfo.Height
 		session.TranscodeBitrate = jellySess.TranscodingInfo.VideoBitrate
 		session.TranscodeReasons = jellySess.TranscodingInfo.TranscodeReasons
+		session.TranscodeFramerate = jellySess.TranscodingInfo.Framerate
+		if hw := jellySess.TranscodingInfo.HardwareAccelerationType; hw != "" && !strings.EqualFold(hw, "none") {
+			session.TranscodeHardware = true
+		}
 
 		// Fill FROM using detected source codecs
 		if sourceVideoCodec != "" {
@@ -554,6 +613,50 @@ func (c *Client) GetUsers() ([]media.User, error) {
 	return users, nil
 }
 
+// AuthenticateUser verifies a user's own Jellyfin credentials via /Users/AuthenticateByName.
+// The access token Jellyfin issues is logged out again right away; only the identity is needed.
+func (c *Client) AuthenticateUser(username, password string) (*media.User, error) {
+	body, _ := json.Marshal(map[string]string{"Username": username, "Pw": password})
+	req, _ := http.NewRequest("POST", c.baseURL+"/Users/AuthenticateByName", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", `MediaBrowser Client="Emby Analytics", Device="Emby Analytics", DeviceId="emby-analytics-login", Version="1.0"`)
+
+	// Bypass retries and credential tracking: a wrong password must not count
+	// against the server's API key health.
+	resp, err := c.http.HTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		resp.Body.Close()
+		return nil, media.ErrInvalidCredentials
+	}
+	var out struct {
+		User        jellyfinUser `json:"User"`
+		AccessToken string       `json:"AccessToken"`
+	}
+	if err := readJSON(resp, &out); err != nil {
+		return nil, err
+	}
+	if out.User.Id == "" {
+		return nil, media.ErrInvalidCredentials
+	}
+
+	if out.AccessToken != "" {
+		logout, _ := http.NewRequest("POST", c.baseURL+"/Sessions/Logout", nil)
+		logout.Header.Set("Authorization", `MediaBrowser Token="`+out.AccessToken+`"`)
+		if r, err := c.http.HTTPClient().Do(logout); err == nil {
+			r.Body.Close()
+		}
+	}
+	return &media.User{
+		ID:         out.User.Id,
+		Name:       out.User.Name,
+		ServerID:   c.serverID,
+		ServerType: media.ServerTypeJellyfin,
+	}, nil
+}
+
 // ItemsByIDs fetches media items by IDs
 func (c *Client) ItemsByIDs(ids []string) ([]media.MediaItem, error) {
 	if len(ids) == 0 {
@@ -570,12 +673,12 @@ func (c *Client) ItemsByIDs(ids []string) ([]media.MediaItem, error) {
 	q := url.Values{}
 	q.Set("api_key", c.apiKey)
 	q.Set("Ids", strings.Join(ids, ","))
-	q.Set("Fields", "SeriesId,SeriesName,ParentIndexNumber,IndexNumber")
+	q.Set("Fields", "SeriesId,SeriesName,ParentIndexNumber,IndexNumber,ProviderIds")
 
 	req, _ := http.NewRequest("GET", u+"?"+q.Encode(), nil)
 	req.Header.Set("X-Emby-Token", c.apiKey)
 
-	resp, err := c.doWithRetry(req, 2)
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -607,6 +710,12 @@ func (c *Client) ItemsByIDs(ids []string) ([]media.MediaItem, error) {
 		if jellyItem.ProductionYear != nil {
 			item.ProductionYear = jellyItem.ProductionYear
 		}
+		if len(jellyItem.ProviderIds) > 0 {
+			item.ProviderIDs = make(map[string]string, len(jellyItem.ProviderIds))
+			for k, v := range jellyItem.ProviderIds {
+				item.ProviderIDs[strings.ToLower(k)] = v
+			}
+		}
 
 		// Episode-specific fields
 		if jellyItem.Type == "Episode" {
@@ -639,7 +748,7 @@ func (c *Client) FetchLibraryItems(includeTypes []string) ([]media.MediaItem, er
 		q.Set("api_key", c.apiKey)
 		q.Set("Recursive", "true")
 		q.Set("IncludeItemTypes", typesParam)
-		q.Set("Fields", "MediaSources,MediaStreams,RunTimeTicks,Container,Genres,ProductionYear,SeriesId,SeriesName,ParentIndexNumber,IndexNumber")
+		q.Set("Fields", "MediaSources,MediaStreams,RunTimeTicks,Container,Genres,Tags,ProductionYear,SeriesId,SeriesName,ParentIndexNumber,IndexNumber,ProviderIds")
 		q.Set("EnableTotalRecordCount", "true")
 		q.Set("StartIndex", strconv.Itoa(start))
 		q.Set("Limit", strconv.Itoa(pageSize))
@@ -647,24 +756,27 @@ func (c *Client) FetchLibraryItems(includeTypes []string) ([]media.MediaItem, er
 		req, _ := http.NewRequest("GET", u+"?"+q.Encode(), nil)
 		req.Header.Set("X-Emby-Token", c.apiKey)
 
-		resp, err := c.doWithRetry(req, 2)
+		resp, err := c.http.Do(req)
 		if err != nil {
 			return nil, err
 		}
 
 		var out struct {
 			Items []struct {
-				Id                string   `json:"Id"`
-				Name              string   `json:"Name"`
-				Type              string   `json:"Type"`
-				RunTimeTicks      *int64   `json:"RunTimeTicks"`
-				Container         string   `json:"Container"`
-				Genres            []string `json:"Genres"`
-				ProductionYear    *int     `json:"ProductionYear"`
-				SeriesId          string   `json:"SeriesId"`
-				SeriesName        string   `json:"SeriesName"`
-				ParentIndexNumber *int     `json:"ParentIndexNumber"`
-				IndexNumber       *int     `json:"IndexNumber"`
+				Id                string            `json:"Id"`
+				Name              string            `json:"Name"`
+				Type              string            `json:"Type"`
+				RunTimeTicks      *int64            `json:"RunTimeTicks"`
+				Container         string            `json:"Container"`
+				Genres            []string          `json:"Genres"`
+				Tags              []string          `json:"Tags"`
+				OfficialRating    string            `json:"OfficialRating"`
+				ProductionYear    *int              `json:"ProductionYear"`
+				SeriesId          string            `json:"SeriesId"`
+				SeriesName        string            `json:"SeriesName"`
+				ParentIndexNumber *int              `json:"ParentIndexNumber"`
+				IndexNumber       *int              `json:"IndexNumber"`
+				ProviderIds       map[string]string `json:"ProviderIds"`
 				MediaSources      []struct {
 					Container string `json:"Container"`
 					Bitrate   *int64 `json:"Bitrate"`
@@ -693,12 +805,20 @@ func (c *Client) FetchLibraryItems(includeTypes []string) ([]media.MediaItem, er
 				Type:           raw.Type,
 				Container:      raw.Container,
 				Genres:         raw.Genres,
+				Tags:           raw.Tags,
+				OfficialRating: raw.OfficialRating,
 				ProductionYear: raw.ProductionYear,
 			}
 			if raw.RunTimeTicks != nil {
 				runtimeMs := ticksToMs(*raw.RunTimeTicks)
 				item.RuntimeMs = &runtimeMs
 			}
+			if len(raw.ProviderIds) > 0 {
+				item.ProviderIDs = make(map[string]string, len(raw.ProviderIds))
+				for k, v := range raw.ProviderIds {
+					item.ProviderIDs[strings.ToLower(k)] = v
+				}
+			}
 			if len(raw.MediaSources) > 0 {
 				source := raw.MediaSources[0]
 				if item.Container == "" {
@@ -825,7 +945,24 @@ func (c *Client) GetUserData(userID string) ([]media.UserDataItem, error) {
 	q.Set("Fields", "UserData,RunTimeTicks")
 	q.Set("IncludeItemTypes", "Movie,Episode")
 	q.Set("Filters", "IsPlayed")
+	return c.fetchUserDataItems(u, q)
+}
 
+// GetResumeItems returns the user's continue-watching list: videos with a saved
+// playback position, most recently played first.
+func (c *Client) GetResumeItems(userID string, limit int) ([]media.UserDataItem, error) {
+	u := fmt.Sprintf("%s/Users/%s/Items/Resume", c.baseURL, userID)
+	q := url.Values{}
+	q.Set("api_key", c.apiKey)
+	q.Set("Fields", "UserData,RunTimeTicks")
+	q.Set("MediaTypes", "Video")
+	if limit > 0 {
+		q.Set("Limit", fmt.Sprintf("%d", limit))
+	}
+	return c.fetchUserDataItems(u, q)
+}
+
+func (c *Client) fetchUserDataItems(u string, q url.Values) ([]media.UserDataItem, error) {
 	req, _ := http.NewRequest("GET", u+"?"+q.Encode(), nil)
 	req.Header.Set("X-Emby-Token", c.apiKey)
 
@@ -839,6 +976,7 @@ func (c *Client) GetUserData(userID string) ([]media.UserDataItem, error) {
 			Id           string `json:"Id"`
 			Name         string `json:"Name"`
 			Type         string `json:"Type"`
+			SeriesName   string `json:"SeriesName"`
 			RunTimeTicks *int64 `json:"RunTimeTicks"`
 			UserData     struct {
 				Played                bool   `json:"Played"`
@@ -869,6 +1007,7 @@ func (c *Client) GetUserData(userID string) ([]media.UserDataItem, error) {
 			PlayCount:          it.UserData.PlayCount,
 			PlaybackPositionMs: ticksToMs(it.UserData.PlaybackPositionTicks),
 			LastPlayed:         it.UserData.LastPlayedDate,
+			SeriesName:         it.SeriesName,
 		})
 	}
 
@@ -882,11 +1021,7 @@ func (c *Client) PauseSession(sessionID string) error {
 	u := fmt.Sprintf("%s/Sessions/%s/Playing/Pause?api_key=%s", c.baseURL, sessionID, url.QueryEscape(c.apiKey))
 	req, _ := http.NewRequest("POST", u, nil)
 	req.Header.Set("X-Emby-Token", c.apiKey)
-	resp, err := c.http.Do(req)
-	if resp != nil {
-		resp.Body.Close()
-	}
-	return err
+	return httpx.CommandStatus(c.http.Do(req))
 }
 
 // UnpauseSession resumes a Jellyfin session
@@ -894,11 +1029,7 @@ func (c *Client) UnpauseSession(sessionID string) error {
 	u := fmt.Sprintf("%s/Sessions/%s/Playing/Unpause?api_key=%s", c.baseURL, sessionID, url.QueryEscape(c.apiKey))
 	req, _ := http.NewRequest("POST", u, nil)
 	req.Header.Set("X-Emby-Token", c.apiKey)
-	resp, err := c.http.Do(req)
-	if resp != nil {
-		resp.Body.Close()
-	}
-	return err
+	return httpx.CommandStatus(c.http.Do(req))
 }
 
 // StopSession stops a Jellyfin session
@@ -906,11 +1037,7 @@ func (c *Client) StopSession(sessionID string) error {
 	u := fmt.Sprintf("%s/Sessions/%s/Playing/Stop?api_key=%s", c.baseURL, sessionID, url.QueryEscape(c.apiKey))
 	req, _ := http.NewRequest("POST", u, nil)
 	req.Header.Set("X-Emby-Token", c.apiKey)
-	resp, err := c.http.Do(req)
-	if resp != nil {
-		resp.Body.Close()
-	}
-	return err
+	return httpx.CommandStatus(c.http.Do(req))
 }
 
 // SendMessage sends a message to a Jellyfin session
@@ -976,6 +1103,203 @@ func (c *Client) CheckHealth() (*media.ServerHealth, error) {
 	return health, nil
 }
 
+// jellyfinLiveTvItem covers the fields shared by recordings and timers
+type jellyfinLiveTvItem struct {
+	Id            string `json:"Id"`
+	Name          string `json:"Name"`
+	SeriesName    string `json:"SeriesName"`
+	ChannelName   string `json:"ChannelName"`
+	Status        string `json:"Status"`
+	StartDate     string `json:"StartDate"`
+	EndDate       string `json:"EndDate"`
+	SeriesTimerId string `json:"SeriesTimerId"`
+	MediaSources  []struct {
+		Size int64 `json:"Size"`
+	} `json:"MediaSources"`
+}
+
+type jellyfinSeriesTimer struct {
+	Id               string `json:"Id"`
+	Name             string `json:"Name"`
+	ChannelName      string `json:"ChannelName"`
+	RecordNewOnly    bool   `json:"RecordNewOnly"`
+	RecordAnyChannel bool   `json:"RecordAnyChannel"`
+}
+
+func parseJellyfinTime(v string) *time.Time {
+	if v == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func (c *Client) convertLiveTvItem(it jellyfinLiveTvItem, scheduled bool) media.Recording {
+	rec := media.Recording{
+		ServerID:      c.serverID,
+		ServerType:    media.ServerTypeJellyfin,
+		ID:            it.Id,
+		Name:          it.Name,
+		SeriesName:    it.SeriesName,
+		ChannelName:   it.ChannelName,
+		Status:        it.Status,
+		StartDate:     parseJellyfinTime(it.StartDate),
+		EndDate:       parseJellyfinTime(it.EndDate),
+		SeriesTimerID: it.SeriesTimerId,
+		Scheduled:     scheduled,
+	}
+	for _, ms := range it.MediaSources {
+		rec.SizeBytes += ms.Size
+	}
+	return rec
+}
+
+// GetRecordings returns all DVR recordings
+func (c *Client) GetRecordings() ([]media.Recording, error) {
+	resp, err := c.doRequest("/LiveTv/Recordings?Fields=MediaSources")
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Items []jellyfinLiveTvItem `json:"Items"`
+	}
+	if err := readJSON(resp, &out); err != nil {
+		return nil, err
+	}
+	recs := make([]media.Recording, 0, len(out.Items))
+	for _, it := range out.Items {
+		recs = append(recs, c.convertLiveTvItem(it, false))
+	}
+	return recs, nil
+}
+
+// GetScheduledRecordings returns upcoming timers
+func (c *Client) GetScheduledRecordings() ([]media.Recording, error) {
+	resp, err := c.doRequest("/LiveTv/Timers")
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Items []jellyfinLiveTvItem `json:"Items"`
+	}
+	if err := readJSON(resp, &out); err != nil {
+		return nil, err
+	}
+	recs := make([]media.Recording, 0, len(out.Items))
+	for _, it := range out.Items {
+		recs = append(recs, c.convertLiveTvItem(it, true))
+	}
+	return recs, nil
+}
+
+// GetSeriesTimers returns configured series recording rules
+func (c *Client) GetSeriesTimers() ([]media.SeriesTimer, error) {
+	resp, err := c.doRequest("/LiveTv/SeriesTimers")
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Items []jellyfinSeriesTimer `json:"Items"`
+	}
+	if err := readJSON(resp, &out); err != nil {
+		return nil, err
+	}
+	timers := make([]media.SeriesTimer, 0, len(out.Items))
+	for _, t := range out.Items {
+		timers = append(timers, media.SeriesTimer{
+			ServerID:         c.serverID,
+			ServerType:       media.ServerTypeJellyfin,
+			ID:               t.Id,
+			Name:             t.Name,
+			ChannelName:      t.ChannelName,
+			RecordNewOnly:    t.RecordNewOnly,
+			RecordAnyChannel: t.RecordAnyChannel,
+		})
+	}
+	return timers, nil
+}
+
+// Collections
+
+// ReplaceCollection creates the named collection with the given items, then deletes
+// previousID, the collection an earlier export created
+func (c *Client) ReplaceCollection(previousID, name string, itemIDs []string) (string, error) {
+	cq := url.Values{}
+	cq.Set("api_key", c.apiKey)
+	cq.Set("Name", name)
+	cq.Set("Ids", strings.Join(itemIDs, ","))
+	cq.Set("IsLocked", "true")
+	req, _ := http.NewRequest("POST", fmt.Sprintf("%s/Collections?%s", c.baseURL, cq.Encode()), nil)
+	req.Header.Set("X-Emby-Token", c.apiKey)
+	cresp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		Id string `json:"Id"`
+	}
+	if err := readJSON(cresp, &created); err != nil {
+		return "", err
+	}
+	if previousID == "" || previousID == created.Id {
+		return created.Id, nil
+	}
+	u := fmt.Sprintf("%s/Items/%s?api_key=%s", c.baseURL, previousID, url.QueryEscape(c.apiKey))
+	dreq, _ := http.NewRequest("DELETE", u, nil)
+	dreq.Header.Set("X-Emby-Token", c.apiKey)
+	dresp, err := c.http.Do(dreq)
+	if err != nil {
+		return created.Id, err
+	}
+	dresp.Body.Close()
+	if dresp.StatusCode >= 300 {
+		return created.Id, fmt.Errorf("delete collection %s: http %d", previousID, dresp.StatusCode)
+	}
+	return created.Id, nil
+}
+
+// Library scans
+
+// RefreshLibrary starts a scan of all libraries on the server
+func (c *Client) RefreshLibrary() error {
+	u := fmt.Sprintf("%s/Library/Refresh?api_key=%s", c.baseURL, url.QueryEscape(c.apiKey))
+	req, _ := http.NewRequest("POST", u, nil)
+	req.Header.Set("X-Emby-Token", c.apiKey)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("library refresh: http %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// LibraryScanRunning reports whether the "Scan Media Library" scheduled task is running
+func (c *Client) LibraryScanRunning() (bool, error) {
+	resp, err := c.doRequest("/ScheduledTasks?IsHidden=false")
+	if err != nil {
+		return false, err
+	}
+	var tasks []struct {
+		Key   string `json:"Key"`
+		State string `json:"State"`
+	}
+	if err := readJSON(resp, &tasks); err != nil {
+		return false, err
+	}
+	for _, t := range tasks {
+		if t.Key == "RefreshLibrary" {
+			return t.State != "Idle", nil
+		}
+	}
+	return false, nil
+}
+
 // Cache management
 
 func (c *Client) generateCacheKey(ids []string) string {
@@ -1027,3 +1351,106 @@ func (c *Client) setCachedItems(cacheKey string, items []media.MediaItem) {
 	}
 	c.cache.Store(cacheKey, entry)
 }
+
+// SearchItems searches movies and series by name and year. Jellyfin has no
+// provider ID query filter, so provider IDs are matched client-side and
+// provider-only queries return no results.
+func (c *Client) SearchItems(query media.ItemQuery) ([]media.MediaItem, error) {
+	if strings.TrimSpace(query.Name) == "" {
+		return nil, nil
+	}
+	u := fmt.Sprintf("%s/Items", c.baseURL)
+	q := url.Values{}
+	q.Set("api_key", c.apiKey)
+	q.Set("Recursive", "true")
+	q.Set("IncludeItemTypes", "Movie,Series")
+	q.Set("SearchTerm", query.Name)
+	q.Set("Fields", "MediaSources,MediaStreams,RunTimeTicks,Container,Genres,ProductionYear,ProviderIds")
+	q.Set("Limit", "50")
+	if query.Year > 0 {
+		q.Set("Years", strconv.Itoa(query.Year))
+	}
+
+	req, _ := http.NewRequest("GET", u+"?"+q.Encode(), nil)
+	req.Header.Set("X-Emby-Token", c.apiKey)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Items []struct {
+			Id             string            `json:"Id"`
+			Name           string            `json:"Name"`
+			Type           string            `json:"Type"`
+			RunTimeTicks   *int64            `json:"RunTimeTicks"`
+			Container      string            `json:"Container"`
+			Genres         []string          `json:"Genres"`
+			ProductionYear *int              `json:"ProductionYear"`
+			ProviderIds    map[string]string `json:"ProviderIds"`
+			MediaSources   []struct {
+				Container string `json:"Container"`
+				Bitrate   *int64 `json:"Bitrate"`
+				Size      *int64 `json:"Size"`
+				Path      string `json:"Path"`
+			} `json:"MediaSources"`
+			MediaStreams []struct {
+				Type   string `json:"Type"`
+				Codec  string `json:"Codec"`
+				Width  *int   `json:"Width"`
+				Height *int   `json:"Height"`
+			} `json:"MediaStreams"`
+		} `json:"Items"`
+	}
+	if err := readJSON(resp, &out); err != nil {
+		return nil, err
+	}
+
+	items := make([]media.MediaItem, 0, len(out.Items))
+	for _, raw := range out.Items {
+		item := media.MediaItem{
+			ID:             raw.Id,
+			ServerID:       c.serverID,
+			ServerType:     media.ServerTypeJellyfin,
+			Name:           raw.Name,
+			Type:           raw.Type,
+			Container:      raw.Container,
+			Genres:         raw.Genres,
+			ProductionYear: raw.ProductionYear,
+		}
+		if len(raw.ProviderIds) > 0 {
+			item.ProviderIDs = make(map[string]string, len(raw.ProviderIds))
+			for k, v := range raw.ProviderIds {
+				item.ProviderIDs[strings.ToLower(k)] = v
+			}
+		}
+		if query.ProviderID != "" && !item.MatchesProviderID(query.ProviderID) {
+			continue
+		}
+		if raw.RunTimeTicks != nil {
+			runtimeMs := ticksToMs(*raw.RunTimeTicks)
+			item.RuntimeMs = &runtimeMs
+		}
+		if len(raw.MediaSources) > 0 {
+			source := raw.MediaSources[0]
+			if item.Container == "" {
+				item.Container = source.Container
+			}
+			item.BitrateBps = source.Bitrate
+			item.FileSizeBytes = source.Size
+			item.FilePath = source.Path
+		}
+		for _, stream := range raw.MediaStreams {
+			if strings.EqualFold(stream.Type, "Video") {
+				item.Width = stream.Width
+				item.Height = stream.Height
+				if stream.Codec != "" {
+					item.Codec = strings.ToUpper(stream.Codec)
+				}
+				break
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
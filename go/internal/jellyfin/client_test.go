@@ -0,0 +1,42 @@
+package jellyfin
+
+import (
+	"testing"
+
+	"emby-analytics/internal/media"
+)
+
+func TestAssignSyncPlayGroups(t *testing.T) {
+	sessions := []media.Session{
+		{SessionID: "1", UserName: "Alice", ItemID: "movie-a"},
+		{SessionID: "2", UserName: "bob", ItemID: "movie-a"},
+		{SessionID: "3", UserName: "Carol", ItemID: "movie-b"},
+		{SessionID: "4", UserName: "alice", ItemID: "episode-1"}, // alice's other device
+	}
+	groups := []jellyfinSyncPlayGroup{{GroupId: "g1", GroupName: "Movie night", Participants: []string{"alice", "Bob", "carol"}}}
+
+	assignSyncPlayGroups(groups, sessions)
+
+	want := map[string]string{"1": "g1", "2": "g1", "3": "", "4": ""}
+	for _, s := range sessions {
+		if s.SyncPlayGroupID != want[s.SessionID] {
+			t.Errorf("session %s (%s) group = %q, want %q", s.SessionID, s.ItemID, s.SyncPlayGroupID, want[s.SessionID])
+		}
+	}
+	if sessions[0].SyncPlayGroupName != "Movie night" {
+		t.Errorf("group name = %q", sessions[0].SyncPlayGroupName)
+	}
+}
+
+func TestAssignSyncPlayGroupsTiedItem(t *testing.T) {
+	sessions := []media.Session{
+		{SessionID: "1", UserName: "alice", ItemID: "movie-a"},
+		{SessionID: "2", UserName: "bob", ItemID: "movie-b"},
+	}
+	assignSyncPlayGroups([]jellyfinSyncPlayGroup{{GroupId: "g1", Participants: []string{"alice", "bob"}}}, sessions)
+	for _, s := range sessions {
+		if s.SyncPlayGroupID != "" {
+			t.Errorf("session %s joined a group whose item is ambiguous", s.SessionID)
+		}
+	}
+}
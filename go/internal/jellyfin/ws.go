@@ -0,0 +1,271 @@
+package jellyfin
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"emby-analytics/internal/httpx"
+	"emby-analytics/internal/logging"
+
+	"github.com/gorilla/websocket"
+)
+
+// WSConfig identifies the server a WS listener connects to
+type WSConfig struct {
+	BaseURL string // e.g. http://jellyfin:8096
+	APIKey  string
+}
+
+// PlaybackEvent is one playback report derived from the socket. MessageType
+// uses the Emby event names: PlaybackStart, PlaybackProgress, PlaybackPaused,
+// PlaybackUnpaused and PlaybackStopped.
+type PlaybackEvent struct {
+	MessageType         string
+	UserID              string
+	SessionID           string
+	DeviceID            string
+	Client              string
+	PlayMethod          string // DirectPlay/DirectStream/Transcode
+	RemoteEndPoint      string
+	TranscodeReasons    []string
+	ItemID              string
+	ItemName            string
+	ItemType            string
+	RunTimeTicks        int64
+	PositionTicks       int64
+	IsPaused            bool
+	AudioStreamIndex    *int
+	SubtitleStreamIndex *int
+}
+
+// WS listens on Jellyfin's /socket endpoint. Jellyfin doesn't push playback
+// start/stop messages to API key sockets; it pushes the session list once
+// subscribed with SessionsStart, so playback events are derived by comparing
+// consecutive Sessions messages.
+type WS struct {
+	Cfg     WSConfig
+	Handler func(evt PlaybackEvent)
+
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	active map[string]PlaybackEvent // session id -> last playback seen
+}
+
+type wsMessage struct {
+	MessageType string          `json:"MessageType"`
+	Data        json.RawMessage `json:"Data"`
+}
+
+type wsOutgoing struct {
+	MessageType string `json:"MessageType"`
+	Data        string `json:"Data,omitempty"`
+}
+
+// sessionsInterval is how often (ms) Jellyfin sends the session list
+const sessionsInterval = 1500
+
+func (w *WS) dial() (*websocket.Conn, *http.Response, error) {
+	u, err := url.Parse(w.Cfg.BaseURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/socket"
+	q := u.Query()
+	q.Set("api_key", w.Cfg.APIKey)
+	q.Set("deviceId", "emby-analytics-go-client")
+	u.RawQuery = q.Encode()
+
+	dialer := &websocket.Dialer{
+		HandshakeTimeout: 15 * time.Second,
+		TLSClientConfig:  &tls.Config{InsecureSkipVerify: true}, // Allow self-signed certs
+		Proxy:            httpx.ProxyFunc(w.Cfg.BaseURL),
+	}
+	logging.Info("Dialing Jellyfin WebSocket", "url", w.Cfg.BaseURL)
+	return dialer.Dial(u.String(), http.Header{"Accept": []string{"application/json"}})
+}
+
+// Start connects in the background and reconnects until ctx is done or Stop is called.
+func (w *WS) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+
+	go func() {
+		defer cancel()
+		retry := 5 * time.Second
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+			conn, _, err := w.dial()
+			if err != nil {
+				logging.Warn("Jellyfin WebSocket dial failed, retrying", "error", err, "retry_in", retry)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(retry):
+				}
+				continue
+			}
+			logging.Info("Jellyfin WebSocket connected", "url", w.Cfg.BaseURL)
+			w.serve(ctx, conn)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+		}
+	}()
+}
+
+// Stop closes the connection and stops reconnecting
+func (w *WS) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+}
+
+func (w *WS) serve(ctx context.Context, conn *websocket.Conn) {
+	defer conn.Close()
+	var writeMu sync.Mutex
+	send := func(msg wsOutgoing) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_ = conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteJSON(msg)
+	}
+	if err := send(wsOutgoing{MessageType: "SessionsStart", Data: fmt.Sprintf("0,%d", sessionsInterval)}); err != nil {
+		logging.Warn("Jellyfin WebSocket subscribe failed", "error", err)
+		return
+	}
+
+	// Jellyfin drops sockets that don't send KeepAlive within the ForceKeepAlive timeout
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				_ = conn.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := send(wsOutgoing{MessageType: "KeepAlive"}); err != nil {
+					logging.Warn("Jellyfin WebSocket keepalive failed", "error", err)
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(90 * time.Second))
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if ctx.Err() == nil {
+				logging.Warn("Jellyfin WebSocket read error, reconnecting", "error", err)
+			}
+			return
+		}
+		if msg.MessageType != "Sessions" {
+			continue
+		}
+		var sessions []jellyfinSession
+		if err := json.Unmarshal(msg.Data, &sessions); err != nil {
+			logging.Debug("Jellyfin WebSocket: failed to parse Sessions data", "error", err)
+			continue
+		}
+		w.handleSessions(sessions)
+	}
+}
+
+// handleSessions emits events for playbacks that started, changed pause
+// state, progressed or disappeared since the previous Sessions message.
+func (w *WS) handleSessions(sessions []jellyfinSession) {
+	if w.Handler == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.active == nil {
+		w.active = make(map[string]PlaybackEvent)
+	}
+
+	seen := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		if s.NowPlayingItem == nil || s.NowPlayingItem.Id == "" || s.Id == "" {
+			continue
+		}
+		evt := playbackFromSession(s)
+		seen[s.Id] = true
+
+		prev, ok := w.active[s.Id]
+		switch {
+		case ok && prev.ItemID != evt.ItemID:
+			prev.MessageType = "PlaybackStopped"
+			w.Handler(prev)
+			evt.MessageType = "PlaybackStart"
+		case !ok:
+			evt.MessageType = "PlaybackStart"
+		case evt.IsPaused && !prev.IsPaused:
+			evt.MessageType = "PlaybackPaused"
+		case !evt.IsPaused && prev.IsPaused:
+			evt.MessageType = "PlaybackUnpaused"
+		default:
+			evt.MessageType = "PlaybackProgress"
+		}
+		w.Handler(evt)
+		w.active[s.Id] = evt
+	}
+
+	for id, prev := range w.active {
+		if seen[id] {
+			continue
+		}
+		prev.MessageType = "PlaybackStopped"
+		w.Handler(prev)
+		delete(w.active, id)
+	}
+}
+
+func playbackFromSession(s jellyfinSession) PlaybackEvent {
+	evt := PlaybackEvent{
+		UserID:         s.UserId,
+		SessionID:      s.Id,
+		DeviceID:       s.DeviceId,
+		Client:         s.Client,
+		PlayMethod:     "DirectPlay",
+		RemoteEndPoint: s.RemoteEndPoint,
+		ItemID:         s.NowPlayingItem.Id,
+		ItemName:       s.NowPlayingItem.Name,
+		ItemType:       s.NowPlayingItem.Type,
+		RunTimeTicks:   s.NowPlayingItem.RunTimeTicks,
+	}
+	if s.PlayState != nil {
+		evt.PositionTicks = s.PlayState.PositionTicks
+		evt.IsPaused = s.PlayState.IsPaused
+		evt.AudioStreamIndex = s.PlayState.AudioStreamIndex
+		evt.SubtitleStreamIndex = s.PlayState.SubtitleStreamIndex
+		if strings.HasPrefix(strings.ToLower(s.PlayState.PlayMethod), "trans") {
+			evt.PlayMethod = "Transcode"
+		}
+	}
+	if s.TranscodingInfo != nil {
+		evt.TranscodeReasons = s.TranscodingInfo.TranscodeReasons
+	}
+	return evt
+}
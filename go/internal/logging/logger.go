@@ -42,6 +42,7 @@ type Config struct {
 type logger struct {
 	slog   *slog.Logger
 	config *Config
+	state  *handlerState
 }
 
 // Regex patterns for sensitive data filtering
@@ -67,28 +68,16 @@ func NewLogger(config *Config) Logger {
 		config.Output = os.Stdout
 	}
 
-	var handler slog.Handler
+	levelVar.Set(slog.Level(config.Level))
+	state := &handlerState{output: config.Output, addSource: config.AddSource}
+	state.setFormat(config.Format)
 
-	opts := &slog.HandlerOptions{
-		Level:     slog.Level(config.Level),
-		AddSource: config.AddSource,
-	}
-
-	switch config.Format {
-	case "json":
-		handler = slog.NewJSONHandler(config.Output, opts)
-	case "dev":
-		// Pretty development format
-		handler = NewDevHandler(config.Output, opts)
-	default:
-		handler = slog.NewTextHandler(config.Output, opts)
-	}
-
-	sl := slog.New(handler)
+	sl := slog.New(&dynamicHandler{state: state})
 
 	return &logger{
 		slog:   sl,
 		config: config,
+		state:  state,
 	}
 }
 
@@ -112,6 +101,7 @@ func (l *logger) With(args ...any) Logger {
 	return &logger{
 		slog:   l.slog.With(l.sanitizeArgs(args)...),
 		config: l.config,
+		state:  l.state,
 	}
 }
 
@@ -119,6 +109,7 @@ func (l *logger) WithContext(ctx context.Context) Logger {
 	return &logger{
 		slog:   l.slog.With(extractContextFields(ctx)...),
 		config: l.config,
+		state:  l.state,
 	}
 }
 
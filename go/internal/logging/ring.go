@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// recentCapacity bounds how many WARN/ERROR records are kept in memory.
+const recentCapacity = 500
+
+// Entry is a captured WARN/ERROR log record.
+type Entry struct {
+	Time    time.Time      `json:"time"`
+	Level   string         `json:"level"`
+	Module  string         `json:"module,omitempty"`
+	Message string         `json:"message"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+}
+
+// recentBuffer is a fixed-size ring of the most recent WARN/ERROR records.
+type recentBuffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+var recent = &recentBuffer{entries: make([]Entry, recentCapacity)}
+
+func (b *recentBuffer) add(e Entry) {
+	b.mu.Lock()
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	b.mu.Unlock()
+}
+
+// RecentFilter narrows the records returned by Recent. Zero values match everything.
+type RecentFilter struct {
+	MinLevel Level
+	Module   string
+	Since    time.Time
+	Limit    int
+}
+
+// Recent returns captured WARN/ERROR records matching f, newest first.
+func Recent(f RecentFilter) []Entry {
+	b := recent
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := b.next
+	if b.full {
+		n = len(b.entries)
+	}
+	out := make([]Entry, 0, n)
+	for i := 0; i < n; i++ {
+		idx := (b.next - 1 - i + len(b.entries)) % len(b.entries)
+		e := b.entries[idx]
+		if lvl, ok := ParseLevel(e.Level); ok && lvl < f.MinLevel {
+			continue
+		}
+		if f.Module != "" && e.Module != f.Module {
+			continue
+		}
+		if !f.Since.IsZero() && e.Time.Before(f.Since) {
+			continue
+		}
+		out = append(out, e)
+		if f.Limit > 0 && len(out) >= f.Limit {
+			break
+		}
+	}
+	return out
+}
+
+func captureRecord(module string, attrs []slog.Attr, r slog.Record) {
+	e := Entry{
+		Time:    r.Time,
+		Level:   r.Level.String(),
+		Module:  module,
+		Message: r.Message,
+	}
+	if len(attrs) > 0 || r.NumAttrs() > 0 {
+		e.Attrs = make(map[string]any, len(attrs)+r.NumAttrs())
+		for _, a := range attrs {
+			if a.Key != ModuleKey {
+				e.Attrs[a.Key] = attrValue(a.Value)
+			}
+		}
+		r.Attrs(func(a slog.Attr) bool {
+			e.Attrs[a.Key] = attrValue(a.Value)
+			return true
+		})
+	}
+	recent.add(e)
+}
+
+// attrValue converts an attribute to a JSON-friendly value (errors become their message).
+func attrValue(v slog.Value) any {
+	val := v.Resolve().Any()
+	if err, ok := val.(error); ok {
+		return err.Error()
+	}
+	return val
+}
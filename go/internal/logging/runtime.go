@@ -0,0 +1,219 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ModuleKey is the attribute used to tag log records with the emitting module.
+const ModuleKey = "module"
+
+var (
+	// levelVar is the global minimum level; adjustable at runtime via SetLevel.
+	levelVar = new(slog.LevelVar)
+
+	// moduleLevels holds per-module overrides (module name -> slog.Level).
+	moduleLevels sync.Map
+)
+
+// handlerState holds the swappable output handler shared by a logger and all
+// loggers derived from it via With.
+type handlerState struct {
+	output    io.Writer
+	addSource bool
+	format    atomic.Pointer[string]
+	inner     atomic.Pointer[slog.Handler]
+}
+
+func (s *handlerState) setFormat(format string) {
+	// Level filtering happens in dynamicHandler; the inner handler accepts everything.
+	opts := &slog.HandlerOptions{Level: slog.Level(-100), AddSource: s.addSource}
+	var h slog.Handler
+	switch format {
+	case "json":
+		h = slog.NewJSONHandler(s.output, opts)
+	case "dev":
+		// Pretty development format
+		h = NewDevHandler(s.output, opts)
+	default:
+		format = "text"
+		h = slog.NewTextHandler(s.output, opts)
+	}
+	s.inner.Store(&h)
+	s.format.Store(&format)
+}
+
+// dynamicHandler applies the runtime level (and per-module overrides) and
+// forwards records to the current output handler.
+type dynamicHandler struct {
+	state  *handlerState
+	module string
+	attrs  []slog.Attr // accumulated With attributes, kept for the recent-records buffer
+	ops    []func(slog.Handler) slog.Handler
+}
+
+func (h *dynamicHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if h.module != "" {
+		if v, ok := moduleLevels.Load(h.module); ok {
+			return level >= v.(slog.Level)
+		}
+	}
+	return level >= levelVar.Level()
+}
+
+func (h *dynamicHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn {
+		captureRecord(h.module, h.attrs, r)
+	}
+	inner := *h.state.inner.Load()
+	for _, op := range h.ops {
+		inner = op(inner)
+	}
+	return inner.Handle(ctx, r)
+}
+
+func (h *dynamicHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := &dynamicHandler{
+		state:  h.state,
+		module: h.module,
+		attrs:  append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...),
+		ops: append(h.ops[:len(h.ops):len(h.ops)], func(in slog.Handler) slog.Handler {
+			return in.WithAttrs(attrs)
+		}),
+	}
+	for _, a := range attrs {
+		if a.Key == ModuleKey {
+			next.module = a.Value.String()
+		}
+	}
+	return next
+}
+
+func (h *dynamicHandler) WithGroup(name string) slog.Handler {
+	return &dynamicHandler{
+		state:  h.state,
+		module: h.module,
+		attrs:  h.attrs,
+		ops: append(h.ops[:len(h.ops):len(h.ops)], func(in slog.Handler) slog.Handler {
+			return in.WithGroup(name)
+		}),
+	}
+}
+
+// ParseLevel converts DEBUG/INFO/WARN/ERROR (case-insensitive) to a Level.
+func ParseLevel(s string) (Level, bool) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "DEBUG":
+		return LevelDebug, true
+	case "INFO":
+		return LevelInfo, true
+	case "WARN", "WARNING":
+		return LevelWarn, true
+	case "ERROR":
+		return LevelError, true
+	}
+	return LevelInfo, false
+}
+
+// LevelName returns the canonical name for a Level.
+func LevelName(l Level) string {
+	return slog.Level(l).String()
+}
+
+// SetLevel changes the global minimum level without restarting.
+func SetLevel(l Level) {
+	levelVar.Set(slog.Level(l))
+}
+
+// GetLevel returns the global minimum level.
+func GetLevel() Level {
+	return Level(levelVar.Level())
+}
+
+// SetModuleLevel overrides the level for records tagged with module=name.
+func SetModuleLevel(name string, l Level) {
+	moduleLevels.Store(name, slog.Level(l))
+}
+
+// ClearModuleLevel removes a module override so it follows the global level again.
+func ClearModuleLevel(name string) {
+	moduleLevels.Delete(name)
+}
+
+// ModuleLevels returns the active per-module overrides.
+func ModuleLevels() map[string]string {
+	out := map[string]string{}
+	moduleLevels.Range(func(k, v interface{}) bool {
+		out[k.(string)] = v.(slog.Level).String()
+		return true
+	})
+	return out
+}
+
+// SetFormat switches the default logger's output format (json, text, dev).
+func SetFormat(format string) error {
+	l, ok := Default().(*logger)
+	if !ok || l.state == nil {
+		return fmt.Errorf("default logger does not support format changes")
+	}
+	switch format {
+	case "json", "text", "dev":
+	default:
+		return fmt.Errorf("unsupported log format %q", format)
+	}
+	l.state.setFormat(format)
+	l.config.Format = format
+	return nil
+}
+
+// GetFormat returns the default logger's output format.
+func GetFormat() string {
+	if l, ok := Default().(*logger); ok && l.state != nil {
+		return *l.state.format.Load()
+	}
+	return ""
+}
+
+// KnownModules lists module names registered via Module.
+func KnownModules() []string {
+	var out []string
+	knownModules.Range(func(k, _ interface{}) bool {
+		out = append(out, k.(string))
+		return true
+	})
+	sort.Strings(out)
+	return out
+}
+
+var knownModules sync.Map
+
+type moduleLogger struct {
+	name string
+}
+
+// Module returns a logger that tags records with module=name, so its level can be
+// overridden independently (e.g. DEBUG only for "session-processor"). The default
+// logger is resolved on each call, so Module is safe to use in package variables.
+func Module(name string) Logger {
+	knownModules.Store(name, struct{}{})
+	return moduleLogger{name: name}
+}
+
+func (m moduleLogger) base() Logger {
+	return Default().With(ModuleKey, m.name)
+}
+
+func (m moduleLogger) Debug(msg string, args ...any) { m.base().Debug(msg, args...) }
+func (m moduleLogger) Info(msg string, args ...any)  { m.base().Info(msg, args...) }
+func (m moduleLogger) Warn(msg string, args ...any)  { m.base().Warn(msg, args...) }
+func (m moduleLogger) Error(msg string, args ...any) { m.base().Error(msg, args...) }
+func (m moduleLogger) With(args ...any) Logger       { return m.base().With(args...) }
+func (m moduleLogger) WithContext(ctx context.Context) Logger {
+	return m.base().WithContext(ctx)
+}
@@ -2,7 +2,12 @@ package media
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"emby-analytics/internal/sessioncache"
 )
@@ -35,6 +40,86 @@ type MediaServerClient interface {
 	CheckHealth() (*ServerHealth, error)
 }
 
+// DownloadReporter is implemented by clients whose server reports offline download / sync jobs
+// separately from streaming sessions (Emby, Plex). Jellyfin has no server-side sync support.
+type DownloadReporter interface {
+	GetActiveDownloads() ([]DownloadJob, error)
+}
+
+// DVRReporter is implemented by clients whose server has Live TV DVR support (Emby, Jellyfin).
+type DVRReporter interface {
+	GetRecordings() ([]Recording, error)
+	GetScheduledRecordings() ([]Recording, error)
+	GetSeriesTimers() ([]SeriesTimer, error)
+}
+
+// ResumeReporter is implemented by clients that can list a user's partially watched
+// (continue watching) items (Emby, Jellyfin). Plex only reports On Deck for the token's
+// own account, so it is not supported.
+type ResumeReporter interface {
+	GetResumeItems(userID string, limit int) ([]UserDataItem, error)
+}
+
+// ErrInvalidCredentials is returned by CredentialAuthenticator when the server rejects the
+// username or password.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// CredentialAuthenticator is implemented by clients that can verify a user's own server
+// credentials (Emby, Jellyfin), so the app can sign users in with their media server account.
+type CredentialAuthenticator interface {
+	AuthenticateUser(username, password string) (*User, error)
+}
+
+// CollectionManager is implemented by clients that can create collections on the server (Emby, Jellyfin).
+// ReplaceCollection creates the named collection and then deletes previousID, the collection
+// an earlier call created, if any. Collections are never looked up by name, so ones made by
+// hand are left alone, and a failed create keeps the previous collection. When only the
+// delete fails, the new id is returned along with the error.
+type CollectionManager interface {
+	ReplaceCollection(previousID, name string, itemIDs []string) (string, error)
+}
+
+// LibraryScanner is implemented by clients that can start a library scan on the server.
+// LibraryScanRunning reports whether a scan is still in progress.
+type LibraryScanner interface {
+	RefreshLibrary() error
+	LibraryScanRunning() (bool, error)
+}
+
+// ItemQuery describes a movie/series lookup used for cross-server availability checks.
+// ProviderID has the form "provider:id", e.g. "imdb:tt0111161" or "tmdb:278".
+type ItemQuery struct {
+	Name       string
+	Year       int
+	ProviderID string
+}
+
+// ItemSearcher is implemented by clients that can search the server's library for movies and series.
+// Servers without a provider ID filter return no results for provider-only queries.
+type ItemSearcher interface {
+	SearchItems(q ItemQuery) ([]MediaItem, error)
+}
+
+// SplitProviderID splits "imdb:tt0111161" into ("imdb", "tt0111161").
+func SplitProviderID(v string) (string, string, bool) {
+	provider, id, ok := strings.Cut(strings.TrimSpace(v), ":")
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	id = strings.TrimSpace(id)
+	if !ok || provider == "" || id == "" {
+		return "", "", false
+	}
+	return provider, id, true
+}
+
+// MatchesProviderID reports whether an item carries the given "provider:id".
+func (mi MediaItem) MatchesProviderID(v string) bool {
+	provider, id, ok := SplitProviderID(v)
+	if !ok {
+		return false
+	}
+	return strings.EqualFold(mi.ProviderIDs[provider], id)
+}
+
 // ClientFactory creates MediaServerClient instances based on server configuration
 type ClientFactory interface {
 	CreateClient(config ServerConfig) (MediaServerClient, error)
@@ -42,45 +127,81 @@ type ClientFactory interface {
 
 // MultiServerManager manages multiple media servers
 type MultiServerManager struct {
+	mu      sync.RWMutex // guards clients and configs; servers can be added at runtime
 	clients map[string]MediaServerClient
 	configs map[string]ServerConfig
 	cache   *sessioncache.SessionCache
+
+	failMu   sync.Mutex
+	failures map[string]serverFailure // servers whose last session request failed
+}
+
+type serverFailure struct {
+	err   error
+	since time.Time
 }
 
 // NewMultiServerManager creates a new multi-server manager
 func NewMultiServerManager(cache *sessioncache.SessionCache) *MultiServerManager {
 	return &MultiServerManager{
-		clients: make(map[string]MediaServerClient),
-		configs: make(map[string]ServerConfig),
-		cache:   cache,
+		clients:  make(map[string]MediaServerClient),
+		configs:  make(map[string]ServerConfig),
+		cache:    cache,
+		failures: make(map[string]serverFailure),
 	}
 }
 
 // AddServer adds a server to the manager
 func (m *MultiServerManager) AddServer(config ServerConfig, client MediaServerClient) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.configs[config.ID] = config
 	m.clients[config.ID] = client
 }
 
 // RemoveServer removes a server from the manager
 func (m *MultiServerManager) RemoveServer(serverID string) {
+	m.mu.Lock()
 	delete(m.configs, serverID)
 	delete(m.clients, serverID)
+	m.mu.Unlock()
+	// A removed server no longer warns about its last failed request
+	m.failMu.Lock()
+	delete(m.failures, serverID)
+	m.failMu.Unlock()
 }
 
 // GetClient returns a client for the specified server ID
 func (m *MultiServerManager) GetClient(serverID string) (MediaServerClient, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	client, exists := m.clients[serverID]
 	return client, exists
 }
 
-// GetAllClients returns all registered clients
+// GetAllClients returns a copy of all registered clients
 func (m *MultiServerManager) GetAllClients() map[string]MediaServerClient {
-	return m.clients
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make(map[string]MediaServerClient, len(m.clients))
+	for id, client := range m.clients {
+		out[id] = client
+	}
+	return out
+}
+
+// serverConfig returns the configuration of a registered server
+func (m *MultiServerManager) serverConfig(serverID string) (ServerConfig, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cfg, ok := m.configs[serverID]
+	return cfg, ok
 }
 
 // ClientsByType returns enabled clients matching a given server type
 func (m *MultiServerManager) ClientsByType(t ServerType) []MediaServerClient {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	out := []MediaServerClient{}
 	for id, client := range m.clients {
 		if client == nil {
@@ -99,6 +220,8 @@ func (m *MultiServerManager) ClientsByType(t ServerType) []MediaServerClient {
 
 // GetEnabledClients returns only enabled clients
 func (m *MultiServerManager) GetEnabledClients() map[string]MediaServerClient {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	enabled := make(map[string]MediaServerClient)
 	for serverID, client := range m.clients {
 		if client == nil {
@@ -111,23 +234,101 @@ func (m *MultiServerManager) GetEnabledClients() map[string]MediaServerClient {
 	return enabled
 }
 
-// GetAllSessions aggregates sessions from all enabled servers
+// GetAllSessions aggregates sessions from all enabled servers. Servers that fail are
+// skipped; use GetAllSessionsWithWarnings to learn which.
 func (m *MultiServerManager) GetAllSessions() ([]Session, error) {
+	sessions, _ := m.GetAllSessionsWithWarnings()
+	return sessions, nil
+}
+
+// GetAllSessionsWithWarnings aggregates sessions from all enabled servers and reports
+// every server whose sessions are missing because the request failed.
+func (m *MultiServerManager) GetAllSessionsWithWarnings() ([]Session, []ServerWarning) {
+	return m.GetSessionsWithWarnings(func(string) bool { return true })
+}
+
+// GetSessionsWithWarnings is GetAllSessionsWithWarnings for the enabled servers poll
+// accepts; the others are neither requested nor reported.
+func (m *MultiServerManager) GetSessionsWithWarnings(poll func(serverID string) bool) ([]Session, []ServerWarning) {
 	var allSessions []Session
+	var warnings []ServerWarning
 
-	for _, client := range m.GetEnabledClients() {
-		if client == nil {
+	for serverID, client := range m.GetEnabledClients() {
+		if !poll(serverID) {
 			continue
 		}
 		sessions, err := client.GetActiveSessions()
+		m.RecordServerResult(serverID, err)
 		if err != nil {
-			// Log error but continue with other servers
+			// Continue with other servers; the caller decides how to surface the gap
+			warnings = append(warnings, m.serverWarning(serverID, err))
 			continue
 		}
 		allSessions = append(allSessions, sessions...)
 	}
 
-	return allSessions, nil
+	sortWarnings(warnings)
+	return allSessions, warnings
+}
+
+// RecordServerResult remembers whether the latest request to a server succeeded, for ServerWarnings.
+func (m *MultiServerManager) RecordServerResult(serverID string, err error) {
+	m.failMu.Lock()
+	defer m.failMu.Unlock()
+	if err == nil {
+		delete(m.failures, serverID)
+		return
+	}
+	f, ok := m.failures[serverID]
+	if !ok {
+		f.since = time.Now().UTC()
+	}
+	f.err = err
+	m.failures[serverID] = f
+}
+
+// ServerWarnings lists enabled servers whose most recent session request failed, i.e. whose
+// live data (and anything synced from them since) is currently missing.
+func (m *MultiServerManager) ServerWarnings() []ServerWarning {
+	m.failMu.Lock()
+	failures := make(map[string]serverFailure, len(m.failures))
+	for id, f := range m.failures {
+		failures[id] = f
+	}
+	m.failMu.Unlock()
+
+	var out []ServerWarning
+	for serverID, f := range failures {
+		if cfg, ok := m.serverConfig(serverID); !ok || !cfg.Enabled {
+			continue
+		}
+		w := m.serverWarning(serverID, f.err)
+		w.Since = f.since
+		out = append(out, w)
+	}
+	sortWarnings(out)
+	return out
+}
+
+func (m *MultiServerManager) serverWarning(serverID string, err error) ServerWarning {
+	w := ServerWarning{ServerID: serverID, ServerName: serverID, Since: time.Now().UTC()}
+	if cfg, ok := m.serverConfig(serverID); ok {
+		w.ServerType = cfg.Type
+		if cfg.Name != "" {
+			w.ServerName = cfg.Name
+		}
+	}
+	w.Message = fmt.Sprintf("%s data unavailable: %v", w.ServerName, err)
+	m.failMu.Lock()
+	if f, ok := m.failures[serverID]; ok {
+		w.Since = f.since
+	}
+	m.failMu.Unlock()
+	return w
+}
+
+func sortWarnings(ws []ServerWarning) {
+	sort.Slice(ws, func(i, j int) bool { return ws[i].ServerID < ws[j].ServerID })
 }
 
 // GetAllSessionsCached returns sessions from cache if fresh, otherwise fetches from servers
@@ -210,6 +411,7 @@ func (m *MultiServerManager) UpdateAllSessions(ctx context.Context) error {
 
 			// Fetch sessions with timeout
 			sessions, err := c.GetActiveSessions()
+			m.RecordServerResult(sID, err)
 
 			if err != nil {
 				// Mark as degraded but keep last known sessions
@@ -235,16 +437,22 @@ func (m *MultiServerManager) UpdateAllSessions(ctx context.Context) error {
 func (m *MultiServerManager) PublishSessionsToCache(serverID string, sessions []Session, status sessioncache.CacheStatus) {
 	if m.cache != nil {
 		var serverType string
-		if config, ok := m.configs[serverID]; ok {
+		if config, ok := m.serverConfig(serverID); ok {
 			serverType = string(config.Type)
 		}
 		m.cache.Set(serverID, sessions, serverType, status)
 	}
 }
 
-// GetServerConfigs returns all server configurations
+// GetServerConfigs returns a copy of all server configurations
 func (m *MultiServerManager) GetServerConfigs() map[string]ServerConfig {
-	return m.configs
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make(map[string]ServerConfig, len(m.configs))
+	for id, cfg := range m.configs {
+		out[id] = cfg
+	}
+	return out
 }
 
 // GetServerHealth checks health of all servers
@@ -252,8 +460,8 @@ func (m *MultiServerManager) GetServerHealth() map[string]*ServerHealth {
 	health := make(map[string]*ServerHealth)
 
 	// Iterate over configs so servers without clients are also reported
-	for serverID, cfg := range m.configs {
-		if client, ok := m.clients[serverID]; ok && client != nil {
+	for serverID, cfg := range m.GetServerConfigs() {
+		if client, ok := m.GetClient(serverID); ok && client != nil {
 			serverHealth, err := client.CheckHealth()
 			if err != nil {
 				health[serverID] = &ServerHealth{
@@ -1,6 +1,7 @@
 package media
 
 import (
+	"errors"
 	"strings"
 	"time"
 
@@ -42,7 +43,7 @@ func (e *EmbyAdapter) GetSystemInfo() (*SystemInfo, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &SystemInfo{ID: info.ID, Name: info.Name, ServerType: ServerTypeEmby}, nil
+	return &SystemInfo{ID: info.ID, Name: info.Name, ServerType: ServerTypeEmby, Version: info.Version}, nil
 }
 
 func (e *EmbyAdapter) GetUsers() ([]User, error) {
@@ -62,6 +63,31 @@ func (e *EmbyAdapter) GetUserData(userID string) ([]UserDataItem, error) {
 	if err != nil {
 		return nil, err
 	}
+	return e.convertUserData(data), nil
+}
+
+// AuthenticateUser verifies a user's own Emby credentials
+func (e *EmbyAdapter) AuthenticateUser(username, password string) (*User, error) {
+	u, err := e.c.AuthenticateByName(username, password)
+	if errors.Is(err, emby.ErrUnauthorized) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &User{ID: u.Id, Name: u.Name, ServerID: e.cfg.ID, ServerType: ServerTypeEmby}, nil
+}
+
+// GetResumeItems returns the user's continue-watching list
+func (e *EmbyAdapter) GetResumeItems(userID string, limit int) ([]UserDataItem, error) {
+	data, err := e.c.GetResumeItems(userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return e.convertUserData(data), nil
+}
+
+func (e *EmbyAdapter) convertUserData(data []emby.UserDataItem) []UserDataItem {
 	out := make([]UserDataItem, 0, len(data))
 	for _, item := range data {
 		out = append(out, UserDataItem{
@@ -75,9 +101,10 @@ func (e *EmbyAdapter) GetUserData(userID string) ([]UserDataItem, error) {
 			PlayCount:          item.UserData.PlayCount,
 			PlaybackPositionMs: item.UserData.PlaybackPos / 10_000,
 			LastPlayed:         item.UserData.LastPlayedDate,
+			SeriesName:         item.SeriesName,
 		})
 	}
-	return out, nil
+	return out
 }
 
 // Items
@@ -99,6 +126,7 @@ func (e *EmbyAdapter) ItemsByIDs(ids []string) ([]MediaItem, error) {
 			ParentIndexNumber: it.ParentIndexNumber,
 			IndexNumber:       it.IndexNumber,
 			ProductionYear:    it.ProductionYear,
+			ProviderIDs:       lowerKeys(it.ProviderIds),
 		}
 		out = append(out, mi)
 	}
@@ -149,7 +177,195 @@ func (e *EmbyAdapter) CheckHealth() (*ServerHealth, error) {
 	return h, nil
 }
 
+// Downloads
+func (e *EmbyAdapter) GetActiveDownloads() ([]DownloadJob, error) {
+	jobs, err := e.c.GetSyncJobs()
+	if err != nil {
+		return nil, err
+	}
+	byJob := make(map[string]emby.SyncJob, len(jobs))
+	for _, j := range jobs {
+		byJob[j.Id] = j
+	}
+	items, err := e.c.GetSyncJobItems()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]DownloadJob, 0, len(items))
+	for _, it := range items {
+		job := byJob[it.JobId]
+		dj := DownloadJob{
+			ServerID:   e.cfg.ID,
+			ServerType: ServerTypeEmby,
+			JobID:      it.Id,
+			UserID:     job.UserId,
+			ItemID:     it.ItemId,
+			ItemName:   it.ItemName,
+			DeviceName: job.TargetName,
+			Status:     it.Status,
+			Progress:   it.Progress,
+		}
+		dj.CreatedAt = parseOptionalTime(it.DateCreated)
+		out = append(out, dj)
+	}
+	return out, nil
+}
+
+// DVR
+func (e *EmbyAdapter) GetRecordings() ([]Recording, error) {
+	recs, err := e.c.GetRecordings()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Recording, 0, len(recs))
+	for _, r := range recs {
+		rec := Recording{
+			ServerID:      e.cfg.ID,
+			ServerType:    ServerTypeEmby,
+			ID:            r.Id,
+			Name:          r.Name,
+			SeriesName:    r.SeriesName,
+			ChannelName:   r.ChannelName,
+			Status:        r.Status,
+			StartDate:     parseOptionalTime(r.StartDate),
+			EndDate:       parseOptionalTime(r.EndDate),
+			SeriesTimerID: r.SeriesTimerId,
+		}
+		for _, ms := range r.MediaSources {
+			rec.SizeBytes += ms.Size
+		}
+		out = append(out, rec)
+	}
+	return out, nil
+}
+
+func (e *EmbyAdapter) GetScheduledRecordings() ([]Recording, error) {
+	timers, err := e.c.GetTimers()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Recording, 0, len(timers))
+	for _, t := range timers {
+		out = append(out, Recording{
+			ServerID:      e.cfg.ID,
+			ServerType:    ServerTypeEmby,
+			ID:            t.Id,
+			Name:          t.Name,
+			ChannelName:   t.ChannelName,
+			Status:        t.Status,
+			StartDate:     parseOptionalTime(t.StartDate),
+			EndDate:       parseOptionalTime(t.EndDate),
+			SeriesTimerID: t.SeriesTimerId,
+			Scheduled:     true,
+		})
+	}
+	return out, nil
+}
+
+func (e *EmbyAdapter) GetSeriesTimers() ([]SeriesTimer, error) {
+	timers, err := e.c.GetSeriesTimers()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SeriesTimer, 0, len(timers))
+	for _, t := range timers {
+		out = append(out, SeriesTimer{
+			ServerID:         e.cfg.ID,
+			ServerType:       ServerTypeEmby,
+			ID:               t.Id,
+			Name:             t.Name,
+			ChannelName:      t.ChannelName,
+			RecordNewOnly:    t.RecordNewOnly,
+			RecordAnyChannel: t.RecordAnyChannel,
+		})
+	}
+	return out, nil
+}
+
+// Collections
+func (e *EmbyAdapter) ReplaceCollection(previousID, name string, itemIDs []string) (string, error) {
+	id, err := e.c.CreateCollection(name, itemIDs)
+	if err != nil {
+		return "", err
+	}
+	if previousID != "" && previousID != id {
+		return id, e.c.DeleteItem(previousID)
+	}
+	return id, nil
+}
+
+// Library scans
+func (e *EmbyAdapter) RefreshLibrary() error             { return e.c.RefreshLibrary() }
+func (e *EmbyAdapter) LibraryScanRunning() (bool, error) { return e.c.LibraryScanRunning() }
+
+// Search
+func (e *EmbyAdapter) SearchItems(q ItemQuery) ([]MediaItem, error) {
+	items, err := e.c.SearchItems(q.Name, q.Year, embyProviderKey(q.ProviderID))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MediaItem, 0, len(items))
+	for _, it := range items {
+		mi := MediaItem{
+			ID:             it.Id,
+			ServerID:       e.cfg.ID,
+			ServerType:     ServerTypeEmby,
+			Name:           it.Name,
+			Type:           it.Type,
+			Height:         it.Height,
+			Width:          it.Width,
+			Codec:          it.Codec,
+			Container:      it.Container,
+			BitrateBps:     it.BitrateBps,
+			FileSizeBytes:  it.FileSizeBytes,
+			FilePath:       it.FilePath,
+			ProductionYear: it.ProductionYear,
+			Genres:         it.Genres,
+			ProviderIDs:    lowerKeys(it.ProviderIds),
+		}
+		if it.RunTimeTicks != nil {
+			ms := *it.RunTimeTicks / 10000
+			mi.RuntimeMs = &ms
+		}
+		out = append(out, mi)
+	}
+	return out, nil
+}
+
 // ---- helpers ----
+
+// embyProviderKey maps "imdb:tt123" to Emby's provider casing ("Imdb:tt123")
+func embyProviderKey(v string) string {
+	provider, id, ok := SplitProviderID(v)
+	if !ok {
+		return ""
+	}
+	return strings.ToUpper(provider[:1]) + provider[1:] + ":" + id
+}
+
+// lowerKeys normalizes provider ID keys ("Imdb", "Tmdb") to lower case
+func lowerKeys(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[strings.ToLower(k)] = v
+	}
+	return out
+}
+
+// parseOptionalTime parses an RFC3339 timestamp, returning nil when empty or malformed
+func parseOptionalTime(v string) *time.Time {
+	if v == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
 func (e *EmbyAdapter) convertSession(s emby.EmbySession) Session {
 	sess := Session{
 		ServerID:            e.cfg.ID,
@@ -164,6 +380,8 @@ func (e *EmbyAdapter) convertSession(s emby.EmbySession) Session {
 		PositionMs:          s.PosTicks / 10_000,
 		DurationMs:          s.DurationTicks / 10_000,
 		ClientApp:           s.App,
+		ClientVersion:       s.AppVersion,
+		Platform:            NormalizePlatform("", s.App, s.Device),
 		DeviceName:          s.Device,
 		RemoteAddress:       s.RemoteAddress,
 		PlayMethod:          s.PlayMethod,
@@ -179,6 +397,8 @@ func (e *EmbyAdapter) convertSession(s emby.EmbySession) Session {
 		SubtitleLanguage:    s.SubLang,
 		SubtitleCodec:       s.SubCodec,
 		SubtitleCount:       s.SubsCount,
+		AudioTrack:          TrackFromIndex(s.AudioIndex),
+		SubtitleTrack:       TrackFromIndex(s.SubIndex),
 		DolbyVision:         s.DolbyVision,
 		HDR10:               s.HDR10,
 		TranscodeContainer:  strings.ToUpper(s.TransContainer),
@@ -189,9 +409,12 @@ func (e *EmbyAdapter) convertSession(s emby.EmbySession) Session {
 		TranscodeWidth:      s.TransWidth,
 		TranscodeHeight:     s.TransHeight,
 		TranscodeBitrate:    s.TransVideoBitrate,
+		TranscodeFramerate:  s.TransFramerate,
+		TranscodeHardware:   s.TransHardware,
 		VideoMethod:         s.VideoMethod,
 		AudioMethod:         s.AudioMethod,
 		IsPaused:            s.IsPaused,
+		SyncPlayGroupID:     s.SyncPlayGroupID,
 		LastUpdate:          time.Now(),
 	}
 	return sess
@@ -220,6 +443,9 @@ func (e *EmbyAdapter) FetchLibraryItems() ([]MediaItem, error) {
 				Container:      it.Container,
 				ProductionYear: it.ProductionYear,
 				Genres:         it.Genres,
+				Tags:           it.Tags,
+				OfficialRating: it.OfficialRating,
+				ProviderIDs:    lowerKeys(it.ProviderIds),
 			}
 			if it.RunTimeTicks != nil {
 				ms := *it.RunTimeTicks / 10000
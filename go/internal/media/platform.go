@@ -0,0 +1,42 @@
+package media
+
+import "strings"
+
+// platformRules map keywords found in a session's reported platform, client app or device name
+// to a platform, checked in order so "Android TV" wins over "Android" and "webOS" over "Web".
+var platformRules = []struct {
+	platform string
+	keywords []string
+}{
+	{"Android TV", []string{"android tv", "androidtv", "android (tv)", "google tv", "shield", "bravia", "chromecast"}},
+	{"Fire TV", []string{"fire tv", "firetv", "fire stick", "firestick"}},
+	{"tvOS", []string{"apple tv", "appletv", "tvos"}},
+	{"iOS", []string{"iphone", "ipad", "ios"}},
+	{"Android", []string{"android"}},
+	{"Roku", []string{"roku"}},
+	{"Samsung Tizen", []string{"tizen", "samsung"}},
+	{"LG webOS", []string{"webos", "lg tv", "lgtv"}},
+	{"Xbox", []string{"xbox"}},
+	{"PlayStation", []string{"playstation", "ps4", "ps5"}},
+	{"Kodi", []string{"kodi"}},
+	{"Windows", []string{"windows"}},
+	{"macOS", []string{"macos", "mac os", "osx"}},
+	{"Linux", []string{"linux"}},
+	{"Web", []string{"web", "chrome", "firefox", "safari", "edge"}},
+}
+
+// NormalizePlatform returns the OS platform a session plays on, e.g. "Android TV" vs "Android",
+// which a client name alone often doesn't tell apart. reported is the platform the server
+// reports (Plex only); client and device are the session's client app and device name.
+// Unrecognized platforms fall back to reported, which may be empty.
+func NormalizePlatform(reported, client, device string) string {
+	s := strings.ToLower(reported + " " + client + " " + device)
+	for _, r := range platformRules {
+		for _, kw := range r.keywords {
+			if strings.Contains(s, kw) {
+				return r.platform
+			}
+		}
+	}
+	return strings.TrimSpace(reported)
+}
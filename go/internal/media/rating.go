@@ -0,0 +1,46 @@
+package media
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ratingAges maps common US film/TV and UK ratings to the minimum viewer age they imply.
+var ratingAges = map[string]int{
+	"G": 0, "TV-Y": 0, "TV-G": 0, "U": 0, "ALL": 0, "APPROVED": 0,
+	"TV-Y7": 7, "TV-Y7-FV": 7,
+	"PG": 10, "TV-PG": 10,
+	"12A": 12, "PG-13": 13, "TV-14": 14,
+	"R": 17, "TV-MA": 17,
+	"NC-17": 18, "X": 18, "XXX": 18, "R18": 18,
+}
+
+// RatingAge returns the minimum viewer age of a parental rating, e.g. 13 for "PG-13" or 12
+// for "de/12" and "FSK-12". Country prefixes ("US-", "gb/") are ignored; unrated or unknown
+// ratings return false.
+func RatingAge(rating string) (int, bool) {
+	r := strings.ToUpper(strings.TrimSpace(rating))
+	if i := strings.LastIndex(r, "/"); i >= 0 {
+		r = r[i+1:]
+	}
+	if _, known := ratingAges[r]; !known && len(r) > 3 && r[2] == '-' && isLetters(r[:2]) {
+		r = r[3:]
+	}
+	r = strings.TrimLeft(strings.TrimPrefix(r, "FSK"), "- ")
+	if age, ok := ratingAges[r]; ok {
+		return age, true
+	}
+	if n, err := strconv.Atoi(strings.TrimSuffix(r, "+")); err == nil && n >= 0 && n <= 21 {
+		return n, true
+	}
+	return 0, false
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
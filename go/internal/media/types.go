@@ -1,7 +1,10 @@
 package media
 
 import (
+	"strconv"
 	"time"
+
+	"emby-analytics/internal/httpx"
 )
 
 // ServerType represents the type of media server
@@ -13,6 +16,22 @@ const (
 	ServerTypeJellyfin ServerType = "jellyfin"
 )
 
+// SubtitlesOff is Session.SubtitleTrack while subtitles are disabled.
+const SubtitlesOff = "off"
+
+// TrackFromIndex turns an Emby/Jellyfin PlayState stream index into a Session track: empty
+// when unknown, SubtitlesOff for a negative index (nothing selected).
+func TrackFromIndex(index *int) string {
+	switch {
+	case index == nil:
+		return ""
+	case *index < 0:
+		return SubtitlesOff
+	default:
+		return strconv.Itoa(*index)
+	}
+}
+
 // ServerConfig holds configuration for a media server
 type ServerConfig struct {
 	ID          string     `json:"id"`
@@ -22,6 +41,22 @@ type ServerConfig struct {
 	APIKey      string     `json:"api_key"`
 	ExternalURL string     `json:"external_url,omitempty"`
 	Enabled     bool       `json:"enabled"`
+
+	// TLS options for HTTPS servers with self-signed or private-CA certificates
+	TLSCAFile             string `json:"tls_ca_file,omitempty"`
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify,omitempty"`
+
+	// Optional HTTP/SOCKS5 proxy for servers only reachable through a tunnel or bastion
+	ProxyURL string `json:"proxy_url,omitempty"`
+}
+
+// TransportOptions returns the connection settings applied to this server's HTTP traffic.
+func (c ServerConfig) TransportOptions() httpx.TransportOptions {
+	return httpx.TransportOptions{
+		CAFile:             c.TLSCAFile,
+		InsecureSkipVerify: c.TLSInsecureSkipVerify,
+		ProxyURL:           c.ProxyURL,
+	}
 }
 
 // SystemInfo represents server system information
@@ -61,6 +96,8 @@ type Session struct {
 
 	// Client information
 	ClientApp     string `json:"client_app"`
+	ClientVersion string `json:"client_version,omitempty"`
+	Platform      string `json:"platform,omitempty"` // OS platform, see NormalizePlatform
 	DeviceName    string `json:"device_name"`
 	RemoteAddress string `json:"remote_address,omitempty"`
 
@@ -83,6 +120,11 @@ type Session struct {
 	SubtitleCodec    string `json:"subtitle_codec,omitempty"`
 	SubtitleCount    int    `json:"subtitle_count"`
 
+	// Selected tracks (stream index or id) for spotting track switches between polls; empty
+	// when the server doesn't say. SubtitleTrack is SubtitlesOff when subtitles are disabled.
+	AudioTrack    string `json:"audio_track,omitempty"`
+	SubtitleTrack string `json:"subtitle_track,omitempty"`
+
 	// Quality indicators
 	DolbyVision bool `json:"dolby_vision"`
 	HDR10       bool `json:"hdr10"`
@@ -96,6 +138,9 @@ type Session struct {
 	TranscodeWidth      int      `json:"transcode_width,omitempty"`
 	TranscodeHeight     int      `json:"transcode_height,omitempty"`
 	TranscodeBitrate    int64    `json:"transcode_bitrate,omitempty"`
+	TranscodeFramerate  float64  `json:"transcode_framerate,omitempty"` // encoder output fps (Emby/Jellyfin)
+	TranscodeSpeed      float64  `json:"transcode_speed,omitempty"`     // x realtime (Plex)
+	TranscodeHardware   bool     `json:"transcode_hardware,omitempty"`  // hardware decoding or encoding in use
 
 	// Track-specific methods
 	VideoMethod string `json:"video_method,omitempty"` // "Direct Play", "Transcode"
@@ -104,6 +149,10 @@ type Session struct {
 	// State
 	IsPaused bool `json:"is_paused"`
 
+	// SyncPlay / watch-together group (empty when watching alone)
+	SyncPlayGroupID   string `json:"syncplay_group_id,omitempty"`
+	SyncPlayGroupName string `json:"syncplay_group_name,omitempty"`
+
 	// Timestamps
 	LastUpdate time.Time `json:"last_update"`
 }
@@ -125,6 +174,11 @@ type MediaItem struct {
 	FilePath       string     `json:"file_path,omitempty"` // Physical file path for deduplication
 	ProductionYear *int       `json:"production_year,omitempty"`
 	Genres         []string   `json:"genres,omitempty"`
+	Tags           []string   `json:"tags,omitempty"`            // Emby/Jellyfin tags, Plex labels
+	OfficialRating string     `json:"official_rating,omitempty"` // parental rating, e.g. "PG-13", "TV-MA"
+
+	// External IDs keyed by lower-case provider ("imdb", "tmdb", "tvdb"); set by SearchItems and library syncs
+	ProviderIDs map[string]string `json:"provider_ids,omitempty"`
 
 	// Episode-specific fields
 	SeriesID          string `json:"series_id,omitempty"`
@@ -143,6 +197,8 @@ type PlayHistoryItem struct {
 	DatePlayed  string     `json:"date_played"`          // ISO8601
 	PlaybackPos int64      `json:"playback_position_ms"` // Position in milliseconds
 	UserID      string     `json:"user_id"`
+	HistoryID   string     `json:"history_id,omitempty"`  // Server-side history entry, when the server keeps one
+	DurationMs  int64      `json:"duration_ms,omitempty"` // Item runtime in milliseconds, when known
 }
 
 // UserDataItem represents a user's playback state for a specific item
@@ -157,9 +213,65 @@ type UserDataItem struct {
 	PlayCount          int        `json:"play_count"`
 	PlaybackPositionMs int64      `json:"playback_position_ms"`
 	LastPlayed         string     `json:"last_played"`
+	SeriesName         string     `json:"series_name,omitempty"`
+}
+
+// DownloadJob represents an offline download / device sync job (normalized across server types)
+type DownloadJob struct {
+	ServerID   string     `json:"server_id"`
+	ServerType ServerType `json:"server_type"`
+	JobID      string     `json:"job_id"`
+	UserID     string     `json:"user_id,omitempty"`
+	UserName   string     `json:"user_name,omitempty"`
+	ItemID     string     `json:"item_id,omitempty"`
+	ItemName   string     `json:"item_name,omitempty"`
+	ItemType   string     `json:"item_type,omitempty"`
+	DeviceName string     `json:"device_name,omitempty"`
+	Status     string     `json:"status"`   // Queued, Converting, Transferring, Completed, Failed...
+	Progress   float64    `json:"progress"` // 0-100
+	SizeBytes  int64      `json:"size_bytes,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+}
+
+// Recording represents a DVR recording or scheduled recording (normalized across server types)
+type Recording struct {
+	ServerID      string     `json:"server_id"`
+	ServerType    ServerType `json:"server_type"`
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	SeriesName    string     `json:"series_name,omitempty"`
+	ChannelName   string     `json:"channel_name,omitempty"`
+	Status        string     `json:"status,omitempty"`
+	StartDate     *time.Time `json:"start_date,omitempty"`
+	EndDate       *time.Time `json:"end_date,omitempty"`
+	SizeBytes     int64      `json:"size_bytes,omitempty"`
+	SeriesTimerID string     `json:"series_timer_id,omitempty"`
+	Scheduled     bool       `json:"scheduled"` // true for upcoming timers, false for recorded items
+}
+
+// SeriesTimer represents a DVR series recording rule
+type SeriesTimer struct {
+	ServerID         string     `json:"server_id"`
+	ServerType       ServerType `json:"server_type"`
+	ID               string     `json:"id"`
+	Name             string     `json:"name"`
+	ChannelName      string     `json:"channel_name,omitempty"`
+	RecordNewOnly    bool       `json:"record_new_only"`
+	RecordAnyChannel bool       `json:"record_any_channel"`
 }
 
 // ServerHealth represents the health status of a media server
+// ServerWarning marks a server whose data is missing from an aggregated response because
+// its last request failed, so callers can say "Plex data unavailable" instead of
+// presenting partial totals as complete.
+type ServerWarning struct {
+	ServerID   string     `json:"server_id"`
+	ServerName string     `json:"server_name"`
+	ServerType ServerType `json:"server_type"`
+	Message    string     `json:"message"`
+	Since      time.Time  `json:"since"` // first failure of the current outage
+}
+
 type ServerHealth struct {
 	ServerID     string     `json:"server_id"`
 	ServerType   ServerType `json:"server_type"`
@@ -13,45 +13,73 @@ import (
 // AdminAuth creates middleware to protect admin endpoints with token authentication
 func AdminAuth(adminToken string) fiber.Handler {
 	return func(c fiber.Ctx) error {
-		// Skip authentication if no token is configured (with warning logged at startup)
-		if adminToken == "" {
+		if hasAdminToken(c, adminToken) {
 			return c.Next()
 		}
 
-		// Check for Authorization: Bearer <token>
-		authHeader := c.Get("Authorization")
-		if authHeader != "" {
-			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
-				providedToken := parts[1]
-				if constantTimeCompare(providedToken, adminToken) {
-					return c.Next()
-				}
-			}
+		// No valid token found
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Unauthorized",
+			"message": "Valid admin token required. Use 'Authorization: Bearer <token>' or 'X-Admin-Token: <token>' header.",
+		})
+	}
+}
+
+// WidgetAuth protects read-only widget endpoints with an API key sent as an X-API-Key header or
+// apikey query parameter. The admin token is accepted too; an empty apiKey leaves the route open.
+func WidgetAuth(apiKey, adminToken string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if apiKey == "" {
+			return c.Next()
+		}
+		if constantTimeCompare(c.Get("X-API-Key"), apiKey) || constantTimeCompare(c.Query("apikey"), apiKey) {
+			return c.Next()
+		}
+		if adminToken != "" && hasAdminToken(c, adminToken) {
+			return c.Next()
 		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Unauthorized",
+			"message": "Valid API key required. Use the 'X-API-Key: <key>' header or '?apikey=<key>'.",
+		})
+	}
+}
 
-		// Check for X-Admin-Token header
-		tokenHeader := c.Get("X-Admin-Token")
-		if tokenHeader != "" {
-			if constantTimeCompare(tokenHeader, adminToken) {
-				return c.Next()
+// hasAdminToken reports whether the request carries adminToken.
+func hasAdminToken(c fiber.Ctx, adminToken string) bool {
+	// Skip authentication if no token is configured (with warning logged at startup)
+	if adminToken == "" {
+		return true
+	}
+
+	// Check for Authorization: Bearer <token>
+	authHeader := c.Get("Authorization")
+	if authHeader != "" {
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+			providedToken := parts[1]
+			if constantTimeCompare(providedToken, adminToken) {
+				return true
 			}
 		}
+	}
 
-		// Check for HttpOnly cookie (auto-auth for same-origin UI)
-		cookieToken := c.Cookies("admin_token")
-		if cookieToken != "" {
-			if constantTimeCompare(cookieToken, adminToken) {
-				return c.Next()
-			}
+	// Check for X-Admin-Token header
+	tokenHeader := c.Get("X-Admin-Token")
+	if tokenHeader != "" {
+		if constantTimeCompare(tokenHeader, adminToken) {
+			return true
 		}
+	}
 
-		// No valid token found
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error":   "Unauthorized",
-			"message": "Valid admin token required. Use 'Authorization: Bearer <token>' or 'X-Admin-Token: <token>' header.",
-		})
+	// Check for HttpOnly cookie (auto-auth for same-origin UI)
+	cookieToken := c.Cookies("admin_token")
+	if cookieToken != "" {
+		if constantTimeCompare(cookieToken, adminToken) {
+			return true
+		}
 	}
+	return false
 }
 
 // WebhookAuth creates middleware to validate webhook signatures using HMAC-SHA256
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"database/sql"
+	"strings"
+
+	"emby-analytics/internal/handlers/settings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// MaintenanceGuard rejects mutating requests while maintenance mode is on. Safe methods
+// (GET/HEAD/OPTIONS) always pass, as do paths starting with one of exempt (e.g. webhook
+// ingest, which must keep running during maintenance).
+func MaintenanceGuard(db *sql.DB, exempt ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		path := c.Path()
+		for _, prefix := range exempt {
+			if strings.HasPrefix(path, prefix) {
+				return c.Next()
+			}
+		}
+		if settings.MaintenanceEnabled(db) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error":       "Maintenance mode",
+				"message":     "The server is in maintenance mode; admin changes are disabled until it is switched off.",
+				"maintenance": true,
+			})
+		}
+		return c.Next()
+	}
+}
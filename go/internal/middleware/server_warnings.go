@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"encoding/json"
+
+	"emby-analytics/internal/media"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ServerWarningsHeader carries the failing servers as a JSON array of media.ServerWarning,
+// so responses that are plain arrays can report missing data without changing shape.
+const ServerWarningsHeader = "X-Server-Warnings"
+
+// ServerWarnings marks every response with the servers whose data is currently missing,
+// letting the UI show "Plex data unavailable" next to totals that would otherwise look complete.
+func ServerWarnings(mgr *media.MultiServerManager) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if mgr != nil {
+			SetServerWarnings(c, mgr.ServerWarnings())
+		}
+		return c.Next()
+	}
+}
+
+// SetServerWarnings sets the warnings header; it is left unset when there are none.
+func SetServerWarnings(c fiber.Ctx, warnings []media.ServerWarning) {
+	if len(warnings) == 0 {
+		return
+	}
+	if b, err := json.Marshal(warnings); err == nil {
+		c.Set(ServerWarningsHeader, string(b))
+	}
+}
@@ -17,6 +17,16 @@ type userCtx struct {
 
 const userLocalsKey = "app_user"
 
+// ViewerLocalsKey holds the request's Viewer, set by AttachViewer. Websocket handlers read it
+// with conn.Locals since request locals are copied to the connection.
+const ViewerLocalsKey = "viewer"
+
+// Viewer describes who is reading a response, for handlers that hide other users' details.
+type Viewer struct {
+	Admin     bool  // admin session or valid ADMIN_TOKEN
+	AppUserID int64 // signed-in app user, 0 if none
+}
+
 // AttachUser parses the auth cookie and attaches the user (if valid) to context locals.
 func AttachUser(db *sql.DB, cfg config.Config) fiber.Handler {
 	return func(c fiber.Ctx) error {
@@ -38,6 +48,29 @@ func AttachUser(db *sql.DB, cfg config.Config) fiber.Handler {
 	}
 }
 
+// AttachViewer records the request's Viewer; it must run after AttachUser.
+func AttachViewer(adminToken string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var v Viewer
+		if u, ok := c.Locals(userLocalsKey).(*userCtx); ok && u != nil {
+			v.AppUserID = u.ID
+			v.Admin = strings.ToLower(u.Role) == "admin"
+		}
+		if !v.Admin {
+			v.Admin = hasAdminToken(c, adminToken)
+		}
+		c.Locals(ViewerLocalsKey, v)
+		return c.Next()
+	}
+}
+
+// CurrentViewer returns the Viewer attached by AttachViewer; requests it did not see are
+// treated as anonymous non-admins.
+func CurrentViewer(c fiber.Ctx) Viewer {
+	v, _ := c.Locals(ViewerLocalsKey).(Viewer)
+	return v
+}
+
 // RequireUserForUI ensures UI pages are accessed by authenticated users. It should be applied
 // to non-API GET routes before static file serving. Excludes /login and /auth/*.
 func RequireUserForUI(cfg config.Config) fiber.Handler {
@@ -50,7 +83,7 @@ func RequireUserForUI(cfg config.Config) fiber.Handler {
 			return c.Next()
 		}
 		// Allow API endpoints through (not UI)
-		if strings.HasPrefix(path, "/stats") || strings.HasPrefix(path, "/admin") || strings.HasPrefix(path, "/now") || strings.HasPrefix(path, "/config") || strings.HasPrefix(path, "/api") || strings.HasPrefix(path, "/items") || strings.HasPrefix(path, "/img") || strings.HasPrefix(path, "/_next/") {
+		if strings.HasPrefix(path, "/stats") || strings.HasPrefix(path, "/admin") || strings.HasPrefix(path, "/now") || strings.HasPrefix(path, "/config") || strings.HasPrefix(path, "/api") || strings.HasPrefix(path, "/items") || strings.HasPrefix(path, "/img") || strings.HasPrefix(path, "/export") || strings.HasPrefix(path, "/grafana") || strings.HasPrefix(path, "/reports") || strings.HasPrefix(path, "/resolve") || strings.HasPrefix(path, "/_next/") {
 			return c.Next()
 		}
 		if c.Locals(userLocalsKey) == nil {
@@ -73,3 +106,27 @@ func AdminAccess(db *sql.DB, adminToken string, cfg config.Config) fiber.Handler
 		return base(c)
 	}
 }
+
+// CurrentUserID returns the ID of the signed-in app user attached by AttachUser.
+func CurrentUserID(c fiber.Ctx) (int64, bool) {
+	if u, ok := c.Locals(userLocalsKey).(*userCtx); ok && u != nil {
+		return u.ID, true
+	}
+	return 0, false
+}
+
+// AdminOrLinkedUser allows admins (session or ADMIN_TOKEN) and the signed-in app user
+// whose linked media user matches the :param route parameter.
+func AdminOrLinkedUser(db *sql.DB, adminToken string, cfg config.Config, param string) fiber.Handler {
+	admin := AdminAccess(db, adminToken, cfg)
+	return func(c fiber.Ctx) error {
+		if u, ok := c.Locals(userLocalsKey).(*userCtx); ok && u != nil && strings.ToLower(u.Role) != "admin" {
+			var linked sql.NullString
+			if err := db.QueryRow(`SELECT media_user_id FROM app_user WHERE id = ?`, u.ID).Scan(&linked); err == nil &&
+				linked.Valid && linked.String != "" && linked.String == c.Params(param) {
+				return c.Next()
+			}
+		}
+		return admin(c)
+	}
+}
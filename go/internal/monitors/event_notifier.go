@@ -0,0 +1,96 @@
+package monitors
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"emby-analytics/internal/hooks"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/notify"
+)
+
+// StartSessionNotifications subscribes to session starts and sends the playback_started,
+// transcode_4k and new_user notification events, each only while its kind is enabled.
+func StartSessionNotifications(db *sql.DB) {
+	hooks.Register(hooks.SessionStarted, func(e hooks.Event) {
+		p, ok := e.Data.(hooks.SessionStart)
+		if !ok {
+			return
+		}
+		s := p.Session
+		fields := map[string]string{
+			"user":        s.UserName,
+			"item":        s.ItemName,
+			"device":      s.DeviceName,
+			"client":      s.ClientApp,
+			"server_id":   s.ServerID,
+			"play_method": s.PlayMethod,
+		}
+
+		if notify.Enabled(notify.KindNewUser) && isFirstSession(db, s, p.SessionFK) {
+			notify.Send(notify.Event{
+				Kind:    notify.KindNewUser,
+				Title:   "New user: " + s.UserName,
+				Message: s.UserName + " played something for the first time: " + s.ItemName,
+				Fields:  fields,
+			})
+		}
+		if notify.Enabled(notify.KindPlaybackStarted) {
+			notify.Send(notify.Event{
+				Kind:    notify.KindPlaybackStarted,
+				Title:   "Playing: " + s.ItemName,
+				Message: s.UserName + " started playing " + s.ItemName + " on " + s.DeviceName,
+				Fields:  fields,
+			})
+		}
+		if notify.Enabled(notify.KindTranscode4K) && is4KTranscode(s) {
+			tf := map[string]string{
+				"source":    fmt.Sprintf("%dx%d %s", s.Width, s.Height, s.VideoCodec),
+				"transcode": strings.TrimSpace(fmt.Sprintf("%dx%d %s", s.TranscodeWidth, s.TranscodeHeight, s.TranscodeVideoCodec)),
+			}
+			for k, v := range fields {
+				tf[k] = v
+			}
+			if len(s.TranscodeReasons) > 0 {
+				tf["reasons"] = strings.Join(s.TranscodeReasons, ", ")
+			}
+			notify.Send(notify.Event{
+				Kind:    notify.KindTranscode4K,
+				Title:   "4K transcode: " + s.ItemName,
+				Message: s.UserName + " is transcoding 4K video of " + s.ItemName + " on " + s.DeviceName,
+				Fields:  tf,
+			})
+		}
+	})
+	logging.Info("Session notifications registered")
+}
+
+// is4KTranscode reports whether a session transcodes the video of a 4K source.
+func is4KTranscode(s media.Session) bool {
+	if s.Width < 3200 && s.Height < 2000 {
+		return false
+	}
+	if s.VideoMethod != "" {
+		return strings.EqualFold(s.VideoMethod, "Transcode")
+	}
+	return strings.EqualFold(s.PlayMethod, "Transcode") && s.TranscodeVideoCodec != ""
+}
+
+// isFirstSession reports whether a user has no session on the server before this one.
+// Imported history counts, so users known from it are not new.
+func isFirstSession(db *sql.DB, s media.Session, sessionFK int64) bool {
+	if s.UserID == "" {
+		return false
+	}
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM play_sessions WHERE user_id = ? AND server_id = ? AND id <> ?)`,
+		s.UserID, s.ServerID, sessionFK).Scan(&exists)
+	if err != nil {
+		logging.Debug("new user check failed", "user_id", s.UserID, "error", err)
+		return false
+	}
+	return !exists
+}
@@ -0,0 +1,173 @@
+package monitors
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/notify"
+	"emby-analytics/internal/queries"
+)
+
+// goalNudgeMinElapsed keeps "behind" nudges out of the first half of a goal period.
+const goalNudgeMinElapsed = 0.5
+
+// GoalMonitor sends nudges for personal watch goals: once per period when a limit is near or
+// exceeded, a target falls behind, or a target is achieved. Nudges go out as notification
+// events and as on-screen messages to the user's active sessions.
+type GoalMonitor struct {
+	db       *sql.DB
+	mgr      *media.MultiServerManager
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	interval time.Duration
+}
+
+// NewGoalMonitor creates a new goal monitor
+func NewGoalMonitor(db *sql.DB, mgr *media.MultiServerManager, interval time.Duration) *GoalMonitor {
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	return &GoalMonitor{
+		db:       db,
+		mgr:      mgr,
+		quit:     make(chan struct{}),
+		interval: interval,
+	}
+}
+
+// Start begins checking goals
+func (gm *GoalMonitor) Start() {
+	gm.wg.Add(1)
+	go gm.monitorLoop()
+	logging.Info("User goal monitor started", "interval", gm.interval)
+}
+
+// Stop gracefully stops the monitor
+func (gm *GoalMonitor) Stop() {
+	close(gm.quit)
+	gm.wg.Wait()
+	logging.Info("User goal monitor stopped")
+}
+
+func (gm *GoalMonitor) monitorLoop() {
+	defer gm.wg.Done()
+
+	ticker := time.NewTicker(gm.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-gm.quit:
+			return
+		case <-ticker.C:
+			gm.checkGoals()
+		}
+	}
+}
+
+func (gm *GoalMonitor) checkGoals() {
+	ctx := context.Background()
+	goals, err := queries.ListNudgeGoals(ctx, gm.db)
+	if err != nil {
+		logging.Debug("Failed to list goals for nudges", "error", err)
+		return
+	}
+	if len(goals) == 0 {
+		return
+	}
+
+	now := time.Now()
+	var sessions []media.Session
+	sessionsLoaded := false
+	for _, g := range goals {
+		p, err := queries.GetGoalProgress(ctx, gm.db, g.UserGoal, g.MediaUserID, now)
+		if err != nil {
+			logging.Debug("goal progress failed", "goal_id", g.ID, "error", err)
+			continue
+		}
+		kind := nudgeKind(p)
+		periodKey := p.PeriodKey
+		if g.Kind == queries.GoalFinishSeries && kind == "achieved" {
+			periodKey = "done" // a finished series stays finished; celebrate it only once
+		}
+		if kind == "" || !gm.recordEvent(g.ID, periodKey, kind, now) {
+			continue
+		}
+		if !sessionsLoaded && gm.mgr != nil {
+			sessions, _ = gm.mgr.GetAllSessions()
+			sessionsLoaded = true
+		}
+		gm.nudge(g, p, kind, sessions)
+	}
+}
+
+// nudgeKind returns the nudge due for the progress, or "" when none is.
+func nudgeKind(p *queries.GoalProgress) string {
+	switch p.Status {
+	case "exceeded", "near_limit", "achieved":
+		return p.Status
+	case "behind":
+		if p.Elapsed >= goalNudgeMinElapsed {
+			return "behind"
+		}
+	}
+	return ""
+}
+
+func (gm *GoalMonitor) nudge(g queries.NudgeGoal, p *queries.GoalProgress, kind string, sessions []media.Session) {
+	var body string
+	switch kind {
+	case "exceeded":
+		body = fmt.Sprintf("You've gone past your goal \"%s\" (%.1fh watched).", g.Title, p.WatchedHours)
+	case "near_limit":
+		body = fmt.Sprintf("You're close to your goal \"%s\" (%.1fh watched).", g.Title, p.WatchedHours)
+	case "behind":
+		body = fmt.Sprintf("You're behind on your goal \"%s\" (%.0f%% done).", g.Title, p.Percent)
+	default:
+		body = fmt.Sprintf("Goal reached: \"%s\"!", g.Title)
+	}
+
+	notify.Send(notify.Event{
+		Kind:    "goal_nudge",
+		Title:   "Watch goal",
+		Message: body,
+		Fields: map[string]string{
+			"user":    g.Username,
+			"goal_id": fmt.Sprint(g.ID),
+			"status":  kind,
+			"period":  p.PeriodKey,
+		},
+		Data: p,
+	})
+
+	for _, s := range sessions {
+		if s.UserID != g.MediaUserID || s.ItemID == "" {
+			continue
+		}
+		client, ok := gm.mgr.GetClient(s.ServerID)
+		if !ok || client == nil {
+			continue
+		}
+		if err := client.SendMessage(s.SessionID, "Watch Goal", body, 8000); err != nil {
+			logging.Debug("Failed to send goal nudge", "error", err, "session_id", s.SessionID)
+		}
+	}
+}
+
+// recordEvent stores a goal nudge; returns false if it was already sent for this period.
+func (gm *GoalMonitor) recordEvent(goalID int64, periodKey, kind string, now time.Time) bool {
+	res, err := gm.db.Exec(`
+		INSERT OR IGNORE INTO user_goal_events (goal_id, period_key, kind, created_at)
+		VALUES (?, ?, ?, ?)
+	`, goalID, periodKey, kind, now.Unix())
+	if err != nil {
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
@@ -0,0 +1,277 @@
+package monitors
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"emby-analytics/internal/audit"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+)
+
+// IdlePolicy configures the paused session auto-stop.
+type IdlePolicy struct {
+	Minutes         int            // pause limit; 0 disables the policy unless a server overrides it
+	WarnMinutes     int            // the user is messaged this long before the stop
+	ServerMinutes   map[string]int // per-server limit overrides; 0 exempts the server
+	ClientWhitelist []string       // client apps that are never stopped (case-insensitive)
+}
+
+// limitFor returns the pause limit for a server, or 0 when sessions there are never stopped.
+func (p IdlePolicy) limitFor(serverID string) time.Duration {
+	mins := p.Minutes
+	if m, ok := p.ServerMinutes[serverID]; ok {
+		mins = m
+	}
+	return time.Duration(mins) * time.Minute
+}
+
+func (p IdlePolicy) whitelisted(client string) bool {
+	for _, c := range p.ClientWhitelist {
+		if strings.EqualFold(c, client) {
+			return true
+		}
+	}
+	return false
+}
+
+// enabled reports whether any server has a pause limit.
+func (p IdlePolicy) enabled() bool {
+	if p.Minutes > 0 {
+		return true
+	}
+	for _, m := range p.ServerMinutes {
+		if m > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// IdleStopStats counts auto-stop actions for one server since startup.
+type IdleStopStats struct {
+	ServerID string `json:"server_id"`
+	Warned   int64  `json:"warned"`
+	Stopped  int64  `json:"stopped"`
+	Failed   int64  `json:"failed"`
+}
+
+type idleCounters struct {
+	warned, stopped, failed atomic.Int64
+}
+
+var idleCounts sync.Map // serverID -> *idleCounters
+
+func idleCountersFor(serverID string) *idleCounters {
+	v, _ := idleCounts.LoadOrStore(serverID, &idleCounters{})
+	return v.(*idleCounters)
+}
+
+// IdleStopSnapshot returns per-server auto-stop counters sorted by server.
+func IdleStopSnapshot() []IdleStopStats {
+	out := []IdleStopStats{}
+	idleCounts.Range(func(k, v interface{}) bool {
+		c := v.(*idleCounters)
+		out = append(out, IdleStopStats{
+			ServerID: k.(string),
+			Warned:   c.warned.Load(),
+			Stopped:  c.stopped.Load(),
+			Failed:   c.failed.Load(),
+		})
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].ServerID < out[j].ServerID })
+	return out
+}
+
+// pausedSession is a session seen paused on consecutive checks.
+type pausedSession struct {
+	since  time.Time
+	warned bool
+}
+
+// IdleSessionMonitor messages and then stops sessions that stay paused past the policy's
+// limit, so abandoned streams stop holding transcoder slots.
+type IdleSessionMonitor struct {
+	db       *sql.DB
+	mgr      *media.MultiServerManager
+	policy   IdlePolicy
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	interval time.Duration
+	paused   map[string]*pausedSession // serverID|sessionID -> pause state
+}
+
+// NewIdleSessionMonitor creates a new paused session monitor
+func NewIdleSessionMonitor(db *sql.DB, mgr *media.MultiServerManager, policy IdlePolicy, interval time.Duration) *IdleSessionMonitor {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	if policy.WarnMinutes < 0 {
+		policy.WarnMinutes = 0
+	}
+	return &IdleSessionMonitor{
+		db:       db,
+		mgr:      mgr,
+		policy:   policy,
+		quit:     make(chan struct{}),
+		interval: interval,
+		paused:   make(map[string]*pausedSession),
+	}
+}
+
+// Start begins monitoring; it does nothing when no server has a pause limit.
+func (im *IdleSessionMonitor) Start() {
+	if !im.policy.enabled() {
+		return
+	}
+	im.wg.Add(1)
+	go im.monitorLoop()
+	logging.Info("Paused session monitor started", "limit_minutes", im.policy.Minutes, "interval", im.interval)
+}
+
+// Stop gracefully stops the monitor
+func (im *IdleSessionMonitor) Stop() {
+	if !im.policy.enabled() {
+		return
+	}
+	close(im.quit)
+	im.wg.Wait()
+	logging.Info("Paused session monitor stopped")
+}
+
+func (im *IdleSessionMonitor) monitorLoop() {
+	defer im.wg.Done()
+
+	ticker := time.NewTicker(im.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-im.quit:
+			return
+		case <-ticker.C:
+			im.checkSessions(time.Now())
+		}
+	}
+}
+
+func (im *IdleSessionMonitor) checkSessions(now time.Time) {
+	if im.mgr == nil {
+		return
+	}
+	sessions, err := im.mgr.GetAllSessions()
+	if err != nil {
+		logging.Debug("Failed to get active sessions for paused session monitor", "error", err)
+		return
+	}
+
+	// Actions of one check share an audit job, created on the first action.
+	var logger *audit.CleanupLogger
+	var checked, acted int
+	logAction := func(action string, s media.Session, pausedFor time.Duration, actionErr error) {
+		if logger == nil {
+			logger, err = audit.NewCleanupLogger(im.db, "idle-session-stop", "system")
+			if err != nil {
+				logging.Warn("failed to create audit job for paused sessions", "error", err)
+				return
+			}
+		}
+		meta := map[string]interface{}{
+			"session_id":     s.SessionID,
+			"server_id":      s.ServerID,
+			"user_id":        s.UserID,
+			"user_name":      s.UserName,
+			"client":         s.ClientApp,
+			"device":         s.DeviceName,
+			"play_method":    s.PlayMethod,
+			"paused_minutes": int(pausedFor / time.Minute),
+		}
+		if actionErr != nil {
+			meta["error"] = actionErr.Error()
+		}
+		if err := logger.LogItemAction(action, s.ItemID, s.ItemName, s.ItemType, "", meta); err != nil {
+			logging.Debug("failed to log paused session action", "error", err)
+		}
+	}
+
+	seen := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		key := s.ServerID + "|" + s.SessionID
+		limit := im.policy.limitFor(s.ServerID)
+		if s.ItemID == "" || !s.IsPaused || limit <= 0 || im.policy.whitelisted(s.ClientApp) {
+			continue
+		}
+		seen[key] = true
+		checked++
+
+		ps, ok := im.paused[key]
+		if !ok {
+			im.paused[key] = &pausedSession{since: now}
+			continue
+		}
+		pausedFor := now.Sub(ps.since)
+		warnAt := limit - time.Duration(im.policy.WarnMinutes)*time.Minute
+
+		client, ok := im.mgr.GetClient(s.ServerID)
+		if !ok || client == nil {
+			continue
+		}
+		counters := idleCountersFor(s.ServerID)
+
+		if pausedFor >= limit && (ps.warned || im.policy.WarnMinutes == 0) {
+			logging.Info("Stopping session paused too long",
+				"session_id", s.SessionID, "server_id", s.ServerID, "user", s.UserName, "paused", pausedFor.Round(time.Minute))
+			body := fmt.Sprintf("Playback was paused for %d minutes and has been stopped to free server resources.", int(pausedFor/time.Minute))
+			if err := client.SendMessage(s.SessionID, "Playback Stopped", body, 5000); err == nil {
+				// Small delay to give the client a chance to render the message
+				time.Sleep(750 * time.Millisecond)
+			}
+			stopErr := client.StopSession(s.SessionID)
+			if stopErr != nil {
+				counters.failed.Add(1)
+				logging.Error("Failed to stop paused session", "error", stopErr, "session_id", s.SessionID)
+				logAction("stop_failed", s, pausedFor, stopErr)
+			} else {
+				counters.stopped.Add(1)
+				logAction("session_stopped", s, pausedFor, nil)
+			}
+			acted++
+			delete(im.paused, key)
+			continue
+		}
+
+		if !ps.warned && pausedFor >= warnAt {
+			remaining := int((limit - pausedFor + time.Minute - 1) / time.Minute)
+			if remaining < 1 {
+				remaining = 1 // warned late; stop on the next check
+			}
+			body := fmt.Sprintf("Playback has been paused for a while and will be stopped in %d minute(s) unless it is resumed.", remaining)
+			if err := client.SendMessage(s.SessionID, "Paused Playback", body, 8000); err != nil {
+				logging.Debug("Failed to send paused session warning", "error", err, "session_id", s.SessionID)
+			}
+			ps.warned = true
+			counters.warned.Add(1)
+			logAction("session_warned", s, pausedFor, nil)
+			acted++
+		}
+	}
+
+	// Resumed or ended sessions start over on their next pause.
+	for key := range im.paused {
+		if !seen[key] {
+			delete(im.paused, key)
+		}
+	}
+
+	if logger != nil {
+		if err := logger.CompleteJob(checked, acted, map[string]interface{}{"paused_sessions": checked}); err != nil {
+			logging.Debug("failed to complete paused session audit job", "error", err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package monitors
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/queries"
+)
+
+// QuotaMonitor enforces per-user viewing quotas: it sends a grace warning as the quota runs low
+// and messages/stops sessions once the daily or weekly quota is exhausted.
+type QuotaMonitor struct {
+	db       *sql.DB
+	mgr      *media.MultiServerManager
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	interval time.Duration
+}
+
+// NewQuotaMonitor creates a new quota monitor
+func NewQuotaMonitor(db *sql.DB, mgr *media.MultiServerManager, interval time.Duration) *QuotaMonitor {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &QuotaMonitor{
+		db:       db,
+		mgr:      mgr,
+		quit:     make(chan struct{}),
+		interval: interval,
+	}
+}
+
+// Start begins quota enforcement
+func (qm *QuotaMonitor) Start() {
+	qm.wg.Add(1)
+	go qm.monitorLoop()
+	logging.Info("User quota monitor started", "interval", qm.interval)
+}
+
+// Stop gracefully stops the monitor
+func (qm *QuotaMonitor) Stop() {
+	close(qm.quit)
+	qm.wg.Wait()
+	logging.Info("User quota monitor stopped")
+}
+
+func (qm *QuotaMonitor) monitorLoop() {
+	defer qm.wg.Done()
+
+	ticker := time.NewTicker(qm.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-qm.quit:
+			return
+		case <-ticker.C:
+			qm.checkQuotas()
+		}
+	}
+}
+
+// enforcedUsers returns the set of user IDs whose quota is marked for enforcement
+func (qm *QuotaMonitor) enforcedUsers() map[string]bool {
+	out := make(map[string]bool)
+	rows, err := qm.db.Query(`SELECT user_id FROM user_quotas WHERE enforce = 1`)
+	if err != nil {
+		return out
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			out[id] = true
+		}
+	}
+	return out
+}
+
+func (qm *QuotaMonitor) checkQuotas() {
+	if qm.mgr == nil {
+		return
+	}
+	enforced := qm.enforcedUsers()
+	if len(enforced) == 0 {
+		return
+	}
+
+	sessions, err := qm.mgr.GetAllSessions()
+	if err != nil {
+		logging.Debug("Failed to get active sessions for quota monitor", "error", err)
+		return
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	statusByUser := make(map[string]*queries.UserQuotaStatus)
+
+	for _, session := range sessions {
+		if session.ItemID == "" || !enforced[session.UserID] {
+			continue
+		}
+		status, ok := statusByUser[session.UserID]
+		if !ok {
+			status, err = queries.GetUserQuotaStatus(ctx, qm.db, session.UserID, now)
+			if err != nil {
+				logging.Debug("quota status failed", "user_id", session.UserID, "error", err)
+				continue
+			}
+			statusByUser[session.UserID] = status
+		}
+		if status.Quota == nil {
+			continue
+		}
+
+		client, ok := qm.mgr.GetClient(session.ServerID)
+		if !ok || client == nil {
+			continue
+		}
+
+		qm.enforceSession(client, session, status, now)
+	}
+}
+
+// enforceSession stops a session whose user exhausted a quota, or sends a one-time grace warning
+func (qm *QuotaMonitor) enforceSession(client media.MediaServerClient, session media.Session, status *queries.UserQuotaStatus, now time.Time) {
+	periods := []queries.QuotaPeriodUsage{status.Daily, status.Weekly}
+
+	// Exhausted: explain and stop playback
+	for _, period := range periods {
+		if !period.Exhausted {
+			continue
+		}
+		logging.Info("Stopping session: viewing quota exhausted",
+			"session_id", session.SessionID, "user", session.UserName, "period", period.PeriodKey)
+		body := fmt.Sprintf("Your %s viewing time limit has been reached.", quotaPeriodLabel(period.PeriodKey))
+		if err := client.SendMessage(session.SessionID, "Viewing Limit Reached", body, 5000); err == nil {
+			// Small delay to give the client a chance to render the message
+			time.Sleep(750 * time.Millisecond)
+		}
+		if err := client.StopSession(session.SessionID); err != nil {
+			logging.Error("Failed to stop quota-exhausted session", "error", err, "session_id", session.SessionID)
+		}
+		qm.recordEvent(session.UserID, period.PeriodKey, "exhausted", now)
+		return
+	}
+
+	// Grace warning: once per period when remaining time drops below the warning threshold
+	for _, period := range periods {
+		if period.RemainingMinutes == nil || *period.RemainingMinutes > float64(status.Quota.WarningMinutes) {
+			continue
+		}
+		if !qm.recordEvent(session.UserID, period.PeriodKey, "warning", now) {
+			continue // already warned this period
+		}
+		remaining := int(math.Ceil(*period.RemainingMinutes))
+		body := fmt.Sprintf("You have %d minute(s) of %s viewing time left.", remaining, quotaPeriodLabel(period.PeriodKey))
+		if err := client.SendMessage(session.SessionID, "Viewing Limit", body, 8000); err != nil {
+			logging.Debug("Failed to send quota warning", "error", err, "session_id", session.SessionID)
+		}
+		return
+	}
+}
+
+// recordEvent stores a quota event; returns false if it was already recorded for this period.
+func (qm *QuotaMonitor) recordEvent(userID, periodKey, kind string, now time.Time) bool {
+	res, err := qm.db.Exec(`
+		INSERT OR IGNORE INTO user_quota_events (user_id, period_key, kind, created_at)
+		VALUES (?, ?, ?, ?)
+	`, userID, periodKey, kind, now.Unix())
+	if err != nil {
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+func quotaPeriodLabel(periodKey string) string {
+	if strings.HasPrefix(periodKey, "week:") {
+		return "weekly"
+	}
+	return "daily"
+}
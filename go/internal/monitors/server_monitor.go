@@ -0,0 +1,118 @@
+package monitors
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/notify"
+)
+
+// serverOfflineAfter is how many failed checks in a row mark a server offline, so a single
+// slow response doesn't page anyone.
+const serverOfflineAfter = 2
+
+// ServerMonitor checks every enabled media server and sends server_offline when one stops
+// answering and server_online when it is back.
+type ServerMonitor struct {
+	mgr      *media.MultiServerManager
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	interval time.Duration
+	// failures counts consecutive failed checks per server; offline holds the servers an
+	// offline notice went out for
+	failures map[string]int
+	offline  map[string]bool
+}
+
+// NewServerMonitor creates a new server availability monitor
+func NewServerMonitor(mgr *media.MultiServerManager, interval time.Duration) *ServerMonitor {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &ServerMonitor{
+		mgr:      mgr,
+		quit:     make(chan struct{}),
+		interval: interval,
+		failures: map[string]int{},
+		offline:  map[string]bool{},
+	}
+}
+
+// Start begins checking servers
+func (sm *ServerMonitor) Start() {
+	sm.wg.Add(1)
+	go sm.monitorLoop()
+	logging.Info("Server availability monitor started", "interval", sm.interval)
+}
+
+// Stop gracefully stops the monitor
+func (sm *ServerMonitor) Stop() {
+	close(sm.quit)
+	sm.wg.Wait()
+	logging.Info("Server availability monitor stopped")
+}
+
+func (sm *ServerMonitor) monitorLoop() {
+	defer sm.wg.Done()
+
+	ticker := time.NewTicker(sm.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sm.quit:
+			return
+		case <-ticker.C:
+			sm.checkServers()
+		}
+	}
+}
+
+func (sm *ServerMonitor) checkServers() {
+	if sm.mgr == nil {
+		return
+	}
+	configs := sm.mgr.GetServerConfigs()
+	for id, h := range sm.mgr.GetServerHealth() {
+		if h == nil || !configs[id].Enabled {
+			continue
+		}
+		name := h.ServerName
+		if name == "" {
+			name = id
+		}
+		fields := map[string]string{"server_id": id, "server_type": string(h.ServerType)}
+
+		if h.IsReachable {
+			if sm.offline[id] {
+				notify.Send(notify.Event{
+					Kind:    notify.KindServerOnline,
+					Title:   "Server back online: " + name,
+					Message: name + " is answering again",
+					Fields:  fields,
+				})
+				logging.Info("Media server is back online", "server", id)
+			}
+			delete(sm.failures, id)
+			delete(sm.offline, id)
+			continue
+		}
+
+		sm.failures[id]++
+		if sm.failures[id] < serverOfflineAfter || sm.offline[id] {
+			continue
+		}
+		sm.offline[id] = true
+		fields["error"] = h.Error
+		notify.Send(notify.Event{
+			Kind:    notify.KindServerOffline,
+			Title:   "Server offline: " + name,
+			Message: name + " has not answered the last " + strconv.Itoa(sm.failures[id]) + " checks",
+			Fields:  fields,
+		})
+		logging.Warn("Media server is offline", "server", id, "error", h.Error)
+	}
+}
@@ -0,0 +1,350 @@
+package monitors
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/notify"
+)
+
+// TranscodeAlertMonitor raises an alert when sessions transcoding for a configured reason
+// spike above their usual rate, e.g. SubtitleCodecNotSupported after a client update.
+// Rules with digest delivery are collected and sent once a day at the digest time.
+type TranscodeAlertMonitor struct {
+	db           *sql.DB
+	quit         chan struct{}
+	wg           sync.WaitGroup
+	interval     time.Duration
+	digestHour   int
+	digestMinute int
+	digestLoc    *time.Location
+}
+
+type transcodeAlertRule struct {
+	id           int64
+	reason       string
+	serverID     string
+	window       time.Duration
+	baselineDays int
+	minCount     int
+	spikeFactor  float64
+	delivery     string
+	note         string
+}
+
+// transcodeReasonSession is one session that reported at least one transcode reason.
+type transcodeReasonSession struct {
+	startedAt int64
+	serverID  string
+	reasons   []string
+}
+
+// NewTranscodeAlertMonitor creates a new transcode reason alert monitor
+func NewTranscodeAlertMonitor(db *sql.DB, interval time.Duration, digestHour, digestMinute int, digestLoc *time.Location) *TranscodeAlertMonitor {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	if digestLoc == nil {
+		digestLoc = time.Local
+	}
+	return &TranscodeAlertMonitor{
+		db:           db,
+		quit:         make(chan struct{}),
+		interval:     interval,
+		digestHour:   digestHour,
+		digestMinute: digestMinute,
+		digestLoc:    digestLoc,
+	}
+}
+
+// Start begins evaluating the alert rules
+func (tm *TranscodeAlertMonitor) Start() {
+	tm.wg.Add(1)
+	go tm.monitorLoop()
+	logging.Info("Transcode alert monitor started", "interval", tm.interval)
+}
+
+// Stop gracefully stops the monitor
+func (tm *TranscodeAlertMonitor) Stop() {
+	close(tm.quit)
+	tm.wg.Wait()
+	logging.Info("Transcode alert monitor stopped")
+}
+
+func (tm *TranscodeAlertMonitor) monitorLoop() {
+	defer tm.wg.Done()
+
+	ticker := time.NewTicker(tm.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tm.quit:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			tm.evaluate(now)
+			tm.sendDigest(now)
+		}
+	}
+}
+
+func (tm *TranscodeAlertMonitor) loadRules() []transcodeAlertRule {
+	rows, err := tm.db.Query(`
+		SELECT id, reason, COALESCE(server_id, ''), window_minutes, baseline_days, min_count, spike_factor, delivery, COALESCE(note, '')
+		FROM transcode_alert_rules WHERE enabled = 1`)
+	if err != nil {
+		logging.Debug("transcode alerts: failed to load rules", "error", err)
+		return nil
+	}
+	defer rows.Close()
+	var out []transcodeAlertRule
+	for rows.Next() {
+		var r transcodeAlertRule
+		var windowMinutes int
+		if err := rows.Scan(&r.id, &r.reason, &r.serverID, &windowMinutes, &r.baselineDays, &r.minCount, &r.spikeFactor, &r.delivery, &r.note); err != nil {
+			continue
+		}
+		if windowMinutes <= 0 {
+			windowMinutes = 60
+		}
+		r.window = time.Duration(windowMinutes) * time.Minute
+		out = append(out, r)
+	}
+	return out
+}
+
+// loadReasonSessions returns sessions started at or after since that recorded transcode reasons.
+func (tm *TranscodeAlertMonitor) loadReasonSessions(since int64) ([]transcodeReasonSession, error) {
+	rows, err := tm.db.Query(`
+		SELECT started_at, COALESCE(server_id, ''), transcode_reasons
+		FROM play_sessions
+		WHERE started_at >= ? AND COALESCE(transcode_reasons, '') != ''`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []transcodeReasonSession
+	for rows.Next() {
+		var s transcodeReasonSession
+		var reasons string
+		if err := rows.Scan(&s.startedAt, &s.serverID, &reasons); err != nil {
+			return nil, err
+		}
+		s.reasons = SplitTranscodeReasons(reasons)
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (tm *TranscodeAlertMonitor) evaluate(now time.Time) {
+	rules := tm.loadRules()
+	if len(rules) == 0 {
+		return
+	}
+	since := now.Unix()
+	for _, r := range rules {
+		if start := now.Add(-r.window).AddDate(0, 0, -r.baselineDays).Unix(); start < since {
+			since = start
+		}
+	}
+	sessions, err := tm.loadReasonSessions(since)
+	if err != nil {
+		logging.Debug("transcode alerts: failed to load sessions", "error", err)
+		return
+	}
+	for _, r := range rules {
+		count, baseline := r.measure(sessions, now)
+		if !r.isSpike(count, baseline) {
+			continue
+		}
+		tm.recordSpike(r, now, count, baseline)
+	}
+}
+
+// measure counts matching sessions in the rule's window ending at now, and the expected
+// count per window over the baseline period that precedes it.
+func (r transcodeAlertRule) measure(sessions []transcodeReasonSession, now time.Time) (int, float64) {
+	windowStart := now.Add(-r.window).Unix()
+	baselineStart := now.Add(-r.window).AddDate(0, 0, -r.baselineDays).Unix()
+	var count, baseCount int
+	for _, s := range sessions {
+		if r.serverID != "" && r.serverID != s.serverID {
+			continue
+		}
+		if !hasTranscodeReason(s.reasons, r.reason) {
+			continue
+		}
+		switch {
+		case s.startedAt >= windowStart:
+			count++
+		case s.startedAt >= baselineStart:
+			baseCount++
+		}
+	}
+	var baseline float64
+	if windowStart > baselineStart {
+		baseline = float64(baseCount) * float64(r.window/time.Second) / float64(windowStart-baselineStart)
+	}
+	return count, baseline
+}
+
+// isSpike requires at least minCount sessions and spikeFactor times the baseline rate; a
+// reason that never appeared before only has to reach minCount.
+func (r transcodeAlertRule) isSpike(count int, baseline float64) bool {
+	if count <= 0 || count < r.minCount {
+		return false
+	}
+	return float64(count) >= r.spikeFactor*baseline
+}
+
+// recordSpike stores the spike and notifies immediately unless the rule uses the digest.
+// A rule fires at most once per window so a lasting spike isn't reported on every tick.
+func (tm *TranscodeAlertMonitor) recordSpike(r transcodeAlertRule, now time.Time, count int, baseline float64) {
+	windowStart := now.Add(-r.window).Unix()
+	var recent int
+	if err := tm.db.QueryRow(`SELECT COUNT(*) FROM transcode_alert_events WHERE rule_id = ? AND created_at > ?`,
+		r.id, windowStart).Scan(&recent); err != nil || recent > 0 {
+		return
+	}
+
+	var deliveredAt any
+	if r.delivery != "digest" {
+		deliveredAt = now.Unix()
+	}
+	if _, err := tm.db.Exec(`
+		INSERT INTO transcode_alert_events
+			(rule_id, reason, server_id, window_start, window_end, session_count, baseline, delivery, delivered_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.id, r.reason, nullableString(r.serverID), windowStart, now.Unix(), count, baseline, r.delivery, deliveredAt, now.Unix()); err != nil {
+		logging.Debug("transcode alerts: failed to record spike", "rule_id", r.id, "error", err)
+		return
+	}
+	logging.Info("Transcode reason spike detected", "reason", r.reason, "sessions", count, "baseline", baseline, "delivery", r.delivery)
+	if r.delivery == "digest" {
+		return
+	}
+
+	fields := map[string]string{
+		"reason":   r.reason,
+		"sessions": fmt.Sprintf("%d", count),
+		"baseline": fmt.Sprintf("%.1f", baseline),
+		"window":   r.window.String(),
+	}
+	if r.serverID != "" {
+		fields["server_id"] = r.serverID
+	}
+	if r.note != "" {
+		fields["note"] = r.note
+	}
+	notify.Send(notify.Event{
+		Kind:    "transcode_reason_spike",
+		Title:   "Transcode reason spike: " + r.reason,
+		Message: fmt.Sprintf("%d sessions transcoded for %s in the last %s (usually %.1f)", count, r.reason, r.window, baseline),
+		Fields:  fields,
+	})
+}
+
+// TranscodeDigestEntry is one undelivered spike included in the daily digest.
+type TranscodeDigestEntry struct {
+	Reason       string  `json:"reason"`
+	ServerID     string  `json:"server_id,omitempty"`
+	WindowStart  int64   `json:"window_start"`
+	WindowEnd    int64   `json:"window_end"`
+	SessionCount int     `json:"session_count"`
+	Baseline     float64 `json:"baseline"`
+}
+
+// sendDigest delivers the spikes recorded before today's digest time in one notification.
+// Spikes detected after the digest time wait for the next day's digest.
+func (tm *TranscodeAlertMonitor) sendDigest(now time.Time) {
+	local := now.In(tm.digestLoc)
+	due := time.Date(local.Year(), local.Month(), local.Day(), tm.digestHour, tm.digestMinute, 0, 0, tm.digestLoc)
+	if local.Before(due) {
+		return
+	}
+
+	rows, err := tm.db.Query(`
+		SELECT id, reason, COALESCE(server_id, ''), window_start, window_end, session_count, baseline
+		FROM transcode_alert_events
+		WHERE delivered_at IS NULL AND created_at < ?
+		ORDER BY created_at`, due.Unix())
+	if err != nil {
+		logging.Debug("transcode alerts: failed to load digest", "error", err)
+		return
+	}
+	var ids []int64
+	var entries []TranscodeDigestEntry
+	for rows.Next() {
+		var id int64
+		var e TranscodeDigestEntry
+		if err := rows.Scan(&id, &e.Reason, &e.ServerID, &e.WindowStart, &e.WindowEnd, &e.SessionCount, &e.Baseline); err != nil {
+			continue
+		}
+		ids = append(ids, id)
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if len(entries) == 0 {
+		return
+	}
+
+	totals := map[string]int{}
+	for _, e := range entries {
+		totals[e.Reason] += e.SessionCount
+	}
+	reasons := make([]string, 0, len(totals))
+	for reason := range totals {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool { return totals[reasons[i]] > totals[reasons[j]] })
+	fields := map[string]string{}
+	for _, reason := range reasons {
+		fields[reason] = fmt.Sprintf("%d sessions", totals[reason])
+	}
+	notify.Send(notify.Event{
+		Kind:    "transcode_reason_digest",
+		Title:   "Transcode reason digest — " + local.Format("2006-01-02"),
+		Message: fmt.Sprintf("%d transcode reason spikes since the last digest: %s", len(entries), strings.Join(reasons, ", ")),
+		Fields:  fields,
+		Data:    entries,
+	})
+
+	for _, id := range ids {
+		if _, err := tm.db.Exec(`UPDATE transcode_alert_events SET delivered_at = ? WHERE id = ?`, now.Unix(), id); err != nil {
+			logging.Debug("transcode alerts: failed to mark digest entry", "id", id, "error", err)
+		}
+	}
+}
+
+// SplitTranscodeReasons splits the comma-separated transcode_reasons column into reasons.
+func SplitTranscodeReasons(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func hasTranscodeReason(reasons []string, want string) bool {
+	for _, r := range reasons {
+		if strings.EqualFold(r, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func nullableString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"emby-analytics/internal/emby"
@@ -20,6 +21,7 @@ type TranscodingMonitor struct {
 	quit     chan struct{}
 	wg       sync.WaitGroup
 	interval time.Duration
+	paused   atomic.Bool
 }
 
 // NewTranscodingMonitor creates a new transcoding monitor
@@ -50,6 +52,25 @@ func (tm *TranscodingMonitor) Stop() {
 	logging.Info("4K video transcoding monitor stopped")
 }
 
+// Pause skips transcoding checks until Resume.
+func (tm *TranscodingMonitor) Pause() {
+	if !tm.paused.Swap(true) {
+		logging.Info("4K video transcoding monitor paused")
+	}
+}
+
+// Resume restarts transcoding checks from the next tick.
+func (tm *TranscodingMonitor) Resume() {
+	if tm.paused.Swap(false) {
+		logging.Info("4K video transcoding monitor resumed")
+	}
+}
+
+// Paused reports whether the monitor is paused.
+func (tm *TranscodingMonitor) Paused() bool {
+	return tm.paused.Load()
+}
+
 // monitorLoop is the main monitoring loop
 func (tm *TranscodingMonitor) monitorLoop() {
 	defer tm.wg.Done()
@@ -62,7 +83,7 @@ func (tm *TranscodingMonitor) monitorLoop() {
 		case <-tm.quit:
 			return
 		case <-ticker.C:
-			if tm.isMonitoringEnabled() {
+			if !tm.Paused() && tm.isMonitoringEnabled() {
 				tm.checkAndStopTranscodingSessions()
 			}
 		}
@@ -0,0 +1,198 @@
+package monitors
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/notify"
+)
+
+// WatchForMonitor alerts admins when a session starts playing an item, series or
+// name pattern on the watch-for list.
+type WatchForMonitor struct {
+	db       *sql.DB
+	mgr      *media.MultiServerManager
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	interval time.Duration
+}
+
+type watchForRule struct {
+	id       int64
+	kind     string
+	value    string
+	serverID string
+	note     string
+	pattern  *regexp.Regexp
+}
+
+// NewWatchForMonitor creates a new watch-for monitor
+func NewWatchForMonitor(db *sql.DB, mgr *media.MultiServerManager, interval time.Duration) *WatchForMonitor {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	return &WatchForMonitor{
+		db:       db,
+		mgr:      mgr,
+		quit:     make(chan struct{}),
+		interval: interval,
+	}
+}
+
+// Start begins watching sessions
+func (wm *WatchForMonitor) Start() {
+	wm.wg.Add(1)
+	go wm.monitorLoop()
+	logging.Info("Watch-for monitor started", "interval", wm.interval)
+}
+
+// Stop gracefully stops the monitor
+func (wm *WatchForMonitor) Stop() {
+	close(wm.quit)
+	wm.wg.Wait()
+	logging.Info("Watch-for monitor stopped")
+}
+
+func (wm *WatchForMonitor) monitorLoop() {
+	defer wm.wg.Done()
+
+	ticker := time.NewTicker(wm.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-wm.quit:
+			return
+		case <-ticker.C:
+			wm.checkSessions()
+		}
+	}
+}
+
+func (wm *WatchForMonitor) loadRules() []watchForRule {
+	rows, err := wm.db.Query(`SELECT id, kind, value, COALESCE(server_id, ''), COALESCE(note, '') FROM watch_for_rules WHERE enabled = 1`)
+	if err != nil {
+		logging.Debug("watch-for: failed to load rules", "error", err)
+		return nil
+	}
+	defer rows.Close()
+	var out []watchForRule
+	for rows.Next() {
+		var r watchForRule
+		if err := rows.Scan(&r.id, &r.kind, &r.value, &r.serverID, &r.note); err != nil {
+			continue
+		}
+		if r.kind == "pattern" {
+			re, err := WatchForPattern(r.value)
+			if err != nil {
+				continue
+			}
+			r.pattern = re
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func (wm *WatchForMonitor) checkSessions() {
+	if wm.mgr == nil {
+		return
+	}
+	rules := wm.loadRules()
+	if len(rules) == 0 {
+		return
+	}
+	sessions, err := wm.mgr.GetAllSessions()
+	if err != nil {
+		logging.Debug("Failed to get active sessions for watch-for monitor", "error", err)
+		return
+	}
+	for _, s := range sessions {
+		if s.ItemID == "" {
+			continue
+		}
+		for _, r := range rules {
+			if r.matches(s) {
+				wm.recordHit(r, s)
+			}
+		}
+	}
+}
+
+func (r watchForRule) matches(s media.Session) bool {
+	if r.serverID != "" && r.serverID != s.ServerID {
+		return false
+	}
+	switch r.kind {
+	case "item":
+		return s.ItemID == r.value
+	case "series":
+		return s.SeriesID != "" && s.SeriesID == r.value
+	case "pattern":
+		return r.pattern != nil && r.pattern.MatchString(s.ItemName)
+	}
+	return false
+}
+
+// recordHit stores the match and notifies once per rule/session/item.
+func (wm *WatchForMonitor) recordHit(r watchForRule, s media.Session) {
+	res, err := wm.db.Exec(`
+		INSERT OR IGNORE INTO watch_for_hits
+			(rule_id, server_id, session_id, item_id, item_name, user_id, user_name, device_name, client_app, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.id, s.ServerID, s.SessionID, s.ItemID, s.ItemName, s.UserID, s.UserName, s.DeviceName, s.ClientApp, time.Now().Unix())
+	if err != nil {
+		logging.Debug("watch-for: failed to record hit", "rule_id", r.id, "error", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return // already alerted for this session
+	}
+
+	fields := map[string]string{
+		"item":      s.ItemName,
+		"item_id":   s.ItemID,
+		"user":      s.UserName,
+		"device":    s.DeviceName,
+		"client":    s.ClientApp,
+		"server_id": s.ServerID,
+	}
+	if r.note != "" {
+		fields["note"] = r.note
+	}
+	notify.Send(notify.Event{
+		Kind:    "watch_for",
+		Title:   "Watch-for match: " + s.ItemName,
+		Message: s.UserName + " started playing " + s.ItemName + " on " + s.DeviceName,
+		Fields:  fields,
+	})
+}
+
+// WatchForPattern compiles a watch-for name pattern. '*' and '?' are wildcards
+// matching the whole title; a pattern without wildcards matches any title
+// containing it. Matching is case-insensitive.
+func WatchForPattern(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimSpace(pattern)
+	if !strings.ContainsAny(pattern, "*?") {
+		return regexp.Compile("(?i)" + regexp.QuoteMeta(pattern))
+	}
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,261 @@
+package notify
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event kinds. Each can be switched off for every destination with its notify_event_<kind>
+// setting.
+const (
+	KindPlaybackStarted = "playback_started" // a session started
+	KindTranscode4K     = "transcode_4k"     // a session started transcoding 4K video
+	KindServerOffline   = "server_offline"   // a media server stopped answering
+	KindServerOnline    = "server_online"    // an offline media server answers again
+	KindNewUser         = "new_user"         // a user played something for the first time
+
+	KindServerAuthBroken   = "server_auth_broken"   // a media server keeps rejecting our API key
+	KindServerAuthRestored = "server_auth_restored" // a media server accepts the API key again
+)
+
+// EventKind describes a kind of event for the notification settings.
+type EventKind struct {
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	// Default is whether the kind is delivered while its setting is unset
+	Default bool `json:"default"`
+}
+
+// EventKinds lists every kind sent. Playback starts are off unless switched on, as they
+// come with every session.
+var EventKinds = []EventKind{
+	{KindPlaybackStarted, "A session started", false},
+	{KindTranscode4K, "A session is transcoding 4K video", true},
+	{KindServerOffline, "A media server stopped answering", true},
+	{KindServerOnline, "An offline media server is back", true},
+	{KindNewUser, "A user played something for the first time", true},
+	{KindServerAuthBroken, "A media server is rejecting its API key", true},
+	{KindServerAuthRestored, "A media server accepts its API key again", true},
+	{"session_ended", "A session finished (needs NOTIFY_SESSION_ENDED)", true},
+	{"watch_for", "A watch-for rule matched", true},
+	{"transcode_reason_spike", "A transcode reason spiked", true},
+	{"transcode_reason_digest", "The daily transcode reason digest", true},
+	{"goal_nudge", "A personal watch goal nudge", true},
+}
+
+// DefaultEnabled is whether a kind is delivered while its setting is unset; unknown kinds are.
+func DefaultEnabled(kind string) bool {
+	for _, k := range EventKinds {
+		if k.Kind == kind {
+			return k.Default
+		}
+	}
+	return true
+}
+
+// Channel delivers events to a chat or push service.
+type Channel interface {
+	Name() string
+	Deliver(e Event) error
+}
+
+var (
+	channelsMu  sync.RWMutex
+	channels    []Channel
+	eventFilter func(kind string) bool
+)
+
+// AddChannel adds a destination that receives every enabled event.
+func AddChannel(ch Channel) {
+	channelsMu.Lock()
+	channels = append(channels, ch)
+	channelsMu.Unlock()
+}
+
+// ChannelNames lists the configured channels.
+func ChannelNames() []string {
+	channelsMu.RLock()
+	defer channelsMu.RUnlock()
+	out := make([]string, 0, len(channels))
+	for _, ch := range channels {
+		out = append(out, ch.Name())
+	}
+	sort.Strings(out)
+	return out
+}
+
+// SetEventFilter decides which kinds are sent; without one only DefaultEnabled applies.
+func SetEventFilter(enabled func(kind string) bool) {
+	channelsMu.Lock()
+	eventFilter = enabled
+	channelsMu.Unlock()
+}
+
+// Enabled reports whether events of a kind are sent, so callers can skip building them.
+func Enabled(kind string) bool {
+	channelsMu.RLock()
+	f := eventFilter
+	channelsMu.RUnlock()
+	if f == nil {
+		return DefaultEnabled(kind)
+	}
+	return f(kind)
+}
+
+// SendTo delivers an event to one channel right away, ignoring the event filter.
+func SendTo(name string, e Event) error {
+	channelsMu.RLock()
+	var target Channel
+	for _, ch := range channels {
+		if ch.Name() == name {
+			target = ch
+		}
+	}
+	channelsMu.RUnlock()
+	if target == nil {
+		return fmt.Errorf("channel %q is not configured", name)
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	return target.Deliver(e)
+}
+
+func deliverChannels(e Event) {
+	channelsMu.RLock()
+	chs := append([]Channel(nil), channels...)
+	channelsMu.RUnlock()
+	for _, ch := range chs {
+		go func(ch Channel) {
+			if err := ch.Deliver(e); err != nil {
+				log.Warn("notification delivery failed", "channel", ch.Name(), "kind", e.Kind, "error", err)
+			}
+		}(ch)
+	}
+}
+
+// sortedFields returns an event's fields in key order, without the link.
+func sortedFields(e Event) [][2]string {
+	out := make([][2]string, 0, len(e.Fields))
+	for k, v := range e.Fields {
+		if k != "link" && v != "" {
+			out = append(out, [2]string{k, v})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i][0] < out[j][0] })
+	return out
+}
+
+// DiscordChannel posts events as embeds to a Discord channel webhook.
+type DiscordChannel struct {
+	WebhookURL string
+}
+
+func (DiscordChannel) Name() string { return "discord" }
+
+func (d DiscordChannel) Deliver(e Event) error {
+	var fields []map[string]any
+	for _, f := range sortedFields(e) {
+		fields = append(fields, map[string]any{"name": f[0], "value": f[1], "inline": true})
+	}
+	embed := map[string]any{
+		"title":       e.Title,
+		"description": e.Message,
+		"color":       0x52B54B,
+		"fields":      fields,
+		"timestamp":   e.Time.Format("2006-01-02T15:04:05Z07:00"),
+		"footer":      map[string]any{"text": "Emby Analytics · " + e.Kind},
+	}
+	if link := e.Fields["link"]; strings.HasPrefix(link, "http") {
+		embed["url"] = link
+	}
+	return postJSON(d.WebhookURL, map[string]any{"embeds": []map[string]any{embed}})
+}
+
+// TelegramChannel sends events as HTML messages through a Telegram bot.
+type TelegramChannel struct {
+	BotToken string
+	ChatID   string
+}
+
+func (TelegramChannel) Name() string { return "telegram" }
+
+func (t TelegramChannel) Deliver(e Event) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<b>%s</b>\n%s", html.EscapeString(e.Title), html.EscapeString(e.Message))
+	for _, f := range sortedFields(e) {
+		fmt.Fprintf(&b, "\n%s: %s", html.EscapeString(f[0]), html.EscapeString(f[1]))
+	}
+	if link := e.Fields["link"]; strings.HasPrefix(link, "http") {
+		fmt.Fprintf(&b, "\n<a href=\"%s\">Open</a>", html.EscapeString(link))
+	}
+	return postJSON("https://api.telegram.org/bot"+url.PathEscape(t.BotToken)+"/sendMessage", map[string]any{
+		"chat_id":    t.ChatID,
+		"text":       b.String(),
+		"parse_mode": "HTML",
+	})
+}
+
+// GotifyChannel pushes events to a Gotify server with an application token.
+type GotifyChannel struct {
+	URL   string // e.g. https://gotify.example.com
+	Token string
+}
+
+func (GotifyChannel) Name() string { return "gotify" }
+
+func (g GotifyChannel) Deliver(e Event) error {
+	msg := e.Message
+	for _, f := range sortedFields(e) {
+		msg += "\n" + f[0] + ": " + f[1]
+	}
+	payload := map[string]any{"title": e.Title, "message": msg, "priority": gotifyPriority(e.Kind)}
+	if link := e.Fields["link"]; strings.HasPrefix(link, "http") {
+		payload["extras"] = map[string]any{"client::notification": map[string]any{"click": map[string]string{"url": link}}}
+	}
+	return postJSON(strings.TrimRight(g.URL, "/")+"/message?token="+url.QueryEscape(g.Token), payload)
+}
+
+// gotifyPriority raises server outages and rejected credentials above Gotify's default of 5.
+func gotifyPriority(kind string) int {
+	if kind == KindServerOffline || kind == KindServerAuthBroken {
+		return 8
+	}
+	return 5
+}
+
+// PushoverChannel pushes events through a Pushover application to a user or group key.
+type PushoverChannel struct {
+	AppToken string
+	UserKey  string
+}
+
+func (PushoverChannel) Name() string { return "pushover" }
+
+func (p PushoverChannel) Deliver(e Event) error {
+	msg := e.Message
+	for _, f := range sortedFields(e) {
+		msg += "\n" + f[0] + ": " + f[1]
+	}
+	form := url.Values{"token": {p.AppToken}, "user": {p.UserKey}, "title": {e.Title}, "message": {msg}}
+	if link := e.Fields["link"]; strings.HasPrefix(link, "http") {
+		form.Set("url", link)
+	}
+	if e.Kind == KindServerOffline || e.Kind == KindServerAuthBroken {
+		form.Set("priority", "1")
+	}
+	resp, err := httpClient.PostForm("https://api.pushover.net/1/messages.json", form)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("http %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"text/template"
+)
+
+// PersonalDigest is a user's weekly activity digest sent by email.
+type PersonalDigest struct {
+	Username      string
+	WeekStart     string // local date, YYYY-MM-DD
+	WeekEnd       string // last day of the week, inclusive
+	Hours         float64
+	Sessions      int
+	TopItems      []DigestItem // most watched titles of the week
+	FinishedShows []string     // series the user has now watched every episode of
+	Suggestions   []DigestItem // trending titles the user hasn't watched
+}
+
+// DigestItem is a title listed in a digest.
+type DigestItem struct {
+	Name  string
+	Type  string
+	Hours float64
+}
+
+const digestHTML = `<!DOCTYPE html>
+<html><body style="margin:0;padding:24px;background:#111;color:#eee;font-family:Arial,Helvetica,sans-serif">
+<div style="max-width:560px;margin:0 auto">
+<h2 style="color:#d4af37;margin:0 0 4px">Your week in review</h2>
+<p style="color:#999;margin:0 0 20px">{{.WeekStart}} – {{.WeekEnd}}</p>
+<p>Hi {{.Username}}, you watched <b>{{printf "%.1f" .Hours}} hours</b> across <b>{{.Sessions}}</b> {{if eq .Sessions 1}}session{{else}}sessions{{end}} this week.</p>
+{{if .TopItems}}<h3 style="color:#d4af37">Most watched</h3>
+<ul>{{range .TopItems}}<li>{{.Name}} <span style="color:#999">({{printf "%.1f" .Hours}}h)</span></li>{{end}}</ul>{{end}}
+{{if .FinishedShows}}<h3 style="color:#d4af37">Finished</h3>
+<ul>{{range .FinishedShows}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .Suggestions}}<h3 style="color:#d4af37">Trending on the server</h3>
+<ul>{{range .Suggestions}}<li>{{.Name}}{{if .Type}} <span style="color:#999">({{.Type}})</span>{{end}}</li>{{end}}</ul>{{end}}
+<p style="color:#777;font-size:12px;margin-top:28px">You receive this weekly digest from Emby Analytics. Turn it off under your account's subscriptions.</p>
+</div></body></html>`
+
+const digestText = `Your week in review ({{.WeekStart}} – {{.WeekEnd}})
+
+Hi {{.Username}}, you watched {{printf "%.1f" .Hours}} hours across {{.Sessions}} {{if eq .Sessions 1}}session{{else}}sessions{{end}} this week.
+{{if .TopItems}}
+Most watched:
+{{range .TopItems}}- {{.Name}} ({{printf "%.1f" .Hours}}h)
+{{end}}{{end}}{{if .FinishedShows}}
+Finished:
+{{range .FinishedShows}}- {{.}}
+{{end}}{{end}}{{if .Suggestions}}
+Trending on the server:
+{{range .Suggestions}}- {{.Name}}{{if .Type}} ({{.Type}}){{end}}
+{{end}}{{end}}
+You receive this weekly digest from Emby Analytics. Turn it off under your account's subscriptions.
+`
+
+var (
+	digestHTMLTmpl = htmltemplate.Must(htmltemplate.New("digest.html").Parse(digestHTML))
+	digestTextTmpl = template.Must(template.New("digest.txt").Parse(digestText))
+)
+
+// RenderPersonalDigest renders the digest as an email to the given address.
+func RenderPersonalDigest(to string, d PersonalDigest) (Email, error) {
+	var html, text bytes.Buffer
+	if err := digestHTMLTmpl.Execute(&html, d); err != nil {
+		return Email{}, err
+	}
+	if err := digestTextTmpl.Execute(&text, d); err != nil {
+		return Email{}, err
+	}
+	return Email{
+		To:      to,
+		Subject: "Your week in review — " + d.WeekStart,
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
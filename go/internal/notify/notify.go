@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"emby-analytics/internal/logging"
+)
+
+// Event is an admin-facing notification (e.g. a watch-for match).
+type Event struct {
+	Kind    string            `json:"kind"`
+	Title   string            `json:"title"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	// Data is the complete machine-readable record behind the event (e.g. a session summary)
+	Data any       `json:"data,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+var (
+	log        = logging.Module("notify")
+	webhookURL atomic.Pointer[string]
+	publicURL  atomic.Pointer[string]
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// Configure sets the URL that receives every event as a JSON POST; empty disables delivery.
+func Configure(url string) {
+	webhookURL.Store(&url)
+}
+
+// WebhookConfigured reports whether events are posted to NOTIFY_WEBHOOK_URL.
+func WebhookConfigured() bool {
+	url := webhookURL.Load()
+	return url != nil && *url != ""
+}
+
+// SetPublicURL sets the address the web UI is reached at (e.g. https://stats.example.com),
+// used to make deep links absolute.
+func SetPublicURL(url string) {
+	url = strings.TrimRight(strings.TrimSpace(url), "/")
+	publicURL.Store(&url)
+}
+
+// Link returns the deep link for a public id; without a public URL it is a path.
+func Link(publicID string) string {
+	base := ""
+	if u := publicURL.Load(); u != nil {
+		base = *u
+	}
+	return base + "/resolve/" + publicID
+}
+
+// Send logs the event and delivers it to the configured webhook and channels in the
+// background, unless its kind is switched off.
+func Send(e Event) {
+	if !Enabled(e.Kind) {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	args := []any{"kind", e.Kind, "title", e.Title}
+	for k, v := range e.Fields {
+		args = append(args, k, v)
+	}
+	log.Info(e.Message, args...)
+
+	deliverChannels(e)
+	url := webhookURL.Load()
+	if url == nil || *url == "" {
+		return
+	}
+	go deliver(*url, e)
+}
+
+func deliver(url string, e Event) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	resp, err := httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Warn("notification delivery failed", "kind", e.Kind, "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Warn("notification delivery failed", "kind", e.Kind, "status", resp.StatusCode)
+	}
+}
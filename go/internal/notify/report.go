@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"text/template"
+)
+
+// WeeklyReport is the server-wide weekly summary emailed to admins.
+type WeeklyReport struct {
+	WeekStart string // local date, YYYY-MM-DD
+	WeekEnd   string // last day of the week, inclusive
+	Timezone  string
+	Hours     float64
+	Sessions  int
+	// Transcodes counts sessions that transcoded video or audio; TranscodePercent is their
+	// share of Sessions
+	Transcodes       int
+	TranscodePercent float64
+	TopUsers         []DigestItem
+	TopItems         []DigestItem
+}
+
+const reportHTML = `<!DOCTYPE html>
+<html><body style="margin:0;padding:24px;background:#111;color:#eee;font-family:Arial,Helvetica,sans-serif">
+<div style="max-width:560px;margin:0 auto">
+<h2 style="color:#d4af37;margin:0 0 4px">Weekly server report</h2>
+<p style="color:#999;margin:0 0 20px">{{.WeekStart}} – {{.WeekEnd}} ({{.Timezone}})</p>
+<table style="width:100%;border-collapse:collapse;margin-bottom:8px">
+<tr><td style="padding:4px 0">Hours watched</td><td style="text-align:right"><b>{{printf "%.1f" .Hours}}</b></td></tr>
+<tr><td style="padding:4px 0">Sessions</td><td style="text-align:right"><b>{{.Sessions}}</b></td></tr>
+<tr><td style="padding:4px 0">Transcoded</td><td style="text-align:right"><b>{{.Transcodes}}</b> <span style="color:#999">({{printf "%.1f" .TranscodePercent}}%)</span></td></tr>
+</table>
+{{if .TopUsers}}<h3 style="color:#d4af37">Top users</h3>
+<ol>{{range .TopUsers}}<li>{{.Name}} <span style="color:#999">({{printf "%.1f" .Hours}}h)</span></li>{{end}}</ol>{{end}}
+{{if .TopItems}}<h3 style="color:#d4af37">Most watched</h3>
+<ol>{{range .TopItems}}<li>{{.Name}}{{if .Type}} <span style="color:#999">· {{.Type}}</span>{{end}} <span style="color:#999">({{printf "%.1f" .Hours}}h)</span></li>{{end}}</ol>{{end}}
+<p style="color:#777;font-size:12px;margin-top:28px">Sent by Emby Analytics to the addresses in REPORT_EMAIL_TO.</p>
+</div></body></html>`
+
+const reportText = `Weekly server report ({{.WeekStart}} – {{.WeekEnd}}, {{.Timezone}})
+
+Hours watched: {{printf "%.1f" .Hours}}
+Sessions: {{.Sessions}}
+Transcoded: {{.Transcodes}} ({{printf "%.1f" .TranscodePercent}}%)
+{{if .TopUsers}}
+Top users:
+{{range $i, $u := .TopUsers}}{{inc $i}}. {{$u.Name}} ({{printf "%.1f" $u.Hours}}h)
+{{end}}{{end}}{{if .TopItems}}
+Most watched:
+{{range $i, $it := .TopItems}}{{inc $i}}. {{$it.Name}}{{if $it.Type}} · {{$it.Type}}{{end}} ({{printf "%.1f" $it.Hours}}h)
+{{end}}{{end}}
+Sent by Emby Analytics to the addresses in REPORT_EMAIL_TO.
+`
+
+var (
+	reportHTMLTmpl = htmltemplate.Must(htmltemplate.New("report.html").Parse(reportHTML))
+	reportTextTmpl = template.Must(template.New("report.txt").
+			Funcs(template.FuncMap{"inc": func(i int) int { return i + 1 }}).Parse(reportText))
+)
+
+// RenderWeeklyReport renders the report as an email to the given address.
+func RenderWeeklyReport(to string, r WeeklyReport) (Email, error) {
+	var html, text bytes.Buffer
+	if err := reportHTMLTmpl.Execute(&html, r); err != nil {
+		return Email{}, err
+	}
+	if err := reportTextTmpl.Execute(&text, r); err != nil {
+		return Email{}, err
+	}
+	return Email{
+		To:      to,
+		Subject: "Weekly server report — " + r.WeekStart,
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Summary is the daily activity digest posted to chat destinations.
+type Summary struct {
+	Date         string // local date the summary covers, YYYY-MM-DD
+	Timezone     string
+	Streams      int
+	Hours        float64
+	NewItems     int
+	TopUser      string
+	TopUserHours float64
+}
+
+func (s Summary) topUser() string {
+	if s.TopUser == "" {
+		return "—"
+	}
+	return fmt.Sprintf("%s (%.1fh)", s.TopUser, s.TopUserHours)
+}
+
+// Event renders the summary as a daily_summary event for a Discord or Telegram channel.
+// Summaries are sent to their destinations on their own schedule, not through Send.
+func (s Summary) Event() Event {
+	return Event{
+		Kind:    "daily_summary",
+		Title:   "Daily summary — " + s.Date,
+		Message: "Activity on " + s.Date + " (" + s.Timezone + ")",
+		Fields: map[string]string{
+			"streams":       fmt.Sprintf("%d", s.Streams),
+			"hours_watched": fmt.Sprintf("%.1f", s.Hours),
+			"new_items":     fmt.Sprintf("%d", s.NewItems),
+			"top_user":      s.topUser(),
+		},
+		Time: time.Now(),
+	}
+}
+
+func postJSON(target string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("http %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// Package overseerr reads media requests from Overseerr or Jellyseerr, which share the
+// same API.
+package overseerr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/httpx"
+)
+
+// Request statuses
+const (
+	RequestPending  = 1
+	RequestApproved = 2
+	RequestDeclined = 3
+)
+
+// Media statuses
+const (
+	MediaUnknown            = 1
+	MediaPending            = 2
+	MediaProcessing         = 3
+	MediaPartiallyAvailable = 4
+	MediaAvailable          = 5
+)
+
+// pageSize is how many requests are fetched per call.
+const pageSize = 100
+
+// Client talks to an Overseerr/Jellyseerr instance.
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *httpx.Client
+}
+
+// New creates a client for the instance at baseURL using an API key from its settings
+func New(baseURL, apiKey string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    httpx.New(),
+	}
+}
+
+// Request is a media request as returned by the API.
+type Request struct {
+	ID        int    `json:"id"`
+	Status    int    `json:"status"`
+	Type      string `json:"type"` // movie|tv
+	Is4K      bool   `json:"is4k"`
+	CreatedAt Time   `json:"createdAt"`
+	Media     struct {
+		TMDBID int   `json:"tmdbId"`
+		TVDBID *int  `json:"tvdbId"`
+		Status int   `json:"status"`
+		Added  *Time `json:"mediaAddedAt"`
+		// Item ids on the media servers once the title is available
+		JellyfinMediaID string `json:"jellyfinMediaId"`
+		RatingKey       string `json:"ratingKey"`
+	} `json:"media"`
+	RequestedBy struct {
+		DisplayName      string `json:"displayName"`
+		Username         string `json:"username"`
+		JellyfinUsername string `json:"jellyfinUsername"`
+		PlexUsername     string `json:"plexUsername"`
+	} `json:"requestedBy"`
+}
+
+// MediaUsername is the requester's account name on the media server, if known.
+func (r Request) MediaUsername() string {
+	for _, name := range []string{r.RequestedBy.JellyfinUsername, r.RequestedBy.PlexUsername, r.RequestedBy.Username} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// Time accepts the API's timestamps, which are sometimes null or empty.
+type Time struct{ time.Time }
+
+// UnmarshalJSON parses an RFC 3339 timestamp; an empty value leaves the time zero.
+func (t *Time) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+type requestPage struct {
+	PageInfo struct {
+		Pages   int `json:"pages"`
+		Results int `json:"results"`
+	} `json:"pageInfo"`
+	Results []Request `json:"results"`
+}
+
+// Requests returns every request, newest first.
+func (c *Client) Requests() ([]Request, error) {
+	var out []Request
+	for skip := 0; ; skip += pageSize {
+		q := url.Values{}
+		q.Set("take", strconv.Itoa(pageSize))
+		q.Set("skip", strconv.Itoa(skip))
+		q.Set("filter", "all")
+		q.Set("sort", "added")
+		var page requestPage
+		if err := c.get("/api/v1/request?"+q.Encode(), &page); err != nil {
+			return nil, err
+		}
+		out = append(out, page.Results...)
+		if len(page.Results) < pageSize || len(out) >= page.PageInfo.Results {
+			return out, nil
+		}
+	}
+}
+
+func (c *Client) get(path string, dst any) error {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Api-Key", c.apiKey)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		snippet := string(body)
+		if len(snippet) > 240 {
+			snippet = snippet[:240] + "…"
+		}
+		return fmt.Errorf("http %d from %s: %s", resp.StatusCode, path, snippet)
+	}
+	if err := json.Unmarshal(body, dst); err != nil {
+		return fmt.Errorf("decode %s: %w", path, err)
+	}
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"emby-analytics/internal/httpx"
 	"emby-analytics/internal/media"
 )
 
@@ -22,7 +23,7 @@ type Client struct {
 	baseURL     string
 	token       string
 	externalURL string
-	http        *http.Client
+	http        *httpx.Client
 	cache       sync.Map
 	cacheTTL    time.Duration
 }
@@ -36,14 +37,7 @@ func New(config media.ServerConfig) *Client {
 		token:       config.APIKey,
 		externalURL: config.ExternalURL,
 		cacheTTL:    time.Hour,
-		http: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:       10,
-				IdleConnTimeout:    30 * time.Second,
-				DisableCompression: false,
-			},
-		},
+		http:        httpx.New(),
 	}
 }
 
@@ -59,8 +53,9 @@ type plexMediaContainer struct {
 }
 
 type plexLibrarySection struct {
-	Key  string `xml:"key,attr"`
-	Type string `xml:"type,attr"`
+	Key        string `xml:"key,attr"`
+	Type       string `xml:"type,attr"`
+	Refreshing string `xml:"refreshing,attr"`
 }
 
 type plexSession struct {
@@ -78,6 +73,18 @@ type plexSession struct {
 	ViewOffset       int64    `xml:"viewOffset,attr"` // milliseconds
 	ParentIndex      int      `xml:"parentIndex,attr"`
 	Index            int      `xml:"index,attr"`
+	Year             int      `xml:"year,attr"`
+	ContentRating    string   `xml:"contentRating,attr"` // e.g. "PG-13", "TV-MA"
+
+	// Labels and genres are only present on library listings, not on live sessions
+	Label []struct {
+		Tag string `xml:"tag,attr"`
+	} `xml:"Label"`
+	Genre []struct {
+		Tag string `xml:"tag,attr"`
+	} `xml:"Genre"`
+	// External ids, only present when requested with includeGuids=1
+	Guids []plexGuid `xml:"Guid"`
 
 	User struct {
 		ID    string `xml:"id,attr"`
@@ -221,6 +228,29 @@ type plexMediaItem struct {
 	Duration         int64    `xml:"duration,attr"`
 	AddedAt          int64    `xml:"addedAt,attr"`
 	UpdatedAt        int64    `xml:"updatedAt,attr"`
+	// External ids, only present when requested with includeGuids=1
+	Guids []plexGuid `xml:"Guid"`
+}
+
+// plexGuid is an external id of an item, e.g. "imdb://tt0111161".
+type plexGuid struct {
+	ID string `xml:"id,attr"`
+}
+
+// plexProviderIDs maps an item's external ids by lower-cased provider, or nil when it has none.
+func plexProviderIDs(guids []plexGuid) map[string]string {
+	var out map[string]string
+	for _, g := range guids {
+		provider, id, ok := strings.Cut(g.ID, "://")
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[strings.ToLower(provider)] = id
+	}
+	return out
 }
 
 // Interface implementation
@@ -312,8 +342,8 @@ func readXML(resp *http.Response, dst interface{}) error {
 	return nil
 }
 
-// GetActiveSessions returns active Plex sessions
-func (c *Client) GetActiveSessions() ([]media.Session, error) {
+// fetchStatusSessions returns the raw entries from /status/sessions
+func (c *Client) fetchStatusSessions() ([]plexSession, error) {
 	resp, err := c.doRequest("/status/sessions")
 	if err != nil {
 		return nil, err
@@ -324,21 +354,78 @@ func (c *Client) GetActiveSessions() ([]media.Session, error) {
 		return nil, err
 	}
 
-	sessions := make([]media.Session, 0, container.Size)
-
 	if len(container.Videos) > 0 {
-		for _, plexSess := range container.Videos {
-			sessions = append(sessions, c.convertSession(plexSess))
-		}
-	} else if len(container.Metadata) > 0 {
-		for _, plexSess := range container.Metadata {
-			sessions = append(sessions, c.convertSession(plexSess))
+		return container.Videos, nil
+	}
+	return container.Metadata, nil
+}
+
+// isSyncSession reports whether a status entry is an offline download / sync transcode rather than playback
+func isSyncSession(plexSess plexSession) bool {
+	return plexSess.TranscodeSession != nil && strings.EqualFold(plexSess.TranscodeSession.Context, "sync")
+}
+
+// GetActiveSessions returns active Plex sessions
+func (c *Client) GetActiveSessions() ([]media.Session, error) {
+	entries, err := c.fetchStatusSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]media.Session, 0, len(entries))
+	for _, plexSess := range entries {
+		// Downloads are reported via GetActiveDownloads and must not count as streams
+		if isSyncSession(plexSess) {
+			continue
 		}
+		sessions = append(sessions, c.convertSession(plexSess))
 	}
 
 	return sessions, nil
 }
 
+// GetActiveDownloads returns in-progress offline download / sync transcodes
+func (c *Client) GetActiveDownloads() ([]media.DownloadJob, error) {
+	entries, err := c.fetchStatusSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]media.DownloadJob, 0)
+	for _, plexSess := range entries {
+		if !isSyncSession(plexSess) {
+			continue
+		}
+		ts := plexSess.TranscodeSession
+		jobID := ts.Key
+		if jobID == "" {
+			jobID = plexSess.SessionKey
+		}
+		status := "Transferring"
+		if ts.Complete {
+			status = "Completed"
+		} else if ts.Error {
+			status = "Failed"
+		}
+		jobs = append(jobs, media.DownloadJob{
+			ServerID:   c.serverID,
+			ServerType: media.ServerTypePlex,
+			JobID:      extractPlexID(jobID),
+			UserID:     plexSess.User.ID,
+			UserName:   plexSess.User.Title,
+			ItemID:     plexSess.RatingKey,
+			ItemName:   plexSess.Title,
+			ItemType:   plexSess.Type,
+			DeviceName: plexSess.Player.Title,
+			Status:     status,
+			Progress:   ts.Progress,
+			SizeBytes:  ts.Size,
+		})
+	}
+
+	return jobs, nil
+}
+
 // convertSession converts Plex session to normalized Session
 func (c *Client) convertSession(plexSess plexSession) media.Session {
 	session := media.Session{
@@ -354,6 +441,8 @@ func (c *Client) convertSession(plexSess plexSession) media.Session {
 		PositionMs:    plexSess.ViewOffset,
 		DurationMs:    plexSess.Duration,
 		ClientApp:     plexSess.Player.Product,
+		ClientVersion: plexSess.Player.Version,
+		Platform:      media.NormalizePlatform(plexSess.Player.Platform, plexSess.Player.Product, plexSess.Player.Device),
 		DeviceName:    plexSess.Player.Title,
 		RemoteAddress: plexSess.Player.Address,
 		IsPaused:      plexSess.Player.State == "paused",
@@ -428,9 +517,11 @@ func (c *Client) convertSession(plexSess plexSession) media.Session {
 					case 2: // Audio
 						session.AudioLanguage = stream.Language
 						session.AudioDefault = true
+						session.AudioTrack = stream.ID
 					case 3: // Subtitle
 						session.SubtitleLanguage = stream.Language
 						session.SubtitleCodec = strings.ToUpper(stream.Codec)
+						session.SubtitleTrack = stream.ID
 					}
 				}
 				if stream.StreamType == 3 { // Count subtitles
@@ -439,6 +530,10 @@ func (c *Client) convertSession(plexSess plexSession) media.Session {
 			}
 		}
 	}
+	// The part lists every stream, so no selected subtitle stream means subtitles are off
+	if session.AudioTrack != "" && session.SubtitleTrack == "" {
+		session.SubtitleTrack = media.SubtitlesOff
+	}
 
 	// Handle transcode session
 	if plexSess.TranscodeSession != nil {
@@ -450,6 +545,8 @@ func (c *Client) convertSession(plexSess plexSession) media.Session {
 		session.TranscodeProgress = ts.Progress
 		session.TranscodeWidth = ts.Width
 		session.TranscodeHeight = ts.Height
+		session.TranscodeSpeed = ts.Speed
+		session.TranscodeHardware = ts.TranscodeHwDecoding != "" || ts.TranscodeHwEncoding != ""
 
 		// Determine track methods
 		if ts.VideoDecision == "transcode" {
@@ -568,7 +665,7 @@ func (c *Client) ItemsByIDs(ids []string) ([]media.MediaItem, error) {
 
 	// Plex doesn't support bulk requests, fetch individually
 	for _, id := range ids {
-		resp, err := c.doRequest(fmt.Sprintf("/library/metadata/%s", id))
+		resp, err := c.doRequest(fmt.Sprintf("/library/metadata/%s?includeGuids=1", id))
 		if err != nil {
 			continue // Skip failed items
 		}
@@ -588,18 +685,19 @@ func (c *Client) ItemsByIDs(ids []string) ([]media.MediaItem, error) {
 				ServerID:   c.serverID,
 				ServerType: media.ServerTypePlex,
 				Name:       plexItem.Title,
-				Type:       plexItem.Type,
+				Type:       libraryItemType(plexItem.Type),
 				RuntimeMs:  &plexItem.Duration,
 			}
 
 			if plexItem.Year > 0 {
 				item.ProductionYear = &plexItem.Year
 			}
+			item.ProviderIDs = plexProviderIDs(plexItem.Guids)
 
 			// Episode-specific fields
 			if plexItem.Type == "episode" {
 				item.SeriesName = plexItem.GrandparentTitle
-				item.SeriesID = plexItem.GrandparentKey
+				item.SeriesID = extractPlexID(plexItem.GrandparentKey)
 				if plexItem.ParentIndex > 0 {
 					item.ParentIndexNumber = &plexItem.ParentIndex
 				}
@@ -635,13 +733,13 @@ func (c *Client) FetchLibraryItems() ([]media.MediaItem, error) {
 		case "movie":
 			videos, err = c.fetchSectionEntries(
 				fmt.Sprintf("/library/sections/%s/all", section.Key),
-				"type=1",
+				"type=1&includeGuids=1",
 				pageSize,
 			)
 		case "show":
 			videos, err = c.fetchSectionEntries(
 				fmt.Sprintf("/library/sections/%s/all", section.Key),
-				"type=4",
+				"type=4&includeGuids=1",
 				pageSize,
 			)
 			if err == nil && len(videos) == 0 {
@@ -660,12 +758,27 @@ func (c *Client) FetchLibraryItems() ([]media.MediaItem, error) {
 				continue
 			}
 			item := media.MediaItem{
-				ID:         video.RatingKey,
-				ServerID:   c.serverID,
-				ServerType: media.ServerTypePlex,
-				Name:       video.Title,
-				Type:       video.Type,
-				Genres:     nil,
+				ID:             video.RatingKey,
+				ServerID:       c.serverID,
+				ServerType:     media.ServerTypePlex,
+				Name:           video.Title,
+				Type:           libraryItemType(video.Type),
+				OfficialRating: video.ContentRating,
+			}
+			for _, label := range video.Label {
+				if tag := strings.TrimSpace(label.Tag); tag != "" {
+					item.Tags = append(item.Tags, tag)
+				}
+			}
+			for _, genre := range video.Genre {
+				if g := strings.TrimSpace(genre.Tag); g != "" {
+					item.Genres = append(item.Genres, g)
+				}
+			}
+			item.ProviderIDs = plexProviderIDs(video.Guids)
+			if video.Year > 0 {
+				year := video.Year
+				item.ProductionYear = &year
 			}
 			if video.Duration > 0 {
 				runtime := video.Duration
@@ -673,8 +786,32 @@ func (c *Client) FetchLibraryItems() ([]media.MediaItem, error) {
 			}
 			if len(video.Media) > 0 {
 				mediaEntry := video.Media[0]
-				if mediaEntry.VideoCodec != "" {
-					item.Codec = strings.ToUpper(mediaEntry.VideoCodec)
+				codec, width, height := mediaEntry.VideoCodec, mediaEntry.Width, mediaEntry.Height
+				var size int64
+				for _, part := range mediaEntry.Part {
+					// Multi-part movies (cd1/cd2) add up to the item's size
+					size += part.Size
+					if item.FilePath == "" && part.File != "" {
+						item.FilePath = part.File
+					}
+					// Stream details fill in what the Media element left out
+					for _, stream := range part.Stream {
+						if stream.StreamType != 1 {
+							continue
+						}
+						if codec == "" {
+							codec = stream.Codec
+						}
+						if width == 0 {
+							width = stream.Width
+						}
+						if height == 0 {
+							height = stream.Height
+						}
+					}
+				}
+				if codec != "" {
+					item.Codec = strings.ToUpper(codec)
 				}
 				if mediaEntry.Container != "" {
 					item.Container = mediaEntry.Container
@@ -683,31 +820,22 @@ func (c *Client) FetchLibraryItems() ([]media.MediaItem, error) {
 					bitrate := mediaEntry.Bitrate * 1000 // Plex stores kbps
 					item.BitrateBps = &bitrate
 				}
-				if mediaEntry.Width > 0 {
-					w := mediaEntry.Width
-					item.Width = &w
+				if width > 0 {
+					item.Width = &width
 				}
-				if mediaEntry.Height > 0 {
-					h := mediaEntry.Height
-					item.Height = &h
+				if height > 0 {
+					item.Height = &height
 				}
-				if len(mediaEntry.Part) > 0 {
-					part := mediaEntry.Part[0]
-					if part.Size > 0 {
-						size := part.Size
-						item.FileSizeBytes = &size
-					}
-					if part.File != "" {
-						item.FilePath = part.File
-					}
+				if size > 0 {
+					item.FileSizeBytes = &size
 				}
 			}
 
 			if strings.EqualFold(video.Type, "episode") {
-				item.SeriesID = video.GrandparentKey
+				item.SeriesID = extractPlexID(video.GrandparentKey)
 				item.SeriesName = video.GrandparentTitle
 				if item.SeriesID == "" {
-					item.SeriesID = video.ParentKey
+					item.SeriesID = extractPlexID(video.ParentKey)
 				}
 				if item.SeriesName == "" {
 					item.SeriesName = video.ParentTitle
@@ -729,6 +857,26 @@ func (c *Client) FetchLibraryItems() ([]media.MediaItem, error) {
 	return items, nil
 }
 
+// libraryItemType maps Plex metadata types ("movie", "episode", ...) to the item type
+// names library_item uses for every server, so Plex items show up in library stats.
+func libraryItemType(t string) string {
+	switch strings.ToLower(strings.TrimSpace(t)) {
+	case "movie":
+		return "Movie"
+	case "episode":
+		return "Episode"
+	case "show":
+		return "Series"
+	case "season":
+		return "Season"
+	case "track":
+		return "Audio"
+	case "clip":
+		return "Video"
+	}
+	return t
+}
+
 func (c *Client) fetchSectionEntries(basePath, querySuffix string, pageSize int) ([]plexSession, error) {
 	entries := make([]plexSession, 0)
 	start := 0
@@ -786,7 +934,7 @@ func (c *Client) fetchShowEpisodesFallback(sectionKey string, pageSize int) ([]p
 
 		showEpisodes, err := c.fetchSectionEntries(
 			fmt.Sprintf("/library/metadata/%s/allLeaves", ratingKey),
-			"includeAllLeaves=1",
+			"includeAllLeaves=1&includeGuids=1",
 			pageSize,
 		)
 		if err != nil {
@@ -809,6 +957,22 @@ func (c *Client) fetchShowEpisodesFallback(sectionKey string, pageSize int) ([]p
 }
 
 func (c *Client) fetchLibrarySections() ([]plexLibrarySection, error) {
+	all, err := c.fetchAllLibrarySections()
+	if err != nil {
+		return nil, err
+	}
+	sections := make([]plexLibrarySection, 0, len(all))
+	for _, dir := range all {
+		typeName := strings.ToLower(dir.Type)
+		if typeName == "movie" || typeName == "show" {
+			sections = append(sections, dir)
+		}
+	}
+	return sections, nil
+}
+
+// fetchAllLibrarySections returns every library section regardless of type
+func (c *Client) fetchAllLibrarySections() ([]plexLibrarySection, error) {
 	resp, err := c.doRequest("/library/sections")
 	if err != nil {
 		return nil, err
@@ -819,21 +983,131 @@ func (c *Client) fetchLibrarySections() ([]plexLibrarySection, error) {
 	if err := readXML(resp, &container); err != nil {
 		return nil, err
 	}
-	sections := make([]plexLibrarySection, 0, len(container.Directories))
-	for _, dir := range container.Directories {
-		typeName := strings.ToLower(dir.Type)
-		if typeName == "movie" || typeName == "show" {
-			sections = append(sections, dir)
+	return container.Directories, nil
+}
+
+// RefreshLibrary asks Plex to scan every library section
+func (c *Client) RefreshLibrary() error {
+	sections, err := c.fetchAllLibrarySections()
+	if err != nil {
+		return err
+	}
+	for _, section := range sections {
+		resp, err := c.doRequest(fmt.Sprintf("/library/sections/%s/refresh", section.Key))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("refresh section %s: http %d", section.Key, resp.StatusCode)
 		}
 	}
-	return sections, nil
+	return nil
+}
+
+// LibraryScanRunning reports whether any library section is currently refreshing
+func (c *Client) LibraryScanRunning() (bool, error) {
+	sections, err := c.fetchAllLibrarySections()
+	if err != nil {
+		return false, err
+	}
+	for _, section := range sections {
+		if section.Refreshing == "1" {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
-// GetUserPlayHistory returns user play history
+const (
+	historyPageSize = 200
+	// historyMaxEntries bounds a full-history fetch for a single account
+	historyMaxEntries = 50000
+)
+
+// plexHistoryContainer is the /status/sessions/history/all response; music
+// plays come back as Track elements, everything else as Video.
+type plexHistoryContainer struct {
+	XMLName xml.Name           `xml:"MediaContainer"`
+	Size    int                `xml:"size,attr"`
+	Videos  []plexHistoryEntry `xml:"Video"`
+	Tracks  []plexHistoryEntry `xml:"Track"`
+}
+
+type plexHistoryEntry struct {
+	HistoryKey string `xml:"historyKey,attr"`
+	RatingKey  string `xml:"ratingKey,attr"`
+	Key        string `xml:"key,attr"`
+	Title      string `xml:"title,attr"`
+	Type       string `xml:"type,attr"`
+	ViewedAt   int64  `xml:"viewedAt,attr"` // unix seconds
+	AccountID  string `xml:"accountID,attr"`
+	Duration   int64  `xml:"duration,attr"` // milliseconds, not always present
+}
+
+// GetUserPlayHistory pages through the server's watch history for one
+// account, newest first. daysBack <= 0 fetches the full history.
 func (c *Client) GetUserPlayHistory(userID string, daysBack int) ([]media.PlayHistoryItem, error) {
-	// Plex doesn't have a direct play history API like Emby
-	// This would require more complex implementation or return empty for now
-	return []media.PlayHistoryItem{}, nil
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return nil, fmt.Errorf("user id is required")
+	}
+	filter := fmt.Sprintf("accountID=%s&sort=viewedAt:desc", url.QueryEscape(userID))
+	if daysBack > 0 {
+		mindate := time.Now().AddDate(0, 0, -daysBack).Unix()
+		filter = fmt.Sprintf("%s&viewedAt>=%d", filter, mindate)
+	}
+
+	history := make([]media.PlayHistoryItem, 0)
+	for start := 0; start < historyMaxEntries; {
+		path := fmt.Sprintf("/status/sessions/history/all?X-Plex-Container-Start=%d&X-Plex-Container-Size=%d&%s",
+			start, historyPageSize, filter)
+		resp, err := c.doRequest(path)
+		if err != nil {
+			return history, err
+		}
+		var container plexHistoryContainer
+		if err := readXML(resp, &container); err != nil {
+			return history, err
+		}
+
+		rows := append(container.Videos, container.Tracks...)
+		for _, entry := range rows {
+			if item, ok := c.convertHistoryEntry(entry, userID); ok {
+				history = append(history, item)
+			}
+		}
+		if len(rows) < historyPageSize {
+			break
+		}
+		start += len(rows)
+	}
+	return history, nil
+}
+
+func (c *Client) convertHistoryEntry(entry plexHistoryEntry, userID string) (media.PlayHistoryItem, bool) {
+	itemID := strings.TrimSpace(entry.RatingKey)
+	if itemID == "" {
+		itemID = extractPlexID(entry.Key)
+	}
+	if itemID == "" || entry.ViewedAt <= 0 {
+		return media.PlayHistoryItem{}, false
+	}
+	// Other accounts' plays can slip through on servers that ignore the filter
+	if entry.AccountID != "" && entry.AccountID != userID {
+		return media.PlayHistoryItem{}, false
+	}
+	return media.PlayHistoryItem{
+		ID:         itemID,
+		ServerID:   c.serverID,
+		ServerType: media.ServerTypePlex,
+		Name:       entry.Title,
+		Type:       libraryItemType(entry.Type),
+		DatePlayed: time.Unix(entry.ViewedAt, 0).UTC().Format(time.RFC3339),
+		UserID:     userID,
+		HistoryID:  extractPlexID(entry.HistoryKey),
+		DurationMs: entry.Duration,
+	}, true
 }
 
 // Session control methods
@@ -841,35 +1115,20 @@ func (c *Client) GetUserPlayHistory(userID string, daysBack int) ([]media.PlayHi
 // PauseSession pauses a Plex session
 func (c *Client) PauseSession(sessionID string) error {
 	endpoint := fmt.Sprintf("/player/playback/pause?sessionId=%s", sessionID)
-	resp, err := c.doRequest(endpoint)
-	if err != nil {
-		return err
-	}
-	resp.Body.Close()
-	return nil
+	return httpx.CommandStatus(c.doRequest(endpoint))
 }
 
 // UnpauseSession resumes a Plex session
 func (c *Client) UnpauseSession(sessionID string) error {
 	endpoint := fmt.Sprintf("/player/playback/play?sessionId=%s", sessionID)
-	resp, err := c.doRequest(endpoint)
-	if err != nil {
-		return err
-	}
-	resp.Body.Close()
-	return nil
+	return httpx.CommandStatus(c.doRequest(endpoint))
 }
 
 // StopSession stops a Plex session
 func (c *Client) StopSession(sessionID string) error {
 	// Prefer server-side terminate endpoint for active sessions
 	endpoint := fmt.Sprintf("/status/sessions/terminate?sessionId=%s&reason=%s", url.QueryEscape(sessionID), url.QueryEscape("Stopped by admin"))
-	resp, err := c.doRequest(endpoint)
-	if err != nil {
-		return err
-	}
-	resp.Body.Close()
-	return nil
+	return httpx.CommandStatus(c.doRequest(endpoint))
 }
 
 // SendMessage sends a message to a Plex session
@@ -970,3 +1229,112 @@ func (c *Client) setCachedItems(cacheKey string, items []media.MediaItem) {
 	}
 	c.cache.Store(cacheKey, entry)
 }
+
+type plexSearchItem struct {
+	RatingKey string     `xml:"ratingKey,attr"`
+	Type      string     `xml:"type,attr"`
+	Title     string     `xml:"title,attr"`
+	Year      int        `xml:"year,attr"`
+	Duration  int64      `xml:"duration,attr"`
+	Guids     []plexGuid `xml:"Guid"`
+	Media     []struct {
+		Bitrate    int64  `xml:"bitrate,attr"`
+		Container  string `xml:"container,attr"`
+		Height     int    `xml:"height,attr"`
+		Width      int    `xml:"width,attr"`
+		VideoCodec string `xml:"videoCodec,attr"`
+		Part       []struct {
+			File string `xml:"file,attr"`
+			Size int64  `xml:"size,attr"`
+		} `xml:"Part"`
+	} `xml:"Media"`
+}
+
+// SearchItems searches movie and show sections by title and year. Provider IDs
+// are matched against the item's Guid entries; provider-only queries return no results.
+func (c *Client) SearchItems(query media.ItemQuery) ([]media.MediaItem, error) {
+	if strings.TrimSpace(query.Name) == "" {
+		return nil, nil
+	}
+	sections, err := c.fetchLibrarySections()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]media.MediaItem, 0)
+	for _, section := range sections {
+		q := url.Values{}
+		q.Set("title", query.Name)
+		q.Set("includeGuids", "1")
+		switch strings.ToLower(section.Type) {
+		case "movie":
+			q.Set("type", "1")
+		case "show":
+			q.Set("type", "2")
+		default:
+			continue
+		}
+		if query.Year > 0 {
+			q.Set("year", fmt.Sprintf("%d", query.Year))
+		}
+		resp, err := c.doRequest(fmt.Sprintf("/library/sections/%s/all?%s", section.Key, q.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		var container struct {
+			Videos      []plexSearchItem `xml:"Video"`
+			Directories []plexSearchItem `xml:"Directory"`
+		}
+		if err := readXML(resp, &container); err != nil {
+			return nil, err
+		}
+
+		for _, raw := range append(container.Videos, container.Directories...) {
+			item := media.MediaItem{
+				ID:         raw.RatingKey,
+				ServerID:   c.serverID,
+				ServerType: media.ServerTypePlex,
+				Name:       raw.Title,
+				Type:       raw.Type,
+			}
+			item.ProviderIDs = plexProviderIDs(raw.Guids)
+			if query.ProviderID != "" && !item.MatchesProviderID(query.ProviderID) {
+				continue
+			}
+			if raw.Year > 0 {
+				year := raw.Year
+				item.ProductionYear = &year
+			}
+			if raw.Duration > 0 {
+				runtime := raw.Duration
+				item.RuntimeMs = &runtime
+			}
+			if len(raw.Media) > 0 {
+				m := raw.Media[0]
+				item.Codec = strings.ToUpper(m.VideoCodec)
+				item.Container = m.Container
+				if m.Bitrate > 0 {
+					bitrate := m.Bitrate * 1000 // Plex stores kbps
+					item.BitrateBps = &bitrate
+				}
+				if m.Width > 0 {
+					w := m.Width
+					item.Width = &w
+				}
+				if m.Height > 0 {
+					h := m.Height
+					item.Height = &h
+				}
+				if len(m.Part) > 0 {
+					if m.Part[0].Size > 0 {
+						size := m.Part[0].Size
+						item.FileSizeBytes = &size
+					}
+					item.FilePath = m.Part[0].File
+				}
+			}
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
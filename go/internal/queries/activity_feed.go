@@ -0,0 +1,171 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Activity feed entry types
+const (
+	FeedSessionStart = "session_start"
+	FeedSessionEnd   = "session_end"
+	FeedLibraryAdded = "library_added"
+	FeedNewUser      = "new_user"
+	FeedAdminAction  = "admin_action"
+)
+
+// FeedTypes lists every activity feed entry type.
+var FeedTypes = []string{FeedSessionStart, FeedSessionEnd, FeedLibraryAdded, FeedNewUser, FeedAdminAction}
+
+// ErrBadCursor is returned for a feed cursor that wasn't produced by ActivityFeed.
+var ErrBadCursor = errors.New("invalid cursor")
+
+// FeedItem is one activity feed entry. Ref identifies the source row within its type.
+type FeedItem struct {
+	Type     string `json:"type"`
+	Time     int64  `json:"time"` // unix seconds
+	Ref      string `json:"ref"`
+	UserID   string `json:"user_id,omitempty"`
+	UserName string `json:"user_name,omitempty"`
+	ItemID   string `json:"item_id,omitempty"`
+	ItemName string `json:"item_name,omitempty"`
+	ItemType string `json:"item_type,omitempty"`
+	ServerID string `json:"server_id,omitempty"`
+	// Detail is the client for sessions, the play method for ended sessions, the resolution
+	// tier for library additions and the operation and status for admin actions.
+	Detail string `json:"detail,omitempty"`
+}
+
+// feedColumns names the columns every feed source selects, in order.
+const feedColumns = "type, ts, ref, user_id, user_name, item_id, item_name, item_type, server_id, detail"
+
+// feedSources select feedColumns per entry type; each takes the upper time bound as its
+// only argument.
+var feedSources = map[string]string{
+	FeedSessionStart: `
+		SELECT 'session_start' AS type, ps.started_at AS ts, CAST(ps.id AS TEXT) AS ref, ps.user_id AS user_id,
+		       COALESCE(ps.user_name, u.name, '') AS user_name, ps.item_id AS item_id, COALESCE(ps.item_name, '') AS item_name,
+		       COALESCE(ps.item_type, '') AS item_type, COALESCE(ps.server_id, '') AS server_id, COALESCE(ps.client_name, '') AS detail
+		FROM play_sessions ps
+		LEFT JOIN emby_user u ON u.id = ps.user_id
+		WHERE ps.started_at <= ? AND COALESCE(u.exclude_from_stats, 0) = 0`,
+	FeedSessionEnd: `
+		SELECT 'session_end' AS type, ps.ended_at AS ts, CAST(ps.id AS TEXT) AS ref, ps.user_id AS user_id,
+		       COALESCE(ps.user_name, u.name, '') AS user_name, ps.item_id AS item_id, COALESCE(ps.item_name, '') AS item_name,
+		       COALESCE(ps.item_type, '') AS item_type, COALESCE(ps.server_id, '') AS server_id, COALESCE(ps.play_method, '') AS detail
+		FROM play_sessions ps
+		LEFT JOIN emby_user u ON u.id = ps.user_id
+		WHERE ps.is_active = 0 AND ps.ended_at IS NOT NULL AND ps.ended_at <= ? AND COALESCE(u.exclude_from_stats, 0) = 0`,
+	// A tier or size change is recorded as a removal plus an addition at the same moment;
+	// only genuine additions are listed.
+	FeedLibraryAdded: `
+		SELECT 'library_added' AS type, le.occurred_at AS ts, CAST(le.id AS TEXT) AS ref, '' AS user_id,
+		       '' AS user_name, li.id AS item_id, COALESCE(li.name, '') AS item_name,
+		       COALESCE(li.media_type, '') AS item_type, COALESCE(le.server_id, '') AS server_id, le.tier AS detail
+		FROM library_events le
+		JOIN library_item li ON li.id = le.item_id
+		WHERE le.event = 'added' AND le.occurred_at <= ?
+		  AND NOT EXISTS (SELECT 1 FROM library_events r
+		                  WHERE r.item_id = le.item_id AND r.event = 'removed' AND r.occurred_at = le.occurred_at)`,
+	FeedNewUser: `
+		SELECT 'new_user' AS type, first_seen_at AS ts, id AS ref, id AS user_id,
+		       COALESCE(name, '') AS user_name, '' AS item_id, '' AS item_name,
+		       '' AS item_type, COALESCE(server_id, '') AS server_id, '' AS detail
+		FROM emby_user
+		WHERE first_seen_at IS NOT NULL AND first_seen_at <= ? AND deleted_at IS NULL AND exclude_from_stats = 0`,
+	FeedAdminAction: `
+		SELECT 'admin_action' AS type, started_at AS ts, id AS ref, '' AS user_id,
+		       COALESCE(created_by, '') AS user_name, '' AS item_id, '' AS item_name,
+		       '' AS item_type, '' AS server_id, operation_type || ' (' || status || ')' AS detail
+		FROM cleanup_jobs
+		WHERE started_at <= ?`,
+}
+
+// FeedCursor is the position after the last entry of a page.
+type FeedCursor struct {
+	Time int64
+	Type string
+	Ref  string
+}
+
+// Encode returns the cursor as an opaque token.
+func (c FeedCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d|%s|%s", c.Time, c.Type, c.Ref)))
+}
+
+// DecodeFeedCursor parses a token produced by FeedCursor.Encode.
+func DecodeFeedCursor(token string) (FeedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return FeedCursor{}, ErrBadCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 {
+		return FeedCursor{}, ErrBadCursor
+	}
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return FeedCursor{}, ErrBadCursor
+	}
+	return FeedCursor{Time: ts, Type: parts[1], Ref: parts[2]}, nil
+}
+
+// ActivityFeed returns up to limit entries of the given types, newest first, starting after
+// the cursor (nil for the first page). Entries at the same second are ordered by type and
+// ref so pages never skip or repeat one. The returned cursor is nil on the last page.
+func ActivityFeed(ctx context.Context, db *sql.DB, types []string, after *FeedCursor, limit int) ([]FeedItem, *FeedCursor, error) {
+	pos := FeedCursor{Time: math.MaxInt64}
+	if after != nil {
+		pos = *after
+	}
+	// Each source is limited on its own first, so every page reads at most limit+1 rows per
+	// type through the time indexes instead of merging whole tables.
+	var parts []string
+	var args []any
+	for _, t := range types {
+		src, ok := feedSources[t]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown activity type %q", t)
+		}
+		parts = append(parts, `SELECT * FROM (`+src+`
+		  AND (ts, type, ref) < (?, ?, ?)
+		ORDER BY ts DESC, ref DESC LIMIT ?)`)
+		args = append(args, pos.Time, pos.Time, pos.Type, pos.Ref, limit+1)
+	}
+	if len(parts) == 0 {
+		return []FeedItem{}, nil, nil
+	}
+	query := `SELECT ` + feedColumns + ` FROM (` + strings.Join(parts, "\nUNION ALL\n") + `)
+		ORDER BY ts DESC, type DESC, ref DESC LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	out := []FeedItem{}
+	for rows.Next() {
+		var it FeedItem
+		if err := rows.Scan(&it.Type, &it.Time, &it.Ref, &it.UserID, &it.UserName, &it.ItemID, &it.ItemName, &it.ItemType, &it.ServerID, &it.Detail); err != nil {
+			return nil, nil, err
+		}
+		out = append(out, it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(out) <= limit {
+		return out, nil, nil
+	}
+	out = out[:limit]
+	last := out[limit-1]
+	return out, &FeedCursor{Time: last.Time, Type: last.Type, Ref: last.Ref}, nil
+}
@@ -0,0 +1,55 @@
+package queries
+
+import (
+	"context"
+	"testing"
+)
+
+func TestActivityFeedPagination(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	types := []string{FeedSessionStart, FeedSessionEnd}
+
+	var got []string
+	var after *FeedCursor
+	for page := 0; ; page++ {
+		if page > 5 {
+			t.Fatal("feed did not terminate")
+		}
+		items, next, err := ActivityFeed(ctx, conn, types, after, 2)
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		for _, it := range items {
+			got = append(got, it.Type+"/"+it.Ref)
+		}
+		if next == nil {
+			break
+		}
+		// Cursors go through the client as opaque strings.
+		cur, err := DecodeFeedCursor(next.Encode())
+		if err != nil {
+			t.Fatalf("decode cursor: %v", err)
+		}
+		after = &cur
+	}
+
+	// Carol's session is excluded; ties at the same second break on type, then ref.
+	want := []string{
+		"session_end/4", "session_start/4",
+		"session_end/2", "session_end/1",
+		"session_start/2", "session_start/1",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+
+	if _, err := DecodeFeedCursor("not-a-cursor"); err != ErrBadCursor {
+		t.Errorf("expected ErrBadCursor, got %v", err)
+	}
+}
@@ -20,10 +20,10 @@ type TopItemRow struct {
 	Display string  `json:"display"`
 }
 
-// TopUsersByWatchSeconds calculates top users based on interval overlap in a time window.
-func TopUsersByWatchSeconds(ctx context.Context, db *sql.DB, winStart, winEnd int64, limit int) ([]TopUserRow, error) {
-	// Sum overlapped duration across all intervals in the window
-	query := `
+// topUsersByWatchSecondsSQL sums each interval's overlap with the window, capped at
+// its recorded duration so paused time is not counted.
+// Args: winEnd, winStart, winEnd, winStart, serverType, serverType, serverID, serverID, limit.
+const topUsersByWatchSecondsSQL = `
         SELECT
             l.user_id,
             u.name,
@@ -41,37 +41,22 @@ func TopUsersByWatchSeconds(ctx context.Context, db *sql.DB, winStart, winEnd in
                 )
             ) / 3600.0 AS hours
         FROM play_intervals l
-        JOIN emby_user u ON u.id = l.user_id AND u.deleted_at IS NULL
+        JOIN emby_user u ON u.id = l.user_id AND u.deleted_at IS NULL AND u.exclude_from_stats = 0
         JOIN library_item li ON li.id = l.item_id
         WHERE
             l.start_ts <= ? AND l.end_ts >= ?
             AND COALESCE(li.media_type, 'Unknown') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
+            AND (? = '' OR LOWER(COALESCE(u.server_type, '')) = ?)
+            AND (? = '' OR u.server_id = ?)
         GROUP BY l.user_id, u.name, u.server_id
         HAVING hours > 0
         ORDER BY hours DESC
         LIMIT ?;
     `
-	rows, err := db.QueryContext(ctx, query, winEnd, winStart, winEnd, winStart, limit)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
 
-	var out []TopUserRow
-	for rows.Next() {
-		var r TopUserRow
-		if err := rows.Scan(&r.UserID, &r.Name, &r.ServerID, &r.Hours); err != nil {
-			return nil, err
-		}
-		out = append(out, r)
-	}
-	return out, rows.Err()
-}
-
-// TopItemsByWatchSeconds calculates top items based on interval overlap.
-func TopItemsByWatchSeconds(ctx context.Context, db *sql.DB, winStart, winEnd int64, limit int) ([]TopItemRow, error) {
-	// Sum overlapped duration across all intervals in the window
-	query := `
+// topItemsByWatchSecondsSQL is the per-item counterpart of topUsersByWatchSecondsSQL.
+// Args: winEnd, winStart, winEnd, winStart, limit.
+const topItemsByWatchSecondsSQL = `
         SELECT
             l.item_id,
             li.name,
@@ -98,7 +83,90 @@ func TopItemsByWatchSeconds(ctx context.Context, db *sql.DB, winStart, winEnd in
         ORDER BY hours DESC
         LIMIT ?;
     `
-	rows, err := db.QueryContext(ctx, query, winEnd, winStart, winEnd, winStart, limit)
+
+// topUsersAllTimeSQL ranks users by their lifetime_watch totals, optionally adding Trakt time.
+// Args: includeTrakt, serverType, serverType, serverID, serverID, limit.
+const topUsersAllTimeSQL = `
+        SELECT
+            u.id,
+            u.name,
+            u.server_id,
+            CASE WHEN ? = 1 THEN
+                COALESCE((lw.emby_ms + lw.trakt_ms) / 3600000.0, 0)
+            ELSE
+                COALESCE(lw.emby_ms / 3600000.0, 0)
+            END AS hours
+        FROM emby_user u
+        LEFT JOIN lifetime_watch lw ON lw.user_id = u.id
+        WHERE (lw.emby_ms > 0 OR lw.trakt_ms > 0) AND u.deleted_at IS NULL AND u.exclude_from_stats = 0
+          AND (? = '' OR LOWER(COALESCE(u.server_type, '')) = ?)
+          AND (? = '' OR u.server_id = ?)
+        ORDER BY hours DESC
+        LIMIT ?;
+    `
+
+// topUsersBySessionCountSQL estimates half an hour per session for when intervals are not populated.
+// Args: winStart, winEnd, serverType, serverType, serverID, serverID, limit.
+const topUsersBySessionCountSQL = `
+        SELECT
+            u.id,
+            u.name,
+            u.server_id,
+            COUNT(DISTINCT ps.id) * 0.5 AS hours
+        FROM emby_user u
+        JOIN play_sessions ps ON ps.user_id = u.id
+        LEFT JOIN library_item li ON li.id = ps.item_id
+        WHERE ps.started_at >= ? AND ps.started_at <= ?
+          AND u.exclude_from_stats = 0
+          AND (li.id IS NULL OR li.media_type NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram'))
+          AND (? = '' OR LOWER(COALESCE(u.server_type, '')) = ?)
+          AND (? = '' OR u.server_id = ?)
+        GROUP BY u.id, u.name, u.server_id
+        ORDER BY hours DESC
+        LIMIT ?;
+    `
+
+// topItemsBySessionCountSQL is the per-item counterpart of topUsersBySessionCountSQL.
+// Args: winStart, winEnd, limit.
+const topItemsBySessionCountSQL = `
+        SELECT
+            li.id,
+            li.name,
+            li.media_type,
+            COUNT(DISTINCT ps.id) * 0.5 AS hours
+        FROM library_item li
+        JOIN play_sessions ps ON ps.item_id = li.id
+        WHERE ps.started_at >= ? AND ps.started_at <= ?
+          AND li.media_type NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
+        GROUP BY li.id, li.name, li.media_type
+        ORDER BY hours DESC
+        LIMIT ?;
+    `
+
+// TopUsersByWatchSeconds calculates top users based on interval overlap in a time window.
+// serverType (lower-case) or serverID optionally limit it to the users of one server kind or
+// instance.
+func TopUsersByWatchSeconds(ctx context.Context, db *sql.DB, winStart, winEnd int64, serverType, serverID string, limit int) ([]TopUserRow, error) {
+	rows, err := db.QueryContext(ctx, topUsersByWatchSecondsSQL, winEnd, winStart, winEnd, winStart, serverType, serverType, serverID, serverID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TopUserRow
+	for rows.Next() {
+		var r TopUserRow
+		if err := rows.Scan(&r.UserID, &r.Name, &r.ServerID, &r.Hours); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// TopItemsByWatchSeconds calculates top items based on interval overlap.
+func TopItemsByWatchSeconds(ctx context.Context, db *sql.DB, winStart, winEnd int64, limit int) ([]TopItemRow, error) {
+	rows, err := db.QueryContext(ctx, topItemsByWatchSecondsSQL, winEnd, winStart, winEnd, winStart, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -115,3 +183,65 @@ func TopItemsByWatchSeconds(ctx context.Context, db *sql.DB, winStart, winEnd in
 	}
 	return out, rows.Err()
 }
+
+// TopUsersAllTime returns users ranked by lifetime watch time. Trakt-imported time is
+// only counted when includeTrakt is set. serverType and serverID scope it like
+// TopUsersByWatchSeconds.
+func TopUsersAllTime(ctx context.Context, db *sql.DB, includeTrakt bool, serverType, serverID string, limit int) ([]TopUserRow, error) {
+	rows, err := db.QueryContext(ctx, topUsersAllTimeSQL, includeTrakt, serverType, serverType, serverID, serverID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TopUserRow
+	for rows.Next() {
+		var r TopUserRow
+		if err := rows.Scan(&r.UserID, &r.Name, &r.ServerID, &r.Hours); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// TopUsersBySessionCount is a coarse fallback for TopUsersByWatchSeconds that counts
+// sessions started in the window instead of summing intervals.
+func TopUsersBySessionCount(ctx context.Context, db *sql.DB, winStart, winEnd int64, serverType, serverID string, limit int) ([]TopUserRow, error) {
+	rows, err := db.QueryContext(ctx, topUsersBySessionCountSQL, winStart, winEnd, serverType, serverType, serverID, serverID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TopUserRow
+	for rows.Next() {
+		var r TopUserRow
+		if err := rows.Scan(&r.UserID, &r.Name, &r.ServerID, &r.Hours); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// TopItemsBySessionCount is a coarse fallback for TopItemsByWatchSeconds that counts
+// sessions started in the window instead of summing intervals.
+func TopItemsBySessionCount(ctx context.Context, db *sql.DB, winStart, winEnd int64, limit int) ([]TopItemRow, error) {
+	rows, err := db.QueryContext(ctx, topItemsBySessionCountSQL, winStart, winEnd, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TopItemRow
+	for rows.Next() {
+		var r TopItemRow
+		if err := rows.Scan(&r.ItemID, &r.Name, &r.Type, &r.Hours); err != nil {
+			return nil, err
+		}
+		r.Display = r.Name
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
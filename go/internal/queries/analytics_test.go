@@ -0,0 +1,205 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"emby-analytics/internal/db"
+)
+
+// openFixtureDB migrates a fresh SQLite file and seeds it with a small, fixed data set:
+//
+//	alice watches Movie A for one hour (two back-to-back intervals) and Channel 1 for an hour
+//	bob watches Movie A for 30 minutes, with a 30 minute pause recorded in the interval
+//	carol (excluded from stats) watches Movie B for two hours
+func openFixtureDB(t *testing.T) *sql.DB {
+	t.Helper()
+	path := filepath.ToSlash(filepath.Join(t.TempDir(), "fixture.db"))
+	if err := db.MigrateUp(fmt.Sprintf("sqlite://file:%s?mode=rwc", path)); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	conn, err := db.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	stmts := []string{
+		`INSERT INTO emby_user (id, name, server_id) VALUES ('alice', 'Alice', 's1'), ('bob', 'Bob', 's1'), ('carol', 'Carol', 's1')`,
+		`UPDATE emby_user SET exclude_from_stats = 1 WHERE id = 'carol'`,
+		`INSERT INTO library_item (id, server_id, item_id, name, media_type)
+		 VALUES ('movie-a', 's1', 'movie-a', 'Movie A', 'Movie'),
+		        ('movie-b', 's1', 'movie-b', 'Movie B', 'Movie'),
+		        ('chan-1', 's1', 'chan-1', 'Channel 1', 'TvChannel')`,
+		`INSERT INTO play_sessions (id, user_id, session_id, item_id, item_name, device_id, client_name, started_at, ended_at, is_active)
+		 VALUES (1, 'alice', 'sa', 'movie-a', 'Movie A', 'd', 'Web', 1000, 4600, 0),
+		        (2, 'bob', 'sb', 'movie-a', 'Movie A', 'd', 'Web', 1000, 4600, 0),
+		        (3, 'carol', 'sc', 'movie-b', 'Movie B', 'd', 'Web', 1000, 8200, 0),
+		        (4, 'alice', 'sd', 'chan-1', 'Channel 1', 'd', 'Web', 5000, 8600, 0)`,
+		`INSERT INTO play_intervals (session_fk, item_id, user_id, start_ts, end_ts, start_pos_ticks, end_pos_ticks, duration_seconds, seeked)
+		 VALUES (1, 'movie-a', 'alice', 1000, 2800, 0, 0, 1800, 0),
+		        (1, 'movie-a', 'alice', 2800, 4600, 0, 0, 1800, 0),
+		        (2, 'movie-a', 'bob', 1000, 4600, 0, 0, 1800, 0),
+		        (3, 'movie-b', 'carol', 1000, 8200, 0, 0, 7200, 0),
+		        (4, 'chan-1', 'alice', 5000, 8600, 0, 0, 3600, 0)`,
+	}
+	seed(t, conn, stmts...)
+	return conn
+}
+
+// seed runs fixture statements, failing the test on the first error.
+func seed(t *testing.T, conn *sql.DB, stmts ...string) {
+	t.Helper()
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			t.Fatalf("seed: %v\n%s", err, s)
+		}
+	}
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestTopUsersByWatchSeconds(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+
+	rows, err := TopUsersByWatchSeconds(ctx, conn, 0, 10000, "", "", 10)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 users (carol excluded), got %d: %+v", len(rows), rows)
+	}
+	if rows[0].UserID != "alice" || !approx(rows[0].Hours, 1.0) {
+		t.Errorf("expected alice with 1h (live TV excluded), got %+v", rows[0])
+	}
+	if rows[1].UserID != "bob" || !approx(rows[1].Hours, 0.5) {
+		t.Errorf("expected bob with 0.5h (paused time not counted), got %+v", rows[1])
+	}
+}
+
+func TestTopUsersByWatchSecondsClampsToWindow(t *testing.T) {
+	conn := openFixtureDB(t)
+
+	// Only the first of alice's intervals and half of bob's wall-clock span fall in the window.
+	rows, err := TopUsersByWatchSeconds(context.Background(), conn, 1000, 2800, "", "", 10)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	got := map[string]float64{}
+	for _, r := range rows {
+		got[r.UserID] = r.Hours
+	}
+	if !approx(got["alice"], 0.5) {
+		t.Errorf("alice: expected 0.5h, got %v", got["alice"])
+	}
+	if !approx(got["bob"], 0.5) {
+		t.Errorf("bob: expected 0.5h, got %v", got["bob"])
+	}
+}
+
+func TestTopUsersServerFilter(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	seed(t, conn,
+		`UPDATE emby_user SET server_id = 'p1', server_type = 'plex' WHERE id = 'bob'`,
+		`INSERT INTO lifetime_watch (user_id, emby_ms, trakt_ms) VALUES ('alice', 3600000, 0), ('bob', 7200000, 0)`)
+
+	only := func(rows []TopUserRow, err error) string {
+		if err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		if len(rows) != 1 {
+			t.Fatalf("expected one user, got %+v", rows)
+		}
+		return rows[0].UserID
+	}
+	if id := only(TopUsersByWatchSeconds(ctx, conn, 0, 10000, "plex", "", 10)); id != "bob" {
+		t.Errorf("plex users = %s, want bob", id)
+	}
+	if id := only(TopUsersByWatchSeconds(ctx, conn, 0, 10000, "", "s1", 10)); id != "alice" {
+		t.Errorf("s1 users = %s, want alice", id)
+	}
+	if id := only(TopUsersAllTime(ctx, conn, false, "emby", "", 10)); id != "alice" {
+		t.Errorf("all-time emby users = %s, want alice", id)
+	}
+	if id := only(TopUsersBySessionCount(ctx, conn, 0, 10000, "", "p1", 10)); id != "bob" {
+		t.Errorf("p1 session counts = %s, want bob", id)
+	}
+}
+
+func TestTopItemsByWatchSeconds(t *testing.T) {
+	conn := openFixtureDB(t)
+
+	rows, err := TopItemsByWatchSeconds(context.Background(), conn, 0, 10000, 10)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	got := map[string]float64{}
+	for _, r := range rows {
+		got[r.ItemID] = r.Hours
+	}
+	if _, ok := got["chan-1"]; ok {
+		t.Errorf("live TV item should be excluded: %+v", rows)
+	}
+	if !approx(got["movie-a"], 1.5) {
+		t.Errorf("movie-a: expected 1.5h, got %v", got["movie-a"])
+	}
+	if !approx(got["movie-b"], 2.0) {
+		t.Errorf("movie-b: expected 2h, got %v", got["movie-b"])
+	}
+}
+
+func TestTopUsersAllTime(t *testing.T) {
+	conn := openFixtureDB(t)
+	seed(t, conn, `INSERT INTO lifetime_watch (user_id, emby_ms, trakt_ms) VALUES ('alice', 3600000, 7200000), ('bob', 7200000, 0)`)
+	ctx := context.Background()
+
+	rows, err := TopUsersAllTime(ctx, conn, false, "", "", 10)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(rows) != 2 || rows[0].UserID != "bob" || !approx(rows[0].Hours, 2.0) {
+		t.Fatalf("without Trakt expected bob first with 2h, got %+v", rows)
+	}
+
+	rows, err = TopUsersAllTime(ctx, conn, true, "", "", 10)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(rows) != 2 || rows[0].UserID != "alice" || !approx(rows[0].Hours, 3.0) {
+		t.Fatalf("with Trakt expected alice first with 3h, got %+v", rows)
+	}
+}
+
+func TestSessionCountFallbacks(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+
+	users, err := TopUsersBySessionCount(ctx, conn, 0, 10000, "", "", 10)
+	if err != nil {
+		t.Fatalf("users: %v", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %+v", users)
+	}
+	for _, u := range users {
+		if !approx(u.Hours, 0.5) || u.ServerID != "s1" {
+			t.Errorf("expected one non-live session (0.5h) on s1, got %+v", u)
+		}
+	}
+
+	items, err := TopItemsBySessionCount(ctx, conn, 0, 10000, 10)
+	if err != nil {
+		t.Fatalf("items: %v", err)
+	}
+	if len(items) != 2 || items[0].ItemID != "movie-a" || !approx(items[0].Hours, 1.0) {
+		t.Fatalf("expected movie-a first with two sessions, got %+v", items)
+	}
+}
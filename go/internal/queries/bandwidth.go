@@ -0,0 +1,181 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+)
+
+// BandwidthBucket is the outbound streaming bandwidth of one hour.
+type BandwidthBucket struct {
+	Start int64 `json:"start"` // unix seconds, start of the hour
+	// AvgMbps spreads the data delivered in the hour over the whole hour
+	AvgMbps float64 `json:"avg_mbps"`
+	// PeakMbps is the highest total bitrate of the streams playing at one time
+	PeakMbps    float64 `json:"peak_mbps"`
+	PeakStreams int     `json:"peak_streams"`
+	GB          float64 `json:"gb"`
+}
+
+// BandwidthUsage is the data one user streamed from one server.
+type BandwidthUsage struct {
+	UserID   string  `json:"user_id"`
+	UserName string  `json:"user_name"`
+	ServerID string  `json:"server_id"`
+	Hours    float64 `json:"hours"` // time spent streaming
+	GB       float64 `json:"gb"`
+	AvgMbps  float64 `json:"avg_mbps"` // while streaming
+}
+
+// BandwidthServer is one server's outbound streaming bandwidth.
+type BandwidthServer struct {
+	ServerID   string  `json:"server_id"`
+	ServerType string  `json:"server_type"`
+	GB         float64 `json:"gb"`
+	PeakMbps   float64 `json:"peak_mbps"`
+	PeakAt     int64   `json:"peak_at,omitempty"`
+}
+
+// BandwidthReport sums the bandwidth samples of a time range.
+type BandwidthReport struct {
+	GB       float64           `json:"gb"`
+	PeakMbps float64           `json:"peak_mbps"`
+	PeakAt   int64             `json:"peak_at,omitempty"`
+	Hours    []BandwidthBucket `json:"hours"`
+	Users    []BandwidthUsage  `json:"users"`
+	Servers  []BandwidthServer `json:"servers"`
+}
+
+// BandwidthStats aggregates the bandwidth samples taken in [from, to) (unix seconds) per
+// hour, per user and per server. Each sample delivers bitrate × seconds bits; samples
+// taken at the same moment add up to the concurrent bitrate used for peaks. Every stream
+// counts, including live TV and users excluded from stats. serverType (lower-case) or
+// serverID optionally limit it to one server kind or instance.
+func BandwidthStats(ctx context.Context, db *sql.DB, from, to int64, serverType, serverID string) (BandwidthReport, error) {
+	out := BandwidthReport{Hours: []BandwidthBucket{}, Users: []BandwidthUsage{}, Servers: []BandwidthServer{}}
+	if to <= from {
+		return out, nil
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT ts, server_id, LOWER(COALESCE(server_type, '')), user_id, COALESCE(user_name, user_id),
+		       bitrate_bps, seconds
+		FROM bandwidth_samples
+		WHERE ts >= ? AND ts < ?
+		  AND (? = '' OR LOWER(COALESCE(server_type, '')) = ?)
+		  AND (? = '' OR server_id = ?)
+		ORDER BY ts
+	`, from, to, serverType, serverType, serverID, serverID)
+	if err != nil {
+		return out, err
+	}
+	defer rows.Close()
+
+	firstHour := from - from%3600
+	for h := firstHour; h < to; h += 3600 {
+		out.Hours = append(out.Hours, BandwidthBucket{Start: h})
+	}
+
+	type instant struct {
+		bitrate int64
+		streams int
+	}
+	type userKey struct{ server, user string }
+	type serverKey struct {
+		server string
+		ts     int64
+	}
+	var bits float64
+	concurrent := map[int64]*instant{}
+	serverConcurrent := map[serverKey]int64{}
+	servers := map[string]*BandwidthServer{}
+	users := map[userKey]*BandwidthUsage{}
+	for rows.Next() {
+		var ts, bitrate, seconds int64
+		var server, kind, userID, userName string
+		if err := rows.Scan(&ts, &server, &kind, &userID, &userName, &bitrate, &seconds); err != nil {
+			return out, err
+		}
+		sampleBits := float64(bitrate) * float64(seconds)
+		bits += sampleBits
+		gb := sampleBits / 8 / bytesPerGB
+
+		b := &out.Hours[(ts-firstHour)/3600]
+		b.GB += gb
+		in := concurrent[ts]
+		if in == nil {
+			in = &instant{}
+			concurrent[ts] = in
+		}
+		in.bitrate += bitrate
+		in.streams++
+		if mbps := float64(in.bitrate) / 1e6; mbps > b.PeakMbps {
+			b.PeakMbps = mbps
+		}
+		if in.streams > b.PeakStreams {
+			b.PeakStreams = in.streams
+		}
+		if mbps := float64(in.bitrate) / 1e6; mbps > out.PeakMbps {
+			out.PeakMbps, out.PeakAt = mbps, ts
+		}
+
+		s := servers[server]
+		if s == nil {
+			s = &BandwidthServer{ServerID: server, ServerType: kind}
+			servers[server] = s
+		}
+		s.GB += gb
+		sk := serverKey{server, ts}
+		serverConcurrent[sk] += bitrate
+		if mbps := float64(serverConcurrent[sk]) / 1e6; mbps > s.PeakMbps {
+			s.PeakMbps, s.PeakAt = mbps, ts
+		}
+
+		uk := userKey{server, userID}
+		u := users[uk]
+		if u == nil {
+			u = &BandwidthUsage{UserID: userID, UserName: userName, ServerID: server}
+			users[uk] = u
+		}
+		u.Hours += float64(seconds) / 3600
+		u.GB += gb
+	}
+	if err := rows.Err(); err != nil {
+		return out, err
+	}
+
+	out.GB = roundCost(bits / 8 / bytesPerGB)
+	out.PeakMbps = roundCost(out.PeakMbps)
+	for i := range out.Hours {
+		b := &out.Hours[i]
+		b.AvgMbps = roundCost(b.GB * 8 * bytesPerGB / 3600 / 1e6)
+		b.GB, b.PeakMbps = roundCost(b.GB), roundCost(b.PeakMbps)
+	}
+	for _, u := range users {
+		if u.Hours > 0 {
+			u.AvgMbps = roundCost(u.GB * 8 * bytesPerGB / (u.Hours * 3600) / 1e6)
+		}
+		u.Hours, u.GB = roundCost(u.Hours), roundCost(u.GB)
+		out.Users = append(out.Users, *u)
+	}
+	sort.Slice(out.Users, func(i, j int) bool {
+		a, b := out.Users[i], out.Users[j]
+		if a.GB != b.GB {
+			return a.GB > b.GB
+		}
+		if a.ServerID != b.ServerID {
+			return a.ServerID < b.ServerID
+		}
+		return a.UserID < b.UserID
+	})
+	for _, s := range servers {
+		s.GB, s.PeakMbps = roundCost(s.GB), roundCost(s.PeakMbps)
+		out.Servers = append(out.Servers, *s)
+	}
+	sort.Slice(out.Servers, func(i, j int) bool {
+		if out.Servers[i].GB != out.Servers[j].GB {
+			return out.Servers[i].GB > out.Servers[j].GB
+		}
+		return out.Servers[i].ServerID < out.Servers[j].ServerID
+	})
+	return out, nil
+}
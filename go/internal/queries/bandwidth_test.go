@@ -0,0 +1,58 @@
+package queries
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBandwidthStats(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+
+	// Two streams share the 7260 poll; bob streams alone an hour later on another server
+	seed(t, conn, `
+		INSERT INTO bandwidth_samples (ts, server_id, server_type, user_id, user_name, bitrate_bps, seconds)
+		VALUES (7200, 's1', 'emby', 'alice', 'Alice', 8000000, 60),
+		       (7260, 's1', 'emby', 'alice', 'Alice', 8000000, 60),
+		       (7260, 's1', 'emby', 'bob', 'Bob', 4000000, 60),
+		       (10800, 's2', 'plex', 'bob', NULL, 2000000, 3600),
+		       (99999, 's1', 'emby', 'alice', 'Alice', 8000000, 60)`)
+
+	report, err := BandwidthStats(ctx, conn, 7200, 14400, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Hours) != 2 {
+		t.Fatalf("hours = %+v", report.Hours)
+	}
+	first := report.Hours[0]
+	if first.Start != 7200 || !approx(first.PeakMbps, 12) || first.PeakStreams != 2 {
+		t.Errorf("first hour = %+v", first)
+	}
+	// 20 Mbit/s-minutes spread over an hour
+	if !approx(first.AvgMbps, 0.33) {
+		t.Errorf("first hour avg = %v", first.AvgMbps)
+	}
+	if second := report.Hours[1]; !approx(second.AvgMbps, 2) || !approx(second.PeakMbps, 2) {
+		t.Errorf("second hour = %+v", second)
+	}
+	if !approx(report.PeakMbps, 12) || report.PeakAt != 7260 {
+		t.Errorf("peak = %v at %d", report.PeakMbps, report.PeakAt)
+	}
+
+	if len(report.Users) != 3 || report.Users[0].UserID != "bob" || report.Users[0].ServerID != "s2" ||
+		report.Users[0].UserName != "bob" || !approx(report.Users[0].Hours, 1) || !approx(report.Users[0].AvgMbps, 2) {
+		t.Fatalf("users = %+v", report.Users)
+	}
+	if len(report.Servers) != 2 || report.Servers[0].ServerID != "s2" || !approx(report.Servers[1].PeakMbps, 12) {
+		t.Fatalf("servers = %+v", report.Servers)
+	}
+
+	plex, err := BandwidthStats(ctx, conn, 7200, 14400, "plex", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plex.Users) != 1 || len(plex.Servers) != 1 || !approx(plex.PeakMbps, 2) {
+		t.Fatalf("plex report = %+v", plex)
+	}
+}
@@ -0,0 +1,131 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+)
+
+// RebuildCanonicalItems regroups movies, series and episodes that share an imdb, tmdb or
+// tvdb id of the same media type into canonical_item, returning the number of groups. A
+// group keeps its canonical item while that item is still in it; new groups use the item
+// added first.
+func RebuildCanonicalItems(ctx context.Context, db *sql.DB, now int64) (int, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT li.id, li.media_type, li.provider_ids, COALESCE(ci.canonical_id, '')
+		FROM library_item li
+		LEFT JOIN canonical_item ci ON ci.item_id = li.id
+		WHERE li.media_type IN ('Movie', 'Series', 'Episode') AND COALESCE(li.provider_ids, '') <> ''
+		ORDER BY li.created_at, li.id
+	`)
+	if err != nil {
+		return 0, err
+	}
+	type item struct {
+		id, previous string
+	}
+	var items []item
+	parent := map[string]string{}   // union-find over item ids
+	byKey := map[string]string{}    // provider key -> first item with it
+	matchKey := map[string]string{} // item id -> a provider key it shares
+	var find func(string) string
+	find = func(id string) string {
+		if p := parent[id]; p != id {
+			parent[id] = find(p)
+		}
+		return parent[id]
+	}
+	for rows.Next() {
+		var id, mediaType, providers, previous string
+		if err := rows.Scan(&id, &mediaType, &providers, &previous); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		items = append(items, item{id: id, previous: previous})
+		parent[id] = id
+		keys := compareProviderKeys(LibraryCompareItem{MediaType: mediaType, ProviderIDs: ParseProviderIDs(providers)})
+		for _, key := range keys {
+			other, ok := byKey[key]
+			if !ok {
+				byKey[key] = id
+				continue
+			}
+			if a, b := find(other), find(id); a != b {
+				parent[b] = a
+			}
+			matchKey[id], matchKey[other] = key, key
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// items is in the order they were added, so each group's members are too
+	members := map[string][]item{}
+	var roots []string
+	for _, it := range items {
+		root := find(it.id)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], it)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM canonical_item`); err != nil {
+		return 0, err
+	}
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO canonical_item (item_id, canonical_id, match_key, updated_at) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	groups := 0
+	for _, root := range roots {
+		group := members[root]
+		if len(group) < 2 {
+			continue
+		}
+		canonical := group[0].id
+		ids := map[string]bool{}
+		for _, it := range group {
+			ids[it.id] = true
+		}
+		for _, it := range group {
+			if it.previous != "" && ids[it.previous] {
+				canonical = it.previous
+				break
+			}
+		}
+		key := matchKey[canonical]
+		for _, it := range group {
+			if _, err := stmt.ExecContext(ctx, it.id, canonical, key, now); err != nil {
+				return 0, err
+			}
+		}
+		groups++
+	}
+	return groups, tx.Commit()
+}
+
+// CanonicalItemIDs maps every grouped library item to its group's canonical item.
+func CanonicalItemIDs(ctx context.Context, db *sql.DB) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT item_id, canonical_id FROM canonical_item`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]string{}
+	for rows.Next() {
+		var id, canonical string
+		if err := rows.Scan(&id, &canonical); err != nil {
+			return nil, err
+		}
+		out[id] = canonical
+	}
+	return out, rows.Err()
+}
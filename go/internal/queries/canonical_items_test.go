@@ -0,0 +1,55 @@
+package queries
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRebuildCanonicalItems(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	stmts := []string{
+		`UPDATE library_item SET provider_ids = 'imdb:tt1,tmdb:10', created_at = '2024-01-01' WHERE id = 'movie-a'`,
+		`INSERT INTO library_item (id, server_id, item_id, name, media_type, provider_ids, created_at)
+		 VALUES ('plex::9', 'plex', '9', 'Movie A', 'Movie', 'plex:abc,tmdb:10', '2024-02-01'),
+		        ('jf::5', 'jf', '5', 'Movie A', 'Movie', 'imdb:TT1', '2024-03-01'),
+		        ('plex::show', 'plex', 'show', 'Show', 'Series', 'tmdb:10', '2024-02-01'),
+		        ('plex::other', 'plex', 'other', 'Movie B', 'Movie', 'tmdb:11', '2024-02-01')`,
+	}
+	seed(t, conn, stmts...)
+
+	// movie-a matches plex::9 by tmdb and jf::5 by imdb (case-insensitively); the series
+	// with the same tmdb id is a different title
+	n, err := RebuildCanonicalItems(ctx, conn, 1)
+	if err != nil || n != 1 {
+		t.Fatalf("rebuild = %d, %v", n, err)
+	}
+	ids, err := CanonicalItemIDs(ctx, conn)
+	if err != nil {
+		t.Fatalf("canonical ids: %v", err)
+	}
+	want := map[string]string{"movie-a": "movie-a", "plex::9": "movie-a", "jf::5": "movie-a"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("canonical ids = %v", ids)
+	}
+
+	// The group keeps its canonical item when the first-added copy goes away and comes back
+	seed(t, conn, `UPDATE canonical_item SET canonical_id = 'jf::5'`)
+	if _, err := RebuildCanonicalItems(ctx, conn, 2); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	if ids, _ = CanonicalItemIDs(ctx, conn); ids["movie-a"] != "jf::5" || len(ids) != 3 {
+		t.Errorf("canonical ids after rebuild = %v", ids)
+	}
+
+	if _, err := conn.Exec(`DELETE FROM library_item WHERE id IN ('movie-a', 'plex::9')`); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if n, _ = RebuildCanonicalItems(ctx, conn, 3); n != 0 {
+		t.Errorf("a single copy left should not be a group, got %d groups", n)
+	}
+	if ids, _ = CanonicalItemIDs(ctx, conn); len(ids) != 0 {
+		t.Errorf("canonical ids = %v", ids)
+	}
+}
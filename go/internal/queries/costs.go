@@ -0,0 +1,169 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+)
+
+// bytesPerGB matches the GB figures of the library stats (binary gigabytes).
+const bytesPerGB = 1073741824.0
+
+// CostModel prices streaming. Rates are in Currency; zero rates cost nothing.
+type CostModel struct {
+	Currency string `json:"currency"`
+	// PerStreamHour covers running costs such as electricity per hour streamed
+	PerStreamHour float64 `json:"per_stream_hour"`
+	// PerGB is the bandwidth cost per GB delivered
+	PerGB float64 `json:"per_gb"`
+	// PerTranscodeGB is charged on top of PerGB for GB delivered from a transcode
+	PerTranscodeGB float64 `json:"per_transcode_gb"`
+	// MonthlyFixed (hardware, subscriptions) is split by each row's share of the month's hours
+	MonthlyFixed float64 `json:"monthly_fixed"`
+}
+
+// CostRow is one user's usage and attributed cost on one server in one month.
+type CostRow struct {
+	UserID        string  `json:"user_id"`
+	UserName      string  `json:"user_name"`
+	ServerID      string  `json:"server_id"`
+	Hours         float64 `json:"hours"`
+	GB            float64 `json:"gb"`           // estimated from the bitrate served
+	TranscodeGB   float64 `json:"transcode_gb"` // the part delivered from a transcode
+	StreamCost    float64 `json:"stream_cost"`
+	BandwidthCost float64 `json:"bandwidth_cost"`
+	TranscodeCost float64 `json:"transcode_cost"`
+	FixedShare    float64 `json:"fixed_share"`
+	Total         float64 `json:"total"`
+	SharePct      float64 `json:"share_pct"` // of the month's total cost
+}
+
+// CostMonth is the cost attribution of one local calendar month.
+type CostMonth struct {
+	Month string    `json:"month"` // YYYY-MM
+	Hours float64   `json:"hours"`
+	GB    float64   `json:"gb"`
+	Total float64   `json:"total"`
+	Rows  []CostRow `json:"rows"`
+}
+
+// CostAttribution prices the play intervals of the `months` local calendar months in loc
+// up to now (the current month so far) with model, per month, user and server. Intervals
+// count in the month they start in; GB are estimated from the session's average observed
+// bitrate, falling back to its transcode bitrate and then the item's bitrate. Every stream
+// counts, including live TV and users excluded from stats, since all of them load the
+// server. serverType (lower-case) or serverID optionally limit it to one server kind or
+// instance.
+func CostAttribution(ctx context.Context, db *sql.DB, months int, loc *time.Location, now time.Time, model CostModel, serverType, serverID string) ([]CostMonth, error) {
+	out := []CostMonth{}
+	if months <= 0 {
+		return out, nil
+	}
+	now = now.In(loc)
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc).AddDate(0, -(months - 1), 0)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT pi.start_ts, pi.duration_seconds, ps.user_id, COALESCE(u.name, ps.user_name, ps.user_id),
+		       COALESCE(ps.server_id, ''),
+		       COALESCE(NULLIF(ss.avg_bitrate_bps, 0), NULLIF(ps.transcode_bitrate, 0), NULLIF(li.bitrate_bps, 0), 0),
+		       CASE WHEN LOWER(COALESCE(ps.play_method, '')) LIKE 'transcode%' THEN 1 ELSE 0 END
+		FROM play_intervals pi
+		JOIN play_sessions ps ON ps.id = pi.session_fk
+		LEFT JOIN emby_user u ON u.id = ps.user_id
+		LEFT JOIN session_summaries ss ON ss.session_fk = ps.id
+		LEFT JOIN library_item li ON li.id = ps.item_id
+		WHERE pi.start_ts >= ? AND pi.start_ts <= ? AND pi.duration_seconds > 0
+		  AND (? = '' OR LOWER(COALESCE(ps.server_type, '')) = ?)
+		  AND (? = '' OR ps.server_id = ?)
+	`, first.Unix(), now.Unix(), serverType, serverType, serverID, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type rowKey struct{ month, user, server string }
+	usage := map[rowKey]*CostRow{}
+	for rows.Next() {
+		var start, seconds, bitrate int64
+		var transcode bool
+		var r CostRow
+		if err := rows.Scan(&start, &seconds, &r.UserID, &r.UserName, &r.ServerID, &bitrate, &transcode); err != nil {
+			return nil, err
+		}
+		k := rowKey{time.Unix(start, 0).In(loc).Format("2006-01"), r.UserID, r.ServerID}
+		acc := usage[k]
+		if acc == nil {
+			acc = &CostRow{UserID: r.UserID, UserName: r.UserName, ServerID: r.ServerID}
+			usage[k] = acc
+		}
+		acc.Hours += float64(seconds) / 3600
+		gb := float64(bitrate) * float64(seconds) / 8 / bytesPerGB
+		acc.GB += gb
+		if transcode {
+			acc.TranscodeGB += gb
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var ordered []*CostMonth
+	byMonth := map[string]*CostMonth{}
+	for m := first; !m.After(now); m = m.AddDate(0, 1, 0) {
+		cm := &CostMonth{Month: m.Format("2006-01"), Rows: []CostRow{}}
+		byMonth[cm.Month] = cm
+		ordered = append(ordered, cm)
+	}
+	for k, r := range usage {
+		if cm := byMonth[k.month]; cm != nil {
+			cm.Hours += r.Hours
+			cm.Rows = append(cm.Rows, *r)
+		}
+	}
+
+	for _, cm := range ordered {
+		for j := range cm.Rows {
+			r := &cm.Rows[j]
+			r.StreamCost = r.Hours * model.PerStreamHour
+			r.BandwidthCost = r.GB * model.PerGB
+			r.TranscodeCost = r.TranscodeGB * model.PerTranscodeGB
+			if cm.Hours > 0 {
+				r.FixedShare = model.MonthlyFixed * r.Hours / cm.Hours
+			}
+			r.Total = r.StreamCost + r.BandwidthCost + r.TranscodeCost + r.FixedShare
+			cm.GB += r.GB
+			cm.Total += r.Total
+		}
+		for j := range cm.Rows {
+			r := &cm.Rows[j]
+			if cm.Total > 0 {
+				r.SharePct = roundPercent(r.Total / cm.Total * 100)
+			}
+			r.Hours, r.GB, r.TranscodeGB = roundCost(r.Hours), roundCost(r.GB), roundCost(r.TranscodeGB)
+			r.StreamCost, r.BandwidthCost, r.TranscodeCost = roundCost(r.StreamCost), roundCost(r.BandwidthCost), roundCost(r.TranscodeCost)
+			r.FixedShare, r.Total = roundCost(r.FixedShare), roundCost(r.Total)
+		}
+		sort.Slice(cm.Rows, func(a, b int) bool {
+			ra, rb := cm.Rows[a], cm.Rows[b]
+			if ra.Total != rb.Total {
+				return ra.Total > rb.Total
+			}
+			if ra.Hours != rb.Hours {
+				return ra.Hours > rb.Hours
+			}
+			if ra.UserID != rb.UserID {
+				return ra.UserID < rb.UserID
+			}
+			return ra.ServerID < rb.ServerID
+		})
+		cm.Hours, cm.GB, cm.Total = roundCost(cm.Hours), roundCost(cm.GB), roundCost(cm.Total)
+		out = append(out, *cm)
+	}
+	return out, nil
+}
+
+func roundCost(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,60 @@
+package queries
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCostAttribution(t *testing.T) {
+	conn := openFixtureDB(t)
+	// ~1 GB per hour: alice's Movie A session observed, bob's Movie A session transcoded
+	stmts := []string{
+		`INSERT INTO session_summaries (session_fk, avg_bitrate_bps, finalized_at) VALUES (1, 2386093, 4600)`,
+		`UPDATE play_sessions SET play_method = 'Transcode', transcode_bitrate = 2386093 WHERE id = 2`,
+	}
+	seed(t, conn, stmts...)
+	model := CostModel{PerStreamHour: 0.1, PerGB: 1, PerTranscodeGB: 2, MonthlyFixed: 10}
+	now := time.Unix(9000, 0).UTC()
+
+	months, err := CostAttribution(context.Background(), conn, 1, time.UTC, now, model, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(months) != 1 || months[0].Month != "1970-01" {
+		t.Fatalf("months = %+v", months)
+	}
+	m := months[0]
+	if !approx(m.Hours, 4.5) || !approx(m.GB, 1.5) || !approx(m.Total, 12.95) || len(m.Rows) != 3 {
+		t.Fatalf("month = %+v", m)
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 0.011 }
+	want := []struct {
+		user                         string
+		hours, gb, tgb, fixed, total float64
+	}{
+		{"alice", 2, 1, 0, 4.44, 5.64},
+		{"carol", 2, 0, 0, 4.44, 4.64},
+		{"bob", 0.5, 0.5, 0.5, 1.11, 2.66},
+	}
+	for i, w := range want {
+		r := m.Rows[i]
+		if r.UserID != w.user || !near(r.Hours, w.hours) || !near(r.GB, w.gb) || !near(r.TranscodeGB, w.tgb) ||
+			!near(r.FixedShare, w.fixed) || !near(r.Total, w.total) {
+			t.Fatalf("row %d = %+v, want %+v", i, r, w)
+		}
+	}
+	if !near(m.Rows[2].TranscodeCost, 1) || !near(m.Rows[0].SharePct, 43.6) {
+		t.Fatalf("rows = %+v", m.Rows)
+	}
+
+	// the month of now comes last, empty months are kept and filters narrow the usage
+	months, err = CostAttribution(context.Background(), conn, 2, time.UTC, time.Date(1970, 2, 3, 0, 0, 0, 0, time.UTC), model, "jellyfin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(months) != 2 || months[1].Month != "1970-02" || len(months[0].Rows) != 0 || months[0].Total != 0 {
+		t.Fatalf("filtered months = %+v", months)
+	}
+}
@@ -0,0 +1,100 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Daily time series exposed to external dashboards.
+const (
+	SeriesWatchHours  = "watch_hours"
+	SeriesStreams     = "streams"
+	SeriesTranscodes  = "transcodes"
+	SeriesActiveUsers = "active_users"
+)
+
+// DailySeriesNames lists the available series.
+var DailySeriesNames = []string{SeriesWatchHours, SeriesStreams, SeriesTranscodes, SeriesActiveUsers}
+
+// SeriesPoint is one UTC day of a series; Day is the unix second the day starts.
+type SeriesPoint struct {
+	Day   int64   `json:"day"`
+	Value float64 `json:"value"`
+}
+
+// dailySeriesSQL selects (day start, value) for a series. Every query binds from, to,
+// serverType twice and serverID twice. Watch hours come from play intervals, the others
+// from sessions; users excluded from stats and live TV are left out.
+var dailySeriesSQL = map[string]string{
+	SeriesWatchHours: `
+		SELECT (pi.start_ts / 86400) * 86400 AS day, SUM(COALESCE(pi.duration_seconds, 0)) / 3600.0
+		FROM play_intervals pi
+		JOIN play_sessions ps ON ps.id = pi.session_fk
+		JOIN emby_user u ON u.id = pi.user_id AND u.deleted_at IS NULL AND u.exclude_from_stats = 0
+		LEFT JOIN library_item li ON li.id = pi.item_id
+		WHERE pi.start_ts >= ? AND pi.start_ts < ? AND %s
+		GROUP BY day`,
+	SeriesStreams: `
+		SELECT (ps.started_at / 86400) * 86400 AS day, COUNT(*)
+		FROM play_sessions ps
+		JOIN emby_user u ON u.id = ps.user_id AND u.deleted_at IS NULL AND u.exclude_from_stats = 0
+		LEFT JOIN library_item li ON li.id = ps.item_id
+		WHERE ps.started_at >= ? AND ps.started_at < ? AND %s
+		GROUP BY day`,
+	SeriesTranscodes: `
+		SELECT (ps.started_at / 86400) * 86400 AS day, COUNT(*)
+		FROM play_sessions ps
+		JOIN emby_user u ON u.id = ps.user_id AND u.deleted_at IS NULL AND u.exclude_from_stats = 0
+		LEFT JOIN library_item li ON li.id = ps.item_id
+		WHERE ps.started_at >= ? AND ps.started_at < ? AND %s
+		  AND 'transcode' IN (LOWER(COALESCE(ps.play_method, '')), LOWER(COALESCE(ps.video_method, '')), LOWER(COALESCE(ps.audio_method, '')))
+		GROUP BY day`,
+	SeriesActiveUsers: `
+		SELECT (ps.started_at / 86400) * 86400 AS day, COUNT(DISTINCT ps.user_id)
+		FROM play_sessions ps
+		JOIN emby_user u ON u.id = ps.user_id AND u.deleted_at IS NULL AND u.exclude_from_stats = 0
+		LEFT JOIN library_item li ON li.id = ps.item_id
+		WHERE ps.started_at >= ? AND ps.started_at < ? AND %s
+		GROUP BY day`,
+}
+
+const dailySeriesFilter = `COALESCE(li.media_type, 'Unknown') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
+		  AND (? = '' OR LOWER(COALESCE(ps.server_type, '')) = ?)
+		  AND (? = '' OR ps.server_id = ?)`
+
+// DailySeries returns one point per UTC day overlapping [from, to) (unix seconds), zero on
+// days without activity. serverType (lower-case) or serverID optionally limit it to one
+// server kind or instance.
+func DailySeries(ctx context.Context, db *sql.DB, name string, from, to int64, serverType, serverID string) ([]SeriesPoint, error) {
+	query, ok := dailySeriesSQL[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown series %q", name)
+	}
+	first := (from / 86400) * 86400
+	values := map[int64]float64{}
+	if to > from {
+		rows, err := db.QueryContext(ctx, fmt.Sprintf(query, dailySeriesFilter), first, to, serverType, serverType, serverID, serverID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var day int64
+			var v float64
+			if err := rows.Scan(&day, &v); err != nil {
+				return nil, err
+			}
+			values[day] = v
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	out := []SeriesPoint{}
+	for day := first; day < to; day += 86400 {
+		out = append(out, SeriesPoint{Day: day, Value: values[day]})
+	}
+	return out, nil
+}
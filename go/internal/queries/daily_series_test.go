@@ -0,0 +1,48 @@
+package queries
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDailySeries(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	seed(t, conn,
+		`INSERT INTO play_sessions (id, user_id, session_id, item_id, item_name, device_id, client_name, started_at, ended_at, is_active, play_method)
+		 VALUES (5, 'bob', 'se', 'movie-b', 'Movie B', 'd', 'Web', 86500, 88300, 0, 'Transcode')`,
+		`INSERT INTO play_intervals (session_fk, item_id, user_id, start_ts, end_ts, start_pos_ticks, end_pos_ticks, duration_seconds, seeked)
+		 VALUES (5, 'movie-b', 'bob', 86500, 88300, 0, 0, 1800, 0)`,
+	)
+
+	// Carol is excluded from stats and the channel is live TV
+	want := map[string][2]float64{
+		SeriesWatchHours:  {1.5, 0.5},
+		SeriesStreams:     {2, 1},
+		SeriesTranscodes:  {0, 1},
+		SeriesActiveUsers: {2, 1},
+	}
+	for _, name := range DailySeriesNames {
+		points, err := DailySeries(ctx, conn, name, 500, 2*86400, "", "")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(points) != 2 || points[0].Day != 0 || points[1].Day != 86400 {
+			t.Fatalf("%s: expected two UTC days, got %+v", name, points)
+		}
+		if !approx(points[0].Value, want[name][0]) || !approx(points[1].Value, want[name][1]) {
+			t.Errorf("%s = %+v, want %v", name, points, want[name])
+		}
+	}
+
+	points, err := DailySeries(ctx, conn, SeriesStreams, 0, 86400, "plex", "")
+	if err != nil {
+		t.Fatalf("plex streams: %v", err)
+	}
+	if len(points) != 1 || points[0].Value != 0 {
+		t.Errorf("expected an empty day for plex, got %+v", points)
+	}
+	if _, err := DailySeries(ctx, conn, "nope", 0, 86400, "", ""); err == nil {
+		t.Error("expected an error for an unknown series")
+	}
+}
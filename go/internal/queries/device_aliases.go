@@ -0,0 +1,210 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// DeviceGroup is a canonical device with every device id merged into it.
+type DeviceGroup struct {
+	CanonicalID string   `json:"canonical_id"`
+	Name        string   `json:"name,omitempty"`
+	DeviceIDs   []string `json:"device_ids"`
+}
+
+// DeviceInfo is one device id seen in playback history.
+type DeviceInfo struct {
+	DeviceID    string   `json:"device_id"`
+	CanonicalID string   `json:"canonical_id"`   // the device it is merged into, or itself
+	Name        string   `json:"name,omitempty"` // admin override for the merged group
+	Sessions    int      `json:"sessions"`
+	Users       int      `json:"users"`
+	Clients     []string `json:"clients"`
+	LastSeen    int64    `json:"last_seen"`
+}
+
+// ListDevices returns every device id with playback history, most recently used first.
+func ListDevices(ctx context.Context, db *sql.DB) ([]DeviceInfo, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT ps.device_id, COALESCE(MAX(da.canonical_id), ps.device_id), COALESCE(MAX(da.name), ''),
+		       COUNT(*), COUNT(DISTINCT ps.user_id), COALESCE(GROUP_CONCAT(DISTINCT NULLIF(ps.client_name, '')), ''),
+		       COALESCE(MAX(ps.started_at), 0)
+		FROM play_sessions ps
+		LEFT JOIN device_aliases da ON da.device_id = ps.device_id
+		WHERE COALESCE(ps.device_id, '') <> ''
+		GROUP BY ps.device_id
+		ORDER BY 7 DESC, 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []DeviceInfo{}
+	for rows.Next() {
+		var d DeviceInfo
+		var clients string
+		if err := rows.Scan(&d.DeviceID, &d.CanonicalID, &d.Name, &d.Sessions, &d.Users, &clients, &d.LastSeen); err != nil {
+			return nil, err
+		}
+		d.Clients = []string{}
+		if clients != "" {
+			d.Clients = strings.Split(clients, ",")
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// canonicalDevice returns the device deviceID is merged into, or deviceID itself.
+func canonicalDevice(ctx context.Context, q queryer, deviceID string) (string, error) {
+	var canonical string
+	err := q.QueryRowContext(ctx, `SELECT canonical_id FROM device_aliases WHERE device_id = ?`, deviceID).Scan(&canonical)
+	if err == sql.ErrNoRows {
+		return deviceID, nil
+	}
+	return canonical, err
+}
+
+func deviceGroup(ctx context.Context, q queryer, canonical string) (DeviceGroup, error) {
+	g := DeviceGroup{CanonicalID: canonical, DeviceIDs: []string{canonical}}
+	rows, err := q.QueryContext(ctx, `
+		SELECT device_id, COALESCE(name, '') FROM device_aliases WHERE canonical_id = ? ORDER BY device_id
+	`, canonical)
+	if err != nil {
+		return g, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return g, err
+		}
+		if name != "" {
+			g.Name = name
+		}
+		if id != canonical {
+			g.DeviceIDs = append(g.DeviceIDs, id)
+		}
+	}
+	return g, rows.Err()
+}
+
+// ResolveDevice returns the merged group deviceID belongs to; a device that was never
+// renamed or merged is a group of its own.
+func ResolveDevice(ctx context.Context, db *sql.DB, deviceID string) (DeviceGroup, error) {
+	canonical, err := canonicalDevice(ctx, db, deviceID)
+	if err != nil {
+		return DeviceGroup{}, err
+	}
+	return deviceGroup(ctx, db, canonical)
+}
+
+// RenameDevice sets the display name of the group deviceID belongs to; an empty name
+// clears the override.
+func RenameDevice(ctx context.Context, db *sql.DB, deviceID, name string, now int64) (DeviceGroup, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return DeviceGroup{}, err
+	}
+	defer tx.Rollback()
+	canonical, err := canonicalDevice(ctx, tx, deviceID)
+	if err != nil {
+		return DeviceGroup{}, err
+	}
+	if err := upsertCanonicalDevice(ctx, tx, canonical, now); err != nil {
+		return DeviceGroup{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE device_aliases SET name = NULLIF(?, ''), updated_at = ? WHERE canonical_id = ?`, name, now, canonical); err != nil {
+		return DeviceGroup{}, err
+	}
+	// A device that is neither named nor merged needs no row
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM device_aliases
+		WHERE device_id = ? AND name IS NULL AND NOT EXISTS (SELECT 1 FROM device_aliases WHERE canonical_id = ? AND device_id <> ?)
+	`, canonical, canonical, canonical); err != nil {
+		return DeviceGroup{}, err
+	}
+	g, err := deviceGroup(ctx, tx, canonical)
+	if err != nil {
+		return DeviceGroup{}, err
+	}
+	return g, tx.Commit()
+}
+
+// MergeDevices maps deviceIDs, along with any devices already merged into them, onto the
+// group of canonicalID. The group keeps its name unless name is given, falling back to
+// the name of a merged group.
+func MergeDevices(ctx context.Context, db *sql.DB, canonicalID string, deviceIDs []string, name string, now int64) (DeviceGroup, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return DeviceGroup{}, err
+	}
+	defer tx.Rollback()
+	canonical, err := canonicalDevice(ctx, tx, canonicalID)
+	if err != nil {
+		return DeviceGroup{}, err
+	}
+	g, err := deviceGroup(ctx, tx, canonical)
+	if err != nil {
+		return DeviceGroup{}, err
+	}
+	if name == "" {
+		name = g.Name
+	}
+	if err := upsertCanonicalDevice(ctx, tx, canonical, now); err != nil {
+		return DeviceGroup{}, err
+	}
+	for _, id := range deviceIDs {
+		root, err := canonicalDevice(ctx, tx, id)
+		if err != nil {
+			return DeviceGroup{}, err
+		}
+		if root == canonical {
+			continue
+		}
+		if name == "" {
+			other, err := deviceGroup(ctx, tx, root)
+			if err != nil {
+				return DeviceGroup{}, err
+			}
+			name = other.Name
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE device_aliases SET canonical_id = ?, updated_at = ? WHERE canonical_id = ?`, canonical, now, root); err != nil {
+			return DeviceGroup{}, err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO device_aliases (device_id, canonical_id, updated_at) VALUES (?, ?, ?)
+			ON CONFLICT(device_id) DO UPDATE SET canonical_id = excluded.canonical_id, updated_at = excluded.updated_at
+		`, id, canonical, now); err != nil {
+			return DeviceGroup{}, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE device_aliases SET name = NULLIF(?, ''), updated_at = ? WHERE canonical_id = ?`, name, now, canonical); err != nil {
+		return DeviceGroup{}, err
+	}
+	if g, err = deviceGroup(ctx, tx, canonical); err != nil {
+		return DeviceGroup{}, err
+	}
+	return g, tx.Commit()
+}
+
+// RemoveDeviceAlias takes deviceID out of its merged group. Removing the canonical device
+// dissolves the whole group and its name.
+func RemoveDeviceAlias(ctx context.Context, db *sql.DB, deviceID string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM device_aliases WHERE device_id = ? OR canonical_id = ?`, deviceID, deviceID)
+	return err
+}
+
+func upsertCanonicalDevice(ctx context.Context, tx *sql.Tx, canonical string, now int64) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO device_aliases (device_id, canonical_id, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(device_id) DO NOTHING
+	`, canonical, canonical, now)
+	return err
+}
@@ -0,0 +1,75 @@
+package queries
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDeviceAliases(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	seed(t, conn, `UPDATE play_sessions SET device_id = CASE id WHEN 1 THEN 'guid-1' WHEN 2 THEN 'guid-2' WHEN 3 THEN 'guid-3' ELSE 'guid-1' END`)
+
+	g, err := RenameDevice(ctx, conn, "guid-1", "Living Room TV", 1)
+	if err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if g.CanonicalID != "guid-1" || g.Name != "Living Room TV" || !reflect.DeepEqual(g.DeviceIDs, []string{"guid-1"}) {
+		t.Errorf("renamed group = %+v", g)
+	}
+
+	// guid-3 first absorbs guid-2, then the pair is merged into guid-1 by naming guid-2
+	if _, err := MergeDevices(ctx, conn, "guid-3", []string{"guid-2"}, "", 2); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	g, err = MergeDevices(ctx, conn, "guid-1", []string{"guid-2"}, "", 3)
+	if err != nil {
+		t.Fatalf("merge groups: %v", err)
+	}
+	if g.Name != "Living Room TV" || !reflect.DeepEqual(g.DeviceIDs, []string{"guid-1", "guid-2", "guid-3"}) {
+		t.Errorf("merged group = %+v", g)
+	}
+	if g, _ = ResolveDevice(ctx, conn, "guid-3"); g.CanonicalID != "guid-1" || len(g.DeviceIDs) != 3 {
+		t.Errorf("resolve guid-3 = %+v", g)
+	}
+
+	devices, err := ListDevices(ctx, conn)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(devices) != 3 || devices[0].DeviceID != "guid-1" || devices[0].Sessions != 2 || devices[0].LastSeen != 5000 {
+		t.Fatalf("devices = %+v", devices)
+	}
+	for _, d := range devices {
+		if d.CanonicalID != "guid-1" || d.Name != "Living Room TV" {
+			t.Errorf("device %s not in the merged group: %+v", d.DeviceID, d)
+		}
+	}
+
+	if err := RemoveDeviceAlias(ctx, conn, "guid-3"); err != nil {
+		t.Fatalf("remove member: %v", err)
+	}
+	if g, _ = ResolveDevice(ctx, conn, "guid-3"); g.CanonicalID != "guid-3" || g.Name != "" {
+		t.Errorf("guid-3 should stand alone, got %+v", g)
+	}
+	if err := RemoveDeviceAlias(ctx, conn, "guid-1"); err != nil {
+		t.Fatalf("remove group: %v", err)
+	}
+	if g, _ = ResolveDevice(ctx, conn, "guid-2"); g.CanonicalID != "guid-2" {
+		t.Errorf("removing the canonical device should dissolve the group, got %+v", g)
+	}
+
+	// Clearing the name of an unmerged device leaves no row behind
+	if _, err := RenameDevice(ctx, conn, "guid-2", "Phone", 4); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if _, err := RenameDevice(ctx, conn, "guid-2", "", 5); err != nil {
+		t.Fatalf("clear name: %v", err)
+	}
+	var n int
+	_ = conn.QueryRow(`SELECT COUNT(*) FROM device_aliases`).Scan(&n)
+	if n != 0 {
+		t.Errorf("expected no alias rows, got %d", n)
+	}
+}
@@ -0,0 +1,106 @@
+package queries
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// hotPathQueries are the statements served on every dashboard refresh. Each must reach
+// the large history tables through an index rather than a full scan.
+var hotPathQueries = []struct {
+	name string
+	sql  string
+	args []any
+}{
+	{"TopUsersByWatchSeconds", topUsersByWatchSecondsSQL, []any{int64(2), int64(1), int64(2), int64(1), "", "", "", "", 10}},
+	{"TopItemsByWatchSeconds", topItemsByWatchSecondsSQL, []any{int64(2), int64(1), int64(2), int64(1), 10}},
+	{"TopUsersBySessionCount", topUsersBySessionCountSQL, []any{int64(1), int64(2), "", "", "", "", 10}},
+	{"TopItemsBySessionCount", topItemsBySessionCountSQL, []any{int64(1), int64(2), 10}},
+}
+
+// scanGuardedTables are too large in real deployments to be scanned per request.
+var scanGuardedTables = []string{"play_intervals", "play_sessions"}
+
+func TestHotPathQueriesAvoidFullScans(t *testing.T) {
+	// No ANALYZE: without sqlite_stat1 the planner assumes large tables, which matches
+	// production far better than stats gathered from the tiny fixture would.
+	conn := openFixtureDB(t)
+
+	for _, q := range hotPathQueries {
+		t.Run(q.name, func(t *testing.T) {
+			rows, err := conn.QueryContext(context.Background(), "EXPLAIN QUERY PLAN "+q.sql, q.args...)
+			if err != nil {
+				t.Fatalf("explain: %v", err)
+			}
+			defer rows.Close()
+
+			var plan []string
+			for rows.Next() {
+				var id, parent, notused int
+				var detail string
+				if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+					t.Fatalf("scan plan: %v", err)
+				}
+				plan = append(plan, detail)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatalf("plan rows: %v", err)
+			}
+
+			for _, table := range scanGuardedTables {
+				names := tableRefs(q.sql, table)
+				for _, step := range plan {
+					if isFullScan(step, names) {
+						t.Errorf("full table scan of %s: %q\nplan:\n  %s", table, step, strings.Join(plan, "\n  "))
+					}
+				}
+			}
+		})
+	}
+}
+
+var tableRefRe = regexp.MustCompile(`(?i)\b(?:FROM|JOIN)\s+(\w+)(?:\s+(?:AS\s+)?(\w+))?`)
+
+// tableRefs returns the names SQLite may use for table in a query plan: the table itself
+// and every alias it is given in query.
+func tableRefs(query, table string) map[string]bool {
+	names := map[string]bool{table: true}
+	for _, m := range tableRefRe.FindAllStringSubmatch(query, -1) {
+		if !strings.EqualFold(m[1], table) || m[2] == "" {
+			continue
+		}
+		switch strings.ToUpper(m[2]) {
+		case "ON", "WHERE", "JOIN", "LEFT", "INNER", "GROUP", "ORDER", "LIMIT", "USING":
+			continue
+		}
+		names[m[2]] = true
+	}
+	return names
+}
+
+// isFullScan reports whether an EXPLAIN QUERY PLAN detail line scans one of names without
+// an index. Both the current ("SCAN l") and pre-3.36 ("SCAN TABLE play_intervals AS l")
+// formats are recognised; index scans are not flagged.
+func isFullScan(detail string, names map[string]bool) bool {
+	fields := strings.Fields(detail)
+	if len(fields) < 2 || fields[0] != "SCAN" {
+		return false
+	}
+	if fields[1] == "TABLE" {
+		fields = fields[2:]
+	} else {
+		fields = fields[1:]
+	}
+	matched := false
+	for i, f := range fields {
+		if f == "USING" {
+			return false
+		}
+		if i == 0 || (i == 2 && fields[1] == "AS") {
+			matched = matched || names[f]
+		}
+	}
+	return matched
+}
@@ -0,0 +1,205 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+)
+
+// Goal kinds
+const (
+	GoalMaxHours     = "max_hours"     // watch at most TargetHours per period
+	GoalMinHours     = "min_hours"     // watch at least TargetHours per period
+	GoalFinishSeries = "finish_series" // watch every episode of SeriesID by the end of the period
+)
+
+// goalNearLimitPercent is how much of a max_hours target may be used before the goal is at risk.
+const goalNearLimitPercent = 80
+
+// UserGoal is a personal watch goal owned by an app user.
+type UserGoal struct {
+	ID          int64    `json:"id"`
+	UserID      int64    `json:"user_id"`
+	Kind        string   `json:"kind"`
+	Period      string   `json:"period"` // "week" or "month"
+	TargetHours *float64 `json:"target_hours,omitempty"`
+	SeriesID    string   `json:"series_id,omitempty"`
+	Title       string   `json:"title"`
+	Notify      bool     `json:"notify"`
+	CreatedAt   int64    `json:"created_at"`
+	UpdatedAt   int64    `json:"updated_at"`
+}
+
+// GoalProgress is a goal's state in the current period.
+type GoalProgress struct {
+	PeriodKey string  `json:"period_key"`
+	Start     int64   `json:"start"`
+	End       int64   `json:"end"`
+	Elapsed   float64 `json:"elapsed"` // share of the period that has passed, 0..1
+	// Hours goals
+	WatchedHours float64 `json:"watched_hours"`
+	// finish_series goals
+	SeriesName      string `json:"series_name,omitempty"`
+	EpisodesWatched int    `json:"episodes_watched,omitempty"`
+	EpisodesTotal   int    `json:"episodes_total,omitempty"`
+	// Percent of the target reached; above 100 once a max_hours goal is exceeded
+	Percent float64 `json:"percent"`
+	Status  string  `json:"status"` // on_track, near_limit, exceeded, behind or achieved
+}
+
+// NudgeGoal is a goal with nudges enabled together with its owner's linked media user.
+type NudgeGoal struct {
+	UserGoal
+	Username    string
+	MediaUserID string
+}
+
+const userGoalColumns = `id, user_id, kind, period, target_hours, COALESCE(series_id, ''), COALESCE(title, ''), notify, created_at, updated_at`
+
+type goalScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanGoal(r goalScanner, extra ...any) (*UserGoal, error) {
+	var g UserGoal
+	var target sql.NullFloat64
+	dest := append([]any{&g.ID, &g.UserID, &g.Kind, &g.Period, &target, &g.SeriesID, &g.Title, &g.Notify, &g.CreatedAt, &g.UpdatedAt}, extra...)
+	if err := r.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if target.Valid {
+		v := target.Float64
+		g.TargetHours = &v
+	}
+	return &g, nil
+}
+
+// GetUserGoal loads a goal owned by the given app user.
+func GetUserGoal(ctx context.Context, db *sql.DB, id, userID int64) (*UserGoal, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+userGoalColumns+` FROM user_goals WHERE id = ? AND user_id = ?`, id, userID)
+	return scanGoal(row)
+}
+
+// ListUserGoals returns the app user's goals, oldest first.
+func ListUserGoals(ctx context.Context, db *sql.DB, userID int64) ([]UserGoal, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+userGoalColumns+` FROM user_goals WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []UserGoal{}
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *g)
+	}
+	return out, rows.Err()
+}
+
+// ListNudgeGoals returns goals with nudges enabled whose owner is linked to a media user.
+func ListNudgeGoals(ctx context.Context, db *sql.DB) ([]NudgeGoal, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT g.id, g.user_id, g.kind, g.period, g.target_hours, COALESCE(g.series_id, ''), COALESCE(g.title, ''),
+		       g.notify, g.created_at, g.updated_at, u.username, u.media_user_id
+		FROM user_goals g
+		JOIN app_user u ON u.id = g.user_id
+		WHERE g.notify = 1 AND COALESCE(u.media_user_id, '') <> ''
+		ORDER BY g.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []NudgeGoal
+	for rows.Next() {
+		var n NudgeGoal
+		g, err := scanGoal(rows, &n.Username, &n.MediaUserID)
+		if err != nil {
+			return nil, err
+		}
+		n.UserGoal = *g
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// GoalPeriodBounds returns the local start and end of the week (Monday based) or calendar
+// month containing now, and a key identifying that period.
+func GoalPeriodBounds(period string, now time.Time) (start, end time.Time, key string) {
+	if period == "month" {
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0), "month:" + start.Format("2006-01-02")
+	}
+	_, start = QuotaPeriodStarts(now)
+	return start, start.AddDate(0, 0, 7), "week:" + start.Format("2006-01-02")
+}
+
+// GetGoalProgress computes a goal's progress in the current period from the linked media
+// user's history: play intervals for hour goals (Live TV excluded), finished sessions for
+// finish_series.
+func GetGoalProgress(ctx context.Context, db *sql.DB, g UserGoal, mediaUserID string, now time.Time) (*GoalProgress, error) {
+	start, end, key := GoalPeriodBounds(g.Period, now)
+	p := &GoalProgress{PeriodKey: key, Start: start.Unix(), End: end.Unix()}
+	p.Elapsed = math.Min(1, math.Max(0, now.Sub(start).Seconds()/end.Sub(start).Seconds()))
+
+	switch g.Kind {
+	case GoalFinishSeries:
+		err := db.QueryRowContext(ctx, `
+			SELECT COALESCE(MAX(li.series_name), ''),
+			       COUNT(DISTINCT li.id),
+			       COUNT(DISTINCT CASE WHEN EXISTS (
+			           SELECT 1 FROM play_sessions ps
+			           WHERE ps.item_id = li.id AND ps.user_id = ? AND ps.ended_at IS NOT NULL
+			       ) THEN li.id END)
+			FROM library_item li
+			WHERE li.series_id = ? AND LOWER(COALESCE(li.media_type, '')) = 'episode'
+		`, mediaUserID, g.SeriesID).Scan(&p.SeriesName, &p.EpisodesTotal, &p.EpisodesWatched)
+		if err != nil {
+			return nil, err
+		}
+		if p.EpisodesTotal > 0 {
+			p.Percent = roundPercent(float64(p.EpisodesWatched) / float64(p.EpisodesTotal) * 100)
+		}
+	default:
+		minutes, err := UserWatchMinutesSince(ctx, db, mediaUserID, start.Unix())
+		if err != nil {
+			return nil, err
+		}
+		p.WatchedHours = math.Round(minutes/60*100) / 100
+		if g.TargetHours != nil && *g.TargetHours > 0 {
+			p.Percent = roundPercent(minutes / 60 / *g.TargetHours * 100)
+		}
+	}
+	p.Status = goalStatus(g.Kind, p.Percent, p.Elapsed)
+	return p, nil
+}
+
+// goalStatus classifies progress: limits are at risk from goalNearLimitPercent, while
+// targets fall behind when they trail the share of the period that has passed.
+func goalStatus(kind string, percent, elapsed float64) string {
+	if kind == GoalMaxHours {
+		switch {
+		case percent > 100:
+			return "exceeded"
+		case percent >= goalNearLimitPercent:
+			return "near_limit"
+		}
+		return "on_track"
+	}
+	switch {
+	case percent >= 100:
+		return "achieved"
+	case percent < elapsed*100:
+		return "behind"
+	}
+	return "on_track"
+}
+
+func roundPercent(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,65 @@
+package queries
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGoalProgress(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	seed(t, conn,
+		`INSERT INTO library_item (id, server_id, item_id, name, media_type, series_id, series_name)
+		 VALUES ('ep-1', 's1', 'ep-1', 'Show - Pilot', 'Episode', 'show', 'Show'),
+		        ('ep-2', 's1', 'ep-2', 'Show - Finale', 'Episode', 'show', 'Show')`,
+		`INSERT INTO play_sessions (user_id, session_id, item_id, item_name, device_id, client_name, started_at, ended_at, is_active)
+		 VALUES ('bob', 'se', 'ep-1', 'Show - Pilot', 'd', 'Web', 5000, 6000, 0)`)
+	// Thursday 1970-01-01 12:00 UTC: the week started on Monday 1969-12-29, so 3.5 of 7 days have passed.
+	now := time.Unix(12*3600, 0).UTC()
+	hours := 1.0
+
+	p, err := GetGoalProgress(ctx, conn, UserGoal{Kind: GoalMinHours, Period: "week", TargetHours: &hours}, "bob", now)
+	if err != nil {
+		t.Fatalf("min_hours: %v", err)
+	}
+	if p.PeriodKey != "week:1969-12-29" || !approx(p.WatchedHours, 0.5) || !approx(p.Percent, 50) || p.Status != "on_track" {
+		t.Errorf("expected bob on track at 0.5h of 1h, got %+v", p)
+	}
+
+	p, err = GetGoalProgress(ctx, conn, UserGoal{Kind: GoalMaxHours, Period: "month", TargetHours: &hours}, "bob", now)
+	if err != nil {
+		t.Fatalf("max_hours: %v", err)
+	}
+	if p.PeriodKey != "month:1970-01-01" || p.Status != "on_track" {
+		t.Errorf("expected a January period with bob under his limit, got %+v", p)
+	}
+
+	p, err = GetGoalProgress(ctx, conn, UserGoal{Kind: GoalFinishSeries, Period: "month", SeriesID: "show"}, "bob", now)
+	if err != nil {
+		t.Fatalf("finish_series: %v", err)
+	}
+	if p.SeriesName != "Show" || p.EpisodesWatched != 1 || p.EpisodesTotal != 2 || !approx(p.Percent, 50) {
+		t.Errorf("expected 1 of 2 episodes of Show, got %+v", p)
+	}
+}
+
+func TestGoalStatus(t *testing.T) {
+	cases := []struct {
+		kind             string
+		percent, elapsed float64
+		want             string
+	}{
+		{GoalMaxHours, 50, 0.9, "on_track"},
+		{GoalMaxHours, 85, 0.1, "near_limit"},
+		{GoalMaxHours, 120, 0.5, "exceeded"},
+		{GoalMinHours, 40, 0.5, "behind"},
+		{GoalMinHours, 60, 0.5, "on_track"},
+		{GoalFinishSeries, 100, 0.2, "achieved"},
+	}
+	for _, c := range cases {
+		if got := goalStatus(c.kind, c.percent, c.elapsed); got != c.want {
+			t.Errorf("goalStatus(%s, %v, %v) = %s, want %s", c.kind, c.percent, c.elapsed, got, c.want)
+		}
+	}
+}
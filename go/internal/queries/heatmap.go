@@ -0,0 +1,96 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+)
+
+// WatchHeatmap is watch time by local day of the week and hour of the day.
+type WatchHeatmap struct {
+	// Seconds is indexed by weekday (0 = Sunday, as time.Weekday) and hour (0-23)
+	Seconds    [7][24]int64 `json:"seconds"`
+	DayTotals  [7]int64     `json:"day_totals"`
+	HourTotals [24]int64    `json:"hour_totals"`
+	// MaxSeconds is the busiest cell, for scaling a heatmap's colours
+	MaxSeconds int64   `json:"max_seconds"`
+	TotalHours float64 `json:"total_hours"`
+}
+
+// WatchHeatmapStats spreads the watch time of the play intervals overlapping the last days
+// local calendar days in loc up to now over the weekday and hour they were watched in.
+// Paused time is left out by scaling each interval's wall-clock span down to its recorded
+// duration. Live TV and users excluded from stats don't count. userID, serverType
+// (lower-case) and serverID optionally narrow it down.
+func WatchHeatmapStats(ctx context.Context, db *sql.DB, days int, loc *time.Location, now time.Time, userID, serverType, serverID string) (WatchHeatmap, error) {
+	var out WatchHeatmap
+	if days <= 0 {
+		return out, nil
+	}
+	now = now.In(loc)
+	first := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, -(days - 1))
+	winStart, winEnd := first.Unix(), now.Unix()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT l.start_ts, l.end_ts, COALESCE(l.duration_seconds, 0)
+		FROM play_intervals l
+		JOIN emby_user u ON u.id = l.user_id AND u.deleted_at IS NULL AND u.exclude_from_stats = 0
+		LEFT JOIN library_item li ON li.id = l.item_id
+		LEFT JOIN play_sessions ps ON ps.id = l.session_fk
+		WHERE l.start_ts < ? AND l.end_ts > ?
+		  AND COALESCE(li.media_type, 'Unknown') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
+		  AND (? = '' OR l.user_id = ?)
+		  AND (? = '' OR LOWER(COALESCE(ps.server_type, '')) = ?)
+		  AND (? = '' OR ps.server_id = ?)
+	`, winEnd, winStart, userID, userID, serverType, serverType, serverID, serverID)
+	if err != nil {
+		return out, err
+	}
+	defer rows.Close()
+
+	var cells [7][24]float64
+	for rows.Next() {
+		var start, end, duration int64
+		if err := rows.Scan(&start, &end, &duration); err != nil {
+			return out, err
+		}
+		wall := end - start
+		if wall <= 0 {
+			continue
+		}
+		// Share of the span actually spent playing
+		scale := 1.0
+		if duration > 0 && duration < wall {
+			scale = float64(duration) / float64(wall)
+		}
+		from, to := max(start, winStart), min(end, winEnd)
+		for t := from; t < to; {
+			lt := time.Unix(t, 0).In(loc)
+			next := time.Date(lt.Year(), lt.Month(), lt.Day(), lt.Hour()+1, 0, 0, 0, loc).Unix()
+			if next <= t {
+				next = t + 3600 // guard against DST oddities
+			}
+			piece := min(next, to) - t
+			cells[lt.Weekday()][lt.Hour()] += float64(piece) * scale
+			t += piece
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return out, err
+	}
+
+	var total int64
+	for d := range cells {
+		for h, v := range cells[d] {
+			s := int64(math.Round(v))
+			out.Seconds[d][h] = s
+			out.DayTotals[d] += s
+			out.HourTotals[h] += s
+			out.MaxSeconds = max(out.MaxSeconds, s)
+			total += s
+		}
+	}
+	out.TotalHours = roundPercent(float64(total) / 3600)
+	return out, nil
+}
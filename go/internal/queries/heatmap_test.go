@@ -0,0 +1,44 @@
+package queries
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatchHeatmapStats(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	now := time.Unix(43200, 0) // noon on Thursday 1970-01-01 UTC
+
+	heat, err := WatchHeatmapStats(ctx, conn, 1, time.UTC, now, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// alice watches fully, bob's span is half paused; carol and live TV don't count
+	thu := heat.Seconds[time.Thursday]
+	if thu[0] != 3900 || thu[1] != 1500 || thu[2] != 0 {
+		t.Fatalf("thursday = %v", thu[:3])
+	}
+	if heat.DayTotals[time.Thursday] != 5400 || heat.HourTotals[0] != 3900 || heat.MaxSeconds != 3900 || !approx(heat.TotalHours, 1.5) {
+		t.Errorf("totals = %+v", heat)
+	}
+
+	bob, err := WatchHeatmapStats(ctx, conn, 1, time.UTC, now, "bob", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bob.Seconds[time.Thursday][0] != 1300 || bob.Seconds[time.Thursday][1] != 500 {
+		t.Errorf("bob = %v", bob.Seconds[time.Thursday][:2])
+	}
+
+	// Hours follow the requested zone
+	east := time.FixedZone("UTC+2", 2*3600)
+	shifted, err := WatchHeatmapStats(ctx, conn, 1, east, now, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shifted.Seconds[time.Thursday][2] != 3900 || shifted.Seconds[time.Thursday][3] != 1500 {
+		t.Errorf("shifted = %v", shifted.Seconds[time.Thursday][:4])
+	}
+}
@@ -0,0 +1,106 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// PaletteTarget is a poster whose palette hasn't been extracted yet.
+type PaletteTarget struct {
+	ServerID string `json:"server_id"`
+	ItemID   string `json:"item_id"` // the server's own id
+}
+
+// LoadItemPalette returns the stored palette of a server item's poster.
+func LoadItemPalette(ctx context.Context, db *sql.DB, serverID, itemID string) ([]string, error) {
+	var colors string
+	err := db.QueryRowContext(ctx, `SELECT colors FROM item_palettes WHERE server_id = ? AND item_id = ?`, serverID, itemID).Scan(&colors)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return splitPalette(colors), nil
+}
+
+// SaveItemPalette stores the palette of a server item's poster.
+func SaveItemPalette(ctx context.Context, db *sql.DB, serverID, itemID string, palette []string, now int64) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO item_palettes (server_id, item_id, colors, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(server_id, item_id) DO UPDATE SET colors = excluded.colors, updated_at = excluded.updated_at
+	`, serverID, itemID, strings.Join(palette, ","), now)
+	return err
+}
+
+// ItemPalettes returns the palettes of library items by their stored id. Episodes use
+// their series poster's palette, falling back to their own; items without one are left out.
+func ItemPalettes(ctx context.Context, db *sql.DB, ids []string) (map[string][]string, error) {
+	out := map[string][]string{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT li.id, COALESCE(sp.colors, p.colors)
+		FROM library_item li
+		LEFT JOIN item_palettes p ON p.server_id = li.server_id AND p.item_id = COALESCE(NULLIF(li.item_id, ''), li.id)
+		LEFT JOIN item_palettes sp ON sp.server_id = li.server_id AND sp.item_id = li.series_id AND li.media_type = 'Episode'
+		WHERE li.id IN (`+placeholders+`) AND COALESCE(sp.colors, p.colors) IS NOT NULL
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, colors string
+		if err := rows.Scan(&id, &colors); err != nil {
+			return nil, err
+		}
+		out[id] = splitPalette(colors)
+	}
+	return out, rows.Err()
+}
+
+// MissingItemPalettes lists up to limit posters without a stored palette: movies and
+// series, with episodes standing in for their series.
+func MissingItemPalettes(ctx context.Context, db *sql.DB, limit int) ([]PaletteTarget, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT DISTINCT t.server_id, t.item_id
+		FROM (
+			SELECT li.server_id,
+			       CASE WHEN li.media_type = 'Episode' THEN li.series_id ELSE COALESCE(NULLIF(li.item_id, ''), li.id) END AS item_id
+			FROM library_item li
+			WHERE li.media_type IN ('Movie', 'Series', 'Episode') AND COALESCE(li.server_id, '') <> ''
+		) t
+		WHERE COALESCE(t.item_id, '') <> ''
+		  AND NOT EXISTS (SELECT 1 FROM item_palettes p WHERE p.server_id = t.server_id AND p.item_id = t.item_id)
+		ORDER BY t.server_id, t.item_id
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []PaletteTarget{}
+	for rows.Next() {
+		var t PaletteTarget
+		if err := rows.Scan(&t.ServerID, &t.ItemID); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func splitPalette(colors string) []string {
+	if colors == "" {
+		return nil
+	}
+	return strings.Split(colors, ",")
+}
@@ -0,0 +1,51 @@
+package queries
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestItemPalettes(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	seed(t, conn, `INSERT INTO library_item (id, server_id, item_id, name, media_type, series_id)
+		VALUES ('ep-1', 's1', 'ep-1', 'Pilot', 'Episode', 'show-1')`)
+
+	missing, err := MissingItemPalettes(ctx, conn, 10)
+	if err != nil {
+		t.Fatalf("missing: %v", err)
+	}
+	want := []PaletteTarget{{"s1", "movie-a"}, {"s1", "movie-b"}, {"s1", "show-1"}}
+	if !reflect.DeepEqual(missing, want) {
+		t.Fatalf("missing = %+v, want %+v", missing, want)
+	}
+
+	if err := SaveItemPalette(ctx, conn, "s1", "movie-a", []string{"#112233", "#445566"}, 1); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := SaveItemPalette(ctx, conn, "s1", "show-1", []string{"#abcdef"}, 1); err != nil {
+		t.Fatalf("save series: %v", err)
+	}
+	if err := SaveItemPalette(ctx, conn, "s1", "movie-a", []string{"#010203"}, 2); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if p, err := LoadItemPalette(ctx, conn, "s1", "movie-a"); err != nil || !reflect.DeepEqual(p, []string{"#010203"}) {
+		t.Errorf("load = %v, %v", p, err)
+	}
+	if p, err := LoadItemPalette(ctx, conn, "s1", "nope"); err != nil || p != nil {
+		t.Errorf("expected no palette, got %v, %v", p, err)
+	}
+
+	palettes, err := ItemPalettes(ctx, conn, []string{"movie-a", "movie-b", "ep-1"})
+	if err != nil {
+		t.Fatalf("palettes: %v", err)
+	}
+	if len(palettes) != 2 || palettes["movie-a"][0] != "#010203" || palettes["ep-1"][0] != "#abcdef" {
+		t.Errorf("palettes = %v", palettes)
+	}
+
+	if missing, _ = MissingItemPalettes(ctx, conn, 10); len(missing) != 1 || missing[0].ItemID != "movie-b" {
+		t.Errorf("expected only movie-b missing, got %+v", missing)
+	}
+}
@@ -0,0 +1,294 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// compareProviders are the external ids trusted to identify the same title on different
+// servers; other keys (collections, plex:// guids) are server specific.
+var compareProviders = map[string]bool{"imdb": true, "tmdb": true, "tvdb": true}
+
+// episodeDisplayName matches the "Series - Episode (S01E02)" names library syncs give episodes.
+var episodeDisplayName = regexp.MustCompile(`^(.*) - (.*?)(?: \(S\d+E\d+\))?$`)
+
+// FormatProviderIDs encodes external ids for library_item.provider_ids as sorted
+// "provider:id" pairs, e.g. "imdb:tt0111161,tmdb:278". It returns "" for no ids.
+func FormatProviderIDs(ids map[string]string) string {
+	pairs := make([]string, 0, len(ids))
+	for provider, id := range ids {
+		provider, id = strings.ToLower(strings.TrimSpace(provider)), strings.TrimSpace(id)
+		if provider == "" || id == "" {
+			continue
+		}
+		pairs = append(pairs, provider+":"+id)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// ParseProviderIDs decodes a library_item.provider_ids value.
+func ParseProviderIDs(s string) map[string]string {
+	var out map[string]string
+	for _, pair := range strings.Split(s, ",") {
+		provider, id, ok := strings.Cut(pair, ":")
+		if !ok || provider == "" || id == "" {
+			continue
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[provider] = id
+	}
+	return out
+}
+
+// LibraryCompareItem is a movie or episode on one side of a library comparison.
+type LibraryCompareItem struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	MediaType   string            `json:"media_type"`
+	SeriesName  string            `json:"series_name,omitempty"`
+	Year        int               `json:"year,omitempty"`
+	Width       int               `json:"width,omitempty"`
+	Height      int               `json:"height,omitempty"`
+	Resolution  string            `json:"resolution"`
+	ProviderIDs map[string]string `json:"provider_ids,omitempty"`
+}
+
+// LibraryResolutionDiff is a title both servers have, in different resolutions.
+type LibraryResolutionDiff struct {
+	A LibraryCompareItem `json:"a"`
+	B LibraryCompareItem `json:"b"`
+	// MatchedBy is "provider" or "title"
+	MatchedBy string `json:"matched_by"`
+}
+
+// LibraryComparison lists what one server's library has that the other's lacks. The
+// counts cover every difference; the lists are cut to the requested limit.
+type LibraryComparison struct {
+	ServerA              string                  `json:"server_a"`
+	ServerB              string                  `json:"server_b"`
+	ItemsA               int                     `json:"items_a"`
+	ItemsB               int                     `json:"items_b"`
+	Matched              int                     `json:"matched"`
+	MatchedByTitle       int                     `json:"matched_by_title"`
+	OnlyInACount         int                     `json:"only_in_a_count"`
+	OnlyInBCount         int                     `json:"only_in_b_count"`
+	ResolutionDiffsCount int                     `json:"resolution_diffs_count"`
+	OnlyInA              []LibraryCompareItem    `json:"only_in_a"`
+	OnlyInB              []LibraryCompareItem    `json:"only_in_b"`
+	ResolutionDiffs      []LibraryResolutionDiff `json:"resolution_diffs"`
+}
+
+// compareResolution buckets by width like the quality stats, falling back to height.
+func compareResolution(width, height int) string {
+	switch {
+	case width > 3840:
+		return "8K"
+	case width > 1920:
+		return "4K"
+	case width > 1280:
+		return "1080p"
+	case width >= 1200:
+		return "720p"
+	case width > 0:
+		return "SD"
+	case height >= 4320:
+		return "8K"
+	case height >= 2160:
+		return "4K"
+	case height >= 1080:
+		return "1080p"
+	case height >= 720:
+		return "720p"
+	case height > 0:
+		return "SD"
+	}
+	return "Unknown"
+}
+
+// normalizeTitle lower-cases a title and keeps only its letters and digits, so
+// "Spider-Man: No Way Home" and "Spider Man No Way Home" compare equal.
+func normalizeTitle(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// compareTitleKey is the fallback identity of an item without a shared provider id:
+// title and year for movies, series and episode title for episodes.
+func compareTitleKey(it LibraryCompareItem) string {
+	if it.MediaType != "Episode" {
+		name := normalizeTitle(it.Name)
+		if name == "" {
+			return ""
+		}
+		return it.MediaType + "|" + name + "|" + strconv.Itoa(it.Year)
+	}
+	series, episode := it.SeriesName, it.Name
+	if m := episodeDisplayName.FindStringSubmatch(it.Name); m != nil && (series == "" || strings.EqualFold(m[1], series)) {
+		series, episode = m[1], m[2]
+	}
+	series, episode = normalizeTitle(series), normalizeTitle(episode)
+	if series == "" || episode == "" {
+		return ""
+	}
+	return "Episode|" + series + "|" + episode
+}
+
+// compareProviderKeys are the provider ids an item can be matched on.
+func compareProviderKeys(it LibraryCompareItem) []string {
+	var keys []string
+	for provider, id := range it.ProviderIDs {
+		if compareProviders[provider] {
+			keys = append(keys, it.MediaType+"|"+provider+":"+strings.ToLower(id))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func loadCompareItems(ctx context.Context, db *sql.DB, serverID, mediaType string) ([]LibraryCompareItem, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, COALESCE(name, ''), media_type, COALESCE(series_name, ''), COALESCE(production_year, 0),
+		       COALESCE(width, 0), COALESCE(height, 0), COALESCE(provider_ids, '')
+		FROM library_item
+		WHERE server_id = ? AND media_type IN ('Movie', 'Episode') AND (? = '' OR media_type = ?)
+		ORDER BY media_type, name, id
+	`, serverID, mediaType, mediaType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []LibraryCompareItem
+	for rows.Next() {
+		var it LibraryCompareItem
+		var providers string
+		if err := rows.Scan(&it.ID, &it.Name, &it.MediaType, &it.SeriesName, &it.Year,
+			&it.Width, &it.Height, &providers); err != nil {
+			return nil, err
+		}
+		it.Resolution = compareResolution(it.Width, it.Height)
+		it.ProviderIDs = ParseProviderIDs(providers)
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// CompareLibraries matches the movies and episodes of two servers, first by a shared imdb,
+// tmdb or tvdb id and then by normalized title (and year for movies), and returns the items
+// each server lacks plus the matched items whose resolution differs. mediaType optionally
+// limits the comparison to "Movie" or "Episode"; limit caps each list (0 for no cap).
+func CompareLibraries(ctx context.Context, db *sql.DB, serverA, serverB, mediaType string, limit int) (LibraryComparison, error) {
+	out := LibraryComparison{
+		ServerA:         serverA,
+		ServerB:         serverB,
+		OnlyInA:         []LibraryCompareItem{},
+		OnlyInB:         []LibraryCompareItem{},
+		ResolutionDiffs: []LibraryResolutionDiff{},
+	}
+	itemsA, err := loadCompareItems(ctx, db, serverA, mediaType)
+	if err != nil {
+		return out, err
+	}
+	itemsB, err := loadCompareItems(ctx, db, serverB, mediaType)
+	if err != nil {
+		return out, err
+	}
+	out.ItemsA, out.ItemsB = len(itemsA), len(itemsB)
+
+	matchA := make([]int, len(itemsA))
+	for i := range matchA {
+		matchA[i] = -1
+	}
+	matchedB := make([]bool, len(itemsB))
+	byTitle := make([]bool, len(itemsA))
+
+	// Provider ids are matched for every item before titles, so a title match can't
+	// take an item that has an exact match elsewhere
+	passes := []struct {
+		keys    func(LibraryCompareItem) []string
+		byTitle bool
+	}{
+		{keys: compareProviderKeys},
+		{keys: func(it LibraryCompareItem) []string {
+			if k := compareTitleKey(it); k != "" {
+				return []string{k}
+			}
+			return nil
+		}, byTitle: true},
+	}
+	for _, pass := range passes {
+		index := map[string][]int{}
+		for j, it := range itemsB {
+			if matchedB[j] {
+				continue
+			}
+			for _, k := range pass.keys(it) {
+				index[k] = append(index[k], j)
+			}
+		}
+		for i, it := range itemsA {
+			if matchA[i] >= 0 {
+				continue
+			}
+		keys:
+			for _, k := range pass.keys(it) {
+				for _, j := range index[k] {
+					if !matchedB[j] {
+						matchA[i], matchedB[j], byTitle[i] = j, true, pass.byTitle
+						break keys
+					}
+				}
+			}
+		}
+	}
+
+	for i, it := range itemsA {
+		j := matchA[i]
+		if j < 0 {
+			out.OnlyInACount++
+			if limit <= 0 || len(out.OnlyInA) < limit {
+				out.OnlyInA = append(out.OnlyInA, it)
+			}
+			continue
+		}
+		out.Matched++
+		if byTitle[i] {
+			out.MatchedByTitle++
+		}
+		other := itemsB[j]
+		if it.Resolution == "Unknown" || other.Resolution == "Unknown" || it.Resolution == other.Resolution {
+			continue
+		}
+		out.ResolutionDiffsCount++
+		if limit <= 0 || len(out.ResolutionDiffs) < limit {
+			diff := LibraryResolutionDiff{A: it, B: other, MatchedBy: "provider"}
+			if byTitle[i] {
+				diff.MatchedBy = "title"
+			}
+			out.ResolutionDiffs = append(out.ResolutionDiffs, diff)
+		}
+	}
+	for j, it := range itemsB {
+		if matchedB[j] {
+			continue
+		}
+		out.OnlyInBCount++
+		if limit <= 0 || len(out.OnlyInB) < limit {
+			out.OnlyInB = append(out.OnlyInB, it)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,66 @@
+package queries
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCompareLibraries(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+
+	stmts := []string{
+		`DELETE FROM library_item`,
+		`INSERT INTO library_item (id, server_id, item_id, name, media_type, production_year, width, height, provider_ids, series_name)
+		 VALUES ('e1', 'emby', 'e1', 'Heat', 'Movie', 1995, 3840, 2160, 'imdb:tt0113277,tmdb:949', NULL),
+		        ('e2', 'emby', 'e2', 'Spider-Man: No Way Home', 'Movie', 2021, 1920, 1080, NULL, NULL),
+		        ('e3', 'emby', 'e3', 'Alien', 'Movie', 1979, 1920, 1080, 'imdb:tt0078748', NULL),
+		        ('e4', 'emby', 'e4', 'Show - Pilot (S01E01)', 'Episode', NULL, 1920, 1080, NULL, 'Show'),
+		        ('j1', 'jelly', 'j1', 'Heat (1995)', 'Movie', 1995, 1920, 1080, 'tmdb:949', NULL),
+		        ('j2', 'jelly', 'j2', 'Spider Man No Way Home', 'Movie', 2021, 1920, 1080, NULL, NULL),
+		        ('j3', 'jelly', 'j3', 'Alien', 'Movie', 1986, 1920, 1080, NULL, NULL),
+		        ('j4', 'jelly', 'j4', 'Pilot', 'Episode', NULL, 1280, 720, NULL, 'Show')`,
+	}
+	seed(t, conn, stmts...)
+
+	cmp, err := CompareLibraries(ctx, conn, "emby", "jelly", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmp.ItemsA != 4 || cmp.ItemsB != 4 || cmp.Matched != 3 || cmp.MatchedByTitle != 2 {
+		t.Fatalf("counts = %+v", cmp)
+	}
+	// The two Alien entries differ in year and share no provider id
+	if cmp.OnlyInACount != 1 || cmp.OnlyInA[0].ID != "e3" || cmp.OnlyInBCount != 1 || cmp.OnlyInB[0].ID != "j3" {
+		t.Fatalf("only in = %+v / %+v", cmp.OnlyInA, cmp.OnlyInB)
+	}
+	if cmp.ResolutionDiffsCount != 2 {
+		t.Fatalf("resolution diffs = %+v", cmp.ResolutionDiffs)
+	}
+	for _, d := range cmp.ResolutionDiffs {
+		switch d.A.ID {
+		case "e1":
+			if d.B.ID != "j1" || d.MatchedBy != "provider" || d.A.Resolution != "4K" || d.B.Resolution != "1080p" {
+				t.Errorf("heat diff = %+v", d)
+			}
+		case "e4":
+			if d.B.ID != "j4" || d.MatchedBy != "title" || d.B.Resolution != "720p" {
+				t.Errorf("episode diff = %+v", d)
+			}
+		default:
+			t.Errorf("unexpected diff %+v", d)
+		}
+	}
+
+	cmp, err = CompareLibraries(ctx, conn, "emby", "jelly", "Movie", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmp.ItemsA != 3 || cmp.ResolutionDiffsCount != 1 || len(cmp.ResolutionDiffs) != 1 {
+		t.Fatalf("movie-only comparison = %+v", cmp)
+	}
+
+	if got := FormatProviderIDs(map[string]string{"Tmdb": "949", "imdb": "tt0113277", "tvdb": ""}); got != "imdb:tt0113277,tmdb:949" {
+		t.Errorf("FormatProviderIDs = %q", got)
+	}
+}
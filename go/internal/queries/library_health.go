@@ -0,0 +1,234 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Library health factors and their weights in the overall score.
+const (
+	HealthFactorUnwatched  = "unwatched"
+	HealthFactorDuplicates = "duplicates"
+	HealthFactorCodecs     = "codec_modernity"
+	HealthFactorBitrate    = "bitrate"
+)
+
+var healthFactorWeights = map[string]float64{
+	HealthFactorUnwatched:  0.35,
+	HealthFactorDuplicates: 0.15,
+	HealthFactorCodecs:     0.25,
+	HealthFactorBitrate:    0.25,
+}
+
+// modernVideoCodecs are codecs that need noticeably less bitrate than H.264 for the same quality.
+var modernVideoCodecs = map[string]bool{"hevc": true, "h265": true, "av1": true, "vp9": true}
+
+// LibraryHealthFactor is one ingredient of a library's health score.
+type LibraryHealthFactor struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+	// Score is 0-100, higher is healthier
+	Score float64 `json:"score"`
+	// Judged is how many items the factor could be computed for (e.g. items with a known codec);
+	// Matching is how many of those are unwatched, extra copies, in a modern codec or at a
+	// suitable bitrate
+	Judged   int `json:"judged"`
+	Matching int `json:"matching"`
+}
+
+// LibraryHealth scores one server's movie or TV library.
+type LibraryHealth struct {
+	ServerID   string `json:"server_id"`
+	ServerName string `json:"server_name,omitempty"`
+	ServerType string `json:"server_type"`
+	Library    string `json:"library"` // movies|tv
+	Items      int    `json:"items"`
+	// Score is the weighted average of the factors that could be judged, 0-100
+	Score          float64               `json:"score"`
+	AvgBitrateMbps float64               `json:"avg_bitrate_mbps"`
+	Factors        []LibraryHealthFactor `json:"factors"`
+}
+
+// healthItem is the per-item input of the health score.
+type healthItem struct {
+	serverID, serverType, library string
+	codec                         string
+	height                        int64
+	bitrateBps                    int64
+	dupKey                        string
+	watched                       bool
+}
+
+// bitrateAppropriate reports whether a video's bitrate is in a sensible range for its
+// resolution: not starved, and not so high that a re-encode would lose nothing visible.
+// Modern codecs are expected to need 60% of the H.264 bitrate.
+func bitrateAppropriate(height, bitrateBps int64, codec string) bool {
+	mbps := float64(bitrateBps) / 1e6
+	var lo, hi float64
+	switch {
+	case height >= 1800:
+		lo, hi = 12, 60
+	case height >= 900:
+		lo, hi = 4, 25
+	case height >= 600:
+		lo, hi = 2, 12
+	default:
+		lo, hi = 0.5, 5
+	}
+	if modernVideoCodecs[strings.ToLower(codec)] {
+		lo, hi = lo*0.6, hi*0.6
+	}
+	return mbps >= lo && mbps <= hi
+}
+
+// LibraryHealthStats scores each server's movie and TV library on how much of it was watched
+// since `since` (unix seconds, 0 for ever), how much is duplicated (movies with the same title
+// and year or items sharing a file path), how much uses modern video codecs and how much has a
+// bitrate that suits its resolution. serverType or serverID optionally limit the servers.
+func LibraryHealthStats(ctx context.Context, db *sql.DB, serverType, serverID string, since int64) ([]LibraryHealth, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT li.server_id, LOWER(COALESCE(li.server_type, 'emby')), li.media_type,
+		       LOWER(COALESCE(li.video_codec, '')), COALESCE(li.height, 0), COALESCE(li.bitrate_bps, 0),
+		       LOWER(COALESCE(li.name, '')), COALESCE(li.production_year, 0),
+		       LOWER(REPLACE(COALESCE(li.file_path, ''), '\', '/')),
+		       EXISTS (SELECT 1 FROM play_sessions ps WHERE ps.item_id = li.id AND ps.started_at >= ?)
+		FROM library_item li
+		WHERE li.media_type IN ('Movie', 'Episode')
+		  AND (? = '' OR LOWER(COALESCE(li.server_type, 'emby')) = ?)
+		  AND (? = '' OR li.server_id = ?)
+	`, since, serverType, serverType, serverID, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []healthItem
+	for rows.Next() {
+		var it healthItem
+		var mediaType, name, path string
+		var year int
+		if err := rows.Scan(&it.serverID, &it.serverType, &mediaType, &it.codec, &it.height, &it.bitrateBps,
+			&name, &year, &path, &it.watched); err != nil {
+			return nil, err
+		}
+		it.library = "tv"
+		if mediaType == "Movie" {
+			it.library = "movies"
+		}
+		switch {
+		case it.library == "movies" && name != "":
+			it.dupKey = "t:" + name + "|" + strconv.Itoa(year)
+		case path != "":
+			it.dupKey = "p:" + path
+		}
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return scoreLibraries(items), nil
+}
+
+type libraryTally struct {
+	health                    LibraryHealth
+	watched                   int
+	dupCounts                 map[string]int
+	codecKnown, modern        int
+	bitrateKnown, appropriate int
+	withBitrate               int
+	bitrateSum                float64
+}
+
+func scoreLibraries(items []healthItem) []LibraryHealth {
+	tallies := map[string]*libraryTally{}
+	for _, it := range items {
+		key := it.serverID + "\x00" + it.library
+		t := tallies[key]
+		if t == nil {
+			t = &libraryTally{
+				health:    LibraryHealth{ServerID: it.serverID, ServerType: it.serverType, Library: it.library},
+				dupCounts: map[string]int{},
+			}
+			tallies[key] = t
+		}
+		t.health.Items++
+		if it.watched {
+			t.watched++
+		}
+		if it.dupKey != "" {
+			t.dupCounts[it.dupKey]++
+		}
+		if it.codec != "" {
+			t.codecKnown++
+			if modernVideoCodecs[it.codec] {
+				t.modern++
+			}
+		}
+		if it.bitrateBps > 0 {
+			t.withBitrate++
+			t.bitrateSum += float64(it.bitrateBps)
+			if it.height > 0 {
+				t.bitrateKnown++
+				if bitrateAppropriate(it.height, it.bitrateBps, it.codec) {
+					t.appropriate++
+				}
+			}
+		}
+	}
+
+	out := make([]LibraryHealth, 0, len(tallies))
+	for _, t := range tallies {
+		h := t.health
+		extraCopies := 0
+		for _, n := range t.dupCounts {
+			extraCopies += n - 1
+		}
+		h.Factors = []LibraryHealthFactor{
+			healthFactor(HealthFactorUnwatched, h.Items, h.Items-t.watched, true),
+			healthFactor(HealthFactorDuplicates, h.Items, extraCopies, true),
+			healthFactor(HealthFactorCodecs, t.codecKnown, t.modern, false),
+			healthFactor(HealthFactorBitrate, t.bitrateKnown, t.appropriate, false),
+		}
+		var weighted, weights float64
+		for _, f := range h.Factors {
+			if f.Judged == 0 {
+				continue
+			}
+			weighted += f.Score * f.Weight
+			weights += f.Weight
+		}
+		if weights > 0 {
+			h.Score = roundPercent(weighted / weights)
+		}
+		if t.withBitrate > 0 {
+			h.AvgBitrateMbps = roundPercent(t.bitrateSum / 1e6 / float64(t.withBitrate))
+		}
+		out = append(out, h)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ServerID != out[j].ServerID {
+			return out[i].ServerID < out[j].ServerID
+		}
+		return out[i].Library < out[j].Library
+	})
+	return out
+}
+
+// healthFactor scores the share of judged items that match; bad factors score the share
+// that doesn't.
+func healthFactor(name string, judged, matching int, bad bool) LibraryHealthFactor {
+	f := LibraryHealthFactor{Name: name, Weight: healthFactorWeights[name], Judged: judged, Matching: matching}
+	if judged == 0 {
+		return f
+	}
+	share := math.Min(float64(matching)/float64(judged), 1)
+	if bad {
+		share = 1 - share
+	}
+	f.Score = roundPercent(share * 100)
+	return f
+}
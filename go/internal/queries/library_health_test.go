@@ -0,0 +1,69 @@
+package queries
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestLibraryHealthStats(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	seed(t, conn,
+		`UPDATE library_item SET video_codec = 'hevc', height = 1080, bitrate_bps = 8000000 WHERE id = 'movie-a'`,
+		`UPDATE library_item SET video_codec = 'h264', height = 2160, bitrate_bps = 100000000, production_year = 2000 WHERE id = 'movie-b'`,
+		`INSERT INTO library_item (id, server_id, item_id, name, media_type, video_codec, height, bitrate_bps, production_year)
+		 VALUES ('movie-c', 's1', 'movie-c', 'Movie B', 'Movie', 'h264', 720, 5000000, 2000),
+		        ('ep-1', 's1', 'ep-1', 'Pilot', 'Episode', NULL, NULL, NULL, NULL)`,
+		`INSERT INTO library_item (id, server_id, server_type, item_id, name, media_type) VALUES ('plex-1', 's2', 'plex', 'plex-1', 'Other', 'Movie')`,
+	)
+
+	libs, err := LibraryHealthStats(ctx, conn, "", "", 0)
+	if err != nil {
+		t.Fatalf("health: %v", err)
+	}
+	if len(libs) != 3 {
+		t.Fatalf("expected s1 movies, s1 tv and s2 movies, got %+v", libs)
+	}
+	movies := libs[0]
+	if movies.ServerID != "s1" || movies.Library != "movies" || movies.Items != 3 {
+		t.Fatalf("unexpected first library %+v", movies)
+	}
+	want := map[string]LibraryHealthFactor{
+		HealthFactorUnwatched:  {Judged: 3, Matching: 1, Score: 66.7},
+		HealthFactorDuplicates: {Judged: 3, Matching: 1, Score: 66.7}, // movie-c repeats movie-b's title and year
+		HealthFactorCodecs:     {Judged: 3, Matching: 1, Score: 33.3},
+		HealthFactorBitrate:    {Judged: 3, Matching: 2, Score: 66.7}, // 100 Mbps is too much for 4K H.264
+	}
+	for _, f := range movies.Factors {
+		w := want[f.Name]
+		if f.Judged != w.Judged || f.Matching != w.Matching || f.Score != w.Score {
+			t.Errorf("factor %s = %+v, want %+v", f.Name, f, w)
+		}
+	}
+	if math.Abs(movies.Score-58.35) > 0.1 {
+		t.Errorf("movies score = %v, want ~58.35", movies.Score)
+	}
+	if movies.AvgBitrateMbps != 37.7 {
+		t.Errorf("avg bitrate = %v, want 37.7", movies.AvgBitrateMbps)
+	}
+
+	// Factors without data are left out of the score: unwatched (0) and duplicates (100) only
+	if tv := libs[1]; tv.Library != "tv" || tv.Score != 30 {
+		t.Errorf("tv library = %+v, want score 30", tv)
+	}
+
+	libs, err = LibraryHealthStats(ctx, conn, "plex", "", 0)
+	if err != nil {
+		t.Fatalf("plex health: %v", err)
+	}
+	if len(libs) != 1 || libs[0].ServerID != "s2" {
+		t.Errorf("server type filter: got %+v", libs)
+	}
+
+	// Plays before `since` don't count
+	libs, _ = LibraryHealthStats(ctx, conn, "", "s1", 2000)
+	if f := libs[0].Factors[0]; f.Name != HealthFactorUnwatched || f.Matching != 3 {
+		t.Errorf("expected every movie unwatched since 2000, got %+v", f)
+	}
+}
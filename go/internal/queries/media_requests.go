@@ -0,0 +1,222 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// MediaRequest is a request synced from Overseerr/Jellyseerr.
+type MediaRequest struct {
+	ID                  int64
+	MediaType           string // movie|tv
+	TMDBID              int64
+	TVDBID              int64
+	Is4K                bool
+	RequestStatus       int
+	MediaStatus         int
+	RequestedBy         string
+	RequestedByUsername string
+	RequestedAt         int64
+	AddedAt             int64 // 0 until the title is available
+	MediaItemID         string
+	PlexRatingKey       string
+}
+
+// Request statuses and media statuses as reported by Overseerr/Jellyseerr.
+const (
+	requestDeclined         = 3
+	mediaPartiallyAvailable = 4
+	mediaAvailable          = 5
+)
+
+// MediaItemKey normalises an Emby/Jellyfin item id for matching; Jellyfin ids are reported
+// both with and without dashes.
+func MediaItemKey(id string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(id)), "-", "")
+}
+
+// ReplaceMediaRequests stores the full list of requests, dropping ones that no longer exist.
+func ReplaceMediaRequests(ctx context.Context, db *sql.DB, reqs []MediaRequest, now int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO media_requests (id, media_type, tmdb_id, tvdb_id, is_4k, request_status, media_status, requested_by,
+			requested_by_username, requested_at, added_at, media_item_key, plex_rating_key, synced_at)
+		VALUES (?, ?, NULLIF(?, 0), NULLIF(?, 0), ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, ''), ?)
+		ON CONFLICT(id) DO UPDATE SET
+			media_type = excluded.media_type, tmdb_id = excluded.tmdb_id, tvdb_id = excluded.tvdb_id, is_4k = excluded.is_4k,
+			request_status = excluded.request_status, media_status = excluded.media_status,
+			requested_by = excluded.requested_by, requested_by_username = excluded.requested_by_username,
+			requested_at = excluded.requested_at, added_at = excluded.added_at,
+			media_item_key = excluded.media_item_key, plex_rating_key = excluded.plex_rating_key, synced_at = excluded.synced_at
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range reqs {
+		if _, err := stmt.ExecContext(ctx, r.ID, r.MediaType, r.TMDBID, r.TVDBID, r.Is4K, r.RequestStatus, r.MediaStatus,
+			r.RequestedBy, r.RequestedByUsername, r.RequestedAt, r.AddedAt, MediaItemKey(r.MediaItemID), r.PlexRatingKey, now); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM media_requests WHERE synced_at <> ?`, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RequestFunnelEntry is one request and how far it got.
+type RequestFunnelEntry struct {
+	ID          int64  `json:"id"`
+	MediaType   string `json:"media_type"`
+	TMDBID      int64  `json:"tmdb_id,omitempty"`
+	Title       string `json:"title,omitempty"` // from the matched library item
+	RequestedBy string `json:"requested_by"`
+	RequestedAt int64  `json:"requested_at"`
+	AddedAt     *int64 `json:"added_at"`
+	// Stage is requested, added or watched
+	Stage string `json:"stage"`
+	// InLibrary is false while an added title couldn't be matched to a synced library item,
+	// so whether it was watched is unknown
+	InLibrary          bool   `json:"in_library"`
+	FirstWatchedAt     *int64 `json:"first_watched_at"`
+	WatchedByRequester bool   `json:"watched_by_requester"`
+}
+
+// RequestFunnelTotals counts requests per funnel stage.
+type RequestFunnelTotals struct {
+	Requested          int     `json:"requested"`
+	Added              int     `json:"added"`
+	Watched            int     `json:"watched"`
+	WatchedByRequester int     `json:"watched_by_requester"`
+	Unmatched          int     `json:"unmatched"`   // added but not found in the library
+	AddedPct           float64 `json:"added_pct"`   // of requested
+	WatchedPct         float64 `json:"watched_pct"` // of added
+}
+
+func (t *RequestFunnelTotals) add(e RequestFunnelEntry) {
+	t.Requested++
+	if e.AddedAt == nil {
+		return
+	}
+	t.Added++
+	if !e.InLibrary {
+		t.Unmatched++
+	}
+	if e.FirstWatchedAt != nil {
+		t.Watched++
+	}
+	if e.WatchedByRequester {
+		t.WatchedByRequester++
+	}
+}
+
+func (t *RequestFunnelTotals) finish() {
+	if t.Requested > 0 {
+		t.AddedPct = float64(t.Added) * 100 / float64(t.Requested)
+	}
+	if t.Added > 0 {
+		t.WatchedPct = float64(t.Watched) * 100 / float64(t.Added)
+	}
+}
+
+// RequestFunnel is the requested → added → watched funnel.
+type RequestFunnel struct {
+	Totals   RequestFunnelTotals            `json:"totals"`
+	ByType   map[string]RequestFunnelTotals `json:"by_type"`
+	Declined int                            `json:"declined"`
+	Requests []RequestFunnelEntry           `json:"requests"`
+}
+
+// RequestFunnelStats follows requests made since `since` (unix seconds): whether the title
+// was added, and whether anyone (and the requester) watched it within windowSec of being
+// added. Titles are matched to library items by their Emby/Jellyfin item id or Plex rating
+// key; series requests count plays of any of their episodes.
+func RequestFunnelStats(ctx context.Context, db *sql.DB, since, windowSec int64) (*RequestFunnel, error) {
+	rows, err := db.QueryContext(ctx, `
+		WITH req AS (
+			SELECT id, media_type, COALESCE(tmdb_id, 0) AS tmdb_id, COALESCE(requested_by, '') AS requested_by,
+			       COALESCE(requested_by_username, '') AS username, requested_at,
+			       CASE WHEN added_at IS NOT NULL THEN added_at
+			            WHEN media_status IN (?, ?) THEN requested_at END AS added_at,
+			       COALESCE(media_item_key, '') AS media_item_key, COALESCE(plex_rating_key, '') AS plex_rating_key
+			FROM media_requests
+			WHERE requested_at >= ? AND request_status <> ?
+		),
+		matched AS (
+			SELECT r.id AS request_id, li.id AS item_id,
+			       COALESCE(NULLIF(li.series_name, ''), li.name) AS title
+			FROM req r
+			JOIN library_item li ON (
+			       r.media_item_key <> '' AND COALESCE(li.server_type, 'emby') IN ('emby', 'jellyfin')
+			       AND (REPLACE(LOWER(COALESCE(li.item_id, li.id)), '-', '') = r.media_item_key
+			            OR REPLACE(LOWER(COALESCE(li.series_id, '')), '-', '') = r.media_item_key)
+			   ) OR (
+			       r.plex_rating_key <> '' AND li.server_type = 'plex'
+			       AND (COALESCE(li.item_id, li.id) = r.plex_rating_key OR li.series_id = r.plex_rating_key)
+			   )
+		)
+		SELECT r.id, r.media_type, r.tmdb_id, r.requested_by, r.requested_at, r.added_at,
+		       (SELECT MAX(m.title) FROM matched m WHERE m.request_id = r.id),
+		       EXISTS (SELECT 1 FROM matched m WHERE m.request_id = r.id),
+		       (SELECT MIN(ps.started_at) FROM matched m JOIN play_sessions ps ON ps.item_id = m.item_id
+		        WHERE m.request_id = r.id AND ps.started_at >= r.added_at AND ps.started_at < r.added_at + ?),
+		       EXISTS (SELECT 1 FROM matched m JOIN play_sessions ps ON ps.item_id = m.item_id
+		               LEFT JOIN emby_user u ON u.id = ps.user_id
+		               WHERE m.request_id = r.id AND ps.started_at >= r.added_at AND ps.started_at < r.added_at + ?
+		                 AND r.username <> '' AND LOWER(COALESCE(u.name, ps.user_name, '')) = LOWER(r.username))
+		FROM req r
+		ORDER BY r.requested_at DESC, r.id DESC
+	`, mediaPartiallyAvailable, mediaAvailable, since, requestDeclined, windowSec, windowSec)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := &RequestFunnel{ByType: map[string]RequestFunnelTotals{}, Requests: []RequestFunnelEntry{}}
+	for rows.Next() {
+		var e RequestFunnelEntry
+		var added, watched sql.NullInt64
+		var title sql.NullString
+		if err := rows.Scan(&e.ID, &e.MediaType, &e.TMDBID, &e.RequestedBy, &e.RequestedAt, &added,
+			&title, &e.InLibrary, &watched, &e.WatchedByRequester); err != nil {
+			return nil, err
+		}
+		e.Title = title.String
+		e.Stage = "requested"
+		if added.Valid {
+			v := added.Int64
+			e.AddedAt = &v
+			e.Stage = "added"
+		}
+		if watched.Valid {
+			v := watched.Int64
+			e.FirstWatchedAt = &v
+			e.Stage = "watched"
+		}
+		out.Totals.add(e)
+		t := out.ByType[e.MediaType]
+		t.add(e)
+		out.ByType[e.MediaType] = t
+		out.Requests = append(out.Requests, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	out.Totals.finish()
+	for k, t := range out.ByType {
+		t.finish()
+		out.ByType[k] = t
+	}
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM media_requests WHERE requested_at >= ? AND request_status = ?`,
+		since, requestDeclined).Scan(&out.Declined); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
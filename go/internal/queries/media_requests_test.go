@@ -0,0 +1,55 @@
+package queries
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRequestFunnelStats(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+
+	reqs := []MediaRequest{
+		{ID: 1, MediaType: "movie", RequestStatus: 2, MediaStatus: 5, RequestedByUsername: "Alice", RequestedAt: 100, AddedAt: 500, MediaItemID: "MOVIE-A"},
+		{ID: 2, MediaType: "movie", RequestStatus: 2, MediaStatus: 5, RequestedByUsername: "bob", RequestedAt: 100, AddedAt: 500, MediaItemID: "movie-b"},
+		{ID: 3, MediaType: "tv", RequestStatus: 1, MediaStatus: 2, RequestedAt: 100},
+		{ID: 4, MediaType: "movie", RequestStatus: 3, MediaStatus: 1, RequestedAt: 100},
+		{ID: 5, MediaType: "movie", RequestStatus: 2, MediaStatus: 5, RequestedAt: 100, AddedAt: 500, MediaItemID: "not-synced"},
+		{ID: 6, MediaType: "movie", RequestStatus: 2, MediaStatus: 5, RequestedAt: 100, AddedAt: 50000, MediaItemID: "movie-a"},
+	}
+	if err := ReplaceMediaRequests(ctx, conn, append(reqs, MediaRequest{ID: 7, MediaType: "movie", RequestedAt: 100}), 1); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	// A later sync drops requests deleted upstream
+	if err := ReplaceMediaRequests(ctx, conn, reqs, 2); err != nil {
+		t.Fatalf("resync: %v", err)
+	}
+
+	f, err := RequestFunnelStats(ctx, conn, 0, 86400)
+	if err != nil {
+		t.Fatalf("funnel: %v", err)
+	}
+	want := RequestFunnelTotals{Requested: 5, Added: 4, Watched: 2, WatchedByRequester: 1, Unmatched: 1, AddedPct: 80, WatchedPct: 50}
+	if f.Totals != want {
+		t.Errorf("totals = %+v, want %+v", f.Totals, want)
+	}
+	if f.Declined != 1 {
+		t.Errorf("declined = %d, want 1", f.Declined)
+	}
+
+	stages := map[int64]string{}
+	for _, e := range f.Requests {
+		stages[e.ID] = e.Stage
+	}
+	for id, stage := range map[int64]string{1: "watched", 2: "watched", 3: "requested", 5: "added", 6: "added"} {
+		if stages[id] != stage {
+			t.Errorf("request %d stage = %q, want %q", id, stages[id], stage)
+		}
+	}
+	if _, ok := stages[7]; ok {
+		t.Errorf("request removed upstream is still listed")
+	}
+	if tv := f.ByType["tv"]; tv.Requested != 1 || tv.Added != 0 {
+		t.Errorf("tv totals = %+v", tv)
+	}
+}
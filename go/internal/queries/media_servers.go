@@ -0,0 +1,151 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/secrets"
+)
+
+// ListStoredMediaServers returns the stored media servers in the order they were added,
+// without deleted ones. API keys are returned unsealed.
+func ListStoredMediaServers(ctx context.Context, db *sql.DB) ([]media.ServerConfig, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, type, name, base_url, api_key, COALESCE(external_url, ''), enabled, tls_insecure_skip_verify,
+		       COALESCE(tls_ca_file, ''), COALESCE(proxy_url, '')
+		FROM media_server_config
+		WHERE deleted_at IS NULL
+		ORDER BY created_at, rowid
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []media.ServerConfig
+	for rows.Next() {
+		var sc media.ServerConfig
+		if err := rows.Scan(&sc.ID, &sc.Type, &sc.Name, &sc.BaseURL, &sc.APIKey, &sc.ExternalURL, &sc.Enabled, &sc.TLSInsecureSkipVerify,
+			&sc.TLSCAFile, &sc.ProxyURL); err != nil {
+			return nil, err
+		}
+		if sc.APIKey, err = secrets.Open(sc.APIKey); err != nil {
+			return nil, fmt.Errorf("server %s: api key: %w", sc.ID, err)
+		}
+		out = append(out, sc)
+	}
+	return out, rows.Err()
+}
+
+// SaveStoredMediaServer creates or replaces a stored media server, bringing back a deleted
+// one with the same id.
+func SaveStoredMediaServer(ctx context.Context, db *sql.DB, sc media.ServerConfig, now int64) error {
+	key, err := secrets.Seal(sc.APIKey)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO media_server_config (id, type, name, base_url, api_key, external_url, enabled, tls_insecure_skip_verify,
+		                                 tls_ca_file, proxy_url, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			type = excluded.type, name = excluded.name, base_url = excluded.base_url, api_key = excluded.api_key,
+			external_url = excluded.external_url, enabled = excluded.enabled,
+			tls_insecure_skip_verify = excluded.tls_insecure_skip_verify, tls_ca_file = excluded.tls_ca_file,
+			proxy_url = excluded.proxy_url, updated_at = excluded.updated_at, deleted_at = NULL
+	`, sc.ID, string(sc.Type), sc.Name, sc.BaseURL, key, sc.ExternalURL, sc.Enabled, sc.TLSInsecureSkipVerify,
+		sc.TLSCAFile, sc.ProxyURL, now, now)
+	return err
+}
+
+// SeedStoredMediaServers stores the servers whose id has never been stored, returning their
+// ids. Servers already stored, including deleted ones, are left as they are.
+func SeedStoredMediaServers(ctx context.Context, db *sql.DB, servers []media.ServerConfig, now int64) ([]string, error) {
+	var seeded []string
+	for _, sc := range servers {
+		var n int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM media_server_config WHERE id = ?`, sc.ID).Scan(&n); err != nil {
+			return seeded, err
+		}
+		if n > 0 {
+			continue
+		}
+		if err := SaveStoredMediaServer(ctx, db, sc, now); err != nil {
+			return seeded, err
+		}
+		seeded = append(seeded, sc.ID)
+	}
+	return seeded, nil
+}
+
+// DeletedMediaServerIDs returns the ids of deleted servers.
+func DeletedMediaServerIDs(ctx context.Context, db *sql.DB) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id FROM media_server_config WHERE deleted_at IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out[id] = true
+	}
+	return out, rows.Err()
+}
+
+// DeleteStoredMediaServer marks a stored server deleted and drops its API key. It reports
+// whether the server was stored.
+func DeleteStoredMediaServer(ctx context.Context, db *sql.DB, id string, now int64) (bool, error) {
+	res, err := db.ExecContext(ctx, `
+		UPDATE media_server_config SET deleted_at = ?, updated_at = ?, api_key = ''
+		WHERE id = ? AND deleted_at IS NULL
+	`, now, now, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// SealStoredMediaServerKeys seals API keys stored before keys were sealed, returning how
+// many were. Without a secret key nothing can be sealed: it returns how many keys are left
+// in plain text with an error wrapping secrets.ErrNoKey.
+func SealStoredMediaServerKeys(ctx context.Context, db *sql.DB) (int, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, api_key FROM media_server_config WHERE api_key <> ''`)
+	if err != nil {
+		return 0, err
+	}
+	plain := map[string]string{}
+	for rows.Next() {
+		var id, key string
+		if err := rows.Scan(&id, &key); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !secrets.IsSealed(key) {
+			plain[id] = key
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(plain) > 0 && !secrets.Enabled() {
+		return len(plain), fmt.Errorf("%d media server API keys stored unsealed: %w", len(plain), secrets.ErrNoKey)
+	}
+	for id, key := range plain {
+		sealed, err := secrets.Seal(key)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := db.ExecContext(ctx, `UPDATE media_server_config SET api_key = ? WHERE id = ?`, sealed, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(plain), nil
+}
@@ -0,0 +1,100 @@
+package queries
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/secrets"
+)
+
+func TestStoredMediaServers(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+
+	sc := media.ServerConfig{ID: "home-jf", Type: media.ServerTypeJellyfin, Name: "Home", BaseURL: "http://jf:8096", APIKey: "k1", Enabled: true}
+	if err := SaveStoredMediaServer(ctx, conn, sc, 100); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	sc.APIKey = "k2"
+	sc.ExternalURL = "https://jf.example.com"
+	if err := SaveStoredMediaServer(ctx, conn, sc, 200); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	got, err := ListStoredMediaServers(ctx, conn)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected one stored server, got %+v", got)
+	}
+	if got[0] != sc {
+		t.Errorf("stored server = %+v, want %+v", got[0], sc)
+	}
+}
+
+func TestStoredMediaServersSealAndSeed(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	if err := secrets.Configure([]byte("test-key")); err != nil {
+		t.Fatalf("configure: %v", err)
+	}
+
+	// A key stored in plain text before sealing existed
+	seed(t, conn, `INSERT INTO media_server_config (id, type, name, base_url, api_key, enabled, created_at, updated_at)
+		VALUES ('old', 'emby', 'Old', 'http://emby:8096', 'plain-key', 1, 1, 1)`)
+	if n, err := SealStoredMediaServerKeys(ctx, conn); err != nil || n != 1 {
+		t.Fatalf("seal = %d, %v; want 1", n, err)
+	}
+
+	env := []media.ServerConfig{
+		{ID: "old", Type: media.ServerTypeEmby, Name: "Renamed", BaseURL: "http://emby:8096", APIKey: "env-key", Enabled: true},
+		{ID: "plex-1", Type: media.ServerTypePlex, Name: "Plex", BaseURL: "http://plex:32400", APIKey: "tok", Enabled: true, ProxyURL: "socks5://bastion:1080"},
+	}
+	seeded, err := SeedStoredMediaServers(ctx, conn, env, 2)
+	if err != nil || len(seeded) != 1 || seeded[0] != "plex-1" {
+		t.Fatalf("seeded = %v, %v; want [plex-1]", seeded, err)
+	}
+
+	var raw string
+	if err := conn.QueryRow(`SELECT api_key FROM media_server_config WHERE id = 'plex-1'`).Scan(&raw); err != nil {
+		t.Fatalf("raw key: %v", err)
+	}
+	if !secrets.IsSealed(raw) || strings.Contains(raw, "tok") {
+		t.Errorf("api key stored as %q, want it sealed", raw)
+	}
+
+	got, err := ListStoredMediaServers(ctx, conn)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "old" || got[0].Name != "Old" || got[0].APIKey != "plain-key" || got[1] != env[1] {
+		t.Fatalf("stored servers = %+v", got)
+	}
+
+	if ok, err := DeleteStoredMediaServer(ctx, conn, "plex-1", 3); err != nil || !ok {
+		t.Fatalf("delete = %v, %v", ok, err)
+	}
+	if ok, _ := DeleteStoredMediaServer(ctx, conn, "plex-1", 4); ok {
+		t.Error("deleting twice should report nothing deleted")
+	}
+	// The environment doesn't bring a deleted server back
+	if seeded, _ := SeedStoredMediaServers(ctx, conn, env, 5); len(seeded) != 0 {
+		t.Errorf("reseeded %v after delete", seeded)
+	}
+	if deleted, _ := DeletedMediaServerIDs(ctx, conn); !deleted["plex-1"] || len(deleted) != 1 {
+		t.Errorf("deleted ids = %v", deleted)
+	}
+	if got, _ := ListStoredMediaServers(ctx, conn); len(got) != 1 {
+		t.Errorf("list after delete = %+v", got)
+	}
+	// Saving it again brings it back
+	if err := SaveStoredMediaServer(ctx, conn, env[1], 6); err != nil {
+		t.Fatalf("resave: %v", err)
+	}
+	if got, _ := ListStoredMediaServers(ctx, conn); len(got) != 2 {
+		t.Errorf("list after resave = %+v", got)
+	}
+}
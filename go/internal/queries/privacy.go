@@ -0,0 +1,54 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+)
+
+// AnonymousName is shown instead of the name of a user who hides from other viewers.
+const AnonymousName = "Anonymous"
+
+// HiddenUsers returns the media users who opted out of appearing by name to other viewers,
+// leaving out the user linked to appUserID (people always see themselves). appUserID 0
+// means an anonymous viewer.
+func HiddenUsers(ctx context.Context, db *sql.DB, appUserID int64) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id FROM emby_user
+		WHERE hide_from_others = 1
+		  AND id NOT IN (SELECT media_user_id FROM app_user WHERE id = ? AND media_user_id IS NOT NULL)
+	`, appUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hidden := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		hidden[id] = true
+	}
+	return hidden, rows.Err()
+}
+
+// GetUserHidden reports whether a media user hides from other viewers.
+func GetUserHidden(ctx context.Context, db *sql.DB, userID string) (bool, error) {
+	var hidden bool
+	err := db.QueryRowContext(ctx, `SELECT hide_from_others FROM emby_user WHERE id = ?`, userID).Scan(&hidden)
+	return hidden, err
+}
+
+// SetUserHidden sets a media user's hide_from_others flag; it returns sql.ErrNoRows for an
+// unknown user.
+func SetUserHidden(ctx context.Context, db *sql.DB, userID string, hidden bool) error {
+	res, err := db.ExecContext(ctx, `UPDATE emby_user SET hide_from_others = ? WHERE id = ?`, hidden, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
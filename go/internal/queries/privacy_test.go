@@ -0,0 +1,35 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestHiddenUsers(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	if err := SetUserHidden(ctx, conn, "alice", true); err != nil {
+		t.Fatalf("hide alice: %v", err)
+	}
+	if err := SetUserHidden(ctx, conn, "nobody", true); err != sql.ErrNoRows {
+		t.Fatalf("expected ErrNoRows for an unknown user, got %v", err)
+	}
+	seed(t, conn, `INSERT INTO app_user (id, username, password_hash, media_user_id) VALUES (1, 'alice', 'x', 'alice'), (2, 'bob', 'x', 'bob')`)
+
+	hidden, err := HiddenUsers(ctx, conn, 2)
+	if err != nil {
+		t.Fatalf("hidden for bob: %v", err)
+	}
+	if len(hidden) != 1 || !hidden["alice"] {
+		t.Errorf("expected alice hidden from bob, got %v", hidden)
+	}
+
+	hidden, err = HiddenUsers(ctx, conn, 1)
+	if err != nil {
+		t.Fatalf("hidden for alice: %v", err)
+	}
+	if len(hidden) != 0 {
+		t.Errorf("expected alice to see their own name, got %v", hidden)
+	}
+}
@@ -0,0 +1,177 @@
+package queries
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"fmt"
+	"time"
+)
+
+// Kinds of entities that can be given a public id.
+const (
+	PublicKindSession = "session"
+	PublicKindItem    = "item"
+	PublicKindUser    = "user"
+	PublicKindReport  = "report"
+)
+
+// publicKind describes where an entity lives. fingerprint is an SQL expression that
+// identifies the entity on its server independently of its internal id, so a public id
+// can follow it after a re-sync hands it a new one.
+type publicKind struct {
+	prefix      string
+	table       string
+	server      string
+	name        string
+	fingerprint string
+}
+
+var publicKinds = map[string]publicKind{
+	PublicKindSession: {
+		prefix:      "ses",
+		table:       "play_sessions",
+		server:      "COALESCE(server_id, '')",
+		name:        "COALESCE(item_name, '')",
+		fingerprint: "COALESCE(session_id, '') || '|' || COALESCE(item_id, '') || '|' || started_at",
+	},
+	PublicKindItem: {
+		prefix:      "itm",
+		table:       "library_item",
+		server:      "COALESCE(server_id, '')",
+		name:        "COALESCE(name, '')",
+		fingerprint: "CASE WHEN COALESCE(file_path, '') <> '' THEN file_path ELSE COALESCE(media_type, '') || '|' || COALESCE(name, '') END",
+	},
+	PublicKindUser: {
+		prefix:      "usr",
+		table:       "emby_user",
+		server:      "COALESCE(server_id, '')",
+		name:        "COALESCE(name, '')",
+		fingerprint: "LOWER(COALESCE(name, ''))",
+	},
+	PublicKindReport: {
+		prefix:      "rpt",
+		table:       "saved_views",
+		server:      "''",
+		name:        "name",
+		fingerprint: "user_id || '|' || name",
+	},
+}
+
+// publicIDEncoding is lowercase Crockford base32: URL-safe and free of look-alike letters.
+var publicIDEncoding = base32.NewEncoding("0123456789abcdefghjkmnpqrstvwxyz").WithPadding(base32.NoPadding)
+
+// PublicRef is what a public id currently points at.
+type PublicRef struct {
+	PublicID string `json:"public_id"`
+	Kind     string `json:"kind"`
+	ID       string `json:"id"`
+	ServerID string `json:"server_id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	// Found is false when the entity is gone and nothing on its server matches its fingerprint
+	Found bool `json:"found"`
+	// Remapped is set when the entity was re-found under a new internal id by this lookup
+	Remapped bool `json:"remapped"`
+}
+
+// ValidPublicKind reports whether kind can be given public ids.
+func ValidPublicKind(kind string) bool {
+	_, ok := publicKinds[kind]
+	return ok
+}
+
+func lookupPublicTarget(ctx context.Context, db *sql.DB, k publicKind, id string) (serverID, name, fingerprint string, err error) {
+	err = db.QueryRowContext(ctx, fmt.Sprintf(`SELECT %s, %s, %s FROM %s WHERE id = ?`,
+		k.server, k.name, k.fingerprint, k.table), id).Scan(&serverID, &name, &fingerprint)
+	return
+}
+
+// PublicID returns the public id of an entity, creating one on first use. It returns
+// sql.ErrNoRows when the entity does not exist.
+func PublicID(ctx context.Context, db *sql.DB, kind, id string) (string, error) {
+	k, ok := publicKinds[kind]
+	if !ok {
+		return "", fmt.Errorf("unknown kind %q", kind)
+	}
+	var existing string
+	err := db.QueryRowContext(ctx, `
+		SELECT public_id FROM public_ids WHERE kind = ? AND target_id = ?
+		ORDER BY created_at, public_id LIMIT 1`, kind, id).Scan(&existing)
+	if err == nil {
+		return existing, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	serverID, _, fingerprint, err := lookupPublicTarget(ctx, db, k, id)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	publicID := k.prefix + "_" + publicIDEncoding.EncodeToString(buf)
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO public_ids (public_id, kind, target_id, server_id, fingerprint, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, publicID, kind, id, serverID, fingerprint, time.Now().Unix())
+	if err != nil {
+		return "", err
+	}
+	return publicID, nil
+}
+
+// ResolvePublicID returns what publicID points at, or sql.ErrNoRows for unknown ids. When
+// the entity's internal id no longer exists it is looked up by fingerprint on the same
+// server, and the public id is moved to the match.
+func ResolvePublicID(ctx context.Context, db *sql.DB, publicID string) (PublicRef, error) {
+	ref := PublicRef{PublicID: publicID}
+	var fingerprint string
+	err := db.QueryRowContext(ctx, `
+		SELECT kind, target_id, server_id, fingerprint FROM public_ids WHERE public_id = ?`,
+		publicID).Scan(&ref.Kind, &ref.ID, &ref.ServerID, &fingerprint)
+	if err != nil {
+		return ref, err
+	}
+	k, ok := publicKinds[ref.Kind]
+	if !ok {
+		return ref, fmt.Errorf("unknown kind %q", ref.Kind)
+	}
+
+	_, name, _, err := lookupPublicTarget(ctx, db, k, ref.ID)
+	switch {
+	case err == nil:
+		ref.Name, ref.Found = name, true
+		return ref, nil
+	case err != sql.ErrNoRows:
+		return ref, err
+	}
+	if fingerprint == "" {
+		return ref, nil
+	}
+
+	var newID string
+	err = db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT CAST(id AS TEXT), %s FROM %s WHERE %s = ? AND %s = ? LIMIT 1`,
+		k.name, k.table, k.server, k.fingerprint), ref.ServerID, fingerprint).Scan(&newID, &name)
+	if err == sql.ErrNoRows {
+		return ref, nil
+	}
+	if err != nil {
+		return ref, err
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE public_ids SET target_id = ? WHERE public_id = ?`, newID, publicID); err != nil {
+		return ref, err
+	}
+	ref.ID, ref.Name, ref.Found, ref.Remapped = newID, name, true, true
+	return ref, nil
+}
+
+// RemapPublicIDs moves the public ids of an entity to the id it was merged into, so
+// links created before an admin remap lead to the surviving entity.
+func RemapPublicIDs(ctx context.Context, tx *sql.Tx, kind, fromID, toID string) error {
+	_, err := tx.ExecContext(ctx, `UPDATE public_ids SET target_id = ? WHERE kind = ? AND target_id = ?`, toID, kind, fromID)
+	return err
+}
@@ -0,0 +1,84 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestPublicIDs(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+
+	id, err := PublicID(ctx, conn, PublicKindItem, "movie-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(id, "itm_") || strings.ContainsAny(id, "/?#=+") {
+		t.Fatalf("public id = %q", id)
+	}
+	again, err := PublicID(ctx, conn, PublicKindItem, "movie-a")
+	if err != nil || again != id {
+		t.Fatalf("second lookup = %q, %v; want %q", again, err, id)
+	}
+	if _, err := PublicID(ctx, conn, PublicKindItem, "missing"); err != sql.ErrNoRows {
+		t.Fatalf("missing item err = %v", err)
+	}
+
+	ref, err := ResolvePublicID(ctx, conn, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ref.Found || ref.Remapped || ref.ID != "movie-a" || ref.Name != "Movie A" || ref.ServerID != "s1" {
+		t.Fatalf("ref = %+v", ref)
+	}
+
+	// A re-sync gives the item a new id; the fingerprint finds it again
+	stmts := []string{
+		`DELETE FROM library_item WHERE id = 'movie-a'`,
+		`INSERT INTO library_item (id, server_id, item_id, name, media_type) VALUES ('movie-a2', 's1', 'movie-a2', 'Movie A', 'Movie')`,
+	}
+	seed(t, conn, stmts...)
+	ref, err = ResolvePublicID(ctx, conn, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ref.Found || !ref.Remapped || ref.ID != "movie-a2" {
+		t.Fatalf("after re-sync ref = %+v", ref)
+	}
+	if ref, _ = ResolvePublicID(ctx, conn, id); ref.Remapped || ref.ID != "movie-a2" {
+		t.Fatalf("remap not persisted: %+v", ref)
+	}
+
+	// An admin remap carries the link along
+	userID, err := PublicID(ctx, conn, PublicKindUser, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := conn.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RemapPublicIDs(ctx, tx, PublicKindUser, "bob", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if ref, err = ResolvePublicID(ctx, conn, userID); err != nil || ref.ID != "alice" || ref.Name != "Alice" {
+		t.Fatalf("remapped user ref = %+v, %v", ref, err)
+	}
+
+	sessionID, err := PublicID(ctx, conn, PublicKindSession, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref, err = ResolvePublicID(ctx, conn, sessionID); err != nil || !ref.Found || ref.ID != "1" || ref.Name != "Movie A" {
+		t.Fatalf("session ref = %+v, %v", ref, err)
+	}
+
+	if _, err := ResolvePublicID(ctx, conn, "itm_unknown"); err != sql.ErrNoRows {
+		t.Fatalf("unknown id err = %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type UserQuota struct {
+	UserID         string `json:"user_id"`
+	DailyMinutes   *int   `json:"daily_minutes"`
+	WeeklyMinutes  *int   `json:"weekly_minutes"`
+	Enforce        bool   `json:"enforce"`
+	WarningMinutes int    `json:"warning_minutes"`
+	UpdatedAt      int64  `json:"updated_at"`
+}
+
+type QuotaPeriodUsage struct {
+	PeriodKey        string   `json:"period_key"`
+	Start            int64    `json:"start"`
+	UsedMinutes      float64  `json:"used_minutes"`
+	LimitMinutes     *int     `json:"limit_minutes"`
+	RemainingMinutes *float64 `json:"remaining_minutes"`
+	Exhausted        bool     `json:"exhausted"`
+}
+
+type UserQuotaStatus struct {
+	Quota  *UserQuota       `json:"quota"`
+	Daily  QuotaPeriodUsage `json:"daily"`
+	Weekly QuotaPeriodUsage `json:"weekly"`
+}
+
+// QuotaPeriodStarts returns local midnight today and local midnight of the current ISO week's Monday.
+func QuotaPeriodStarts(now time.Time) (day time.Time, week time.Time) {
+	day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	offset := (int(day.Weekday()) + 6) % 7 // Monday = 0
+	week = day.AddDate(0, 0, -offset)
+	return day, week
+}
+
+// GetUserQuota loads the configured quota for a user; nil when none is set.
+func GetUserQuota(ctx context.Context, db *sql.DB, userID string) (*UserQuota, error) {
+	var q UserQuota
+	var daily, weekly sql.NullInt64
+	err := db.QueryRowContext(ctx, `
+        SELECT user_id, daily_minutes, weekly_minutes, enforce, warning_minutes, updated_at
+        FROM user_quotas WHERE user_id = ?
+    `, userID).Scan(&q.UserID, &daily, &weekly, &q.Enforce, &q.WarningMinutes, &q.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if daily.Valid {
+		v := int(daily.Int64)
+		q.DailyMinutes = &v
+	}
+	if weekly.Valid {
+		v := int(weekly.Int64)
+		q.WeeklyMinutes = &v
+	}
+	return &q, nil
+}
+
+// UserQuotaUsage is a configured quota with the user's usage in the current periods.
+type UserQuotaUsage struct {
+	UserQuota
+	Daily  QuotaPeriodUsage `json:"daily"`
+	Weekly QuotaPeriodUsage `json:"weekly"`
+}
+
+// ListUserQuotas returns all configured quotas with today's and this week's usage.
+func ListUserQuotas(ctx context.Context, db *sql.DB, now time.Time) ([]UserQuotaUsage, error) {
+	rows, err := db.QueryContext(ctx, `
+        SELECT user_id, daily_minutes, weekly_minutes, enforce, warning_minutes, updated_at
+        FROM user_quotas ORDER BY user_id
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []UserQuotaUsage{}
+	for rows.Next() {
+		var q UserQuota
+		var daily, weekly sql.NullInt64
+		if err := rows.Scan(&q.UserID, &daily, &weekly, &q.Enforce, &q.WarningMinutes, &q.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if daily.Valid {
+			v := int(daily.Int64)
+			q.DailyMinutes = &v
+		}
+		if weekly.Valid {
+			v := int(weekly.Int64)
+			q.WeeklyMinutes = &v
+		}
+		out = append(out, UserQuotaUsage{UserQuota: q})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	dayStart, weekStart := QuotaPeriodStarts(now)
+	dayUsed, err := usersWatchMinutesSince(ctx, db, dayStart.Unix())
+	if err != nil {
+		return nil, err
+	}
+	weekUsed, err := usersWatchMinutesSince(ctx, db, weekStart.Unix())
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		q := &out[i]
+		q.Daily = quotaPeriodUsage("day", dayStart, dayUsed[q.UserID], q.DailyMinutes)
+		q.Weekly = quotaPeriodUsage("week", weekStart, weekUsed[q.UserID], q.WeeklyMinutes)
+	}
+	return out, nil
+}
+
+// watchSecondsSinceExpr sums the active watch seconds of play_intervals (pi) since the bound
+// start, clamping each interval to it; Live TV is left out by watchSecondsFrom.
+const watchSecondsSinceExpr = `COALESCE(SUM(
+            MAX(0, MIN(
+                pi.end_ts - MAX(pi.start_ts, ?),
+                CASE WHEN pi.duration_seconds IS NULL OR pi.duration_seconds <= 0
+                     THEN pi.end_ts - pi.start_ts
+                     ELSE pi.duration_seconds
+                END
+            ))
+        ), 0)`
+
+const watchSecondsFrom = `
+        FROM play_intervals pi
+        JOIN play_sessions ps ON ps.id = pi.session_fk
+        WHERE pi.end_ts >= ?
+          AND COALESCE(ps.item_type, '') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')`
+
+// UserWatchMinutesSince sums active watch minutes for a user from play_intervals since the given time (Live TV excluded).
+func UserWatchMinutesSince(ctx context.Context, db *sql.DB, userID string, since int64) (float64, error) {
+	var secs int64
+	err := db.QueryRowContext(ctx, `SELECT `+watchSecondsSinceExpr+watchSecondsFrom+` AND pi.user_id = ?`,
+		since, since, userID).Scan(&secs)
+	if err != nil {
+		return 0, err
+	}
+	return float64(secs) / 60.0, nil
+}
+
+// usersWatchMinutesSince is UserWatchMinutesSince for every user at once, keyed by user id.
+func usersWatchMinutesSince(ctx context.Context, db *sql.DB, since int64) (map[string]float64, error) {
+	rows, err := db.QueryContext(ctx, `SELECT pi.user_id, `+watchSecondsSinceExpr+watchSecondsFrom+` GROUP BY pi.user_id`,
+		since, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]float64)
+	for rows.Next() {
+		var id string
+		var secs int64
+		if err := rows.Scan(&id, &secs); err != nil {
+			return nil, err
+		}
+		out[id] = float64(secs) / 60.0
+	}
+	return out, rows.Err()
+}
+
+// quotaPeriodUsage reports used minutes in a period against its limit, if any.
+func quotaPeriodUsage(prefix string, start time.Time, used float64, limit *int) QuotaPeriodUsage {
+	u := QuotaPeriodUsage{
+		PeriodKey:    prefix + ":" + start.Format("2006-01-02"),
+		Start:        start.Unix(),
+		UsedMinutes:  used,
+		LimitMinutes: limit,
+	}
+	if limit != nil {
+		remaining := float64(*limit) - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		u.RemainingMinutes = &remaining
+		u.Exhausted = remaining <= 0
+	}
+	return u
+}
+
+// GetUserQuotaStatus computes today's and this week's usage against the user's quota.
+func GetUserQuotaStatus(ctx context.Context, db *sql.DB, userID string, now time.Time) (*UserQuotaStatus, error) {
+	q, err := GetUserQuota(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+	dayStart, weekStart := QuotaPeriodStarts(now)
+	status := &UserQuotaStatus{Quota: q}
+
+	build := func(prefix string, start time.Time, limit *int) (QuotaPeriodUsage, error) {
+		used, err := UserWatchMinutesSince(ctx, db, userID, start.Unix())
+		if err != nil {
+			return QuotaPeriodUsage{}, err
+		}
+		return quotaPeriodUsage(prefix, start, used, limit), nil
+	}
+
+	var dailyLimit, weeklyLimit *int
+	if q != nil {
+		dailyLimit, weeklyLimit = q.DailyMinutes, q.WeeklyMinutes
+	}
+	if status.Daily, err = build("day", dayStart, dailyLimit); err != nil {
+		return nil, err
+	}
+	if status.Weekly, err = build("week", weekStart, weeklyLimit); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
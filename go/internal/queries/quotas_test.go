@@ -0,0 +1,57 @@
+package queries
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestListUserQuotas(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	seed(t, conn,
+		`UPDATE play_sessions SET item_type = 'TvChannel' WHERE item_id = 'chan-1'`,
+		`INSERT INTO user_quotas (user_id, daily_minutes, weekly_minutes, enforce, warning_minutes, updated_at)
+		 VALUES ('alice', 45, NULL, 1, 10, 1), ('bob', NULL, 600, 0, 5, 2), ('dave', 30, 60, 0, 10, 3)`,
+	)
+	now := time.Unix(5000, 0).UTC()
+
+	quotas, err := ListUserQuotas(ctx, conn, now)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(quotas) != 3 {
+		t.Fatalf("expected 3 quotas, got %+v", quotas)
+	}
+	byUser := map[string]UserQuotaUsage{}
+	for _, q := range quotas {
+		byUser[q.UserID] = q
+	}
+
+	alice := byUser["alice"]
+	if alice.DailyMinutes == nil || *alice.DailyMinutes != 45 || alice.WeeklyMinutes != nil || !alice.Enforce {
+		t.Errorf("alice quota = %+v", alice.UserQuota)
+	}
+	if !approx(alice.Daily.UsedMinutes, 60) || !alice.Daily.Exhausted || alice.Weekly.RemainingMinutes != nil {
+		t.Errorf("alice usage: daily %+v weekly %+v", alice.Daily, alice.Weekly)
+	}
+	bob := byUser["bob"]
+	if !approx(bob.Weekly.UsedMinutes, 30) || bob.Weekly.RemainingMinutes == nil || !approx(*bob.Weekly.RemainingMinutes, 570) {
+		t.Errorf("bob weekly usage = %+v", bob.Weekly)
+	}
+	dave := byUser["dave"]
+	if dave.Daily.UsedMinutes != 0 || dave.Daily.Exhausted || dave.Daily.PeriodKey != "day:1970-01-01" {
+		t.Errorf("dave daily usage = %+v", dave.Daily)
+	}
+
+	// The list agrees with the per-user status used by the quota monitor.
+	for id, q := range byUser {
+		status, err := GetUserQuotaStatus(ctx, conn, id, now)
+		if err != nil {
+			t.Fatalf("status %s: %v", id, err)
+		}
+		if !approx(status.Daily.UsedMinutes, q.Daily.UsedMinutes) || !approx(status.Weekly.UsedMinutes, q.Weekly.UsedMinutes) {
+			t.Errorf("%s: list %v/%v, status %v/%v", id, q.Daily.UsedMinutes, q.Weekly.UsedMinutes, status.Daily.UsedMinutes, status.Weekly.UsedMinutes)
+		}
+	}
+}
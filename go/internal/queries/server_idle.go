@@ -0,0 +1,184 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+)
+
+// IdleWindow is a stretch of time in which a server streamed nothing.
+type IdleWindow struct {
+	Start int64   `json:"start"` // unix seconds
+	End   int64   `json:"end"`
+	Hours float64 `json:"hours"`
+}
+
+// DailyIdleWindow is the longest run of local hours that are usually idle. EndHour is
+// exclusive and is smaller than StartHour when the window spans midnight.
+type DailyIdleWindow struct {
+	StartHour  int     `json:"start_hour"`
+	EndHour    int     `json:"end_hour"`
+	Hours      int     `json:"hours"`
+	MinIdlePct float64 `json:"min_idle_pct"` // the least idle hour in the window
+}
+
+// ServerIdleWindows summarizes when a server streams nothing.
+type ServerIdleWindows struct {
+	ServerID   string  `json:"server_id"`
+	ServerType string  `json:"server_type"`
+	ServerName string  `json:"server_name,omitempty"`
+	IdlePct    float64 `json:"idle_pct"` // share of the whole range without streaming
+	// Longest is the longest idle stretch in the range; nil when the server was never idle
+	Longest *IdleWindow `json:"longest"`
+	// Typical is nil when no hour of the day reaches the idle threshold
+	Typical *DailyIdleWindow `json:"typical"`
+	// HourlyIdlePct is, per local hour of the day, the share of days that hour had no streaming
+	HourlyIdlePct [24]float64 `json:"hourly_idle_pct"`
+}
+
+// IdleWindows finds, per server, the time without any streaming over the last days local
+// calendar days in loc up to now. Every play interval counts, including live TV and users
+// excluded from stats, since any stream loads the server. Typical is the longest run of
+// hours (wrapping past midnight) that were idle on at least minIdlePct percent of days.
+// serverType (lower-case) or serverID optionally limit it to one server kind or instance.
+func IdleWindows(ctx context.Context, db *sql.DB, days int, loc *time.Location, now time.Time, minIdlePct float64, serverType, serverID string) ([]ServerIdleWindows, error) {
+	out := []ServerIdleWindows{}
+	if days <= 0 {
+		return out, nil
+	}
+	now = now.In(loc)
+	first := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, -(days - 1))
+	winStart, winEnd := first.Unix(), now.Unix()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT DISTINCT COALESCE(server_id, ''), LOWER(COALESCE(server_type, ''))
+		FROM play_sessions
+		WHERE COALESCE(server_id, '') <> ''
+		  AND (? = '' OR LOWER(COALESCE(server_type, '')) = ?)
+		  AND (? = '' OR server_id = ?)
+		ORDER BY 1
+	`, serverType, serverType, serverID, serverID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var s ServerIdleWindows
+		if err := rows.Scan(&s.ServerID, &s.ServerType); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range out {
+		busy, err := serverBusySpans(ctx, db, out[i].ServerID, winStart, winEnd)
+		if err != nil {
+			return nil, err
+		}
+		summarizeIdle(&out[i], busy, first, now, minIdlePct)
+	}
+	return out, nil
+}
+
+// serverBusySpans returns the server's play intervals within [from, to], clipped to it,
+// sorted and merged into non-overlapping [start, end) spans.
+func serverBusySpans(ctx context.Context, db *sql.DB, serverID string, from, to int64) ([][2]int64, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT pi.start_ts, pi.end_ts
+		FROM play_intervals pi
+		JOIN play_sessions ps ON ps.id = pi.session_fk
+		WHERE ps.server_id = ? AND pi.start_ts < ? AND pi.end_ts > ?
+		ORDER BY pi.start_ts
+	`, serverID, to, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var spans [][2]int64
+	for rows.Next() {
+		var start, end int64
+		if err := rows.Scan(&start, &end); err != nil {
+			return nil, err
+		}
+		start, end = max(start, from), min(end, to)
+		if end <= start {
+			continue
+		}
+		if n := len(spans); n > 0 && start <= spans[n-1][1] {
+			spans[n-1][1] = max(spans[n-1][1], end)
+			continue
+		}
+		spans = append(spans, [2]int64{start, end})
+	}
+	return spans, rows.Err()
+}
+
+// summarizeIdle fills the idle figures of s from its merged busy spans over [first, now].
+func summarizeIdle(s *ServerIdleWindows, busy [][2]int64, first, now time.Time, minIdlePct float64) {
+	winStart, winEnd := first.Unix(), now.Unix()
+
+	// Gaps between busy spans, including before the first and after the last
+	var busySec int64
+	cursor := winStart
+	for _, b := range append(busy, [2]int64{winEnd, winEnd}) {
+		if gap := b[0] - cursor; gap > 0 && (s.Longest == nil || gap > s.Longest.End-s.Longest.Start) {
+			s.Longest = &IdleWindow{Start: cursor, End: b[0]}
+		}
+		busySec += b[1] - b[0]
+		cursor = b[1]
+	}
+	if s.Longest != nil {
+		s.Longest.Hours = math.Round(float64(s.Longest.End-s.Longest.Start)/36) / 100
+	}
+	if winEnd > winStart {
+		s.IdlePct = roundPercent(float64(winEnd-winStart-busySec) / float64(winEnd-winStart) * 100)
+	}
+
+	// Complete local hour slots; a slot is idle when no busy span overlaps it
+	var slots, idle [24]int
+	j := 0
+	for t := first; !t.Add(time.Hour).After(now); t = t.Add(time.Hour) {
+		lo, hi := t.Unix(), t.Add(time.Hour).Unix()
+		for j < len(busy) && busy[j][1] <= lo {
+			j++
+		}
+		h := t.Hour()
+		slots[h]++
+		if j >= len(busy) || busy[j][0] >= hi {
+			idle[h]++
+		}
+	}
+	for h := range slots {
+		if slots[h] > 0 {
+			s.HourlyIdlePct[h] = roundPercent(float64(idle[h]) / float64(slots[h]) * 100)
+		}
+	}
+	s.Typical = typicalIdleWindow(s.HourlyIdlePct, minIdlePct)
+}
+
+// typicalIdleWindow returns the longest circular run of hours idle at least minIdlePct
+// percent of the time, preferring the more reliably idle run and then the earlier start.
+func typicalIdleWindow(hourly [24]float64, minIdlePct float64) *DailyIdleWindow {
+	var best *DailyIdleWindow
+	for start := 0; start < 24; start++ {
+		// Only consider runs that begin right after a busy hour (or the all-idle day)
+		if hourly[start] < minIdlePct || (hourly[(start+23)%24] >= minIdlePct && start != 0) {
+			continue
+		}
+		w := DailyIdleWindow{StartHour: start, MinIdlePct: 100}
+		for w.Hours < 24 && hourly[(start+w.Hours)%24] >= minIdlePct {
+			w.MinIdlePct = min(w.MinIdlePct, hourly[(start+w.Hours)%24])
+			w.Hours++
+		}
+		w.EndHour = (start + w.Hours) % 24
+		if best == nil || w.Hours > best.Hours || (w.Hours == best.Hours && w.MinIdlePct > best.MinIdlePct) {
+			best = &w
+		}
+	}
+	return best
+}
@@ -0,0 +1,58 @@
+package queries
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIdleWindows(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	seed(t, conn,
+		`UPDATE play_sessions SET server_id = 's1', server_type = 'Emby'`,
+		`INSERT INTO play_sessions (id, user_id, session_id, item_id, item_name, device_id, client_name, started_at, ended_at, is_active, server_id, server_type)
+		 VALUES (9, 'bob', 'old', 'movie-a', 'Movie A', 'd', 'Web', -900000, -899000, 0, 's2', 'plex')`,
+	)
+
+	// Two UTC days up to 02:00 on the second; s1 streams from 00:16 to 02:23 on the first
+	now := time.Unix(86400+7200, 0)
+	servers, err := IdleWindows(ctx, conn, 2, time.UTC, now, 90, "", "")
+	if err != nil {
+		t.Fatalf("idle windows: %v", err)
+	}
+	if len(servers) != 2 || servers[0].ServerID != "s1" || servers[0].ServerType != "emby" {
+		t.Fatalf("unexpected servers %+v", servers)
+	}
+	s1 := servers[0]
+	if s1.Longest == nil || s1.Longest.Start != 8600 || s1.Longest.End != 93600 || s1.Longest.Hours != 23.61 {
+		t.Errorf("longest = %+v", s1.Longest)
+	}
+	if s1.IdlePct != 91.9 {
+		t.Errorf("idle pct = %v, want 91.9", s1.IdlePct)
+	}
+	if h := s1.HourlyIdlePct; h[0] != 50 || h[1] != 50 || h[2] != 0 || h[3] != 100 || h[23] != 100 {
+		t.Errorf("hourly = %v", h)
+	}
+	if w := s1.Typical; w == nil || w.StartHour != 3 || w.EndHour != 0 || w.Hours != 21 || w.MinIdlePct != 100 {
+		t.Errorf("typical = %+v, want 03:00-00:00", w)
+	}
+
+	// A server without streams in range is idle throughout
+	s2 := servers[1]
+	if s2.IdlePct != 100 || s2.Longest == nil || s2.Longest.Hours != 26 || s2.Typical == nil || s2.Typical.Hours != 24 {
+		t.Errorf("s2 = %+v", s2)
+	}
+
+	// A lower threshold lets the half-idle hours join the window across midnight
+	servers, err = IdleWindows(ctx, conn, 2, time.UTC, now, 50, "", "s1")
+	if err != nil {
+		t.Fatalf("filtered: %v", err)
+	}
+	if len(servers) != 1 {
+		t.Fatalf("server filter: got %+v", servers)
+	}
+	if w := servers[0].Typical; w == nil || w.StartHour != 3 || w.EndHour != 2 || w.Hours != 23 || w.MinIdlePct != 50 {
+		t.Errorf("typical at 50%% = %+v", w)
+	}
+}
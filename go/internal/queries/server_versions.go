@@ -0,0 +1,67 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ServerVersionChange is one observed server software version; PreviousVersion is empty
+// for the first version recorded for a server.
+type ServerVersionChange struct {
+	ServerID        string `json:"server_id"`
+	ServerType      string `json:"server_type"`
+	Version         string `json:"version"`
+	PreviousVersion string `json:"previous_version,omitempty"`
+	DetectedAt      int64  `json:"detected_at"`
+	Day             string `json:"day"` // YYYY-MM-DD (UTC), matching the usage chart buckets
+}
+
+// RecordServerVersion stores version when it differs from the last one recorded for the
+// server and reports whether it did.
+func RecordServerVersion(ctx context.Context, db *sql.DB, serverID, serverType, version string, now time.Time) (bool, error) {
+	if version == "" {
+		return false, nil
+	}
+	var last sql.NullString
+	err := db.QueryRowContext(ctx, `
+		SELECT version FROM server_versions WHERE server_id = ? ORDER BY detected_at DESC, id DESC LIMIT 1
+	`, serverID).Scan(&last)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	if last.Valid && last.String == version {
+		return false, nil
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO server_versions (server_id, server_type, version, previous_version, detected_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, serverID, serverType, version, last, now.Unix())
+	return err == nil, err
+}
+
+// ServerVersionChanges returns recorded versions detected at or after since, oldest first.
+// An empty serverID returns every server's history.
+func ServerVersionChanges(ctx context.Context, db *sql.DB, serverID string, since int64) ([]ServerVersionChange, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT server_id, server_type, version, COALESCE(previous_version, ''), detected_at,
+		       strftime('%Y-%m-%d', datetime(detected_at, 'unixepoch'))
+		FROM server_versions
+		WHERE detected_at >= ? AND (? = '' OR server_id = ?)
+		ORDER BY detected_at, id
+	`, since, serverID, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []ServerVersionChange{}
+	for rows.Next() {
+		var v ServerVersionChange
+		if err := rows.Scan(&v.ServerID, &v.ServerType, &v.Version, &v.PreviousVersion, &v.DetectedAt, &v.Day); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,42 @@
+package queries
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecordServerVersion(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+
+	steps := []struct {
+		version string
+		at      int64
+		want    bool
+	}{
+		{"4.8.0", 1000, true},
+		{"4.8.0", 90000, false}, // unchanged on the next daily check
+		{"4.9.1", 180000, true},
+	}
+	for _, s := range steps {
+		got, err := RecordServerVersion(ctx, conn, "s1", "emby", s.version, time.Unix(s.at, 0))
+		if err != nil {
+			t.Fatalf("record %s: %v", s.version, err)
+		}
+		if got != s.want {
+			t.Errorf("record %s at %d: changed=%v, want %v", s.version, s.at, got, s.want)
+		}
+	}
+
+	history, err := ServerVersionChanges(ctx, conn, "s1", 0)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 2 || history[0].PreviousVersion != "" || history[1].PreviousVersion != "4.8.0" || history[1].Version != "4.9.1" || history[1].Day != "1970-01-03" {
+		t.Fatalf("expected 4.8.0 then 4.8.0 -> 4.9.1 on 1970-01-03, got %+v", history)
+	}
+	if others, _ := ServerVersionChanges(ctx, conn, "s2", 0); len(others) != 0 {
+		t.Errorf("expected no history for s2, got %+v", others)
+	}
+}
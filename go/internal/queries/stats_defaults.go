@@ -0,0 +1,51 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+)
+
+// StatsDefaults are the timeframe and server scope applied to stats requests that don't
+// pass them. Empty fields mean no default.
+type StatsDefaults struct {
+	Timeframe string `json:"timeframe"`
+	Server    string `json:"server"`
+}
+
+// Or returns d with its empty fields taken from fallback.
+func (d StatsDefaults) Or(fallback StatsDefaults) StatsDefaults {
+	if d.Timeframe == "" {
+		d.Timeframe = fallback.Timeframe
+	}
+	if d.Server == "" {
+		d.Server = fallback.Server
+	}
+	return d
+}
+
+// UserStatsDefaults returns the app user's own defaults, which override the global ones.
+func UserStatsDefaults(ctx context.Context, db *sql.DB, userID int64) (StatsDefaults, error) {
+	var d StatsDefaults
+	err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(timeframe, ''), COALESCE(server, '') FROM user_stats_defaults WHERE user_id = ?
+	`, userID).Scan(&d.Timeframe, &d.Server)
+	if err == sql.ErrNoRows {
+		return StatsDefaults{}, nil
+	}
+	return d, err
+}
+
+// SaveUserStatsDefaults stores the app user's defaults; clearing both removes them.
+func SaveUserStatsDefaults(ctx context.Context, db *sql.DB, userID int64, d StatsDefaults, now int64) error {
+	if d == (StatsDefaults{}) {
+		_, err := db.ExecContext(ctx, `DELETE FROM user_stats_defaults WHERE user_id = ?`, userID)
+		return err
+	}
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO user_stats_defaults (user_id, timeframe, server, updated_at)
+		VALUES (?, NULLIF(?, ''), NULLIF(?, ''), ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			timeframe = excluded.timeframe, server = excluded.server, updated_at = excluded.updated_at
+	`, userID, d.Timeframe, d.Server, now)
+	return err
+}
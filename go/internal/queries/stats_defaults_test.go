@@ -0,0 +1,36 @@
+package queries
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUserStatsDefaults(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	seed(t, conn, `INSERT INTO app_user (id, username, password_hash) VALUES (1, 'alice', 'x')`)
+	global := StatsDefaults{Timeframe: "30d", Server: "plex"}
+
+	d, err := UserStatsDefaults(ctx, conn, 1)
+	if err != nil {
+		t.Fatalf("load unset: %v", err)
+	}
+	if got := d.Or(global); got != global {
+		t.Errorf("without overrides got %+v, want the global defaults", got)
+	}
+
+	if err := SaveUserStatsDefaults(ctx, conn, 1, StatsDefaults{Server: "srv-1"}, 100); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if d, _ = UserStatsDefaults(ctx, conn, 1); d.Or(global) != (StatsDefaults{Timeframe: "30d", Server: "srv-1"}) {
+		t.Errorf("server override not applied: %+v", d.Or(global))
+	}
+
+	if err := SaveUserStatsDefaults(ctx, conn, 1, StatsDefaults{}, 200); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	var n int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM user_stats_defaults`).Scan(&n); err != nil || n != 0 {
+		t.Errorf("expected cleared defaults to be removed, %d rows (err %v)", n, err)
+	}
+}
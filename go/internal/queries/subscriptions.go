@@ -0,0 +1,117 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SubscriptionWeeklyDigest is the weekly personal email digest.
+const SubscriptionWeeklyDigest = "weekly_digest"
+
+// UserSubscription is an app user's email subscription.
+type UserSubscription struct {
+	UserID        int64  `json:"-"`
+	Kind          string `json:"kind"`
+	Email         string `json:"email"`
+	Enabled       bool   `json:"enabled"`
+	LastPeriodKey string `json:"last_period_key,omitempty"`
+	LastSentAt    *int64 `json:"last_sent_at"`
+	LastError     string `json:"last_error,omitempty"`
+	UpdatedAt     int64  `json:"updated_at"`
+}
+
+// DigestSubscription is an enabled subscription together with its owner.
+type DigestSubscription struct {
+	UserSubscription
+	Username    string
+	MediaUserID string
+}
+
+const userSubscriptionColumns = `s.user_id, s.kind, s.email, s.enabled, COALESCE(s.last_period_key, ''), s.last_sent_at, COALESCE(s.last_error, ''), s.updated_at`
+
+func scanSubscription(r goalScanner, extra ...any) (*UserSubscription, error) {
+	var s UserSubscription
+	var sent sql.NullInt64
+	dest := append([]any{&s.UserID, &s.Kind, &s.Email, &s.Enabled, &s.LastPeriodKey, &sent, &s.LastError, &s.UpdatedAt}, extra...)
+	if err := r.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if sent.Valid {
+		v := sent.Int64
+		s.LastSentAt = &v
+	}
+	return &s, nil
+}
+
+// ListUserSubscriptions returns the app user's subscriptions.
+func ListUserSubscriptions(ctx context.Context, db *sql.DB, userID int64) ([]UserSubscription, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+userSubscriptionColumns+` FROM user_subscriptions s WHERE s.user_id = ? ORDER BY s.kind`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []UserSubscription{}
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// SaveUserSubscription creates or replaces the app user's subscription of a kind, keeping its
+// delivery history.
+func SaveUserSubscription(ctx context.Context, db *sql.DB, userID int64, kind, email string, enabled bool, now int64) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO user_subscriptions (user_id, kind, email, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, kind) DO UPDATE SET email = excluded.email, enabled = excluded.enabled, updated_at = excluded.updated_at
+	`, userID, kind, email, enabled, now, now)
+	return err
+}
+
+// ListDigestSubscriptions returns enabled subscriptions of a kind whose owner is linked to a
+// media user and that weren't yet sent for periodKey.
+func ListDigestSubscriptions(ctx context.Context, db *sql.DB, kind, periodKey string) ([]DigestSubscription, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+userSubscriptionColumns+`, u.username, u.media_user_id
+		FROM user_subscriptions s
+		JOIN app_user u ON u.id = s.user_id
+		WHERE s.kind = ? AND s.enabled = 1 AND COALESCE(u.media_user_id, '') <> ''
+		  AND COALESCE(s.last_period_key, '') <> ?
+		ORDER BY s.user_id
+	`, kind, periodKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DigestSubscription
+	for rows.Next() {
+		var d DigestSubscription
+		s, err := scanSubscription(rows, &d.Username, &d.MediaUserID)
+		if err != nil {
+			return nil, err
+		}
+		d.UserSubscription = *s
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// RecordSubscriptionDelivery stores the outcome of sending a subscription for periodKey; a
+// failed delivery keeps the previous period so it is retried.
+func RecordSubscriptionDelivery(ctx context.Context, db *sql.DB, userID int64, kind, periodKey string, sendErr error, now int64) error {
+	if sendErr != nil {
+		_, err := db.ExecContext(ctx, `UPDATE user_subscriptions SET last_error = ? WHERE user_id = ? AND kind = ?`,
+			sendErr.Error(), userID, kind)
+		return err
+	}
+	_, err := db.ExecContext(ctx, `
+		UPDATE user_subscriptions SET last_period_key = ?, last_sent_at = ?, last_error = NULL
+		WHERE user_id = ? AND kind = ?`, periodKey, now, userID, kind)
+	return err
+}
@@ -0,0 +1,52 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDigestSubscriptions(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	seed(t, conn, `INSERT INTO app_user (id, username, password_hash, media_user_id) VALUES (1, 'alice', 'x', 'alice'), (2, 'bob', 'x', NULL)`)
+	for _, id := range []int64{1, 2} {
+		if err := SaveUserSubscription(ctx, conn, id, SubscriptionWeeklyDigest, "user@example.com", true, 100); err != nil {
+			t.Fatalf("subscribe %d: %v", id, err)
+		}
+	}
+
+	due, err := ListDigestSubscriptions(ctx, conn, SubscriptionWeeklyDigest, "week:2025-01-27")
+	if err != nil {
+		t.Fatalf("list due: %v", err)
+	}
+	if len(due) != 1 || due[0].Username != "alice" || due[0].MediaUserID != "alice" {
+		t.Fatalf("expected only alice due (bob isn't linked), got %+v", due)
+	}
+
+	if err := RecordSubscriptionDelivery(ctx, conn, 1, SubscriptionWeeklyDigest, "week:2025-01-27", errors.New("smtp down"), 200); err != nil {
+		t.Fatalf("record failure: %v", err)
+	}
+	if due, _ = ListDigestSubscriptions(ctx, conn, SubscriptionWeeklyDigest, "week:2025-01-27"); len(due) != 1 || due[0].LastError != "smtp down" {
+		t.Fatalf("expected a failed digest to stay due with its error, got %+v", due)
+	}
+
+	if err := RecordSubscriptionDelivery(ctx, conn, 1, SubscriptionWeeklyDigest, "week:2025-01-27", nil, 300); err != nil {
+		t.Fatalf("record delivery: %v", err)
+	}
+	if due, _ = ListDigestSubscriptions(ctx, conn, SubscriptionWeeklyDigest, "week:2025-01-27"); len(due) != 0 {
+		t.Errorf("expected no digest due after delivery, got %+v", due)
+	}
+
+	// Changing the address keeps the delivery history
+	if err := SaveUserSubscription(ctx, conn, 1, SubscriptionWeeklyDigest, "new@example.com", false, 400); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	subs, err := ListUserSubscriptions(ctx, conn, 1)
+	if err != nil {
+		t.Fatalf("list alice: %v", err)
+	}
+	if len(subs) != 1 || subs[0].Email != "new@example.com" || subs[0].Enabled || subs[0].LastSentAt == nil || *subs[0].LastSentAt != 300 || subs[0].LastError != "" {
+		t.Errorf("unexpected subscription after update: %+v", subs)
+	}
+}
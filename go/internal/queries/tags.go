@@ -0,0 +1,92 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+)
+
+type TagStatsRow struct {
+	Tag   string  `json:"tag"`
+	Items int     `json:"items"`
+	Hours float64 `json:"hours"`
+	Plays int     `json:"plays"`
+	Users int     `json:"users"`
+}
+
+// topTagsSQL groups library items by tag (case-insensitively) and sums each item's interval
+// overlap with the window, capped at the recorded duration like topItemsByWatchSecondsSQL.
+// Args: winEnd, winStart, winEnd, winStart, serverType, serverType, serverID, serverID, limit.
+const topTagsSQL = `
+        WITH watch AS (
+            SELECT
+                l.item_id,
+                l.user_id,
+                l.session_fk,
+                MAX(
+                    0,
+                    MIN(
+                        MIN(l.end_ts, ?) - MAX(l.start_ts, ?),
+                        CASE WHEN l.duration_seconds IS NULL OR l.duration_seconds <= 0
+                             THEN (l.end_ts - l.start_ts)
+                             ELSE l.duration_seconds
+                        END
+                    )
+                ) AS secs
+            FROM play_intervals l
+            WHERE l.start_ts <= ? AND l.end_ts >= ?
+        )
+        SELECT
+            MIN(t.tag) AS tag,
+            COUNT(DISTINCT t.item_id) AS items,
+            COALESCE(SUM(w.secs), 0) / 3600.0 AS hours,
+            COUNT(DISTINCT w.session_fk) AS plays,
+            COUNT(DISTINCT w.user_id) AS users
+        FROM item_tags t
+        JOIN library_item li ON li.id = t.item_id
+        LEFT JOIN watch w ON w.item_id = t.item_id
+        WHERE (? = '' OR LOWER(COALESCE(li.server_type, '')) = ?)
+          AND (? = '' OR li.server_id = ?)
+        GROUP BY t.tag COLLATE NOCASE
+        ORDER BY hours DESC, items DESC
+        LIMIT ?;
+    `
+
+// TopTags returns tags ranked by watch hours in [winStart, winEnd], with the number of tagged
+// items, plays and distinct users. serverType (lower-case) or serverID optionally scope the
+// result to one server kind or instance.
+func TopTags(ctx context.Context, db *sql.DB, winStart, winEnd int64, serverType, serverID string, limit int) ([]TagStatsRow, error) {
+	rows, err := db.QueryContext(ctx, topTagsSQL, winEnd, winStart, winEnd, winStart, serverType, serverType, serverID, serverID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TagStatsRow
+	for rows.Next() {
+		var r TagStatsRow
+		if err := rows.Scan(&r.Tag, &r.Items, &r.Hours, &r.Plays, &r.Users); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// ItemIDsWithTag returns the library item IDs carrying tag (case-insensitive).
+func ItemIDsWithTag(ctx context.Context, db *sql.DB, tag string) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT item_id FROM item_tags WHERE tag = ? COLLATE NOCASE`, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out[id] = true
+	}
+	return out, rows.Err()
+}
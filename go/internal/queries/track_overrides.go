@@ -0,0 +1,82 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+)
+
+// TrackOverrideRow is how often playback of one item left the tracks it started on or the
+// item's default audio track.
+type TrackOverrideRow struct {
+	ItemID   string `json:"item_id"`
+	ItemName string `json:"item_name"`
+	ItemType string `json:"item_type"`
+	// Sessions counts sessions with known track selections
+	Sessions               int `json:"sessions"`
+	AudioSwitchSessions    int `json:"audio_switch_sessions"`
+	SubtitleToggleSessions int `json:"subtitle_toggle_sessions"`
+	// NonDefaultAudioSessions started on an audio track other than the item's default
+	NonDefaultAudioSessions int `json:"non_default_audio_sessions"`
+	// OverrideSessions had any of the above
+	OverrideSessions int     `json:"override_sessions"`
+	OverridePct      float64 `json:"override_pct"`
+	AudioSwitches    int     `json:"audio_switches"`
+	SubtitleChanges  int     `json:"subtitle_changes"`
+}
+
+// TrackOverrides aggregates, per item, how often sessions started since `since` overrode
+// the default tracks: switched audio, turned subtitles on, off or to another language, or
+// started on a non-default audio track. Items with the most overriding sessions come
+// first; itemID optionally limits it to one item. Users excluded from stats are skipped.
+func TrackOverrides(ctx context.Context, db *sql.DB, since int64, itemID string, limit int) ([]TrackOverrideRow, error) {
+	rows, err := db.QueryContext(ctx, `
+		WITH ch AS (
+			SELECT session_fk,
+			       SUM(CASE WHEN track = 'audio' THEN 1 ELSE 0 END) AS audio,
+			       SUM(CASE WHEN track = 'subtitle' THEN 1 ELSE 0 END) AS subtitle
+			FROM play_track_changes
+			GROUP BY session_fk
+		), s AS (
+			SELECT ps.item_id, ps.item_name, ps.item_type,
+			       COALESCE(ch.audio, 0) AS audio, COALESCE(ch.subtitle, 0) AS subtitle,
+			       CASE WHEN COALESCE(ps.audio_default, 1) = 0 THEN 1 ELSE 0 END AS non_default
+			FROM play_sessions ps
+			LEFT JOIN ch ON ch.session_fk = ps.id
+			LEFT JOIN emby_user u ON u.id = ps.user_id
+			WHERE ps.started_at >= ?
+			  AND (? = '' OR ps.item_id = ?)
+			  AND COALESCE(u.exclude_from_stats, 0) = 0
+			  AND (ps.audio_language IS NOT NULL OR ps.subtitle_language IS NOT NULL OR ch.session_fk IS NOT NULL)
+		)
+		SELECT s.item_id, COALESCE(MAX(li.name), MAX(s.item_name), s.item_id), COALESCE(MAX(li.media_type), MAX(s.item_type), ''),
+		       COUNT(*),
+		       SUM(CASE WHEN s.audio > 0 THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN s.subtitle > 0 THEN 1 ELSE 0 END),
+		       SUM(s.non_default),
+		       SUM(CASE WHEN s.audio > 0 OR s.subtitle > 0 OR s.non_default = 1 THEN 1 ELSE 0 END) AS overrides,
+		       SUM(s.audio), SUM(s.subtitle)
+		FROM s
+		LEFT JOIN library_item li ON li.id = s.item_id
+		GROUP BY s.item_id
+		ORDER BY overrides DESC, COUNT(*) DESC, s.item_id
+		LIMIT ?`, since, itemID, itemID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []TrackOverrideRow{}
+	for rows.Next() {
+		var r TrackOverrideRow
+		if err := rows.Scan(&r.ItemID, &r.ItemName, &r.ItemType, &r.Sessions, &r.AudioSwitchSessions,
+			&r.SubtitleToggleSessions, &r.NonDefaultAudioSessions, &r.OverrideSessions,
+			&r.AudioSwitches, &r.SubtitleChanges); err != nil {
+			return nil, err
+		}
+		if r.Sessions > 0 {
+			r.OverridePct = roundPercent(float64(r.OverrideSessions) / float64(r.Sessions) * 100)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
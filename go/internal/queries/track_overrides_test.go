@@ -0,0 +1,43 @@
+package queries
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTrackOverrides(t *testing.T) {
+	conn := openFixtureDB(t)
+	stmts := []string{
+		// alice started Movie A on a non-default track; bob switched audio twice and turned
+		// subtitles on; carol is excluded from stats; the channel has no track data
+		`UPDATE play_sessions SET audio_language = 'eng', audio_default = 0, subtitle_language = 'off' WHERE id = 1`,
+		`UPDATE play_sessions SET audio_language = 'eng', audio_default = 1, subtitle_language = 'off' WHERE id = 2`,
+		`UPDATE play_sessions SET audio_language = 'eng', audio_default = 1 WHERE id = 3`,
+		`INSERT INTO play_track_changes (session_fk, track, from_language, to_language, position_ticks, created_at)
+		 VALUES (2, 'audio', 'eng', 'fre', 100, 1100), (2, 'audio', 'fre', 'eng', 200, 1200),
+		        (2, 'subtitle', 'off', 'eng', 300, 1300), (3, 'subtitle', 'off', 'eng', 300, 1300)`,
+	}
+	seed(t, conn, stmts...)
+
+	rows, err := TrackOverrides(context.Background(), conn, 0, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("rows = %+v", rows)
+	}
+	r := rows[0]
+	want := TrackOverrideRow{ItemID: "movie-a", ItemName: "Movie A", ItemType: "Movie", Sessions: 2,
+		AudioSwitchSessions: 1, SubtitleToggleSessions: 1, NonDefaultAudioSessions: 1,
+		OverrideSessions: 2, OverridePct: 100, AudioSwitches: 2, SubtitleChanges: 1}
+	if r != want {
+		t.Fatalf("row = %+v, want %+v", r, want)
+	}
+
+	if rows, err = TrackOverrides(context.Background(), conn, 2000, "", 10); err != nil || len(rows) != 0 {
+		t.Fatalf("since filter = %+v, %v", rows, err)
+	}
+	if rows, err = TrackOverrides(context.Background(), conn, 0, "movie-b", 10); err != nil || len(rows) != 0 {
+		t.Fatalf("item filter = %+v, %v", rows, err)
+	}
+}
@@ -0,0 +1,56 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+)
+
+type TrendingItemRow struct {
+	ItemID        string  `json:"item_id"`
+	Name          string  `json:"name"`
+	Type          string  `json:"type"`
+	Score         float64 `json:"score"`
+	Hours7d       float64 `json:"hours_7d"`
+	LastWatchedAt int64   `json:"last_watched_at"`
+	ServerID      string  `json:"server_id,omitempty"`
+	ServerType    string  `json:"server_type,omitempty"`
+	UpdatedAt     int64   `json:"updated_at"`
+}
+
+// TrendingItems returns items ordered by the decayed watch-hour score computed by the rollup job.
+// serverType (lower-case) or serverID optionally scope the result to one server kind or instance.
+func TrendingItems(ctx context.Context, db *sql.DB, serverType, serverID string, limit int) ([]TrendingItemRow, error) {
+	query := `
+        SELECT t.item_id,
+               COALESCE(NULLIF(li.name, ''),
+                        (SELECT ps.item_name FROM play_sessions ps WHERE ps.item_id = t.item_id ORDER BY ps.started_at DESC LIMIT 1),
+                        ''),
+               COALESCE(NULLIF(li.media_type, ''),
+                        (SELECT ps.item_type FROM play_sessions ps WHERE ps.item_id = t.item_id ORDER BY ps.started_at DESC LIMIT 1),
+                        'Unknown'),
+               t.score, t.hours_7d, COALESCE(t.last_watched_at, 0),
+               COALESCE(t.server_id, ''), COALESCE(t.server_type, ''), t.updated_at
+        FROM item_trending t
+        LEFT JOIN library_item li ON li.id = t.item_id
+        WHERE (? = '' OR LOWER(COALESCE(t.server_type, '')) = ?)
+          AND (? = '' OR t.server_id = ?)
+        ORDER BY t.score DESC
+        LIMIT ?;
+    `
+	rows, err := db.QueryContext(ctx, query, serverType, serverType, serverID, serverID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []TrendingItemRow{}
+	for rows.Next() {
+		var r TrendingItemRow
+		if err := rows.Scan(&r.ItemID, &r.Name, &r.Type, &r.Score, &r.Hours7d, &r.LastWatchedAt,
+			&r.ServerID, &r.ServerType, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
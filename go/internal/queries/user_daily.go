@@ -0,0 +1,100 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UserDayRow is one local calendar day of a user's watch time.
+type UserDayRow struct {
+	Day     string  `json:"day"` // YYYY-MM-DD in the requested timezone
+	Weekday string  `json:"weekday"`
+	Weekend bool    `json:"weekend"`
+	Holiday bool    `json:"holiday"`
+	Hours   float64 `json:"hours"`
+	// RollingAvg7 is the mean of this day and the six days before it
+	RollingAvg7 float64 `json:"rolling_avg_7d"`
+}
+
+// rollingWindowDays is the length of the rolling average attached to each day.
+const rollingWindowDays = 7
+
+// UserDailyWatch returns the user's watch hours for each of the last days local calendar
+// days in loc, ending with today. Intervals spanning midnight are split at local midnight;
+// an interval whose recorded duration is shorter than its wall-clock span (pauses) is
+// scaled down evenly. Live TV is excluded. holidays holds YYYY-MM-DD dates to flag.
+func UserDailyWatch(ctx context.Context, db *sql.DB, userID string, days int, loc *time.Location, now time.Time, holidays map[string]bool) ([]UserDayRow, error) {
+	if days <= 0 {
+		return []UserDayRow{}, nil
+	}
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	// Fetch six extra days so every returned day has a full rolling window.
+	total := days + rollingWindowDays - 1
+	first := today.AddDate(0, 0, -(total - 1))
+	winStart, winEnd := first.Unix(), now.Unix()
+
+	// Day boundaries; AddDate keeps DST days at their real 23/25 hour length.
+	bounds := make([]int64, total+1)
+	for i := 0; i <= total; i++ {
+		bounds[i] = first.AddDate(0, 0, i).Unix()
+	}
+	seconds := make([]float64, total)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT l.start_ts, l.end_ts, COALESCE(l.duration_seconds, 0)
+		FROM play_intervals l
+		LEFT JOIN library_item li ON li.id = l.item_id
+		WHERE l.user_id = ?
+		  AND l.start_ts <= ? AND l.end_ts >= ?
+		  AND COALESCE(li.media_type, 'Unknown') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
+	`, userID, winEnd, winStart)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var start, end, dur int64
+		if err := rows.Scan(&start, &end, &dur); err != nil {
+			return nil, err
+		}
+		span := end - start
+		if span <= 0 {
+			continue
+		}
+		ratio := 1.0
+		if dur > 0 && dur < span {
+			ratio = float64(dur) / float64(span)
+		}
+		for i := 0; i < total; i++ {
+			lo, hi := max(start, bounds[i], winStart), min(end, bounds[i+1], winEnd)
+			if hi > lo {
+				seconds[i] += float64(hi-lo) * ratio
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]UserDayRow, 0, days)
+	for i := rollingWindowDays - 1; i < total; i++ {
+		var window float64
+		for _, s := range seconds[i-rollingWindowDays+1 : i+1] {
+			window += s
+		}
+		d := first.AddDate(0, 0, i)
+		key := d.Format("2006-01-02")
+		out = append(out, UserDayRow{
+			Day:         key,
+			Weekday:     d.Weekday().String(),
+			Weekend:     d.Weekday() == time.Saturday || d.Weekday() == time.Sunday,
+			Holiday:     holidays[key],
+			Hours:       seconds[i] / 3600.0,
+			RollingAvg7: window / 3600.0 / rollingWindowDays,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,56 @@
+package queries
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUserDailyWatchSplitsAtLocalMidnight(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	now := time.Unix(3*86400, 0)
+
+	// In UTC all of alice's Movie A watching falls on 1970-01-01; live TV is excluded.
+	days, err := UserDailyWatch(ctx, conn, "alice", 4, time.UTC, now, map[string]bool{"1970-01-01": true})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(days) != 4 || days[0].Day != "1970-01-01" || days[3].Day != "1970-01-04" {
+		t.Fatalf("expected 1970-01-01..04, got %+v", days)
+	}
+	if !approx(days[0].Hours, 1.0) || !approx(days[0].RollingAvg7, 1.0/7) || !days[0].Holiday {
+		t.Errorf("unexpected first day: %+v", days[0])
+	}
+	if !days[2].Weekend || days[2].Weekday != "Saturday" || days[1].Weekend {
+		t.Errorf("expected only 1970-01-03 (Saturday) to be a weekend day: %+v", days)
+	}
+
+	// One hour west of UTC, local midnight falls at ts 3600, inside the second interval,
+	// and "now" is 1970-01-03 23:00 local, so four days start at 1969-12-31.
+	west := time.FixedZone("UTC-1", -3600)
+	days, err = UserDailyWatch(ctx, conn, "alice", 4, west, now, nil)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	got := map[string]float64{}
+	for _, d := range days {
+		got[d.Day] = d.Hours
+	}
+	if !approx(got["1969-12-31"], 2600.0/3600) || !approx(got["1970-01-01"], 1000.0/3600) {
+		t.Errorf("expected 2600s/1000s split for alice, got %+v", days)
+	}
+
+	// bob's interval records 1800s over a 3600s span, so each side is scaled by half.
+	days, err = UserDailyWatch(ctx, conn, "bob", 4, west, now, nil)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	got = map[string]float64{}
+	for _, d := range days {
+		got[d.Day] = d.Hours
+	}
+	if !approx(got["1969-12-31"], 1300.0/3600) || !approx(got["1970-01-01"], 500.0/3600) {
+		t.Errorf("expected 1300s/500s split for bob, got %+v", days)
+	}
+}
@@ -0,0 +1,92 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+)
+
+// UserDevice is one device and client app a user played from.
+type UserDevice struct {
+	DeviceID      string `json:"device_id"` // canonical id when the device is merged
+	DeviceName    string `json:"device_name"`
+	Client        string `json:"client"`
+	ClientVersion string `json:"client_version,omitempty"` // of the latest session
+	Platform      string `json:"platform,omitempty"`
+	Sessions      int    `json:"sessions"`
+	FirstSeen     int64  `json:"first_seen"`
+	LastSeen      int64  `json:"last_seen"`
+}
+
+// UserAddress is one remote address a user played from.
+type UserAddress struct {
+	Address   string `json:"address"`
+	Sessions  int    `json:"sessions"`
+	Devices   int    `json:"devices"`
+	FirstSeen int64  `json:"first_seen"`
+	LastSeen  int64  `json:"last_seen"`
+}
+
+// UserDevices returns every device and client pair in a user's playback history, most
+// recently used first. Merged devices count as one, under the group's name.
+func UserDevices(ctx context.Context, db *sql.DB, userID string) ([]UserDevice, error) {
+	rows, err := db.QueryContext(ctx, `
+		WITH s AS (
+			SELECT COALESCE(da.canonical_id, ps.device_id) AS device,
+			       COALESCE(NULLIF(da.name, ''), ps.device_id) AS name,
+			       COALESCE(ps.client_name, '') AS client,
+			       COALESCE(ps.client_version, '') AS version,
+			       COALESCE(ps.platform, '') AS platform,
+			       ps.started_at, COALESCE(ps.ended_at, ps.started_at) AS last,
+			       ROW_NUMBER() OVER (PARTITION BY COALESCE(da.canonical_id, ps.device_id), COALESCE(ps.client_name, '')
+			                          ORDER BY ps.started_at DESC) AS rn
+			FROM play_sessions ps
+			LEFT JOIN device_aliases da ON da.device_id = ps.device_id
+			WHERE ps.user_id = ? AND COALESCE(ps.device_id, '') <> ''
+		)
+		SELECT device, MAX(CASE WHEN rn = 1 THEN name END), client, MAX(CASE WHEN rn = 1 THEN version END),
+		       MAX(CASE WHEN rn = 1 THEN platform END), COUNT(*), MIN(started_at), MAX(last)
+		FROM s
+		GROUP BY device, client
+		ORDER BY 8 DESC, 1, 3
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []UserDevice{}
+	for rows.Next() {
+		var d UserDevice
+		if err := rows.Scan(&d.DeviceID, &d.DeviceName, &d.Client, &d.ClientVersion, &d.Platform, &d.Sessions, &d.FirstSeen, &d.LastSeen); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// UserAddresses returns every remote address a user played from with the number of
+// devices seen there, most recently used first.
+func UserAddresses(ctx context.Context, db *sql.DB, userID string) ([]UserAddress, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT ps.remote_address, COUNT(*), COUNT(DISTINCT COALESCE(da.canonical_id, NULLIF(ps.device_id, ''))),
+		       MIN(ps.started_at), MAX(COALESCE(ps.ended_at, ps.started_at))
+		FROM play_sessions ps
+		LEFT JOIN device_aliases da ON da.device_id = ps.device_id
+		WHERE ps.user_id = ? AND COALESCE(ps.remote_address, '') <> ''
+		GROUP BY ps.remote_address
+		ORDER BY 5 DESC, 1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []UserAddress{}
+	for rows.Next() {
+		var a UserAddress
+		if err := rows.Scan(&a.Address, &a.Sessions, &a.Devices, &a.FirstSeen, &a.LastSeen); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,50 @@
+package queries
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestUserDevicesAndAddresses(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	stmts := []string{
+		`UPDATE play_sessions SET remote_address = '10.0.0.1', client_version = '4.7', platform = 'Windows' WHERE id = 1`,
+		`INSERT INTO play_sessions (id, user_id, session_id, item_id, device_id, client_name, client_version, platform, remote_address, started_at, ended_at, is_active)
+		 VALUES (5, 'alice', 'se', 'movie-b', 'd', 'Web', '4.8', 'Windows', '10.0.0.1', 9000, 9500, 0),
+		        (6, 'alice', 'sf', 'movie-b', 'tv', 'Emby for Android', '', 'Android TV', '203.0.113.7', 10000, NULL, 1),
+		        (7, 'alice', 'sg', 'movie-b', 'tv-2', 'Emby for Android', '', 'Android TV', '203.0.113.7', 11000, 11500, 0)`,
+		`INSERT INTO device_aliases (device_id, canonical_id, name, updated_at) VALUES ('tv', 'tv', 'Living Room', 1), ('tv-2', 'tv', 'Living Room', 1)`,
+	}
+	seed(t, conn, stmts...)
+
+	devices, err := UserDevices(ctx, conn, "alice")
+	if err != nil {
+		t.Fatalf("devices: %v", err)
+	}
+	// tv-2 is merged into tv; the Web client reports the version of its latest session
+	want := []UserDevice{
+		{DeviceID: "tv", DeviceName: "Living Room", Client: "Emby for Android", Platform: "Android TV", Sessions: 2, FirstSeen: 10000, LastSeen: 11500},
+		{DeviceID: "d", DeviceName: "d", Client: "Web", ClientVersion: "4.8", Platform: "Windows", Sessions: 3, FirstSeen: 1000, LastSeen: 9500},
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("devices = %+v", devices)
+	}
+
+	addrs, err := UserAddresses(ctx, conn, "alice")
+	if err != nil {
+		t.Fatalf("addresses: %v", err)
+	}
+	wantAddrs := []UserAddress{
+		{Address: "203.0.113.7", Sessions: 2, Devices: 1, FirstSeen: 10000, LastSeen: 11500},
+		{Address: "10.0.0.1", Sessions: 2, Devices: 1, FirstSeen: 1000, LastSeen: 9500},
+	}
+	if !reflect.DeepEqual(addrs, wantAddrs) {
+		t.Errorf("addresses = %+v", addrs)
+	}
+
+	if devices, _ = UserDevices(ctx, conn, "nobody"); len(devices) != 0 {
+		t.Errorf("devices for unknown user = %+v", devices)
+	}
+}
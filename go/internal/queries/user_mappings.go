@@ -0,0 +1,224 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrUnknownUser   = errors.New("unknown user")
+	ErrUnknownPerson = errors.New("unknown person")
+)
+
+// Person is one human with accounts on one or more media servers.
+type Person struct {
+	PersonID string          `json:"person_id"`
+	Name     string          `json:"name"`
+	Users    []PersonAccount `json:"users"`
+}
+
+// PersonAccount is a media server account mapped to a person.
+type PersonAccount struct {
+	UserID   string `json:"user_id"`
+	Name     string `json:"name"`
+	ServerID string `json:"server_id"`
+}
+
+// PersonRef is the person an account is mapped to.
+type PersonRef struct {
+	PersonID string
+	Name     string
+}
+
+// ListPeople returns every person with their accounts, by name.
+func ListPeople(ctx context.Context, db *sql.DB) ([]Person, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT um.person_id, um.person_name, um.user_id, COALESCE(u.name, ''), COALESCE(u.server_id, '')
+		FROM user_mappings um
+		LEFT JOIN emby_user u ON u.id = um.user_id
+		ORDER BY um.person_name COLLATE NOCASE, um.person_id, um.user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Person{}
+	for rows.Next() {
+		var personID, personName string
+		var a PersonAccount
+		if err := rows.Scan(&personID, &personName, &a.UserID, &a.Name, &a.ServerID); err != nil {
+			return nil, err
+		}
+		if n := len(out); n == 0 || out[n-1].PersonID != personID {
+			out = append(out, Person{PersonID: personID, Name: personName, Users: []PersonAccount{}})
+		}
+		out[len(out)-1].Users = append(out[len(out)-1].Users, a)
+	}
+	return out, rows.Err()
+}
+
+// GetPerson returns a person with their accounts, or ErrUnknownPerson.
+func GetPerson(ctx context.Context, db *sql.DB, personID string) (Person, error) {
+	return getPerson(ctx, db, personID)
+}
+
+func getPerson(ctx context.Context, q queryer, personID string) (Person, error) {
+	p := Person{PersonID: personID, Users: []PersonAccount{}}
+	rows, err := q.QueryContext(ctx, `
+		SELECT um.person_name, um.user_id, COALESCE(u.name, ''), COALESCE(u.server_id, '')
+		FROM user_mappings um
+		LEFT JOIN emby_user u ON u.id = um.user_id
+		WHERE um.person_id = ?
+		ORDER BY um.user_id
+	`, personID)
+	if err != nil {
+		return p, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a PersonAccount
+		if err := rows.Scan(&p.Name, &a.UserID, &a.Name, &a.ServerID); err != nil {
+			return p, err
+		}
+		p.Users = append(p.Users, a)
+	}
+	if err := rows.Err(); err != nil {
+		return p, err
+	}
+	if len(p.Users) == 0 {
+		return p, ErrUnknownPerson
+	}
+	return p, nil
+}
+
+// UserPeople maps every mapped account to its person.
+func UserPeople(ctx context.Context, db *sql.DB) (map[string]PersonRef, error) {
+	rows, err := db.QueryContext(ctx, `SELECT user_id, person_id, person_name FROM user_mappings`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]PersonRef{}
+	for rows.Next() {
+		var userID string
+		var ref PersonRef
+		if err := rows.Scan(&userID, &ref.PersonID, &ref.Name); err != nil {
+			return nil, err
+		}
+		out[userID] = ref
+	}
+	return out, rows.Err()
+}
+
+// MapUsers maps userIDs onto a person, moving them out of any person they were mapped to.
+// An empty personID creates a new person named name, with an id derived from the name; for
+// an existing person a non-empty name renames it. Every user id must be a known account.
+func MapUsers(ctx context.Context, db *sql.DB, personID, name string, userIDs []string, now int64) (Person, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Person{}, err
+	}
+	defer tx.Rollback()
+
+	if personID == "" {
+		if name == "" {
+			return Person{}, fmt.Errorf("a name is required for a new person")
+		}
+		if personID, err = newPersonID(ctx, tx, name); err != nil {
+			return Person{}, err
+		}
+	} else {
+		cur, err := getPerson(ctx, tx, personID)
+		if err != nil && !errors.Is(err, ErrUnknownPerson) {
+			return Person{}, err
+		}
+		if errors.Is(err, ErrUnknownPerson) && name == "" {
+			return Person{}, fmt.Errorf("%w: %s", ErrUnknownPerson, personID)
+		}
+		if name == "" {
+			name = cur.Name
+		}
+	}
+
+	for _, id := range userIDs {
+		var n int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM emby_user WHERE id = ?`, id).Scan(&n); err != nil {
+			return Person{}, err
+		}
+		if n == 0 {
+			return Person{}, fmt.Errorf("%w: %s", ErrUnknownUser, id)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO user_mappings (user_id, person_id, person_name, updated_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(user_id) DO UPDATE SET person_id = excluded.person_id, person_name = excluded.person_name,
+				updated_at = excluded.updated_at
+		`, id, personID, name, now); err != nil {
+			return Person{}, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE user_mappings SET person_name = ?, updated_at = ? WHERE person_id = ?`, name, now, personID); err != nil {
+		return Person{}, err
+	}
+	p, err := getPerson(ctx, tx, personID)
+	if err != nil {
+		return Person{}, err
+	}
+	return p, tx.Commit()
+}
+
+// UnmapUser takes an account out of its person, reporting whether it was mapped. A person
+// left without accounts is gone.
+func UnmapUser(ctx context.Context, db *sql.DB, userID string) (bool, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM user_mappings WHERE user_id = ?`, userID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// DeletePerson removes a person, leaving their accounts unmapped. It reports whether the
+// person existed.
+func DeletePerson(ctx context.Context, db *sql.DB, personID string) (bool, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM user_mappings WHERE person_id = ?`, personID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// newPersonID derives an unused id from name, e.g. "Jane Doe" becomes "jane-doe", or
+// "jane-doe-2" when that is taken.
+func newPersonID(ctx context.Context, q queryer, name string) (string, error) {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	base := strings.TrimSuffix(b.String(), "-")
+	if base == "" {
+		base = "person"
+	}
+	id := base
+	for i := 2; ; i++ {
+		var n int
+		if err := q.QueryRowContext(ctx, `SELECT COUNT(*) FROM user_mappings WHERE person_id = ?`, id).Scan(&n); err != nil {
+			return "", err
+		}
+		if n == 0 {
+			return id, nil
+		}
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+}
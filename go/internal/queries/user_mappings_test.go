@@ -0,0 +1,71 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUserMappings(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	seed(t, conn, `INSERT INTO emby_user (id, name, server_id) VALUES ('plex::a1', 'alice_p', 'plex')`)
+
+	p, err := MapUsers(ctx, conn, "", "Alice Smith", []string{"alice", "plex::a1"}, 1)
+	if err != nil {
+		t.Fatalf("map: %v", err)
+	}
+	if p.PersonID != "alice-smith" || p.Name != "Alice Smith" || len(p.Users) != 2 || p.Users[1].ServerID != "plex" {
+		t.Errorf("person = %+v", p)
+	}
+
+	// A second person with the same name gets its own id; bob moves from it to alice-smith
+	other, err := MapUsers(ctx, conn, "", "Alice Smith", []string{"bob"}, 2)
+	if err != nil || other.PersonID != "alice-smith-2" {
+		t.Fatalf("second person = %+v, %v", other, err)
+	}
+	if p, err = MapUsers(ctx, conn, "alice-smith", "Alice", []string{"bob"}, 3); err != nil {
+		t.Fatalf("extend: %v", err)
+	}
+	if p.Name != "Alice" || len(p.Users) != 3 {
+		t.Errorf("extended person = %+v", p)
+	}
+	if _, err := GetPerson(ctx, conn, "alice-smith-2"); !errors.Is(err, ErrUnknownPerson) {
+		t.Errorf("emptied person should be gone, got %v", err)
+	}
+
+	if _, err := MapUsers(ctx, conn, "alice-smith", "", []string{"nobody"}, 4); !errors.Is(err, ErrUnknownUser) {
+		t.Errorf("unknown user: %v", err)
+	}
+	if _, err := MapUsers(ctx, conn, "missing", "", []string{"carol"}, 4); !errors.Is(err, ErrUnknownPerson) {
+		t.Errorf("unknown person: %v", err)
+	}
+
+	people, err := UserPeople(ctx, conn)
+	if err != nil {
+		t.Fatalf("user people: %v", err)
+	}
+	want := map[string]PersonRef{
+		"alice":    {PersonID: "alice-smith", Name: "Alice"},
+		"bob":      {PersonID: "alice-smith", Name: "Alice"},
+		"plex::a1": {PersonID: "alice-smith", Name: "Alice"},
+	}
+	if !reflect.DeepEqual(people, want) {
+		t.Errorf("user people = %+v", people)
+	}
+
+	if ok, err := UnmapUser(ctx, conn, "bob"); !ok || err != nil {
+		t.Fatalf("unmap: %v, %v", ok, err)
+	}
+	list, err := ListPeople(ctx, conn)
+	if err != nil || len(list) != 1 || len(list[0].Users) != 2 {
+		t.Fatalf("people = %+v, %v", list, err)
+	}
+	if ok, _ := DeletePerson(ctx, conn, "alice-smith"); !ok {
+		t.Error("delete person reported nothing deleted")
+	}
+	if list, _ = ListPeople(ctx, conn); len(list) != 0 {
+		t.Errorf("people after delete = %+v", list)
+	}
+}
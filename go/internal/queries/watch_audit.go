@@ -0,0 +1,279 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"strings"
+)
+
+// maxEventGap caps the watch time credited between two playback events; a longer silence
+// means events were lost rather than that playback went on unobserved.
+const maxEventGap = 600
+
+// Watch time sources compared by WatchTimeAudit.
+const (
+	AuditSourceEvents    = "events"
+	AuditSourceSummaries = "summaries"
+	AuditSourceServer    = "server"
+)
+
+// AuditSource is one source's watch time next to the interval watch time of the sessions
+// (or items) it covers.
+type AuditSource struct {
+	Source        string  `json:"source"`
+	Sessions      int     `json:"sessions,omitempty"` // sessions the source has data for
+	Items         int     `json:"items,omitempty"`    // items the source has data for
+	Hours         float64 `json:"hours"`
+	IntervalHours float64 `json:"interval_hours"`
+	DiffHours     float64 `json:"diff_hours"` // Hours - IntervalHours
+	DiffPct       float64 `json:"diff_pct"`   // relative to the larger of the two
+	Divergent     bool    `json:"divergent"`
+}
+
+// WatchAuditRow compares one user's watch time as counted by each source, for sessions
+// started in the audited period.
+type WatchAuditRow struct {
+	UserID   string `json:"user_id"`
+	UserName string `json:"user_name"`
+	ServerID string `json:"server_id"`
+	Sessions int    `json:"sessions"`
+	// IntervalHours is what the stats report: the sum of play_intervals durations
+	IntervalHours float64       `json:"interval_hours"`
+	Sources       []AuditSource `json:"sources"`
+	Divergent     bool          `json:"divergent"`
+	// ItemSeconds is the interval watch time per item (stored id)
+	ItemSeconds map[string]float64 `json:"-"`
+}
+
+// AddSource compares s.Hours against s.IntervalHours, flagging the source divergent when
+// the two differ by more than thresholdPct percent and at least minHours.
+func (r *WatchAuditRow) AddSource(s AuditSource, thresholdPct, minHours float64) {
+	diff := s.Hours - s.IntervalHours
+	if larger := math.Max(s.Hours, s.IntervalHours); larger > 0 {
+		s.DiffPct = roundPercent(math.Abs(diff) / larger * 100)
+	}
+	s.Divergent = s.DiffPct > thresholdPct && math.Abs(diff) >= minHours
+	s.Hours = roundAuditHours(s.Hours)
+	s.IntervalHours = roundAuditHours(s.IntervalHours)
+	s.DiffHours = roundAuditHours(diff)
+	r.Divergent = r.Divergent || s.Divergent
+	r.Sources = append(r.Sources, s)
+}
+
+func roundAuditHours(h float64) float64 {
+	return math.Round(h*100) / 100
+}
+
+// playEvent is one play_events row of a session.
+type playEvent struct {
+	Kind     string
+	Paused   bool
+	Position sql.NullInt64 // ticks
+	At       int64
+}
+
+// eventWatchSeconds rebuilds a session's watch time from its events in order. The span
+// after an event counts while playing (not paused, not stopped), up to maxEventGap, and
+// no more than the position advanced when both positions are known.
+func eventWatchSeconds(events []playEvent) float64 {
+	var total float64
+	for i := 0; i+1 < len(events); i++ {
+		a, b := events[i], events[i+1]
+		if a.Paused || a.Kind == "stop" {
+			continue
+		}
+		wall := float64(b.At - a.At)
+		if wall <= 0 {
+			continue
+		}
+		watched := math.Min(wall, maxEventGap)
+		if a.Position.Valid && b.Position.Valid {
+			advanced := float64(b.Position.Int64-a.Position.Int64) / 1e7
+			if advanced <= 0 {
+				continue // seeked back or stalled
+			}
+			watched = math.Min(watched, advanced)
+		}
+		total += watched
+	}
+	return total
+}
+
+// WatchTimeAudit compares, per user with sessions started in [from, to), the watch time
+// from play intervals with the time rebuilt from play events and the poll-based active
+// time of session summaries. Each source is compared only over the sessions it has data
+// for, since events are only recorded for Emby and summaries for recent sessions. userID
+// optionally limits it to one user, serverType (lower-case) or serverID to one server kind
+// or instance.
+func WatchTimeAudit(ctx context.Context, db *sql.DB, from, to int64, userID, serverType, serverID string, thresholdPct, minHours float64) ([]WatchAuditRow, error) {
+	const sessionFilter = `ps.started_at >= ? AND ps.started_at < ?
+		  AND (? = '' OR ps.user_id = ?)
+		  AND (? = '' OR LOWER(COALESCE(ps.server_type, '')) = ?)
+		  AND (? = '' OR ps.server_id = ?)`
+	args := []any{from, to, userID, userID, serverType, serverType, serverID, serverID}
+
+	// Event watch time per session
+	eventSeconds := map[int64]float64{}
+	rows, err := db.QueryContext(ctx, `
+		SELECT pe.session_fk, pe.kind, pe.is_paused, pe.position_ticks, pe.created_at
+		FROM play_events pe
+		JOIN play_sessions ps ON ps.id = pe.session_fk
+		WHERE `+sessionFilter+`
+		ORDER BY pe.session_fk, pe.created_at, pe.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	var events []playEvent
+	var session int64
+	for rows.Next() {
+		var e playEvent
+		var fk int64
+		if err := rows.Scan(&fk, &e.Kind, &e.Paused, &e.Position, &e.At); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if fk != session && len(events) > 0 {
+			eventSeconds[session] = eventWatchSeconds(events)
+			events = events[:0]
+		}
+		session = fk
+		events = append(events, e)
+	}
+	if len(events) > 0 {
+		eventSeconds[session] = eventWatchSeconds(events)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT ps.id, ps.user_id, COALESCE(u.name, ps.user_name, ps.user_id), COALESCE(ps.server_id, ''),
+		       COALESCE(iv.seconds, 0), ss.active_seconds
+		FROM play_sessions ps
+		LEFT JOIN emby_user u ON u.id = ps.user_id
+		LEFT JOIN (
+			SELECT session_fk, SUM(duration_seconds) AS seconds FROM play_intervals GROUP BY session_fk
+		) iv ON iv.session_fk = ps.id
+		LEFT JOIN session_summaries ss ON ss.session_fk = ps.id
+		WHERE `+sessionFilter+`
+		ORDER BY ps.user_id, ps.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	type sourceTotals struct {
+		sessions        int
+		seconds, basSec float64
+	}
+	type userTotals struct {
+		row               WatchAuditRow
+		events, summaries sourceTotals
+	}
+	var totals []*userTotals
+	index := map[string]*userTotals{}
+	for rows.Next() {
+		var id int64
+		var uid, name, sid string
+		var seconds float64
+		var active sql.NullInt64
+		if err := rows.Scan(&id, &uid, &name, &sid, &seconds, &active); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		t := index[uid]
+		if t == nil {
+			t = &userTotals{row: WatchAuditRow{UserID: uid, UserName: name, ServerID: sid, Sources: []AuditSource{}, ItemSeconds: map[string]float64{}}}
+			index[uid] = t
+			totals = append(totals, t)
+		}
+		t.row.Sessions++
+		t.row.IntervalHours += seconds / 3600
+		if ev, ok := eventSeconds[id]; ok {
+			t.events.sessions++
+			t.events.seconds += ev
+			t.events.basSec += seconds
+		}
+		if active.Valid {
+			t.summaries.sessions++
+			t.summaries.seconds += float64(active.Int64)
+			t.summaries.basSec += seconds
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT ps.user_id, pi.item_id, SUM(pi.duration_seconds)
+		FROM play_intervals pi
+		JOIN play_sessions ps ON ps.id = pi.session_fk
+		WHERE `+sessionFilter+`
+		GROUP BY 1, 2`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var uid, item string
+		var seconds float64
+		if err := rows.Scan(&uid, &item, &seconds); err != nil {
+			return nil, err
+		}
+		if t := index[uid]; t != nil {
+			t.row.ItemSeconds[item] = seconds
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]WatchAuditRow, 0, len(totals))
+	for _, t := range totals {
+		r := t.row
+		if t.events.sessions > 0 {
+			r.AddSource(AuditSource{Source: AuditSourceEvents, Sessions: t.events.sessions,
+				Hours: t.events.seconds / 3600, IntervalHours: t.events.basSec / 3600}, thresholdPct, minHours)
+		}
+		if t.summaries.sessions > 0 {
+			r.AddSource(AuditSource{Source: AuditSourceSummaries, Sessions: t.summaries.sessions,
+				Hours: t.summaries.seconds / 3600, IntervalHours: t.summaries.basSec / 3600}, thresholdPct, minHours)
+		}
+		r.IntervalHours = roundAuditHours(r.IntervalHours)
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// ItemRuntimeHours returns the library runtime of each known item id, in hours.
+func ItemRuntimeHours(ctx context.Context, db *sql.DB, ids []string) (map[string]float64, error) {
+	out := map[string]float64{}
+	for start := 0; start < len(ids); start += 500 {
+		chunk := ids[start:min(start+500, len(ids))]
+		args := make([]any, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+		rows, err := db.QueryContext(ctx, `
+			SELECT id, COALESCE(run_time_ticks, 0) / 36000000000.0 FROM library_item
+			WHERE id IN (?`+strings.Repeat(", ?", len(chunk)-1)+`)`, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id string
+			var hours float64
+			if err := rows.Scan(&id, &hours); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			out[id] = hours
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,110 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+func TestEventWatchSeconds(t *testing.T) {
+	pos := func(sec int64) sql.NullInt64 { return sql.NullInt64{Int64: sec * 1e7, Valid: true} }
+	events := []playEvent{
+		{Kind: "start", Position: pos(0), At: 0},
+		{Kind: "progress", Position: pos(300), At: 300},               // +300
+		{Kind: "progress", Paused: true, Position: pos(400), At: 400}, // +100
+		{Kind: "progress", Position: pos(400), At: 1000},              // paused: +0
+		{Kind: "progress", Position: pos(100), At: 1200},              // seeked back: +0
+		{Kind: "progress", Position: pos(5000), At: 3000},             // gap capped: +600
+		{Kind: "progress", Position: sql.NullInt64{}, At: 3100},       // position unknown: +100
+		{Kind: "stop", Position: pos(5200), At: 3200},                 // +100
+		{Kind: "start", Position: pos(5200), At: 9000},                // after stop: +0
+	}
+	if got := eventWatchSeconds(events); !approx(got, 1200) {
+		t.Fatalf("eventWatchSeconds = %v, want 1200", got)
+	}
+}
+
+func TestWatchTimeAudit(t *testing.T) {
+	conn := openFixtureDB(t)
+	ctx := context.Background()
+	// alice's Movie A session has progress events every five minutes, which agree with
+	// the intervals; bob's summary claims twice his interval time
+	for at := int64(1000); at <= 4600; at += 300 {
+		kind := "progress"
+		if at == 4600 {
+			kind = "stop"
+		}
+		if _, err := conn.Exec(`INSERT INTO play_events (session_fk, kind, is_paused, position_ticks, created_at) VALUES (1, ?, 0, ?, ?)`,
+			kind, (at-1000)*1e7, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seed(t, conn, `INSERT INTO session_summaries (session_fk, active_seconds, finalized_at) VALUES (2, 3600, 4600)`)
+
+	rows, err := WatchTimeAudit(ctx, conn, 0, 10000, "", "", "", 10, 0.25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byUser := map[string]WatchAuditRow{}
+	for _, r := range rows {
+		byUser[r.UserID] = r
+	}
+	if len(byUser) != 3 {
+		t.Fatalf("rows = %+v", rows)
+	}
+
+	alice := byUser["alice"]
+	if alice.Sessions != 2 || !approx(alice.IntervalHours, 2) || alice.Divergent {
+		t.Fatalf("alice = %+v", alice)
+	}
+	if len(alice.Sources) != 1 || alice.Sources[0].Source != AuditSourceEvents ||
+		!approx(alice.Sources[0].Hours, 1) || !approx(alice.Sources[0].IntervalHours, 1) {
+		t.Fatalf("alice sources = %+v", alice.Sources)
+	}
+	if !approx(alice.ItemSeconds["movie-a"], 3600) || !approx(alice.ItemSeconds["chan-1"], 3600) {
+		t.Fatalf("alice items = %v", alice.ItemSeconds)
+	}
+
+	bob := byUser["bob"]
+	if !bob.Divergent || len(bob.Sources) != 1 {
+		t.Fatalf("bob = %+v", bob)
+	}
+	s := bob.Sources[0]
+	if s.Source != AuditSourceSummaries || !approx(s.Hours, 1) || !approx(s.IntervalHours, 0.5) ||
+		!approx(s.DiffHours, 0.5) || !approx(s.DiffPct, 50) {
+		t.Fatalf("bob source = %+v", s)
+	}
+
+	if len(byUser["carol"].Sources) != 0 || byUser["carol"].Divergent {
+		t.Fatalf("carol = %+v", byUser["carol"])
+	}
+
+	// a wider threshold accepts bob's summary, and filters narrow the audit
+	rows, err = WatchTimeAudit(ctx, conn, 0, 10000, "bob", "emby", "", 60, 0.25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Divergent {
+		t.Fatalf("bob only = %+v", rows)
+	}
+	rows, err = WatchTimeAudit(ctx, conn, 4000, 10000, "", "", "", 10, 0.25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].UserID != "alice" || rows[0].Sessions != 1 {
+		t.Fatalf("late window = %s", fmt.Sprint(rows))
+	}
+}
+
+func TestItemRuntimeHours(t *testing.T) {
+	conn := openFixtureDB(t)
+	seed(t, conn, `UPDATE library_item SET run_time_ticks = 72000000000 WHERE id = 'movie-a'`)
+	got, err := ItemRuntimeHours(context.Background(), conn, []string{"movie-a", "movie-b", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !approx(got["movie-a"], 2) || got["movie-b"] != 0 {
+		t.Fatalf("runtimes = %v", got)
+	}
+}
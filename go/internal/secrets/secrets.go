@@ -0,0 +1,117 @@
+// Package secrets seals credentials stored in the database (media server API keys) with
+// AES-GCM. The key comes from SECRET_KEY or, when that is unset, from a random key file
+// kept next to the database, so a copied database alone doesn't reveal the credentials.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// sealedPrefix marks sealed values; anything else is read back as plain text, which is how
+// values stored before sealing existed are kept readable.
+const sealedPrefix = "enc:v1:"
+
+// ErrNoKey reports that no key is configured, so values can neither be sealed nor opened.
+var ErrNoKey = errors.New("no secret key is configured")
+
+var aead atomic.Pointer[cipher.AEAD]
+
+// Configure sets the key values are sealed with; any length works as it is hashed.
+func Configure(key []byte) error {
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	aead.Store(&gcm)
+	return nil
+}
+
+// LoadKey returns the passphrase when set, else the key in path, creating the file with a
+// random key on first use.
+func LoadKey(passphrase, path string) ([]byte, error) {
+	if passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	if b, err := os.ReadFile(path); err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+		if err != nil || len(key) == 0 {
+			return nil, fmt.Errorf("%s: not a hex key", path)
+		}
+		return key, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0o600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Seal encrypts a value for storage. Without a configured key values are returned as given,
+// so callers that must not store plain text check Enabled first.
+func Seal(plain string) (string, error) {
+	g := aead.Load()
+	if g == nil || plain == "" {
+		return plain, nil
+	}
+	nonce := make([]byte, (*g).NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	out := (*g).Seal(nonce, nonce, []byte(plain), nil)
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(out), nil
+}
+
+// Open decrypts a value read from storage; values that were never sealed are returned as is.
+func Open(stored string) (string, error) {
+	if !IsSealed(stored) {
+		return stored, nil
+	}
+	g := aead.Load()
+	if g == nil {
+		return "", fmt.Errorf("value is sealed but %w", ErrNoKey)
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(stored, sealedPrefix))
+	if err != nil || len(raw) < (*g).NonceSize() {
+		return "", errors.New("malformed sealed value")
+	}
+	ns := (*g).NonceSize()
+	plain, err := (*g).Open(nil, raw[:ns], raw[ns:], nil)
+	if err != nil {
+		return "", errors.New("sealed value does not match the secret key (was SECRET_KEY changed?)")
+	}
+	return string(plain), nil
+}
+
+// IsSealed reports whether a stored value was sealed.
+func IsSealed(stored string) bool {
+	return strings.HasPrefix(stored, sealedPrefix)
+}
+
+// Enabled reports whether a key is configured.
+func Enabled() bool {
+	return aead.Load() != nil
+}
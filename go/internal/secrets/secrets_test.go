@@ -0,0 +1,117 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// configure sets key for one test and clears it afterwards; nil leaves no key configured.
+func configure(t *testing.T, key []byte) {
+	t.Helper()
+	aead.Store(nil)
+	t.Cleanup(func() { aead.Store(nil) })
+	if key != nil {
+		if err := Configure(key); err != nil {
+			t.Fatalf("configure: %v", err)
+		}
+	}
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	configure(t, []byte("passphrase"))
+
+	sealed, err := Seal("api-key-123")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if !IsSealed(sealed) || strings.Contains(sealed, "api-key-123") {
+		t.Fatalf("sealed = %q", sealed)
+	}
+	if again, _ := Seal("api-key-123"); again == sealed {
+		t.Error("sealing twice gave the same value; nonces must differ")
+	}
+	if plain, err := Open(sealed); err != nil || plain != "api-key-123" {
+		t.Errorf("open = %q, %v", plain, err)
+	}
+	if empty, _ := Seal(""); empty != "" {
+		t.Errorf("an empty value is sealed as %q", empty)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	configure(t, []byte("old"))
+	sealed, _ := Seal("secret")
+
+	configure(t, []byte("new"))
+	if _, err := Open(sealed); err == nil || !strings.Contains(err.Error(), "does not match the secret key") {
+		t.Errorf("wrong key: %v", err)
+	}
+	for _, v := range []string{"enc:v1:not base64!", "enc:v1:AAAA", sealedPrefix} {
+		if _, err := Open(v); err == nil || err.Error() != "malformed sealed value" {
+			t.Errorf("Open(%q) = %v, want malformed", v, err)
+		}
+	}
+
+	configure(t, nil)
+	if _, err := Open(sealed); !errors.Is(err, ErrNoKey) {
+		t.Errorf("no key: %v", err)
+	}
+}
+
+func TestPlainTextPassthrough(t *testing.T) {
+	configure(t, nil)
+	if Enabled() {
+		t.Fatal("enabled without a key")
+	}
+	// Without a key Seal stores values as given; callers check Enabled
+	if v, err := Seal("plain"); err != nil || v != "plain" {
+		t.Errorf("seal without key = %q, %v", v, err)
+	}
+
+	configure(t, []byte("k"))
+	// Values stored before sealing existed read back unchanged
+	if v, err := Open("plain"); err != nil || v != "plain" {
+		t.Errorf("open plain = %q, %v", v, err)
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "secret.key")
+
+	if key, err := LoadKey("from-env", path); err != nil || string(key) != "from-env" {
+		t.Fatalf("passphrase = %q, %v", key, err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("a passphrase must not create the key file")
+	}
+
+	created, err := LoadKey("", path)
+	if err != nil || len(created) != 32 {
+		t.Fatalf("create = %x, %v", created, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("key file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("key file mode = %o, want 600", perm)
+	}
+	if b, _ := os.ReadFile(path); strings.TrimSpace(string(b)) != hex.EncodeToString(created) {
+		t.Errorf("key file = %q", b)
+	}
+	if again, err := LoadKey("", path); err != nil || !bytes.Equal(again, created) {
+		t.Errorf("reload = %x, %v; want %x", again, err, created)
+	}
+
+	if err := os.WriteFile(path, []byte("not hex\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKey("", path); err == nil || !strings.Contains(err.Error(), "not a hex key") {
+		t.Errorf("bad key file: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package sync
 import (
 	"context"
 	"database/sql"
+	"sync/atomic"
 	"time"
 
 	"emby-analytics/internal/emby"
@@ -16,6 +17,15 @@ type Scheduler struct {
 	rm     RefreshManager
 	ctx    context.Context
 	cancel context.CancelFunc
+	paused atomic.Bool
+	jobs   []Job
+}
+
+// Job is work the scheduler runs every minute alongside session ingest. Run decides for
+// itself whether it is due, e.g. by comparing now with a stored last-run time.
+type Job struct {
+	Name string
+	Run  func(now time.Time)
 }
 
 // NewScheduler creates a new sync scheduler
@@ -30,6 +40,11 @@ func NewScheduler(db *sql.DB, em *emby.Client, rm RefreshManager) *Scheduler {
 	}
 }
 
+// AddJob registers a job; jobs must be added before Start.
+func (s *Scheduler) AddJob(name string, run func(now time.Time)) {
+	s.jobs = append(s.jobs, Job{Name: name, Run: run})
+}
+
 // Start begins the automatic sync scheduling
 func (s *Scheduler) Start() {
 	logging.Info("Starting smart sync scheduler")
@@ -58,21 +73,31 @@ func (s *Scheduler) Start() {
 				return
 
 			case <-initialTimer.C:
+				if s.Paused() {
+					continue
+				}
 				logging.Info("Running initial incremental sync")
 				s.runIncrementalSync()
 
 			case <-incrementalTicker.C:
+				if s.Paused() {
+					logging.Debug("Skipping scheduled incremental sync - scheduler paused")
+					continue
+				}
 				logging.Info("Running scheduled incremental sync")
 				s.runIncrementalSync()
 
 			case <-dailyTicker.C:
-				if s.shouldRunDailySync() {
+				if !s.Paused() && s.shouldRunDailySync() {
 					logging.Info("Running nightly full sync")
 					s.runFullSync()
 				}
 
 			case <-ingestTicker.C:
-				s.runActiveSessionIngest()
+				if !s.Paused() {
+					s.runActiveSessionIngest()
+					s.runJobs()
+				}
 			}
 		}
 	}()
@@ -85,6 +110,42 @@ func (s *Scheduler) Stop() {
 	}
 }
 
+// Pause skips scheduled syncs and session ingests until Resume; a sync already
+// running is left to finish.
+func (s *Scheduler) Pause() {
+	if !s.paused.Swap(true) {
+		logging.Info("Sync scheduler paused")
+	}
+}
+
+// Resume restarts scheduled syncs from the next tick.
+func (s *Scheduler) Resume() {
+	if s.paused.Swap(false) {
+		logging.Info("Sync scheduler resumed")
+	}
+}
+
+// Paused reports whether the scheduler is paused.
+func (s *Scheduler) Paused() bool {
+	return s.paused.Load()
+}
+
+// runJobs runs each registered job, recovering from a panicking one so the scheduler
+// keeps going.
+func (s *Scheduler) runJobs() {
+	now := time.Now()
+	for _, j := range s.jobs {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logging.Error("Scheduled job panicked", "job", j.Name, "panic", r)
+				}
+			}()
+			j.Run(now)
+		}()
+	}
+}
+
 // runIncrementalSync performs an incremental sync if conditions are met
 func (s *Scheduler) runIncrementalSync() {
 	// Check if refresh manager is already running
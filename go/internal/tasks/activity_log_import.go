@@ -0,0 +1,206 @@
+package tasks
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/emby"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+)
+
+const (
+	activityLogPageSize = 500
+	// activityLogMaxSession caps a synthesized session when start/stop are far apart
+	activityLogMaxSession = 6 * time.Hour
+	// activityLogMinSession drops start/stop pairs too short to be real viewing
+	activityLogMinSession = time.Minute
+	// activitySessionPrefix marks play_sessions rows created by the importer
+	activitySessionPrefix = "activitylog-"
+)
+
+// ActivityLogImportResult summarizes one activity log import run.
+type ActivityLogImportResult struct {
+	Cutoff    time.Time `json:"cutoff"`
+	Since     time.Time `json:"since"`
+	Entries   int       `json:"entries_scanned"`
+	Playback  int       `json:"playback_events"`
+	Imported  int       `json:"sessions_imported"`
+	Skipped   int       `json:"sessions_skipped"`
+	Unmatched int       `json:"unmatched_stops"`
+}
+
+type activityEvent struct {
+	entry emby.ActivityLogEntry
+	at    time.Time
+	start bool
+}
+
+// isPlaybackActivity classifies activity log types from Emby ("playback.start")
+// and Jellyfin-style ("VideoPlayback"/"VideoPlaybackStopped") servers.
+func isPlaybackActivity(t string) (isPlayback, start bool) {
+	switch strings.ToLower(t) {
+	case "playback.start", "videoplayback", "audioplayback":
+		return true, true
+	case "playback.stop", "videoplaybackstopped", "audioplaybackstopped":
+		return true, false
+	}
+	return false, false
+}
+
+// activityImportCutoff returns when live tracking began for serverID: entries
+// before it are imported, later ones are already covered by real sessions.
+// Sessions synthesized by the importers never move the cutoff.
+func activityImportCutoff(db *sql.DB, serverID string) time.Time {
+	var first sql.NullInt64
+	_ = db.QueryRow(`
+		SELECT MIN(started_at) FROM play_sessions
+		WHERE COALESCE(server_id, '') IN (?, '') AND session_id NOT LIKE ? AND session_id NOT LIKE ?
+		  AND session_id NOT LIKE ? AND session_id NOT LIKE ?`,
+		serverID, activitySessionPrefix+"%", historySessionPrefix+"%", tautulliSessionPrefix+"%",
+		playbackReportingSessionPrefix+"%").Scan(&first)
+	if first.Valid && first.Int64 > 0 {
+		return time.Unix(first.Int64, 0).UTC()
+	}
+	return time.Now().UTC()
+}
+
+// ImportEmbyActivityLog pages through the server's activity log for playback
+// start/stop events older than our first tracked session and synthesizes
+// approximate play_sessions/play_intervals from them. Each start is paired with
+// the next stop for the same user and item; unpaired starts fall back to the
+// item's runtime. Re-running the import skips sessions it already created.
+func ImportEmbyActivityLog(db *sql.DB, em *emby.Client, serverID string, serverType media.ServerType, days int) (*ActivityLogImportResult, error) {
+	if em == nil {
+		return nil, fmt.Errorf("emby client not configured")
+	}
+	cutoff := activityImportCutoff(db, serverID)
+	res := &ActivityLogImportResult{Cutoff: cutoff, Since: cutoff.AddDate(0, 0, -days)}
+
+	var events []activityEvent
+	for start := 0; ; start += activityLogPageSize {
+		page, total, err := em.GetActivityLog(start, activityLogPageSize, res.Since)
+		if err != nil {
+			return res, err
+		}
+		res.Entries += len(page)
+		for _, e := range page {
+			ok, isStart := isPlaybackActivity(e.Type)
+			if !ok || e.UserId == "" || e.ItemId == "" {
+				continue
+			}
+			at, err := time.Parse(time.RFC3339, e.Date)
+			if err != nil || !at.Before(cutoff) {
+				continue
+			}
+			events = append(events, activityEvent{entry: e, at: at.UTC(), start: isStart})
+		}
+		if len(page) < activityLogPageSize || start+len(page) >= total {
+			break
+		}
+	}
+	res.Playback = len(events)
+	sort.Slice(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+
+	runtimes := activityItemRuntimes(db, em, serverID, string(serverType), events)
+	open := map[string]activityEvent{}
+	for _, ev := range events {
+		key := ev.entry.UserId + "|" + ev.entry.ItemId
+		if ev.start {
+			// A second start without a stop closes the previous one at its runtime
+			if prev, ok := open[key]; ok {
+				importActivitySession(db, res, serverID, string(serverType), prev, prev.at.Add(runtimes[prev.entry.ItemId]), runtimes)
+			}
+			open[key] = ev
+			continue
+		}
+		prev, ok := open[key]
+		if !ok {
+			res.Unmatched++
+			continue
+		}
+		delete(open, key)
+		importActivitySession(db, res, serverID, string(serverType), prev, ev.at, runtimes)
+	}
+	for _, prev := range open {
+		importActivitySession(db, res, serverID, string(serverType), prev, prev.at.Add(runtimes[prev.entry.ItemId]), runtimes)
+	}
+
+	logging.Info("Activity log import finished", "server_id", serverID, "entries", res.Entries,
+		"playback_events", res.Playback, "imported", res.Imported, "skipped", res.Skipped)
+	return res, nil
+}
+
+// activityItemRuntimes returns item runtimes from library_item, fetching names
+// for items the library has not seen so imported sessions get titles.
+func activityItemRuntimes(db *sql.DB, em *emby.Client, serverID, serverType string, events []activityEvent) map[string]time.Duration {
+	out := map[string]time.Duration{}
+	missing := []string{}
+	seen := map[string]bool{}
+	for _, ev := range events {
+		id := ev.entry.ItemId
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		var ticks sql.NullInt64
+		err := db.QueryRow(`SELECT run_time_ticks FROM library_item WHERE id = ?`, storageItemID(serverID, id)).Scan(&ticks)
+		if err == sql.ErrNoRows {
+			missing = append(missing, id)
+			continue
+		}
+		if ticks.Valid && ticks.Int64 > 0 {
+			out[id] = time.Duration(ticks.Int64 * 100)
+		}
+	}
+	for len(missing) > 0 {
+		n := min(len(missing), 50)
+		items, err := em.ItemsByIDs(missing[:n])
+		if err != nil {
+			logging.Debug("Activity log import: item lookup failed", "error", err)
+			break
+		}
+		for _, it := range items {
+			_, _ = db.Exec(`
+				INSERT OR IGNORE INTO library_item (id, server_id, server_type, item_id, name, media_type, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+				storageItemID(serverID, it.Id), serverID, serverType, it.Id, it.Name, it.Type)
+		}
+		missing = missing[n:]
+	}
+	return out
+}
+
+func importActivitySession(db *sql.DB, res *ActivityLogImportResult, serverID, serverType string, start activityEvent, end time.Time, runtimes map[string]time.Duration) {
+	dur := end.Sub(start.at)
+	if rt := runtimes[start.entry.ItemId]; rt > 0 && dur > rt {
+		dur = rt
+	}
+	if dur > activityLogMaxSession {
+		dur = activityLogMaxSession
+	}
+	if dur < activityLogMinSession {
+		res.Skipped++
+		return
+	}
+	end = start.at.Add(dur)
+	sessionID := fmt.Sprintf("%s%d", activitySessionPrefix, start.entry.Id)
+
+	created, err := insertImportedSession(retryExecer{db}, importedSession{
+		ServerID: serverID, ServerType: serverType, SessionID: sessionID,
+		UserID: start.entry.UserId, ItemID: start.entry.ItemId,
+		StartedAt: start.at.Unix(), EndedAt: end.Unix(),
+		Intervals: []importedInterval{{Start: start.at.Unix(), End: end.Unix(), Seconds: int64(dur.Seconds())}},
+	})
+	if err != nil {
+		logging.Debug("Activity log import: insert session failed", "error", err)
+	}
+	if !created {
+		res.Skipped++
+		return
+	}
+	res.Imported++
+}
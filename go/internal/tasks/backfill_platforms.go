@@ -0,0 +1,84 @@
+package tasks
+
+import (
+	"database/sql"
+	"strings"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+)
+
+const sessionPlatformBackfillKey = "session_platform_backfill_done"
+
+// BackfillSessionPlatforms derives the platform of sessions recorded before it was captured
+// from their client and device names. Client versions weren't reported back then and stay
+// empty. Runs once.
+func BackfillSessionPlatforms(db *sql.DB) {
+	if db == nil {
+		return
+	}
+	if done, err := getSettingValue(db, sessionPlatformBackfillKey); err == nil {
+		if strings.EqualFold(strings.TrimSpace(done), "true") {
+			return
+		}
+	}
+
+	rows, err := db.Query(`
+		SELECT DISTINCT COALESCE(client_name, ''), COALESCE(device_id, '')
+		FROM play_sessions
+		WHERE platform IS NULL
+	`)
+	if err != nil {
+		logging.Warn("session platform backfill: failed to list clients", "error", err)
+		return
+	}
+	type pair struct{ client, device string }
+	var pairs []pair
+	for rows.Next() {
+		var p pair
+		if err := rows.Scan(&p.client, &p.device); err == nil {
+			pairs = append(pairs, p)
+		}
+	}
+	rows.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		logging.Warn("session platform backfill: failed to begin transaction", "error", err)
+		return
+	}
+	stmt, err := tx.Prepare(`
+		UPDATE play_sessions SET platform = ?
+		WHERE platform IS NULL AND COALESCE(client_name, '') = ? AND COALESCE(device_id, '') = ?
+	`)
+	if err != nil {
+		_ = tx.Rollback()
+		logging.Warn("session platform backfill: failed to prepare statement", "error", err)
+		return
+	}
+	updated := 0
+	for _, p := range pairs {
+		platform := media.NormalizePlatform("", p.client, p.device)
+		if platform == "" {
+			continue
+		}
+		res, err := stmt.Exec(platform, p.client, p.device)
+		if err != nil {
+			logging.Debug("session platform backfill: update failed", "client", p.client, "error", err)
+			continue
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			updated += int(n)
+		}
+	}
+	_ = stmt.Close()
+	if err := tx.Commit(); err != nil {
+		logging.Warn("session platform backfill: failed to commit transaction", "error", err)
+		return
+	}
+
+	if updated > 0 {
+		logging.Info("session platform backfill completed", "updated", updated)
+	}
+	_ = setSettingValue(db, sessionPlatformBackfillKey, "true")
+}
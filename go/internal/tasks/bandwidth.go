@@ -0,0 +1,56 @@
+package tasks
+
+import (
+	"time"
+
+	dbutil "emby-analytics/internal/db"
+	"emby-analytics/internal/media"
+)
+
+const (
+	// bandwidthSampleInterval is how often the outbound bitrate of playing streams is sampled
+	bandwidthSampleInterval = time.Minute
+	// bandwidthMaxSampleSpan caps the time one sample stands for, so a gap in polling (a
+	// restart, an unreachable server) isn't filled with the bitrate seen after it
+	bandwidthMaxSampleSpan = 5 * time.Minute
+)
+
+// sessionBitrate is the bitrate a session is delivered at: the transcode output while
+// transcoding, the source bitrate otherwise.
+func sessionBitrate(s media.Session) int64 {
+	if s.Bitrate > 0 {
+		return s.Bitrate
+	}
+	return s.TranscodeBitrate
+}
+
+// sampleBandwidth records the bitrate of every playing stream into bandwidth_samples, at
+// most once per bandwidthSampleInterval. Live TV and users excluded from stats are sampled
+// too, since they load the server like any other stream. Callers hold sp.mu.
+func (sp *SessionProcessor) sampleBandwidth(sessions []media.Session, now time.Time) {
+	if !sp.lastBandwidthSample.IsZero() && now.Sub(sp.lastBandwidthSample) < bandwidthSampleInterval {
+		return
+	}
+	span := bandwidthSampleInterval
+	if !sp.lastBandwidthSample.IsZero() {
+		span = now.Sub(sp.lastBandwidthSample)
+		if span > bandwidthMaxSampleSpan {
+			span = bandwidthMaxSampleSpan
+		}
+	}
+	sp.lastBandwidthSample = now
+
+	for _, s := range sessions {
+		bitrate := sessionBitrate(s)
+		if s.IsPaused || bitrate <= 0 || s.ServerID == "" || s.UserID == "" {
+			continue
+		}
+		_, err := dbutil.ExecWithRetry(sp.DB, `
+			INSERT INTO bandwidth_samples (ts, server_id, server_type, user_id, user_name, session_id, play_method, bitrate_bps, seconds)
+			VALUES (?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?)
+		`, now.Unix(), s.ServerID, string(s.ServerType), s.UserID, s.UserName, s.SessionID, s.PlayMethod, bitrate, int64(span.Seconds()))
+		if err != nil {
+			spLog.Debug("failed to record bandwidth sample", "session", s.SessionID, "error", err)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package tasks
 import (
 	"context"
 	"database/sql"
+	"sync/atomic"
 	"time"
 
 	"emby-analytics/internal/audit"
@@ -18,6 +19,7 @@ type CleanupScheduler struct {
 	ctx          context.Context
 	cancel       context.CancelFunc
 	intervalizer *Intervalizer
+	paused       atomic.Bool
 }
 
 // NewCleanupScheduler creates a new cleanup scheduler
@@ -56,17 +58,20 @@ func (s *CleanupScheduler) Start() {
 
 			case <-initialTimer.C:
 				logging.Debug("Running initial cleanup check")
-				if s.shouldRunWeeklyCleanup() {
+				if !s.Paused() && s.shouldRunWeeklyCleanup() {
 					s.runWeeklyCleanup()
 				}
 
 			case <-weeklyTicker.C:
-				if s.shouldRunWeeklyCleanup() {
+				if !s.Paused() && s.shouldRunWeeklyCleanup() {
 					logging.Info("Running scheduled weekly cleanup")
 					s.runWeeklyCleanup()
 				}
 			case <-timeoutTicker.C:
-				s.intervalizer.TickTimeoutSweep()
+				// Stale sessions are swept on the first tick after resuming
+				if !s.Paused() {
+					s.intervalizer.TickTimeoutSweep()
+				}
 			}
 		}
 	}()
@@ -79,6 +84,25 @@ func (s *CleanupScheduler) Stop() {
 	}
 }
 
+// Pause skips the weekly cleanup and the session timeout sweep until Resume.
+func (s *CleanupScheduler) Pause() {
+	if !s.paused.Swap(true) {
+		logging.Info("Cleanup scheduler paused")
+	}
+}
+
+// Resume restarts scheduled cleanups from the next tick.
+func (s *CleanupScheduler) Resume() {
+	if s.paused.Swap(false) {
+		logging.Info("Cleanup scheduler resumed")
+	}
+}
+
+// Paused reports whether the scheduler is paused.
+func (s *CleanupScheduler) Paused() bool {
+	return s.paused.Load()
+}
+
 // runWeeklyCleanup performs automatic cleanup of stale library items
 func (s *CleanupScheduler) runWeeklyCleanup() {
 	if s.em == nil {
@@ -0,0 +1,150 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/notify"
+	"emby-analytics/internal/queries"
+)
+
+// SummaryDestination is a chat destination that receives the daily summary at a
+// local time in its own timezone.
+type SummaryDestination struct {
+	Name     string // "discord", "telegram"; also used to remember the last delivery
+	Hour     int
+	Minute   int
+	Location *time.Location
+	Send     func(notify.Summary) error
+}
+
+// DailySummaryScheduler posts yesterday's activity summary to each destination once per day
+type DailySummaryScheduler struct {
+	db    *sql.DB
+	dests []SummaryDestination
+	quit  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewDailySummaryScheduler creates a new daily summary scheduler
+func NewDailySummaryScheduler(db *sql.DB, dests []SummaryDestination) *DailySummaryScheduler {
+	return &DailySummaryScheduler{db: db, dests: dests, quit: make(chan struct{})}
+}
+
+// ParseSummaryTime parses "HH:MM" (24h), defaulting to 08:00.
+func ParseSummaryTime(v string) (int, int) {
+	t, err := time.Parse("15:04", strings.TrimSpace(v))
+	if err != nil {
+		return 8, 0
+	}
+	return t.Hour(), t.Minute()
+}
+
+// LoadSummaryLocation resolves an IANA timezone name; empty or unknown falls back to the server's local zone.
+func LoadSummaryLocation(name string) *time.Location {
+	if strings.TrimSpace(name) == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		logging.Warn("Invalid summary timezone, using local time", "timezone", name, "error", err)
+		return time.Local
+	}
+	return loc
+}
+
+// Start begins checking once a minute whether a destination is due
+func (s *DailySummaryScheduler) Start() {
+	if len(s.dests) == 0 {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.quit:
+				return
+			case <-ticker.C:
+				s.checkDue(time.Now())
+			}
+		}
+	}()
+	logging.Info("Daily summary scheduler started", "destinations", len(s.dests))
+}
+
+// Stop gracefully stops the scheduler
+func (s *DailySummaryScheduler) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+func (s *DailySummaryScheduler) checkDue(now time.Time) {
+	for _, d := range s.dests {
+		local := now.In(d.Location)
+		due := time.Date(local.Year(), local.Month(), local.Day(), d.Hour, d.Minute, 0, 0, d.Location)
+		if local.Before(due) {
+			continue
+		}
+		today := local.Format("2006-01-02")
+		key := "daily_summary_last_sent_" + d.Name
+		if last, _ := getSettingValue(s.db, key); last == today {
+			continue
+		}
+
+		summary, err := BuildDailySummary(context.Background(), s.db, local.AddDate(0, 0, -1), d.Location)
+		if err != nil {
+			logging.Warn("Failed to build daily summary", "destination", d.Name, "error", err)
+			continue
+		}
+		if err := d.Send(summary); err != nil {
+			logging.Warn("Failed to send daily summary", "destination", d.Name, "error", err)
+			continue
+		}
+		_ = setSettingValue(s.db, key, today)
+		logging.Info("Daily summary sent", "destination", d.Name, "date", summary.Date)
+	}
+}
+
+// BuildDailySummary computes streams, watch hours, new library items and the top
+// user for the calendar day containing day, in loc.
+func BuildDailySummary(ctx context.Context, db *sql.DB, day time.Time, loc *time.Location) (notify.Summary, error) {
+	day = day.In(loc)
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, 1)
+	out := notify.Summary{Date: start.Format("2006-01-02"), Timezone: loc.String()}
+
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM play_sessions WHERE started_at >= ? AND started_at < ?`,
+		start.Unix(), end.Unix()).Scan(&out.Streams); err != nil {
+		return out, fmt.Errorf("streams: %w", err)
+	}
+
+	users, err := queries.TopUsersByWatchSeconds(ctx, db, start.Unix(), end.Unix()-1, "", "", 10000)
+	if err != nil {
+		return out, fmt.Errorf("watch time: %w", err)
+	}
+	for _, u := range users {
+		out.Hours += u.Hours
+	}
+	if len(users) > 0 {
+		out.TopUser = users[0].Name
+		out.TopUserHours = users[0].Hours
+	}
+
+	// library_item.created_at is a UTC "YYYY-MM-DD HH:MM:SS" timestamp
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM library_item
+		WHERE created_at >= ? AND created_at < ?
+		  AND COALESCE(media_type, '') IN ('Movie', 'Episode', 'Series')`,
+		start.UTC().Format("2006-01-02 15:04:05"), end.UTC().Format("2006-01-02 15:04:05")).Scan(&out.NewItems); err != nil {
+		return out, fmt.Errorf("new items: %w", err)
+	}
+	return out, nil
+}
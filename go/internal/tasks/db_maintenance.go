@@ -0,0 +1,283 @@
+package tasks
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"emby-analytics/internal/logging"
+)
+
+const (
+	// DBMaintenanceLockName keeps scheduled and manual maintenance runs from overlapping.
+	DBMaintenanceLockName = "db_maintenance"
+	// dbMaintenanceLastRunKey remembers the local date of the last scheduled run.
+	dbMaintenanceLastRunKey = "db_maintenance_last_run"
+	// maxIntegrityMessages caps how many integrity_check problems are reported.
+	maxIntegrityMessages = 100
+)
+
+// DBMaintenanceOptions selects which steps a maintenance run performs.
+type DBMaintenanceOptions struct {
+	IntegrityCheck bool `json:"integrity_check"`
+	Analyze        bool `json:"analyze"`
+	Vacuum         bool `json:"vacuum"`
+}
+
+// DefaultDBMaintenanceOptions runs every step.
+func DefaultDBMaintenanceOptions() DBMaintenanceOptions {
+	return DBMaintenanceOptions{IntegrityCheck: true, Analyze: true, Vacuum: true}
+}
+
+// DBMaintenanceStep is the outcome of one maintenance step.
+type DBMaintenanceStep struct {
+	Name           string `json:"name"`
+	Done           bool   `json:"done"`
+	DurationMillis int64  `json:"duration_ms"`
+	Error          string `json:"error,omitempty"`
+}
+
+// DBMaintenanceProgress is the state of the current (or most recent) maintenance run.
+type DBMaintenanceProgress struct {
+	Running         bool                `json:"running"`
+	Trigger         string              `json:"trigger,omitempty"` // "manual" or "schedule"
+	Stage           string              `json:"stage,omitempty"`
+	Steps           []DBMaintenanceStep `json:"steps"`
+	StartedAt       *time.Time          `json:"started_at,omitempty"`
+	FinishedAt      *time.Time          `json:"finished_at,omitempty"`
+	SizeBeforeBytes int64               `json:"size_before_bytes"`
+	SizeAfterBytes  int64               `json:"size_after_bytes,omitempty"`
+	ReclaimedBytes  int64               `json:"reclaimed_bytes,omitempty"`
+	IntegrityOK     *bool               `json:"integrity_ok,omitempty"`
+	IntegrityErrors []string            `json:"integrity_errors,omitempty"`
+	Error           string              `json:"error,omitempty"`
+}
+
+var (
+	dbMaintenanceMu       sync.RWMutex
+	dbMaintenanceProgress = DBMaintenanceProgress{Steps: []DBMaintenanceStep{}}
+)
+
+// GetDBMaintenanceProgress returns a copy of the current maintenance progress.
+func GetDBMaintenanceProgress() DBMaintenanceProgress {
+	dbMaintenanceMu.RLock()
+	defer dbMaintenanceMu.RUnlock()
+	p := dbMaintenanceProgress
+	p.Steps = append([]DBMaintenanceStep(nil), dbMaintenanceProgress.Steps...)
+	p.IntegrityErrors = append([]string(nil), dbMaintenanceProgress.IntegrityErrors...)
+	return p
+}
+
+func updateDBMaintenanceProgress(fn func(p *DBMaintenanceProgress)) {
+	dbMaintenanceMu.Lock()
+	defer dbMaintenanceMu.Unlock()
+	fn(&dbMaintenanceProgress)
+}
+
+// DBFileSize returns the on-disk size of the SQLite database including its WAL file.
+func DBFileSize(dbPath string) int64 {
+	var total int64
+	for _, p := range []string{dbPath, dbPath + "-wal"} {
+		if fi, err := os.Stat(p); err == nil {
+			total += fi.Size()
+		}
+	}
+	return total
+}
+
+// StartDBMaintenance acquires the maintenance lock and runs the selected steps in the
+// background. It returns false when another run already holds the lock.
+func StartDBMaintenance(db *sql.DB, dbPath string, opts DBMaintenanceOptions, trigger string) (bool, error) {
+	lock, err := TryJobLock(db, DBMaintenanceLockName)
+	if err != nil {
+		return false, err
+	}
+	if lock == nil {
+		return false, nil
+	}
+
+	started := time.Now()
+	steps := make([]DBMaintenanceStep, 0, 4)
+	for _, s := range plannedDBMaintenanceSteps(opts) {
+		steps = append(steps, DBMaintenanceStep{Name: s})
+	}
+	updateDBMaintenanceProgress(func(p *DBMaintenanceProgress) {
+		*p = DBMaintenanceProgress{
+			Running:         true,
+			Trigger:         trigger,
+			Stage:           "Starting...",
+			Steps:           steps,
+			StartedAt:       &started,
+			SizeBeforeBytes: DBFileSize(dbPath),
+		}
+	})
+
+	go func() {
+		defer lock.Release()
+		runDBMaintenance(db, dbPath, opts)
+	}()
+	return true, nil
+}
+
+func plannedDBMaintenanceSteps(opts DBMaintenanceOptions) []string {
+	var steps []string
+	if opts.IntegrityCheck {
+		steps = append(steps, "integrity_check")
+	}
+	if opts.Analyze {
+		steps = append(steps, "analyze")
+	}
+	if opts.Vacuum {
+		steps = append(steps, "vacuum")
+	}
+	// Always fold the WAL back into the main file so the size after is meaningful.
+	return append(steps, "checkpoint")
+}
+
+func runDBMaintenance(db *sql.DB, dbPath string, opts DBMaintenanceOptions) {
+	logging.Info("Database maintenance started", "integrity_check", opts.IntegrityCheck, "analyze", opts.Analyze, "vacuum", opts.Vacuum)
+
+	var runErr error
+	for i, name := range plannedDBMaintenanceSteps(opts) {
+		updateDBMaintenanceProgress(func(p *DBMaintenanceProgress) {
+			p.Stage = fmt.Sprintf("Running %s (%d/%d)...", name, i+1, len(p.Steps))
+		})
+		start := time.Now()
+		err := runDBMaintenanceStep(db, name)
+		elapsed := time.Since(start).Milliseconds()
+		updateDBMaintenanceProgress(func(p *DBMaintenanceProgress) {
+			p.Steps[i].Done = true
+			p.Steps[i].DurationMillis = elapsed
+			if err != nil {
+				p.Steps[i].Error = err.Error()
+			}
+		})
+		if err != nil {
+			logging.Warn("Database maintenance step failed", "step", name, "error", err)
+			runErr = err
+			break
+		}
+	}
+
+	finished := time.Now()
+	updateDBMaintenanceProgress(func(p *DBMaintenanceProgress) {
+		p.Running = false
+		p.FinishedAt = &finished
+		p.SizeAfterBytes = DBFileSize(dbPath)
+		p.ReclaimedBytes = p.SizeBeforeBytes - p.SizeAfterBytes
+		if runErr != nil {
+			p.Stage = "Failed"
+			p.Error = runErr.Error()
+		} else {
+			p.Stage = "Completed"
+		}
+	})
+	p := GetDBMaintenanceProgress()
+	logging.Info("Database maintenance finished", "size_before", p.SizeBeforeBytes, "size_after", p.SizeAfterBytes, "duration", finished.Sub(*p.StartedAt), "error", p.Error)
+}
+
+func runDBMaintenanceStep(db *sql.DB, name string) error {
+	switch name {
+	case "integrity_check":
+		problems, err := integrityCheck(db)
+		if err != nil {
+			return err
+		}
+		ok := len(problems) == 0
+		updateDBMaintenanceProgress(func(p *DBMaintenanceProgress) {
+			p.IntegrityOK = &ok
+			p.IntegrityErrors = problems
+		})
+		if !ok {
+			// Never VACUUM a damaged database; it can make recovery harder.
+			return fmt.Errorf("integrity check reported %d problem(s)", len(problems))
+		}
+		return nil
+	case "analyze":
+		_, err := db.Exec(`ANALYZE`)
+		return err
+	case "vacuum":
+		_, err := db.Exec(`VACUUM`)
+		return err
+	case "checkpoint":
+		_, err := db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`)
+		return err
+	}
+	return fmt.Errorf("unknown maintenance step %q", name)
+}
+
+// integrityCheck runs PRAGMA integrity_check and returns the reported problems (none when "ok").
+func integrityCheck(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(fmt.Sprintf(`PRAGMA integrity_check(%d)`, maxIntegrityMessages))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(strings.TrimSpace(msg), "ok") {
+			problems = append(problems, msg)
+		}
+	}
+	return problems, rows.Err()
+}
+
+// ParseMaintenanceTime parses "HH:MM" (24h), defaulting to 03:00.
+func ParseMaintenanceTime(v string) (int, int) {
+	t, err := time.Parse("15:04", strings.TrimSpace(v))
+	if err != nil {
+		return 3, 0
+	}
+	return t.Hour(), t.Minute()
+}
+
+// ParseMaintenanceWeekday parses a weekday name ("sun", "Sunday", ...); ok is false
+// when v is empty or unknown, which disables the schedule.
+func ParseMaintenanceWeekday(v string) (time.Weekday, bool) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if len(v) < 3 {
+		return 0, false
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.HasPrefix(strings.ToLower(d.String()), v) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// StartDBMaintenanceSchedule runs full maintenance once a week on weekday at hour:minute
+// server local time. Missed runs (e.g. the server was down) are not caught up.
+func StartDBMaintenanceSchedule(db *sql.DB, dbPath string, weekday time.Weekday, hour, minute int) {
+	logging.Info("Weekly database maintenance scheduled", "weekday", weekday, "time", fmt.Sprintf("%02d:%02d", hour, minute))
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			now := (<-ticker.C).In(time.Local)
+			if now.Weekday() != weekday || now.Hour() != hour || now.Minute() < minute {
+				continue
+			}
+			today := now.Format("2006-01-02")
+			if last, _ := getSettingValue(db, dbMaintenanceLastRunKey); last == today {
+				continue
+			}
+			started, err := StartDBMaintenance(db, dbPath, DefaultDBMaintenanceOptions(), "schedule")
+			if err != nil {
+				logging.Warn("Scheduled database maintenance failed to start", "error", err)
+				continue
+			}
+			if started {
+				_ = setSettingValue(db, dbMaintenanceLastRunKey, today)
+			}
+		}
+	}()
+}
@@ -0,0 +1,98 @@
+package tasks
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/config"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+)
+
+// StartDownloadTrackingLoop periodically polls servers that report offline download / sync jobs
+// and records them in download_jobs, keeping that traffic separate from streaming sessions.
+func StartDownloadTrackingLoop(db *sql.DB, mgr *media.MultiServerManager, cfg config.Config) {
+	if cfg.DownloadPollSec <= 0 {
+		logging.Debug("download tracking loop disabled (interval <= 0)")
+		return
+	}
+	interval := time.Duration(cfg.DownloadPollSec) * time.Second
+	logging.Debug("Starting download tracking loop", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			<-ticker.C
+			runDownloadTracking(db, mgr)
+		}
+	}()
+}
+
+func runDownloadTracking(db *sql.DB, mgr *media.MultiServerManager) {
+	if mgr == nil {
+		return
+	}
+	for serverID, client := range mgr.GetEnabledClients() {
+		reporter, ok := client.(media.DownloadReporter)
+		if !ok {
+			continue
+		}
+		jobs, err := reporter.GetActiveDownloads()
+		if err != nil {
+			logging.Debug("download poll failed", "server_id", serverID, "error", err)
+			continue
+		}
+		now := time.Now().Unix()
+		for _, j := range jobs {
+			if err := upsertDownloadJob(db, j, now); err != nil {
+				logging.Debug("download upsert failed", "server_id", serverID, "job_id", j.JobID, "error", err)
+			}
+		}
+		if err := completeVanishedDownloads(db, serverID, now); err != nil {
+			logging.Debug("download completion failed", "server_id", serverID, "error", err)
+		}
+	}
+}
+
+// completeVanishedDownloads marks the server's unfinished jobs missing from this poll as
+// completed as of when they were last seen. Plex drops a sync transcode from its session
+// list once it finishes, without ever reporting it completed.
+func completeVanishedDownloads(db *sql.DB, serverID string, now int64) error {
+	_, err := db.Exec(`
+		UPDATE download_jobs SET completed_at = last_seen_at
+		WHERE server_id = ? AND completed_at IS NULL AND last_seen_at < ?
+		  AND LOWER(status) NOT IN ('failed', 'cancelled')`, serverID, now)
+	return err
+}
+
+func upsertDownloadJob(db *sql.DB, j media.DownloadJob, now int64) error {
+	if strings.TrimSpace(j.JobID) == "" {
+		return nil
+	}
+	firstSeen := now
+	if j.CreatedAt != nil && !j.CreatedAt.IsZero() {
+		firstSeen = j.CreatedAt.Unix()
+	}
+	var completedAt any
+	if strings.EqualFold(j.Status, "Completed") || strings.EqualFold(j.Status, "Synced") {
+		completedAt = now
+	}
+	_, err := db.Exec(`
+		INSERT INTO download_jobs
+		(server_id, server_type, job_id, user_id, user_name, item_id, item_name, item_type,
+		 device_name, status, progress, size_bytes, first_seen_at, last_seen_at, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(server_id, job_id) DO UPDATE SET
+			user_name    = COALESCE(NULLIF(excluded.user_name, ''), user_name),
+			item_name    = COALESCE(NULLIF(excluded.item_name, ''), item_name),
+			status       = excluded.status,
+			progress     = excluded.progress,
+			size_bytes   = MAX(size_bytes, excluded.size_bytes),
+			last_seen_at = excluded.last_seen_at,
+			completed_at = COALESCE(completed_at, excluded.completed_at)
+	`, j.ServerID, string(j.ServerType), j.JobID, j.UserID, j.UserName, j.ItemID, j.ItemName, j.ItemType,
+		j.DeviceName, j.Status, j.Progress, j.SizeBytes, firstSeen, now, completedAt)
+	return err
+}
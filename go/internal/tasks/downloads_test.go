@@ -0,0 +1,34 @@
+package tasks
+
+import (
+	"testing"
+
+	"emby-analytics/internal/media"
+)
+
+func TestCompleteVanishedDownloads(t *testing.T) {
+	conn := openTestDB(t, `
+		INSERT INTO download_jobs (server_id, server_type, job_id, status, first_seen_at, last_seen_at, completed_at) VALUES
+			('plex-1', 'plex', 'gone', 'Transcoding', 100, 200, NULL),
+			('plex-1', 'plex', 'failed', 'Failed', 100, 200, NULL),
+			('plex-1', 'plex', 'done', 'Completed', 100, 150, 150),
+			('plex-2', 'plex', 'other', 'Transcoding', 100, 200, NULL)`)
+
+	if err := upsertDownloadJob(conn, media.DownloadJob{ServerID: "plex-1", ServerType: media.ServerTypePlex, JobID: "live", Status: "Transcoding"}, 300); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if err := completeVanishedDownloads(conn, "plex-1", 300); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	want := map[string]int64{"gone": 200, "failed": 0, "done": 150, "other": 0, "live": 0}
+	for job, at := range want {
+		var got int64
+		if err := conn.QueryRow(`SELECT COALESCE(completed_at, 0) FROM download_jobs WHERE job_id = ?`, job).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", job, err)
+		}
+		if got != at {
+			t.Errorf("%s completed_at = %d, want %d", job, got, at)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package tasks
+
+import (
+	"database/sql"
+	"time"
+
+	"emby-analytics/internal/config"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+)
+
+// StartDVRSyncLoop periodically syncs DVR recordings, scheduled timers and series rules
+// from servers with Live TV support. The first sync runs shortly after startup.
+func StartDVRSyncLoop(db *sql.DB, mgr *media.MultiServerManager, cfg config.Config) {
+	if cfg.DVRSyncIntervalSec <= 0 {
+		logging.Debug("DVR sync loop disabled (interval <= 0)")
+		return
+	}
+	interval := time.Duration(cfg.DVRSyncIntervalSec) * time.Second
+	logging.Debug("Starting DVR sync loop", "interval", interval)
+
+	go func() {
+		time.Sleep(30 * time.Second) // let the initial library/user syncs go first
+		runDVRSync(db, mgr)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			<-ticker.C
+			runDVRSync(db, mgr)
+		}
+	}()
+}
+
+func runDVRSync(db *sql.DB, mgr *media.MultiServerManager) {
+	if mgr == nil {
+		return
+	}
+	for serverID, client := range mgr.GetEnabledClients() {
+		reporter, ok := client.(media.DVRReporter)
+		if !ok {
+			continue
+		}
+		if err := syncServerDVR(db, serverID, string(client.GetServerType()), reporter, time.Now().Unix()); err != nil {
+			logging.Debug("DVR sync failed", "server_id", serverID, "error", err)
+		}
+	}
+}
+
+// syncServerDVR stores one server's recordings, timers and series rules as of now. Rows the
+// server stopped reporting are marked removed, but only for the lists it returned: a failed
+// timers fetch leaves scheduled recordings and series rules as they were.
+func syncServerDVR(db *sql.DB, serverID, serverType string, reporter media.DVRReporter, now int64) error {
+	recordings, err := reporter.GetRecordings()
+	if err != nil {
+		// Servers without a tuner/DVR configured return errors here; nothing to sync
+		return err
+	}
+	scheduled, timersErr := reporter.GetScheduledRecordings()
+	if timersErr != nil {
+		logging.Debug("DVR timers unavailable", "server_id", serverID, "error", timersErr)
+	}
+	seriesTimers, err := reporter.GetSeriesTimers()
+	if err != nil {
+		logging.Debug("DVR series timers unavailable", "server_id", serverID, "error", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	upsert := func(r media.Recording) error {
+		var startTs, endTs any
+		if r.StartDate != nil {
+			startTs = r.StartDate.Unix()
+		}
+		if r.EndDate != nil {
+			endTs = r.EndDate.Unix()
+		}
+		scheduledFlag := 0
+		if r.Scheduled {
+			scheduledFlag = 1
+		}
+		_, err := tx.Exec(`
+			INSERT INTO dvr_recordings
+			(server_id, server_type, recording_id, name, series_name, channel_name, status,
+			 start_ts, end_ts, size_bytes, series_timer_id, is_scheduled, last_synced_at, removed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL)
+			ON CONFLICT(server_id, recording_id, is_scheduled) DO UPDATE SET
+				name = excluded.name,
+				series_name = COALESCE(NULLIF(excluded.series_name, ''), series_name),
+				channel_name = excluded.channel_name,
+				status = excluded.status,
+				start_ts = excluded.start_ts,
+				end_ts = excluded.end_ts,
+				size_bytes = excluded.size_bytes,
+				series_timer_id = excluded.series_timer_id,
+				last_synced_at = excluded.last_synced_at,
+				removed_at = NULL
+		`, serverID, serverType, r.ID, r.Name, r.SeriesName, r.ChannelName, r.Status,
+			startTs, endTs, r.SizeBytes, r.SeriesTimerID, scheduledFlag, now)
+		return err
+	}
+
+	for _, r := range recordings {
+		if err := upsert(r); err != nil {
+			return err
+		}
+	}
+	for _, r := range scheduled {
+		r.Scheduled = true
+		if err := upsert(r); err != nil {
+			return err
+		}
+	}
+
+	// Recordings the server no longer reports were deleted; keep them for history but stop counting storage
+	sweep := `UPDATE dvr_recordings SET removed_at = ?
+		WHERE server_id = ? AND last_synced_at < ? AND removed_at IS NULL`
+	if timersErr != nil {
+		sweep += ` AND is_scheduled = 0`
+	}
+	if _, err := tx.Exec(sweep, now, serverID, now); err != nil {
+		return err
+	}
+
+	if seriesTimers != nil {
+		if _, err := tx.Exec(`DELETE FROM dvr_series_timers WHERE server_id = ?`, serverID); err != nil {
+			return err
+		}
+		for _, st := range seriesTimers {
+			if _, err := tx.Exec(`
+				INSERT INTO dvr_series_timers
+				(server_id, server_type, timer_id, name, channel_name, record_new_only, record_any_channel, last_synced_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			`, serverID, serverType, st.ID, st.Name, st.ChannelName, st.RecordNewOnly, st.RecordAnyChannel, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	logging.Debug("DVR sync completed", "server_id", serverID,
+		"recordings", len(recordings), "scheduled", len(scheduled), "series_timers", len(seriesTimers))
+	return nil
+}
@@ -0,0 +1,64 @@
+package tasks
+
+import (
+	"errors"
+	"testing"
+
+	"emby-analytics/internal/media"
+)
+
+type fakeDVR struct {
+	recordings, scheduled []media.Recording
+	timers                []media.SeriesTimer
+	timersErr             error
+}
+
+func (f fakeDVR) GetRecordings() ([]media.Recording, error) { return f.recordings, nil }
+func (f fakeDVR) GetScheduledRecordings() ([]media.Recording, error) {
+	return f.scheduled, f.timersErr
+}
+func (f fakeDVR) GetSeriesTimers() ([]media.SeriesTimer, error) { return f.timers, nil }
+
+func TestSyncServerDVRKeepsScheduledWhenTimersFail(t *testing.T) {
+	conn := openTestDB(t)
+	full := fakeDVR{
+		recordings: []media.Recording{{ID: "r1", Name: "News"}},
+		scheduled:  []media.Recording{{ID: "t1", Name: "Match"}},
+	}
+	if err := syncServerDVR(conn, "s1", "emby", full, 100); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	// The timers endpoint fails once: the recording the server dropped is removed, the
+	// scheduled one is kept
+	failed := fakeDVR{timersErr: errors.New("timeout")}
+	if err := syncServerDVR(conn, "s1", "emby", failed, 200); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	removed := map[string]bool{}
+	rows, err := conn.Query(`SELECT recording_id, removed_at IS NOT NULL FROM dvr_recordings`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var gone bool
+		if err := rows.Scan(&id, &gone); err != nil {
+			t.Fatal(err)
+		}
+		removed[id] = gone
+	}
+	if !removed["r1"] || removed["t1"] {
+		t.Errorf("removed = %v, want r1 removed and t1 kept", removed)
+	}
+
+	// Once the timers list comes back without it, the scheduled recording goes too
+	if err := syncServerDVR(conn, "s1", "emby", fakeDVR{}, 300); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	var open int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM dvr_recordings WHERE removed_at IS NULL`).Scan(&open); err != nil || open != 0 {
+		t.Errorf("open recordings = %d, %v", open, err)
+	}
+}
@@ -0,0 +1,136 @@
+package tasks
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	dbutil "emby-analytics/internal/db"
+	"emby-analytics/internal/media"
+)
+
+// Emby occasionally reports two session objects (different SessionIds) for the
+// same device playing the same item. Only one of them is tracked so watch time
+// is not counted twice; the others are linked to it in session_ghosts.
+
+// GhostSessionStats counts ghost sessions detected on one server since startup.
+type GhostSessionStats struct {
+	ServerID string `json:"server_id"`
+	Detected int64  `json:"detected"`
+}
+
+var ghostCounts sync.Map // serverID -> *atomic.Int64
+
+func countGhost(serverID string) {
+	v, _ := ghostCounts.LoadOrStore(serverID, &atomic.Int64{})
+	v.(*atomic.Int64).Add(1)
+}
+
+// GhostSessionSnapshot returns per-server ghost session counts sorted by server.
+func GhostSessionSnapshot() []GhostSessionStats {
+	out := []GhostSessionStats{}
+	ghostCounts.Range(func(k, v interface{}) bool {
+		out = append(out, GhostSessionStats{ServerID: k.(string), Detected: v.(*atomic.Int64).Load()})
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].ServerID < out[j].ServerID })
+	return out
+}
+
+// ghostGroupKey identifies sessions that are the same playback; empty when the
+// session carries too little information to compare.
+func ghostGroupKey(s media.Session) string {
+	if s.DeviceName == "" || s.ItemID == "" {
+		return ""
+	}
+	return strings.Join([]string{s.ServerID, s.UserID, s.DeviceName, s.ClientApp, s.ItemID}, "|")
+}
+
+// dropGhostSessions keeps one session per device+item and records the rest as
+// ghosts of it. A session that is already tracked is preferred as the primary.
+// Ghosts of skipped servers, which weren't polled this time, stay known.
+// Must be called with sp.mu held.
+func (sp *SessionProcessor) dropGhostSessions(sessions []media.Session, now time.Time, skipped map[string]bool) []media.Session {
+	groups := map[string][]int{}
+	for i, s := range sessions {
+		if k := ghostGroupKey(s); k != "" {
+			groups[k] = append(groups[k], i)
+		}
+	}
+
+	ghost := map[int]bool{}
+	seen := map[string]bool{}
+	for _, idx := range groups {
+		if len(idx) < 2 {
+			continue
+		}
+		primary := idx[0]
+		for _, i := range idx[1:] {
+			if sp.preferAsPrimary(sessions[i], sessions[primary]) {
+				primary = i
+			}
+		}
+		for _, i := range idx {
+			if i == primary {
+				continue
+			}
+			ghost[i] = true
+			key := sessions[i].ServerID + "|" + sessions[i].SessionID
+			seen[key] = true
+			if !sp.knownGhosts[key] {
+				sp.knownGhosts[key] = true
+				sp.recordGhost(sessions[i], sessions[primary], now)
+			}
+		}
+	}
+
+	// Forget ghosts that are no longer reported so a reappearance counts again
+	for key := range sp.knownGhosts {
+		if serverID, _, _ := strings.Cut(key, "|"); !seen[key] && !skipped[serverID] {
+			delete(sp.knownGhosts, key)
+		}
+	}
+	if len(ghost) == 0 {
+		return sessions
+	}
+
+	kept := make([]media.Session, 0, len(sessions)-len(ghost))
+	for i, s := range sessions {
+		if !ghost[i] {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// preferAsPrimary reports whether a should replace b as a group's primary session:
+// tracked sessions win (earliest start first), then the lowest session id.
+func (sp *SessionProcessor) preferAsPrimary(a, b media.Session) bool {
+	ta := sp.trackedSessions[a.ServerID+"|"+a.SessionID]
+	tb := sp.trackedSessions[b.ServerID+"|"+b.SessionID]
+	switch {
+	case ta != nil && tb == nil:
+		return true
+	case ta == nil && tb != nil:
+		return false
+	case ta != nil && tb != nil && !ta.StartTime.Equal(tb.StartTime):
+		return ta.StartTime.Before(tb.StartTime)
+	}
+	return a.SessionID < b.SessionID
+}
+
+func (sp *SessionProcessor) recordGhost(g, primary media.Session, now time.Time) {
+	countGhost(g.ServerID)
+	spLog.Info("Ghost session detected", "server", g.ServerID, "ghost", g.SessionID,
+		"primary", primary.SessionID, "device", g.DeviceName, "item", g.ItemID)
+	_, err := dbutil.ExecWithRetry(sp.DB, `
+		INSERT OR IGNORE INTO session_ghosts
+		(server_id, ghost_session_id, primary_session_id, user_id, item_id, device_name, client_name, detected_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		g.ServerID, g.SessionID, primary.SessionID, g.UserID, g.ItemID, g.DeviceName, g.ClientApp, now.Unix())
+	if err != nil {
+		spLog.Error("Failed to record ghost session", "error", err)
+	}
+}
@@ -0,0 +1,95 @@
+package tasks
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"emby-analytics/internal/media"
+)
+
+func ghostCount(serverID string) int64 {
+	for _, s := range GhostSessionSnapshot() {
+		if s.ServerID == serverID {
+			return s.Detected
+		}
+	}
+	return 0
+}
+
+func sessionIDs(sessions []media.Session) []string {
+	ids := make([]string, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.SessionID
+	}
+	return ids
+}
+
+func TestDropGhostSessionsPrimary(t *testing.T) {
+	play := func(id, device, item string) media.Session {
+		return media.Session{ServerID: "ghost-primary", SessionID: id, UserID: "u1", DeviceName: device, ClientApp: "Emby Web", ItemID: item}
+	}
+	now := time.Unix(1000, 0)
+
+	tests := []struct {
+		name    string
+		tracked map[string]time.Time // session id -> start
+		want    []string
+	}{
+		{"lowest id when none is tracked", nil, []string{"s-a", "other", "nodevice"}},
+		{"tracked session wins", map[string]time.Time{"s-c": now}, []string{"s-c", "other", "nodevice"}},
+		{"earliest tracked session wins", map[string]time.Time{"s-b": now, "s-c": now.Add(-time.Minute)}, []string{"s-c", "other", "nodevice"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp := NewSessionProcessor(openTestDB(t), nil)
+			for id, start := range tt.tracked {
+				sp.trackedSessions["ghost-primary|"+id] = &TrackedSession{SessionID: id, StartTime: start}
+			}
+			sessions := []media.Session{
+				play("s-c", "TV", "movie"), play("s-a", "TV", "movie"), play("s-b", "TV", "movie"),
+				play("other", "TV", "episode"), play("nodevice", "", "movie"),
+			}
+			kept := sp.dropGhostSessions(sessions, now, nil)
+			if got := sessionIDs(kept); !slices.Equal(got, tt.want) {
+				t.Fatalf("kept %v, want %v", got, tt.want)
+			}
+
+			var ghosts int
+			if err := sp.DB.QueryRow(`SELECT COUNT(*) FROM session_ghosts WHERE primary_session_id = ?`, tt.want[0]).Scan(&ghosts); err != nil || ghosts != 2 {
+				t.Errorf("%d ghosts recorded for %s (%v), want 2", ghosts, tt.want[0], err)
+			}
+		})
+	}
+}
+
+func TestDropGhostSessionsForgetsVanishedGhosts(t *testing.T) {
+	const server = "ghost-forget"
+	sp := NewSessionProcessor(openTestDB(t), nil)
+	primary := media.Session{ServerID: server, SessionID: "s1", UserID: "u1", DeviceName: "TV", ItemID: "movie"}
+	dup := primary
+	dup.SessionID = "s2"
+	now := time.Unix(1000, 0)
+
+	sp.dropGhostSessions([]media.Session{primary, dup}, now, nil)
+	sp.dropGhostSessions([]media.Session{primary, dup}, now.Add(time.Minute), nil)
+	if n := ghostCount(server); n != 1 {
+		t.Fatalf("a ghost reported twice was counted %d times, want once", n)
+	}
+
+	// The ghost goes away and is forgotten...
+	if kept := sp.dropGhostSessions([]media.Session{primary}, now.Add(2*time.Minute), nil); len(kept) != 1 {
+		t.Fatalf("kept %v", sessionIDs(kept))
+	}
+	if len(sp.knownGhosts) != 0 {
+		t.Errorf("known ghosts after it vanished: %v", sp.knownGhosts)
+	}
+	// ...so coming back counts as a new detection
+	kept := sp.dropGhostSessions([]media.Session{dup, primary}, now.Add(3*time.Minute), nil)
+	if got := sessionIDs(kept); !slices.Equal(got, []string{"s1"}) {
+		t.Errorf("kept %v, want [s1]", got)
+	}
+	if n := ghostCount(server); n != 2 {
+		t.Errorf("detections = %d after the ghost reappeared, want 2", n)
+	}
+}
@@ -0,0 +1,36 @@
+package tasks
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"emby-analytics/internal/db"
+)
+
+// openTestDB returns a migrated, empty database seeded with stmts.
+func openTestDB(t *testing.T, stmts ...string) *sql.DB {
+	t.Helper()
+	path := filepath.ToSlash(filepath.Join(t.TempDir(), "tasks.db"))
+	if err := db.MigrateUp(fmt.Sprintf("sqlite://file:%s?mode=rwc", path)); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	conn, err := db.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	seed(t, conn, stmts...)
+	return conn
+}
+
+// seed runs fixture statements, failing the test on the first error.
+func seed(t *testing.T, conn *sql.DB, stmts ...string) {
+	t.Helper()
+	for _, s := range stmts {
+		if _, err := conn.Exec(s); err != nil {
+			t.Fatalf("seed: %v\n%s", err, s)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package tasks
+
+import (
+	"database/sql"
+	"time"
+
+	"emby-analytics/internal/config"
+	"emby-analytics/internal/handlers/settings"
+	"emby-analytics/internal/logging"
+)
+
+// HistoryRetentionLockName guards pruning so only one worker deletes history at a time.
+const HistoryRetentionLockName = "history_retention"
+
+// RetentionPolicy is how many days of playback data are kept; 0 keeps everything.
+type RetentionPolicy struct {
+	SessionDays int `json:"session_days"`
+	// SessionDaysSource is "env" when RETENTION_DAYS_SESSIONS is set, "setting" otherwise
+	SessionDaysSource string `json:"session_days_source"`
+	EventDays         int    `json:"event_days"`
+}
+
+// ResolveRetentionPolicy combines the RETENTION_DAYS_* environment with the
+// history_retention_days setting, which applies unless RETENTION_DAYS_SESSIONS is set.
+func ResolveRetentionPolicy(db *sql.DB, cfg config.Config) RetentionPolicy {
+	p := RetentionPolicy{SessionDays: cfg.RetentionDaysSessions, SessionDaysSource: "env", EventDays: max(cfg.RetentionDaysEvents, 0)}
+	if p.SessionDays < 0 {
+		p.SessionDays, p.SessionDaysSource = settings.HistoryRetentionDays(db), "setting"
+	}
+	return p
+}
+
+// RetentionReport counts the rows one retention pass deletes, or would delete on a dry run.
+type RetentionReport struct {
+	Policy        RetentionPolicy `json:"policy"`
+	DryRun        bool            `json:"dry_run"`
+	SessionCutoff int64           `json:"session_cutoff,omitempty"` // unix seconds; 0 when sessions are kept
+	EventCutoff   int64           `json:"event_cutoff,omitempty"`
+	Sessions      int64           `json:"sessions"`
+	Intervals     int64           `json:"intervals"`
+	// Events includes those of deleted sessions as well as expired events of kept ones
+	Events           int64 `json:"events"`
+	BandwidthSamples int64 `json:"bandwidth_samples"`
+	PublicIDs        int64 `json:"public_ids"` // deep links to deleted sessions
+}
+
+// StartHistoryRetentionLoop periodically deletes playback data older than the retention
+// policy every RETENTION_INTERVAL seconds. The policy is resolved on every pass, so changes
+// to the history_retention_days setting apply without a restart.
+func StartHistoryRetentionLoop(db *sql.DB, cfg config.Config) {
+	if cfg.RetentionIntervalSec <= 0 {
+		logging.Debug("history retention loop disabled (interval <= 0)")
+		return
+	}
+	interval := time.Duration(cfg.RetentionIntervalSec) * time.Second
+	go func() {
+		time.Sleep(5 * time.Minute) // stay clear of startup syncs
+		for {
+			runHistoryRetention(db, cfg)
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func runHistoryRetention(db *sql.DB, cfg config.Config) {
+	policy := ResolveRetentionPolicy(db, cfg)
+	if policy.SessionDays <= 0 && policy.EventDays <= 0 {
+		return
+	}
+	lock, err := TryJobLock(db, HistoryRetentionLockName)
+	if err != nil {
+		logging.Debug("history retention lock failed", "error", err)
+		return
+	}
+	if lock == nil {
+		return
+	}
+	defer lock.Release()
+
+	r, err := PruneHistory(db, policy, time.Now(), false)
+	if err != nil {
+		logging.Warn("history retention failed", "error", err)
+		return
+	}
+	if r.Sessions > 0 || r.Events > 0 {
+		logging.Info("Pruned playback history", "sessions", r.Sessions, "intervals", r.Intervals,
+			"events", r.Events, "session_days", policy.SessionDays, "event_days", policy.EventDays)
+	}
+}
+
+// PruneHistory applies policy as of now. Finished playback sessions that started more than
+// SessionDays ago are deleted together with their events, intervals, summaries and public
+// ids, as are bandwidth samples taken before then; play events of finished sessions older than EventDays
+// are deleted on their own. Lifetime watch totals synced from the servers are kept. With
+// dryRun nothing is deleted and the report says what would have been.
+func PruneHistory(db *sql.DB, policy RetentionPolicy, now time.Time, dryRun bool) (RetentionReport, error) {
+	r := RetentionReport{Policy: policy, DryRun: dryRun}
+	if policy.SessionDays > 0 {
+		r.SessionCutoff = now.AddDate(0, 0, -policy.SessionDays).Unix()
+	}
+	if policy.EventDays > 0 {
+		r.EventCutoff = now.AddDate(0, 0, -policy.EventDays).Unix()
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return r, err
+	}
+	defer tx.Rollback()
+
+	// With no session cutoff the expired-session set is empty and only events expire
+	const expired = `
+		SELECT id FROM play_sessions
+		WHERE started_at < ? AND ended_at IS NOT NULL AND COALESCE(is_active, 0) = 0`
+	const finished = `
+		SELECT id FROM play_sessions
+		WHERE ended_at IS NOT NULL AND COALESCE(is_active, 0) = 0`
+	// public_ids has no foreign key to play_sessions, so its rows don't cascade
+	const expiredPublicIDs = `kind = 'session' AND target_id IN (SELECT CAST(id AS TEXT) FROM (` + expired + `))`
+	counts := []struct {
+		dst   *int64
+		query string
+		args  []any
+	}{
+		{&r.Sessions, `SELECT COUNT(*) FROM (` + expired + `)`, []any{r.SessionCutoff}},
+		{&r.Intervals, `SELECT COUNT(*) FROM play_intervals WHERE session_fk IN (` + expired + `)`, []any{r.SessionCutoff}},
+		{&r.Events, `
+			SELECT COUNT(*) FROM play_events
+			WHERE session_fk IN (` + expired + `)
+			   OR (created_at < ? AND session_fk IN (` + finished + `))`, []any{r.SessionCutoff, r.EventCutoff}},
+		{&r.BandwidthSamples, `SELECT COUNT(*) FROM bandwidth_samples WHERE ts < ?`, []any{r.SessionCutoff}},
+		{&r.PublicIDs, `SELECT COUNT(*) FROM public_ids WHERE ` + expiredPublicIDs, []any{r.SessionCutoff}},
+	}
+	for _, q := range counts {
+		if err := tx.QueryRow(q.query, q.args...).Scan(q.dst); err != nil {
+			return r, err
+		}
+	}
+	if dryRun {
+		return r, nil
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM play_events
+		WHERE created_at < ? AND session_fk IN (`+finished+`)`, r.EventCutoff); err != nil {
+		return r, err
+	}
+	if _, err := tx.Exec(`DELETE FROM public_ids WHERE `+expiredPublicIDs, r.SessionCutoff); err != nil {
+		return r, err
+	}
+	if _, err := tx.Exec(`DELETE FROM play_sessions WHERE id IN (`+expired+`)`, r.SessionCutoff); err != nil {
+		return r, err
+	}
+	if _, err := tx.Exec(`DELETE FROM bandwidth_samples WHERE ts < ?`, r.SessionCutoff); err != nil {
+		return r, err
+	}
+	return r, tx.Commit()
+}
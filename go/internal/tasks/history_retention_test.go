@@ -0,0 +1,77 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPruneHistory(t *testing.T) {
+	const day = 86400
+	conn := openTestDB(t,
+		`INSERT INTO play_sessions (id, user_id, session_id, item_id, started_at, ended_at, is_active)
+		 VALUES (1, 'u', 'old', 'i', 10*86400, 10*86400 + 3600, 0),
+		        (2, 'u', 'open', 'i', 10*86400, NULL, 1),
+		        (3, 'u', 'recent', 'i', 90*86400, 90*86400 + 3600, 0)`,
+		`INSERT INTO play_intervals (session_fk, item_id, user_id, start_ts, end_ts, duration_seconds)
+		 VALUES (1, 'i', 'u', 10*86400, 10*86400 + 1800, 1800), (1, 'i', 'u', 10*86400 + 1800, 10*86400 + 3600, 1800),
+		        (3, 'i', 'u', 90*86400, 90*86400 + 3600, 3600)`,
+		`INSERT INTO play_events (session_fk, kind, created_at)
+		 VALUES (1, 'start', 10*86400), (1, 'stop', 10*86400 + 3600),
+		        (2, 'start', 10*86400),
+		        (3, 'start', 90*86400), (3, 'stop', 99*86400)`,
+		`INSERT INTO bandwidth_samples (ts, server_id, user_id, bitrate_bps, seconds)
+		 VALUES (10*86400, 's', 'u', 1000, 60), (99*86400, 's', 'u', 1000, 60)`,
+		`INSERT INTO public_ids (public_id, kind, target_id, created_at)
+		 VALUES ('ses_old', 'session', '1', 0), ('ses_recent', 'session', '3', 0), ('itm_1', 'item', '1', 0)`,
+	)
+	now := time.Unix(100*day, 0)
+	policy := RetentionPolicy{SessionDays: 30, EventDays: 7}
+
+	dry, err := PruneHistory(conn, policy, now, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	// Session 1 goes with its intervals, events and public id; session 3 only loses its
+	// expired start event; the open session 2 is kept whole
+	if dry.Sessions != 1 || dry.Intervals != 2 || dry.Events != 3 || dry.BandwidthSamples != 1 || dry.PublicIDs != 1 {
+		t.Errorf("dry run = %+v", dry)
+	}
+	var sessions int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM play_sessions`).Scan(&sessions); err != nil || sessions != 3 {
+		t.Fatalf("dry run deleted sessions: %d, %v", sessions, err)
+	}
+
+	tables := []string{"play_sessions", "play_intervals", "play_events", "bandwidth_samples", "public_ids"}
+	before := map[string]int64{}
+	for _, table := range tables {
+		var n int64
+		if err := conn.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		before[table] = n
+	}
+	live, err := PruneHistory(conn, policy, now, false)
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	live.DryRun = true
+	if live != dry {
+		t.Errorf("prune = %+v, dry run said %+v", live, dry)
+	}
+	// The report's counts are exactly what left each table
+	reported := map[string]int64{"play_sessions": dry.Sessions, "play_intervals": dry.Intervals,
+		"play_events": dry.Events, "bandwidth_samples": dry.BandwidthSamples, "public_ids": dry.PublicIDs}
+	for _, table := range tables {
+		var n int64
+		if err := conn.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if before[table]-n != reported[table] {
+			t.Errorf("%s: %d rows deleted, report says %d", table, before[table]-n, reported[table])
+		}
+	}
+	var orphans int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM public_ids WHERE kind = 'session' AND target_id = '1'`).Scan(&orphans); err != nil || orphans != 0 {
+		t.Errorf("public ids of deleted sessions left: %d, %v", orphans, err)
+	}
+}
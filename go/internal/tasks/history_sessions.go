@@ -0,0 +1,56 @@
+package tasks
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+)
+
+// historySessionPrefix marks play_sessions rows synthesized from a server's
+// own watch history (currently Plex's /status/sessions/history/all)
+const historySessionPrefix = "history-"
+
+// importHistorySession turns a server history entry recorded before live
+// tracking began into a play_sessions/play_intervals pair. The server only
+// records when the item was viewed, so the session is assumed to end then and
+// to span the item's runtime. Reports whether a session was created.
+func importHistorySession(db *sql.DB, serverID string, serverType media.ServerType, h media.PlayHistoryItem, cutoff time.Time) bool {
+	if strings.TrimSpace(h.HistoryID) == "" || strings.TrimSpace(h.UserID) == "" {
+		return false
+	}
+	end, err := time.Parse(time.RFC3339, h.DatePlayed)
+	if err != nil || !end.Before(cutoff) {
+		return false
+	}
+
+	dur := time.Duration(h.DurationMs) * time.Millisecond
+	var ticks sql.NullInt64
+	_ = db.QueryRow(`SELECT run_time_ticks FROM library_item WHERE id = ?`, storageItemID(serverID, h.ID)).Scan(&ticks)
+	if ticks.Valid && ticks.Int64 > 0 {
+		dur = time.Duration(ticks.Int64 * 100)
+	}
+	if dur > activityLogMaxSession {
+		dur = activityLogMaxSession
+	}
+	if dur < activityLogMinSession {
+		return false
+	}
+	start := end.Add(-dur)
+	sessionID := historySessionPrefix + h.HistoryID
+
+	created, err := insertImportedSession(retryExecer{db}, importedSession{
+		ServerID: serverID, ServerType: string(serverType), SessionID: sessionID,
+		UserID: h.UserID, ItemID: h.ID, ItemName: h.Name, ItemType: h.Type,
+		StartedAt: start.Unix(), EndedAt: end.Unix(),
+		Intervals: []importedInterval{{
+			Start: start.Unix(), End: end.Unix(), EndPosTicks: dur.Nanoseconds() / 100, Seconds: int64(dur.Seconds()),
+		}},
+	})
+	if err != nil {
+		logging.Debug("History import: insert session failed", "server_id", serverID, "error", err)
+	}
+	return created
+}
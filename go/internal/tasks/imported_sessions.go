@@ -0,0 +1,99 @@
+package tasks
+
+import (
+	"database/sql"
+
+	dbutil "emby-analytics/internal/db"
+)
+
+// importedSession is a finished playback read from history that was recorded before live
+// tracking: a server's activity log or watch history, Playback Reporting or Tautulli.
+type importedSession struct {
+	ServerID   string
+	ServerType string
+	SessionID  string // prefixed by the source, so re-running an import finds its rows
+	UserID     string
+	UserName   string
+	ItemID     string
+	// ItemName and ItemType are used when the item isn't in library_item
+	ItemName      string
+	ItemType      string
+	DeviceID      string
+	ClientName    string
+	Platform      string
+	RemoteAddress string
+	PlayMethod    string // "Unknown" when empty
+	VideoMethod   string
+	AudioMethod   string
+	StartedAt     int64
+	EndedAt       int64
+	Intervals     []importedInterval
+}
+
+// importedInterval is a stretch of watch time within an imported session.
+type importedInterval struct {
+	Start, End                 int64
+	StartPosTicks, EndPosTicks int64
+	Seconds                    int64
+}
+
+// importExecer is satisfied by *sql.Tx and by retryExecer.
+type importExecer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// retryExecer runs a *sql.DB's statements through dbutil.ExecWithRetry, for imports that
+// write outside a transaction.
+type retryExecer struct{ *sql.DB }
+
+func (r retryExecer) Exec(query string, args ...any) (sql.Result, error) {
+	return dbutil.ExecWithRetry(r.DB, query, args...)
+}
+
+// importedSessionExists reports whether an import already created the session.
+func importedSessionExists(q importExecer, serverID, sessionID string) bool {
+	var n int
+	_ = q.QueryRow(`SELECT COUNT(*) FROM play_sessions WHERE server_id = ? AND session_id = ?`, serverID, sessionID).Scan(&n)
+	return n > 0
+}
+
+// insertImportedSession stores an imported session with its intervals, taking the item's
+// name and type from library_item when it is known there. Reports false without writing
+// when the session was imported before.
+func insertImportedSession(q importExecer, s importedSession) (bool, error) {
+	if importedSessionExists(q, s.ServerID, s.SessionID) {
+		return false, nil
+	}
+	playMethod := s.PlayMethod
+	if playMethod == "" {
+		playMethod = "Unknown"
+	}
+	r, err := q.Exec(`
+		INSERT INTO play_sessions
+		(user_id, user_name, session_id, device_id, client_name, item_id, item_name, item_type,
+		 play_method, video_method, audio_method, started_at, ended_at, is_active,
+		 remote_address, server_id, server_type, platform)
+		SELECT ?, NULLIF(?, ''), ?, NULLIF(?, ''), NULLIF(?, ''), ?, COALESCE(li.name, ?), COALESCE(li.media_type, ?),
+		       ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, false,
+		       NULLIF(?, ''), ?, ?, NULLIF(?, '')
+		FROM (SELECT 1) LEFT JOIN library_item li ON li.id = ?`,
+		s.UserID, s.UserName, s.SessionID, s.DeviceID, s.ClientName, s.ItemID, s.ItemName, s.ItemType,
+		playMethod, s.VideoMethod, s.AudioMethod, s.StartedAt, s.EndedAt,
+		s.RemoteAddress, s.ServerID, s.ServerType, s.Platform,
+		storageItemID(s.ServerID, s.ItemID))
+	if err != nil {
+		return false, err
+	}
+	fk, _ := r.LastInsertId()
+	for _, iv := range s.Intervals {
+		if _, err := q.Exec(`
+			INSERT INTO play_intervals
+			(session_fk, item_id, user_id, start_ts, end_ts, start_pos_ticks, end_pos_ticks, duration_seconds, seeked, server_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0, ?)`,
+			fk, s.ItemID, s.UserID, iv.Start, iv.End, iv.StartPosTicks, iv.EndPosTicks, iv.Seconds, s.ServerID); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
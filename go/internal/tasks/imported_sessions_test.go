@@ -0,0 +1,44 @@
+package tasks
+
+import "testing"
+
+func TestInsertImportedSession(t *testing.T) {
+	conn := openTestDB(t, `
+		INSERT INTO library_item (id, server_id, server_type, item_id, name, media_type, updated_at)
+		VALUES ('movie-a', 'default-emby', 'emby', 'movie-a', 'Library Title', 'Movie', CURRENT_TIMESTAMP)`)
+
+	s := importedSession{
+		ServerID: "default-emby", ServerType: "emby", SessionID: "test-1",
+		UserID: "alice", ItemID: "movie-a", ItemName: "Imported Title", ItemType: "Video",
+		StartedAt: 1000, EndedAt: 4000,
+		Intervals: []importedInterval{{Start: 1000, End: 2000, Seconds: 1000}, {Start: 3000, End: 4000, Seconds: 900}},
+	}
+	for i, want := range []bool{true, false} {
+		created, err := insertImportedSession(retryExecer{conn}, s)
+		if err != nil || created != want {
+			t.Fatalf("insert %d = %v, %v; want %v", i, created, err, want)
+		}
+	}
+
+	var name, itemType, method string
+	var sessions, seconds int
+	if err := conn.QueryRow(`SELECT COUNT(*), MAX(item_name), MAX(item_type), MAX(play_method) FROM play_sessions`).
+		Scan(&sessions, &name, &itemType, &method); err != nil {
+		t.Fatal(err)
+	}
+	if sessions != 1 || name != "Library Title" || itemType != "Movie" || method != "Unknown" {
+		t.Errorf("sessions = %d, item = %q/%q, method = %q", sessions, name, itemType, method)
+	}
+	if err := conn.QueryRow(`SELECT SUM(duration_seconds) FROM play_intervals`).Scan(&seconds); err != nil || seconds != 1900 {
+		t.Errorf("interval seconds = %d, %v", seconds, err)
+	}
+
+	// Items missing from the library keep the imported name
+	s.SessionID, s.ItemID = "test-2", "gone"
+	if _, err := insertImportedSession(retryExecer{conn}, s); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.QueryRow(`SELECT item_name FROM play_sessions WHERE session_id = 'test-2'`).Scan(&name); err != nil || name != "Imported Title" {
+		t.Errorf("item name = %q, %v", name, err)
+	}
+}
@@ -0,0 +1,123 @@
+package tasks
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A playback can be ingested twice: the session processor polls every server while Emby
+// playback events (webhooks, WebSocket) feed the Intervalizer. Both paths share the
+// play_sessions row of a (server, session, item); claims decide which of them records the
+// watch time, so the other's intervals don't count the same minutes again.
+
+// ingestSource identifies an ingestion path.
+type ingestSource string
+
+const (
+	sourcePolling ingestSource = "polling"
+	sourceEvents  ingestSource = "events"
+)
+
+// claimWindow is how long a playback stays with the path that claimed it after that path
+// last saw it. Once it lapses (events stopped arriving, polling lost the session) the other
+// path takes over.
+const claimWindow = 5 * time.Minute
+
+type playbackClaim struct {
+	source   ingestSource
+	lastSeen time.Time
+}
+
+var (
+	claimsMu sync.Mutex
+	claims   = map[string]*playbackClaim{} // server|session|item -> owner
+)
+
+func claimKey(serverID, sessionID, itemID string) string {
+	if serverID == "" {
+		serverID = "default-emby"
+	}
+	return serverID + "|" + sessionID + "|" + itemID
+}
+
+// claimPlayback records that source saw a playback at now and reports whether source owns
+// its watch time: the first path to see a playback owns it until its claim lapses.
+func claimPlayback(source ingestSource, serverID, sessionID, itemID string, now time.Time) bool {
+	key := claimKey(serverID, sessionID, itemID)
+	claimsMu.Lock()
+	defer claimsMu.Unlock()
+
+	c, ok := claims[key]
+	if ok && c.source != source && now.Sub(c.lastSeen) < claimWindow {
+		return false
+	}
+	if ok && c.source != source {
+		spLog.Info("Taking over playback from stale ingestion path", "playback", key, "from", c.source, "to", source)
+	}
+	claims[key] = &playbackClaim{source: source, lastSeen: now}
+
+	// Claims of playbacks neither path reported ending would otherwise pile up
+	for k, other := range claims {
+		if now.Sub(other.lastSeen) >= 2*claimWindow {
+			delete(claims, k)
+		}
+	}
+	return true
+}
+
+// releasePlayback drops source's claim on a playback that ended.
+func releasePlayback(source ingestSource, serverID, sessionID, itemID string) {
+	key := claimKey(serverID, sessionID, itemID)
+	claimsMu.Lock()
+	defer claimsMu.Unlock()
+	if c, ok := claims[key]; ok && c.source == source {
+		delete(claims, key)
+	}
+}
+
+// IngestDedupStats counts playbacks seen by both ingestion paths on one server since startup.
+type IngestDedupStats struct {
+	ServerID          string `json:"server_id"`
+	Merged            int64  `json:"merged"`             // playbacks recorded once instead of twice
+	SuppressedSeconds int64  `json:"suppressed_seconds"` // duplicate watch time not recorded
+}
+
+type dedupCounters struct {
+	merged, suppressedSec atomic.Int64
+}
+
+var dedupCounts sync.Map // serverID -> *dedupCounters
+
+func dedupCountersFor(serverID string) *dedupCounters {
+	if serverID == "" {
+		serverID = "default-emby"
+	}
+	v, _ := dedupCounts.LoadOrStore(serverID, &dedupCounters{})
+	return v.(*dedupCounters)
+}
+
+func countMerged(serverID string) { dedupCountersFor(serverID).merged.Add(1) }
+
+func countSuppressed(serverID string, seconds int) {
+	if seconds > 0 {
+		dedupCountersFor(serverID).suppressedSec.Add(int64(seconds))
+	}
+}
+
+// IngestDedupSnapshot returns per-server de-duplication counters sorted by server.
+func IngestDedupSnapshot() []IngestDedupStats {
+	out := []IngestDedupStats{}
+	dedupCounts.Range(func(k, v interface{}) bool {
+		c := v.(*dedupCounters)
+		out = append(out, IngestDedupStats{
+			ServerID:          k.(string),
+			Merged:            c.merged.Load(),
+			SuppressedSeconds: c.suppressedSec.Load(),
+		})
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].ServerID < out[j].ServerID })
+	return out
+}
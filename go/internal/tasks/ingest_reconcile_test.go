@@ -0,0 +1,65 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimPlayback(t *testing.T) {
+	const server = "claim-test"
+	start := time.Unix(1_000_000, 0)
+	claim := func(source ingestSource, at time.Duration) bool {
+		return claimPlayback(source, server, "s1", "movie", start.Add(at))
+	}
+
+	if !claim(sourceEvents, 0) {
+		t.Fatal("the first path to see a playback must own it")
+	}
+	if claim(sourcePolling, time.Minute) {
+		t.Error("polling took over a playback events saw a minute ago")
+	}
+	// Events keep refreshing their claim, so polling stays out past the first window
+	if !claim(sourceEvents, 4*time.Minute) {
+		t.Error("the owner lost its own claim")
+	}
+	if claim(sourcePolling, 4*time.Minute+claimWindow-time.Second) {
+		t.Error("polling took over before the claim lapsed")
+	}
+
+	// Events stop arriving: once claimWindow passes polling takes over
+	if !claim(sourcePolling, 4*time.Minute+claimWindow) {
+		t.Fatal("polling did not take over a lapsed claim")
+	}
+	if claim(sourceEvents, 4*time.Minute+claimWindow+time.Second) {
+		t.Error("events got the playback back while polling's claim is fresh")
+	}
+
+	// Another item of the same session is a separate playback
+	if !claimPlayback(sourceEvents, server, "s1", "episode", start.Add(10*time.Minute)) {
+		t.Error("a different item was blocked by another playback's claim")
+	}
+
+	// A released claim is free straight away
+	releasePlayback(sourceEvents, server, "s1", "movie") // not the owner: no effect
+	if claim(sourceEvents, 10*time.Minute) {
+		t.Error("a release by a path that does not own the playback dropped the claim")
+	}
+	releasePlayback(sourcePolling, server, "s1", "movie")
+	if !claim(sourceEvents, 10*time.Minute) {
+		t.Error("events could not claim a released playback")
+	}
+}
+
+func TestClaimPlaybackPrunesStaleClaims(t *testing.T) {
+	start := time.Unix(2_000_000, 0)
+	claimPlayback(sourcePolling, "claim-prune", "gone", "movie", start)
+	claimPlayback(sourcePolling, "claim-prune", "live", "movie", start.Add(2*claimWindow))
+
+	claimsMu.Lock()
+	_, stale := claims[claimKey("claim-prune", "gone", "movie")]
+	_, live := claims[claimKey("claim-prune", "live", "movie")]
+	claimsMu.Unlock()
+	if stale || !live {
+		t.Errorf("stale claim kept = %v, live claim kept = %v", stale, live)
+	}
+}
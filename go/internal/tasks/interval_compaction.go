@@ -0,0 +1,248 @@
+package tasks
+
+import (
+	"database/sql"
+	"time"
+
+	"emby-analytics/internal/config"
+	"emby-analytics/internal/logging"
+)
+
+const (
+	// IntervalCompactionLockName guards compaction so only one worker rewrites intervals at a time.
+	IntervalCompactionLockName = "interval_compaction"
+	// compactionSettleSec keeps compaction away from sessions that ended recently and may still be reopened.
+	compactionSettleSec = 3600
+	// compactionBatchSize is how many sessions are rewritten per transaction.
+	compactionBatchSize = 200
+)
+
+// CompactionResult summarises one compaction pass.
+type CompactionResult struct {
+	Sessions       int `json:"sessions"`
+	RowsRemoved    int `json:"rows_removed"`
+	DurationMillis int `json:"duration_ms"`
+}
+
+// StartIntervalCompactionLoop periodically merges near-adjacent intervals of finished sessions.
+func StartIntervalCompactionLoop(db *sql.DB, cfg config.Config) {
+	if cfg.IntervalCompactIntervalSec <= 0 {
+		logging.Debug("interval compaction loop disabled (interval <= 0)")
+		return
+	}
+	interval := time.Duration(cfg.IntervalCompactIntervalSec) * time.Second
+	logging.Debug("Starting interval compaction loop", "interval", interval)
+
+	go func() {
+		time.Sleep(2 * time.Minute) // stay clear of startup syncs and the first rollup
+		runIntervalCompaction(db, cfg.IntervalCompactGapSec)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			<-ticker.C
+			runIntervalCompaction(db, cfg.IntervalCompactGapSec)
+		}
+	}()
+}
+
+func runIntervalCompaction(db *sql.DB, maxGapSec int) {
+	lock, err := TryJobLock(db, IntervalCompactionLockName)
+	if err != nil {
+		logging.Debug("interval compaction lock failed", "error", err)
+		return
+	}
+	if lock == nil {
+		logging.Debug("interval compaction already running elsewhere")
+		return
+	}
+	defer lock.Release()
+
+	res, err := CompactIntervals(db, maxGapSec, time.Now().Unix()-compactionSettleSec)
+	if err != nil {
+		logging.Warn("interval compaction failed", "error", err)
+		return
+	}
+	if res.RowsRemoved > 0 {
+		logging.Info("Compacted play intervals", "sessions", res.Sessions, "rows_removed", res.RowsRemoved, "duration_ms", res.DurationMillis)
+	}
+}
+
+type compactInterval struct {
+	id            int64
+	startTS       int64
+	endTS         int64
+	endPosTicks   sql.NullInt64
+	durationSec   int64
+	seeked        int
+	mergedIntoRow bool
+}
+
+// CompactIntervals merges consecutive intervals of the same finished session when the gap
+// between them is at most maxGapSec. The merged row spans both intervals while its
+// duration_seconds is their sum, so the gap itself is never counted as watch time.
+// Only sessions that are inactive and ended before endedBefore are touched, which keeps
+// the session processor's in-flight interval IDs valid.
+func CompactIntervals(db *sql.DB, maxGapSec int, endedBefore int64) (CompactionResult, error) {
+	start := time.Now()
+	var res CompactionResult
+	if maxGapSec < 0 {
+		maxGapSec = 0
+	}
+
+	for {
+		sessionIDs, err := compactableSessions(db, maxGapSec, endedBefore)
+		if err != nil {
+			return res, err
+		}
+		if len(sessionIDs) == 0 {
+			break
+		}
+
+		removed, err := compactSessions(db, sessionIDs, int64(maxGapSec))
+		if err != nil {
+			return res, err
+		}
+		res.Sessions += len(sessionIDs)
+		res.RowsRemoved += removed
+		if removed == 0 {
+			// Nothing merged despite candidates (e.g. rows changed underneath us); stop rather than spin.
+			break
+		}
+	}
+
+	res.DurationMillis = int(time.Since(start).Milliseconds())
+	return res, nil
+}
+
+// compactableSessions returns up to compactionBatchSize finished sessions with at least one
+// pair of consecutive, non-overlapping intervals no more than maxGapSec apart.
+func compactableSessions(db *sql.DB, maxGapSec int, endedBefore int64) ([]int64, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT session_fk FROM (
+			SELECT pi.session_fk,
+			       pi.start_ts - LAG(pi.end_ts) OVER (PARTITION BY pi.session_fk ORDER BY pi.start_ts, pi.id) AS gap
+			FROM play_intervals pi
+			JOIN play_sessions ps ON ps.id = pi.session_fk
+			WHERE COALESCE(ps.is_active, 0) = 0
+			  AND COALESCE(ps.ended_at, ps.started_at) < ?
+		)
+		WHERE gap BETWEEN 0 AND ?
+		LIMIT ?
+	`, endedBefore, maxGapSec, compactionBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// compactSessions merges the intervals of each session in a single transaction and returns
+// the number of rows deleted.
+func compactSessions(db *sql.DB, sessionIDs []int64, maxGapSec int64) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	updateStmt, err := tx.Prepare(`UPDATE play_intervals SET end_ts = ?, end_pos_ticks = ?, duration_seconds = ?, seeked = ? WHERE id = ?`)
+	if err != nil {
+		return 0, err
+	}
+	defer updateStmt.Close()
+	deleteStmt, err := tx.Prepare(`DELETE FROM play_intervals WHERE id = ?`)
+	if err != nil {
+		return 0, err
+	}
+	defer deleteStmt.Close()
+
+	removed := 0
+	for _, sessionID := range sessionIDs {
+		intervals, err := loadSessionIntervals(tx, sessionID)
+		if err != nil {
+			return 0, err
+		}
+
+		var cur *compactInterval
+		for i := range intervals {
+			next := &intervals[i]
+			if cur != nil {
+				gap := next.startTS - cur.endTS
+				if gap >= 0 && gap <= maxGapSec {
+					cur.endTS = next.endTS
+					cur.endPosTicks = next.endPosTicks
+					cur.durationSec += next.durationSec
+					if next.seeked != 0 {
+						cur.seeked = 1
+					}
+					cur.mergedIntoRow = true
+					if _, err := deleteStmt.Exec(next.id); err != nil {
+						return 0, err
+					}
+					removed++
+					continue
+				}
+				if err := flushCompactedInterval(updateStmt, cur); err != nil {
+					return 0, err
+				}
+			}
+			cur = next
+		}
+		if cur != nil {
+			if err := flushCompactedInterval(updateStmt, cur); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+func loadSessionIntervals(tx *sql.Tx, sessionID int64) ([]compactInterval, error) {
+	rows, err := tx.Query(`
+		SELECT id, start_ts, end_ts, end_pos_ticks, duration_seconds, seeked
+		FROM play_intervals
+		WHERE session_fk = ?
+		ORDER BY start_ts, id
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []compactInterval
+	for rows.Next() {
+		var iv compactInterval
+		if err := rows.Scan(&iv.id, &iv.startTS, &iv.endTS, &iv.endPosTicks, &iv.durationSec, &iv.seeked); err != nil {
+			return nil, err
+		}
+		// Aggregations fall back to the wall-clock span when duration is unset; make that
+		// explicit so the merged row doesn't start counting the gap.
+		if iv.durationSec <= 0 {
+			iv.durationSec = iv.endTS - iv.startTS
+		}
+		out = append(out, iv)
+	}
+	return out, rows.Err()
+}
+
+func flushCompactedInterval(stmt *sql.Stmt, iv *compactInterval) error {
+	if !iv.mergedIntoRow {
+		return nil
+	}
+	_, err := stmt.Exec(iv.endTS, iv.endPosTicks, iv.durationSec, iv.seeked, iv.id)
+	return err
+}
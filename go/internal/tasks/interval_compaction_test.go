@@ -0,0 +1,74 @@
+package tasks
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompactIntervals(t *testing.T) {
+	conn := openTestDB(t,
+		`INSERT INTO play_sessions (id, user_id, session_id, item_id, started_at, ended_at, is_active)
+		 VALUES (1, 'u', 's1', 'i', 1000, 2000, 0),
+		        (2, 'u', 's2', 'i', 1000, 2000, 1),
+		        (3, 'u', 's3', 'i', 5000, 9000, 0)`,
+		`INSERT INTO play_intervals (id, session_fk, item_id, user_id, start_ts, end_ts, start_pos_ticks, end_pos_ticks, duration_seconds, seeked)
+		 VALUES (1, 1, 'i', 'u', 1000, 1100, 0, 100, 100, 0),
+		        (2, 1, 'i', 'u', 1130, 1200, 100, 170, 0, 1),
+		        (3, 1, 'i', 'u', 1210, 1300, 170, 260, 80, 0),
+		        (4, 1, 'i', 'u', 1280, 1400, 260, 380, 120, 0),
+		        (5, 1, 'i', 'u', 1500, 1600, 380, 480, 100, 0),
+		        (6, 2, 'i', 'u', 1000, 1100, 0, 100, 100, 0),
+		        (7, 2, 'i', 'u', 1110, 1200, 100, 190, 90, 0),
+		        (8, 3, 'i', 'u', 5000, 5100, 0, 100, 100, 0),
+		        (9, 3, 'i', 'u', 5110, 5200, 100, 190, 90, 0)`,
+	)
+
+	// Session 3 ended after the settle cutoff and session 2 is still active
+	res, err := CompactIntervals(conn, 30, 3000)
+	if err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if res.Sessions != 1 || res.RowsRemoved != 2 {
+		t.Errorf("result = %+v", res)
+	}
+
+	type row struct{ id, start, end, endPos, duration, seeked int64 }
+	rows, err := conn.Query(`SELECT id, start_ts, end_ts, end_pos_ticks, duration_seconds, seeked FROM play_intervals ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.start, &r.end, &r.endPos, &r.duration, &r.seeked); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r)
+	}
+	want := []row{
+		// 1-3 merge across gaps of 30s and 10s; the duration is the sum (row 2's unset
+		// duration counts as its span), not the 300s the merged row spans
+		{1, 1000, 1300, 260, 250, 1},
+		// 4 overlaps 3 and 5 is 100s after 4, so both stay as they were
+		{4, 1280, 1400, 380, 120, 0},
+		{5, 1500, 1600, 480, 100, 0},
+		{6, 1000, 1100, 100, 100, 0},
+		{7, 1110, 1200, 190, 90, 0},
+		{8, 5000, 5100, 100, 100, 0},
+		{9, 5110, 5200, 190, 90, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("intervals =\n%v\nwant\n%v", got, want)
+	}
+
+	var total int64
+	if err := conn.QueryRow(`SELECT SUM(duration_seconds) FROM play_intervals WHERE session_fk = 1`).Scan(&total); err != nil || total != 470 {
+		t.Errorf("session 1 watch time = %d, %v; want 470 as before compaction", total, err)
+	}
+
+	// Once session 3 has settled it is compacted too; a second pass over session 1 is a no-op
+	if res, err = CompactIntervals(conn, 30, 10000); err != nil || res.Sessions != 1 || res.RowsRemoved != 1 {
+		t.Errorf("second pass = %+v, %v", res, err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"emby-analytics/internal/emby"
+	"emby-analytics/internal/media"
 )
 
 type Intervalizer struct {
@@ -16,10 +17,39 @@ type Intervalizer struct {
 	NoProgressTimeout time.Duration
 	PausedTimeout     time.Duration // NEW: Timeout for paused sessions
 	SeekThreshold     time.Duration
+	ServerID          string           // server the events come from; empty means the legacy default Emby
+	ServerType        media.ServerType // type stored on new sessions; empty means Emby
+}
+
+// newIntervalizer returns an Intervalizer with the default timeouts for serverID.
+func newIntervalizer(db *sql.DB, serverID string, serverType media.ServerType) *Intervalizer {
+	return &Intervalizer{
+		DB:                db,
+		NoProgressTimeout: 15 * time.Minute,
+		PausedTimeout:     24 * time.Hour, // Default to 24 hours for paused sessions
+		SeekThreshold:     2 * time.Minute,
+		ServerID:          serverID,
+		ServerType:        serverType,
+	}
+}
+
+func (iz *Intervalizer) serverID() string {
+	if iz.ServerID == "" {
+		return "default-emby"
+	}
+	return iz.ServerID
+}
+
+func (iz *Intervalizer) serverType() media.ServerType {
+	if iz.ServerType == "" {
+		return media.ServerTypeEmby
+	}
+	return iz.ServerType
 }
 
 type liveState struct {
 	SessionFK        int64
+	ServerID         string // Server the session is playing on
 	SessionID        string // Session identifier from Emby
 	DeviceID         string // Device identifier from Emby
 	UserID           string
@@ -34,6 +64,8 @@ type liveState struct {
 	IsPaused         bool // NEW: Track if the session is currently paused
 	// Tracks whether we have recorded any interval for this session
 	HadAnyInterval bool
+	// Suppressed is set while the session processor records this playback, see claimPlayback
+	Suppressed bool
 }
 
 var (
@@ -58,7 +90,7 @@ func GetLiveUserWatchTimes() map[string]float64 {
 	watchTimes := make(map[string]float64)
 	now := time.Now()
 	for _, session := range LiveSessions {
-		if session.IsIntervalOpen {
+		if session.IsIntervalOpen && !session.Suppressed {
 			duration := now.Sub(session.IntervalStartTS).Seconds()
 			watchTimes[session.UserID] += duration
 		}
@@ -73,7 +105,7 @@ func GetLiveItemWatchTimes() map[string]float64 {
 	watchTimes := make(map[string]float64)
 	now := time.Now()
 	for _, session := range LiveSessions {
-		if session.IsIntervalOpen && !isLiveTVType(session.ItemType) {
+		if session.IsIntervalOpen && !session.Suppressed && !isLiveTVType(session.ItemType) {
 			duration := now.Sub(session.IntervalStartTS).Seconds()
 			watchTimes[session.ItemID] += duration
 		}
@@ -88,7 +120,7 @@ func GetLiveUserWatchTimesExcludingLiveTV() map[string]float64 {
 	watchTimes := make(map[string]float64)
 	now := time.Now()
 	for _, session := range LiveSessions {
-		if session.IsIntervalOpen && !isLiveTVType(session.ItemType) {
+		if session.IsIntervalOpen && !session.Suppressed && !isLiveTVType(session.ItemType) {
 			duration := now.Sub(session.IntervalStartTS).Seconds()
 			watchTimes[session.UserID] += duration
 		}
@@ -96,18 +128,27 @@ func GetLiveUserWatchTimesExcludingLiveTV() map[string]float64 {
 	return watchTimes
 }
 
-func sessionKey(sessionID, itemID string) string { return sessionID + "|" + itemID }
+// sessionKey keys LiveSessions; several Intervalizers (one per server) share the map.
+func (iz *Intervalizer) sessionKey(sessionID, itemID string) string {
+	return iz.serverID() + "|" + sessionID + "|" + itemID
+}
 
 func (iz *Intervalizer) Handle(evt emby.EmbyEvent) {
 	logging.Debug("Received event: %s", evt.MessageType)
 
-	LiveMutex.Lock()
-	defer LiveMutex.Unlock()
 	var data emby.PlaybackProgressData
 	if err := json.Unmarshal(evt.Data, &data); err != nil {
 		logging.Debug("JSON unmarshal error: %v", err)
 		return
 	}
+	iz.HandlePlayback(evt.MessageType, data)
+}
+
+// HandlePlayback processes an already decoded playback event, for sources
+// that don't deliver Emby's JSON envelope (e.g. the Jellyfin socket).
+func (iz *Intervalizer) HandlePlayback(messageType string, data emby.PlaybackProgressData) {
+	LiveMutex.Lock()
+	defer LiveMutex.Unlock()
 	if data.NowPlaying.ID == "" {
 		logging.Debug("[intervalizer] Empty NowPlaying.ID, skipping event")
 		return
@@ -118,9 +159,9 @@ func (iz *Intervalizer) Handle(evt emby.EmbyEvent) {
 		return
 	}
 
-	logging.Debug("Processing %s for user %s, item %s", evt.MessageType, data.UserID, data.NowPlaying.Name)
+	logging.Debug("Processing %s for user %s, item %s", messageType, data.UserID, data.NowPlaying.Name)
 
-	switch evt.MessageType {
+	switch messageType {
 	case "PlaybackStart":
 		iz.onStart(data)
 	case "PlaybackProgress":
@@ -132,16 +173,16 @@ func (iz *Intervalizer) Handle(evt emby.EmbyEvent) {
 	case "PlaybackUnpaused":
 		iz.onUnpause(data)
 	default:
-		logging.Debug("Unhandled event type: %s", evt.MessageType)
+		logging.Debug("Unhandled event type: %s", messageType)
 	}
 }
 
 func (iz *Intervalizer) onStart(d emby.PlaybackProgressData) {
 	logging.Debug("onStart called for user %s, item %s, session %s", d.UserID, d.NowPlaying.Name, d.SessionID)
 
-	k := sessionKey(d.SessionID, d.NowPlaying.ID)
+	k := iz.sessionKey(d.SessionID, d.NowPlaying.ID)
 	now := time.Now().UTC()
-	sessionFK, err := upsertSession(iz.DB, d)
+	sessionFK, err := upsertSession(iz.DB, iz.serverID(), iz.serverType(), d)
 	if err != nil {
 		logging.Debug("onStart upsertSession failed: %v", err)
 		return
@@ -151,6 +192,7 @@ func (iz *Intervalizer) onStart(d emby.PlaybackProgressData) {
 	insertEvent(iz.DB, sessionFK, "start", d.PlayState.IsPaused, d.PlayState.PositionTicks)
 	s := &liveState{
 		SessionFK:      sessionFK,
+		ServerID:       iz.serverID(),
 		SessionID:      d.SessionID,
 		DeviceID:       d.DeviceID,
 		UserID:         d.UserID,
@@ -161,6 +203,11 @@ func (iz *Intervalizer) onStart(d emby.PlaybackProgressData) {
 		SessionStartTS: now, // Store the absolute start time
 		IsIntervalOpen: false,
 	}
+	if !claimPlayback(sourceEvents, iz.serverID(), d.SessionID, d.NowPlaying.ID, now) {
+		// Already recorded by the session processor: share its row, not its watch time
+		s.Suppressed = true
+		countMerged(iz.serverID())
+	}
 
 	var intervalCount int
 	err = iz.DB.QueryRow(`SELECT COUNT(*) FROM play_intervals WHERE session_fk = ?`, sessionFK).Scan(&intervalCount)
@@ -172,7 +219,7 @@ func (iz *Intervalizer) onStart(d emby.PlaybackProgressData) {
 }
 
 func (iz *Intervalizer) onProgress(d emby.PlaybackProgressData) {
-	k := sessionKey(d.SessionID, d.NowPlaying.ID)
+	k := iz.sessionKey(d.SessionID, d.NowPlaying.ID)
 	s, ok := LiveSessions[k]
 	if !ok {
 		iz.onStart(d)
@@ -182,6 +229,7 @@ func (iz *Intervalizer) onProgress(d emby.PlaybackProgressData) {
 		}
 	}
 	now := time.Now().UTC()
+	iz.reconcile(s, now)
 	insertEvent(iz.DB, s.SessionFK, "progress", d.PlayState.IsPaused, d.PlayState.PositionTicks)
 	if d.PlayState.IsPaused {
 		if s.IsIntervalOpen {
@@ -212,7 +260,7 @@ func (iz *Intervalizer) onProgress(d emby.PlaybackProgressData) {
 }
 
 func (iz *Intervalizer) onStop(d emby.PlaybackProgressData) {
-	k := sessionKey(d.SessionID, d.NowPlaying.ID)
+	k := iz.sessionKey(d.SessionID, d.NowPlaying.ID)
 	s, ok := LiveSessions[k]
 	if !ok {
 		return
@@ -243,7 +291,7 @@ func (iz *Intervalizer) onStop(d emby.PlaybackProgressData) {
 		// Optional cap: do not let a single session's total intervals exceed item runtime
 		// Fetch item runtime (in ticks) -> seconds
 		var runTimeTicks sql.NullInt64
-		_ = iz.DB.QueryRow(`SELECT run_time_ticks FROM library_item WHERE id = ?`, d.NowPlaying.ID).Scan(&runTimeTicks)
+		_ = iz.DB.QueryRow(`SELECT run_time_ticks FROM library_item WHERE id = ?`, storageItemID(iz.serverID(), d.NowPlaying.ID)).Scan(&runTimeTicks)
 		if runTimeTicks.Valid && runTimeTicks.Int64 > 0 {
 			runtimeSec := int(runTimeTicks.Int64 / ticksPerSecond)
 			var alreadySec sql.NullInt64
@@ -265,12 +313,16 @@ func (iz *Intervalizer) onStop(d emby.PlaybackProgressData) {
 		iz.closeInterval(s, startTS, endTS, 0, d.PlayState.PositionTicks, false)
 	}
 
-	_, _ = iz.DB.Exec(`UPDATE play_sessions SET ended_at = ?, is_active = false WHERE id = ?`, now.Unix(), s.SessionFK)
+	// A suppressed playback's row is closed by the session processor
+	if !s.Suppressed {
+		_, _ = iz.DB.Exec(`UPDATE play_sessions SET ended_at = ?, is_active = false WHERE id = ?`, now.Unix(), s.SessionFK)
+	}
+	releasePlayback(sourceEvents, iz.serverID(), s.SessionID, s.ItemID)
 	delete(LiveSessions, k)
 }
 
 func (iz *Intervalizer) onPause(d emby.PlaybackProgressData) {
-	k := sessionKey(d.SessionID, d.NowPlaying.ID)
+	k := iz.sessionKey(d.SessionID, d.NowPlaying.ID)
 	s, ok := LiveSessions[k]
 	if !ok {
 		// If we didn't track it, try to start it (e.g., if we missed the PlaybackStart)
@@ -281,6 +333,7 @@ func (iz *Intervalizer) onPause(d emby.PlaybackProgressData) {
 		}
 	}
 	now := time.Now().UTC()
+	iz.reconcile(s, now)
 	insertEvent(iz.DB, s.SessionFK, "pause", true, d.PlayState.PositionTicks)
 	if s.IsIntervalOpen {
 		iz.closeInterval(s, s.IntervalStartTS, now, s.IntervalStartPos, d.PlayState.PositionTicks, false)
@@ -291,7 +344,7 @@ func (iz *Intervalizer) onPause(d emby.PlaybackProgressData) {
 }
 
 func (iz *Intervalizer) onUnpause(d emby.PlaybackProgressData) {
-	k := sessionKey(d.SessionID, d.NowPlaying.ID)
+	k := iz.sessionKey(d.SessionID, d.NowPlaying.ID)
 	s, ok := LiveSessions[k]
 	if !ok {
 		// If we didn't track it, try to start it (e.g., if we missed the PlaybackStart)
@@ -302,6 +355,7 @@ func (iz *Intervalizer) onUnpause(d emby.PlaybackProgressData) {
 		}
 	}
 	now := time.Now().UTC()
+	iz.reconcile(s, now)
 	insertEvent(iz.DB, s.SessionFK, "unpause", false, d.PlayState.PositionTicks)
 	s.IsPaused = false
 	s.LastEventTS = now
@@ -322,6 +376,9 @@ func (iz *Intervalizer) DetectStoppedSessions(activeSessionKeys map[string]bool)
 	logging.Debug("DetectStoppedSessions called with %d active sessions, %d live sessions", len(activeSessionKeys), len(LiveSessions))
 
 	for liveSessionKey, liveSession := range LiveSessions {
+		if liveSession.ServerID != iz.serverID() {
+			continue
+		}
 		// Check if this session is still active by SessionID only
 		// This avoids the Device vs DeviceID mismatch between WebSocket and REST API
 		sessionFound := false
@@ -383,6 +440,9 @@ func (iz *Intervalizer) TickTimeoutSweep() {
 	defer LiveMutex.Unlock()
 	now := time.Now().UTC()
 	for k, s := range LiveSessions {
+		if s.ServerID != iz.serverID() {
+			continue
+		}
 		var timeout time.Duration
 		if s.IsPaused {
 			timeout = iz.PausedTimeout
@@ -395,21 +455,48 @@ func (iz *Intervalizer) TickTimeoutSweep() {
 			if s.IsIntervalOpen {
 				iz.closeInterval(s, s.IntervalStartTS, s.LastEventTS, s.IntervalStartPos, s.LastPosTicks, false)
 			}
-			_, _ = iz.DB.Exec(`UPDATE play_sessions SET ended_at = ?, is_active = false WHERE id = ?`, s.LastEventTS.Unix(), s.SessionFK)
+			if !s.Suppressed {
+				_, _ = iz.DB.Exec(`UPDATE play_sessions SET ended_at = ?, is_active = false WHERE id = ?`, s.LastEventTS.Unix(), s.SessionFK)
+			}
+			releasePlayback(sourceEvents, iz.serverID(), s.SessionID, s.ItemID)
 			delete(LiveSessions, k)
 		}
 	}
 }
 
+// reconcile re-checks who records a tracked playback's watch time. Taking over from the
+// session processor starts any open interval now, so time it already recorded isn't counted.
+func (iz *Intervalizer) reconcile(s *liveState, now time.Time) {
+	owns := claimPlayback(sourceEvents, iz.serverID(), s.SessionID, s.ItemID, now)
+	switch {
+	case s.Suppressed && owns:
+		s.Suppressed = false
+		if s.IsIntervalOpen {
+			s.IntervalStartTS = now
+		}
+	case !s.Suppressed && !owns:
+		if s.IsIntervalOpen {
+			iz.closeInterval(s, s.IntervalStartTS, now, s.IntervalStartPos, s.LastPosTicks, false)
+		}
+		s.Suppressed = true
+	}
+}
+
 func (iz *Intervalizer) closeInterval(s *liveState, start time.Time, end time.Time, startPos int64, endPos int64, seeked bool) {
 	if end.Before(start) || end.Sub(start).Seconds() < 1 {
 		s.IsIntervalOpen = false
 		return
 	}
 	dur := int(end.Sub(start).Seconds())
+	if s.Suppressed {
+		countSuppressed(iz.serverID(), dur)
+		s.IsIntervalOpen = false
+		s.HadAnyInterval = true
+		return
+	}
 	_, err := iz.DB.Exec(`
-        INSERT INTO play_intervals (session_fk, item_id, user_id, start_ts, end_ts, start_pos_ticks, end_pos_ticks, duration_seconds, seeked)
-        SELECT id, item_id, user_id, ?, ?, ?, ?, ?, ?
+        INSERT INTO play_intervals (session_fk, item_id, user_id, start_ts, end_ts, start_pos_ticks, end_pos_ticks, duration_seconds, seeked, server_id)
+        SELECT id, item_id, user_id, ?, ?, ?, ?, ?, ?, server_id
         FROM play_sessions
         WHERE id = ?
     `, start.Unix(), end.Unix(), startPos, endPos, dur, boolToInt(seeked), s.SessionFK)
@@ -421,10 +508,10 @@ func (iz *Intervalizer) closeInterval(s *liveState, start time.Time, end time.Ti
 }
 
 // ... (upsertSession, insertEvent, boolToInt are unchanged)
-func upsertSession(db *sql.DB, d emby.PlaybackProgressData) (int64, error) {
+func upsertSession(db *sql.DB, serverID string, serverType media.ServerType, d emby.PlaybackProgressData) (int64, error) {
 	var id int64
-	// Check for ANY existing session (active or inactive)
-	err := db.QueryRow(`SELECT id FROM play_sessions WHERE session_id=? AND item_id=?`, d.SessionID, d.NowPlaying.ID).Scan(&id)
+	// Check for ANY existing session (active or inactive), including rows the session processor created
+	err := db.QueryRow(`SELECT id FROM play_sessions WHERE server_id=? AND session_id=? AND item_id=?`, serverID, d.SessionID, d.NowPlaying.ID).Scan(&id)
 	if err == nil {
 		// Found existing session, reactivate it
 
@@ -464,9 +551,9 @@ func upsertSession(db *sql.DB, d emby.PlaybackProgressData) (int64, error) {
 	videoMethod, audioMethod, videoCodecFrom, videoCodecTo, audioCodecFrom, audioCodecTo := determineDetailedMethods(d)
 
 	res, err := db.Exec(`
-		INSERT INTO play_sessions(user_id, session_id, device_id, client_name, item_id, item_name, item_type, play_method, started_at, is_active, transcode_reasons, remote_address, video_method, audio_method, video_codec_from, video_codec_to, audio_codec_from, audio_codec_to)
-		VALUES(?,?,?,?,?,?,?,?,?,true,?,?,?,?,?,?,?,?)
-	`, d.UserID, d.SessionID, d.DeviceID, d.Client, d.NowPlaying.ID, d.NowPlaying.Name, d.NowPlaying.Type, d.PlayMethod, now, transcodeReasonsStr, d.RemoteEndPoint, videoMethod, audioMethod, videoCodecFrom, videoCodecTo, audioCodecFrom, audioCodecTo)
+		INSERT INTO play_sessions(user_id, session_id, device_id, client_name, item_id, item_name, item_type, play_method, started_at, is_active, transcode_reasons, remote_address, video_method, audio_method, video_codec_from, video_codec_to, audio_codec_from, audio_codec_to, server_id, server_type)
+		VALUES(?,?,?,?,?,?,?,?,?,true,?,?,?,?,?,?,?,?,?,?)
+	`, d.UserID, d.SessionID, d.DeviceID, d.Client, d.NowPlaying.ID, d.NowPlaying.Name, d.NowPlaying.Type, d.PlayMethod, now, transcodeReasonsStr, d.RemoteEndPoint, videoMethod, audioMethod, videoCodecFrom, videoCodecTo, audioCodecFrom, audioCodecTo, serverID, string(serverType))
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,48 @@
+package tasks
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// LibraryItemHash fingerprints the metadata a library sync writes for an item. An item
+// whose hash matches its stored content_hash is unchanged and needs no writes.
+func LibraryItemHash(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:16])
+}
+
+// LibraryItemHashes returns the stored content hashes of a server's library items by id.
+// Episodes not yet linked to their series are left out so the sync retries them.
+func LibraryItemHashes(db *sql.DB, serverID string) (map[string]string, error) {
+	rows, err := db.Query(`
+		SELECT id, content_hash FROM library_item
+		WHERE server_id = ? AND content_hash IS NOT NULL
+		  AND (media_type <> 'Episode' OR series_id IS NOT NULL)
+	`, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]string)
+	for rows.Next() {
+		var id, hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return nil, err
+		}
+		hashes[id] = hash
+	}
+	return hashes, rows.Err()
+}
+
+// unchangedItem reports whether hash matches the stored hash of item id.
+func unchangedItem(hashes map[string]string, id, hash string) bool {
+	return hash != "" && hashes[id] == hash
+}
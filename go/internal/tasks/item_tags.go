@@ -0,0 +1,32 @@
+package tasks
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// tagExecer is satisfied by both *sql.DB and *sql.Tx.
+type tagExecer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// ReplaceItemTags overwrites the stored tags of one library item. Tags are trimmed and
+// de-duplicated case-insensitively; an empty list clears the item's tags.
+func ReplaceItemTags(db tagExecer, itemID, serverID string, tags []string) error {
+	if _, err := db.Exec(`DELETE FROM item_tags WHERE item_id = ?`, itemID); err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, err := db.Exec(`INSERT OR IGNORE INTO item_tags (item_id, tag, server_id) VALUES (?, ?, ?)`, itemID, tag, blankToNil(serverID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"emby-analytics/internal/emby"
+	"emby-analytics/internal/jellyfin"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+)
+
+// StartJellyfinEventIngest connects to a Jellyfin server's WebSocket and feeds
+// its playback events through an Intervalizer for that server, so Jellyfin
+// watch intervals follow the socket rather than only the REST polls. Polling
+// keeps running; claimPlayback decides which path records each playback.
+func StartJellyfinEventIngest(ctx context.Context, db *sql.DB, sc media.ServerConfig) {
+	iz := newIntervalizer(db, sc.ID, media.ServerTypeJellyfin)
+	ws := &jellyfin.WS{
+		Cfg: jellyfin.WSConfig{BaseURL: sc.BaseURL, APIKey: sc.APIKey},
+		Handler: func(evt jellyfin.PlaybackEvent) {
+			iz.HandlePlayback(evt.MessageType, jellyfinPlaybackData(evt))
+		},
+	}
+	ws.Start(ctx)
+	logging.Info("Jellyfin event ingestion started", "server", sc.Name, "server_id", sc.ID)
+
+	// Sessions left open by a dropped socket are closed by the timeout sweep
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				ws.Stop()
+				return
+			case <-ticker.C:
+				iz.TickTimeoutSweep()
+			}
+		}
+	}()
+}
+
+// JellyfinEventIngests runs one event ingest per Jellyfin server, so servers created,
+// updated or deleted under /admin/servers start, restart or stop their socket.
+type JellyfinEventIngests struct {
+	db *sql.DB
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc // by server ID
+}
+
+// NewJellyfinEventIngests returns an empty set of ingests writing to db.
+func NewJellyfinEventIngests(db *sql.DB) *JellyfinEventIngests {
+	return &JellyfinEventIngests{db: db, cancels: map[string]context.CancelFunc{}}
+}
+
+// Sync stops the server's running ingest, if any, and starts a new one from sc when it is an
+// enabled Jellyfin server, picking up a new address or key.
+func (j *JellyfinEventIngests) Sync(sc media.ServerConfig) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if cancel, ok := j.cancels[sc.ID]; ok {
+		cancel()
+		delete(j.cancels, sc.ID)
+		logging.Info("Jellyfin event ingestion stopped", "server_id", sc.ID)
+	}
+	if sc.Type != media.ServerTypeJellyfin || !sc.Enabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancels[sc.ID] = cancel
+	StartJellyfinEventIngest(ctx, j.db, sc)
+}
+
+// Stop stops the server's ingest, if one is running.
+func (j *JellyfinEventIngests) Stop(serverID string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if cancel, ok := j.cancels[serverID]; ok {
+		cancel()
+		delete(j.cancels, serverID)
+		logging.Info("Jellyfin event ingestion stopped", "server_id", serverID)
+	}
+}
+
+// StopAll stops every running ingest.
+func (j *JellyfinEventIngests) StopAll() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for id, cancel := range j.cancels {
+		cancel()
+		delete(j.cancels, id)
+	}
+}
+
+var jellyfinIngests atomic.Pointer[JellyfinEventIngests]
+
+// SetJellyfinEventIngests installs the ingests the server admin handlers keep in step.
+func SetJellyfinEventIngests(j *JellyfinEventIngests) { jellyfinIngests.Store(j) }
+
+// SyncJellyfinEventIngest starts or restarts a saved server's ingest when ingestion is on.
+func SyncJellyfinEventIngest(sc media.ServerConfig) {
+	if j := jellyfinIngests.Load(); j != nil {
+		j.Sync(sc)
+	}
+}
+
+// StopJellyfinEventIngest stops a removed server's ingest when ingestion is on.
+func StopJellyfinEventIngest(serverID string) {
+	if j := jellyfinIngests.Load(); j != nil {
+		j.Stop(serverID)
+	}
+}
+
+// jellyfinPlaybackData converts a socket event into the payload the Intervalizer handles.
+func jellyfinPlaybackData(evt jellyfin.PlaybackEvent) emby.PlaybackProgressData {
+	var d emby.PlaybackProgressData
+	d.UserID = evt.UserID
+	d.SessionID = evt.SessionID
+	d.DeviceID = evt.DeviceID
+	d.Client = evt.Client
+	d.PlayMethod = evt.PlayMethod
+	d.RemoteEndPoint = evt.RemoteEndPoint
+	d.TranscodeReasons = evt.TranscodeReasons
+	d.NowPlaying.ID = evt.ItemID
+	d.NowPlaying.Name = evt.ItemName
+	d.NowPlaying.Type = evt.ItemType
+	d.NowPlaying.RunTimeTicks = evt.RunTimeTicks
+	d.PlayState.IsPaused = evt.IsPaused
+	d.PlayState.PositionTicks = evt.PositionTicks
+	d.PlayState.AudioStreamIndex = evt.AudioStreamIndex
+	d.PlayState.SubtitleStreamIndex = evt.SubtitleStreamIndex
+	return d
+}
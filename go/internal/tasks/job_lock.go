@@ -0,0 +1,91 @@
+package tasks
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"emby-analytics/internal/logging"
+)
+
+// jobLockTTL is how long a lock survives without a heartbeat before another
+// worker may take it over (e.g. after a crash).
+const jobLockTTL = 10 * time.Minute
+
+// JobLock is a held DB-backed lock, renewed in the background until released.
+type JobLock struct {
+	db    *sql.DB
+	name  string
+	owner string
+	quit  chan struct{}
+	once  sync.Once
+	wg    sync.WaitGroup
+}
+
+// LibrarySyncLockName is the lock shared by every library sync of a server.
+func LibrarySyncLockName(serverID string) string {
+	return "library_sync:" + serverID
+}
+
+// TryJobLock acquires the named lock if it is free or its holder's lease expired.
+// It returns nil (and no error) when another worker holds the lock.
+func TryJobLock(db *sql.DB, name string) (*JobLock, error) {
+	owner := uuid.New().String()
+	now := time.Now().Unix()
+	res, err := db.Exec(`
+		INSERT INTO job_locks (name, owner, acquired_at, expires_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET owner = excluded.owner, acquired_at = excluded.acquired_at, expires_at = excluded.expires_at
+		WHERE job_locks.expires_at < ?`,
+		name, owner, now, now+int64(jobLockTTL.Seconds()), now)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+
+	l := &JobLock{db: db, name: name, owner: owner, quit: make(chan struct{})}
+	l.wg.Add(1)
+	go l.heartbeat()
+	return l, nil
+}
+
+func (l *JobLock) heartbeat() {
+	defer l.wg.Done()
+	ticker := time.NewTicker(jobLockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.quit:
+			return
+		case <-ticker.C:
+			res, err := l.db.Exec(`UPDATE job_locks SET expires_at = ? WHERE name = ? AND owner = ?`,
+				time.Now().Add(jobLockTTL).Unix(), l.name, l.owner)
+			if err != nil {
+				logging.Debug("job lock heartbeat failed", "lock", l.name, "error", err)
+				continue
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				logging.Warn("job lock was taken over by another worker", "lock", l.name)
+				return
+			}
+		}
+	}
+}
+
+// Release stops the heartbeat and frees the lock. Safe to call more than once.
+func (l *JobLock) Release() {
+	if l == nil {
+		return
+	}
+	l.once.Do(func() {
+		close(l.quit)
+		l.wg.Wait()
+		if _, err := l.db.Exec(`DELETE FROM job_locks WHERE name = ? AND owner = ?`, l.name, l.owner); err != nil {
+			logging.Debug("job lock release failed", "lock", l.name, "error", err)
+		}
+	})
+}
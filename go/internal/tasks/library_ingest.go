@@ -1,19 +1,26 @@
 package tasks
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"emby-analytics/internal/hooks"
 	"emby-analytics/internal/jellyfin"
 	"emby-analytics/internal/logging"
 	"emby-analytics/internal/media"
 	"emby-analytics/internal/plex"
+	"emby-analytics/internal/queries"
 )
 
-const librarySyncSettingPrefix = "library_sync_at_"
+const (
+	librarySyncSettingPrefix     = "library_sync_at_"
+	libraryReportedSettingPrefix = "library_sync_items_" // movies and episodes the server returned
+)
 
 // IngestLibraries pulls library metadata from external servers so stats endpoints can operate on up-to-date data.
 func IngestLibraries(db *sql.DB, mgr *media.MultiServerManager, include map[string]bool, force map[string]bool) {
@@ -37,60 +44,108 @@ func IngestLibraries(db *sql.DB, mgr *media.MultiServerManager, include map[stri
 		if !forced && !shouldRunLibraryIngest(db, serverID, sc.Enabled, 6*time.Hour) {
 			continue
 		}
+		IngestServerLibrary(db, sc, client)
+	}
 
-		StartServerSyncProgress(serverID, sc.Name)
-		SetServerSyncStage(serverID, "Fetching library metadata...")
-		var err error
-		switch sc.Type {
-		case media.ServerTypeJellyfin:
-			if jf, ok := client.(*jellyfin.Client); ok {
-				err = ingestJellyfinLibrary(db, sc, jf)
-			}
-		case media.ServerTypePlex:
-			if px, ok := client.(*plex.Client); ok {
-				err = ingestPlexLibrary(db, sc, px)
-			}
-		case media.ServerTypeEmby:
-			if em, ok := client.(*media.EmbyAdapter); ok {
-				err = ingestEmbyLibrary(db, sc, em)
-			}
-		default:
-			continue
+	// Post-ingestion cleanup: remove series that no longer have any episodes/items
+	CleanupOrphanedSeries(db)
+	RebuildCanonicalItems(db)
+}
+
+// RebuildCanonicalItems regroups the titles that more than one server has, by provider id,
+// so stats count them once.
+func RebuildCanonicalItems(db *sql.DB) {
+	groups, err := queries.RebuildCanonicalItems(context.Background(), db, time.Now().Unix())
+	if err != nil {
+		logging.Warn("Failed to group duplicate library items", "error", err)
+		return
+	}
+	logging.Debug("Grouped duplicate library items", "groups", groups)
+}
+
+// IngestServerLibrary syncs one server's library now, ignoring the ingest interval. It
+// returns false when another library sync already holds the server's lock. Callers run
+// CleanupOrphanedSeries and RebuildCanonicalItems once they're done ingesting.
+func IngestServerLibrary(db *sql.DB, sc media.ServerConfig, client media.MediaServerClient) bool {
+	// Only one library sync per server at a time (manual refresh, scheduler, other instances)
+	lock, err := TryJobLock(db, LibrarySyncLockName(sc.ID))
+	if err != nil {
+		logging.Debug("library ingest lock failed", "server_id", sc.ID, "error", err)
+		return false
+	}
+	if lock == nil {
+		logging.Info("library ingest skipped: sync already running", "server", sc.Name, "server_id", sc.ID)
+		return false
+	}
+	defer lock.Release()
+	ingestServerLibrary(db, sc.ID, sc, client)
+	return true
+}
+
+// ingestServerLibrary runs a library ingest for one server; callers hold its library sync lock.
+func ingestServerLibrary(db *sql.DB, serverID string, sc media.ServerConfig, client media.MediaServerClient) {
+	StartServerSyncProgress(serverID, sc.Name)
+	SetServerSyncStage(serverID, "Fetching library metadata...")
+	var reported int
+	var err error
+	switch sc.Type {
+	case media.ServerTypeJellyfin:
+		if jf, ok := client.(*jellyfin.Client); ok {
+			reported, err = ingestJellyfinLibrary(db, sc, jf)
 		}
-		if err != nil {
-			if errors.Is(err, ErrSyncCancelled) {
-				logging.Debug("library ingest cancelled", "server", sc.Name, "server_id", sc.ID)
-			} else {
-				logging.Debug("library ingest failed", "server", sc.Name, "server_id", sc.ID, "error", err)
-				SetServerSyncStage(serverID, fmt.Sprintf("Sync failed: %v", err))
-				FailServerSyncProgress(serverID, err)
-			}
-			continue
+	case media.ServerTypePlex:
+		if px, ok := client.(*plex.Client); ok {
+			reported, err = ingestPlexLibrary(db, sc, px)
+		}
+	case media.ServerTypeEmby:
+		if em, ok := client.(*media.EmbyAdapter); ok {
+			reported, err = ingestEmbyLibrary(db, sc, em)
+		}
+	default:
+		return
+	}
+	if err != nil {
+		if errors.Is(err, ErrSyncCancelled) {
+			logging.Debug("library ingest cancelled", "server", sc.Name, "server_id", sc.ID)
+		} else {
+			logging.Debug("library ingest failed", "server", sc.Name, "server_id", sc.ID, "error", err)
+			SetServerSyncStage(serverID, fmt.Sprintf("Sync failed: %v", err))
+			FailServerSyncProgress(serverID, err)
 		}
-		_ = setSettingValue(db, librarySyncSettingPrefix+serverID, time.Now().UTC().Format(time.RFC3339))
+		return
 	}
+	_ = setSettingValue(db, librarySyncSettingPrefix+serverID, time.Now().UTC().Format(time.RFC3339))
+	_ = setSettingValue(db, libraryReportedSettingPrefix+serverID, strconv.Itoa(reported))
+}
 
-	// Post-ingestion cleanup: remove series that no longer have any episodes/items
-	CleanupOrphanedSeries(db)
+// countVideoItems counts the movies and episodes among fetched library items.
+func countVideoItems(items []media.MediaItem) int {
+	n := 0
+	for _, it := range items {
+		if strings.EqualFold(it.Type, "Movie") || strings.EqualFold(it.Type, "Episode") {
+			n++
+		}
+	}
+	return n
 }
 
-func ingestEmbyLibrary(db *sql.DB, sc media.ServerConfig, client *media.EmbyAdapter) error {
+func ingestEmbyLibrary(db *sql.DB, sc media.ServerConfig, client *media.EmbyAdapter) (int, error) {
 	items, err := client.FetchLibraryItems()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if isSyncDisabled(db, sc.ID, sc.Enabled) {
 		CancelServerSyncProgress(sc.ID, "Sync cancelled by user")
-		return ErrSyncCancelled
+		return 0, ErrSyncCancelled
 	}
 	UpdateServerSyncTotals(sc.ID, len(items))
 	SetServerSyncProcessed(sc.ID, 0)
 	if len(items) == 0 {
 		SetServerSyncStage(sc.ID, "No library items returned")
-		return nil
+		return 0, nil
 	}
 	SetServerSyncStage(sc.ID, fmt.Sprintf("Ingesting %d items...", len(items)))
-	return upsertMediaItems(db, sc, items)
+	return countVideoItems(items), upsertMediaItems(db, sc, items)
 }
 
 func shouldRunLibraryIngest(db *sql.DB, serverID string, defaultEnabled bool, interval time.Duration) bool {
@@ -110,42 +165,42 @@ func shouldRunLibraryIngest(db *sql.DB, serverID string, defaultEnabled bool, in
 	return true
 }
 
-func ingestJellyfinLibrary(db *sql.DB, sc media.ServerConfig, client *jellyfin.Client) error {
+func ingestJellyfinLibrary(db *sql.DB, sc media.ServerConfig, client *jellyfin.Client) (int, error) {
 	items, err := client.FetchLibraryItems([]string{"Movie", "Episode"})
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if isSyncDisabled(db, sc.ID, sc.Enabled) {
 		CancelServerSyncProgress(sc.ID, "Sync cancelled by user")
-		return ErrSyncCancelled
+		return 0, ErrSyncCancelled
 	}
 	UpdateServerSyncTotals(sc.ID, len(items))
 	SetServerSyncProcessed(sc.ID, 0)
 	if len(items) == 0 {
 		SetServerSyncStage(sc.ID, "No library items returned")
-		return nil
+		return 0, nil
 	}
 	SetServerSyncStage(sc.ID, fmt.Sprintf("Ingesting %d items...", len(items)))
-	return upsertMediaItems(db, sc, items)
+	return countVideoItems(items), upsertMediaItems(db, sc, items)
 }
 
-func ingestPlexLibrary(db *sql.DB, sc media.ServerConfig, client *plex.Client) error {
+func ingestPlexLibrary(db *sql.DB, sc media.ServerConfig, client *plex.Client) (int, error) {
 	items, err := client.FetchLibraryItems()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if isSyncDisabled(db, sc.ID, sc.Enabled) {
 		CancelServerSyncProgress(sc.ID, "Sync cancelled by user")
-		return ErrSyncCancelled
+		return 0, ErrSyncCancelled
 	}
 	UpdateServerSyncTotals(sc.ID, len(items))
 	SetServerSyncProcessed(sc.ID, 0)
 	if len(items) == 0 {
 		SetServerSyncStage(sc.ID, "No library items returned")
-		return nil
+		return 0, nil
 	}
 	SetServerSyncStage(sc.ID, fmt.Sprintf("Ingesting %d items...", len(items)))
-	return upsertMediaItems(db, sc, items)
+	return countVideoItems(items), upsertMediaItems(db, sc, items)
 }
 
 func upsertMediaItems(db *sql.DB, sc media.ServerConfig, items []media.MediaItem) error {
@@ -160,6 +215,12 @@ func upsertMediaItems(db *sql.DB, sc media.ServerConfig, items []media.MediaItem
 		logging.Info("IngestLibraries: tracking deletions", "existing_db_count", len(existingIDs))
 	}
 
+	// Items whose content hash matches the stored one are skipped
+	hashes, err := LibraryItemHashes(db, sc.ID)
+	if err != nil {
+		logging.Warn("failed to load library item hashes - writing every item", "server", sc.Name, "error", err)
+	}
+
 	// Start transaction for bulk operations
 	tx, err := db.Begin()
 	if err != nil {
@@ -169,8 +230,8 @@ func upsertMediaItems(db *sql.DB, sc media.ServerConfig, items []media.MediaItem
 
 	// Prepare statements for performance
 	upsertStmt, err := tx.Prepare(`
-		INSERT INTO library_item (id, server_id, server_type, item_id, name, media_type, height, width, run_time_ticks, container, video_codec, file_size_bytes, bitrate_bps, file_path, genres, series_id, series_name, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		INSERT INTO library_item (id, server_id, server_type, item_id, name, media_type, height, width, run_time_ticks, container, video_codec, file_size_bytes, bitrate_bps, file_path, genres, series_id, series_name, production_year, official_rating, provider_ids, content_hash, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		ON CONFLICT(id) DO UPDATE SET
 			server_id = COALESCE(excluded.server_id, library_item.server_id),
 			server_type = COALESCE(excluded.server_type, library_item.server_type),
@@ -188,6 +249,10 @@ func upsertMediaItems(db *sql.DB, sc media.ServerConfig, items []media.MediaItem
 			genres = COALESCE(NULLIF(excluded.genres, ''), library_item.genres),
 			series_id = COALESCE(NULLIF(excluded.series_id, ''), library_item.series_id),
 			series_name = COALESCE(NULLIF(excluded.series_name, ''), library_item.series_name),
+			production_year = COALESCE(excluded.production_year, library_item.production_year),
+			official_rating = COALESCE(NULLIF(excluded.official_rating, ''), library_item.official_rating),
+			provider_ids = COALESCE(NULLIF(excluded.provider_ids, ''), library_item.provider_ids),
+			content_hash = excluded.content_hash,
 			updated_at = CURRENT_TIMESTAMP
 	`)
 	if err != nil {
@@ -196,6 +261,10 @@ func upsertMediaItems(db *sql.DB, sc media.ServerConfig, items []media.MediaItem
 	defer upsertStmt.Close()
 
 	seriesUpserts := make(map[string]string)
+	seriesRatings := seriesOfficialRatings(items)
+	var synced []hooks.LibraryItem
+	notify := hooks.Enabled(hooks.LibraryItemSynced)
+	changed, unchanged := 0, 0
 	for idx, item := range items {
 		if idx%cancelCheckInterval == 0 && isSyncDisabled(db, sc.ID, sc.Enabled) {
 			CancelServerSyncProgress(sc.ID, "Sync cancelled by user")
@@ -239,11 +308,32 @@ func upsertMediaItems(db *sql.DB, sc media.ServerConfig, items []media.MediaItem
 			}
 		}
 
-		_, err := upsertStmt.Exec(storedID, sc.ID, string(sc.Type), item.ID, item.Name, item.Type, height, width, runtimeTicks, item.Container, item.Codec, item.FileSizeBytes, item.BitrateBps, blankToNil(item.FilePath), genres, blankToNil(item.SeriesID), blankToNil(item.SeriesName))
+		rating := strings.TrimSpace(item.OfficialRating)
+		if rating == "" && item.SeriesID != "" {
+			// Episodes are often only rated on their series
+			rating = seriesRatings[item.SeriesID]
+		}
+		hash := LibraryItemHash([]any{sc.Type, rating, item})
+		if unchangedItem(hashes, storedID, hash) {
+			unchanged++
+			AddServerSyncChanges(sc.ID, 0, 1)
+			IncrementServerSyncProcessed(sc.ID, 1)
+			continue
+		}
+
+		_, err := upsertStmt.Exec(storedID, sc.ID, string(sc.Type), item.ID, item.Name, item.Type, height, width, runtimeTicks, item.Container, item.Codec, item.FileSizeBytes, item.BitrateBps, blankToNil(item.FilePath), genres, blankToNil(item.SeriesID), blankToNil(item.SeriesName), item.ProductionYear, blankToNil(rating), blankToNil(queries.FormatProviderIDs(item.ProviderIDs)), blankToNil(hash))
 		if err != nil {
 			logging.Debug("failed to upsert item", "item_id", item.ID, "error", err)
 			continue // Don't fail entire batch for one bad item
 		}
+		if err := ReplaceItemTags(tx, storedID, sc.ID, item.Tags); err != nil {
+			logging.Debug("failed to store item tags", "item_id", item.ID, "error", err)
+		}
+		changed++
+		AddServerSyncChanges(sc.ID, 1, 0)
+		if notify {
+			synced = append(synced, hooks.LibraryItem{ID: storedID, ServerID: sc.ID, ServerType: string(sc.Type), Item: item})
+		}
 		IncrementServerSyncProcessed(sc.ID, 1)
 	}
 
@@ -251,6 +341,9 @@ func upsertMediaItems(db *sql.DB, sc media.ServerConfig, items []media.MediaItem
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit upserts: %w", err)
 	}
+	for _, li := range synced {
+		hooks.EmitWait(hooks.LibraryItemSynced, li)
+	}
 
 	// Step 2: Delete items that were not found in the current sync
 	if existingIDs != nil && len(existingIDs) > 0 {
@@ -312,11 +405,30 @@ func upsertMediaItems(db *sql.DB, sc media.ServerConfig, items []media.MediaItem
 			return err
 		}
 	}
-	SetServerSyncStage(sc.ID, fmt.Sprintf("Library ingest complete (%d items)", len(items)))
+	logging.Info("IngestLibraries: items written", "server", sc.Name, "changed", changed, "unchanged", unchanged)
+	SetServerSyncStage(sc.ID, fmt.Sprintf("Library ingest complete (%d items, %d changed, %d unchanged)", len(items), changed, unchanged))
 	SetServerSyncProcessed(sc.ID, len(items))
 	return nil
 }
 
+// seriesOfficialRatings maps series IDs to their parental rating, taken from the series item
+// when the server lists series (Emby) or else from the first rated episode.
+func seriesOfficialRatings(items []media.MediaItem) map[string]string {
+	ratings := make(map[string]string)
+	for _, item := range items {
+		rating := strings.TrimSpace(item.OfficialRating)
+		if rating == "" {
+			continue
+		}
+		if strings.EqualFold(item.Type, "Series") {
+			ratings[item.ID] = rating
+		} else if item.SeriesID != "" && ratings[item.SeriesID] == "" {
+			ratings[item.SeriesID] = rating
+		}
+	}
+	return ratings
+}
+
 func getAllLibraryItemIDs(db *sql.DB, serverID string) (map[string]bool, error) {
 	rows, err := db.Query("SELECT id FROM library_item WHERE server_id = ?", serverID)
 	if err != nil {
@@ -348,7 +460,10 @@ func deleteLibraryItems(db *sql.DB, ids []string) error {
 		args[i] = id
 	}
 
-	_, err := db.Exec(query, args...)
+	if _, err := db.Exec(query, args...); err != nil {
+		return err
+	}
+	_, err := db.Exec(fmt.Sprintf("DELETE FROM item_tags WHERE item_id IN (%s)", placeholders), args...)
 	return err
 }
 
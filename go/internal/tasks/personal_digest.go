@@ -0,0 +1,221 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/notify"
+	"emby-analytics/internal/queries"
+)
+
+// digestCheckInterval is how often due digests are looked for; failed deliveries are retried
+// at the same pace.
+const digestCheckInterval = 10 * time.Minute
+
+// intervalSecondsExpr is an interval's watch time, capped at its wall-clock length.
+const intervalSecondsExpr = `CASE WHEN pi.duration_seconds > 0 AND pi.duration_seconds < pi.end_ts - pi.start_ts
+	THEN pi.duration_seconds ELSE pi.end_ts - pi.start_ts END`
+
+// PersonalDigestScheduler emails subscribed app users a digest of their previous week every
+// Monday at a local time.
+type PersonalDigestScheduler struct {
+	db       *sql.DB
+	smtp     notify.SMTPConfig
+	hour     int
+	minute   int
+	location *time.Location
+	quit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewPersonalDigestScheduler creates a new weekly digest scheduler
+func NewPersonalDigestScheduler(db *sql.DB, smtp notify.SMTPConfig, hour, minute int, loc *time.Location) *PersonalDigestScheduler {
+	return &PersonalDigestScheduler{db: db, smtp: smtp, hour: hour, minute: minute, location: loc, quit: make(chan struct{})}
+}
+
+// Start begins checking for due digests; it does nothing while SMTP isn't configured
+func (s *PersonalDigestScheduler) Start() {
+	if !s.smtp.Enabled() {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(digestCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.quit:
+				return
+			case <-ticker.C:
+				s.checkDue(time.Now())
+			}
+		}
+	}()
+	logging.Info("Personal digest scheduler started", "smtp_host", s.smtp.Host)
+}
+
+// Stop gracefully stops the scheduler
+func (s *PersonalDigestScheduler) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+func (s *PersonalDigestScheduler) checkDue(now time.Time) {
+	local := now.In(s.location)
+	thisWeek, _, _ := queries.GoalPeriodBounds("week", local)
+	due := time.Date(thisWeek.Year(), thisWeek.Month(), thisWeek.Day(), s.hour, s.minute, 0, 0, s.location)
+	if local.Before(due) {
+		return
+	}
+	start := thisWeek.AddDate(0, 0, -7)
+	_, _, periodKey := queries.GoalPeriodBounds("week", start)
+
+	ctx := context.Background()
+	subs, err := queries.ListDigestSubscriptions(ctx, s.db, queries.SubscriptionWeeklyDigest, periodKey)
+	if err != nil {
+		logging.Warn("Failed to list digest subscriptions", "error", err)
+		return
+	}
+	for _, sub := range subs {
+		sendErr := s.send(ctx, sub, start, thisWeek)
+		if sendErr != nil {
+			logging.Warn("Failed to send weekly digest", "user", sub.Username, "error", sendErr)
+		} else {
+			logging.Info("Weekly digest sent", "user", sub.Username, "week", periodKey)
+		}
+		if err := queries.RecordSubscriptionDelivery(ctx, s.db, sub.UserID, sub.Kind, periodKey, sendErr, time.Now().Unix()); err != nil {
+			logging.Warn("Failed to record digest delivery", "user", sub.Username, "error", err)
+		}
+	}
+}
+
+func (s *PersonalDigestScheduler) send(ctx context.Context, sub queries.DigestSubscription, start, end time.Time) error {
+	digest, err := BuildPersonalDigest(ctx, s.db, sub.Username, sub.MediaUserID, start, end)
+	if err != nil {
+		return err
+	}
+	msg, err := notify.RenderPersonalDigest(sub.Email, digest)
+	if err != nil {
+		return err
+	}
+	return notify.SendEmail(s.smtp, msg)
+}
+
+// BuildPersonalDigest computes a media user's watch time, most watched titles and finished
+// series between start and end, plus trending titles they haven't watched yet.
+func BuildPersonalDigest(ctx context.Context, db *sql.DB, username, mediaUserID string, start, end time.Time) (notify.PersonalDigest, error) {
+	out := notify.PersonalDigest{
+		Username:  username,
+		WeekStart: start.Format("2006-01-02"),
+		WeekEnd:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+	}
+	window := []any{mediaUserID, start.Unix(), end.Unix()}
+
+	var secs int64
+	if err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(`+intervalSecondsExpr+`), 0), COUNT(DISTINCT pi.session_fk)
+		FROM play_intervals pi
+		JOIN play_sessions ps ON ps.id = pi.session_fk
+		WHERE pi.user_id = ? AND pi.start_ts >= ? AND pi.start_ts < ? AND pi.end_ts > pi.start_ts
+		  AND COALESCE(ps.item_type, '') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
+	`, window...).Scan(&secs, &out.Sessions); err != nil {
+		return out, fmt.Errorf("watch time: %w", err)
+	}
+	out.Hours = float64(secs) / 3600.0
+
+	// Episodes count towards their series
+	rows, err := db.QueryContext(ctx, `
+		SELECT COALESCE(NULLIF(li.series_name, ''), NULLIF(li.name, ''), ps.item_name, '') AS title,
+		       SUM(`+intervalSecondsExpr+`) / 3600.0 AS hours
+		FROM play_intervals pi
+		JOIN play_sessions ps ON ps.id = pi.session_fk
+		LEFT JOIN library_item li ON li.id = pi.item_id
+		WHERE pi.user_id = ? AND pi.start_ts >= ? AND pi.start_ts < ? AND pi.end_ts > pi.start_ts
+		  AND COALESCE(ps.item_type, '') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
+		GROUP BY title
+		HAVING title <> ''
+		ORDER BY hours DESC
+		LIMIT 5
+	`, window...)
+	if err != nil {
+		return out, fmt.Errorf("top items: %w", err)
+	}
+	for rows.Next() {
+		var it notify.DigestItem
+		if err := rows.Scan(&it.Name, &it.Hours); err != nil {
+			rows.Close()
+			return out, err
+		}
+		out.TopItems = append(out.TopItems, it)
+	}
+	rows.Close()
+
+	// A series is finished once every episode in the library has a completed session by the
+	// user, the last of them during the week
+	rows, err = db.QueryContext(ctx, `
+		SELECT MAX(li.series_name)
+		FROM library_item li
+		WHERE LOWER(COALESCE(li.media_type, '')) = 'episode'
+		  AND li.series_id IN (
+		      SELECT DISTINCT w.series_id FROM play_sessions ps
+		      JOIN library_item w ON w.id = ps.item_id
+		      WHERE ps.user_id = ? AND ps.ended_at >= ? AND ps.ended_at < ? AND COALESCE(w.series_id, '') <> ''
+		  )
+		GROUP BY li.series_id
+		HAVING COUNT(DISTINCT li.id) = COUNT(DISTINCT CASE WHEN EXISTS (
+		           SELECT 1 FROM play_sessions ps
+		           WHERE ps.item_id = li.id AND ps.user_id = ? AND ps.ended_at IS NOT NULL
+		       ) THEN li.id END)
+		ORDER BY 1
+	`, append(window, mediaUserID)...)
+	if err != nil {
+		return out, fmt.Errorf("finished series: %w", err)
+	}
+	for rows.Next() {
+		var name sql.NullString
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return out, err
+		}
+		if name.String != "" {
+			out.FinishedShows = append(out.FinishedShows, name.String)
+		}
+	}
+	rows.Close()
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT COALESCE(NULLIF(li.series_name, ''), NULLIF(li.name, ''), '') AS title,
+		       CASE WHEN COALESCE(li.series_id, '') <> '' THEN 'Series' ELSE COALESCE(li.media_type, '') END,
+		       SUM(t.score) AS score
+		FROM item_trending t
+		JOIN library_item li ON li.id = t.item_id
+		WHERE NOT EXISTS (
+		    SELECT 1 FROM play_sessions ps
+		    LEFT JOIN library_item w ON w.id = ps.item_id
+		    WHERE ps.user_id = ?
+		      AND (ps.item_id = li.id OR (COALESCE(li.series_id, '') <> '' AND w.series_id = li.series_id))
+		)
+		GROUP BY 1, 2
+		HAVING title <> ''
+		ORDER BY score DESC
+		LIMIT 5
+	`, mediaUserID)
+	if err != nil {
+		return out, fmt.Errorf("suggestions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var it notify.DigestItem
+		var score float64
+		if err := rows.Scan(&it.Name, &it.Type, &score); err != nil {
+			return out, err
+		}
+		out.Suggestions = append(out.Suggestions, it)
+	}
+	return out, rows.Err()
+}
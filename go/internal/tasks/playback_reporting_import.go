@@ -0,0 +1,337 @@
+package tasks
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+)
+
+// playbackReportingSessionPrefix marks play_sessions rows imported from the Playback
+// Reporting plugin
+const playbackReportingSessionPrefix = "playbackreporting-"
+
+// PlaybackReportingRow is one row of the plugin's PlaybackActivity table or export.
+type PlaybackReportingRow struct {
+	At             time.Time // playback start
+	UserID         string
+	ItemID         string
+	ItemType       string
+	ItemName       string
+	PlaybackMethod string // e.g. "DirectPlay" or "Transcode (v:direct a:aac)"
+	ClientName     string
+	DeviceName     string
+	PlaySeconds    int64 // time played, excluding pauses
+}
+
+// PlaybackReportingImportResult summarizes a Playback Reporting import.
+type PlaybackReportingImportResult struct {
+	ServerID string `json:"server_id"`
+	DryRun   bool   `json:"dry_run"`
+	Rows     int    `json:"rows"`
+	Imported int    `json:"sessions_imported"`
+	// Existing counts rows imported by an earlier run, Tracked those after live tracking
+	// began and Short those under a minute long
+	Existing int `json:"sessions_existing"`
+	Tracked  int `json:"sessions_skipped_tracked"`
+	Short    int `json:"sessions_skipped_short"`
+	// ReplacedActivityLog counts sessions from /admin/import/activity-log that an imported
+	// row supersedes
+	ReplacedActivityLog int `json:"activity_log_sessions_replaced"`
+	// UsersUnmatched lists plugin user ids not found on the server (deleted users); their
+	// plays are kept under that id
+	UsersUnmatched []string `json:"users_unmatched"`
+	// ItemsUnmatched counts items no longer in the library; they get a library_item row
+	// from the plugin's item name and type
+	ItemsUnmatched int `json:"items_unmatched"`
+}
+
+// playbackReportingTimeLayout reads DateCreated with or without fractional seconds.
+const playbackReportingTimeLayout = "2006-01-02 15:04:05.999999999"
+
+// ReadPlaybackReportingDB reads the PlaybackActivity table of the plugin's
+// playback_reporting.db. DateCreated is stored in the media server's local time, loc.
+func ReadPlaybackReportingDB(path string, loc *time.Location) ([]PlaybackReportingRow, error) {
+	src, err := sql.Open("sqlite", "file:"+path+"?mode=ro&immutable=1")
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	var n int
+	if err := src.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'PlaybackActivity'`).Scan(&n); err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("not a Playback Reporting database: PlaybackActivity table missing")
+	}
+	// CAST keeps the driver from reading DateCreated as a UTC time
+	rows, err := src.Query(`
+		SELECT CAST(DateCreated AS TEXT), COALESCE(UserId, ''), COALESCE(CAST(ItemId AS TEXT), ''),
+		       COALESCE(ItemType, ''), COALESCE(ItemName, ''), COALESCE(PlaybackMethod, ''),
+		       COALESCE(ClientName, ''), COALESCE(DeviceName, ''), COALESCE(PlayDuration, 0)
+		FROM PlaybackActivity
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("read PlaybackActivity: %w", err)
+	}
+	defer rows.Close()
+	var out []PlaybackReportingRow
+	for rows.Next() {
+		var r PlaybackReportingRow
+		var date string
+		if err := rows.Scan(&date, &r.UserID, &r.ItemID, &r.ItemType, &r.ItemName, &r.PlaybackMethod,
+			&r.ClientName, &r.DeviceName, &r.PlaySeconds); err != nil {
+			return nil, fmt.Errorf("read PlaybackActivity: %w", err)
+		}
+		if r.At, err = time.ParseInLocation(playbackReportingTimeLayout, strings.TrimSpace(date), loc); err != nil {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// ReadPlaybackReportingCSV reads the plugin's backup export: tab- or comma-separated
+// DateCreated, UserId, ItemId, ItemType, ItemName, PlaybackMethod, ClientName, DeviceName
+// and PlayDuration, with or without a header row.
+func ReadPlaybackReportingCSV(r io.Reader, loc *time.Location) ([]PlaybackReportingRow, error) {
+	br := bufio.NewReader(r)
+	first, _ := br.Peek(4096)
+	cr := csv.NewReader(br)
+	if line, _, _ := bytes.Cut(first, []byte("\n")); bytes.Contains(line, []byte("\t")) {
+		cr.Comma = '\t'
+	}
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	var out []PlaybackReportingRow
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(rec) < 9 {
+			if line == 1 {
+				return nil, fmt.Errorf("expected 9 columns, found %d", len(rec))
+			}
+			continue
+		}
+		at, err := time.ParseInLocation(playbackReportingTimeLayout, strings.TrimSpace(rec[0]), loc)
+		if err != nil {
+			if line == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: bad DateCreated %q", line, rec[0])
+		}
+		secs, _ := strconv.ParseInt(strings.TrimSpace(rec[8]), 10, 64)
+		out = append(out, PlaybackReportingRow{
+			At: at, UserID: strings.TrimSpace(rec[1]), ItemID: strings.TrimSpace(rec[2]),
+			ItemType: rec[3], ItemName: rec[4], PlaybackMethod: rec[5],
+			ClientName: rec[6], DeviceName: rec[7], PlaySeconds: secs,
+		})
+	}
+	return out, nil
+}
+
+// ImportPlaybackReporting converts Playback Reporting rows into play_sessions and
+// play_intervals of an Emby or Jellyfin server. Each row is one play starting at
+// DateCreated and lasting PlayDuration, capped at six hours.
+//
+// Rows after live tracking of the server began are skipped. Sessions from the activity log
+// import for the same user and item starting within five minutes of a row are replaced,
+// since the plugin records the time played rather than an estimate. Re-running the import,
+// from the database or its export, skips rows it already created. With dryRun nothing is
+// written.
+func ImportPlaybackReporting(db *sql.DB, rows []PlaybackReportingRow, serverID string, serverType media.ServerType, dryRun bool) (*PlaybackReportingImportResult, error) {
+	res := &PlaybackReportingImportResult{ServerID: serverID, DryRun: dryRun, Rows: len(rows), UsersUnmatched: []string{}}
+	cutoff := activityImportCutoff(db, serverID)
+	sort.Slice(rows, func(i, j int) bool { return rows[i].At.Before(rows[j].At) })
+
+	users, err := playbackReportingUsers(db, serverID)
+	if err != nil {
+		return res, err
+	}
+	// Users and items are resolved before any session is written so those writes don't
+	// wait on an open transaction
+	type todoRow struct {
+		PlaybackReportingRow
+		userID, sessionID string
+		played            time.Duration
+	}
+	var todo []todoRow
+	seenUsers, seenItems := map[string]bool{}, map[string]bool{}
+	for _, r := range rows {
+		if r.UserID == "" || r.ItemID == "" {
+			continue
+		}
+		if !r.At.Before(cutoff) {
+			res.Tracked++
+			continue
+		}
+		played := min(time.Duration(r.PlaySeconds)*time.Second, activityLogMaxSession)
+		if played < activityLogMinSession {
+			res.Short++
+			continue
+		}
+		sessionID := playbackReportingSessionID(r)
+		if importedSessionExists(db, serverID, sessionID) {
+			res.Existing++
+			continue
+		}
+
+		userID, known := users[normalizeGUID(r.UserID)]
+		if !known {
+			userID = r.UserID
+			if !seenUsers[userID] {
+				seenUsers[userID] = true
+				res.UsersUnmatched = append(res.UsersUnmatched, userID)
+				if !dryRun {
+					upsertUserAndItem(db, serverID, serverType, userID, userID, "", "", "")
+				}
+			}
+		}
+		if !seenItems[r.ItemID] {
+			seenItems[r.ItemID] = true
+			var n int
+			_ = db.QueryRow(`SELECT COUNT(*) FROM library_item WHERE id = ?`, storageItemID(serverID, r.ItemID)).Scan(&n)
+			if n == 0 {
+				res.ItemsUnmatched++
+				if !dryRun {
+					_, _ = db.Exec(`
+						INSERT OR IGNORE INTO library_item (id, server_id, server_type, item_id, name, media_type, updated_at)
+						VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+						storageItemID(serverID, r.ItemID), serverID, string(serverType), r.ItemID, r.ItemName, r.ItemType)
+				}
+			}
+		}
+		todo = append(todo, todoRow{PlaybackReportingRow: r, userID: userID, sessionID: sessionID, played: played})
+	}
+	if dryRun {
+		res.Imported = len(todo)
+		return res, nil
+	}
+
+	var tx *sql.Tx
+	pending := 0
+	flush := func() error {
+		if tx == nil {
+			return nil
+		}
+		err := tx.Commit()
+		tx, pending = nil, 0
+		return err
+	}
+	for _, r := range todo {
+		userID, sessionID, played := r.userID, r.sessionID, r.played
+		if tx == nil {
+			if tx, err = db.Begin(); err != nil {
+				return res, err
+			}
+		}
+		start := r.At.Unix()
+		end := start + int64(played.Seconds())
+		method, video, audio := playbackReportingMethods(r.PlaybackMethod)
+		if _, err := insertImportedSession(tx, importedSession{
+			ServerID: serverID, ServerType: string(serverType), SessionID: sessionID,
+			UserID: userID, ItemID: r.ItemID, ItemName: r.ItemName, ItemType: r.ItemType,
+			DeviceID: r.DeviceName, ClientName: r.ClientName,
+			PlayMethod: method, VideoMethod: video, AudioMethod: audio,
+			StartedAt: start, EndedAt: end,
+			Intervals: []importedInterval{{Start: start, End: end, Seconds: int64(played.Seconds())}},
+		}); err != nil {
+			tx.Rollback()
+			return res, fmt.Errorf("insert session for %s: %w", sessionID, err)
+		}
+		del, err := tx.Exec(`
+			DELETE FROM play_sessions
+			WHERE server_id = ? AND session_id LIKE ? AND user_id = ? AND item_id = ?
+			  AND started_at BETWEEN ? AND ?`,
+			serverID, activitySessionPrefix+"%", userID, r.ItemID, start-300, start+300)
+		if err != nil {
+			tx.Rollback()
+			return res, err
+		}
+		n, _ := del.RowsAffected()
+		res.ReplacedActivityLog += int(n)
+		res.Imported++
+		if pending++; pending >= importBatchSize {
+			if err := flush(); err != nil {
+				return res, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return res, err
+	}
+
+	logging.Info("Playback Reporting import finished", "server_id", serverID, "dry_run", dryRun, "rows", res.Rows,
+		"imported", res.Imported, "existing", res.Existing, "replaced_activity_log", res.ReplacedActivityLog)
+	return res, nil
+}
+
+// playbackReportingUsers maps the server's user ids, normalized, to their remote ids.
+func playbackReportingUsers(db *sql.DB, serverID string) (map[string]string, error) {
+	rows, err := db.Query(`SELECT id FROM emby_user WHERE server_id = ?`, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		id = remoteID(serverID, id)
+		out[normalizeGUID(id)] = id
+	}
+	return out, rows.Err()
+}
+
+// normalizeGUID lets a dashed user id match the plugin's undashed one.
+func normalizeGUID(id string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(id), "-", ""))
+}
+
+// playbackReportingSessionID identifies a row by user, item and start so the database and
+// its export import to the same sessions.
+func playbackReportingSessionID(r PlaybackReportingRow) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d", normalizeGUID(r.UserID), r.ItemID, r.At.Unix())))
+	return playbackReportingSessionPrefix + hex.EncodeToString(sum[:8])
+}
+
+// playbackReportingMethods splits the plugin's "Transcode (v:h264 a:direct)" into the
+// overall, video and audio play methods.
+func playbackReportingMethods(s string) (method, video, audio string) {
+	lower := strings.ToLower(s)
+	if !strings.HasPrefix(lower, "transcode") {
+		if lower == "" {
+			return "Unknown", "DirectPlay", "DirectPlay"
+		}
+		return "Direct", "DirectPlay", "DirectPlay"
+	}
+	video, audio = "Transcode", "Transcode"
+	for _, f := range strings.Fields(strings.Trim(lower[len("transcode"):], " ()")) {
+		switch {
+		case f == "v:direct":
+			video = "DirectPlay"
+		case f == "a:direct":
+			audio = "DirectPlay"
+		}
+	}
+	return "Transcode", video, audio
+}
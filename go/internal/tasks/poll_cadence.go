@@ -0,0 +1,203 @@
+package tasks
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Session polling modes.
+const (
+	PollModeREST           = "rest"
+	PollModeWebhookPrimary = "webhook-primary"
+)
+
+// PollCadence picks the REST session polling interval per server. In webhook-primary mode
+// a server backs off to Slow while its webhooks keep arriving and returns to Fast once none
+// has arrived for StaleAfter, so servers are polled less without losing sessions when
+// webhooks stop. Servers that don't send webhooks keep being polled at Fast. Playback
+// webhooks also wake the poller for an immediate poll of their server.
+type PollCadence struct {
+	Mode       string
+	Fast       time.Duration
+	Slow       time.Duration
+	StaleAfter time.Duration
+	// Servers lists the IDs of the servers polled; the loop only waits Slow when all of
+	// them deliver webhooks
+	Servers func() []string
+
+	mu       sync.Mutex
+	webhooks map[string]time.Time // last webhook per server
+	polled   map[string]time.Time // last poll per server
+	slowed   map[string]bool      // current cadence per server, to log switches
+	wake     chan struct{}
+}
+
+// PollCadenceStatus is the polling state reported by GET /admin/webhook/stats.
+type PollCadenceStatus struct {
+	Mode            string     `json:"mode"`
+	IntervalSeconds float64    `json:"interval_seconds"`
+	FastSeconds     float64    `json:"fast_seconds"`
+	SlowSeconds     float64    `json:"slow_seconds,omitempty"`
+	StaleAfterMin   float64    `json:"stale_after_minutes,omitempty"`
+	LastWebhookAt   *time.Time `json:"last_webhook_at"`
+	// WebhooksHealthy is set when every polled server delivers webhooks
+	WebhooksHealthy bool                      `json:"webhooks_healthy"`
+	Servers         []PollCadenceServerStatus `json:"servers,omitempty"`
+}
+
+// PollCadenceServerStatus is one server's polling state.
+type PollCadenceServerStatus struct {
+	ServerID        string     `json:"server_id"`
+	IntervalSeconds float64    `json:"interval_seconds"`
+	LastWebhookAt   *time.Time `json:"last_webhook_at"`
+	WebhooksHealthy bool       `json:"webhooks_healthy"`
+}
+
+// NewPollCadence returns the cadence for mode; anything but webhook-primary polls at fast.
+func NewPollCadence(mode string, fast, slow, staleAfter time.Duration) *PollCadence {
+	if mode != PollModeWebhookPrimary {
+		mode = PollModeREST
+	}
+	if slow < fast {
+		slow = fast
+	}
+	return &PollCadence{
+		Mode: mode, Fast: fast, Slow: slow, StaleAfter: staleAfter,
+		webhooks: map[string]time.Time{},
+		polled:   map[string]time.Time{},
+		slowed:   map[string]bool{},
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// WebhookReceived records a webhook from a server; playback webhooks also request a poll
+// of that server now.
+func (p *PollCadence) WebhookReceived(serverID string, now time.Time, playback bool) {
+	p.mu.Lock()
+	p.webhooks[serverID] = now
+	if playback {
+		delete(p.polled, serverID)
+	}
+	p.mu.Unlock()
+	if playback && p.Mode == PollModeWebhookPrimary {
+		select {
+		case p.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Wake signals when a webhook asks for an immediate poll.
+func (p *PollCadence) Wake() <-chan struct{} { return p.wake }
+
+// healthy reports whether the server's last webhook arrived within StaleAfter of now.
+// Must be called with p.mu held.
+func (p *PollCadence) healthy(serverID string, now time.Time) bool {
+	last, ok := p.webhooks[serverID]
+	return ok && now.Sub(last) < p.StaleAfter
+}
+
+// ShouldPoll reports whether a server is due for a session poll, and if so records the poll.
+// Servers with healthy webhooks are due every Slow, or right after a playback webhook.
+func (p *PollCadence) ShouldPoll(serverID string, now time.Time) bool {
+	if p.Mode != PollModeWebhookPrimary {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	slow := p.healthy(serverID, now)
+	if p.slowed[serverID] != slow {
+		p.slowed[serverID] = slow
+		if slow {
+			spLog.Info("Webhooks healthy; slowing session polling", "server_id", serverID, "interval", p.Slow)
+		} else if _, seen := p.webhooks[serverID]; seen {
+			spLog.Warn("No webhook received recently; back to fast session polling",
+				"server_id", serverID, "interval", p.Fast, "stale_after", p.StaleAfter)
+		}
+	}
+	if last, ok := p.polled[serverID]; slow && ok && now.Sub(last) < p.Slow {
+		return false
+	}
+	p.polled[serverID] = now
+	return true
+}
+
+// Interval returns how long to wait before the next poll: Slow when every server delivers
+// webhooks, otherwise Fast.
+func (p *PollCadence) Interval(now time.Time) time.Duration {
+	if p.Mode != PollModeWebhookPrimary || p.Servers == nil {
+		return p.Fast
+	}
+	servers := p.Servers()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(servers) == 0 {
+		return p.Fast
+	}
+	for _, id := range servers {
+		if !p.healthy(id, now) {
+			return p.Fast
+		}
+	}
+	return p.Slow
+}
+
+// Status reports the current cadence.
+func (p *PollCadence) Status(now time.Time) PollCadenceStatus {
+	s := PollCadenceStatus{
+		Mode:            p.Mode,
+		IntervalSeconds: p.Interval(now).Seconds(),
+		FastSeconds:     p.Fast.Seconds(),
+	}
+	var servers []string
+	if p.Servers != nil {
+		servers = p.Servers()
+	}
+	sort.Strings(servers)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, at := range p.webhooks {
+		if s.LastWebhookAt == nil || at.After(*s.LastWebhookAt) {
+			t := at.UTC()
+			s.LastWebhookAt = &t
+		}
+	}
+	if p.Mode != PollModeWebhookPrimary {
+		return s
+	}
+	s.SlowSeconds = p.Slow.Seconds()
+	s.StaleAfterMin = p.StaleAfter.Minutes()
+	s.WebhooksHealthy = len(servers) > 0
+	for _, id := range servers {
+		st := PollCadenceServerStatus{ServerID: id, IntervalSeconds: p.Fast.Seconds()}
+		if at, ok := p.webhooks[id]; ok {
+			t := at.UTC()
+			st.LastWebhookAt = &t
+		}
+		if st.WebhooksHealthy = p.healthy(id, now); st.WebhooksHealthy {
+			st.IntervalSeconds = p.Slow.Seconds()
+		} else {
+			s.WebhooksHealthy = false
+		}
+		s.Servers = append(s.Servers, st)
+	}
+	return s
+}
+
+var pollCadence atomic.Pointer[PollCadence]
+
+// SetPollCadence installs the cadence the session poller and webhook handler share.
+func SetPollCadence(p *PollCadence) { pollCadence.Store(p) }
+
+// CurrentPollCadence returns the installed cadence, or nil.
+func CurrentPollCadence() *PollCadence { return pollCadence.Load() }
+
+// RecordWebhook notes a webhook from a server for the installed cadence, if any.
+func RecordWebhook(serverID string, now time.Time, playback bool) {
+	if p := pollCadence.Load(); p != nil {
+		p.WebhookReceived(serverID, now, playback)
+	}
+}
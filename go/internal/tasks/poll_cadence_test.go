@@ -0,0 +1,63 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPollCadencePerServer(t *testing.T) {
+	p := NewPollCadence(PollModeWebhookPrimary, 5*time.Second, time.Minute, 5*time.Minute)
+	p.Servers = func() []string { return []string{"emby", "plex"} }
+	now := time.Unix(1_700_000_000, 0)
+
+	p.WebhookReceived("emby", now, false)
+	if !p.ShouldPoll("emby", now) || !p.ShouldPoll("plex", now) {
+		t.Fatal("first poll of each server should run")
+	}
+	later := now.Add(10 * time.Second)
+	if p.ShouldPoll("emby", later) {
+		t.Error("emby has healthy webhooks and was polled 10s ago")
+	}
+	if !p.ShouldPoll("plex", later) {
+		t.Error("plex sends no webhooks and should be polled every time")
+	}
+	if got := p.Interval(later); got != p.Fast {
+		t.Errorf("interval with plex unhealthy = %v, want %v", got, p.Fast)
+	}
+
+	// A playback webhook makes its server due right away
+	p.WebhookReceived("emby", later, true)
+	if !p.ShouldPoll("emby", later) {
+		t.Error("emby should be polled after a playback webhook")
+	}
+	if p.ShouldPoll("emby", later.Add(30*time.Second)) {
+		t.Error("emby polled again before the slow interval")
+	}
+	if !p.ShouldPoll("emby", later.Add(time.Minute)) {
+		t.Error("emby should be polled once the slow interval passed")
+	}
+
+	p.WebhookReceived("plex", later, false)
+	if got := p.Interval(later); got != p.Slow {
+		t.Errorf("interval with every server healthy = %v, want %v", got, p.Slow)
+	}
+	st := p.Status(later)
+	if !st.WebhooksHealthy || len(st.Servers) != 2 || st.Servers[1].IntervalSeconds != 60 {
+		t.Errorf("status = %+v", st)
+	}
+
+	// Webhooks going stale bring the server back to fast polling
+	stale := later.Add(6 * time.Minute)
+	if !p.ShouldPoll("emby", stale) || !p.ShouldPoll("emby", stale.Add(time.Second)) {
+		t.Error("emby should be polled every time once its webhooks are stale")
+	}
+}
+
+func TestPollCadenceREST(t *testing.T) {
+	p := NewPollCadence(PollModeREST, 5*time.Second, time.Minute, 5*time.Minute)
+	now := time.Now()
+	p.WebhookReceived("emby", now, true)
+	if !p.ShouldPoll("emby", now) || !p.ShouldPoll("emby", now) || p.Interval(now) != p.Fast {
+		t.Error("rest mode should poll every server at the fast interval")
+	}
+}
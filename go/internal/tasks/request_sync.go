@@ -0,0 +1,72 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"emby-analytics/internal/config"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/overseerr"
+	"emby-analytics/internal/queries"
+)
+
+// StartRequestSyncLoop periodically copies Overseerr/Jellyseerr requests into media_requests
+// when OVERSEERR_URL and OVERSEERR_API_KEY are set.
+func StartRequestSyncLoop(db *sql.DB, cfg config.Config) {
+	if cfg.OverseerrURL == "" || cfg.OverseerrAPIKey == "" {
+		logging.Debug("request sync disabled (OVERSEERR_URL/OVERSEERR_API_KEY not set)")
+		return
+	}
+	interval := time.Duration(cfg.OverseerrSyncIntervalMin) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	client := overseerr.New(cfg.OverseerrURL, cfg.OverseerrAPIKey)
+	logging.Info("Starting request sync loop", "url", cfg.OverseerrURL, "interval", interval)
+
+	go func() {
+		time.Sleep(time.Minute) // stay clear of startup syncs
+		for {
+			if n, err := SyncMediaRequests(context.Background(), db, client); err != nil {
+				logging.Warn("request sync failed", "error", err)
+			} else {
+				logging.Debug("Synced media requests", "requests", n)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// SyncMediaRequests replaces the stored requests with the current list and returns how many
+// there are.
+func SyncMediaRequests(ctx context.Context, db *sql.DB, client *overseerr.Client) (int, error) {
+	reqs, err := client.Requests()
+	if err != nil {
+		return 0, err
+	}
+	rows := make([]queries.MediaRequest, 0, len(reqs))
+	for _, r := range reqs {
+		row := queries.MediaRequest{
+			ID:                  int64(r.ID),
+			MediaType:           r.Type,
+			TMDBID:              int64(r.Media.TMDBID),
+			Is4K:                r.Is4K,
+			RequestStatus:       r.Status,
+			MediaStatus:         r.Media.Status,
+			RequestedBy:         r.RequestedBy.DisplayName,
+			RequestedByUsername: r.MediaUsername(),
+			RequestedAt:         r.CreatedAt.Unix(),
+			MediaItemID:         r.Media.JellyfinMediaID,
+			PlexRatingKey:       r.Media.RatingKey,
+		}
+		if r.Media.TVDBID != nil {
+			row.TVDBID = int64(*r.Media.TVDBID)
+		}
+		if r.Media.Added != nil && !r.Media.Added.IsZero() {
+			row.AddedAt = r.Media.Added.Unix()
+		}
+		rows = append(rows, row)
+	}
+	return len(rows), queries.ReplaceMediaRequests(ctx, db, rows, time.Now().Unix())
+}
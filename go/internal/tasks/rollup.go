@@ -0,0 +1,138 @@
+package tasks
+
+import (
+	"database/sql"
+	"math"
+	"time"
+
+	"emby-analytics/internal/config"
+	"emby-analytics/internal/logging"
+)
+
+// StartRollupLoop periodically recomputes derived aggregates (currently item trending scores).
+func StartRollupLoop(db *sql.DB, cfg config.Config) {
+	if cfg.RollupIntervalSec <= 0 {
+		logging.Debug("rollup loop disabled (interval <= 0)")
+		return
+	}
+	interval := time.Duration(cfg.RollupIntervalSec) * time.Second
+	logging.Debug("Starting rollup loop", "interval", interval)
+
+	go func() {
+		time.Sleep(10 * time.Second) // let startup migrations/syncs settle
+		RunRollupOnce(db, cfg)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			<-ticker.C
+			RunRollupOnce(db, cfg)
+		}
+	}()
+}
+
+// RunRollupOnce executes a single rollup pass immediately.
+func RunRollupOnce(db *sql.DB, cfg config.Config) {
+	if err := RefreshTrendingScores(db, cfg.TrendingHalfLifeDays); err != nil {
+		logging.Debug("trending score refresh failed", "error", err)
+	}
+}
+
+// RefreshTrendingScores recomputes item_trending from play_intervals.
+// Each day's watch hours are weighted by 0.5^(age_days/halfLifeDays), so recent activity dominates.
+func RefreshTrendingScores(db *sql.DB, halfLifeDays int) error {
+	if halfLifeDays <= 0 {
+		halfLifeDays = 7
+	}
+	start := time.Now()
+	now := start.Unix()
+	// Beyond ~10 half-lives a day's contribution is under 0.1%; ignore older history
+	lookbackDays := halfLifeDays * 10
+	if lookbackDays > 365 {
+		lookbackDays = 365
+	}
+	since := now - int64(lookbackDays)*86400
+	weekAgo := now - 7*86400
+
+	rows, err := db.Query(`
+		SELECT pi.item_id,
+		       COALESCE(ps.server_id, ''),
+		       COALESCE(ps.server_type, ''),
+		       (? - pi.end_ts) / 86400 AS age_days,
+		       SUM(CASE WHEN pi.duration_seconds > 0 AND pi.duration_seconds < pi.end_ts - pi.start_ts
+		                THEN pi.duration_seconds ELSE pi.end_ts - pi.start_ts END) AS secs,
+		       MAX(pi.end_ts)
+		FROM play_intervals pi
+		JOIN play_sessions ps ON ps.id = pi.session_fk
+		WHERE pi.end_ts >= ? AND pi.end_ts > pi.start_ts
+		  AND COALESCE(ps.item_type, '') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
+		GROUP BY pi.item_id, age_days
+	`, now, since)
+	if err != nil {
+		return err
+	}
+
+	type trend struct {
+		serverID, serverType string
+		score, hours7d       float64
+		lastWatched          int64
+	}
+	scores := make(map[string]*trend)
+	for rows.Next() {
+		var itemID, serverID, serverType string
+		var ageDays, secs, lastEnd int64
+		if err := rows.Scan(&itemID, &serverID, &serverType, &ageDays, &secs, &lastEnd); err != nil {
+			rows.Close()
+			return err
+		}
+		t, ok := scores[itemID]
+		if !ok {
+			t = &trend{serverID: serverID, serverType: serverType}
+			scores[itemID] = t
+		}
+		hours := float64(secs) / 3600.0
+		t.score += hours * math.Pow(0.5, float64(ageDays)/float64(halfLifeDays))
+		if lastEnd >= weekAgo {
+			t.hours7d += hours
+		}
+		if lastEnd > t.lastWatched {
+			t.lastWatched = lastEnd
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM item_trending`); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`
+		INSERT INTO item_trending (item_id, server_id, server_type, score, hours_7d, last_watched_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for itemID, t := range scores {
+		if t.score <= 0 {
+			continue
+		}
+		if _, err := stmt.Exec(itemID, t.serverID, t.serverType, t.score, t.hours7d, t.lastWatched, now); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logging.Debug("trending scores refreshed", "items", len(scores), "duration", time.Since(start).Round(time.Millisecond))
+	return nil
+}
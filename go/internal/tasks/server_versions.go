@@ -0,0 +1,55 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+	"emby-analytics/internal/queries"
+)
+
+// serverVersionCheckInterval is how often each server's software version is polled.
+const serverVersionCheckInterval = 24 * time.Hour
+
+// StartServerVersionLoop records each server's software version at startup and once a day,
+// so usage changes can be correlated with server updates.
+func StartServerVersionLoop(db *sql.DB, mgr *media.MultiServerManager) {
+	if mgr == nil {
+		return
+	}
+	logging.Debug("Starting server version loop", "interval", serverVersionCheckInterval)
+
+	go func() {
+		time.Sleep(time.Minute) // let the initial syncs go first
+		runServerVersionCheck(db, mgr)
+
+		ticker := time.NewTicker(serverVersionCheckInterval)
+		defer ticker.Stop()
+		for {
+			<-ticker.C
+			runServerVersionCheck(db, mgr)
+		}
+	}()
+}
+
+func runServerVersionCheck(db *sql.DB, mgr *media.MultiServerManager) {
+	ctx := context.Background()
+	now := time.Now()
+	for serverID, client := range mgr.GetEnabledClients() {
+		info, err := client.GetSystemInfo()
+		if err != nil {
+			logging.Debug("server version check failed", "server_id", serverID, "error", err)
+			continue
+		}
+		changed, err := queries.RecordServerVersion(ctx, db, serverID, string(client.GetServerType()), info.Version, now)
+		if err != nil {
+			logging.Warn("failed to record server version", "server_id", serverID, "error", err)
+			continue
+		}
+		if changed {
+			logging.Info("Server version recorded", "server_id", serverID, "version", info.Version)
+		}
+	}
+}
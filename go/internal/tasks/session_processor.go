@@ -2,23 +2,34 @@ package tasks
 
 import (
 	"database/sql"
-	"log"
 	"sync"
 	"time"
 
 	dbutil "emby-analytics/internal/db"
+	"emby-analytics/internal/hooks"
 	"emby-analytics/internal/logging"
 	"emby-analytics/internal/media"
 	"strings"
 )
 
+var spLog = logging.Module("session-processor")
+
+// unreachableServerGrace is how long sessions stay open while their server can't be polled
+// before they are ended at their last sighting.
+const unreachableServerGrace = 15 * time.Minute
+
 // SessionProcessor implements the hybrid state-polling approach used by playback_reporting plugin
 type SessionProcessor struct {
 	DB              *sql.DB
 	MultiServerMgr  *media.MultiServerManager
 	trackedSessions map[string]*TrackedSession // Internal "live list"
+	knownGhosts     map[string]bool            // duplicate sessions already recorded, see dropGhostSessions
 	mu              sync.Mutex
 	Intervalizer    *Intervalizer
+	// lastBandwidthSample is when playing streams were last sampled, see sampleBandwidth
+	lastBandwidthSample time.Time
+	// lastPolled holds each server's sessions from its latest poll
+	lastPolled map[string][]media.Session
 }
 
 // TrackedSession represents a session we're tracking internally
@@ -29,14 +40,24 @@ type TrackedSession struct {
 	ServerType     media.ServerType
 	UserID         string
 	ItemID         string
+	ItemType       string
 	StartTime      time.Time
 	LastUpdate     time.Time
 	LastPosTicks   int64
-	AccumulatedSec int // sum of active (unpaused, progressing) seconds
+	DurationMs     int64 // item runtime as last reported, for playback hooks
+	AccumulatedSec int   // sum of active (unpaused, progressing) seconds
 	LastPaused     bool
 	// CurrentIntervalID tracks the play_intervals.id for the active contiguous segment
 	// so we don't overwrite previous segments when a session is re-activated later.
 	CurrentIntervalID int64
+	// TranscodeSizeKnown is set once the transcode output resolution has been stored
+	TranscodeSizeKnown bool
+	// Observations feed the end-of-session summary (pauses, seeks, bitrate, resolution)
+	Observations sessionObservations
+	// Suppressed is set while Emby playback events record this playback, see claimPlayback
+	Suppressed bool
+	// Tracks are the audio and subtitle tracks last seen, to record switches
+	Tracks trackSelection
 }
 
 // NewSessionProcessor creates a new session processor
@@ -45,12 +66,9 @@ func NewSessionProcessor(db *sql.DB, multiServerMgr *media.MultiServerManager) *
 		DB:              db,
 		MultiServerMgr:  multiServerMgr,
 		trackedSessions: make(map[string]*TrackedSession),
-		Intervalizer: &Intervalizer{
-			DB:                db,
-			NoProgressTimeout: 15 * time.Minute,
-			PausedTimeout:     24 * time.Hour, // Default to 24 hours for paused sessions
-			SeekThreshold:     2 * time.Minute,
-		},
+		knownGhosts:     make(map[string]bool),
+		lastPolled:      make(map[string][]media.Session),
+		Intervalizer:    newIntervalizer(db, "", ""),
 	}
 }
 
@@ -58,22 +76,58 @@ func NewSessionProcessor(db *sql.DB, multiServerMgr *media.MultiServerManager) *
 func (sp *SessionProcessor) ProcessActiveSessions() {
 	// Get sessions from all enabled servers
 	var activeSessions []media.Session
+	unreachable := map[string]bool{}
+	// Servers the poll cadence slows down aren't polled every time; their sessions stay as
+	// last seen until the next poll
+	skipped := map[string]bool{}
 	if sp.MultiServerMgr != nil {
-		sessions, err := sp.MultiServerMgr.GetAllSessions()
-		if err != nil {
-			logging.Error("Failed to get sessions from multi-server manager", "error", err)
-			return
+		poll := func(string) bool { return true }
+		if p := CurrentPollCadence(); p != nil {
+			now := time.Now()
+			poll = func(serverID string) bool {
+				if p.ShouldPoll(serverID, now) {
+					return true
+				}
+				skipped[serverID] = true
+				return false
+			}
+		}
+		sessions, warnings := sp.MultiServerMgr.GetSessionsWithWarnings(poll)
+		for _, w := range warnings {
+			spLog.Warn("Server unreachable; keeping its sessions open", "server_id", w.ServerID, "error", w.Message)
+			unreachable[w.ServerID] = true
 		}
 		activeSessions = sessions
 	}
 	sp.mu.Lock()
 	defer sp.mu.Unlock()
 
-	logging.Debug("Session processor running", "active_sessions", len(activeSessions), "tracked_sessions", len(sp.trackedSessions))
+	spLog.Debug("Session processor running", "active_sessions", len(activeSessions), "tracked_sessions", len(sp.trackedSessions))
 
 	currentTime := time.Now().UTC()
 	activeSessionMap := make(map[string]bool)
 
+	// Step A: Drop duplicate session objects for the same device+item. A tracked
+	// ghost is absent from activeSessionMap, so Step C finalizes it.
+	activeSessions = sp.dropGhostSessions(activeSessions, currentTime, skipped)
+
+	// Streams of skipped servers are sampled as of their last poll
+	sampled := activeSessions
+	for id, sessions := range sp.lastPolled {
+		if skipped[id] {
+			sampled = append(sampled, sessions...)
+		}
+	}
+	sp.sampleBandwidth(sampled, currentTime)
+	for id := range sp.lastPolled {
+		if !skipped[id] {
+			delete(sp.lastPolled, id)
+		}
+	}
+	for _, s := range activeSessions {
+		sp.lastPolled[s.ServerID] = append(sp.lastPolled[s.ServerID], s)
+	}
+
 	// Step B: Process Active Sessions
 	for _, session := range activeSessions {
 		// Composite key to avoid collisions across servers
@@ -89,10 +143,11 @@ func (sp *SessionProcessor) ProcessActiveSessions() {
 		if tracked, exists := sp.trackedSessions[sessionKey]; exists {
 			// Detect item change within the same session
 			if tracked.ItemID != session.ItemID {
-				log.Printf("[session-processor] Item changed within session %s: %s -> %s; rotating session row",
-					sessionKey, tracked.ItemID, session.ItemID)
+				spLog.Info("Item changed within session; rotating session row",
+					"session", sessionKey, "from_item", tracked.ItemID, "to_item", session.ItemID)
 				// Finalize previous item session
 				sp.finalizeSession(tracked, currentTime)
+				releasePlayback(sourcePolling, tracked.ServerID, tracked.SessionID, tracked.ItemID)
 				delete(sp.trackedSessions, sessionKey)
 				// Start new session for the new item
 				sp.startNewSession(session, currentTime)
@@ -100,11 +155,18 @@ func (sp *SessionProcessor) ProcessActiveSessions() {
 			}
 			// Same item: accumulate only when playing (not paused) and position advanced
 			advancedSec := 0
+			if session.IsPaused && !tracked.LastPaused {
+				tracked.Observations.PauseCount++
+			}
 			if !session.IsPaused {
 				// Prefer player position delta when available
 				curTicks := msToTicks(session.PositionMs)
 				if curTicks > 0 && tracked.LastPosTicks > 0 {
 					deltaTicks := curTicks - tracked.LastPosTicks
+					wallSec := int64(currentTime.Sub(tracked.LastUpdate).Seconds())
+					if drift := deltaTicks/10_000_000 - wallSec; drift > seekToleranceSec || drift < -seekToleranceSec {
+						tracked.Observations.SeekCount++
+					}
 					if deltaTicks < 0 {
 						deltaTicks = 0
 					}
@@ -117,17 +179,39 @@ func (sp *SessionProcessor) ProcessActiveSessions() {
 						advancedSec = 0
 					}
 				}
+				observeSession(&tracked.Observations, session)
+			}
+			recordTrackChanges(sp.DB, tracked.SessionFK, &tracked.Tracks, sessionTracks(session),
+				msToTicks(session.PositionMs), currentTime)
+			sp.reconcile(tracked, currentTime)
+			if tracked.Suppressed {
+				countSuppressed(tracked.ServerID, advancedSec)
+			} else {
+				tracked.AccumulatedSec += advancedSec
 			}
-			tracked.AccumulatedSec += advancedSec
 			tracked.LastUpdate = currentTime
 			tracked.LastPosTicks = msToTicks(session.PositionMs)
+			if session.DurationMs > 0 {
+				tracked.DurationMs = session.DurationMs
+			}
+			if session.IsPaused != tracked.LastPaused {
+				event := hooks.PlaybackResumed
+				if session.IsPaused {
+					event = hooks.PlaybackPaused
+				}
+				hooks.Emit(event, tracked.playback())
+			}
 			tracked.LastPaused = session.IsPaused
+			// Servers often report the transcode resolution a few polls into playback
+			if !tracked.TranscodeSizeKnown && (session.TranscodeWidth > 0 || session.TranscodeHeight > 0) {
+				sp.recordTranscodeSize(tracked, session)
+			}
 
 			// Persist: end_ts reflects last seen; duration_seconds is accumulated active seconds
 			sp.updateSessionDuration(tracked, currentTime)
 		} else {
 			// New session - add to tracked list and create database entry
-			log.Printf("[session-processor] New session detected: %s (server:%s user:%s item:%s)", sessionKey, session.ServerID, session.UserID, session.ItemName)
+			spLog.Info("New session detected", "session", sessionKey, "server", session.ServerID, "user", session.UserID, "item", session.ItemName)
 			sp.startNewSession(session, currentTime)
 		}
 	}
@@ -135,20 +219,48 @@ func (sp *SessionProcessor) ProcessActiveSessions() {
 	// Step C: Find What's Missing (The Crucial Part)
 	for sessionKey, tracked := range sp.trackedSessions {
 		if !activeSessionMap[sessionKey] {
+			if skipped[tracked.ServerID] {
+				continue
+			}
+			endTime := currentTime
+			if unreachable[tracked.ServerID] {
+				// A failed poll says nothing about the session; only give up after a long outage,
+				// ending it when it was last seen.
+				if currentTime.Sub(tracked.LastUpdate) < unreachableServerGrace {
+					continue
+				}
+				endTime = tracked.LastUpdate
+			}
 			// Session has stopped - perform final update and remove from tracked list
-			log.Printf("[session-processor] Session stopped: %s (user: %s)", sessionKey, tracked.UserID)
-			sp.finalizeSession(tracked, currentTime)
+			spLog.Info("Session stopped", "session", sessionKey, "user", tracked.UserID)
+			sp.finalizeSession(tracked, endTime)
+			releasePlayback(sourcePolling, tracked.ServerID, tracked.SessionID, tracked.ItemID)
 			delete(sp.trackedSessions, sessionKey)
 		}
 	}
 }
 
+// reconcile re-checks who records a tracked playback's watch time. Taking over from the
+// event path starts a new interval, so time it already recorded isn't counted.
+func (sp *SessionProcessor) reconcile(tracked *TrackedSession, now time.Time) {
+	owns := claimPlayback(sourcePolling, tracked.ServerID, tracked.SessionID, tracked.ItemID, now)
+	switch {
+	case tracked.Suppressed && owns:
+		tracked.Suppressed = false
+		tracked.StartTime = now
+		tracked.AccumulatedSec = 0
+		tracked.CurrentIntervalID = 0
+	case !tracked.Suppressed && !owns:
+		tracked.Suppressed = true
+	}
+}
+
 // startNewSession creates a new session in the database and adds it to tracked sessions
 func (sp *SessionProcessor) startNewSession(session media.Session, startTime time.Time) {
 	// Create play_session record
 	sessionFK, err := sp.createPlaySession(session, startTime)
 	if err != nil {
-		log.Printf("[session-processor] Failed to create play session: %v", err)
+		spLog.Error("Failed to create play session", "error", err)
 		return
 	}
 
@@ -161,15 +273,29 @@ func (sp *SessionProcessor) startNewSession(session media.Session, startTime tim
 		ServerType:        session.ServerType,
 		UserID:            session.UserID,
 		ItemID:            session.ItemID,
+		ItemType:          session.ItemType,
 		StartTime:         startTime,
 		LastUpdate:        startTime,
 		LastPosTicks:      msToTicks(session.PositionMs),
+		DurationMs:        session.DurationMs,
 		AccumulatedSec:    0,
 		LastPaused:        session.IsPaused,
 		CurrentIntervalID: 0,
+
+		TranscodeSizeKnown: session.TranscodeWidth > 0 || session.TranscodeHeight > 0,
+		Tracks:             sessionTracks(session),
+	}
+	if !session.IsPaused {
+		observeSession(&sp.trackedSessions[key].Observations, session)
+	}
+	if !claimPlayback(sourcePolling, session.ServerID, session.SessionID, session.ItemID, startTime) {
+		// Already recorded from Emby playback events: share the row, not the watch time
+		sp.trackedSessions[key].Suppressed = true
+		countMerged(session.ServerID)
 	}
 
-	log.Printf("[session-processor] Started tracking session %s (FK: %d)", session.SessionID, sessionFK)
+	spLog.Debug("Started tracking session", "session", session.SessionID, "session_fk", sessionFK)
+	hooks.Emit(hooks.SessionStarted, hooks.SessionStart{SessionFK: sessionFK, StartedAt: startTime.Unix(), Session: session})
 
 	// Write-through enrichment: ensure library_item has basic metadata for this item
 	go sp.enrichLibraryItem(session)
@@ -186,7 +312,7 @@ func (sp *SessionProcessor) updateSessionDuration(tracked *TrackedSession, curre
     `, currentTime.Unix(), tracked.SessionFK)
 
 	if err != nil {
-		log.Printf("[session-processor] Failed to update session duration: %v", err)
+		spLog.Error("Failed to update session duration", "error", err)
 		return
 	}
 
@@ -194,6 +320,22 @@ func (sp *SessionProcessor) updateSessionDuration(tracked *TrackedSession, curre
 	sp.createOrUpdateInterval(tracked, currentTime, duration)
 }
 
+// recordTranscodeSize stores the transcode output resolution (and bitrate, when reported)
+// once it is known
+func (sp *SessionProcessor) recordTranscodeSize(tracked *TrackedSession, session media.Session) {
+	_, err := dbutil.ExecWithRetry(sp.DB, `
+		UPDATE play_sessions
+		SET transcode_width = NULLIF(?, 0), transcode_height = NULLIF(?, 0),
+		    transcode_bitrate = COALESCE(NULLIF(?, 0), transcode_bitrate)
+		WHERE id = ?
+	`, session.TranscodeWidth, session.TranscodeHeight, session.TranscodeBitrate, tracked.SessionFK)
+	if err != nil {
+		spLog.Error("Failed to record transcode resolution", "error", err)
+		return
+	}
+	tracked.TranscodeSizeKnown = true
+}
+
 // finalizeSession performs final database updates when a session ends
 func (sp *SessionProcessor) finalizeSession(tracked *TrackedSession, endTime time.Time) {
 	duration := tracked.AccumulatedSec
@@ -206,20 +348,58 @@ func (sp *SessionProcessor) finalizeSession(tracked *TrackedSession, endTime tim
 	`, endTime.Unix(), tracked.SessionFK)
 
 	if err != nil {
-		log.Printf("[session-processor] Failed to finalize session: %v", err)
+		spLog.Error("Failed to finalize session", "error", err)
 		return
 	}
 
 	// Create final play interval
 	sp.createOrUpdateInterval(tracked, endTime, duration)
+	if tracked.CurrentIntervalID != 0 {
+		hooks.Emit(hooks.IntervalClosed, hooks.Interval{
+			IntervalID:      tracked.CurrentIntervalID,
+			SessionFK:       tracked.SessionFK,
+			ServerID:        tracked.ServerID,
+			SessionID:       tracked.SessionID,
+			UserID:          tracked.UserID,
+			ItemID:          tracked.ItemID,
+			StartTS:         tracked.StartTime.Unix(),
+			EndTS:           endTime.Unix(),
+			DurationSeconds: duration,
+		})
+	}
+
+	hooks.Emit(hooks.PlaybackStopped, tracked.playback())
+
+	summary, err := recordSessionSummary(sp.DB, tracked.SessionFK, tracked.Observations, endTime)
+	if err != nil {
+		spLog.Error("Failed to record session summary", "error", err)
+	} else {
+		notifySessionEnded(sp.DB, summary)
+		hooks.Emit(hooks.SessionFinalized, summary)
+	}
+
+	spLog.Debug("Finalized session", "session", tracked.SessionID, "duration_seconds", duration)
+}
 
-	log.Printf("[session-processor] Finalized session %s (total duration: %d seconds)", tracked.SessionID, duration)
+// playback is the tracked playback's state for playback hooks.
+func (t *TrackedSession) playback() hooks.Playback {
+	return hooks.Playback{
+		SessionFK:  t.SessionFK,
+		ServerID:   t.ServerID,
+		ServerType: t.ServerType,
+		SessionID:  t.SessionID,
+		UserID:     t.UserID,
+		ItemID:     t.ItemID,
+		ItemType:   t.ItemType,
+		PositionMs: t.LastPosTicks / 10_000,
+		DurationMs: t.DurationMs,
+	}
 }
 
 // createOrUpdateInterval creates or updates a play interval
 func (sp *SessionProcessor) createOrUpdateInterval(tracked *TrackedSession, endTime time.Time, duration int) {
-	if duration < 1 {
-		return // Skip very short intervals
+	if duration < 1 || tracked.Suppressed {
+		return // Skip very short intervals and playbacks recorded from events
 	}
 
 	// Maintain multiple intervals per session (one per contiguous active segment):
@@ -232,7 +412,7 @@ func (sp *SessionProcessor) createOrUpdateInterval(tracked *TrackedSession, endT
             WHERE id = ?
         `, endTime.Unix(), duration, tracked.CurrentIntervalID)
 		if uerr != nil {
-			log.Printf("[session-processor] Failed to update interval: %v", uerr)
+			spLog.Error("Failed to update interval", "error", uerr)
 		}
 		return
 	}
@@ -245,7 +425,7 @@ func (sp *SessionProcessor) createOrUpdateInterval(tracked *TrackedSession, endT
         WHERE id = ?
     `, tracked.StartTime.Unix(), endTime.Unix(), duration, tracked.SessionFK)
 	if ierr != nil {
-		log.Printf("[session-processor] Failed to insert interval: %v", ierr)
+		spLog.Error("Failed to insert interval", "error", ierr)
 		return
 	}
 	newID, _ := res.LastInsertId()
@@ -269,6 +449,7 @@ func (sp *SessionProcessor) createPlaySession(session media.Session, startTime t
 		videoTo := strings.ToUpper(session.TranscodeVideoCodec)
 		audioFrom := strings.ToUpper(session.AudioCodec)
 		audioTo := strings.ToUpper(session.TranscodeAudioCodec)
+		audioLang, audioDefault, subtitleLang := initialTrackColumns(session)
 		_, _ = dbutil.ExecWithRetry(sp.DB, `
             UPDATE play_sessions 
             SET is_active = true, ended_at = NULL,
@@ -279,10 +460,21 @@ func (sp *SessionProcessor) createPlaySession(session media.Session, startTime t
                 video_codec_from = COALESCE(NULLIF(?, ''), video_codec_from),
                 video_codec_to   = COALESCE(NULLIF(?, ''), video_codec_to),
                 audio_codec_from = COALESCE(NULLIF(?, ''), audio_codec_from),
-                audio_codec_to   = COALESCE(NULLIF(?, ''), audio_codec_to)
+                audio_codec_to   = COALESCE(NULLIF(?, ''), audio_codec_to),
+                syncplay_group_id = COALESCE(NULLIF(?, ''), syncplay_group_id),
+                transcode_width  = COALESCE(NULLIF(?, 0), transcode_width),
+                transcode_height = COALESCE(NULLIF(?, 0), transcode_height),
+                transcode_bitrate = COALESCE(NULLIF(?, 0), transcode_bitrate),
+                client_version = COALESCE(NULLIF(?, ''), client_version),
+                platform = COALESCE(NULLIF(?, ''), platform),
+                audio_language = COALESCE(audio_language, ?),
+                audio_default = COALESCE(audio_default, ?),
+                subtitle_language = COALESCE(subtitle_language, ?)
             WHERE id = ?
 		`, session.PlayMethod, transcodeReasons, session.VideoMethod, session.AudioMethod,
-			videoFrom, videoTo, audioFrom, audioTo, existingID)
+			videoFrom, videoTo, audioFrom, audioTo, session.SyncPlayGroupID,
+			session.TranscodeWidth, session.TranscodeHeight, session.TranscodeBitrate,
+			session.ClientVersion, session.Platform, audioLang, audioDefault, subtitleLang, existingID)
 		return existingID, nil
 	}
 	if err != nil && err != sql.ErrNoRows {
@@ -294,18 +486,23 @@ func (sp *SessionProcessor) createPlaySession(session media.Session, startTime t
 	videoTo := strings.ToUpper(session.TranscodeVideoCodec)
 	audioFrom := strings.ToUpper(session.AudioCodec)
 	audioTo := strings.ToUpper(session.TranscodeAudioCodec)
+	audioLang, audioDefault, subtitleLang := initialTrackColumns(session)
 	res, ierr := dbutil.ExecWithRetry(sp.DB, `
         INSERT INTO play_sessions
         (user_id, user_name, session_id, device_id, client_name, item_id, item_name, item_type,
          play_method, started_at, is_active, transcode_reasons, remote_address,
          video_method, audio_method, video_codec_from, video_codec_to,
-         audio_codec_from, audio_codec_to, server_id, server_type)
-        VALUES(?,?,?,?,?,?,?,?,?, ?,true,?,?,?,?, ?, ?, ?, ?, ?, ?)
+         audio_codec_from, audio_codec_to, server_id, server_type, syncplay_group_id,
+         transcode_width, transcode_height, transcode_bitrate, client_version, platform,
+         audio_language, audio_default, subtitle_language)
+        VALUES(?,?,?,?,?,?,?,?,?, ?,true,?,?,?,?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?)
     `, session.UserID, session.UserName, session.SessionID, session.DeviceName, session.ClientApp,
 		session.ItemID, session.ItemName, session.ItemType, session.PlayMethod,
 		startTime.Unix(), transcodeReasons, session.RemoteAddress,
 		session.VideoMethod, session.AudioMethod, videoFrom, videoTo, audioFrom, audioTo,
-		session.ServerID, string(session.ServerType))
+		session.ServerID, string(session.ServerType), session.SyncPlayGroupID,
+		session.TranscodeWidth, session.TranscodeHeight, session.TranscodeBitrate,
+		session.ClientVersion, session.Platform, audioLang, audioDefault, subtitleLang)
 
 	if ierr != nil {
 		return 0, ierr
@@ -314,6 +511,20 @@ func (sp *SessionProcessor) createPlaySession(session media.Session, startTime t
 	return res.LastInsertId()
 }
 
+// observeSession samples the bitrate and the resolution actually served (the transcode
+// output when transcoding, the source otherwise) of a playing session.
+func observeSession(o *sessionObservations, session media.Session) {
+	bitrate := session.Bitrate
+	if bitrate <= 0 {
+		bitrate = session.TranscodeBitrate
+	}
+	width, height := session.Width, session.Height
+	if session.TranscodeWidth > 0 || session.TranscodeHeight > 0 {
+		width, height = session.TranscodeWidth, session.TranscodeHeight
+	}
+	o.observe(bitrate, width, height)
+}
+
 // msToTicks converts milliseconds to 100-nanosecond ticks
 func msToTicks(ms int64) int64 {
 	if ms <= 0 {
@@ -0,0 +1,205 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	dbutil "emby-analytics/internal/db"
+	"emby-analytics/internal/notify"
+	"emby-analytics/internal/queries"
+)
+
+// seekToleranceSec is how far the reported position may drift from wall-clock progress
+// between two polls before the jump is counted as a seek.
+const seekToleranceSec = 60
+
+// sessionEndedNotifications gates the session_ended notification; summaries are always stored.
+var sessionEndedNotifications atomic.Bool
+
+// SetSessionEndedNotifications enables or disables session_ended notifications.
+func SetSessionEndedNotifications(enabled bool) {
+	sessionEndedNotifications.Store(enabled)
+}
+
+// sessionObservations are the per-poll figures the session processor collects for the summary.
+type sessionObservations struct {
+	PauseCount     int
+	SeekCount      int
+	BitrateSum     int64
+	BitrateSamples int
+	MaxWidth       int
+	MaxHeight      int
+}
+
+// observe records one poll of a playing (unpaused) session.
+func (o *sessionObservations) observe(bitrate int64, width, height int) {
+	if bitrate > 0 {
+		o.BitrateSum += bitrate
+		o.BitrateSamples++
+	}
+	if width > o.MaxWidth || (width == o.MaxWidth && height > o.MaxHeight) {
+		o.MaxWidth, o.MaxHeight = width, height
+	}
+}
+
+// SessionSummary is the one-row record of a finalized session.
+type SessionSummary struct {
+	SessionFK     int64  `json:"session_fk"`
+	ServerID      string `json:"server_id"`
+	SessionID     string `json:"session_id"`
+	UserID        string `json:"user_id"`
+	UserName      string `json:"user_name"`
+	ItemID        string `json:"item_id"`
+	ItemName      string `json:"item_name"`
+	ItemType      string `json:"item_type"`
+	Device        string `json:"device"`
+	Client        string `json:"client"`
+	PlayMethod    string `json:"play_method"`
+	StartedAt     int64  `json:"started_at"`
+	EndedAt       int64  `json:"ended_at"`
+	ActiveSeconds int64  `json:"active_seconds"`
+	PauseCount    int    `json:"pause_count"`
+	SeekCount     int    `json:"seek_count"`
+	AvgBitrateBps *int64 `json:"avg_bitrate_bps,omitempty"`
+	MaxWidth      *int   `json:"max_width,omitempty"`
+	MaxHeight     *int   `json:"max_height,omitempty"`
+	MaxResolution string `json:"max_resolution,omitempty"`
+}
+
+// recordSessionSummary upserts the summary row of a finalized session and returns the
+// stored record. Active seconds are recomputed from play_intervals; the observed
+// counters add to those of earlier finalizations of the same row.
+func recordSessionSummary(db *sql.DB, sessionFK int64, obs sessionObservations, endTime time.Time) (SessionSummary, error) {
+	_, err := dbutil.ExecWithRetry(db, `
+		INSERT INTO session_summaries
+			(session_fk, active_seconds, pause_count, seek_count, bitrate_sum, bitrate_samples, avg_bitrate_bps, max_width, max_height, finalized_at)
+		VALUES (?,
+			(SELECT COALESCE(SUM(duration_seconds), 0) FROM play_intervals WHERE session_fk = ?),
+			?, ?, ?, ?, CASE WHEN ? > 0 THEN ? / ? END, NULLIF(?, 0), NULLIF(?, 0), ?)
+		ON CONFLICT(session_fk) DO UPDATE SET
+			active_seconds = excluded.active_seconds,
+			pause_count = session_summaries.pause_count + excluded.pause_count,
+			seek_count = session_summaries.seek_count + excluded.seek_count,
+			bitrate_sum = session_summaries.bitrate_sum + excluded.bitrate_sum,
+			bitrate_samples = session_summaries.bitrate_samples + excluded.bitrate_samples,
+			avg_bitrate_bps = CASE WHEN session_summaries.bitrate_samples + excluded.bitrate_samples > 0
+				THEN (session_summaries.bitrate_sum + excluded.bitrate_sum) / (session_summaries.bitrate_samples + excluded.bitrate_samples) END,
+			max_width = MAX(COALESCE(session_summaries.max_width, 0), COALESCE(excluded.max_width, 0)),
+			max_height = MAX(COALESCE(session_summaries.max_height, 0), COALESCE(excluded.max_height, 0)),
+			finalized_at = excluded.finalized_at
+	`, sessionFK, sessionFK, obs.PauseCount, obs.SeekCount, obs.BitrateSum, obs.BitrateSamples,
+		obs.BitrateSamples, obs.BitrateSum, obs.BitrateSamples, obs.MaxWidth, obs.MaxHeight, endTime.Unix())
+	if err != nil {
+		return SessionSummary{}, err
+	}
+	return GetSessionSummary(db, sessionFK)
+}
+
+// GetSessionSummary loads the stored summary of a session joined with its session details.
+func GetSessionSummary(db *sql.DB, sessionFK int64) (SessionSummary, error) {
+	var s SessionSummary
+	var avgBitrate sql.NullInt64
+	var maxWidth, maxHeight sql.NullInt64
+	var endedAt sql.NullInt64
+	err := db.QueryRow(`
+		SELECT ss.session_fk, COALESCE(ps.server_id, ''), ps.session_id, ps.user_id,
+		       COALESCE(ps.user_name, ''), ps.item_id, COALESCE(ps.item_name, ''), COALESCE(ps.item_type, ''),
+		       COALESCE(ps.device_id, ''), COALESCE(ps.client_name, ''), COALESCE(ps.play_method, ''),
+		       ps.started_at, ps.ended_at, ss.active_seconds, ss.pause_count, ss.seek_count,
+		       ss.avg_bitrate_bps, NULLIF(ss.max_width, 0), NULLIF(ss.max_height, 0)
+		FROM session_summaries ss
+		JOIN play_sessions ps ON ps.id = ss.session_fk
+		WHERE ss.session_fk = ?
+	`, sessionFK).Scan(&s.SessionFK, &s.ServerID, &s.SessionID, &s.UserID,
+		&s.UserName, &s.ItemID, &s.ItemName, &s.ItemType,
+		&s.Device, &s.Client, &s.PlayMethod,
+		&s.StartedAt, &endedAt, &s.ActiveSeconds, &s.PauseCount, &s.SeekCount,
+		&avgBitrate, &maxWidth, &maxHeight)
+	if err != nil {
+		return s, err
+	}
+	s.EndedAt = endedAt.Int64
+	if avgBitrate.Valid {
+		v := avgBitrate.Int64
+		s.AvgBitrateBps = &v
+	}
+	if maxWidth.Valid {
+		v := int(maxWidth.Int64)
+		s.MaxWidth = &v
+	}
+	if maxHeight.Valid {
+		v := int(maxHeight.Int64)
+		s.MaxHeight = &v
+	}
+	s.MaxResolution = servedResolutionLabel(s.MaxWidth, s.MaxHeight)
+	return s, nil
+}
+
+// servedResolutionLabel buckets by width like the quality stats, falling back to height.
+func servedResolutionLabel(width, height *int) string {
+	if width != nil && *width > 0 {
+		switch w := *width; {
+		case w > 3840:
+			return "8K"
+		case w > 1920:
+			return "4K"
+		case w > 1280:
+			return "1080p"
+		case w >= 1200:
+			return "720p"
+		default:
+			return "SD"
+		}
+	}
+	if height != nil && *height > 0 {
+		switch h := *height; {
+		case h >= 4320:
+			return "8K"
+		case h >= 2160:
+			return "4K"
+		case h >= 1080:
+			return "1080p"
+		case h >= 720:
+			return "720p"
+		default:
+			return "SD"
+		}
+	}
+	return ""
+}
+
+// notifySessionEnded sends the session_ended notification with the full summary attached.
+func notifySessionEnded(db *sql.DB, s SessionSummary) {
+	if !sessionEndedNotifications.Load() {
+		return
+	}
+	fields := map[string]string{
+		"user":           s.UserName,
+		"item":           s.ItemName,
+		"item_id":        s.ItemID,
+		"server_id":      s.ServerID,
+		"active_seconds": strconv.FormatInt(s.ActiveSeconds, 10),
+		"pauses":         strconv.Itoa(s.PauseCount),
+		"seeks":          strconv.Itoa(s.SeekCount),
+	}
+	if s.AvgBitrateBps != nil {
+		fields["avg_mbps"] = fmt.Sprintf("%.1f", float64(*s.AvgBitrateBps)/1_000_000)
+	}
+	if s.MaxResolution != "" {
+		fields["max_resolution"] = s.MaxResolution
+	}
+	if id, err := queries.PublicID(context.Background(), db, queries.PublicKindSession, strconv.FormatInt(s.SessionFK, 10)); err == nil {
+		fields["link"] = notify.Link(id)
+	}
+	notify.Send(notify.Event{
+		Kind:    "session_ended",
+		Title:   "Session ended: " + s.ItemName,
+		Message: fmt.Sprintf("%s finished %s after %s of playback", s.UserName, s.ItemName, (time.Duration(s.ActiveSeconds) * time.Second).String()),
+		Fields:  fields,
+		Data:    s,
+	})
+}
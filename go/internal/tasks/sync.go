@@ -179,6 +179,11 @@ func syncServer(db *sql.DB, client media.MediaServerClient, sc media.ServerConfi
 		return insertedEvents, apiCalls, err
 	}
 
+	// Servers that report history entries (Plex) also get sessions/intervals
+	// for plays that predate live tracking, so stats cover their history too
+	historyCutoff := activityImportCutoff(db, serverID)
+	importedSessions := 0
+
 	for idx, user := range users {
 		if idx%cancelCheckInterval == 0 && checkCancelled() {
 			CancelServerSyncProgress(serverID, "Sync cancelled by user")
@@ -218,8 +223,14 @@ func syncServer(db *sql.DB, client media.MediaServerClient, sc media.ServerConfi
 			if insertPlayEventWithTimestamp(db, storedUserID, storedItemID, posMs, eventTime) {
 				insertedEvents++
 			}
+			if h.HistoryID != "" && importHistorySession(db, serverID, serverType, h, historyCutoff) {
+				importedSessions++
+			}
 		}
 	}
+	if importedSessions > 0 {
+		logging.Info("Imported sessions from server history", "server", serverName, "server_id", serverID, "sessions", importedSessions)
+	}
 
 	if !isInitialized {
 		if err := setSettingValue(db, syncInitializedKey(serverID), "true"); err != nil {
@@ -12,6 +12,8 @@ type ServerSyncProgress struct {
 	ServerName string    `json:"server_name,omitempty"`
 	Total      int       `json:"total"`
 	Processed  int       `json:"processed"`
+	Changed    int       `json:"changed"`   // new or changed items written
+	Unchanged  int       `json:"unchanged"` // items skipped because their content hash matched
 	Stage      string    `json:"stage,omitempty"`
 	Running    bool      `json:"running"`
 	Done       bool      `json:"done"`
@@ -93,6 +95,20 @@ func IncrementServerSyncProcessed(serverID string, delta int) {
 	syncProgress[serverID] = p
 }
 
+// AddServerSyncChanges adds to the changed and unchanged item counts.
+func AddServerSyncChanges(serverID string, changed, unchanged int) {
+	syncProgressMu.Lock()
+	defer syncProgressMu.Unlock()
+	p, ok := syncProgress[serverID]
+	if !ok {
+		return
+	}
+	p.Changed += changed
+	p.Unchanged += unchanged
+	p.UpdatedAt = time.Now()
+	syncProgress[serverID] = p
+}
+
 // SetServerSyncStage updates the descriptive stage text.
 func SetServerSyncStage(serverID, stage string) {
 	if stage == "" {
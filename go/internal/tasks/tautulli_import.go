@@ -0,0 +1,415 @@
+package tasks
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	dbutil "emby-analytics/internal/db"
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/media"
+)
+
+const (
+	// tautulliSessionPrefix marks play_sessions rows imported from a Tautulli database
+	tautulliSessionPrefix = "tautulli-"
+	// importBatchSize is how many plays the history importers write per transaction, so
+	// live tracking isn't locked out of the database for the whole import
+	importBatchSize = 500
+)
+
+// TautulliImportResult summarizes a Tautulli import.
+type TautulliImportResult struct {
+	ServerID string `json:"server_id"`
+	DryRun   bool   `json:"dry_run"`
+	Rows     int    `json:"history_rows"` // movie and episode rows of session_history
+	Plays    int    `json:"plays"`        // rows grouped into plays by reference_id
+	Imported int    `json:"sessions_imported"`
+	// Existing counts plays imported by an earlier run, Tracked those after live tracking
+	// began and Short those under a minute long
+	Existing int `json:"sessions_existing"`
+	Tracked  int `json:"sessions_skipped_tracked"`
+	Short    int `json:"sessions_skipped_short"`
+	// ReplacedHistory counts sessions synthesized from Plex's own watch history that an
+	// imported play supersedes
+	ReplacedHistory int `json:"history_sessions_replaced"`
+	UsersByID       int `json:"users_matched_by_id"`
+	UsersByName     int `json:"users_matched_by_name"`
+	// UsersUnmatched lists Tautulli users not found on the server; their plays are kept
+	// under their Plex account id
+	UsersUnmatched []string `json:"users_unmatched"`
+	ItemsByKey     int      `json:"items_matched_by_rating_key"`
+	ItemsByTitle   int      `json:"items_matched_by_title"`
+	// ItemsUnmatched counts items no longer in the library; they get a library_item row
+	// from the Tautulli metadata so their plays keep a title
+	ItemsUnmatched int `json:"items_unmatched"`
+}
+
+// tautulliRow is one session_history row with its metadata.
+type tautulliRow struct {
+	id, reference             int64
+	started, stopped, paused  int64
+	userID                    string
+	user, friendly, username  string
+	ratingKey, mediaType      string
+	viewOffsetMs              int64
+	player, product, platform string
+	ip                        string
+	title, series             string
+	season, episode           int
+	year                      int
+	durationMs                int64
+	decision                  string
+}
+
+// tautulliPlay is the rows of one play, in start order.
+type tautulliPlay struct {
+	rows []tautulliRow
+}
+
+func (p *tautulliPlay) sessionID() string {
+	return fmt.Sprintf("%s%d", tautulliSessionPrefix, p.rows[0].reference)
+}
+
+// OpenTautulliDB opens a Tautulli database read-only and checks that it has the history
+// tables.
+func OpenTautulliDB(path string) (*sql.DB, error) {
+	src, err := sql.Open("sqlite", "file:"+path+"?mode=ro&immutable=1")
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	err = src.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name IN ('session_history', 'session_history_metadata', 'users')
+	`).Scan(&n)
+	if err != nil || n < 3 {
+		src.Close()
+		if err == nil {
+			err = fmt.Errorf("not a Tautulli database: session_history, session_history_metadata or users table missing")
+		}
+		return nil, err
+	}
+	return src, nil
+}
+
+// ImportTautulli converts the movie and episode plays of a Tautulli database into
+// play_sessions and play_intervals of a Plex server. Rows sharing a reference_id (a play
+// resumed after a pause or stop) become one session with an interval per row, counting
+// the time between start and stop minus the recorded pause time.
+//
+// Tautulli's Plex account ids are matched against the server's users, falling back to the
+// user name (the server owner has a different id locally). Rating keys are matched against
+// library_item, falling back to the title and year of movies or the series and title of
+// episodes, so plays of re-added items count for the current item. Plays recorded after
+// live tracking of the server began are skipped, and sessions synthesized from Plex's own
+// watch history for the same user and item within six hours of an imported play are
+// replaced. Re-running the import skips plays it already created. With dryRun nothing is
+// written.
+func ImportTautulli(db, src *sql.DB, serverID string, dryRun bool) (*TautulliImportResult, error) {
+	res := &TautulliImportResult{ServerID: serverID, DryRun: dryRun, UsersUnmatched: []string{}}
+	cutoff := activityImportCutoff(db, serverID).Unix()
+
+	plays, order, err := readTautulliPlays(src, res)
+	if err != nil {
+		return res, err
+	}
+	res.Plays = len(order)
+
+	// Only plays that will be imported need their users and items matched
+	var todo []*tautulliPlay
+	for _, ref := range order {
+		p := plays[ref]
+		if p.rows[0].started >= cutoff {
+			res.Tracked++
+			continue
+		}
+		active := int64(0)
+		for _, r := range p.rows {
+			active += tautulliActiveSeconds(r)
+		}
+		if time.Duration(active)*time.Second < activityLogMinSession {
+			res.Short++
+			continue
+		}
+		if importedSessionExists(db, serverID, p.sessionID()) {
+			res.Existing++
+			continue
+		}
+		todo = append(todo, p)
+	}
+
+	users, err := tautulliUserMap(db, serverID, todo, res)
+	if err != nil {
+		return res, err
+	}
+	items := tautulliItemMap(db, serverID, todo, res)
+
+	var tx *sql.Tx
+	pending := 0
+	flush := func() error {
+		if tx == nil {
+			return nil
+		}
+		err := tx.Commit()
+		tx, pending = nil, 0
+		return err
+	}
+	for _, p := range todo {
+		first, last := p.rows[0], p.rows[len(p.rows)-1]
+		userID, itemID := users[first.userID], items[first.ratingKey]
+		if dryRun {
+			res.Imported++
+			continue
+		}
+
+		if tx == nil {
+			if tx, err = db.Begin(); err != nil {
+				return res, err
+			}
+		}
+		if err := insertTautulliPlay(tx, serverID, userID, itemID, p); err != nil {
+			tx.Rollback()
+			return res, err
+		}
+		r, err := tx.Exec(`
+			DELETE FROM play_sessions
+			WHERE server_id = ? AND session_id LIKE ? AND user_id = ? AND item_id = ?
+			  AND ended_at BETWEEN ? AND ?`,
+			serverID, historySessionPrefix+"%", userID, itemID,
+			first.started, last.stopped+int64(activityLogMaxSession.Seconds()))
+		if err != nil {
+			tx.Rollback()
+			return res, err
+		}
+		n, _ := r.RowsAffected()
+		res.ReplacedHistory += int(n)
+		res.Imported++
+		if pending++; pending >= importBatchSize {
+			if err := flush(); err != nil {
+				return res, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return res, err
+	}
+
+	logging.Info("Tautulli import finished", "server_id", serverID, "dry_run", dryRun, "plays", res.Plays,
+		"imported", res.Imported, "existing", res.Existing, "replaced_history", res.ReplacedHistory)
+	return res, nil
+}
+
+func readTautulliPlays(src *sql.DB, res *TautulliImportResult) (map[int64]*tautulliPlay, []int64, error) {
+	rows, err := src.Query(`
+		SELECT sh.id, COALESCE(sh.reference_id, sh.id), COALESCE(sh.started, 0), COALESCE(sh.stopped, 0),
+		       COALESCE(sh.paused_counter, 0), CAST(sh.user_id AS TEXT), COALESCE(sh.user, ''),
+		       COALESCE(u.friendly_name, ''), COALESCE(u.username, ''),
+		       CAST(sh.rating_key AS TEXT), sh.media_type, COALESCE(sh.view_offset, 0),
+		       COALESCE(sh.player, ''), COALESCE(sh.product, ''), COALESCE(sh.platform, ''),
+		       COALESCE(sh.ip_address, ''),
+		       COALESCE(m.title, ''), COALESCE(m.grandparent_title, ''),
+		       COALESCE(m.parent_media_index, 0), COALESCE(m.media_index, 0), COALESCE(m.year, 0),
+		       COALESCE(m.duration, 0), COALESCE(mi.transcode_decision, '')
+		FROM session_history sh
+		LEFT JOIN session_history_metadata m ON m.id = sh.id
+		LEFT JOIN session_history_media_info mi ON mi.id = sh.id
+		LEFT JOIN users u ON u.user_id = sh.user_id
+		WHERE sh.media_type IN ('movie', 'episode') AND sh.rating_key IS NOT NULL AND sh.user_id IS NOT NULL
+		ORDER BY COALESCE(sh.reference_id, sh.id), sh.started
+	`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read session_history: %w", err)
+	}
+	defer rows.Close()
+
+	plays := map[int64]*tautulliPlay{}
+	var order []int64
+	for rows.Next() {
+		var r tautulliRow
+		if err := rows.Scan(&r.id, &r.reference, &r.started, &r.stopped, &r.paused, &r.userID, &r.user,
+			&r.friendly, &r.username, &r.ratingKey, &r.mediaType, &r.viewOffsetMs,
+			&r.player, &r.product, &r.platform, &r.ip, &r.title, &r.series,
+			&r.season, &r.episode, &r.year, &r.durationMs, &r.decision); err != nil {
+			return nil, nil, fmt.Errorf("read session_history: %w", err)
+		}
+		if r.started <= 0 || r.stopped < r.started {
+			continue
+		}
+		res.Rows++
+		p := plays[r.reference]
+		if p == nil {
+			p = &tautulliPlay{}
+			plays[r.reference] = p
+			order = append(order, r.reference)
+		}
+		p.rows = append(p.rows, r)
+	}
+	return plays, order, rows.Err()
+}
+
+// tautulliUserMap maps Tautulli user ids to the server's remote user ids.
+func tautulliUserMap(db *sql.DB, serverID string, plays []*tautulliPlay, res *TautulliImportResult) (map[string]string, error) {
+	rows, err := db.Query(`SELECT id, COALESCE(name, '') FROM emby_user WHERE server_id = ? AND deleted_at IS NULL`, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	known := map[string]bool{}
+	byName := map[string]string{}
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		id = remoteID(serverID, id)
+		known[id] = true
+		if name != "" {
+			byName[strings.ToLower(name)] = id
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := map[string]string{}
+	for _, p := range plays {
+		r := p.rows[0]
+		if _, done := out[r.userID]; done {
+			continue
+		}
+		if known[r.userID] {
+			out[r.userID] = r.userID
+			res.UsersByID++
+			continue
+		}
+		matched := ""
+		for _, name := range []string{r.username, r.friendly, r.user} {
+			if id, ok := byName[strings.ToLower(strings.TrimSpace(name))]; ok && name != "" {
+				matched = id
+				break
+			}
+		}
+		if matched != "" {
+			out[r.userID] = matched
+			res.UsersByName++
+			continue
+		}
+		out[r.userID] = r.userID
+		name := firstNonEmpty(r.friendly, r.username, r.user, r.userID)
+		res.UsersUnmatched = append(res.UsersUnmatched, name)
+		if !res.DryRun {
+			upsertUserAndItem(db, serverID, media.ServerTypePlex, r.userID, name, "", "", "")
+		}
+	}
+	return out, nil
+}
+
+// tautulliItemMap maps Tautulli rating keys to the server's current item ids.
+func tautulliItemMap(db *sql.DB, serverID string, plays []*tautulliPlay, res *TautulliImportResult) map[string]string {
+	out := map[string]string{}
+	for _, p := range plays {
+		r := p.rows[0]
+		if _, done := out[r.ratingKey]; done {
+			continue
+		}
+		var exists int
+		_ = db.QueryRow(`SELECT COUNT(*) FROM library_item WHERE id = ?`, storageItemID(serverID, r.ratingKey)).Scan(&exists)
+		if exists > 0 {
+			out[r.ratingKey] = r.ratingKey
+			res.ItemsByKey++
+			continue
+		}
+
+		var current string
+		if r.mediaType == "movie" {
+			_ = db.QueryRow(`
+				SELECT item_id FROM library_item
+				WHERE server_id = ? AND media_type = 'Movie' AND LOWER(name) = LOWER(?)
+				  AND (? = 0 OR production_year IS NULL OR production_year = ?)
+				ORDER BY updated_at DESC LIMIT 1`, serverID, r.title, r.year, r.year).Scan(&current)
+		} else {
+			_ = db.QueryRow(`
+				SELECT item_id FROM library_item
+				WHERE server_id = ? AND media_type = 'Episode' AND LOWER(name) = LOWER(?)
+				  AND LOWER(COALESCE(series_name, '')) = LOWER(?)
+				ORDER BY updated_at DESC LIMIT 1`, serverID, r.title, r.series).Scan(&current)
+		}
+		if current != "" {
+			out[r.ratingKey] = current
+			res.ItemsByTitle++
+			continue
+		}
+
+		out[r.ratingKey] = r.ratingKey
+		res.ItemsUnmatched++
+		if !res.DryRun {
+			mediaType := "Movie"
+			if r.mediaType == "episode" {
+				mediaType = "Episode"
+			}
+			_, _ = dbutil.ExecWithRetry(db, `
+				INSERT OR IGNORE INTO library_item
+				(id, server_id, server_type, item_id, name, media_type, series_name, production_year, run_time_ticks, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, 0), CURRENT_TIMESTAMP)`,
+				storageItemID(serverID, r.ratingKey), serverID, string(media.ServerTypePlex), r.ratingKey,
+				r.title, mediaType, r.series, r.year, msToTicks(r.durationMs))
+		}
+	}
+	return out
+}
+
+func insertTautulliPlay(tx *sql.Tx, serverID, userID, itemID string, p *tautulliPlay) error {
+	first, last := p.rows[0], p.rows[len(p.rows)-1]
+	itemName, itemType := first.title, "Movie"
+	if first.mediaType == "episode" {
+		itemType = "Episode"
+		if first.series != "" {
+			itemName = fmt.Sprintf("%s - %s (S%02dE%02d)", first.series, first.title, first.season, first.episode)
+		}
+	}
+	playMethod := "Unknown"
+	switch strings.ToLower(first.decision) {
+	case "transcode":
+		playMethod = "Transcode"
+	case "direct play", "copy":
+		playMethod = "Direct"
+	}
+	s := importedSession{
+		ServerID: serverID, ServerType: string(media.ServerTypePlex), SessionID: p.sessionID(),
+		UserID: userID, UserName: firstNonEmpty(first.friendly, first.user),
+		ItemID: itemID, ItemName: itemName, ItemType: itemType,
+		DeviceID: first.player, ClientName: first.product, Platform: first.platform, RemoteAddress: first.ip,
+		PlayMethod: playMethod, StartedAt: first.started, EndedAt: last.stopped,
+	}
+	for _, row := range p.rows {
+		active := tautulliActiveSeconds(row)
+		if active <= 0 {
+			continue
+		}
+		s.Intervals = append(s.Intervals, importedInterval{
+			Start: row.started, End: row.stopped,
+			StartPosTicks: msToTicks(max(row.viewOffsetMs-active*1000, 0)), EndPosTicks: msToTicks(row.viewOffsetMs),
+			Seconds: active,
+		})
+	}
+	if _, err := insertImportedSession(tx, s); err != nil {
+		return fmt.Errorf("insert play %d: %w", first.reference, err)
+	}
+	return nil
+}
+
+// tautulliActiveSeconds is a row's wall time minus its recorded pauses.
+func tautulliActiveSeconds(r tautulliRow) int64 {
+	return max(r.stopped-r.started-r.paused, 0)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
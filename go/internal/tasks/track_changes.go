@@ -0,0 +1,89 @@
+package tasks
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	dbutil "emby-analytics/internal/db"
+	"emby-analytics/internal/media"
+)
+
+// undeterminedLanguage labels a track without a language tag.
+const undeterminedLanguage = "und"
+
+// trackSelection is the audio and subtitle tracks a session was last seen playing. Track
+// ids are empty when the server didn't report them.
+type trackSelection struct {
+	AudioTrack       string
+	SubtitleTrack    string
+	AudioLanguage    string
+	SubtitleLanguage string
+}
+
+func sessionTracks(s media.Session) trackSelection {
+	t := trackSelection{AudioTrack: s.AudioTrack, SubtitleTrack: s.SubtitleTrack}
+	if s.AudioTrack != "" {
+		t.AudioLanguage = trackLanguage(s.AudioLanguage)
+	}
+	switch s.SubtitleTrack {
+	case "":
+	case media.SubtitlesOff:
+		t.SubtitleLanguage = media.SubtitlesOff
+	default:
+		t.SubtitleLanguage = trackLanguage(s.SubtitleLanguage)
+	}
+	return t
+}
+
+// trackLanguage normalizes a reported language ("English", "eng", "ENG") for comparison.
+func trackLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" {
+		return undeterminedLanguage
+	}
+	return lang
+}
+
+// initialTrackColumns are the audio_language, audio_default and subtitle_language values
+// of a new session row; nil where the server didn't report the track.
+func initialTrackColumns(s media.Session) (audioLang, audioDefault, subtitleLang any) {
+	t := sessionTracks(s)
+	if t.AudioTrack != "" {
+		audioLang = t.AudioLanguage
+		// Plex flags whichever audio stream is selected as the default
+		if s.ServerType != media.ServerTypePlex {
+			audioDefault = boolToInt(s.AudioDefault)
+		}
+	}
+	if t.SubtitleTrack != "" {
+		subtitleLang = t.SubtitleLanguage
+	}
+	return audioLang, audioDefault, subtitleLang
+}
+
+// recordTrackChanges stores the audio and subtitle switches between the tracks last seen
+// for a session and cur, then remembers cur. Tracks the server stops reporting keep their
+// last known value.
+func recordTrackChanges(db *sql.DB, sessionFK int64, last *trackSelection, cur trackSelection, posTicks int64, now time.Time) {
+	record := func(track, from, to string) {
+		_, err := dbutil.ExecWithRetry(db, `
+			INSERT INTO play_track_changes (session_fk, track, from_language, to_language, position_ticks, created_at)
+			VALUES (?, ?, ?, ?, NULLIF(?, 0), ?)`, sessionFK, track, from, to, posTicks, now.Unix())
+		if err != nil {
+			spLog.Error("Failed to record track change", "session_fk", sessionFK, "track", track, "error", err)
+		}
+	}
+	if cur.AudioTrack != "" {
+		if last.AudioTrack != "" && cur.AudioTrack != last.AudioTrack {
+			record("audio", last.AudioLanguage, cur.AudioLanguage)
+		}
+		last.AudioTrack, last.AudioLanguage = cur.AudioTrack, cur.AudioLanguage
+	}
+	if cur.SubtitleTrack != "" {
+		if last.SubtitleTrack != "" && cur.SubtitleTrack != last.SubtitleTrack {
+			record("subtitle", last.SubtitleLanguage, cur.SubtitleLanguage)
+		}
+		last.SubtitleTrack, last.SubtitleLanguage = cur.SubtitleTrack, cur.SubtitleLanguage
+	}
+}
@@ -0,0 +1,70 @@
+package tasks
+
+import (
+	"sync"
+	"time"
+
+	"emby-analytics/internal/logging"
+)
+
+// Readiness states reported by /health.
+const (
+	ReadinessWarmingUp = "warming_up"
+	ReadinessReady     = "ready"
+)
+
+// WarmupStatus tracks the initial syncs that run in the background after startup, so the
+// API can serve requests while data is still being pulled from the media servers.
+type WarmupStatus struct {
+	State     string     `json:"state"` // warming_up|ready
+	Stage     string     `json:"stage,omitempty"`
+	StartedAt time.Time  `json:"started_at"`
+	ReadyAt   *time.Time `json:"ready_at,omitempty"`
+}
+
+var (
+	warmupMu sync.RWMutex
+	warmup   = WarmupStatus{State: ReadinessWarmingUp, StartedAt: time.Now()}
+)
+
+// WarmupJob is one step of the startup warm-up.
+type WarmupJob struct {
+	Stage string
+	Run   func()
+}
+
+// StartWarmup runs the startup jobs in the background and reports ready once they return.
+// Each job is named by the stage shown while it runs.
+func StartWarmup(jobs []WarmupJob) {
+	warmupMu.Lock()
+	warmup = WarmupStatus{State: ReadinessWarmingUp, StartedAt: time.Now()}
+	warmupMu.Unlock()
+
+	go func() {
+		for _, job := range jobs {
+			setWarmupStage(job.Stage)
+			start := time.Now()
+			job.Run()
+			logging.Info("Startup job completed", "stage", job.Stage, "duration", time.Since(start).Round(time.Millisecond))
+		}
+		readyAt := time.Now()
+		warmupMu.Lock()
+		warmup.State, warmup.Stage, warmup.ReadyAt = ReadinessReady, "", &readyAt
+		startedAt := warmup.StartedAt
+		warmupMu.Unlock()
+		logging.Info("Startup warm-up completed", "duration", readyAt.Sub(startedAt).Round(time.Millisecond))
+	}()
+}
+
+func setWarmupStage(stage string) {
+	warmupMu.Lock()
+	defer warmupMu.Unlock()
+	warmup.Stage = stage
+}
+
+// Warmup returns the current warm-up status.
+func Warmup() WarmupStatus {
+	warmupMu.RLock()
+	defer warmupMu.RUnlock()
+	return warmup
+}
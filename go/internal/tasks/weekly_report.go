@@ -0,0 +1,154 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/logging"
+	"emby-analytics/internal/notify"
+	"emby-analytics/internal/queries"
+)
+
+// weeklyReportLastSentKey remembers the week of the last report sent so each goes out once.
+const weeklyReportLastSentKey = "weekly_report_last_sent"
+
+// WeeklyReporter emails the previous week's server report to admins every Monday at a
+// local time. It has no loop of its own: CheckDue runs as a job of the sync scheduler.
+type WeeklyReporter struct {
+	db         *sql.DB
+	smtp       notify.SMTPConfig
+	recipients []string
+	hour       int
+	minute     int
+	location   *time.Location
+	// lastAttempt spaces out retries of a failed delivery; only the scheduler's goroutine
+	// touches it
+	lastAttempt time.Time
+}
+
+// NewWeeklyReporter creates a reporter for the comma-separated recipients.
+func NewWeeklyReporter(db *sql.DB, smtp notify.SMTPConfig, recipients string, hour, minute int, loc *time.Location) *WeeklyReporter {
+	r := &WeeklyReporter{db: db, smtp: smtp, hour: hour, minute: minute, location: loc}
+	for _, to := range strings.Split(recipients, ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			r.recipients = append(r.recipients, to)
+		}
+	}
+	return r
+}
+
+// Enabled reports whether SMTP and at least one recipient are configured.
+func (r *WeeklyReporter) Enabled() bool {
+	return r.smtp.Enabled() && len(r.recipients) > 0
+}
+
+// Recipients returns the configured report addresses.
+func (r *WeeklyReporter) Recipients() []string {
+	return r.recipients
+}
+
+// CheckDue sends last week's report once this week's send time has passed. A failed
+// delivery is retried every ten minutes.
+func (r *WeeklyReporter) CheckDue(now time.Time) {
+	if !r.Enabled() {
+		return
+	}
+	local := now.In(r.location)
+	thisWeek, _, _ := queries.GoalPeriodBounds("week", local)
+	due := time.Date(thisWeek.Year(), thisWeek.Month(), thisWeek.Day(), r.hour, r.minute, 0, 0, r.location)
+	if local.Before(due) {
+		return
+	}
+	start := thisWeek.AddDate(0, 0, -7)
+	week := start.Format("2006-01-02")
+	if last, _ := getSettingValue(r.db, weeklyReportLastSentKey); last == week {
+		return
+	}
+	if now.Sub(r.lastAttempt) < digestCheckInterval {
+		return
+	}
+	r.lastAttempt = now
+	if _, err := r.Send(context.Background(), start, r.recipients); err != nil {
+		logging.Warn("Failed to send weekly report", "week", week, "error", err)
+		return
+	}
+	_ = setSettingValue(r.db, weeklyReportLastSentKey, week)
+	logging.Info("Weekly report sent", "week", week, "recipients", len(r.recipients))
+}
+
+// Send builds the report for the week starting at start and emails it to each address,
+// returning the report. It stops at the first failed delivery.
+func (r *WeeklyReporter) Send(ctx context.Context, start time.Time, to []string) (notify.WeeklyReport, error) {
+	report, err := BuildWeeklyReport(ctx, r.db, start, r.location)
+	if err != nil {
+		return report, err
+	}
+	for _, addr := range to {
+		msg, err := notify.RenderWeeklyReport(addr, report)
+		if err != nil {
+			return report, err
+		}
+		if err := notify.SendEmail(r.smtp, msg); err != nil {
+			return report, fmt.Errorf("%s: %w", addr, err)
+		}
+	}
+	return report, nil
+}
+
+// LastWeekStart is the start of the last complete week as of now, in the report's timezone.
+func (r *WeeklyReporter) LastWeekStart(now time.Time) time.Time {
+	thisWeek, _, _ := queries.GoalPeriodBounds("week", now.In(r.location))
+	return thisWeek.AddDate(0, 0, -7)
+}
+
+// BuildWeeklyReport computes total watch hours, the top five users and items, and how many
+// sessions transcoded in the seven days from start, in loc. Live TV and users excluded from
+// stats are left out.
+func BuildWeeklyReport(ctx context.Context, db *sql.DB, start time.Time, loc *time.Location) (notify.WeeklyReport, error) {
+	start = start.In(loc)
+	end := start.AddDate(0, 0, 7)
+	out := notify.WeeklyReport{
+		WeekStart: start.Format("2006-01-02"),
+		WeekEnd:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Timezone:  loc.String(),
+	}
+
+	users, err := queries.TopUsersByWatchSeconds(ctx, db, start.Unix(), end.Unix()-1, "", "", 10000)
+	if err != nil {
+		return out, fmt.Errorf("watch time: %w", err)
+	}
+	for i, u := range users {
+		out.Hours += u.Hours
+		if i < 5 {
+			out.TopUsers = append(out.TopUsers, notify.DigestItem{Name: u.Name, Hours: u.Hours})
+		}
+	}
+
+	items, err := queries.TopItemsByWatchSeconds(ctx, db, start.Unix(), end.Unix()-1, 5)
+	if err != nil {
+		return out, fmt.Errorf("top items: %w", err)
+	}
+	for _, it := range items {
+		out.TopItems = append(out.TopItems, notify.DigestItem{Name: it.Display, Type: it.Type, Hours: it.Hours})
+	}
+
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+		       COALESCE(SUM(CASE WHEN ps.play_method = 'Transcode' OR ps.video_method = 'Transcode'
+		                              OR ps.audio_method = 'Transcode' THEN 1 ELSE 0 END), 0)
+		FROM play_sessions ps
+		WHERE ps.started_at >= ? AND ps.started_at < ?
+		  AND COALESCE(ps.item_type, '') NOT IN ('TvChannel', 'LiveTv', 'Channel', 'TvProgram')
+		  AND NOT EXISTS (SELECT 1 FROM emby_user u WHERE u.id = ps.user_id AND u.exclude_from_stats = 1)
+	`, start.Unix(), end.Unix()).Scan(&out.Sessions, &out.Transcodes); err != nil {
+		return out, fmt.Errorf("sessions: %w", err)
+	}
+	if out.Sessions > 0 {
+		out.TranscodePercent = math.Round(float64(out.Transcodes)/float64(out.Sessions)*1000) / 10
+	}
+	return out, nil
+}
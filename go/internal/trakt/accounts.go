@@ -0,0 +1,203 @@
+package trakt
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	dbutil "emby-analytics/internal/db"
+	"emby-analytics/internal/logging"
+)
+
+var log = logging.Module("trakt")
+
+// Account is a Trakt account linked to a media user. Tokens are never serialized.
+type Account struct {
+	UserID         string `json:"user_id"`
+	UserName       string `json:"user_name"`
+	TraktUsername  string `json:"trakt_username"`
+	AccessToken    string `json:"-"`
+	RefreshToken   string `json:"-"`
+	ExpiresAt      int64  `json:"expires_at"`
+	LinkedAt       int64  `json:"linked_at"`
+	LastScrobbleAt *int64 `json:"last_scrobble_at,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+}
+
+// Accounts lists the linked accounts by media user name.
+func Accounts(db *sql.DB) ([]Account, error) {
+	rows, err := db.Query(`
+		SELECT t.user_id, COALESCE(u.name, t.user_id), COALESCE(t.trakt_username, ''),
+		       t.expires_at, t.linked_at, t.last_scrobble_at, COALESCE(t.last_error, '')
+		FROM trakt_accounts t
+		LEFT JOIN emby_user u ON u.id = t.user_id
+		ORDER BY LOWER(COALESCE(u.name, t.user_id))
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Account{}
+	for rows.Next() {
+		var a Account
+		var last sql.NullInt64
+		if err := rows.Scan(&a.UserID, &a.UserName, &a.TraktUsername, &a.ExpiresAt, &a.LinkedAt, &last, &a.LastError); err != nil {
+			return nil, err
+		}
+		if last.Valid {
+			a.LastScrobbleAt = &last.Int64
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// account returns the linked account of a media user, or sql.ErrNoRows.
+func account(db *sql.DB, userID string) (Account, error) {
+	a := Account{UserID: userID}
+	err := db.QueryRow(`
+		SELECT COALESCE(trakt_username, ''), access_token, refresh_token, expires_at, linked_at
+		FROM trakt_accounts WHERE user_id = ?
+	`, userID).Scan(&a.TraktUsername, &a.AccessToken, &a.RefreshToken, &a.ExpiresAt, &a.LinkedAt)
+	return a, err
+}
+
+func saveToken(db *sql.DB, userID, traktUsername string, tok Token) error {
+	_, err := dbutil.ExecWithRetry(db, `
+		INSERT INTO trakt_accounts (user_id, trakt_username, access_token, refresh_token, expires_at, linked_at)
+		VALUES (?, NULLIF(?, ''), ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			trakt_username = COALESCE(excluded.trakt_username, trakt_username),
+			access_token = excluded.access_token,
+			refresh_token = excluded.refresh_token,
+			expires_at = excluded.expires_at,
+			last_error = NULL
+	`, userID, traktUsername, tok.AccessToken, tok.RefreshToken, tok.ExpiresAt(), time.Now().Unix())
+	return err
+}
+
+// recordScrobble notes the outcome of a scrobble; a nil err clears the last error.
+func recordScrobble(db *sql.DB, userID string, err error) {
+	var q string
+	var args []any
+	if err == nil {
+		q, args = `UPDATE trakt_accounts SET last_scrobble_at = ?, last_error = NULL WHERE user_id = ?`, []any{time.Now().Unix(), userID}
+	} else {
+		q, args = `UPDATE trakt_accounts SET last_error = ? WHERE user_id = ?`, []any{err.Error(), userID}
+	}
+	if _, dbErr := dbutil.ExecWithRetry(db, q, args...); dbErr != nil {
+		log.Debug("failed to record scrobble outcome", "user", userID, "error", dbErr)
+	}
+}
+
+// Unlink removes a media user's Trakt account, revoking its token when client is non-nil.
+// It reports whether an account was linked.
+func Unlink(db *sql.DB, client *Client, userID string) (bool, error) {
+	a, err := account(db, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if client != nil {
+		if err := client.RevokeToken(a.AccessToken); err != nil {
+			log.Warn("failed to revoke trakt token", "user", userID, "error", err)
+		}
+	}
+	_, err = dbutil.ExecWithRetry(db, `DELETE FROM trakt_accounts WHERE user_id = ?`, userID)
+	return err == nil, err
+}
+
+// PendingLink is a device authorization waiting for the user to enter the code.
+type PendingLink struct {
+	UserID          string `json:"user_id"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"`
+	ExpiresAt       int64  `json:"expires_at"`
+}
+
+var (
+	pendingMu sync.Mutex
+	pending   = map[string]PendingLink{}
+)
+
+// PendingLinks lists the device authorizations still waiting for their user.
+func PendingLinks() []PendingLink {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	out := make([]PendingLink, 0, len(pending))
+	for _, p := range pending {
+		out = append(out, p)
+	}
+	return out
+}
+
+// StartLink requests a device code for a media user and polls for the token in the
+// background; once the user enters the code at the verification URL the account is stored.
+// A new link for the same user replaces a pending one.
+func StartLink(db *sql.DB, client *Client, userID string) (PendingLink, error) {
+	code, err := client.RequestDeviceCode()
+	if err != nil {
+		return PendingLink{}, err
+	}
+	link := PendingLink{
+		UserID:          userID,
+		UserCode:        code.UserCode,
+		VerificationURL: code.VerificationURL,
+		ExpiresAt:       time.Now().Add(time.Duration(code.ExpiresIn) * time.Second).Unix(),
+	}
+	pendingMu.Lock()
+	pending[userID] = link
+	pendingMu.Unlock()
+
+	go pollLink(db, client, link, code)
+	return link, nil
+}
+
+func pollLink(db *sql.DB, client *Client, link PendingLink, code DeviceCode) {
+	// Only the latest link of a user may clear its pending entry
+	defer func() {
+		pendingMu.Lock()
+		if pending[link.UserID] == link {
+			delete(pending, link.UserID)
+		}
+		pendingMu.Unlock()
+	}()
+
+	interval := time.Duration(max(code.Interval, 1)) * time.Second
+	for time.Now().Unix() < link.ExpiresAt {
+		time.Sleep(interval)
+		pendingMu.Lock()
+		current := pending[link.UserID] == link
+		pendingMu.Unlock()
+		if !current {
+			return
+		}
+
+		tok, err := client.PollDeviceToken(code.DeviceCode)
+		switch {
+		case errors.Is(err, ErrAuthorizationPending):
+			continue
+		case errors.Is(err, ErrSlowDown):
+			interval += time.Second
+			continue
+		case err != nil:
+			log.Warn("trakt link failed", "user", link.UserID, "error", err)
+			return
+		}
+
+		username, err := client.Username(tok.AccessToken)
+		if err != nil {
+			log.Debug("failed to look up trakt username", "user", link.UserID, "error", err)
+		}
+		if err := saveToken(db, link.UserID, username, tok); err != nil {
+			log.Error("failed to store trakt account", "user", link.UserID, "error", err)
+			return
+		}
+		log.Info("Linked trakt account", "user", link.UserID, "trakt_user", username)
+		return
+	}
+	log.Info("trakt link expired before the code was entered", "user", link.UserID)
+}
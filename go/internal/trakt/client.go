@@ -0,0 +1,285 @@
+// Package trakt links media users to Trakt.tv accounts with the OAuth device-code flow and
+// scrobbles their playback to Trakt as the session processor tracks it.
+package trakt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/httpx"
+)
+
+const (
+	apiURL = "https://api.trakt.tv"
+	// redirectURI is the out-of-band redirect Trakt expects for device-code apps
+	redirectURI = "urn:ietf:wg:oauth:2.0:oob"
+)
+
+// Scrobble actions
+const (
+	ActionStart = "start"
+	ActionPause = "pause"
+	ActionStop  = "stop"
+)
+
+// Device token polling outcomes other than success
+var (
+	ErrAuthorizationPending = errors.New("trakt: authorization pending")
+	ErrSlowDown             = errors.New("trakt: polling too fast")
+	ErrCodeExpired          = errors.New("trakt: device code expired or already used")
+	ErrAccessDenied         = errors.New("trakt: user denied access")
+)
+
+// ErrUnauthorized is returned when Trakt rejects an access token.
+var ErrUnauthorized = errors.New("trakt: access token rejected")
+
+// Client talks to the Trakt API as one registered application.
+type Client struct {
+	baseURL      string
+	clientID     string
+	clientSecret string
+	http         *httpx.Client
+}
+
+// New creates a client for the application with the given credentials
+func New(clientID, clientSecret string) *Client {
+	return &Client{
+		baseURL:      apiURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		http:         httpx.New(),
+	}
+}
+
+// DeviceCode is a pending device authorization: the user enters UserCode at
+// VerificationURL while the device code is polled for a token.
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"`
+	ExpiresIn       int    `json:"expires_in"` // seconds
+	Interval        int    `json:"interval"`   // seconds between polls
+}
+
+// Token is an OAuth access token.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // seconds from CreatedAt
+	CreatedAt    int64  `json:"created_at"` // unix seconds
+}
+
+// ExpiresAt is when the access token stops working (unix seconds).
+func (t Token) ExpiresAt() int64 {
+	created := t.CreatedAt
+	if created == 0 {
+		created = time.Now().Unix()
+	}
+	return created + t.ExpiresIn
+}
+
+// IDs identifies a movie, show or episode; Trakt matches on any of them.
+type IDs struct {
+	Trakt int    `json:"trakt,omitempty"`
+	IMDB  string `json:"imdb,omitempty"`
+	TMDB  int    `json:"tmdb,omitempty"`
+	TVDB  int    `json:"tvdb,omitempty"`
+}
+
+// IDsFromProviders picks the ids Trakt understands from lower-case provider ids as stored
+// for library items ({"imdb": "tt0111161", "tmdb": "278"}).
+func IDsFromProviders(providers map[string]string) IDs {
+	var ids IDs
+	ids.Trakt, _ = strconv.Atoi(providers["trakt"])
+	if v := providers["imdb"]; strings.HasPrefix(v, "tt") {
+		ids.IMDB = v
+	}
+	ids.TMDB, _ = strconv.Atoi(providers["tmdb"])
+	ids.TVDB, _ = strconv.Atoi(providers["tvdb"])
+	return ids
+}
+
+// Empty reports whether no id is set.
+func (ids IDs) Empty() bool { return ids == IDs{} }
+
+// Episode identifies an episode either by its own ids or by season and number within Show.
+type Episode struct {
+	IDs    *IDs `json:"ids,omitempty"`
+	Season int  `json:"season,omitempty"`
+	Number int  `json:"number,omitempty"`
+}
+
+// Scrobble is the body of a scrobble call: a movie, or an episode with its show when the
+// episode is identified by season and number. Progress is the percentage watched.
+type Scrobble struct {
+	Movie    *Media   `json:"movie,omitempty"`
+	Show     *Media   `json:"show,omitempty"`
+	Episode  *Episode `json:"episode,omitempty"`
+	Progress float64  `json:"progress"`
+}
+
+// Media wraps the ids of a movie or show.
+type Media struct {
+	IDs IDs `json:"ids"`
+}
+
+// RequestDeviceCode starts a device authorization.
+func (c *Client) RequestDeviceCode() (DeviceCode, error) {
+	var out DeviceCode
+	status, err := c.post("/oauth/device/code", "", map[string]string{"client_id": c.clientID}, &out)
+	if err == nil && status != http.StatusOK {
+		err = fmt.Errorf("trakt: device code request failed with http %d", status)
+	}
+	return out, err
+}
+
+// PollDeviceToken exchanges an authorized device code for a token. Until the user has
+// entered the code it returns ErrAuthorizationPending.
+func (c *Client) PollDeviceToken(deviceCode string) (Token, error) {
+	var out Token
+	status, err := c.post("/oauth/device/token", "", map[string]string{
+		"code":          deviceCode,
+		"client_id":     c.clientID,
+		"client_secret": c.clientSecret,
+	}, &out)
+	if err != nil {
+		return out, err
+	}
+	switch status {
+	case http.StatusOK:
+		return out, nil
+	case http.StatusBadRequest:
+		return out, ErrAuthorizationPending
+	case http.StatusTooManyRequests:
+		return out, ErrSlowDown
+	case http.StatusNotFound, http.StatusConflict, http.StatusGone:
+		return out, ErrCodeExpired
+	case http.StatusTeapot:
+		return out, ErrAccessDenied
+	}
+	return out, fmt.Errorf("trakt: device token request failed with http %d", status)
+}
+
+// RefreshToken trades a refresh token for a new token.
+func (c *Client) RefreshToken(refreshToken string) (Token, error) {
+	var out Token
+	status, err := c.post("/oauth/token", "", map[string]string{
+		"refresh_token": refreshToken,
+		"client_id":     c.clientID,
+		"client_secret": c.clientSecret,
+		"redirect_uri":  redirectURI,
+		"grant_type":    "refresh_token",
+	}, &out)
+	if err == nil && status != http.StatusOK {
+		if status == http.StatusUnauthorized || status == http.StatusBadRequest {
+			return out, ErrUnauthorized
+		}
+		err = fmt.Errorf("trakt: token refresh failed with http %d", status)
+	}
+	return out, err
+}
+
+// RevokeToken invalidates an access token.
+func (c *Client) RevokeToken(accessToken string) error {
+	status, err := c.post("/oauth/revoke", "", map[string]string{
+		"token":         accessToken,
+		"client_id":     c.clientID,
+		"client_secret": c.clientSecret,
+	}, nil)
+	if err == nil && status != http.StatusOK {
+		err = fmt.Errorf("trakt: revoke failed with http %d", status)
+	}
+	return err
+}
+
+// Username returns the Trakt username the access token belongs to.
+func (c *Client) Username(accessToken string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/users/settings", nil)
+	if err != nil {
+		return "", err
+	}
+	c.setHeaders(req, accessToken)
+	var out struct {
+		User struct {
+			Username string `json:"username"`
+		} `json:"user"`
+	}
+	status, err := c.do(req, &out)
+	if err != nil {
+		return "", err
+	}
+	if status == http.StatusUnauthorized {
+		return "", ErrUnauthorized
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("trakt: user settings request failed with http %d", status)
+	}
+	return out.User.Username, nil
+}
+
+// Scrobble reports a playback action. Trakt answers a stop it has already recorded with 409,
+// which counts as success.
+func (c *Client) Scrobble(accessToken, action string, body Scrobble) error {
+	status, err := c.post("/scrobble/"+action, accessToken, body, nil)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case http.StatusOK, http.StatusCreated, http.StatusConflict:
+		return nil
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusNotFound:
+		return errors.New("trakt: item not found")
+	}
+	return fmt.Errorf("trakt: scrobble %s failed with http %d", action, status)
+}
+
+func (c *Client) setHeaders(req *http.Request, accessToken string) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("trakt-api-version", "2")
+	req.Header.Set("trakt-api-key", c.clientID)
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+}
+
+// post sends body as JSON and decodes a successful response into dst when it is non-nil.
+// Error statuses are returned for the caller to interpret rather than as errors.
+func (c *Client) post(path, accessToken string, body, dst any) (int, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	c.setHeaders(req, accessToken)
+	return c.do(req, dst)
+}
+
+func (c *Client) do(req *http.Request, dst any) (int, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("read body: %w", err)
+	}
+	if dst != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 && len(body) > 0 {
+		if err := json.Unmarshal(body, dst); err != nil {
+			return resp.StatusCode, fmt.Errorf("decode %s: %w", req.URL.Path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,65 @@
+package trakt
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIDsFromProviders(t *testing.T) {
+	ids := IDsFromProviders(map[string]string{"imdb": "tt0111161", "tmdb": "278", "tvdb": "x", "plex": "5d7768"})
+	if ids != (IDs{IMDB: "tt0111161", TMDB: 278}) {
+		t.Fatalf("ids = %+v", ids)
+	}
+	if !IDsFromProviders(map[string]string{"imdb": "0111161"}).Empty() {
+		t.Error("imdb ids without the tt prefix should be ignored")
+	}
+}
+
+func TestProgress(t *testing.T) {
+	if p := progress(30*60_000, 90*60_000); p != 33.33 {
+		t.Errorf("progress = %v", p)
+	}
+	if p := progress(100, 50); p != 100 {
+		t.Errorf("progress past the end = %v", p)
+	}
+	if p := progress(100, 0); p != 0 {
+		t.Errorf("progress without runtime = %v", p)
+	}
+}
+
+func TestPollDeviceTokenStatuses(t *testing.T) {
+	status := http.StatusBadRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("trakt-api-key") != "id" {
+			t.Errorf("missing api key header")
+		}
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			json.NewEncoder(w).Encode(Token{AccessToken: "a", RefreshToken: "r", ExpiresIn: 100, CreatedAt: 1000})
+		}
+	}))
+	defer srv.Close()
+	c := New("id", "secret")
+	c.baseURL = srv.URL
+
+	for code, want := range map[int]error{
+		http.StatusBadRequest:      ErrAuthorizationPending,
+		http.StatusTooManyRequests: ErrSlowDown,
+		http.StatusGone:            ErrCodeExpired,
+		http.StatusTeapot:          ErrAccessDenied,
+	} {
+		status = code
+		if _, err := c.PollDeviceToken("dev"); !errors.Is(err, want) {
+			t.Errorf("http %d: err = %v, want %v", code, err, want)
+		}
+	}
+
+	status = http.StatusOK
+	tok, err := c.PollDeviceToken("dev")
+	if err != nil || tok.AccessToken != "a" || tok.ExpiresAt() != 1100 {
+		t.Fatalf("token = %+v, %v", tok, err)
+	}
+}
@@ -0,0 +1,172 @@
+package trakt
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"emby-analytics/internal/handlers/settings"
+	"emby-analytics/internal/hooks"
+	"emby-analytics/internal/media"
+)
+
+const (
+	// refreshMargin renews access tokens this long before they expire
+	refreshMargin = 24 * time.Hour
+	// maxCachedItems bounds the resolved items kept between events
+	maxCachedItems = 500
+)
+
+// Scrobbler forwards the playback of users with a linked Trakt account to Trakt while the
+// trakt_scrobble_enabled setting is on. It runs on the hooks goroutine, which hands it one
+// event at a time, so it needs no locking of its own.
+type Scrobbler struct {
+	db      *sql.DB
+	client  *Client
+	servers *media.MultiServerManager
+	// items caches how Trakt identifies a server's item; nil when it can't be identified
+	items map[string]*Scrobble
+}
+
+// StartScrobbler subscribes a scrobbler to the session processor's playback hooks.
+func StartScrobbler(db *sql.DB, client *Client, servers *media.MultiServerManager) *Scrobbler {
+	s := &Scrobbler{db: db, client: client, servers: servers, items: map[string]*Scrobble{}}
+	hooks.Register(hooks.SessionStarted, func(e hooks.Event) {
+		if p, ok := e.Data.(hooks.SessionStart); ok {
+			action := ActionStart
+			if p.Session.IsPaused {
+				action = ActionPause
+			}
+			s.scrobble(action, p.Session.UserID, p.Session.ServerID, p.Session.ItemID, p.Session.ItemType,
+				p.Session.PositionMs, p.Session.DurationMs)
+		}
+	})
+	actions := map[string]string{
+		hooks.PlaybackPaused:  ActionPause,
+		hooks.PlaybackResumed: ActionStart,
+		hooks.PlaybackStopped: ActionStop,
+	}
+	for event, action := range actions {
+		hooks.Register(event, func(e hooks.Event) {
+			if p, ok := e.Data.(hooks.Playback); ok {
+				s.scrobble(action, p.UserID, p.ServerID, p.ItemID, p.ItemType, p.PositionMs, p.DurationMs)
+			}
+		})
+	}
+	log.Info("Trakt scrobbler registered")
+	return s
+}
+
+func (s *Scrobbler) scrobble(action, userID, serverID, itemID, itemType string, positionMs, durationMs int64) {
+	switch strings.ToLower(itemType) {
+	case "movie", "episode":
+	default:
+		return
+	}
+	if !settings.GetSettingBool(s.db, settings.TraktScrobbleEnabledKey, false) {
+		return
+	}
+	acct, err := account(s.db, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		log.Warn("failed to load trakt account", "user", userID, "error", err)
+		return
+	}
+	body := s.resolve(serverID, itemID)
+	if body == nil {
+		log.Debug("item has no ids trakt knows; not scrobbling", "server", serverID, "item", itemID)
+		return
+	}
+	req := *body
+	req.Progress = progress(positionMs, durationMs)
+
+	if time.Until(time.Unix(acct.ExpiresAt, 0)) < refreshMargin {
+		if acct, err = s.refresh(acct); err != nil {
+			recordScrobble(s.db, userID, err)
+			return
+		}
+	}
+	err = s.client.Scrobble(acct.AccessToken, action, req)
+	if errors.Is(err, ErrUnauthorized) {
+		if acct, err = s.refresh(acct); err == nil {
+			err = s.client.Scrobble(acct.AccessToken, action, req)
+		}
+	}
+	if err != nil {
+		log.Warn("trakt scrobble failed", "user", userID, "action", action, "item", itemID, "error", err)
+	} else {
+		log.Debug("Scrobbled to trakt", "user", userID, "action", action, "item", itemID, "progress", req.Progress)
+	}
+	recordScrobble(s.db, userID, err)
+}
+
+// refresh renews an account's token and stores it.
+func (s *Scrobbler) refresh(acct Account) (Account, error) {
+	tok, err := s.client.RefreshToken(acct.RefreshToken)
+	if err != nil {
+		return acct, err
+	}
+	if err := saveToken(s.db, acct.UserID, "", tok); err != nil {
+		return acct, err
+	}
+	acct.AccessToken, acct.RefreshToken, acct.ExpiresAt = tok.AccessToken, tok.RefreshToken, tok.ExpiresAt()
+	return acct, nil
+}
+
+// resolve identifies a server's item for Trakt through its provider ids. Episodes without
+// ids of their own fall back to their series' ids plus season and episode number.
+func (s *Scrobbler) resolve(serverID, itemID string) *Scrobble {
+	key := serverID + "|" + itemID
+	if body, ok := s.items[key]; ok {
+		return body
+	}
+	client, ok := s.servers.GetClient(serverID)
+	if !ok || client == nil {
+		return nil
+	}
+	items, err := client.ItemsByIDs([]string{itemID})
+	if err != nil || len(items) == 0 {
+		return nil // not cached: the server may answer next time
+	}
+	it := items[0]
+	ids := IDsFromProviders(it.ProviderIDs)
+
+	var body *Scrobble
+	switch {
+	case strings.EqualFold(it.Type, "movie"):
+		if !ids.Empty() {
+			body = &Scrobble{Movie: &Media{IDs: ids}}
+		}
+	case !ids.Empty():
+		body = &Scrobble{Episode: &Episode{IDs: &ids}}
+	case it.SeriesID != "" && it.ParentIndexNumber != nil && it.IndexNumber != nil:
+		series, err := client.ItemsByIDs([]string{it.SeriesID})
+		if err != nil {
+			return nil
+		}
+		if len(series) > 0 {
+			if show := IDsFromProviders(series[0].ProviderIDs); !show.Empty() {
+				body = &Scrobble{Show: &Media{IDs: show}, Episode: &Episode{Season: *it.ParentIndexNumber, Number: *it.IndexNumber}}
+			}
+		}
+	}
+
+	if len(s.items) >= maxCachedItems {
+		clear(s.items)
+	}
+	s.items[key] = body
+	return body
+}
+
+// progress is the percentage of the runtime played, as Trakt expects it.
+func progress(positionMs, durationMs int64) float64 {
+	if durationMs <= 0 || positionMs <= 0 {
+		return 0
+	}
+	pct := float64(positionMs) / float64(durationMs) * 100
+	return math.Round(min(pct, 100)*100) / 100
+}
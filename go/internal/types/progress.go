@@ -14,6 +14,8 @@ type Progress struct {
 	Done      bool   `json:"done"`
 	Running   bool   `json:"running"`
 	Page      int    `json:"page"`
+	Changed   int    `json:"changed,omitempty"`   // items written by a full refresh
+	Unchanged int    `json:"unchanged,omitempty"` // items skipped because nothing changed
 }
 
 // RefreshManager interface defines the methods needed by the scheduler